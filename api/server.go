@@ -23,6 +23,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"net/http/pprof"
 
 	"github.com/loadimpact/k6/api/common"
 	"github.com/loadimpact/k6/api/v1"
@@ -39,8 +40,23 @@ func NewHandler() http.Handler {
 	return mux
 }
 
-func ListenAndServe(addr string, engine *core.Engine) error {
+// WithPprof adds the Go pprof endpoints under /debug/pprof/ to a handler, to profile k6 itself.
+func WithPprof(handler http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/", handler)
+	return mux
+}
+
+func ListenAndServe(addr string, engine *core.Engine, withPprof bool) error {
 	mux := NewHandler()
+	if withPprof {
+		mux = WithPprof(mux)
+	}
 
 	n := negroni.New()
 	n.Use(negroni.NewRecovery())
@@ -96,3 +96,16 @@ func TestPing(t *testing.T) {
 	assert.Equal(t, http.StatusOK, res.StatusCode)
 	assert.Equal(t, []byte{'o', 'k'}, rw.Body.Bytes())
 }
+
+func TestWithPprof(t *testing.T) {
+	mux := WithPprof(NewHandler())
+
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rw.Result().StatusCode)
+	assert.Contains(t, rw.Body.String(), "goroutine")
+
+	rw = httptest.NewRecorder()
+	mux.ServeHTTP(rw, httptest.NewRequest("GET", "/ping", nil))
+	assert.Equal(t, "ok", rw.Body.String())
+}
@@ -31,8 +31,9 @@ type Status struct {
 	VUsMax null.Int  `json:"vus-max" yaml:"vus-max"`
 
 	// Readonly.
-	Running bool `json:"running" yaml:"running"`
-	Tainted bool `json:"tainted" yaml:"tainted"`
+	Running              bool `json:"running" yaml:"running"`
+	Tainted              bool `json:"tainted" yaml:"tainted"`
+	ExternallyControlled bool `json:"externally-controlled" yaml:"externally-controlled"`
 }
 
 func NewStatus(engine *core.Engine) Status {
@@ -42,6 +43,8 @@ func NewStatus(engine *core.Engine) Status {
 		VUsMax:  null.IntFrom(engine.Executor.GetVUsMax()),
 		Running: engine.Executor.IsRunning(),
 		Tainted: engine.IsTainted(),
+
+		ExternallyControlled: engine.Options.ExternallyControlled.Bool,
 	}
 }
 
@@ -59,6 +59,18 @@ func TestGetStatus(t *testing.T) {
 		assert.True(t, status.VUs.Valid)
 		assert.True(t, status.VUsMax.Valid)
 		assert.False(t, status.Tainted)
+		assert.False(t, status.ExternallyControlled)
+	})
+
+	t.Run("externally controlled", func(t *testing.T) {
+		engine, err := core.NewEngine(nil, lib.Options{ExternallyControlled: null.BoolFrom(true)})
+		assert.NoError(t, err)
+
+		rw := httptest.NewRecorder()
+		NewHandler().ServeHTTP(rw, newRequestWithEngine(engine, "GET", "/v1/status", nil))
+		var status Status
+		assert.NoError(t, jsonapi.Unmarshal(rw.Body.Bytes(), &status))
+		assert.True(t, status.ExternallyControlled)
 	})
 }
 
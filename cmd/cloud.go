@@ -84,7 +84,7 @@ This will execute the test on the Load Impact cloud service. Use "k6 login cloud
 
 		// Options
 		fs := afero.NewOsFs()
-		fileConf, _, err := readDiskConfig(fs)
+		fileConf, err := readConfigFiles(fs, pwd)
 		if err != nil {
 			return err
 		}
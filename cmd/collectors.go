@@ -28,19 +28,32 @@ import (
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/loadimpact/k6/lib"
+	outputs "github.com/loadimpact/k6/lib/output"
 	"github.com/loadimpact/k6/stats/cloud"
+	"github.com/loadimpact/k6/stats/elasticsearch"
+	"github.com/loadimpact/k6/stats/graphite"
 	"github.com/loadimpact/k6/stats/influxdb"
 	jsonc "github.com/loadimpact/k6/stats/json"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/nats"
+	"github.com/loadimpact/k6/stats/otlp"
+	"github.com/loadimpact/k6/stats/postgres"
+	"github.com/loadimpact/k6/stats/webdashboard"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 )
 
 const (
-	collectorInfluxDB = "influxdb"
-	collectorJSON     = "json"
-	collectorKafka    = "kafka"
-	collectorCloud    = "cloud"
+	collectorInfluxDB      = "influxdb"
+	collectorJSON          = "json"
+	collectorKafka         = "kafka"
+	collectorCloud         = "cloud"
+	collectorOTLP          = "otlp"
+	collectorPostgres      = "postgres"
+	collectorElasticsearch = "elasticsearch"
+	collectorWebDashboard  = "web-dashboard"
+	collectorGraphite      = "graphite"
+	collectorNATS          = "nats"
 )
 
 func parseCollector(s string) (t, arg string) {
@@ -93,8 +106,72 @@ func newCollector(collectorName, arg string, src *lib.SourceData, conf Config) (
 				config = config.Apply(cmdConfig)
 			}
 			return kafka.New(config)
+		case collectorOTLP:
+			config := otlp.NewConfig().Apply(conf.Collectors.OTLP)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := otlp.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return otlp.New(config.Apply(argConfig), Version)
+		case collectorPostgres:
+			config := postgres.NewConfig().Apply(conf.Collectors.Postgres)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := postgres.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return postgres.New(config.Apply(argConfig))
+		case collectorElasticsearch:
+			config := elasticsearch.NewConfig().Apply(conf.Collectors.Elasticsearch)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := elasticsearch.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return elasticsearch.New(config.Apply(argConfig), src, conf.Options, Version)
+		case collectorWebDashboard:
+			config := webdashboard.NewConfig().Apply(conf.Collectors.WebDashboard)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := webdashboard.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return webdashboard.New(config.Apply(argConfig))
+		case collectorGraphite:
+			config := graphite.NewConfig().Apply(conf.Collectors.Graphite)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := graphite.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return graphite.New(config.Apply(argConfig))
+		case collectorNATS:
+			config := nats.NewConfig().Apply(conf.Collectors.NATS)
+			if err := envconfig.Process("k6", &config); err != nil {
+				return nil, err
+			}
+			argConfig, err := nats.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			return nats.New(config.Apply(argConfig), Version)
 		default:
-			return nil, errors.Errorf("unknown output type: %s", collectorName)
+			constructor, ok := outputs.Get(collectorName)
+			if !ok {
+				return nil, errors.Errorf("unknown output type: %s", collectorName)
+			}
+			return constructor(outputs.Params{Arg: arg, Options: conf.Options, Src: src, Version: Version})
 		}
 	}
 
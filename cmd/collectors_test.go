@@ -0,0 +1,50 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	outputs "github.com/loadimpact/k6/lib/output"
+	"github.com/loadimpact/k6/stats/dummy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCollectorExtension(t *testing.T) {
+	var params outputs.Params
+	outputs.Register("collectors-test", func(p outputs.Params) (lib.Collector, error) {
+		params = p
+		return &dummy.Collector{}, nil
+	})
+
+	src := &lib.SourceData{Filename: "/script.js"}
+	collector, err := newCollector("collectors-test", "some=arg", src, Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &dummy.Collector{}, collector)
+	assert.Equal(t, "some=arg", params.Arg)
+	assert.Equal(t, src, params.Src)
+	assert.Equal(t, Version, params.Version)
+
+	_, err = newCollector("nonexistent", "", src, Config{})
+	assert.EqualError(t, err, "unknown output type: nonexistent")
+}
@@ -22,14 +22,24 @@ package cmd
 
 import (
 	"encoding/json"
-	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/dop251/goja"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/js/compiler"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/stats/cloud"
+	"github.com/loadimpact/k6/stats/elasticsearch"
+	"github.com/loadimpact/k6/stats/graphite"
 	"github.com/loadimpact/k6/stats/influxdb"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/nats"
+	"github.com/loadimpact/k6/stats/otlp"
+	"github.com/loadimpact/k6/stats/postgres"
+	"github.com/loadimpact/k6/stats/webdashboard"
+	"github.com/loadimpact/k6/stats/webhook"
+	"github.com/pkg/errors"
 	"github.com/shibukawa/configdir"
 	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
@@ -38,16 +48,13 @@ import (
 
 const configFilename = "config.json"
 
+// The names of per-project config files, which are looked for in the working directory and its
+// parents. The first one that's found is used.
+var projectConfigFilenames = []string{"k6.config.json", "k6.config.js"}
+
 var configDirs = configdir.New("loadimpact", "k6")
 var configFile = os.Getenv("K6_CONFIG") // overridden by `-c` flag!
 
-// configFileFlagSet returns a FlagSet that contains flags needed for specifying a config file.
-func configFileFlagSet() *pflag.FlagSet {
-	flags := pflag.NewFlagSet("", 0)
-	flags.StringVarP(&configFile, "config", "c", configFile, "specify config file to read")
-	return flags
-}
-
 // configFlagSet returns a FlagSet with the default run configuration flags.
 func configFlagSet() *pflag.FlagSet {
 	flags := pflag.NewFlagSet("", 0)
@@ -56,22 +63,40 @@ func configFlagSet() *pflag.FlagSet {
 	flags.BoolP("linger", "l", false, "keep the API server alive past test end")
 	flags.Bool("no-usage-report", false, "don't send anonymous stats to the developers")
 	flags.Bool("no-thresholds", false, "don't run thresholds")
-	flags.AddFlagSet(configFileFlagSet())
+	flags.Bool("no-summary", false, "don't show the summary at the end of the test")
+	flags.Bool("dashboard", false, "show a live dashboard instead of the progress bar")
+	flags.String("junit-export", "", "write the results of thresholds and checks to a JUnit XML `file`")
+	flags.String("summary-export", "", "write the end-of-test summary to a JSON `file`, e.g. to --compare later runs to it")
+	flags.String("compare", "", "compare the summary to that of a previous run, from a --summary-export `file`")
+	flags.StringArray("webhook", []string{}, "POST the test's start, end, abort and threshold events to a `url`, or only some with e.g. end,abort@<url>")
 	return flags
 }
 
 type Config struct {
 	lib.Options
 
-	Out           []string  `json:"out" envconfig:"out"`
-	Linger        null.Bool `json:"linger" envconfig:"linger"`
-	NoUsageReport null.Bool `json:"noUsageReport" envconfig:"no_usage_report"`
-	NoThresholds  null.Bool `json:"noThresholds" envconfig:"no_thresholds"`
+	Out           []string    `json:"out" envconfig:"out"`
+	Linger        null.Bool   `json:"linger" envconfig:"linger"`
+	NoUsageReport null.Bool   `json:"noUsageReport" envconfig:"no_usage_report"`
+	NoThresholds  null.Bool   `json:"noThresholds" envconfig:"no_thresholds"`
+	NoSummary     null.Bool   `json:"noSummary" envconfig:"no_summary"`
+	Dashboard     null.Bool   `json:"dashboard" envconfig:"dashboard"`
+	JUnitExport   null.String `json:"junitExport" envconfig:"junit_export"`
+	SummaryExport null.String `json:"summaryExport" envconfig:"summary_export"`
+	Compare       null.String `json:"compare" envconfig:"compare"`
+
+	Webhooks []webhook.Config `json:"webhooks" ignored:"true"`
 
 	Collectors struct {
-		InfluxDB influxdb.Config `json:"influxdb"`
-		Kafka    kafka.Config    `json:"kafka"`
-		Cloud    cloud.Config    `json:"cloud"`
+		InfluxDB      influxdb.Config      `json:"influxdb"`
+		Kafka         kafka.Config         `json:"kafka"`
+		Cloud         cloud.Config         `json:"cloud"`
+		OTLP          otlp.Config          `json:"otlp"`
+		Postgres      postgres.Config      `json:"postgres"`
+		Elasticsearch elasticsearch.Config `json:"elasticsearch"`
+		WebDashboard  webdashboard.Config  `json:"webDashboard"`
+		Graphite      graphite.Config      `json:"graphite"`
+		NATS          nats.Config          `json:"nats"`
 	} `json:"collectors"`
 }
 
@@ -89,8 +114,32 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.NoThresholds.Valid {
 		c.NoThresholds = cfg.NoThresholds
 	}
+	if cfg.NoSummary.Valid {
+		c.NoSummary = cfg.NoSummary
+	}
+	if cfg.Dashboard.Valid {
+		c.Dashboard = cfg.Dashboard
+	}
+	if cfg.JUnitExport.Valid {
+		c.JUnitExport = cfg.JUnitExport
+	}
+	if cfg.SummaryExport.Valid {
+		c.SummaryExport = cfg.SummaryExport
+	}
+	if cfg.Compare.Valid {
+		c.Compare = cfg.Compare
+	}
+	if len(cfg.Webhooks) > 0 {
+		c.Webhooks = cfg.Webhooks
+	}
 	c.Collectors.InfluxDB = c.Collectors.InfluxDB.Apply(cfg.Collectors.InfluxDB)
 	c.Collectors.Cloud = c.Collectors.Cloud.Apply(cfg.Collectors.Cloud)
+	c.Collectors.OTLP = c.Collectors.OTLP.Apply(cfg.Collectors.OTLP)
+	c.Collectors.Postgres = c.Collectors.Postgres.Apply(cfg.Collectors.Postgres)
+	c.Collectors.Elasticsearch = c.Collectors.Elasticsearch.Apply(cfg.Collectors.Elasticsearch)
+	c.Collectors.WebDashboard = c.Collectors.WebDashboard.Apply(cfg.Collectors.WebDashboard)
+	c.Collectors.Graphite = c.Collectors.Graphite.Apply(cfg.Collectors.Graphite)
+	c.Collectors.NATS = c.Collectors.NATS.Apply(cfg.Collectors.NATS)
 	return c
 }
 
@@ -104,24 +153,38 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	webhookArgs, err := flags.GetStringArray("webhook")
+	if err != nil {
+		return Config{}, err
+	}
+	var webhooks []webhook.Config
+	for _, arg := range webhookArgs {
+		hook, err := webhook.ParseArg(arg)
+		if err != nil {
+			return Config{}, err
+		}
+		webhooks = append(webhooks, hook)
+	}
 	return Config{
 		Options:       opts,
 		Out:           out,
 		Linger:        getNullBool(flags, "linger"),
 		NoUsageReport: getNullBool(flags, "no-usage-report"),
 		NoThresholds:  getNullBool(flags, "no-thresholds"),
+		NoSummary:     getNullBool(flags, "no-summary"),
+		Dashboard:     getNullBool(flags, "dashboard"),
+		JUnitExport:   getNullString(flags, "junit-export"),
+		SummaryExport: getNullString(flags, "summary-export"),
+		Compare:       getNullString(flags, "compare"),
+		Webhooks:      webhooks,
 	}, nil
 }
 
-// Reads a configuration file from disk.
+// Reads a configuration file from disk: the --config file, or the global one in the k6 config
+// directory, which is also where the config is written back to if there's no --config file.
 func readDiskConfig(fs afero.Fs) (Config, *configdir.Config, error) {
 	if configFile != "" {
-		data, err := ioutil.ReadFile(configFile)
-		if err != nil {
-			return Config{}, nil, err
-		}
-		var conf Config
-		err = json.Unmarshal(data, &conf)
+		conf, err := readConfigFile(fs, configFile)
 		return conf, nil, err
 	}
 
@@ -138,16 +201,126 @@ func readDiskConfig(fs afero.Fs) (Config, *configdir.Config, error) {
 	return conf, cdir, err
 }
 
+// Reads the configuration files that a test uses. That's only the --config file if there is one,
+// otherwise it's the global config file, with the first project config file found in pwd or its
+// parents applied on top of it.
+func readConfigFiles(fs afero.Fs, pwd string) (Config, error) {
+	conf, _, err := readDiskConfig(fs)
+	if err != nil || configFile != "" {
+		return conf, err
+	}
+
+	filename, err := findProjectConfig(fs, pwd)
+	if err != nil || filename == "" {
+		return conf, err
+	}
+	projectConf, err := readConfigFile(fs, filename)
+	if err != nil {
+		return conf, err
+	}
+	return conf.Apply(projectConf), nil
+}
+
+// Finds the project config file for a directory, and returns "" if there isn't one.
+func findProjectConfig(fs afero.Fs, dir string) (string, error) {
+	for {
+		for _, name := range projectConfigFilenames {
+			filename := filepath.Join(dir, name)
+			exists, err := afero.Exists(fs, filename)
+			if err != nil {
+				return "", err
+			}
+			if exists {
+				return filename, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Reads a config file, which is either JSON, or a JS file that exports the config, see
+// evalJSConfig.
+func readConfigFile(fs afero.Fs, filename string) (Config, error) {
+	data, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return Config{}, err
+	}
+	if filepath.Ext(filename) == ".js" {
+		if data, err = evalJSConfig(filename, data); err != nil {
+			return Config{}, errors.Wrapf(err, "couldn't evaluate the config file %s", filename)
+		}
+	}
+	var conf Config
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return Config{}, errors.Wrapf(err, "invalid config file %s", filename)
+	}
+	return conf, nil
+}
+
+// Evaluates a JS config file, and returns the config that it exports, either with
+// `export default` or by setting `module.exports`, as JSON. The file can use the environment
+// variables through __ENV, but it can't import anything.
+func evalJSConfig(filename string, data []byte) ([]byte, error) {
+	c, err := compiler.New()
+	if err != nil {
+		return nil, err
+	}
+	pgm, _, err := c.Compile(string(data), filename, "(function(module, exports, __ENV){\n", "\n})", true)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := goja.New()
+	fn, err := rt.RunProgram(pgm)
+	if err != nil {
+		return nil, err
+	}
+	call, ok := goja.AssertFunction(fn)
+	if !ok {
+		return nil, errors.New("it's not a module")
+	}
+	module, exports := rt.NewObject(), rt.NewObject()
+	if err := module.Set("exports", exports); err != nil {
+		return nil, err
+	}
+	if _, err := call(goja.Undefined(), module, exports, rt.ToValue(collectEnv())); err != nil {
+		return nil, err
+	}
+
+	// Babel turns `export default` into `exports.default`
+	exported := module.Get("exports")
+	if obj := exported.ToObject(rt); obj.Get("__esModule") != nil && obj.Get("default") != nil {
+		exported = obj.Get("default")
+	}
+	if goja.IsUndefined(exported) || goja.IsNull(exported) {
+		return nil, errors.New("it doesn't export a config")
+	}
+	return json.Marshal(exported.Export())
+}
+
 // Writes configuration back to disk.
 func writeDiskConfig(fs afero.Fs, cdir *configdir.Config, conf Config) error {
 	data, err := json.MarshalIndent(conf, "", "  ")
 	if err != nil {
 		return err
 	}
-	if configFile != "" {
-		return afero.WriteFile(fs, configFilename, data, 0644)
+	filename := configFile
+	if filename == "" {
+		if err := cdir.CreateParentDir(configFilename); err != nil {
+			return err
+		}
+		filename = filepath.Join(cdir.Path, configFilename)
+	}
+
+	// It can have credentials, so only the user can read it
+	if err := afero.WriteFile(fs, filename, data, 0600); err != nil {
+		return err
 	}
-	return cdir.WriteFile(configFilename, data)
+	return fs.Chmod(filename, 0600)
 }
 
 // Reads configuration variables from the environment.
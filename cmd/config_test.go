@@ -25,7 +25,10 @@ import (
 	"testing"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 )
 
@@ -132,3 +135,73 @@ func TestConfigApply(t *testing.T) {
 		assert.Equal(t, []string{"influxdb", "json"}, conf.Out)
 	})
 }
+
+func TestReadConfigFiles(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/project/k6.config.js", []byte(`
+		export default {
+			out: ["json=" + __ENV.TEST_CONFIG_OUT],
+			tags: { team: "checkout" },
+			duration: "10s",
+		};
+	`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/project/api/k6.config.json", []byte(`{"vus": 5}`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/other/config.json", []byte(`{"linger": true}`), 0644))
+	require.NoError(t, os.Setenv("TEST_CONFIG_OUT", "results.json"))
+	defer func() { _ = os.Unsetenv("TEST_CONFIG_OUT") }()
+
+	t.Run("JS", func(t *testing.T) {
+		conf, err := readConfigFiles(fs, "/project/tests/smoke")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"json=results.json"}, conf.Out)
+		assert.Equal(t, map[string]string{"team": "checkout"}, conf.RunTags.CloneTags())
+		assert.Equal(t, types.NullDurationFrom(10e9), conf.Duration)
+	})
+	t.Run("Closest", func(t *testing.T) {
+		conf, err := readConfigFiles(fs, "/project/api")
+		require.NoError(t, err)
+		assert.Equal(t, null.IntFrom(5), conf.VUs)
+		assert.Nil(t, conf.Out)
+	})
+	t.Run("None", func(t *testing.T) {
+		conf, err := readConfigFiles(fs, "/other")
+		require.NoError(t, err)
+		assert.False(t, conf.Linger.Valid)
+	})
+	t.Run("Flag", func(t *testing.T) {
+		configFile = "/other/config.json"
+		defer func() { configFile = "" }()
+		conf, err := readConfigFiles(fs, "/project/api")
+		require.NoError(t, err)
+		assert.Equal(t, null.BoolFrom(true), conf.Linger)
+		assert.False(t, conf.VUs.Valid)
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/broken/k6.config.js", []byte(`module.exports = { vus: "many" };`), 0644))
+		_, err := readConfigFiles(fs, "/broken")
+		assert.Error(t, err)
+		require.NoError(t, afero.WriteFile(fs, "/broken/k6.config.js", []byte(`throw new Error("oops");`), 0644))
+		_, err = readConfigFiles(fs, "/broken")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "couldn't evaluate the config file /broken/k6.config.js: Error: oops")
+		}
+	})
+}
+
+func TestWriteDiskConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	configFile = "/k6/config.json"
+	defer func() { configFile = "" }()
+	require.NoError(t, afero.WriteFile(fs, configFile, []byte("{}"), 0644))
+
+	var conf Config
+	conf.Collectors.Elasticsearch.Password = null.StringFrom("hunter2")
+	require.NoError(t, writeDiskConfig(fs, nil, conf))
+
+	info, err := fs.Stat(configFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	read, err := readConfigFiles(fs, "/")
+	require.NoError(t, err)
+	assert.Equal(t, null.StringFrom("hunter2"), read.Collectors.Elasticsearch.Password)
+}
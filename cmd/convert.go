@@ -81,49 +81,53 @@ var convertCmd = &cobra.Command{
 			return err
 		}
 
-		// recordings include redirections as separate requests, and we dont want to trigger them twice
-		options := lib.Options{MaxRedirects: null.IntFrom(0)}
-
-		if optionsFilePath != "" {
-			optionsFileContents, err := ioutil.ReadFile(optionsFilePath)
-			if err != nil {
-				return err
-			}
-			var injectedOptions lib.Options
-			if err := json.Unmarshal(optionsFileContents, &injectedOptions); err != nil {
-				return err
-			}
-			options = options.Apply(injectedOptions)
-		}
-
-		//TODO: refactor...
-		script, err := har.Convert(h, options, minSleep, maxSleep, enableChecks, returnOnFailedCheck, threshold, nobatch, correlate, only, skip)
+		script, err := convertHAR(h)
 		if err != nil {
 			return err
 		}
+		return writeScript(script)
+	},
+}
+
+// convertHAR converts a HAR log to a script, with the options of the convert flags.
+func convertHAR(h har.HAR) (string, error) {
+	// recordings include redirections as separate requests, and we dont want to trigger them twice
+	options := lib.Options{MaxRedirects: null.IntFrom(0)}
 
-		// Write script content to stdout or file
-		if output == "" || output == "-" {
-			if _, err := io.WriteString(defaultWriter, script); err != nil {
-				return err
-			}
-		} else {
-			f, err := defaultFs.Create(output)
-			if err != nil {
-				return err
-			}
-			if _, err := f.WriteString(script); err != nil {
-				return err
-			}
-			if err := f.Sync(); err != nil {
-				return err
-			}
-			if err := f.Close(); err != nil {
-				return err
-			}
+	if optionsFilePath != "" {
+		optionsFileContents, err := ioutil.ReadFile(optionsFilePath)
+		if err != nil {
+			return "", err
 		}
-		return nil
-	},
+		var injectedOptions lib.Options
+		if err := json.Unmarshal(optionsFileContents, &injectedOptions); err != nil {
+			return "", err
+		}
+		options = options.Apply(injectedOptions)
+	}
+
+	//TODO: refactor...
+	return har.Convert(h, options, minSleep, maxSleep, enableChecks, returnOnFailedCheck, threshold, nobatch, correlate, only, skip)
+}
+
+// writeScript writes a generated script to stdout or to the --output file.
+func writeScript(script string) error {
+	if output == "" || output == "-" {
+		_, err := io.WriteString(defaultWriter, script)
+		return err
+	}
+
+	f, err := defaultFs.Create(output)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(script); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
 }
 
 func init() {
@@ -137,7 +141,7 @@ func init() {
 	convertCmd.Flags().BoolVarP(&nobatch, "no-batch", "", false, "don't generate batch calls")
 	convertCmd.Flags().BoolVarP(&enableChecks, "enable-status-code-checks", "", false, "add a status code check for each HTTP response")
 	convertCmd.Flags().BoolVarP(&returnOnFailedCheck, "return-on-failed-check", "", false, "return from iteration if we get an unexpected response status code")
-	convertCmd.Flags().BoolVarP(&correlate, "correlate", "", false, "detect values in responses being used in subsequent requests and try adapt the script accordingly (only redirects and JSON values for now)")
+	convertCmd.Flags().BoolVarP(&correlate, "correlate", "", false, "detect values in responses being used in subsequent requests (redirects, IDs in JSON, CSRF tokens and session cookies) and extract them in the script; requires --no-batch")
 	convertCmd.Flags().UintVarP(&minSleep, "min-sleep", "", 20, "the minimum amount of seconds to sleep after each iteration")
 	convertCmd.Flags().UintVarP(&maxSleep, "max-sleep", "", 40, "the maximum amount of seconds to sleep after each iteration")
 }
@@ -0,0 +1,218 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Command k6-build builds a k6 binary with extensions compiled in. Extensions are Go packages that
+// register JS modules with modules.Register() and outputs with output.Register() in their init():
+//
+//	k6-build -o ./k6 github.com/example/xk6-protocol github.com/example/xk6-output
+//
+// Extensions are built against the vendored dependencies of k6, so that they share types like
+// goja.Value with it. To do that without touching the k6 source, the source is copied into a
+// temporary GOPATH, the extensions are copied into its vendor directory (without any vendor
+// directories of their own), and a file that imports them is added to its main package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const k6Package = "github.com/loadimpact/k6"
+
+type config struct {
+	Output     string
+	K6Dir      string
+	Get        bool
+	Extensions []string
+}
+
+func main() {
+	var conf config
+	flag.StringVar(&conf.Output, "o", "k6", "where to write the binary")
+	flag.StringVar(&conf.K6Dir, "k6", "", "the k6 source to build (default: "+k6Package+" in the GOPATH)")
+	flag.BoolVar(&conf.Get, "get", true, "download the extensions with `go get -d` first")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: k6-build [flags] <extension package>...\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	conf.Extensions = flag.Args()
+	if len(conf.Extensions) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := build(conf); err != nil {
+		fmt.Fprintln(os.Stderr, "k6-build:", err)
+		os.Exit(1)
+	}
+}
+
+func build(conf config) error {
+	gopath, err := goEnv("GOPATH")
+	if err != nil {
+		return err
+	}
+	if conf.K6Dir == "" {
+		if conf.K6Dir, err = findPackage(gopath, k6Package); err != nil {
+			return err
+		}
+	}
+	output, err := filepath.Abs(conf.Output)
+	if err != nil {
+		return err
+	}
+
+	work, err := ioutil.TempDir("", "k6-build")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(work) }()
+
+	k6Dir := filepath.Join(work, "src", filepath.FromSlash(k6Package))
+	if err := copyTree(conf.K6Dir, k6Dir, ".git"); err != nil {
+		return errors.Wrap(err, "couldn't copy the k6 source")
+	}
+
+	for _, ext := range conf.Extensions {
+		if conf.Get {
+			if err := run(nil, "go", "get", "-d", ext); err != nil {
+				return errors.Wrapf(err, "couldn't download %s", ext)
+			}
+		}
+		dir, err := findPackage(gopath, ext)
+		if err != nil {
+			return err
+		}
+		if err := copyTree(dir, filepath.Join(k6Dir, "vendor", filepath.FromSlash(ext)), ".git", "vendor"); err != nil {
+			return errors.Wrapf(err, "couldn't copy %s", ext)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(k6Dir, "extensions.go"), extensionsFile(conf.Extensions), 0644); err != nil {
+		return err
+	}
+
+	env := []string{
+		"GOPATH=" + work + string(os.PathListSeparator) + gopath,
+		"GO111MODULE=off",
+	}
+	return run(env, "go", "build", "-o", output, k6Package)
+}
+
+// extensionsFile returns the source of a file in k6's main package that imports the extensions,
+// so that their init() functions register them.
+func extensionsFile(extensions []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by k6-build. DO NOT EDIT.\n\npackage main\n\nimport (\n")
+	for _, ext := range extensions {
+		fmt.Fprintf(&buf, "\t_ %q\n", ext)
+	}
+	buf.WriteString(")\n")
+	return buf.Bytes()
+}
+
+// findPackage returns the directory of a package in the first GOPATH entry that has it.
+func findPackage(gopath, pkg string) (string, error) {
+	for _, root := range filepath.SplitList(gopath) {
+		dir := filepath.Join(root, "src", filepath.FromSlash(pkg))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", errors.Errorf("couldn't find %s in the GOPATH (%s)", pkg, gopath)
+}
+
+// copyTree copies the regular files in a directory recursively, without the directories with the
+// skipped names. The directory itself can be a symlink.
+func copyTree(src, dst string, skip ...string) error {
+	src, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			if rel != "." {
+				for _, name := range skip {
+					if info.Name() == name {
+						return filepath.SkipDir
+					}
+				}
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func goEnv(name string) (string, error) {
+	out, err := exec.Command("go", "env", name).Output()
+	if err != nil {
+		return "", errors.Wrap(err, "couldn't run go env")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func run(env []string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
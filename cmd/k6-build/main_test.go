@@ -0,0 +1,77 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensionsFile(t *testing.T) {
+	src := extensionsFile([]string{"github.com/example/xk6-a", "example.com/xk6-b"})
+	assert.Equal(t, `// Code generated by k6-build. DO NOT EDIT.
+
+package main
+
+import (
+	_ "github.com/example/xk6-a"
+	_ "example.com/xk6-b"
+)
+`, string(src))
+}
+
+func TestCopyTree(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "k6-build-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	src := filepath.Join(tmp, "src")
+	for _, name := range []string{"ext.go", "sub/sub.go", "vendor/dep/dep.go", ".git/HEAD"} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(name), 0644))
+	}
+	link := filepath.Join(tmp, "link")
+	require.NoError(t, os.Symlink(src, link))
+
+	dst := filepath.Join(tmp, "dst")
+	require.NoError(t, copyTree(link, dst, ".git", "vendor"))
+
+	data, err := ioutil.ReadFile(filepath.Join(dst, "sub", "sub.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/sub.go", string(data))
+	assert.FileExists(t, filepath.Join(dst, "ext.go"))
+	_, err = os.Stat(filepath.Join(dst, "vendor"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dst, ".git"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = findPackage(filepath.Join(tmp, "missing"), "sub")
+	assert.Error(t, err)
+	dir, err := findPackage(filepath.Join(tmp, "missing")+string(os.PathListSeparator)+tmp, "sub")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(src, "sub"), dir)
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/loadimpact/k6/js"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [file]",
+	Short: "Check a script for common mistakes",
+	Long: `Check a script for common mistakes.
+
+The script is compiled and its init context is run, without running any of its exported functions,
+so syntax errors, exceptions in the init context and a missing default function are errors. Then
+it's checked for things that work, but likely not the way they were meant to:
+
+  - unknown options, thresholds that can't work and scenarios running functions that don't exist
+  - sleep() in setup() or teardown(), which delays the test for all VUs
+  - metrics created or files opened in the exported functions, instead of the init context
+
+The exit code is 1 if there are any warnings.`,
+	Example: `
+  # Check a script before running it.
+  k6 lint script.js`[1:],
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		fs := afero.NewOsFs()
+		src, err := readSource(args[0], pwd, fs, os.Stdin)
+		if err != nil {
+			return err
+		}
+		if detectType(src.Data) == typeArchive {
+			return errors.New("only scripts can be linted, not archives")
+		}
+
+		runtimeOptions, err := getRuntimeOptions(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		warnings, err := js.Lint(src, fs, runtimeOptions)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fprintf(stdout, "%s: %s\n", src.Filename, w)
+		}
+		if len(warnings) > 0 {
+			return ExitCode{errors.Errorf("%d warnings", len(warnings)), 1}
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().SortFlags = false
+	lintCmd.Flags().AddFlagSet(runtimeOptionFlagSet(false))
+}
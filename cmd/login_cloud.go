@@ -76,10 +76,10 @@ This will set the default token used when just "k6 run -o cloud" is passed.`,
 						Key:   "Email",
 						Label: "Email",
 					},
-					ui.StringField{
+					ui.PasswordField{StringField: ui.StringField{
 						Key:   "Password",
 						Label: "Password",
-					},
+					}},
 				},
 			}
 			vals, err := form.Run(os.Stdin, stdout)
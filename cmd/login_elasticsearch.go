@@ -0,0 +1,106 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats/elasticsearch"
+	"github.com/loadimpact/k6/ui"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// loginElasticsearchCommand represents the 'login elasticsearch' command
+var loginElasticsearchCommand = &cobra.Command{
+	Use:   "elasticsearch [url]",
+	Short: "Authenticate with Elasticsearch",
+	Long: `Authenticate with Elasticsearch.
+
+This will set the default server and credentials used when just "-o elasticsearch" is passed.
+Either a username and password, or an API key can be given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := afero.NewOsFs()
+		config, cdir, err := readDiskConfig(fs)
+		if err != nil {
+			return err
+		}
+
+		conf := elasticsearch.NewConfig().Apply(config.Collectors.Elasticsearch)
+		if len(args) > 0 {
+			argConf, err := elasticsearch.ParseArg(args[0])
+			if err != nil {
+				return err
+			}
+			conf = conf.Apply(argConf)
+		}
+
+		form := ui.Form{
+			Fields: []ui.Field{
+				ui.StringField{
+					Key:     "URL",
+					Label:   "URL",
+					Default: conf.URL.String,
+				},
+				ui.StringField{
+					Key:     "Username",
+					Label:   "Username",
+					Default: conf.Username.String,
+				},
+				ui.PasswordField{StringField: ui.StringField{
+					Key:     "Password",
+					Label:   "Password",
+					Default: conf.Password.String,
+				}},
+				ui.PasswordField{StringField: ui.StringField{
+					Key:     "APIKey",
+					Label:   "API key (instead of a username and password)",
+					Default: conf.APIKey.String,
+				}},
+			},
+		}
+		vals, err := form.Run(os.Stdin, stdout)
+		if err != nil {
+			return err
+		}
+		if err := mapstructure.Decode(vals, &conf); err != nil {
+			return err
+		}
+
+		coll, err := elasticsearch.New(conf, nil, lib.Options{}, Version)
+		if err != nil {
+			return err
+		}
+		if err := coll.Ping(); err != nil {
+			return err
+		}
+
+		config.Collectors.Elasticsearch = conf
+		return writeDiskConfig(fs, cdir, config)
+	},
+}
+
+func init() {
+	loginCmd.AddCommand(loginElasticsearchCommand)
+}
@@ -37,7 +37,8 @@ var loginInfluxDBCommand = &cobra.Command{
 	Short: "Authenticate with InfluxDB",
 	Long: `Authenticate with InfluxDB.
 
-This will set the default server used when just "-o influxdb" is passed.`,
+This will set the default server used when just "-o influxdb" is passed. For InfluxDB 2.x and
+InfluxDB Cloud, enter an organization and a token, and optionally a bucket.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		fs := afero.NewOsFs()
@@ -72,11 +73,26 @@ This will set the default server used when just "-o influxdb" is passed.`,
 					Label:   "Username",
 					Default: conf.Username.String,
 				},
-				ui.StringField{
+				ui.PasswordField{StringField: ui.StringField{
 					Key:     "Password",
 					Label:   "Password",
 					Default: conf.Password.String,
+				}},
+				ui.StringField{
+					Key:     "Organization",
+					Label:   "Organization (InfluxDB 2.x)",
+					Default: conf.Organization.String,
+				},
+				ui.StringField{
+					Key:     "Bucket",
+					Label:   "Bucket (InfluxDB 2.x, the database if empty)",
+					Default: conf.Bucket.String,
 				},
+				ui.PasswordField{StringField: ui.StringField{
+					Key:     "Token",
+					Label:   "Token (InfluxDB 2.x)",
+					Default: conf.Token.String,
+				}},
 			},
 		}
 		vals, err := form.Run(os.Stdin, stdout)
@@ -0,0 +1,98 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/loadimpact/k6/stats/otlp"
+	"github.com/loadimpact/k6/ui"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// loginOTLPCommand represents the 'login otlp' command
+var loginOTLPCommand = &cobra.Command{
+	Use:   "otlp [endpoint]",
+	Short: "Authenticate with an OpenTelemetry collector",
+	Long: `Authenticate with an OpenTelemetry collector or a vendor's OTLP endpoint.
+
+This will set the default endpoint used when just "-o otlp" is passed, and a header with the
+credentials that it's sent with, e.g. "Authorization: Bearer <token>".`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fs := afero.NewOsFs()
+		config, cdir, err := readDiskConfig(fs)
+		if err != nil {
+			return err
+		}
+
+		conf := otlp.NewConfig().Apply(config.Collectors.OTLP)
+		if len(args) > 0 {
+			argConf, err := otlp.ParseArg(args[0])
+			if err != nil {
+				return err
+			}
+			conf = conf.Apply(argConf)
+		}
+
+		form := ui.Form{
+			Fields: []ui.Field{
+				ui.StringField{
+					Key:     "Endpoint",
+					Label:   "Endpoint",
+					Default: conf.Endpoint.String,
+				},
+				ui.StringField{
+					Key:     "Header",
+					Label:   "Header",
+					Default: "Authorization",
+				},
+				ui.PasswordField{StringField: ui.StringField{
+					Key:   "Value",
+					Label: "Header value",
+					Min:   1,
+				}},
+			},
+		}
+		vals, err := form.Run(os.Stdin, stdout)
+		if err != nil {
+			return err
+		}
+
+		endpoint, err := otlp.ParseArg(vals["Endpoint"].(string))
+		if err != nil {
+			return err
+		}
+		conf = conf.Apply(endpoint)
+		if conf.Headers == nil {
+			conf.Headers = make(map[string]string)
+		}
+		conf.Headers[vals["Header"].(string)] = vals["Value"].(string)
+
+		config.Collectors.OTLP = conf
+		return writeDiskConfig(fs, cdir, config)
+	},
+}
+
+func init() {
+	loginCmd.AddCommand(loginOTLPCommand)
+}
@@ -0,0 +1,233 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var (
+	newOutput = "script.js"
+	newForce  = false
+)
+
+// The scripts that `k6 new` generates, by template name.
+var scriptTemplates = map[string]string{
+	"api": `import http from "k6/http";
+import { check, sleep } from "k6";
+
+export let options = {
+    // Ramp up to 10 VUs, stay there, and ramp back down.
+    stages: [
+        { duration: "30s", target: 10 },
+        { duration: "1m", target: 10 },
+        { duration: "30s", target: 0 },
+    ],
+    // Fail the test if requests get slow or start failing.
+    thresholds: {
+        http_req_duration: ["p(95)<500"],
+        checks: ["rate>0.99"],
+    },
+};
+
+const BASE_URL = __ENV.BASE_URL || "https://test-api.k6.io";
+
+export default function() {
+    let res = http.get(` + "`${BASE_URL}/public/crocodiles/`" + `);
+    check(res, {
+        "status is 200": (r) => r.status === 200,
+        "has crocodiles": (r) => r.json().length > 0,
+    });
+    sleep(1);
+}
+`,
+
+	"browser": `import browser from "k6/browser";
+import http from "k6/http";
+import { check, sleep } from "k6";
+
+export let options = {
+    vus: 10,
+    duration: "1m",
+    // One VU in ten measures what real users see with a browser, the others load the backend.
+    scenarios: {
+        browser: { exec: "browserTest", weight: 1 },
+        api: { exec: "apiTest", weight: 9 },
+    },
+    thresholds: {
+        browser_lcp: ["p(95)<2500"],
+        http_req_duration: ["p(95)<500"],
+    },
+};
+
+const BASE_URL = __ENV.BASE_URL || "https://test.k6.io";
+
+export function browserTest() {
+    let b = browser.launch({ headless: true });
+    try {
+        let page = b.newPage();
+        let nav = page.goto(BASE_URL);
+        check(nav, { "page loaded": (n) => n.status === 200 });
+    } finally {
+        b.close();
+    }
+    sleep(1);
+}
+
+export function apiTest() {
+    let res = http.get(BASE_URL);
+    check(res, { "status is 200": (r) => r.status === 200 });
+    sleep(1);
+}
+
+export default function() {} // Not run, the scenarios say which functions the VUs run
+`,
+
+	"scenarios": `import http from "k6/http";
+import { check, group, sleep } from "k6";
+
+export let options = {
+    vus: 20,
+    duration: "5m",
+    // The VUs are shared between the scenarios in proportion to their weights.
+    scenarios: {
+        browse: { exec: "browse", weight: 6 },
+        search: { exec: "search", weight: 3, env: { QUERY: "crocodile" } },
+        login: { exec: "login", weight: 1, tags: { critical: "yes" } },
+    },
+    thresholds: {
+        "http_req_duration{scenario:browse}": ["p(95)<500"],
+        "http_req_duration{scenario:search}": ["p(95)<1000"],
+        "checks{critical:yes}": ["rate>0.99"],
+    },
+};
+
+const BASE_URL = __ENV.BASE_URL || "https://test.k6.io";
+
+export function browse() {
+    group("home page", () => {
+        let res = http.get(BASE_URL);
+        check(res, { "status is 200": (r) => r.status === 200 });
+    });
+    sleep(3);
+}
+
+export function search() {
+    let res = http.get(` + "`${BASE_URL}/?q=${__ENV.QUERY}`" + `);
+    check(res, { "status is 200": (r) => r.status === 200 });
+    sleep(2);
+}
+
+export function login() {
+    let res = http.get(` + "`${BASE_URL}/my_messages.php`" + `);
+    res = res.submitForm({ fields: { login: "admin", password: "123" } });
+    check(res, { "logged in": (r) => r.body.includes("Welcome, admin!") });
+    sleep(1);
+}
+
+export default function() {} // Not run, the scenarios say which functions the VUs run
+`,
+}
+
+func scriptTemplateNames() []string {
+	names := make([]string, 0, len(scriptTemplates))
+	for name := range scriptTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var newCmd = &cobra.Command{
+	Use:   "new [template]",
+	Short: "Create a new script from a template",
+	Long: `Create a new script from a template.
+
+The templates are:
+  api        requests to an HTTP API, with stages and thresholds (the default)
+  browser    a few VUs in a browser, while the others load the backend
+  scenarios  VUs that do different things, with thresholds per scenario
+
+The script is written to script.js, unless --output says otherwise. Existing files aren't
+overwritten, unless --force is given.`,
+	Example: `
+  # Create script.js for testing an API.
+  k6 new
+
+  # Create a browser test, and run it.
+  k6 new browser -O browser.js
+  k6 run browser.js`[1:],
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := "api"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if err := writeScriptTemplate(defaultFs, name, newOutput, newForce); err != nil {
+			return err
+		}
+		if newOutput != "-" {
+			fprintf(stdout, "New script created at %s from the %s template, run it with: k6 run %s\n", newOutput, name, newOutput)
+		}
+		return nil
+	},
+}
+
+// writeScriptTemplate writes the script of a template to a file, or to stdout if it's "-".
+func writeScriptTemplate(fs afero.Fs, name, filename string, force bool) error {
+	script, ok := scriptTemplates[name]
+	if !ok {
+		return errors.Errorf("unknown template '%s', it has to be one of: %s", name, strings.Join(scriptTemplateNames(), ", "))
+	}
+	if filename == "-" {
+		_, err := defaultWriter.Write([]byte(script))
+		return err
+	}
+
+	if !force {
+		if exists, err := afero.Exists(fs, filename); err != nil {
+			return err
+		} else if exists {
+			return errors.Errorf("%s already exists, use --force to overwrite it", filename)
+		}
+	}
+	f, err := fs.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(script); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func init() {
+	RootCmd.AddCommand(newCmd)
+	newCmd.Flags().SortFlags = false
+	newCmd.Flags().StringVarP(&newOutput, "output", "O", newOutput, "script filename, or - for stdout")
+	newCmd.Flags().BoolVarP(&newForce, "force", "f", newForce, "overwrite the script if it already exists")
+}
@@ -0,0 +1,76 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/lib"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteScriptTemplate(t *testing.T) {
+	for _, name := range scriptTemplateNames() {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			require.NoError(t, writeScriptTemplate(fs, name, "/script.js", false))
+
+			data, err := afero.ReadFile(fs, "/script.js")
+			require.NoError(t, err)
+			assert.Equal(t, scriptTemplates[name], string(data))
+
+			r, err := js.New(&lib.SourceData{Filename: "/script.js", Data: data}, fs, lib.RuntimeOptions{})
+			require.NoError(t, err)
+			assert.NotEmpty(t, r.GetOptions().Thresholds)
+		})
+	}
+
+	t.Run("Exists", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/script.js", []byte("mine"), 0644))
+		assert.EqualError(t, writeScriptTemplate(fs, "api", "/script.js", false),
+			"/script.js already exists, use --force to overwrite it")
+		data, err := afero.ReadFile(fs, "/script.js")
+		require.NoError(t, err)
+		assert.Equal(t, "mine", string(data))
+
+		require.NoError(t, writeScriptTemplate(fs, "api", "/script.js", true))
+		data, err = afero.ReadFile(fs, "/script.js")
+		require.NoError(t, err)
+		assert.Equal(t, scriptTemplates["api"], string(data))
+	})
+
+	t.Run("Stdout", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		defaultWriter = buf
+		require.NoError(t, writeScriptTemplate(afero.NewMemMapFs(), "browser", "-", false))
+		assert.Equal(t, scriptTemplates["browser"], buf.String())
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		assert.EqualError(t, writeScriptTemplate(afero.NewMemMapFs(), "grpc", "/script.js", false),
+			"unknown template 'grpc', it has to be one of: api, browser, scenarios")
+	})
+}
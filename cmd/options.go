@@ -47,25 +47,48 @@ func optionFlagSet() *pflag.FlagSet {
 	flags.SortFlags = false
 	flags.Int64P("vus", "u", 1, "number of virtual users")
 	flags.Int64P("max", "m", 0, "max available virtual users")
+	flags.Int64("pre-allocated-vus", 0, "initialize only this many of the max VUs before the test, the others when they're first needed")
 	flags.DurationP("duration", "d", 0, "test duration limit")
 	flags.Int64P("iterations", "i", 0, "script iteration limit")
-	flags.StringSliceP("stage", "s", nil, "add a `stage`, as `[duration]:[target]`")
+	flags.Int64("iterations-per-vu", 0, "run this many iterations in each VU, instead of sharing the iteration limit between them")
+	flags.StringSliceP("stage", "s", nil, "add a `stage`, as `[duration]:[target]` or `[duration]:[target]:[rate]`")
 	flags.BoolP("paused", "p", false, "start the test in a paused state")
+	flags.Bool("externally-controlled", false, "run until stopped, with the VUs only scaled with `k6 scale` or the REST API")
+	flags.Int64("seed", 0, "seed Math.random with this `number`, plus the ID of each VU, to make runs reproducible")
+	flags.Float64("guardrail-cpu", 0, "a guardrail for the `percent` of all CPUs that k6 itself uses, see --guardrail-action")
+	flags.Int64("guardrail-memory", 0, "a guardrail for the `MB` of memory that k6 itself uses")
+	flags.Float64("guardrail-file-descriptors", 0, "a guardrail for the `percent` of its file descriptor limit that k6 uses")
+	flags.String("guardrail-action", "warn", "what to do when a guardrail is exceeded: 'warn', 'cap' to stop adding VUs, or 'abort'")
+	flags.Duration("min-iteration-duration", 0, "pace iterations by sleeping after those that take less than this `duration`")
+	flags.Int64("vu-memory-limit", 0, "restart VUs that keep more than this many `MB` of JS values between iterations")
 	flags.Int64("max-redirects", 10, "follow at most n redirects")
+	flags.Duration("http-timeout", 60*time.Second, "default `timeout` for HTTP requests that don't have a timeout param")
 	flags.Int64("batch", 10, "max parallel batch reqs")
 	flags.Int64("batch-per-host", 0, "max parallel batch reqs per host")
 	flags.Int64("rps", 0, "limit requests per second")
 	flags.String("user-agent", fmt.Sprintf("k6/%s (https://k6.io/);", Version), "user agent for http requests")
-	flags.String("http-debug", "", "log all HTTP requests and responses. Excludes body by default. To include body use '---http-debug=full'")
+	flags.String("http-debug", "", "log all HTTP requests and responses. Excludes body by default. To include body use '---http-debug=full', or 'pretty' to also indent JSON bodies")
 	flags.Lookup("http-debug").NoOptDefVal = "headers"
+	flags.StringSlice("http-debug-urls", nil, "only log the HTTP requests whose URLs match these `patterns`, where * matches anything")
+	flags.Int64("http-debug-body-limit", 1024, "with --http-debug=full or pretty, log at most `n` bytes of each body, 0 for no limit")
 	flags.Bool("insecure-skip-tls-verify", false, "skip verification of TLS certificates")
 	flags.Bool("no-connection-reuse", false, "disable keep-alive connections")
 	flags.Bool("no-vu-connection-reuse", false, "don't reuse connections between iterations")
+	flags.Int64("max-idle-conns", 0, "keep at most `n` idle keep-alive connections in each VU, 0 for no limit")
+	flags.Int64("max-idle-conns-per-host", 2, "keep at most `n` idle keep-alive connections to each host in each VU")
+	flags.Int64("max-conns-per-host", 0, "use at most `n` connections to each host at once in each VU, 0 for no limit")
+	flags.String("tracing-propagator", "", "inject trace context headers into HTTP requests, as 'w3c' (traceparent) or 'b3'")
+	flags.Float64("tracing-sampling", 1, "the `fraction` of traced HTTP requests that are sampled")
+	flags.String("tracing-endpoint", "", "export the sampled HTTP requests as spans to an OTLP/HTTP traces `url`")
 	flags.BoolP("throw", "w", false, "throw warnings (like failed http requests) as errors")
 	flags.StringSlice("blacklist-ip", nil, "blacklist an `ip range` from being called")
+	flags.String("local-ips", "", "make outgoing connections from these local `ips`, in turns, as comma-separated IPs, CIDR ranges or network interfaces")
+	flags.String("proxy", "", "send requests through a `url` proxy (http, https or socks5), instead of HTTP(S)_PROXY")
+	flags.String("no-proxy", "", "comma-separated `hosts` that shouldn't be proxied, instead of NO_PROXY")
 	flags.StringSlice("summary-trend-stats", nil, "define `stats` for trend metrics (response times), one or more as 'avg,p(95),...'")
 	flags.String("summary-time-unit", "", "define the time unit used to display the trend stats. Possible units are: 's', 'ms' and 'us'")
-	flags.StringSlice("system-tags", lib.DefaultSystemTagList, "only include these system tags in metrics")
+	flags.StringSlice("summary-breakdown", nil, "also break the summary's metrics down by `tags`, 'scenario' and/or 'group'")
+	flags.StringSlice("system-tags", lib.DefaultSystemTagList, "only include these system tags in metrics, out of "+strings.Join(lib.SystemTagList, ", "))
 	flags.StringSlice("tag", nil, "add a `tag` to be applied to all samples, as `[name]=[value]`")
 	return flags
 }
@@ -74,10 +97,17 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 	opts := lib.Options{
 		VUs:                   getNullInt64(flags, "vus"),
 		VUsMax:                getNullInt64(flags, "max"),
+		PreAllocatedVUs:       getNullInt64(flags, "pre-allocated-vus"),
 		Duration:              getNullDuration(flags, "duration"),
 		Iterations:            getNullInt64(flags, "iterations"),
+		IterationsPerVU:       getNullInt64(flags, "iterations-per-vu"),
 		Paused:                getNullBool(flags, "paused"),
+		ExternallyControlled:  getNullBool(flags, "externally-controlled"),
+		Seed:                  getNullInt64(flags, "seed"),
+		MinIterationDuration:  getNullDuration(flags, "min-iteration-duration"),
+		VUMemoryLimit:         getNullInt64(flags, "vu-memory-limit"),
 		MaxRedirects:          getNullInt64(flags, "max-redirects"),
+		HTTPTimeout:           getNullDuration(flags, "http-timeout"),
 		Batch:                 getNullInt64(flags, "batch"),
 		RPS:                   getNullInt64(flags, "rps"),
 		UserAgent:             getNullString(flags, "user-agent"),
@@ -85,7 +115,21 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		InsecureSkipTLSVerify: getNullBool(flags, "insecure-skip-tls-verify"),
 		NoConnectionReuse:     getNullBool(flags, "no-connection-reuse"),
 		NoVUConnectionReuse:   getNullBool(flags, "no-vu-connection-reuse"),
+		MaxIdleConns:          getNullInt64(flags, "max-idle-conns"),
+		MaxIdleConnsPerHost:   getNullInt64(flags, "max-idle-conns-per-host"),
+		MaxConnsPerHost:       getNullInt64(flags, "max-conns-per-host"),
+		TracingPropagator:     getNullString(flags, "tracing-propagator"),
+		TracingSampling:       getNullFloat64(flags, "tracing-sampling"),
+		TracingEndpoint:       getNullString(flags, "tracing-endpoint"),
 		Throw:                 getNullBool(flags, "throw"),
+		Proxy:                 getNullString(flags, "proxy"),
+		NoProxy:               getNullString(flags, "no-proxy"),
+
+		GuardrailCPU:             getNullFloat64(flags, "guardrail-cpu"),
+		GuardrailMemory:          getNullInt64(flags, "guardrail-memory"),
+		GuardrailFileDescriptors: getNullFloat64(flags, "guardrail-file-descriptors"),
+		GuardrailAction:          getNullString(flags, "guardrail-action"),
+		HttpDebugBodyLimit:       getNullInt64(flags, "http-debug-body-limit"),
 
 		// Default values for options without CLI flags:
 		// TODO: find a saner and more dev-friendly and error-proof way to handle options
@@ -122,6 +166,22 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		opts.BlacklistIPs = append(opts.BlacklistIPs, net)
 	}
 
+	if flags.Changed("http-debug-urls") {
+		if opts.HttpDebugURLs, err = flags.GetStringSlice("http-debug-urls"); err != nil {
+			return opts, err
+		}
+	}
+
+	if flags.Changed("local-ips") {
+		localIPs, err := flags.GetString("local-ips")
+		if err != nil {
+			return opts, err
+		}
+		if opts.LocalIPs, err = lib.ParseLocalIPs(localIPs); err != nil {
+			return opts, errors.Wrap(err, "local-ips")
+		}
+	}
+
 	trendStatStrings, err := flags.GetStringSlice("summary-trend-stats")
 	if err != nil {
 		return opts, err
@@ -138,17 +198,31 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 	if err != nil {
 		return opts, err
 	}
-	if summaryTimeUnit != "" && summaryTimeUnit != "s" && summaryTimeUnit != "ms" && summaryTimeUnit != "us" {
-		return opts, errors.New("invalid summary time unit. Use: 's', 'ms' or 'us'")
+	if err := validateSummaryTimeUnit(summaryTimeUnit); err != nil {
+		return opts, err
 	}
+	opts.SummaryTimeUnit = null.NewString(summaryTimeUnit, flags.Changed("summary-time-unit"))
 
-	opts.SummaryTimeUnit = null.StringFrom(summaryTimeUnit)
+	if flags.Changed("summary-breakdown") {
+		if opts.SummaryBreakdown, err = flags.GetStringSlice("summary-breakdown"); err != nil {
+			return opts, err
+		}
+		if err := validateSummaryBreakdown(opts.SummaryBreakdown); err != nil {
+			return opts, err
+		}
+	}
 
-	systemTagList, err := flags.GetStringSlice("system-tags")
-	if err != nil {
-		return opts, err
+	// The default system tags are only set in the end, so that they don't override the ones in
+	// the script options or the config file.
+	if flags.Changed("system-tags") {
+		systemTagList, err := flags.GetStringSlice("system-tags")
+		if err != nil {
+			return opts, err
+		}
+		if opts.SystemTags, err = lib.ParseSystemTags(systemTagList...); err != nil {
+			return opts, errors.Wrap(err, "system-tags")
+		}
 	}
-	opts.SystemTags = lib.GetTagSet(systemTagList...)
 
 	runTags, err := flags.GetStringSlice("tag")
 	if err != nil {
@@ -186,3 +260,100 @@ func parseTagNameValue(nv string) (string, string, error) {
 		return nv[:idx], nv[idx+1:], nil
 	}
 }
+
+// validateExternallyControlled checks that an externally controlled test doesn't also have a
+// script for its VUs, since the two would fight over them.
+func validateExternallyControlled(opts lib.Options) error {
+	if !opts.ExternallyControlled.Bool {
+		return nil
+	}
+	if len(opts.Stages) > 0 {
+		return errors.New("externally controlled tests can't have stages, their VUs are only scaled with `k6 scale` or the REST API")
+	}
+	if opts.Iterations.Valid {
+		return errors.New("externally controlled tests can't have an iteration limit, they run until stopped or for their duration")
+	}
+	return nil
+}
+
+// validateIterationsPerVU checks that a test with an iteration count per VU doesn't also have
+// an iteration limit, or VUs that change during the test, which would make the count ambiguous.
+func validateIterationsPerVU(opts lib.Options) error {
+	if !opts.IterationsPerVU.Valid {
+		return nil
+	}
+	if opts.IterationsPerVU.Int64 < 1 {
+		return errors.Errorf("invalid iterations per VU %d, it has to be at least 1", opts.IterationsPerVU.Int64)
+	}
+	if opts.Iterations.Valid {
+		return errors.New("iterations and iterations per VU can't be used together, use only one of them")
+	}
+	if len(opts.Stages) > 0 || opts.ExternallyControlled.Bool {
+		return errors.New("tests with iterations per VU can't have stages or be externally controlled, their VUs have to stay the same")
+	}
+	return nil
+}
+
+// validatePreAllocatedVUs checks the number of VUs to initialize before the test starts.
+func validatePreAllocatedVUs(opts lib.Options) error {
+	if pre := opts.PreAllocatedVUs; pre.Valid && pre.Int64 < 0 {
+		return errors.Errorf("invalid pre-allocated VUs %d, it can't be negative", pre.Int64)
+	}
+	return nil
+}
+
+// validateVUMemoryLimit checks the limit on the memory of each VU's JS values.
+func validateVUMemoryLimit(opts lib.Options) error {
+	if limit := opts.VUMemoryLimit; limit.Valid && limit.Int64 < 1 {
+		return errors.Errorf("invalid VU memory limit %dMB, it has to be at least 1", limit.Int64)
+	}
+	return nil
+}
+
+// validateGuardrails checks the limits on the load generator's own resource usage.
+func validateGuardrails(opts lib.Options) error {
+	if opts.GuardrailCPU.Valid && (opts.GuardrailCPU.Float64 <= 0 || opts.GuardrailCPU.Float64 > 100) {
+		return errors.Errorf("invalid CPU guardrail %g%%, it has to be above 0 and at most 100", opts.GuardrailCPU.Float64)
+	}
+	if opts.GuardrailMemory.Valid && opts.GuardrailMemory.Int64 < 1 {
+		return errors.Errorf("invalid memory guardrail %dMB, it has to be at least 1", opts.GuardrailMemory.Int64)
+	}
+	if fds := opts.GuardrailFileDescriptors; fds.Valid && (fds.Float64 <= 0 || fds.Float64 > 100) {
+		return errors.Errorf("invalid file descriptor guardrail %g%%, it has to be above 0 and at most 100", fds.Float64)
+	}
+	switch action := opts.GuardrailAction.String; action {
+	case "", "warn", "cap", "abort":
+		return nil
+	default:
+		return errors.Errorf("invalid guardrail action '%s', it has to be 'warn', 'cap' or 'abort'", action)
+	}
+}
+
+// validateSummaryOptions checks the summary options, wherever they were set.
+func validateSummaryOptions(opts lib.Options) error {
+	for _, s := range opts.SummaryTrendStats {
+		if err := ui.VerifyTrendColumnStat(s); err != nil {
+			return errors.Wrapf(err, "stat '%s'", s)
+		}
+	}
+	if err := validateSummaryBreakdown(opts.SummaryBreakdown); err != nil {
+		return err
+	}
+	return validateSummaryTimeUnit(opts.SummaryTimeUnit.String)
+}
+
+func validateSummaryBreakdown(tags []string) error {
+	for _, tag := range tags {
+		if tag != "scenario" && tag != "group" {
+			return errors.Errorf("invalid summary breakdown '%s', it has to be 'scenario' or 'group'", tag)
+		}
+	}
+	return nil
+}
+
+func validateSummaryTimeUnit(unit string) error {
+	if unit != "" && unit != "s" && unit != "ms" && unit != "us" {
+		return errors.New("invalid summary time unit. Use: 's', 'ms' or 'us'")
+	}
+	return nil
+}
@@ -21,9 +21,14 @@
 package cmd
 
 import (
+	"net"
 	"testing"
+	"time"
 
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
 )
 
 func TestParseTagKeyValue(t *testing.T) {
@@ -82,3 +87,142 @@ func TestParseTagKeyValue(t *testing.T) {
 	}
 
 }
+
+func TestValidateSummaryOptions(t *testing.T) {
+	assert.NoError(t, validateSummaryOptions(lib.Options{
+		SummaryTrendStats: []string{"min", "med", "p(99.9)", "count"},
+		SummaryTimeUnit:   null.StringFrom("s"),
+	}))
+	assert.NoError(t, validateSummaryOptions(lib.Options{}))
+	assert.EqualError(t, validateSummaryOptions(lib.Options{SummaryTrendStats: []string{"avg", "p99"}}),
+		"stat 'p99': Invalid stat, unknown format")
+	assert.EqualError(t, validateSummaryOptions(lib.Options{SummaryTimeUnit: null.StringFrom("m")}),
+		"invalid summary time unit. Use: 's', 'ms' or 'us'")
+}
+
+func TestValidateExternallyControlled(t *testing.T) {
+	assert.NoError(t, validateExternallyControlled(lib.Options{Iterations: null.IntFrom(10)}))
+	assert.NoError(t, validateExternallyControlled(lib.Options{
+		ExternallyControlled: null.BoolFrom(true),
+		Duration:             types.NullDurationFrom(10 * time.Minute),
+	}))
+	assert.EqualError(t, validateExternallyControlled(lib.Options{
+		ExternallyControlled: null.BoolFrom(true),
+		Stages:               []lib.Stage{{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(10)}},
+	}), "externally controlled tests can't have stages, their VUs are only scaled with `k6 scale` or the REST API")
+	assert.EqualError(t, validateExternallyControlled(lib.Options{
+		ExternallyControlled: null.BoolFrom(true),
+		Iterations:           null.IntFrom(10),
+	}), "externally controlled tests can't have an iteration limit, they run until stopped or for their duration")
+}
+
+func TestSummaryTimeUnitFlag(t *testing.T) {
+	flags := optionFlagSet()
+	opts, err := getOptions(flags)
+	assert.NoError(t, err)
+	assert.False(t, opts.SummaryTimeUnit.Valid, "an unset flag overrides the config file")
+
+	assert.NoError(t, flags.Set("summary-time-unit", "ms"))
+	opts, err = getOptions(flags)
+	assert.NoError(t, err)
+	assert.Equal(t, null.StringFrom("ms"), opts.SummaryTimeUnit)
+}
+
+func TestLocalIPsFlag(t *testing.T) {
+	flags := optionFlagSet()
+	opts, err := getOptions(flags)
+	assert.NoError(t, err)
+	assert.Nil(t, opts.LocalIPs, "an unset flag overrides the script options")
+
+	assert.NoError(t, flags.Set("local-ips", "192.0.2.1,192.0.2.4/30"))
+	opts, err = getOptions(flags)
+	assert.NoError(t, err)
+	assert.Equal(t, lib.LocalIPs{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.5"), net.ParseIP("192.0.2.6")}, opts.LocalIPs)
+
+	assert.NoError(t, flags.Set("local-ips", "192.0.2.300"))
+	_, err = getOptions(flags)
+	assert.EqualError(t, err, "local-ips: invalid local IP '192.0.2.300', it's not an IP, a CIDR range or a network interface")
+}
+
+func TestHTTPDebugURLsFlag(t *testing.T) {
+	flags := optionFlagSet()
+	opts, err := getOptions(flags)
+	assert.NoError(t, err)
+	assert.Nil(t, opts.HttpDebugURLs, "an unset flag overrides the script options")
+	assert.False(t, opts.HttpDebugBodyLimit.Valid)
+
+	assert.NoError(t, flags.Set("http-debug-urls", "*/api/*,https://auth.example.com/*"))
+	assert.NoError(t, flags.Set("http-debug-body-limit", "0"))
+	opts, err = getOptions(flags)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*/api/*", "https://auth.example.com/*"}, opts.HttpDebugURLs)
+	assert.Equal(t, null.IntFrom(0), opts.HttpDebugBodyLimit)
+}
+
+func TestSystemTagsFlag(t *testing.T) {
+	flags := optionFlagSet()
+	opts, err := getOptions(flags)
+	assert.NoError(t, err)
+	assert.Nil(t, opts.SystemTags, "an unset flag overrides the script options")
+
+	assert.NoError(t, flags.Set("system-tags", "status,method,name"))
+	opts, err = getOptions(flags)
+	assert.NoError(t, err)
+	assert.Equal(t, lib.GetTagSet("status", "method", "name"), opts.SystemTags)
+
+	assert.NoError(t, flags.Set("system-tags", "stauts"))
+	_, err = getOptions(flags)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown system tag 'stauts'")
+	}
+}
+
+func TestValidatePreAllocatedVUs(t *testing.T) {
+	assert.NoError(t, validatePreAllocatedVUs(lib.Options{}))
+	assert.NoError(t, validatePreAllocatedVUs(lib.Options{PreAllocatedVUs: null.IntFrom(0)}))
+	assert.EqualError(t, validatePreAllocatedVUs(lib.Options{PreAllocatedVUs: null.IntFrom(-1)}),
+		"invalid pre-allocated VUs -1, it can't be negative")
+}
+
+func TestValidateVUMemoryLimit(t *testing.T) {
+	assert.NoError(t, validateVUMemoryLimit(lib.Options{}))
+	assert.NoError(t, validateVUMemoryLimit(lib.Options{VUMemoryLimit: null.IntFrom(100)}))
+	assert.EqualError(t, validateVUMemoryLimit(lib.Options{VUMemoryLimit: null.IntFrom(0)}),
+		"invalid VU memory limit 0MB, it has to be at least 1")
+}
+
+func TestValidateIterationsPerVU(t *testing.T) {
+	assert.NoError(t, validateIterationsPerVU(lib.Options{Iterations: null.IntFrom(10)}))
+	assert.NoError(t, validateIterationsPerVU(lib.Options{
+		IterationsPerVU: null.IntFrom(10),
+		Duration:        types.NullDurationFrom(1 * time.Minute),
+	}))
+	assert.EqualError(t, validateIterationsPerVU(lib.Options{IterationsPerVU: null.IntFrom(0)}),
+		"invalid iterations per VU 0, it has to be at least 1")
+	assert.EqualError(t, validateIterationsPerVU(lib.Options{
+		IterationsPerVU: null.IntFrom(10),
+		Iterations:      null.IntFrom(10),
+	}), "iterations and iterations per VU can't be used together, use only one of them")
+	assert.EqualError(t, validateIterationsPerVU(lib.Options{
+		IterationsPerVU: null.IntFrom(10),
+		Stages:          []lib.Stage{{Duration: types.NullDurationFrom(1 * time.Minute)}},
+	}), "tests with iterations per VU can't have stages or be externally controlled, their VUs have to stay the same")
+}
+
+func TestValidateGuardrails(t *testing.T) {
+	assert.NoError(t, validateGuardrails(lib.Options{}))
+	assert.NoError(t, validateGuardrails(lib.Options{
+		GuardrailCPU:             null.FloatFrom(90),
+		GuardrailMemory:          null.IntFrom(1024),
+		GuardrailFileDescriptors: null.FloatFrom(80),
+		GuardrailAction:          null.StringFrom("abort"),
+	}))
+	assert.EqualError(t, validateGuardrails(lib.Options{GuardrailCPU: null.FloatFrom(150)}),
+		"invalid CPU guardrail 150%, it has to be above 0 and at most 100")
+	assert.EqualError(t, validateGuardrails(lib.Options{GuardrailMemory: null.IntFrom(0)}),
+		"invalid memory guardrail 0MB, it has to be at least 1")
+	assert.EqualError(t, validateGuardrails(lib.Options{GuardrailFileDescriptors: null.FloatFrom(-1)}),
+		"invalid file descriptor guardrail -1%, it has to be above 0 and at most 100")
+	assert.EqualError(t, validateGuardrails(lib.Options{GuardrailAction: null.StringFrom("panic")}),
+		"invalid guardrail action 'panic', it has to be 'warn', 'cap' or 'abort'")
+}
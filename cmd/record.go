@@ -0,0 +1,190 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/loadimpact/k6/converter/har"
+	"github.com/loadimpact/k6/converter/recorder"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	"github.com/shibukawa/configdir"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recordAddress string
+	recordHAR     string
+	recordCACert  string
+	recordCAKey   string
+	recordTunnel  bool
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Record browser traffic as a k6 script",
+	Long: `Record browser traffic as a k6 script.
+
+This starts an HTTP proxy that records the requests of a browser that uses it. When you stop it
+with Ctrl+C, the recording is converted to a script, like "k6 convert" does with a HAR file.
+
+To record HTTPS traffic, the proxy presents certificates signed by its own CA to the browser,
+so the browser has to trust the CA certificate. It's generated on the first run, and saved in
+the k6 config directory unless --ca-cert and --ca-key say otherwise.
+
+The top of the script has correlation hints: CSRF tokens and session IDs that the server gave
+to the browser and that the browser sent back, which the script has to extract when it runs.
+With --correlate --no-batch, the script extracts them by itself.`,
+	Example: `
+  # Record to a script, with the browser configured to use localhost:8080 as its proxy.
+  k6 record -O session.js
+
+  # Record only requests to a domain, and also keep the recording as a HAR file.
+  k6 record -O session.js --only yourdomain.com --har session.har`[1:],
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ca *recorder.CA
+		if !recordTunnel {
+			var err error
+			if ca, err = loadRecorderCA(defaultFs); err != nil {
+				return err
+			}
+		}
+		rec := recorder.New(ca)
+
+		listener, err := net.Listen("tcp", recordAddress)
+		if err != nil {
+			return err
+		}
+		server := &http.Server{Handler: rec}
+		go func() { _ = server.Serve(listener) }()
+
+		fprintf(stderr, "Recording with the proxy at %s, configure it as the HTTP and HTTPS proxy of your browser.\n",
+			ui.ValueColor.Sprint(listener.Addr().String()))
+		if ca != nil {
+			fprintf(stderr, "For HTTPS sites, the browser has to trust the CA certificate at %s\n",
+				ui.ValueColor.Sprint(recordCACert))
+		}
+		fprintf(stderr, "Press Ctrl+C to stop recording and generate the script.\n")
+
+		sigC := make(chan os.Signal, 1)
+		signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+		<-sigC
+		signal.Stop(sigC)
+		_ = server.Close()
+
+		h := rec.HAR()
+		h.Log.Creator.Version = Version
+		if len(h.Log.Entries) == 0 {
+			return errors.New("nothing was recorded")
+		}
+		if recordHAR != "" {
+			data, err := json.MarshalIndent(h, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := afero.WriteFile(defaultFs, recordHAR, data, 0644); err != nil {
+				return err
+			}
+		}
+
+		script, err := convertHAR(h)
+		if err != nil {
+			return err
+		}
+		if correlate {
+			return writeScript(script)
+		}
+		hints := har.FindCorrelationHints(h.Log.Entries)
+		return writeScript(har.FormatCorrelationHints(h.Log.Entries, hints) + script)
+	},
+}
+
+// loadRecorderCA loads the CA of the recorder, generating it if it doesn't exist yet.
+func loadRecorderCA(fs afero.Fs) (*recorder.CA, error) {
+	if recordCACert == "" || recordCAKey == "" {
+		dir := configDirs.QueryFolders(configdir.Global)[0].Path
+		if recordCACert == "" {
+			recordCACert = filepath.Join(dir, "recorder-ca.crt")
+		}
+		if recordCAKey == "" {
+			recordCAKey = filepath.Join(dir, "recorder-ca.key")
+		}
+	}
+
+	certPEM, err := afero.ReadFile(fs, recordCACert)
+	if os.IsNotExist(err) {
+		certPEM, keyPEM, err := recorder.GenerateCA()
+		if err != nil {
+			return nil, err
+		}
+		if err := fs.MkdirAll(filepath.Dir(recordCACert), 0755); err != nil {
+			return nil, err
+		}
+		if err := fs.MkdirAll(filepath.Dir(recordCAKey), 0755); err != nil {
+			return nil, err
+		}
+		if err := afero.WriteFile(fs, recordCAKey, keyPEM, 0600); err != nil {
+			return nil, err
+		}
+		if err := afero.WriteFile(fs, recordCACert, certPEM, 0644); err != nil {
+			return nil, err
+		}
+		return recorder.LoadCA(certPEM, keyPEM)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := afero.ReadFile(fs, recordCAKey)
+	if err != nil {
+		return nil, err
+	}
+	return recorder.LoadCA(certPEM, keyPEM)
+}
+
+func init() {
+	RootCmd.AddCommand(recordCmd)
+	recordCmd.Flags().SortFlags = false
+	recordCmd.Flags().StringVarP(&recordAddress, "address", "l", "localhost:8080", "address for the recording proxy to listen on")
+	recordCmd.Flags().StringVarP(&output, "output", "O", output, "k6 script output filename (stdout by default)")
+	recordCmd.Flags().StringVar(&recordHAR, "har", "", "also save the recording as a HAR file")
+	recordCmd.Flags().StringVar(&recordCACert, "ca-cert", "", "CA certificate file for HTTPS recording, generated if it doesn't exist (in the k6 config directory by default)")
+	recordCmd.Flags().StringVar(&recordCAKey, "ca-key", "", "CA private key file for HTTPS recording (in the k6 config directory by default)")
+	recordCmd.Flags().BoolVar(&recordTunnel, "no-https-recording", false, "pass HTTPS traffic through without recording it, so that no CA is needed")
+	recordCmd.Flags().StringVarP(&optionsFilePath, "options", "", optionsFilePath, "path to a JSON file with options that would be injected in the output script")
+	recordCmd.Flags().StringSliceVarP(&only, "only", "", []string{}, "include only requests from the given domains")
+	recordCmd.Flags().StringSliceVarP(&skip, "skip", "", []string{}, "skip requests from the given domains")
+	recordCmd.Flags().UintVarP(&threshold, "batch-threshold", "", 500, "batch request idle time threshold")
+	recordCmd.Flags().BoolVarP(&nobatch, "no-batch", "", false, "don't generate batch calls")
+	recordCmd.Flags().BoolVarP(&enableChecks, "enable-status-code-checks", "", false, "add a status code check for each HTTP response")
+	recordCmd.Flags().BoolVarP(&returnOnFailedCheck, "return-on-failed-check", "", false, "return from iteration if we get an unexpected response status code")
+	recordCmd.Flags().BoolVarP(&correlate, "correlate", "", false, "detect values in responses being used in subsequent requests (redirects, IDs in JSON, CSRF tokens and session cookies) and extract them in the script; requires --no-batch")
+	recordCmd.Flags().UintVarP(&minSleep, "min-sleep", "", 20, "the minimum amount of seconds to sleep after each iteration")
+	recordCmd.Flags().UintVarP(&maxSleep, "max-sleep", "", 40, "the maximum amount of seconds to sleep after each iteration")
+}
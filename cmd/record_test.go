@@ -0,0 +1,53 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRecorderCA(t *testing.T) {
+	defer func() { recordCACert, recordCAKey = "", "" }()
+	recordCACert, recordCAKey = "/k6/ca.crt", "/k6/ca.key"
+	fs := afero.NewMemMapFs()
+
+	ca, err := loadRecorderCA(fs)
+	require.NoError(t, err)
+	assert.NotNil(t, ca)
+	certPEM, err := afero.ReadFile(fs, "/k6/ca.crt")
+	require.NoError(t, err)
+	assert.Contains(t, string(certPEM), "BEGIN CERTIFICATE")
+
+	// The second time, the same CA is loaded
+	_, err = loadRecorderCA(fs)
+	require.NoError(t, err)
+	again, err := afero.ReadFile(fs, "/k6/ca.crt")
+	require.NoError(t, err)
+	assert.Equal(t, certPEM, again)
+
+	require.NoError(t, afero.WriteFile(fs, "/k6/ca.key", []byte("garbage"), 0600))
+	_, err = loadRecorderCA(fs)
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/loadimpact/k6/report"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportOutput string
+	reportFormat string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render the results of a test as a report",
+	Long: `Render the results of a test as an HTML or Markdown report.
+
+The results are what the JSON output (--out json=results.json) wrote, or a CSV file with the
+metric_name, timestamp and metric_value columns and a column for each tag; either can be gzipped.
+The report has the outcomes of the thresholds and the checks, the values of all the metrics and
+charts of them over time, and it doesn't need anything else to be viewed.`,
+	Example: `
+  # Run a test, keeping its results.
+  k6 run --out json=results.json script.js
+
+  # Render them as an HTML report.
+  k6 report -O report.html results.json
+
+  # Render them as Markdown, e.g. for a pull request comment.
+  k6 report --format markdown results.json.gz`[1:],
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := reportFormat
+		if format == "" {
+			switch strings.ToLower(filepath.Ext(reportOutput)) {
+			case ".md", ".markdown":
+				format = "markdown"
+			default:
+				format = "html"
+			}
+		}
+		if format != "html" && format != "markdown" {
+			return errors.Errorf("invalid report format '%s', it has to be 'html' or 'markdown'", format)
+		}
+
+		results, err := report.ReadFile(defaultFs, args[0])
+		if err != nil {
+			return err
+		}
+		r := report.New(results)
+
+		w := defaultWriter
+		if reportOutput != "" && reportOutput != "-" {
+			f, err := defaultFs.Create(reportOutput)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			w = f
+		}
+		if format == "markdown" {
+			return r.WriteMarkdown(w)
+		}
+		return r.WriteHTML(w)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().SortFlags = false
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "O", reportOutput, "report output `filename` (stdout by default)")
+	reportCmd.Flags().StringVar(&reportFormat, "format", reportFormat, "report `format`, 'html' or 'markdown' (by the output's extension by default, or html)")
+}
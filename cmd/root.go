@@ -23,13 +23,17 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/fatih/color"
+	"github.com/loadimpact/k6/lib/loki"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
 	"github.com/shibukawa/configdir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -54,13 +58,15 @@ var (
 )
 
 var (
-	cfgFile string
-
-	verbose bool
-	quiet   bool
-	noColor bool
-	logFmt  string
-	address string
+	verbose   bool
+	quiet     bool
+	noColor   bool
+	logFmt    string
+	logOutput string
+	address   string
+
+	// Pushes the logs that are still buffered, for log outputs that buffer them.
+	flushLogs func()
 )
 
 // RootCmd represents the base command when called without any subcommands.
@@ -70,20 +76,29 @@ var RootCmd = &cobra.Command{
 	Long:          BannerColor.Sprint(Banner),
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		setupLoggers(logFmt)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupLoggers(logFmt, logOutput); err != nil {
+			return err
+		}
 		if noColor {
 			stdout.Writer = colorable.NewNonColorable(os.Stdout)
 			stdout.Writer = colorable.NewNonColorable(os.Stderr)
 		}
+		return nil
 	},
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	err := RootCmd.Execute()
+	if err != nil {
 		log.Error(err.Error())
+	}
+	if flushLogs != nil {
+		flushLogs()
+	}
+	if err != nil {
 		if e, ok := err.(ExitCode); ok {
 			os.Exit(e.Code)
 		}
@@ -99,11 +114,12 @@ func init() {
 	}
 
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable debug logging")
-	RootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "disable progress updates")
+	RootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "disable the banner, progress updates and the summary")
 	RootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colored output")
 	RootCmd.PersistentFlags().StringVar(&logFmt, "logformat", "", "log output format")
+	RootCmd.PersistentFlags().StringVar(&logOutput, "log-output", "stderr", "where logs go: `stderr`, stdout, none, file=<path> or loki=<push url>[,label.<name>=<value>...]")
 	RootCmd.PersistentFlags().StringVarP(&address, "address", "a", "localhost:6565", "address for the api server")
-	RootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file"+defaultConfigPathMsg)
+	RootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", configFile, "config file, instead of the global one"+defaultConfigPathMsg+" and the project's k6.config.json or k6.config.js")
 	must(cobra.MarkFlagFilename(RootCmd.PersistentFlags(), "config"))
 }
 
@@ -124,11 +140,38 @@ func (f RawFormater) Format(entry *log.Entry) ([]byte, error) {
 	return append([]byte(entry.Message), '\n'), nil
 }
 
-func setupLoggers(logFmt string) {
+func setupLoggers(logFmt, logOutput string) error {
 	if verbose {
 		log.SetLevel(log.DebugLevel)
 	}
-	log.SetOutput(stderr)
+
+	tty := false
+	switch {
+	case logOutput == "" || logOutput == "stderr":
+		log.SetOutput(stderr)
+		tty = stderrTTY
+	case logOutput == "stdout":
+		log.SetOutput(stdout)
+		tty = stdoutTTY
+	case logOutput == "none":
+		log.SetOutput(ioutil.Discard)
+	case strings.HasPrefix(logOutput, "file="):
+		f, err := os.OpenFile(strings.TrimPrefix(logOutput, "file="), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return errors.Wrap(err, "couldn't open the log file")
+		}
+		log.SetOutput(f)
+	case strings.HasPrefix(logOutput, "loki"):
+		hook, err := loki.New(strings.TrimPrefix(strings.TrimPrefix(logOutput, "loki"), "="))
+		if err != nil {
+			return err
+		}
+		log.AddHook(hook)
+		log.SetOutput(ioutil.Discard)
+		flushLogs = hook.Flush
+	default:
+		return errors.Errorf("invalid log output '%s', it has to be stderr, stdout, none, file=<path> or loki=<push url>", logOutput)
+	}
 
 	switch logFmt {
 	case "raw":
@@ -138,8 +181,8 @@ func setupLoggers(logFmt string) {
 		log.SetFormatter(&log.JSONFormatter{})
 		log.Debug("Logger format: JSON")
 	default:
-		log.SetFormatter(&log.TextFormatter{ForceColors: stderrTTY, DisableColors: noColor})
+		log.SetFormatter(&log.TextFormatter{ForceColors: tty, DisableColors: noColor})
 		log.Debug("Logger format: TEXT")
 	}
-
+	return nil
 }
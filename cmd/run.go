@@ -42,8 +42,10 @@ import (
 	"github.com/loadimpact/k6/core/local"
 	"github.com/loadimpact/k6/js"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/profile"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats/webhook"
 	"github.com/loadimpact/k6/ui"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -61,6 +63,8 @@ var (
 	runType       = os.Getenv("K6_TYPE")
 	runNoSetup    = os.Getenv("K6_NO_SETUP") != ""
 	runNoTeardown = os.Getenv("K6_NO_TEARDOWN") != ""
+	runProfile    = os.Getenv("K6_PROFILE") != ""
+	runWatch      = false
 )
 
 // runCmd represents the run command.
@@ -87,11 +91,23 @@ a commandline interface for interacting with it.`,
   # Ramp VUs from 0 to 100 over 10s, stay there for 60s, then 10s down to 0.
   k6 run -u 0 -s 10s:100 -s 60s -s 10s:0
 
+  # Run one iteration whenever the script changes, while writing it.
+  k6 run --watch script.js
+
   # Send metrics to an influxdb server
   k6 run -o influxdb=http://1.2.3.4:8086/k6`[1:],
 	Args: exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, _ = BannerColor.Fprint(stdout, Banner+"\n\n")
+		if runWatch {
+			return watchScript(cmd.Flags(), args[0])
+		}
+
+		// With --quiet, only logs and errors are shown.
+		headerOut := io.Writer(stdout)
+		if quiet {
+			headerOut = ioutil.Discard
+		}
+		_, _ = BannerColor.Fprint(headerOut, Banner+"\n\n")
 
 		initBar := ui.ProgressBar{
 			Width: 60,
@@ -99,7 +115,7 @@ a commandline interface for interacting with it.`,
 		}
 
 		// Create the Runner.
-		fprintf(stdout, "%s runner\r", initBar.String())
+		fprintf(headerOut, "%s runner\r", initBar.String())
 		pwd, err := os.Getwd()
 		if err != nil {
 			return err
@@ -120,16 +136,29 @@ a commandline interface for interacting with it.`,
 		if err != nil {
 			return err
 		}
+		if jsRunner, ok := r.(*js.Runner); ok {
+			// Keep the secrets that the script gets out of the logs, and out of the errors
+			log.AddHook(jsRunner.Bundle.Secrets)
+		}
+		var prof *profile.Profile
+		if runProfile {
+			jsRunner, ok := r.(*js.Runner)
+			if !ok {
+				return errors.New("only JS tests can be profiled")
+			}
+			prof = profile.New()
+			jsRunner.Profile = prof
+		}
 
 		// Assemble options; start with the CLI-provided options to get shadowed (non-Valid)
 		// defaults in there, override with Runner-provided ones, then merge the CLI opts in
 		// on top to give them priority.
-		fprintf(stdout, "%s options\r", initBar.String())
+		fprintf(headerOut, "%s options\r", initBar.String())
 		cliConf, err := getConfig(cmd.Flags())
 		if err != nil {
 			return err
 		}
-		fileConf, _, err := readDiskConfig(fs)
+		fileConf, err := readConfigFiles(fs, pwd)
 		if err != nil {
 			return err
 		}
@@ -148,15 +177,43 @@ a commandline interface for interacting with it.`,
 				}
 			}
 		}
-		// If -d/--duration, -i/--iterations and -s/--stage are all unset, run to one iteration.
-		if !conf.Duration.Valid && !conf.Iterations.Valid && conf.Stages == nil {
+		if err := validateExternallyControlled(conf.Options); err != nil {
+			return err
+		}
+		if err := validateIterationsPerVU(conf.Options); err != nil {
+			return err
+		}
+		if err := validatePreAllocatedVUs(conf.Options); err != nil {
+			return err
+		}
+		if err := validateVUMemoryLimit(conf.Options); err != nil {
+			return err
+		}
+		if err := validateGuardrails(conf.Options); err != nil {
+			return err
+		}
+		if err := lib.ValidateScenarios(conf.Scenarios); err != nil {
+			return err
+		}
+		// If -d/--duration, -i/--iterations, --iterations-per-vu and -s/--stage are all unset, run
+		// to one iteration, unless the test is externally controlled, in which case it runs until
+		// it's stopped.
+		if !conf.Duration.Valid && !conf.Iterations.Valid && !conf.IterationsPerVU.Valid && conf.Stages == nil &&
+			!conf.ExternallyControlled.Bool {
 			conf.Iterations = null.IntFrom(1)
 		}
 		// If duration is explicitly set to 0, it means run forever.
 		if conf.Duration.Valid && conf.Duration.Duration == 0 {
 			conf.Duration = types.NullDuration{}
 		}
+		// If no system tags are chosen anywhere, use the default ones.
+		if conf.SystemTags == nil {
+			conf.SystemTags = lib.GetTagSet(lib.DefaultSystemTagList...)
+		}
 		// If summary trend stats are defined, update the UI to reflect them
+		if err := validateSummaryOptions(conf.Options); err != nil {
+			return err
+		}
 		if len(conf.SummaryTrendStats) > 0 {
 			ui.UpdateTrendColumns(conf.SummaryTrendStats)
 		}
@@ -165,7 +222,7 @@ a commandline interface for interacting with it.`,
 		r.SetOptions(conf.Options)
 
 		// Create a local executor wrapping the runner.
-		fprintf(stdout, "%s executor\r", initBar.String())
+		fprintf(headerOut, "%s executor\r", initBar.String())
 		ex := local.New(r)
 		if runNoSetup {
 			ex.SetRunSetup(false)
@@ -175,7 +232,7 @@ a commandline interface for interacting with it.`,
 		}
 
 		// Create an engine.
-		fprintf(stdout, "%s   engine\r", initBar.String())
+		fprintf(headerOut, "%s   engine\r", initBar.String())
 		engine, err := core.NewEngine(ex, conf.Options)
 		if err != nil {
 			return err
@@ -185,9 +242,39 @@ a commandline interface for interacting with it.`,
 		if conf.NoThresholds.Valid {
 			engine.NoThresholds = conf.NoThresholds.Bool
 		}
+		engine.NoSummary = !summaryNeeded(conf, quiet)
 
-		// Create a collector and assign it to the engine if requested.
-		fprintf(stdout, "%s   collector\r", initBar.String())
+		// Load the summary of the run to compare this one to, for the summary and the thresholds.
+		var baseline *ui.SummaryExport
+		if conf.Compare.String != "" {
+			f, err := os.Open(conf.Compare.String)
+			if err != nil {
+				return errors.Wrap(err, "couldn't open the baseline")
+			}
+			b, err := ui.ReadSummaryExport(f)
+			_ = f.Close()
+			if err != nil {
+				return errors.Wrapf(err, "couldn't read the baseline %s", conf.Compare.String)
+			}
+			baseline = &b
+			engine.SetBaseline(baseline.MetricValues())
+			for name, ths := range conf.Thresholds {
+				if _, ok := baseline.Metrics[name]; ok {
+					continue
+				}
+				for _, th := range ths.Thresholds {
+					if strings.Contains(th.Source, "baseline") {
+						log.WithField("metric", name).Warn("The baseline doesn't have the metric, so its thresholds that compare to it will fail")
+						break
+					}
+				}
+			}
+		}
+
+		// Create the collectors and assign them to the engine if requested. Outputs that can't be
+		// initialized, e.g. because their backend is down, are skipped, unless all of them fail.
+		fprintf(headerOut, "%s   collector\r", initBar.String())
+		var outs []string
 		for _, out := range conf.Out {
 			t, arg := parseCollector(out)
 			collector, err := newCollector(t, arg, src, conf)
@@ -195,15 +282,24 @@ a commandline interface for interacting with it.`,
 				return err
 			}
 			if err := collector.Init(); err != nil {
-				return err
+				if len(conf.Out) == 1 {
+					return err
+				}
+				log.WithError(err).WithField("output", t).Error("Couldn't initialize an output, the test will run without it")
+				continue
 			}
 			engine.Collectors = append(engine.Collectors, collector)
+			engine.CollectorNames = append(engine.CollectorNames, t)
+			outs = append(outs, out)
+		}
+		if len(conf.Out) > 0 && len(engine.Collectors) == 0 {
+			return errors.New("none of the outputs could be initialized")
 		}
 
 		// Create an API server.
-		fprintf(stdout, "%s   server\r", initBar.String())
+		fprintf(headerOut, "%s   server\r", initBar.String())
 		go func() {
-			if err := api.ListenAndServe(address, engine); err != nil {
+			if err := api.ListenAndServe(address, engine, runProfile); err != nil {
 				log.WithError(err).Warn("Error from API server")
 			}
 		}()
@@ -215,9 +311,9 @@ a commandline interface for interacting with it.`,
 			if engine.Collectors != nil {
 				for idx, collector := range engine.Collectors {
 					if out != "-" {
-						out = out + "; " + conf.Out[idx]
+						out = out + "; " + outs[idx]
 					} else {
-						out = conf.Out[idx]
+						out = outs[idx]
 					}
 
 					if l := collector.Link(); l != "" {
@@ -226,10 +322,10 @@ a commandline interface for interacting with it.`,
 				}
 			}
 
-			fprintf(stdout, "  execution: %s\n", ui.ValueColor.Sprint("local"))
-			fprintf(stdout, "     output: %s%s\n", ui.ValueColor.Sprint(out), ui.ExtraColor.Sprint(link))
-			fprintf(stdout, "     script: %s\n", ui.ValueColor.Sprint(filename))
-			fprintf(stdout, "\n")
+			fprintf(headerOut, "  execution: %s\n", ui.ValueColor.Sprint("local"))
+			fprintf(headerOut, "     output: %s%s\n", ui.ValueColor.Sprint(out), ui.ExtraColor.Sprint(link))
+			fprintf(headerOut, "     script: %s\n", ui.ValueColor.Sprint(filename))
+			fprintf(headerOut, "\n")
 
 			duration := ui.GrayColor.Sprint("-")
 			iterations := ui.GrayColor.Sprint("-")
@@ -238,6 +334,8 @@ a commandline interface for interacting with it.`,
 			}
 			if conf.Iterations.Valid {
 				iterations = ui.ValueColor.Sprint(conf.Iterations.Int64)
+			} else if conf.IterationsPerVU.Valid {
+				iterations = ui.ValueColor.Sprint(conf.IterationsPerVU.Int64) + " per VU"
 			}
 			vus := ui.ValueColor.Sprint(conf.VUs.Int64)
 			max := ui.ValueColor.Sprint(conf.VUsMax.Int64)
@@ -249,13 +347,52 @@ a commandline interface for interacting with it.`,
 			durationPad := strings.Repeat(" ", leftWidth-ui.StrWidth(duration))
 			vusPad := strings.Repeat(" ", leftWidth-ui.StrWidth(vus))
 
-			fprintf(stdout, "    duration: %s,%s iterations: %s\n", duration, durationPad, iterations)
-			fprintf(stdout, "         vus: %s,%s max: %s\n", vus, vusPad, max)
-			fprintf(stdout, "\n")
+			fprintf(headerOut, "    duration: %s,%s iterations: %s\n", duration, durationPad, iterations)
+			fprintf(headerOut, "         vus: %s,%s max: %s\n", vus, vusPad, max)
+			fprintf(headerOut, "\n")
+		}
+
+		// The dashboard gets the samples like an output does, and is drawn instead of the
+		// progress bar. It can only be redrawn in place on terminals.
+		var dashboard *ui.Dashboard
+		if conf.Dashboard.Bool && !quiet {
+			if stdoutTTY {
+				dashboard = &ui.Dashboard{
+					History: 40,
+					VUs: func() (int64, int64) {
+						return engine.Executor.GetVUs(), engine.Executor.GetVUsMax()
+					},
+					Thresholds: func() map[string]bool {
+						engine.MetricsLock.Lock()
+						defer engine.MetricsLock.Unlock()
+						thresholds := make(map[string]bool)
+						for name, m := range engine.Metrics {
+							if len(m.Thresholds.Thresholds) > 0 {
+								thresholds[name] = !m.Tainted.Bool
+							}
+						}
+						return thresholds
+					},
+				}
+				engine.Collectors = append(engine.Collectors, dashboard)
+				engine.CollectorNames = append(engine.CollectorNames, "dashboard")
+			} else {
+				log.Warn("The dashboard can only be shown on terminals")
+			}
+		}
+
+		// Webhooks get the test's events like an output does, but they aren't outputs.
+		if len(conf.Webhooks) > 0 {
+			webhooks, err := webhook.New(conf.Webhooks, filename, Version)
+			if err != nil {
+				return err
+			}
+			engine.Collectors = append(engine.Collectors, webhooks)
+			engine.CollectorNames = append(engine.CollectorNames, "webhooks")
 		}
 
 		// Run the engine with a cancellable context.
-		fprintf(stdout, "%s starting\r", initBar.String())
+		fprintf(headerOut, "%s starting\r", initBar.String())
 		ctx, cancel := context.WithCancel(context.Background())
 		errC := make(chan error)
 		go func() { errC <- engine.Run(ctx) }()
@@ -307,7 +444,7 @@ a commandline interface for interacting with it.`,
 				}
 			},
 			Right: func() string {
-				if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
+				if endIt := getEndIterations(engine.Executor); endIt.Valid {
 					return fmt.Sprintf("%d / %d", engine.Executor.GetIterations(), endIt.Int64)
 				}
 				precision := 100 * time.Millisecond
@@ -326,11 +463,16 @@ a commandline interface for interacting with it.`,
 				return ((atT / precision) * precision).String()
 			},
 		}
+		if dashboard != nil {
+			dashboard.Progress = progress.String
+		}
 
 		// Ticker for progress bar updates. Less frequent updates for non-TTYs, none if quiet.
 		updateFreq := 50 * time.Millisecond
 		if !stdoutTTY {
 			updateFreq = 1 * time.Second
+		} else if dashboard != nil {
+			updateFreq = 500 * time.Millisecond
 		}
 		ticker := time.NewTicker(updateFreq)
 		if quiet || conf.HttpDebug.Valid && conf.HttpDebug.String != "" {
@@ -358,7 +500,7 @@ a commandline interface for interacting with it.`,
 				}
 
 				var prog float64
-				if endIt := engine.Executor.GetEndIterations(); endIt.Valid {
+				if endIt := getEndIterations(engine.Executor); endIt.Valid {
 					prog = float64(engine.Executor.GetIterations()) / float64(endIt.Int64)
 				} else {
 					stagesEndT := lib.SumStages(engine.Executor.GetStages())
@@ -371,6 +513,10 @@ a commandline interface for interacting with it.`,
 					}
 				}
 				progress.Progress = prog
+				if dashboard != nil {
+					dashboard.Redraw(stdout, time.Now())
+					break
+				}
 				fprintf(stdout, "%s\x1b[0K\r", progress.String())
 			case err := <-errC:
 				if err != nil {
@@ -397,7 +543,11 @@ a commandline interface for interacting with it.`,
 			fn("Test finished")
 		} else {
 			progress.Progress = 1
-			fprintf(stdout, "%s\x1b[0K\n", progress.String())
+			if dashboard != nil {
+				dashboard.Redraw(stdout, time.Now())
+			} else {
+				fprintf(stdout, "%s\x1b[0K\n", progress.String())
+			}
 		}
 
 		// Warn if no iterations could be completed.
@@ -406,22 +556,55 @@ a commandline interface for interacting with it.`,
 		}
 
 		// Print the end-of-test summary.
-		if !quiet {
+		summaryData := ui.SummaryData{
+			Opts:       conf.Options,
+			Root:       engine.Executor.GetRunner().GetDefaultGroup(),
+			Metrics:    engine.Metrics,
+			Time:       engine.Executor.GetTime(),
+			Breakdowns: engine.Breakdowns,
+			Baseline:   baseline,
+			TimeSeries: engine.TimeSeries,
+		}
+		if !quiet && !conf.NoSummary.Bool {
 			fprintf(stdout, "\n")
-			ui.Summarize(stdout, "", ui.SummaryData{
-				Opts:    conf.Options,
-				Root:    engine.Executor.GetRunner().GetDefaultGroup(),
-				Metrics: engine.Metrics,
-				Time:    engine.Executor.GetTime(),
-			})
+			ui.Summarize(stdout, "", summaryData)
+			fprintf(stdout, "\n")
+		}
+		if prof != nil {
+			prof.WriteSummary(stdout, "  ")
 			fprintf(stdout, "\n")
 		}
 
+		// Write the JUnit report, for CI servers.
+		if conf.JUnitExport.String != "" {
+			var buf bytes.Buffer
+			if err := ui.SummarizeJUnit(&buf, summaryData); err != nil {
+				return err
+			}
+			if err := afero.WriteFile(afero.NewOsFs(), conf.JUnitExport.String, buf.Bytes(), 0644); err != nil {
+				return errors.Wrap(err, "couldn't write the JUnit report")
+			}
+		}
+
+		// Write the summary as JSON, e.g. for later runs to be compared to.
+		if conf.SummaryExport.String != "" {
+			var buf bytes.Buffer
+			if err := ui.SummarizeJSON(&buf, summaryData); err != nil {
+				return err
+			}
+			if err := afero.WriteFile(afero.NewOsFs(), conf.SummaryExport.String, buf.Bytes(), 0644); err != nil {
+				return errors.Wrap(err, "couldn't write the summary export")
+			}
+		}
+
 		if conf.Linger.Bool {
 			log.Info("Linger set; waiting for Ctrl+C...")
 			<-sigC
 		}
 
+		if engine.IsSaturated() {
+			return ExitCode{errors.New("the load generator was saturated"), 97}
+		}
 		if engine.IsTainted() {
 			return ExitCode{errors.New("some thresholds have failed"), 99}
 		}
@@ -439,6 +622,8 @@ func init() {
 	runCmd.Flags().StringVarP(&runType, "type", "t", runType, "override file `type`, \"js\" or \"archive\"")
 	runCmd.Flags().BoolVar(&runNoSetup, "no-setup", runNoSetup, "don't run setup()")
 	runCmd.Flags().BoolVar(&runNoTeardown, "no-teardown", runNoTeardown, "don't run teardown()")
+	runCmd.Flags().BoolVar(&runWatch, "watch", runWatch, "run one iteration with 1 VU, and again whenever the script or the files it imports or opens change")
+	runCmd.Flags().BoolVar(&runProfile, "profile", runProfile, "profile where the VUs spend their time, summarized at the end, and serve the Go pprof endpoints from the REST API")
 }
 
 // Reads a source file from any supported destination.
@@ -486,3 +671,19 @@ func detectType(data []byte) string {
 	}
 	return typeJS
 }
+
+// getEndIterations returns how many iterations the test ends after, if it's known, including
+// when each VU runs a number of them.
+func getEndIterations(ex lib.Executor) null.Int {
+	if perVU := ex.GetEndIterationsPerVU(); perVU.Valid {
+		return null.IntFrom(perVU.Int64 * ex.GetVUs())
+	}
+	return ex.GetEndIterations()
+}
+
+// summaryNeeded returns whether the engine has to aggregate the samples into the metrics of the
+// end-of-test summary: to print it, unless it's hidden with --quiet or --no-summary, or to export
+// it, which happens regardless of them.
+func summaryNeeded(conf Config, quiet bool) bool {
+	return !(quiet || conf.NoSummary.Bool) || conf.JUnitExport.String != "" || conf.SummaryExport.String != ""
+}
@@ -0,0 +1,50 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestSummaryNeeded(t *testing.T) {
+	testdata := map[string]struct {
+		conf   Config
+		quiet  bool
+		needed bool
+	}{
+		"default":                            {Config{}, false, true},
+		"quiet":                              {Config{}, true, false},
+		"no summary":                         {Config{NoSummary: null.BoolFrom(true)}, false, false},
+		"quiet, no thresholds, junit export": {Config{NoThresholds: null.BoolFrom(true), JUnitExport: null.StringFrom("junit.xml")}, true, true},
+		"quiet, no thresholds, summary export": {
+			Config{NoThresholds: null.BoolFrom(true), SummaryExport: null.StringFrom("summary.json")}, true, true,
+		},
+		"no summary, junit export": {Config{NoSummary: null.BoolFrom(true), JUnitExport: null.StringFrom("junit.xml")}, false, true},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, data.needed, summaryNeeded(data.conf, data.quiet))
+		})
+	}
+}
@@ -53,6 +53,8 @@ func runtimeOptionFlagSet(includeSysEnv bool) *pflag.FlagSet {
 	flags.SortFlags = false
 	flags.Bool("include-system-env-vars", includeSysEnv, "pass the real system environment variables to the runtime")
 	flags.StringSliceP("env", "e", nil, "add/override environment variable with `VAR=value`")
+	flags.Bool("strict-options", false, "fail on unknown options and invalid thresholds in the script, instead of warning about them")
+	flags.StringArray("secret-source", nil, "get the scripts' secrets from `source`, one of file=<path>, env[=<prefix>] or vault=<url>; can be repeated, the first source with a secret wins")
 	return flags
 }
 
@@ -60,6 +62,7 @@ func getRuntimeOptions(flags *pflag.FlagSet) (lib.RuntimeOptions, error) {
 	opts := lib.RuntimeOptions{
 		IncludeSystemEnvVars: getNullBool(flags, "include-system-env-vars"),
 		Env:                  make(map[string]string),
+		StrictOptions:        getNullBool(flags, "strict-options"),
 	}
 
 	// If enabled, gather the actual system environment variables
@@ -83,5 +86,9 @@ func getRuntimeOptions(flags *pflag.FlagSet) (lib.RuntimeOptions, error) {
 		}
 	}
 
+	if opts.SecretSources, err = flags.GetStringArray("secret-source"); err != nil {
+		return opts, err
+	}
+
 	return opts, nil
 }
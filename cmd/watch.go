@@ -0,0 +1,223 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/ui"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// How often --watch checks whether the script's files have changed.
+const watchInterval = 250 * time.Millisecond
+
+// watchScript runs one iteration of a script with 1 VU, and then another one whenever the script
+// or one of the local files that it imports or opens changes, until it's interrupted. It's for
+// developing scripts, so requests and responses are dumped, with JSON bodies indented, unless
+// --http-debug says otherwise.
+func watchScript(flags *pflag.FlagSet, filename string) error {
+	if filename == "-" {
+		return errors.New("--watch needs a script file to watch, it can't read the script from stdin")
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigC := make(chan os.Signal, 1)
+	signal.Notify(sigC, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigC)
+	go func() {
+		select {
+		case <-sigC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fs := afero.NewOsFs()
+	for {
+		files, err := runWatchedIteration(ctx, flags, fs, pwd, filename, stdout)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			fprintf(stdout, "%s\n", ui.ErrorColor.Sprint(err))
+		}
+		fprintf(stdout, "\n%s\n\n", ui.GrayColor.Sprintf("Watching %d files for changes, press Ctrl+C to stop...", len(files)))
+		if !waitForChanges(ctx, fs, files, watchInterval) {
+			return nil
+		}
+	}
+}
+
+// runWatchedIteration runs setup(), one iteration and teardown(), writes how it went to w, and
+// returns the files to watch, which are only the script itself if it can't be loaded.
+func runWatchedIteration(
+	ctx context.Context, flags *pflag.FlagSet, fs afero.Fs, pwd, filename string, w io.Writer,
+) ([]string, error) {
+	files := []string{filename}
+	if !filepath.IsAbs(filename) {
+		files[0] = filepath.Join(pwd, filename)
+	}
+
+	src, err := readSource(filename, pwd, fs, nil)
+	if err != nil {
+		return files, err
+	}
+	if detectType(src.Data) != typeJS {
+		return files, errors.New("--watch only runs scripts, not archives")
+	}
+	runtimeOptions, err := getRuntimeOptions(flags)
+	if err != nil {
+		return files, err
+	}
+	r, err := js.New(src, fs, runtimeOptions)
+	if err != nil {
+		return files, err
+	}
+	files = watchedFiles(r.MakeArchive())
+
+	cliConf, err := getConfig(flags)
+	if err != nil {
+		return files, err
+	}
+	fileConf, err := readConfigFiles(fs, pwd)
+	if err != nil {
+		return files, err
+	}
+	envConf, err := readEnvConfig()
+	if err != nil {
+		return files, err
+	}
+	opts := cliConf.Options.Apply(fileConf.Options).Apply(r.GetOptions()).Apply(envConf.Options).Apply(cliConf.Options)
+	if !opts.HttpDebug.Valid {
+		opts.HttpDebug = null.StringFrom("pretty")
+	}
+	r.SetOptions(opts)
+
+	samples := make(chan stats.SampleContainer, 100)
+	defer close(samples)
+	go func() {
+		for range samples {
+		}
+	}()
+
+	start := time.Now()
+	if err := r.Setup(ctx, samples); err != nil {
+		return files, err
+	}
+	vu, err := r.NewVU(samples)
+	if err != nil {
+		return files, err
+	}
+	if err := vu.Reconfigure(1); err != nil {
+		return files, err
+	}
+	iterErr := vu.RunOnce(ctx)
+	teardownErr := r.Teardown(ctx, samples)
+
+	fprintf(w, "\n")
+	ui.SummarizeGroup(w, "    ", r.GetDefaultGroup())
+	switch {
+	case iterErr != nil:
+		return files, iterErr
+	case teardownErr != nil:
+		return files, teardownErr
+	}
+	fprintf(w, "%s\n", ui.SuccColor.Sprintf("✓ done in %s", time.Since(start).Round(time.Millisecond)))
+	return files, nil
+}
+
+// watchedFiles returns the local files in a script's archive: the script, and the scripts and
+// files that it imports and opens. Remote ones aren't watched.
+func watchedFiles(arc *lib.Archive) []string {
+	seen := map[string]bool{arc.Filename: true}
+	for name := range arc.Scripts {
+		seen[name] = true
+	}
+	for name := range arc.Files {
+		seen[name] = true
+	}
+
+	files := make([]string, 0, len(seen))
+	for name := range seen {
+		if filepath.IsAbs(name) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+type watchedFile struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statWatchedFiles(fs afero.Fs, files []string) map[string]watchedFile {
+	states := make(map[string]watchedFile, len(files))
+	for _, name := range files {
+		if info, err := fs.Stat(name); err == nil {
+			states[name] = watchedFile{true, info.Size(), info.ModTime()}
+		} else {
+			states[name] = watchedFile{}
+		}
+	}
+	return states
+}
+
+// waitForChanges waits until one of the files is modified, created or deleted, and returns false
+// if the context is done first.
+func waitForChanges(ctx context.Context, fs afero.Fs, files []string, interval time.Duration) bool {
+	before := statWatchedFiles(fs, files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for name, state := range statWatchedFiles(fs, files) {
+				if state != before[name] {
+					return true
+				}
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
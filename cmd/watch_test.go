@@ -0,0 +1,124 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchedFiles(t *testing.T) {
+	arc := &lib.Archive{
+		Filename: "/path/to/script.js",
+		Scripts: map[string][]byte{
+			"/path/to/script.js":             nil,
+			"/path/to/lib.js":                nil,
+			"https://example.com/remote.js":  nil,
+			"cdnjs.com/libraries/Faker/x.js": nil,
+		},
+		Files: map[string][]byte{"/path/to/data.json": nil},
+	}
+	assert.Equal(t, []string{"/path/to/data.json", "/path/to/lib.js", "/path/to/script.js"}, watchedFiles(arc))
+}
+
+func TestWaitForChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k6-watch")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(dir)) }()
+
+	fs := afero.NewBasePathFs(afero.NewOsFs(), dir)
+	require.NoError(t, afero.WriteFile(fs, "/script.js", []byte("1"), 0644))
+	files := []string{"/script.js", "/data.json"}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		assert.False(t, waitForChanges(ctx, fs, files, 10*time.Millisecond))
+	})
+
+	changes := map[string]func() error{
+		"Modified": func() error { return afero.WriteFile(fs, "/script.js", []byte("22"), 0644) },
+		"Created":  func() error { return afero.WriteFile(fs, "/data.json", []byte("{}"), 0644) },
+		"Deleted":  func() error { return fs.Remove("/data.json") },
+	}
+	for _, name := range []string{"Modified", "Created", "Deleted"} {
+		change := changes[name]
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			go func() {
+				time.Sleep(30 * time.Millisecond)
+				assert.NoError(t, change())
+			}()
+			assert.True(t, waitForChanges(ctx, fs, files, 10*time.Millisecond))
+			assert.NoError(t, ctx.Err())
+		})
+	}
+}
+
+func TestRunWatchedIteration(t *testing.T) {
+	flags := optionFlagSet()
+	flags.AddFlagSet(runtimeOptionFlagSet(false))
+	flags.AddFlagSet(configFlagSet())
+
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/lib.js", []byte(`export default function() { return 1; }`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/script.js", []byte(`
+		import { check } from "k6";
+		import one from "./lib.js";
+		export default function() {
+			check(one(), { "is one": (v) => v === 1 });
+			if (__ENV.FAIL) { throw new Error("failed"); }
+		};
+	`), 0644))
+
+	var buf bytes.Buffer
+	files, err := runWatchedIteration(context.Background(), flags, fs, "/", "script.js", &buf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/lib.js", "/script.js"}, files)
+	assert.Contains(t, buf.String(), "is one")
+	assert.Contains(t, buf.String(), "done in")
+
+	require.NoError(t, flags.Set("env", "FAIL=1"))
+	buf.Reset()
+	files, err = runWatchedIteration(context.Background(), flags, fs, "/", "script.js", &buf)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Error: failed at /script.js:6")
+	}
+	assert.Equal(t, []string{"/lib.js", "/script.js"}, files)
+	assert.Contains(t, buf.String(), "is one")
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/broken.js", []byte(`export default function() {`), 0644))
+		files, err := runWatchedIteration(context.Background(), flags, fs, "/", "broken.js", &buf)
+		assert.Error(t, err)
+		assert.Equal(t, []string{"/broken.js"}, files)
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"io"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/loadimpact/k6/lib"
@@ -124,13 +125,25 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 		}
 	}
 
+	var orderedEntries []*Entry
+	for _, page := range pages {
+		sort.Sort(EntryByStarted(pageEntries[page.ID]))
+		orderedEntries = append(orderedEntries, pageEntries[page.ID]...)
+	}
+
+	var c *correlator
+	if correlate {
+		c = newCorrelator(orderedEntries)
+		if variables := c.Variables(); len(variables) > 0 {
+			fprintf(w, "\tlet %s;\n\n", strings.Join(variables, ", "))
+		}
+	}
+
 	for i, page := range pages {
 
 		entries := pageEntries[page.ID]
 		fprintf(w, "\tgroup(\"%s - %s\", function() {\n", page.ID, page.Title)
 
-		sort.Sort(EntryByStarted(entries))
-
 		if nobatch {
 			var recordedRedirectURL string
 			previousResponse := map[string]interface{}{}
@@ -149,14 +162,21 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 					body = e.Request.PostData.Text
 				}
 
-				for _, c := range e.Request.Cookies {
-					cookies = append(cookies, fmt.Sprintf(`%q: %q`, c.Name, c.Value))
+				for _, cookie := range e.Request.Cookies {
+					if correlate && c.SkipCookie(cookie) {
+						continue
+					}
+					cookies = append(cookies, fmt.Sprintf(`%q: %q`, cookie.Name, cookie.Value))
 				}
 				if len(cookies) > 0 {
 					params = append(params, fmt.Sprintf("\"cookies\": {\n\t\t\t\t%s\n\t\t\t}", strings.Join(cookies, ",\n\t\t\t\t\t")))
 				}
 
-				if headers := buildK6Headers(e.Request.Headers); len(headers) > 0 {
+				quote := strconv.Quote
+				if correlate {
+					quote = c.Expr
+				}
+				if headers := buildK6HeadersWith(e.Request.Headers, quote); len(headers) > 0 {
 					params = append(params, fmt.Sprintf("\"headers\": {\n\t\t\t\t\t%s\n\t\t\t\t}", strings.Join(headers, ",\n\t\t\t\t\t")))
 				}
 
@@ -169,7 +189,7 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 					fprintf(w, "redirectUrl")
 					recordedRedirectURL = ""
 				} else {
-					fprintf(w, "%q", e.Request.URL)
+					fprint(w, quote(e.Request.URL))
 				}
 
 				if e.Request.Method != "GET" {
@@ -188,13 +208,13 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 						requestText, err := json.Marshal(requestMap)
 						if err == nil {
 							prettyJSONString := string(pretty.PrettyOptions(requestText, &pretty.Options{Width: 999999, Prefix: "\t\t\t", Indent: "\t", SortKeys: true})[:])
-							fprintf(w, ",\n\t\t\t`%s`", strings.TrimSpace(prettyJSONString))
+							fprintf(w, ",\n\t\t\t`%s`", c.Template(strings.TrimSpace(prettyJSONString)))
 						} else {
 							return "", err
 						}
 
 					} else {
-						fprintf(w, ",\n\t\t%q", body)
+						fprintf(w, ",\n\t\t%s", quote(body))
 					}
 				}
 
@@ -236,6 +256,12 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 						fprint(w, "\t\tjson = JSON.parse(res.body);\n")
 					}
 				}
+
+				if correlate {
+					for _, statement := range c.Extract(e) {
+						fprintf(w, "\t\t%s\n", statement)
+					}
+				}
 			}
 		} else {
 			batches := SplitEntriesInBatches(entries, batchTime)
@@ -358,6 +384,11 @@ func buildK6RequestObject(req *Request) (string, error) {
 }
 
 func buildK6Headers(headers []Header) []string {
+	return buildK6HeadersWith(headers, strconv.Quote)
+}
+
+// buildK6HeadersWith builds the headers with the given function for the JS expressions of the values.
+func buildK6HeadersWith(headers []Header, value func(string) string) []string {
 	var h []string
 	if len(headers) > 0 {
 		m := make(map[string]Header)
@@ -367,7 +398,7 @@ func buildK6Headers(headers []Header) []string {
 			// Avoid SPDY's, duplicated or cookie headers
 			if !exists && name[0] != ':' && name != "cookie" {
 				m[strings.ToLower(header.Name)] = header
-				h = append(h, fmt.Sprintf("%q: %q", header.Name, header.Value))
+				h = append(h, fmt.Sprintf("%q: %s", header.Name, value(header.Value)))
 			}
 		}
 	}
@@ -0,0 +1,234 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Names that the generated scripts already use.
+var reservedVariables = map[string]bool{
+	"res": true, "req": true, "json": true, "redirectUrl": true, "http": true,
+	"group": true, "check": true, "sleep": true, "options": true,
+}
+
+// A correlatedValue is a recorded value that the script extracts into a variable.
+type correlatedValue struct {
+	value    string
+	variable string
+}
+
+// A correlator generates the code that extracts the values of correlation hints from the responses,
+// and substitutes them for the recorded values in the later requests.
+type correlator struct {
+	entries map[*Entry]int
+	hints   []CorrelationHint
+
+	variables map[string]string // Kind and name of a hint -> variable
+	names     map[string]bool
+	active    []correlatedValue // The extracted values, which later requests use
+	cookies   map[string]string // The cookies that the responses so far have set
+}
+
+// newCorrelator finds the correlation hints in the entries, in the order that the script sends them.
+func newCorrelator(entries []*Entry) *correlator {
+	c := &correlator{
+		entries:   make(map[*Entry]int, len(entries)),
+		variables: make(map[string]string),
+		names:     make(map[string]bool),
+		cookies:   make(map[string]string),
+	}
+	for i, e := range entries {
+		c.entries[e] = i
+	}
+	for _, hint := range FindCorrelationHints(entries) {
+		if hint.Extract == "" {
+			continue
+		}
+		// Values in JSON bodies are correlated with the previous JSON response by traverseMaps()
+		req := *entries[hint.Target].Request
+		if req.PostData != nil && strings.Contains(req.PostData.MimeType, "json") {
+			req.PostData = nil
+		}
+		if requestSends(&req, hint.Value) {
+			c.hints = append(c.hints, hint)
+		}
+	}
+	return c
+}
+
+// Variables returns the names of all the variables that the extraction code assigns to.
+func (c *correlator) Variables() []string {
+	var variables []string
+	for _, hint := range c.hints {
+		variable := c.variable(hint)
+		if !contains(variables, variable) {
+			variables = append(variables, variable)
+		}
+	}
+	return variables
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// variable returns the variable of a hint. Hints with the same kind and name share a variable,
+// e.g. a CSRF token that every page has.
+func (c *correlator) variable(hint CorrelationHint) string {
+	key := hint.Kind + "\x00" + hint.Name
+	if variable, ok := c.variables[key]; ok {
+		return variable
+	}
+
+	var b strings.Builder
+	upper := false
+	for _, r := range hint.Name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = b.Len() > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		case b.Len() == 0:
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	base := b.String()
+	if base == "" || unicode.IsDigit(rune(base[0])) {
+		base = "value" + strings.Title(base)
+	}
+
+	variable := base
+	for i := 2; reservedVariables[variable] || c.names[variable]; i++ {
+		variable = base + strconv.Itoa(i)
+	}
+	c.variables[key] = variable
+	c.names[variable] = true
+	return variable
+}
+
+// Extract returns the statements that extract the values in the response of an entry, which has
+// to be in `res`.
+func (c *correlator) Extract(e *Entry) []string {
+	index := c.entries[e]
+	if e.Response != nil {
+		for _, cookie := range e.Response.Cookies {
+			c.cookies[cookie.Name] = cookie.Value
+		}
+	}
+
+	var statements []string
+	for _, hint := range c.hints {
+		if hint.Source != index {
+			continue
+		}
+		variable := c.variable(hint)
+		statements = append(statements, variable+" = "+hint.Extract+";")
+
+		active := c.active[:0]
+		for _, v := range c.active {
+			if v.variable != variable {
+				active = append(active, v)
+			}
+		}
+		c.active = append(active, correlatedValue{value: hint.Value, variable: variable})
+	}
+	return statements
+}
+
+// SkipCookie tells whether a recorded cookie was set by an earlier response, in which case the
+// cookie jar sends it, and the request doesn't have to.
+func (c *correlator) SkipCookie(cookie Cookie) bool {
+	value, ok := c.cookies[cookie.Name]
+	return ok && value == cookie.Value
+}
+
+// Expr returns a JS expression for a recorded string, with the extracted values in it replaced by
+// their variables. URL-encoded values are replaced by encodeURIComponent() calls.
+func (c *correlator) Expr(s string) string {
+	var parts []string
+	for len(s) > 0 {
+		start, end, replacement := -1, 0, ""
+		for _, v := range c.active {
+			candidates := []string{v.value}
+			if escaped := url.QueryEscape(v.value); escaped != v.value {
+				candidates = append(candidates, escaped)
+			}
+			for i, candidate := range candidates {
+				if pos := strings.Index(s, candidate); pos >= 0 && (start < 0 || pos < start) {
+					start, end, replacement = pos, pos+len(candidate), v.variable
+					if i > 0 {
+						replacement = "encodeURIComponent(" + v.variable + ")"
+					}
+				}
+			}
+		}
+		if start < 0 {
+			break
+		}
+		if start > 0 {
+			parts = append(parts, strconv.Quote(s[:start]))
+		}
+		parts = append(parts, replacement)
+		s = s[end:]
+	}
+	if len(s) > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(s))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// Template replaces the extracted values in the text of a JS template literal with placeholders,
+// leaving the placeholders that are already there alone.
+func (c *correlator) Template(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			start = len(s)
+		}
+		text := s[:start]
+		for _, v := range c.active {
+			text = strings.Replace(text, v.value, "${"+v.variable+"}", -1)
+		}
+		b.WriteString(text)
+		s = s[start:]
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			end = len(s) - 1
+		}
+		b.WriteString(s[:end+1])
+		s = s[end+1:]
+	}
+	return b.String()
+}
@@ -0,0 +1,138 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/lib"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelator(t *testing.T) {
+	c := &correlator{variables: map[string]string{}, names: map[string]bool{}}
+
+	t.Run("variable", func(t *testing.T) {
+		testdata := map[string]string{
+			"csrf_token":                 "csrfToken",
+			"X-CSRF-Token":               "xCSRFToken",
+			"__RequestVerificationToken": "requestVerificationToken",
+			"0":                          "value0",
+			"res":                        "res2",
+			"data-id":                    "dataId",
+		}
+		for name, expected := range testdata {
+			assert.Equal(t, expected, c.variable(CorrelationHint{Kind: "CSRF token", Name: name}), name)
+		}
+		assert.Equal(t, "csrfToken", c.variable(CorrelationHint{Kind: "CSRF token", Name: "csrf_token"}))
+		assert.Equal(t, "csrfToken2", c.variable(CorrelationHint{Kind: "JSON value", Name: "csrfToken"}))
+	})
+
+	c.active = []correlatedValue{{value: "abc/123", variable: "token"}, {value: "42424242", variable: "id"}}
+
+	t.Run("Expr", func(t *testing.T) {
+		testdata := map[string]string{
+			"":                               `""`,
+			"nothing":                        `"nothing"`,
+			"abc/123":                        `token`,
+			"/items/42424242?t=abc%2F123":    `"/items/" + id + "?t=" + encodeURIComponent(token)`,
+			"42424242-abc/123":               `id + "-" + token`,
+			"\"quoted\" 42424242 \"values\"": `"\"quoted\" " + id + " \"values\""`,
+		}
+		for s, expected := range testdata {
+			assert.Equal(t, expected, c.Expr(s), s)
+		}
+	})
+
+	t.Run("Template", func(t *testing.T) {
+		assert.Equal(t, `{"id": "${id}", "other": "${json.abc/123}", "token": "${token}"}`,
+			c.Template(`{"id": "42424242", "other": "${json.abc/123}", "token": "abc/123"}`))
+	})
+}
+
+func TestConvertCorrelate(t *testing.T) {
+	started := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := HAR{Log: &Log{
+		Creator: &Creator{Name: "test"},
+		Pages:   []Page{{ID: "page_1", Title: "login", StartedDateTime: started}},
+		Entries: []*Entry{
+			{
+				Pageref: "page_1", StartedDateTime: started,
+				Request: &Request{Method: "GET", URL: "https://example.com/login"},
+				Response: &Response{
+					Status:  200,
+					Cookies: []Cookie{{Name: "session_id", Value: "session-1234"}},
+					Content: &Content{MimeType: "text/html", Text: `<input type="hidden" name="csrf_token" value="token/5678">`},
+				},
+			},
+			{
+				Pageref: "page_1", StartedDateTime: started.Add(time.Second),
+				Request: &Request{
+					Method:   "POST",
+					URL:      "https://example.com/login",
+					Cookies:  []Cookie{{Name: "session_id", Value: "session-1234"}, {Name: "theme", Value: "dark"}},
+					PostData: &PostData{MimeType: "application/x-www-form-urlencoded", Text: "user=me&csrf_token=token%2F5678"},
+				},
+				Response: &Response{
+					Status:  200,
+					Content: &Content{MimeType: "application/json", Text: `{"user": {"id": "user-0042", "name": "Some Name"}}`},
+				},
+			},
+			{
+				Pageref: "page_1", StartedDateTime: started.Add(2 * time.Second),
+				Request: &Request{
+					Method:  "GET",
+					URL:     "https://example.com/users/user-0042",
+					Headers: []Header{{Name: "X-CSRF-Token", Value: "token/5678"}},
+				},
+			},
+		},
+	}}
+
+	script, err := Convert(h, lib.Options{}, 0, 1, false, false, 0, true, true, nil, nil)
+	require.NoError(t, err)
+
+	for _, expected := range []string{
+		"\tlet csrfToken, id;\n",
+		`csrfToken = res.html().find("input[name='csrf_token']").first().attr("value");`,
+		`"user=me&csrf_token=" + encodeURIComponent(csrfToken)`,
+		`id = res.json("user.id");`,
+		`res = http.get("https://example.com/users/" + id,`,
+		`"X-CSRF-Token": csrfToken`,
+		`"theme": "dark"`,
+	} {
+		assert.Contains(t, script, expected)
+	}
+	assert.NotContains(t, script, "session-1234", "the cookie jar sends the session cookie")
+	assert.NotContains(t, script, "token/5678")
+	assert.NotContains(t, script, "token%2F5678")
+
+	_, err = js.New(&lib.SourceData{
+		Filename: "/script.js",
+		Data:     []byte(script),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	assert.NoError(t, err, fmt.Sprintf("invalid script:\n%s", script))
+}
@@ -0,0 +1,246 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A CorrelationHint is a value that a response gave to the client, and that a later request sent
+// back. A script that replays the recorded value will most likely fail, so it has to extract the
+// value from the response when it runs.
+type CorrelationHint struct {
+	Kind    string // "CSRF token", "session cookie" or "JSON value"
+	Name    string // The name of the form field, meta tag, header, cookie or JSON property
+	Value   string
+	Extract string // JS code that extracts the value from the response `res`, if there's any
+	Source  int    // The index of the entry whose response has the value
+	Target  int    // The index of the first entry whose request sends it
+}
+
+var (
+	csrfNameRE       = regexp.MustCompile(`(?i)csrf|xsrf|authenticity_token|requestverificationtoken|^_token$|nonce`)
+	sessionNameRE    = regexp.MustCompile(`(?i)sess|sid$|^auth|token|jwt`)
+	inputTagRE       = regexp.MustCompile(`(?is)<input\b[^>]*>`)
+	metaTagRE        = regexp.MustCompile(`(?is)<meta\b[^>]*>`)
+	tagAttrRE        = regexp.MustCompile(`(?is)\b(name|value|content)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	jsonIdentifierRE = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	jsonIDRE         = regexp.MustCompile(`^[A-Za-z0-9_\-.~+/=]+$`)
+)
+
+// The shortest value that is considered for a hint; shorter ones appear in requests by chance.
+const minCorrelationValueLength = 8
+
+// FindCorrelationHints looks for CSRF tokens, session IDs and JSON values in the responses of the
+// entries, and returns the ones that are sent by later requests, in the order of the responses.
+func FindCorrelationHints(entries []*Entry) []CorrelationHint {
+	var hints []CorrelationHint
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		for _, hint := range responseCandidates(e.Response) {
+			if len(hint.Value) < minCorrelationValueLength || seen[hint.Name+"="+hint.Value] {
+				continue
+			}
+			for j := i + 1; j < len(entries); j++ {
+				if requestSends(entries[j].Request, hint.Value) {
+					seen[hint.Name+"="+hint.Value] = true
+					hint.Source, hint.Target = i, j
+					hints = append(hints, hint)
+					break
+				}
+			}
+		}
+	}
+	return hints
+}
+
+func responseCandidates(res *Response) []CorrelationHint {
+	if res == nil {
+		return nil
+	}
+
+	var candidates []CorrelationHint
+	for _, h := range res.Headers {
+		if csrfNameRE.MatchString(h.Name) {
+			candidates = append(candidates, CorrelationHint{
+				Kind: "CSRF token", Name: h.Name, Value: h.Value,
+				Extract: fmt.Sprintf("res.headers[%q]", h.Name),
+			})
+		}
+	}
+	for _, c := range res.Cookies {
+		switch {
+		case csrfNameRE.MatchString(c.Name):
+			candidates = append(candidates, CorrelationHint{
+				Kind: "CSRF token", Name: c.Name, Value: c.Value,
+				Extract: fmt.Sprintf("res.cookies[%q][0].value", c.Name),
+			})
+		case sessionNameRE.MatchString(c.Name):
+			candidates = append(candidates, CorrelationHint{Kind: "session cookie", Name: c.Name, Value: c.Value})
+		}
+	}
+
+	if res.Content == nil || res.Content.Encoding != "" {
+		return candidates
+	}
+	if strings.Contains(res.Content.MimeType, "json") {
+		var data interface{}
+		if err := json.Unmarshal([]byte(res.Content.Text), &data); err == nil {
+			candidates = jsonCandidates(candidates, data, "")
+		}
+		return candidates
+	}
+	if !strings.Contains(res.Content.MimeType, "html") {
+		return candidates
+	}
+	for _, tag := range inputTagRE.FindAllString(res.Content.Text, -1) {
+		attrs := tagAttrs(tag)
+		if csrfNameRE.MatchString(attrs["name"]) {
+			candidates = append(candidates, CorrelationHint{
+				Kind: "CSRF token", Name: attrs["name"], Value: attrs["value"],
+				Extract: fmt.Sprintf(`res.html().find("input[name='%s']").first().attr("value")`, attrs["name"]),
+			})
+		}
+	}
+	for _, tag := range metaTagRE.FindAllString(res.Content.Text, -1) {
+		attrs := tagAttrs(tag)
+		if csrfNameRE.MatchString(attrs["name"]) {
+			candidates = append(candidates, CorrelationHint{
+				Kind: "CSRF token", Name: attrs["name"], Value: attrs["content"],
+				Extract: fmt.Sprintf(`res.html().find("meta[name='%s']").attr("content")`, attrs["name"]),
+			})
+		}
+	}
+	return candidates
+}
+
+// jsonCandidates adds the string values in JSON data, which are most likely IDs or tokens if they're
+// long enough, with the selectors that res.json() needs to get them.
+func jsonCandidates(candidates []CorrelationHint, data interface{}, selector string) []CorrelationHint {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub := selector + "." + key
+			if !jsonIdentifierRE.MatchString(key) {
+				sub = selector + "[" + strconv.Quote(key) + "]"
+			}
+			candidates = jsonCandidates(candidates, v[key], sub)
+		}
+	case []interface{}:
+		for i, item := range v {
+			candidates = jsonCandidates(candidates, item, selector+"["+strconv.Itoa(i)+"]")
+		}
+	case string:
+		// Most strings are names, texts or URLs, which are sent back because they're data that the
+		// client edits, rather than IDs or tokens that the server keeps track of
+		if selector == "" || !jsonIDRE.MatchString(v) || !strings.ContainsAny(v, "0123456789") {
+			return candidates
+		}
+		selector = strings.TrimPrefix(selector, ".")
+		name := selector
+		if i := strings.LastIndexAny(name, ".["); i >= 0 {
+			name = strings.Trim(name[i:], `.[]"`)
+		}
+		candidates = append(candidates, CorrelationHint{
+			Kind: "JSON value", Name: name, Value: v,
+			Extract: fmt.Sprintf("res.json(%q)", selector),
+		})
+	}
+	return candidates
+}
+
+func tagAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, m := range tagAttrRE.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(m[1])] = m[2] + m[3]
+	}
+	return attrs
+}
+
+func requestSends(req *Request, value string) bool {
+	if req == nil {
+		return false
+	}
+	escaped := url.QueryEscape(value)
+	contains := func(s string) bool {
+		return strings.Contains(s, value) || strings.Contains(s, escaped)
+	}
+
+	if contains(req.URL) {
+		return true
+	}
+	for _, h := range req.Headers {
+		if contains(h.Value) {
+			return true
+		}
+	}
+	for _, c := range req.Cookies {
+		if c.Value == value {
+			return true
+		}
+	}
+	if req.PostData != nil {
+		if contains(req.PostData.Text) {
+			return true
+		}
+		for _, p := range req.PostData.Params {
+			if contains(p.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FormatCorrelationHints returns the hints as comments for the top of a script.
+func FormatCorrelationHints(entries []*Entry, hints []CorrelationHint) string {
+	if len(hints) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fprint(&b, "// Correlation hints: the recorded script replays these values, but they were given to\n")
+	fprint(&b, "// the browser by the server, so they most likely have to be extracted when the script runs\n")
+	fprint(&b, "// (which --correlate --no-batch does).\n")
+	for _, hint := range hints {
+		source, target := entries[hint.Source].Request, entries[hint.Target].Request
+		fprintf(&b, "//  - %s %q from the response to %s %s is sent by %s %s",
+			hint.Kind, hint.Name, source.Method, source.URL, target.Method, target.URL)
+		if hint.Extract != "" {
+			fprintf(&b, ";\n//    extract it with %s\n", hint.Extract)
+		} else {
+			fprint(&b, ";\n//    remove it from the recorded cookies, and k6's cookie jar will send the current one\n")
+		}
+	}
+	fprint(&b, "\n")
+	return b.String()
+}
@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package har
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCorrelationHints(t *testing.T) {
+	entries := []*Entry{
+		{
+			Request: &Request{Method: "GET", URL: "https://example.com/login"},
+			Response: &Response{
+				Cookies: []Cookie{{Name: "PHPSESSID", Value: "session-1234"}, {Name: "theme", Value: "dark-mode-theme"}},
+				Headers: []Header{{Name: "X-CSRF-Token", Value: "header-token-42"}},
+				Content: &Content{MimeType: "text/html", Text: `<html><head>
+					<meta name="csrf-token" content="meta-token-1337">
+					</head><body><form>
+					<input type="hidden" value="form/token+9" name="authenticity_token">
+					<input name="short_token" value="abc">
+					<input name="username" value="some-user-name">
+					</form></body></html>`},
+			},
+		},
+		{
+			Request: &Request{
+				Method:   "POST",
+				URL:      "https://example.com/login",
+				Cookies:  []Cookie{{Name: "PHPSESSID", Value: "session-1234"}, {Name: "theme", Value: "dark-mode-theme"}},
+				Headers:  []Header{{Name: "X-CSRF-Token", Value: "meta-token-1337"}},
+				PostData: &PostData{Text: "authenticity_token=form%2Ftoken%2B9&short_token=abc&username=some-user-name"},
+			},
+		},
+		{
+			Request: &Request{Method: "GET", URL: "https://example.com/api?token=header-token-42"},
+		},
+	}
+
+	hints := FindCorrelationHints(entries)
+	if !assert.Len(t, hints, 4) {
+		return
+	}
+	assert.Equal(t, CorrelationHint{
+		Kind: "CSRF token", Name: "X-CSRF-Token", Value: "header-token-42",
+		Extract: `res.headers["X-CSRF-Token"]`, Source: 0, Target: 2,
+	}, hints[0])
+	assert.Equal(t, CorrelationHint{
+		Kind: "session cookie", Name: "PHPSESSID", Value: "session-1234", Source: 0, Target: 1,
+	}, hints[1])
+	assert.Equal(t, "authenticity_token", hints[2].Name)
+	assert.Equal(t, `res.html().find("input[name='authenticity_token']").first().attr("value")`, hints[2].Extract)
+	assert.Equal(t, "csrf-token", hints[3].Name)
+	assert.Equal(t, `res.html().find("meta[name='csrf-token']").attr("content")`, hints[3].Extract)
+
+	comments := FormatCorrelationHints(entries, hints)
+	assert.Contains(t, comments, `//  - session cookie "PHPSESSID" from the response to GET https://example.com/login is sent by POST https://example.com/login;`)
+	assert.Contains(t, comments, "cookie jar")
+	assert.Empty(t, FormatCorrelationHints(entries, nil))
+}
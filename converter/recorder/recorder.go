@@ -0,0 +1,367 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package recorder implements the proxy behind `k6 record`, which records the traffic of a browser
+// as a HAR log, that can be converted to a script like any other HAR file.
+package recorder
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/converter/har"
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers that only concern the connection to the proxy, and aren't forwarded.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// A Recorder is an HTTP proxy that records the requests that go through it, and their responses.
+type Recorder struct {
+	// Transport sends the requests to the servers.
+	Transport http.RoundTripper
+
+	ca *CA // If it's nil, HTTPS connections are tunneled without being recorded.
+
+	mutex   sync.Mutex
+	entries []*har.Entry
+	pages   []har.Page
+}
+
+// New returns a recorder that uses the given CA to intercept HTTPS traffic, if it's not nil.
+func New(ca *CA) *Recorder {
+	return &Recorder{
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		ca: ca,
+	}
+}
+
+// HAR returns the recorded traffic so far. Every page navigation starts a new page, and the
+// requests that follow it are its entries.
+func (r *Recorder) HAR() har.HAR {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return har.HAR{Log: &har.Log{
+		Version: "1.2",
+		Creator: &har.Creator{Name: "k6 record"},
+		Pages:   append([]har.Page{}, r.pages...),
+		Entries: append([]*har.Entry{}, r.entries...),
+	}}
+}
+
+// ServeHTTP proxies a request: plain HTTP requests are forwarded and recorded, and CONNECT
+// requests are intercepted if the recorder has a CA, or tunneled if it doesn't.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.serveConnect(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "This is the k6 recording proxy, configure it as the HTTP proxy of your browser", http.StatusBadRequest)
+		return
+	}
+
+	res := r.roundTrip(req)
+	for name, values := range res.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(res.StatusCode)
+	_, _ = io.Copy(w, res.Body)
+}
+
+func (r *Recorder) serveConnect(w http.ResponseWriter, req *http.Request) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "CONNECT isn't supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		log.WithError(err).Warn("Couldn't take over a proxy connection")
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	if r.ca == nil {
+		tunnel(conn, req.Host)
+		return
+	}
+
+	host := req.URL.Hostname()
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" {
+				return r.ca.Certificate(hello.ServerName)
+			}
+			return r.ca.Certificate(host)
+		},
+		NextProtos: []string{"http/1.1"},
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		log.WithError(err).WithField("host", req.Host).Warn(
+			"The TLS handshake with the browser failed, does it trust the recorder's CA certificate?")
+		return
+	}
+
+	br := bufio.NewReader(tlsConn)
+	for {
+		inner, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		inner.URL.Scheme, inner.URL.Host = "https", req.Host
+		if strings.HasSuffix(inner.URL.Host, ":443") {
+			inner.URL.Host = inner.URL.Host[:len(inner.URL.Host)-4]
+		}
+		res := r.roundTrip(inner)
+		if err := res.Write(tlsConn); err != nil || inner.Close {
+			return
+		}
+	}
+}
+
+// tunnel copies the traffic between the browser and the server, without looking at it.
+func tunnel(conn net.Conn, addr string) {
+	server, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		log.WithError(err).WithField("host", addr).Warn("Couldn't connect to the server")
+		return
+	}
+	defer func() { _ = server.Close() }()
+
+	done := make(chan struct{}, 2)
+	go func() { _, _ = io.Copy(server, conn); done <- struct{}{} }()
+	go func() { _, _ = io.Copy(conn, server); done <- struct{}{} }()
+	<-done
+}
+
+// roundTrip forwards a request to its server and records it. The returned response has its whole
+// body in memory; if the server couldn't be reached, it's a 502 Bad Gateway.
+func (r *Recorder) roundTrip(req *http.Request) *http.Response {
+	started := time.Now()
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return errorResponse(req, err)
+	}
+
+	out, err := http.NewRequest(req.Method, req.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	for name, values := range req.Header {
+		out.Header[name] = values
+	}
+	for _, name := range hopHeaders {
+		out.Header.Del(name)
+	}
+	// The transport asks for and decompresses gzip by itself, so that the recorded bodies are readable
+	out.Header.Del("Accept-Encoding")
+
+	res, err := r.Transport.RoundTrip(out)
+	if err != nil {
+		log.WithError(err).WithField("url", req.URL.String()).Warn("Request failed")
+		return errorResponse(req, err)
+	}
+	resBody, err := ioutil.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return errorResponse(req, err)
+	}
+	for _, name := range hopHeaders {
+		res.Header.Del(name)
+	}
+	res.Header.Del("Content-Length")
+	res.Body = ioutil.NopCloser(bytes.NewReader(resBody))
+	res.ContentLength = int64(len(resBody))
+	res.TransferEncoding = nil
+	res.Close = false
+	res.Request = req
+
+	r.record(req, body, res, resBody, started, time.Since(started))
+	return res
+}
+
+func errorResponse(req *http.Request, err error) *http.Response {
+	body := []byte(err.Error())
+	return &http.Response{
+		Status:        "502 Bad Gateway",
+		StatusCode:    http.StatusBadGateway,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// isNavigation tells whether a request loads a new page in the browser, rather than a resource of
+// the current page.
+func isNavigation(req *http.Request) bool {
+	if mode := req.Header.Get("Sec-Fetch-Mode"); mode != "" {
+		return mode == "navigate"
+	}
+	return req.Method == http.MethodGet && strings.HasPrefix(req.Header.Get("Accept"), "text/html")
+}
+
+func (r *Recorder) record(req *http.Request, body []byte, res *http.Response, resBody []byte, started time.Time, elapsed time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.pages) == 0 || isNavigation(req) {
+		r.pages = append(r.pages, har.Page{
+			StartedDateTime: started,
+			ID:              fmt.Sprintf("page_%d", len(r.pages)+1),
+			Title:           req.URL.String(),
+		})
+	}
+
+	entry := &har.Entry{
+		Pageref:         r.pages[len(r.pages)-1].ID,
+		ID:              fmt.Sprint(len(r.entries) + 1),
+		StartedDateTime: started,
+		Time:            float32(elapsed.Seconds() * 1000),
+		Request: &har.Request{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     harCookies(req.Cookies()),
+			Headers:     harHeaders(req.Header),
+			HeadersSize: -1,
+			BodySize:    int64(len(body)),
+		},
+		Response: &har.Response{
+			Status:      res.StatusCode,
+			StatusText:  http.StatusText(res.StatusCode),
+			HTTPVersion: res.Proto,
+			Cookies:     harCookies(res.Cookies()),
+			Headers:     harHeaders(res.Header),
+			Content:     harContent(res.Header.Get("Content-Type"), resBody),
+			RedirectURL: res.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    int64(len(resBody)),
+		},
+		Cache:   &har.Cache{},
+		Timings: &har.Timings{Wait: float32(elapsed.Seconds() * 1000)},
+	}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, har.QueryString{Name: name, Value: value})
+		}
+	}
+	if len(body) > 0 {
+		entry.Request.PostData = harPostData(req.Header.Get("Content-Type"), body)
+	}
+	r.entries = append(r.entries, entry)
+}
+
+func harHeaders(header http.Header) []har.Header {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers []har.Header
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, har.Header{Name: name, Value: value})
+		}
+	}
+	return headers
+}
+
+func harCookies(cookies []*http.Cookie) []har.Cookie {
+	var result []har.Cookie
+	for _, c := range cookies {
+		result = append(result, har.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  c.Expires,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		})
+	}
+	return result
+}
+
+// harPostData keeps the parameters of URL-encoded bodies escaped, like browsers do in HAR files.
+func harPostData(contentType string, body []byte) *har.PostData {
+	data := &har.PostData{MimeType: contentType, Text: string(body)}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		data.MimeType = mediaType
+		for _, pair := range strings.Split(string(body), "&") {
+			if pair == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			param := har.Param{Name: kv[0]}
+			if len(kv) == 2 {
+				param.Value = kv[1]
+			}
+			data.Params = append(data.Params, param)
+		}
+	}
+	return data
+}
+
+// harContent stores textual bodies as they are, and everything else as base64.
+func harContent(contentType string, body []byte) *har.Content {
+	content := &har.Content{Size: int64(len(body)), MimeType: contentType}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case len(body) == 0:
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "json"),
+		strings.HasSuffix(mediaType, "xml"), strings.HasSuffix(mediaType, "javascript"),
+		mediaType == "application/x-www-form-urlencoded":
+		content.MimeType = mediaType
+		content.Text = string(body)
+	default:
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
@@ -0,0 +1,202 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package recorder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == "POST" {
+				http.SetCookie(w, &http.Cookie{Name: "sid", Value: "session-1234"})
+				http.Redirect(w, r, "/account", http.StatusFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, `<form><input type="hidden" name="csrf_token" value="token-5678"></form>`)
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	})
+
+	certPEM, keyPEM, err := GenerateCA()
+	require.NoError(t, err)
+	ca, err := LoadCA(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	t.Run("HTTP", func(t *testing.T) {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		rec := New(ca)
+		proxy := httptest.NewServer(rec)
+		defer proxy.Close()
+
+		proxyURL, _ := url.Parse(proxy.URL)
+		client := &http.Client{
+			Transport:     &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+
+		req, _ := http.NewRequest("GET", srv.URL+"/login?next=%2F", nil)
+		req.Header.Set("Accept", "text/html")
+		res, err := client.Do(req)
+		require.NoError(t, err)
+		body, _ := ioutil.ReadAll(res.Body)
+		_ = res.Body.Close()
+		assert.Contains(t, string(body), "token-5678")
+
+		res, err = client.Get(srv.URL + "/logo.png")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+
+		res, err = client.Post(srv.URL+"/login", "application/x-www-form-urlencoded",
+			strings.NewReader("user=a%40b.c&csrf_token=token-5678"))
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+
+		h := rec.HAR()
+		require.Len(t, h.Log.Entries, 3)
+		require.Len(t, h.Log.Pages, 1)
+		page, logo, login := h.Log.Entries[0], h.Log.Entries[1], h.Log.Entries[2]
+
+		assert.Equal(t, srv.URL+"/login?next=%2F", page.Request.URL)
+		assert.Equal(t, "next", page.Request.QueryString[0].Name)
+		assert.Equal(t, "/", page.Request.QueryString[0].Value)
+		assert.Equal(t, "text/html", page.Response.Content.MimeType)
+		assert.Contains(t, page.Response.Content.Text, "token-5678")
+
+		assert.Equal(t, "base64", logo.Response.Content.Encoding)
+		assert.Equal(t, "iVBORw==", logo.Response.Content.Text)
+
+		assert.Equal(t, "POST", login.Request.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", login.Request.PostData.MimeType)
+		assert.Equal(t, "a%40b.c", login.Request.PostData.Params[0].Value)
+		assert.Equal(t, 302, login.Response.Status)
+		assert.Equal(t, "/account", login.Response.RedirectURL)
+		assert.Equal(t, "sid", login.Response.Cookies[0].Name)
+		for _, e := range h.Log.Entries {
+			assert.Equal(t, "page_1", e.Pageref)
+		}
+	})
+
+	t.Run("HTTPS", func(t *testing.T) {
+		srv := httptest.NewTLSServer(handler)
+		defer srv.Close()
+		rec := New(ca)
+		rec.Transport = srv.Client().Transport
+		proxy := httptest.NewServer(rec)
+		defer proxy.Close()
+
+		roots := x509.NewCertPool()
+		require.True(t, roots.AppendCertsFromPEM(certPEM))
+		proxyURL, _ := url.Parse(proxy.URL)
+		client := &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{RootCAs: roots},
+		}}
+
+		for i := 0; i < 2; i++ {
+			res, err := client.Get(srv.URL + "/")
+			require.NoError(t, err)
+			body, _ := ioutil.ReadAll(res.Body)
+			_ = res.Body.Close()
+			assert.Equal(t, "ok", string(body))
+		}
+
+		h := rec.HAR()
+		require.Len(t, h.Log.Entries, 2)
+		assert.Equal(t, srv.URL+"/", h.Log.Entries[0].Request.URL)
+		assert.Equal(t, "ok", h.Log.Entries[1].Response.Content.Text)
+	})
+
+	t.Run("tunnel", func(t *testing.T) {
+		srv := httptest.NewTLSServer(handler)
+		defer srv.Close()
+		rec := New(nil)
+		proxy := httptest.NewServer(rec)
+		defer proxy.Close()
+
+		proxyURL, _ := url.Parse(proxy.URL)
+		transport := srv.Client().Transport.(*http.Transport)
+		transport.Proxy = http.ProxyURL(proxyURL)
+		res, err := (&http.Client{Transport: transport}).Get(srv.URL + "/")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, rec.HAR().Log.Entries)
+	})
+
+	t.Run("not a proxy request", func(t *testing.T) {
+		proxy := httptest.NewServer(New(nil))
+		defer proxy.Close()
+		res, err := http.Get(proxy.URL + "/")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestCA(t *testing.T) {
+	certPEM, keyPEM, err := GenerateCA()
+	require.NoError(t, err)
+	ca, err := LoadCA(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	cert, err := ca.Certificate("example.com")
+	require.NoError(t, err)
+	again, err := ca.Certificate("example.com")
+	require.NoError(t, err)
+	assert.True(t, cert == again, "certificates are cached")
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: roots})
+	assert.NoError(t, err)
+
+	cert, err = ca.Certificate("127.0.0.1")
+	require.NoError(t, err)
+	leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", leaf.IPAddresses[0].String())
+
+	_, err = LoadCA(certPEM, []byte("garbage"))
+	assert.Error(t, err)
+}
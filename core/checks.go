@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	log "github.com/sirupsen/logrus"
+)
+
+// The threshold of the checks with a name, which have a severity and a threshold, see
+// lib.Check.SetOptions.
+type checkThreshold struct {
+	severity, threshold string
+
+	// A `checks{check:<name>}` submetric whose sink is the sum of the checks' counters.
+	metric  *stats.Metric
+	failing bool
+}
+
+// processCheckThresholds evaluates the thresholds of the checks with options. The ones of
+// errors are in e.Metrics, so that processThresholds evaluates them with the rest, and they're in
+// the summary; the ones of warnings are evaluated here, and log a warning when they start failing.
+// Checks that have thresholds in the options too are left to those.
+func (e *Engine) processCheckThresholds(t time.Duration) {
+	runner := e.Executor.GetRunner()
+	if runner == nil {
+		return
+	}
+
+	sinks := make(map[string]*stats.RateSink)
+	options := make(map[string]*checkThreshold)
+	runner.GetDefaultGroup().WalkChecks(func(c *lib.Check) {
+		severity, threshold := c.Options()
+		if severity == "" {
+			return
+		}
+		sink, ok := sinks[c.Name]
+		if !ok {
+			sink = &stats.RateSink{}
+			sinks[c.Name] = sink
+			options[c.Name] = &checkThreshold{severity: severity, threshold: threshold}
+		}
+		passes, fails := atomic.LoadInt64(&c.Passes), atomic.LoadInt64(&c.Fails)
+		sink.Trues += passes
+		sink.Total += passes + fails
+	})
+
+	for name, sink := range sinks {
+		metricName := "checks{check:" + name + "}"
+		if _, ok := e.thresholds[metricName]; ok {
+			continue
+		}
+
+		ct := e.checkThresholds[name]
+		if ct == nil || ct.severity != options[name].severity || ct.threshold != options[name].threshold {
+			thresholds, err := stats.NewThresholds([]string{options[name].threshold})
+			if err != nil {
+				e.logger.WithField("check", name).WithError(err).Error("Invalid check threshold")
+				continue
+			}
+			_, sm := stats.NewSubmetric(metricName)
+			ct = options[name]
+			ct.metric = stats.New(sm.Name, stats.Rate)
+			ct.metric.Sub = *sm
+			ct.metric.Thresholds = thresholds
+			e.checkThresholds[name] = ct
+
+			delete(e.Metrics, metricName)
+			if ct.severity == lib.CheckSeverityError {
+				e.Metrics[metricName] = ct.metric
+			}
+		}
+		ct.metric.Sink = sink
+
+		if ct.severity != lib.CheckSeverityWarn {
+			continue
+		}
+		succ, err := ct.metric.Thresholds.Run(sink, t)
+		if err != nil {
+			e.logger.WithField("check", name).WithError(err).Error("Check threshold error")
+			continue
+		}
+		if !succ && !ct.failing {
+			e.logger.WithFields(log.Fields{
+				"check": name, "threshold": ct.threshold, "rate": sink.Format(t)["rate"],
+			}).Warn("Check is failing")
+		}
+		ct.failing = !succ
+	}
+}
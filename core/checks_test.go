@@ -0,0 +1,91 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/loadimpact/k6/core/local"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_processCheckThresholds(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	inner, err := root.Group("inner")
+	require.NoError(t, err)
+	addCheck := func(g *lib.Group, name, severity, threshold string, passes, fails int64) {
+		c, err := g.Check(name)
+		require.NoError(t, err)
+		if severity != "" || threshold != "" {
+			_, err = c.SetOptions(severity, threshold)
+			require.NoError(t, err)
+		}
+		c.Passes += passes
+		c.Fails += fails
+	}
+	addCheck(root, "login", "error", "rate>0.9", 90, 0)
+	addCheck(inner, "login", "error", "rate>0.9", 5, 5)
+	addCheck(root, "cosmetic", "warn", "", 10, 1)
+	addCheck(root, "plain", "", "", 0, 10)
+	addCheck(root, "overridden", "error", "", 0, 10)
+
+	thresholds, err := stats.NewThresholds([]string{"rate>=0"})
+	require.NoError(t, err)
+	e, err, hook := newTestEngine(local.New(&lib.MiniRunner{Group: root}), lib.Options{
+		Thresholds: map[string]stats.Thresholds{"checks{check:overridden}": thresholds},
+	})
+	require.NoError(t, err)
+
+	e.processThresholds(nil)
+	assert.False(t, e.IsTainted())
+	if assert.Contains(t, e.Metrics, "checks{check:login}") {
+		m := e.Metrics["checks{check:login}"]
+		assert.Equal(t, &stats.RateSink{Trues: 95, Total: 100}, m.Sink)
+		assert.Equal(t, "login", m.Sub.Tags.CloneTags()["check"])
+		assert.False(t, m.Tainted.Bool)
+	}
+	assert.NotContains(t, e.Metrics, "checks{check:cosmetic}")
+	assert.NotContains(t, e.Metrics, "checks{check:plain}")
+	assert.NotContains(t, e.Metrics, "checks{check:overridden}")
+
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, logrus.WarnLevel, entries[0].Level)
+		assert.Equal(t, "Check is failing", entries[0].Message)
+		assert.Equal(t, "cosmetic", entries[0].Data["check"])
+	}
+
+	// The warning is only logged when the check starts failing
+	hook.Reset()
+	e.processThresholds(nil)
+	assert.Empty(t, hook.AllEntries())
+
+	// Errors fail the run
+	root.Checks["login"].Fails += 10
+	e.processThresholds(nil)
+	assert.True(t, e.IsTainted())
+	assert.True(t, e.Metrics["checks{check:login}"].Tainted.Bool)
+}
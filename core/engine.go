@@ -22,6 +22,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/loadimpact/k6/core/local"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/guregu/null.v3"
@@ -39,12 +41,25 @@ const (
 	MetricsRate     = 1 * time.Second
 	CollectRate     = 50 * time.Millisecond
 	ThresholdsRate  = 2 * time.Second
+	GuardrailsRate  = 1 * time.Second
 	ShutdownTimeout = 10 * time.Second
 
 	BackoffAmount = 50 * time.Millisecond
 	BackoffMax    = 10 * time.Second
+
+	// Defaults for options.OutputFlushInterval and options.OutputBufferSize.
+	DefaultOutputFlushInterval = 1 * time.Second
+	DefaultOutputBufferSize    = 500000
 )
 
+// The metrics whose values over time are in the summary.
+var TimeSeriesMetrics = []string{
+	metrics.VUs.Name,
+	metrics.HTTPReqs.Name,
+	metrics.HTTPReqDuration.Name,
+	metrics.IterationDuration.Name,
+}
+
 // The Engine is the beating heart of K6.
 type Engine struct {
 	runLock sync.Mutex
@@ -54,11 +69,26 @@ type Engine struct {
 	Collectors   []lib.Collector
 	NoThresholds bool
 
+	// If there are no thresholds and no summary, nothing needs the metrics, so samples aren't
+	// aggregated into them at all, and are only handed to the collectors.
+	NoSummary bool
+
+	// The names of the collectors, in the same order, for logs and the output tag of the
+	// dropped_samples metric; the collectors' types are used for the ones without one.
+	CollectorNames []string
+
 	logger *log.Logger
 
 	Metrics     map[string]*stats.Metric
 	MetricsLock sync.Mutex
 
+	// The metrics of the samples with each value of the options.SummaryBreakdown tags, by tag,
+	// value and metric name, e.g. Breakdowns["scenario"]["checkout"]["http_req_duration"].
+	Breakdowns map[string]map[string]map[string]*stats.Metric
+
+	// The values of the TimeSeriesMetrics over the course of the test.
+	TimeSeries *stats.TimeSeries
+
 	Samples chan stats.SampleContainer
 
 	// Assigned to metrics upon first received sample.
@@ -67,6 +97,17 @@ type Engine struct {
 
 	// Are thresholds tainted?
 	thresholdsTainted bool
+
+	// The thresholds of checks with options, by the checks' names.
+	checkThresholds map[string]*checkThreshold
+
+	// The resources whose guardrails are exceeded, and whether the test was aborted for it.
+	guardrailsExceeded map[string]bool
+	guardrailsAborted  bool
+
+	// Samples are handed to collectors through these, so slow ones can't hold up the engine.
+	outputs        []*outputBuffer
+	droppedWarning sync.Once
 }
 
 func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
@@ -75,13 +116,18 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 	}
 
 	e := &Engine{
-		Executor: ex,
-		Options:  o,
-		Metrics:  make(map[string]*stats.Metric),
-		Samples:  make(chan stats.SampleContainer, o.MetricSamplesBufferSize.Int64),
+		Executor:   ex,
+		Options:    o,
+		Metrics:    make(map[string]*stats.Metric),
+		TimeSeries: stats.NewTimeSeries(TimeSeriesMetrics...),
+		Samples:    make(chan stats.SampleContainer, o.MetricSamplesBufferSize.Int64),
+
+		guardrailsExceeded: make(map[string]bool),
+		checkThresholds:    make(map[string]*checkThreshold),
 	}
 	e.SetLogger(log.StandardLogger())
 
+	ex.SetPreAllocatedVUs(o.PreAllocatedVUs)
 	if err := ex.SetVUsMax(o.VUsMax.Int64); err != nil {
 		return nil, err
 	}
@@ -92,6 +138,14 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 	ex.SetStages(o.Stages)
 	ex.SetEndTime(o.Duration)
 	ex.SetEndIterations(o.Iterations)
+	ex.SetEndIterationsPerVU(o.IterationsPerVU)
+
+	if len(o.SummaryBreakdown) > 0 {
+		e.Breakdowns = make(map[string]map[string]map[string]*stats.Metric, len(o.SummaryBreakdown))
+		for _, tag := range o.SummaryBreakdown {
+			e.Breakdowns[tag] = make(map[string]map[string]*stats.Metric)
+		}
+	}
 
 	e.thresholds = o.Thresholds
 	e.submetrics = make(map[string][]*stats.Submetric)
@@ -108,12 +162,14 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 }
 
 func (e *Engine) setRunStatus(status lib.RunStatus) {
-	if len(e.Collectors) == 0 {
-		return
+	for _, output := range e.outputs {
+		output.setRunStatus(status)
 	}
+}
 
-	for _, c := range e.Collectors {
-		c.SetRunStatus(status)
+func (e *Engine) thresholdChanged(event lib.ThresholdEvent) {
+	for _, output := range e.outputs {
+		output.thresholdChanged(event)
 	}
 }
 
@@ -127,6 +183,9 @@ func (e *Engine) Run(ctx context.Context) error {
 		if st.Target.Valid {
 			fields["tgt"] = st.Target.Int64
 		}
+		if st.Rate.Valid {
+			fields["rate"] = st.Rate.Float64
+		}
 		if st.Duration.Valid {
 			fields["d"] = st.Duration.Duration
 		}
@@ -140,19 +199,46 @@ func (e *Engine) Run(ctx context.Context) error {
 	if endIter := e.Executor.GetEndIterations(); endIter.Valid {
 		fields["iter"] = endIter.Int64
 	}
+	if endIter := e.Executor.GetEndIterationsPerVU(); endIter.Valid {
+		fields["iterPerVU"] = endIter.Int64
+	}
 	e.logger.WithFields(fields).Debug(" - end conditions (if any)")
 
+	flushInterval := time.Duration(e.Options.OutputFlushInterval.Duration)
+	if !e.Options.OutputFlushInterval.Valid || flushInterval <= 0 {
+		flushInterval = DefaultOutputFlushInterval
+	}
+	bufferSize := e.Options.OutputBufferSize.Int64
+	if !e.Options.OutputBufferSize.Valid {
+		bufferSize = DefaultOutputBufferSize
+	}
 	collectorwg := sync.WaitGroup{}
 	collectorctx, collectorcancel := context.WithCancel(context.Background())
-	if len(e.Collectors) > 0 {
-		for _, collector := range e.Collectors {
-			collectorwg.Add(1)
-			go func(collector lib.Collector) {
-				collector.Run(collectorctx)
-				collectorwg.Done()
-			}(collector)
+	flushwg := sync.WaitGroup{}
+	flushctx, flushcancel := context.WithCancel(context.Background())
+	outputs := make([]*outputBuffer, len(e.Collectors))
+	for i, collector := range e.Collectors {
+		name := fmt.Sprintf("%T", collector)
+		if i < len(e.CollectorNames) && e.CollectorNames[i] != "" {
+			name = e.CollectorNames[i]
 		}
+		outputs[i] = newOutputBuffer(name, collector, e.logger, flushInterval, bufferSize)
+
+		collectorwg.Add(1)
+		go func(output *outputBuffer) {
+			output.runCollector(collectorctx)
+			collectorwg.Done()
+		}(outputs[i])
+
+		flushwg.Add(1)
+		go func(output *outputBuffer) {
+			output.run(flushctx)
+			flushwg.Done()
+		}(outputs[i])
 	}
+	e.MetricsLock.Lock()
+	e.outputs = outputs
+	e.MetricsLock.Unlock()
 
 	subctx, subcancel := context.WithCancel(context.Background())
 	subwg := sync.WaitGroup{}
@@ -175,6 +261,16 @@ func (e *Engine) Run(ctx context.Context) error {
 		}()
 	}
 
+	// Run guardrails.
+	if hasGuardrails(e.Options) {
+		subwg.Add(1)
+		go func() {
+			e.runGuardrails(subctx, subcancel)
+			e.logger.Debug("Engine: Guardrails terminated")
+			subwg.Done()
+		}()
+	}
+
 	// Run the executor.
 	errC := make(chan error)
 	subwg.Add(1)
@@ -215,7 +311,9 @@ func (e *Engine) Run(ctx context.Context) error {
 			e.processThresholds(nil)
 		}
 
-		// Finally, shut down collector.
+		// Finally, hand the collectors what's left, and shut them down.
+		flushcancel()
+		flushwg.Wait()
 		collectorcancel()
 		collectorwg.Wait()
 	}()
@@ -247,10 +345,24 @@ func (e *Engine) Run(ctx context.Context) error {
 	}
 }
 
+// SetBaseline makes the values that the metrics had in a previous run, by metric name, available
+// to their thresholds, see stats.Thresholds.SetBaseline.
+func (e *Engine) SetBaseline(metrics map[string]map[string]float64) {
+	for name := range e.thresholds {
+		ths := e.thresholds[name]
+		ths.SetBaseline(metrics[name])
+	}
+}
+
 func (e *Engine) IsTainted() bool {
 	return e.thresholdsTainted
 }
 
+// IsSaturated returns whether the test was aborted because the load generator was saturated.
+func (e *Engine) IsSaturated() bool {
+	return e.guardrailsAborted
+}
+
 func (e *Engine) SetLogger(l *log.Logger) {
 	e.logger = l
 	e.Executor.SetLogger(l)
@@ -275,25 +387,55 @@ func (e *Engine) runMetricsEmission(ctx context.Context) {
 func (e *Engine) emitMetrics() {
 	t := time.Now()
 
-	e.processSamples([]stats.SampleContainer{stats.ConnectedSamples{
-		Samples: []stats.Sample{
-			{
-				Time:   t,
-				Metric: metrics.VUs,
-				Value:  float64(e.Executor.GetVUs()),
-				Tags:   e.Options.RunTags,
-			}, {
-				Time:   t,
-				Metric: metrics.VUsMax,
-				Value:  float64(e.Executor.GetVUsMax()),
-				Tags:   e.Options.RunTags,
-			},
+	samples := []stats.Sample{
+		{
+			Time:   t,
+			Metric: metrics.VUs,
+			Value:  float64(e.Executor.GetVUs()),
+			Tags:   e.Options.RunTags,
+		}, {
+			Time:   t,
+			Metric: metrics.VUsMax,
+			Value:  float64(e.Executor.GetVUsMax()),
+			Tags:   e.Options.RunTags,
 		},
-		Tags: e.Options.RunTags,
-		Time: t,
+	}
+	for output, dropped := range e.takeDroppedSamples() {
+		e.droppedWarning.Do(func() {
+			e.logger.Warn("Some outputs can't keep up, so metric samples are being dropped; " +
+				"see the dropped_samples metric")
+		})
+		tags := e.Options.RunTags.CloneTags()
+		tags["output"] = output
+		samples = append(samples, stats.Sample{
+			Time:   t,
+			Metric: metrics.DroppedSamples,
+			Value:  float64(dropped),
+			Tags:   stats.IntoSampleTags(&tags),
+		})
+	}
+
+	e.processSamples([]stats.SampleContainer{stats.ConnectedSamples{
+		Samples: samples,
+		Tags:    e.Options.RunTags,
+		Time:    t,
 	}})
 }
 
+// takeDroppedSamples returns how many samples each output dropped since the last call, if any.
+func (e *Engine) takeDroppedSamples() map[string]int64 {
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	dropped := make(map[string]int64)
+	for _, output := range e.outputs {
+		if n := output.takeDropped(); n > 0 {
+			dropped[output.name] += n
+		}
+	}
+	return dropped
+}
+
 func (e *Engine) runThresholds(ctx context.Context, abort func()) {
 	ticker := time.NewTicker(ThresholdsRate)
 	for {
@@ -313,6 +455,8 @@ func (e *Engine) processThresholds(abort func()) {
 	t := e.Executor.GetTime()
 	abortOnFail := false
 
+	e.processCheckThresholds(t)
+
 	e.thresholdsTainted = false
 	for _, m := range e.Metrics {
 		if len(m.Thresholds.Thresholds) == 0 {
@@ -321,11 +465,37 @@ func (e *Engine) processThresholds(abort func()) {
 		m.Tainted = null.BoolFrom(false)
 
 		e.logger.WithField("m", m.Name).Debug("running thresholds")
+		m.Thresholds.SetMetrics(e.Metrics, t)
+		breached := make([]bool, len(m.Thresholds.Thresholds))
+		failed := make([]bool, len(m.Thresholds.Thresholds))
+		for i, th := range m.Thresholds.Thresholds {
+			breached[i], failed[i] = th.Breached, th.Failed
+		}
 		succ, err := m.Thresholds.Run(m.Sink, t)
 		if err != nil {
 			e.logger.WithField("m", m.Name).WithError(err).Error("Threshold error")
 			continue
 		}
+		for i, th := range m.Thresholds.Thresholds {
+			if th.Window == 0 {
+				if th.Failed && !failed[i] {
+					e.thresholdChanged(lib.ThresholdEvent{Metric: m.Name, Threshold: th.Source, Breached: true, Time: types.Duration(t)})
+				}
+				continue
+			}
+			if th.Breached == breached[i] {
+				continue
+			}
+			logger := e.logger.WithFields(log.Fields{
+				"metric": m.Name, "threshold": th.Source, "window": th.Window.String(), "t": t,
+			})
+			if th.Breached {
+				logger.Warn("Threshold breached")
+			} else {
+				logger.Info("Threshold recovered")
+			}
+			e.thresholdChanged(lib.ThresholdEvent{Metric: m.Name, Threshold: th.Source, Breached: th.Breached, Time: types.Duration(t)})
+		}
 		if !succ {
 			e.logger.WithField("m", m.Name).Debug("Thresholds failed")
 			m.Tainted = null.BoolFrom(true)
@@ -343,6 +513,27 @@ func (e *Engine) processThresholds(abort func()) {
 	}
 }
 
+// addToBreakdowns aggregates a sample into the breakdown metrics of its tags' values, if any.
+func (e *Engine) addToBreakdowns(sample stats.Sample) {
+	for tag, values := range e.Breakdowns {
+		value, ok := sample.Tags.Get(tag)
+		if !ok || value == "" {
+			continue
+		}
+		metrics, ok := values[value]
+		if !ok {
+			metrics = make(map[string]*stats.Metric)
+			values[value] = metrics
+		}
+		m, ok := metrics[sample.Metric.Name]
+		if !ok {
+			m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+			metrics[m.Name] = m
+		}
+		m.Sink.Add(sample)
+	}
+}
+
 func (e *Engine) processSamples(sampleCointainers []stats.SampleContainer) {
 	if len(sampleCointainers) == 0 {
 		return
@@ -351,6 +542,13 @@ func (e *Engine) processSamples(sampleCointainers []stats.SampleContainer) {
 	e.MetricsLock.Lock()
 	defer e.MetricsLock.Unlock()
 
+	if e.NoThresholds && e.NoSummary {
+		for _, output := range e.outputs {
+			output.add(sampleCointainers)
+		}
+		return
+	}
+
 	for _, sampleCointainer := range sampleCointainers {
 		samples := sampleCointainer.GetSamples()
 
@@ -367,6 +565,7 @@ func (e *Engine) processSamples(sampleCointainers []stats.SampleContainer) {
 				e.Metrics[m.Name] = m
 			}
 			m.Sink.Add(sample)
+			m.Thresholds.AddSample(sample)
 
 			for _, sm := range m.Submetrics {
 				if !sample.Tags.Contains(sm.Tags) {
@@ -380,12 +579,14 @@ func (e *Engine) processSamples(sampleCointainers []stats.SampleContainer) {
 					e.Metrics[sm.Name] = sm.Metric
 				}
 				sm.Metric.Sink.Add(sample)
+				sm.Metric.Thresholds.AddSample(sample)
 			}
+
+			e.addToBreakdowns(sample)
+			e.TimeSeries.Add(sample)
 		}
 	}
-	if len(e.Collectors) > 0 {
-		for _, collector := range e.Collectors {
-			collector.Collect(sampleCointainers)
-		}
+	for _, output := range e.outputs {
+		output.add(sampleCointainers)
 	}
 }
@@ -23,6 +23,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -329,7 +330,7 @@ func TestEngineCollector(t *testing.T) {
 		sink := metric.Sink.(*stats.TrendSink)
 		if assert.NotNil(t, sink) {
 			numCollectorSamples := len(cSamples)
-			numEngineSamples := len(sink.Values)
+			numEngineSamples := int(sink.Count)
 			assert.Equal(t, numEngineSamples, numCollectorSamples)
 		}
 	}
@@ -371,6 +372,61 @@ func TestEngine_processSamples(t *testing.T) {
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric"].Sink)
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric{a:1}"].Sink)
 	})
+	t.Run("no thresholds and no summary", func(t *testing.T) {
+		e, err, _ := newTestEngine(nil, lib.Options{})
+		assert.NoError(t, err)
+		e.NoThresholds = true
+		e.NoSummary = true
+
+		e.processSamples(
+			[]stats.SampleContainer{stats.Sample{Metric: metric, Value: 1.25, Tags: stats.IntoSampleTags(&map[string]string{"a": "1"})}},
+		)
+
+		assert.Empty(t, e.Metrics)
+	})
+	t.Run("breakdown", func(t *testing.T) {
+		e, err, _ := newTestEngine(nil, lib.Options{SummaryBreakdown: []string{"scenario", "group"}})
+		assert.NoError(t, err)
+
+		trend := stats.New("my_trend", stats.Trend)
+		e.processSamples([]stats.SampleContainer{
+			stats.Sample{Metric: trend, Value: 1, Tags: stats.IntoSampleTags(&map[string]string{"scenario": "browse", "group": ""})},
+			stats.Sample{Metric: trend, Value: 2, Tags: stats.IntoSampleTags(&map[string]string{"scenario": "checkout", "group": "::pay"})},
+			stats.Sample{Metric: trend, Value: 4, Tags: stats.IntoSampleTags(&map[string]string{"scenario": "checkout", "group": "::pay"})},
+			stats.Sample{Metric: metric, Value: 8, Tags: stats.IntoSampleTags(&map[string]string{"a": "1"})},
+		})
+
+		assert.Equal(t, uint64(3), e.Metrics["my_trend"].Sink.(*stats.TrendSink).Count)
+		scenarios := e.Breakdowns["scenario"]
+		assert.Len(t, scenarios, 2)
+		assert.Equal(t, uint64(1), scenarios["browse"]["my_trend"].Sink.(*stats.TrendSink).Count)
+		assert.Equal(t, 6.0, scenarios["checkout"]["my_trend"].Sink.(*stats.TrendSink).Sum)
+		assert.Len(t, scenarios["checkout"], 1)
+
+		// The root group's samples are already in the metrics themselves
+		groups := e.Breakdowns["group"]
+		assert.Len(t, groups, 1)
+		assert.Equal(t, uint64(2), groups["::pay"]["my_trend"].Sink.(*stats.TrendSink).Count)
+	})
+	t.Run("time series", func(t *testing.T) {
+		e, err, _ := newTestEngine(nil, lib.Options{})
+		assert.NoError(t, err)
+
+		now := time.Now()
+		e.processSamples([]stats.SampleContainer{
+			stats.Sample{Metric: metrics.VUs, Time: now, Value: 5},
+			stats.Sample{Metric: metrics.VUs, Time: now.Add(2 * time.Second), Value: 10},
+			stats.Sample{Metric: metric, Time: now, Value: 1},
+		})
+
+		lines := e.TimeSeries.Lines("vus")
+		if assert.Len(t, lines, 1) && assert.Len(t, lines[0].Values, 3) {
+			assert.Equal(t, 5.0, lines[0].Values[0])
+			assert.True(t, math.IsNaN(lines[0].Values[1]))
+			assert.Equal(t, 10.0, lines[0].Values[2])
+		}
+		assert.NotContains(t, e.TimeSeries.Buckets, "my_metric")
+	})
 }
 
 func TestEngine_runThresholds(t *testing.T) {
@@ -447,6 +503,9 @@ func TestEngine_processThresholds(t *testing.T) {
 		"submetric,match,failing":   {false, map[string][]string{"my_metric{a:1}": {"1+1==3"}}, false},
 		"submetric,nomatch,passing": {true, map[string][]string{"my_metric{a:2}": {"1+1==2"}}, false},
 		"submetric,nomatch,failing": {true, map[string][]string{"my_metric{a:2}": {"1+1==3"}}, false},
+
+		"multiple metrics,passing": {true, map[string][]string{"my_metric{a:1}": {"value - metric('my_metric').value == 0"}}, false},
+		"multiple metrics,failing": {false, map[string][]string{"my_metric": {"metric('my_metric{a:2}').value > 0"}}, false},
 	}
 
 	for name, data := range testdata {
@@ -816,3 +875,22 @@ func TestSetupTeardownThresholds(t *testing.T) {
 		require.False(t, engine.IsTainted())
 	}
 }
+
+func TestEngineThresholdEvents(t *testing.T) {
+	metric := stats.New("my_metric", stats.Gauge)
+	ths, err := stats.NewThresholds([]string{"value < 2", "value < 5"})
+	require.NoError(t, err)
+	e, err, _ := newTestEngine(nil, lib.Options{Thresholds: map[string]stats.Thresholds{"my_metric": ths}})
+	require.NoError(t, err)
+	c := &dummy.Collector{}
+	e.outputs = []*outputBuffer{newOutputBuffer("dummy", c, e.logger, time.Second, 0)}
+
+	for _, value := range []float64{1, 3, 4, 1} {
+		e.processSamples([]stats.SampleContainer{stats.Sample{Metric: metric, Value: value}})
+		e.processThresholds(nil)
+	}
+	// A threshold without a window fails once, for good
+	assert.Equal(t, []lib.ThresholdEvent{
+		{Metric: "my_metric", Threshold: "value < 2", Breached: true},
+	}, c.ThresholdEvents)
+}
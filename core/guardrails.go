@@ -0,0 +1,150 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"gopkg.in/guregu/null.v3"
+)
+
+// The load generator's own usage of resources; a saturated generator delays its VUs, so the
+// latencies they measure are bogus.
+type resourceUsage struct {
+	CPU             float64 // % of all CPUs, since the previous measurement; -1 if unknown
+	Memory          int64   // MB that the Go runtime got from the OS
+	FileDescriptors float64 // % of the process's limit; -1 if unknown
+}
+
+// A usageMonitor measures the resource usage of the process, with the CPU usage between calls.
+type usageMonitor struct {
+	lastCPU  time.Duration
+	lastTime time.Time
+}
+
+func (m *usageMonitor) measure() resourceUsage {
+	usage := resourceUsage{CPU: -1, FileDescriptors: -1}
+
+	now := time.Now()
+	if cpu, ok := processCPUTime(); ok {
+		if !m.lastTime.IsZero() {
+			wall := now.Sub(m.lastTime) * time.Duration(runtime.NumCPU())
+			if wall > 0 {
+				usage.CPU = 100 * float64(cpu-m.lastCPU) / float64(wall)
+			}
+		}
+		m.lastCPU, m.lastTime = cpu, now
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	usage.Memory = int64(mem.Sys / (1024 * 1024))
+
+	if open, limit, ok := openFileDescriptors(); ok && limit > 0 {
+		usage.FileDescriptors = 100 * float64(open) / float64(limit)
+	}
+	return usage
+}
+
+// exceededGuardrails returns the guardrails that the usage exceeds, as descriptions by resource.
+func exceededGuardrails(o lib.Options, usage resourceUsage) map[string]string {
+	exceeded := make(map[string]string)
+	if o.GuardrailCPU.Valid && usage.CPU > o.GuardrailCPU.Float64 {
+		exceeded["cpu"] = fmt.Sprintf("CPU usage is %.1f%%, above %g%%", usage.CPU, o.GuardrailCPU.Float64)
+	}
+	if o.GuardrailMemory.Valid && usage.Memory > o.GuardrailMemory.Int64 {
+		exceeded["memory"] = fmt.Sprintf("memory usage is %dMB, above %dMB", usage.Memory, o.GuardrailMemory.Int64)
+	}
+	if fds := o.GuardrailFileDescriptors; fds.Valid && usage.FileDescriptors > fds.Float64 {
+		exceeded["fileDescriptors"] = fmt.Sprintf("%.1f%% of the file descriptors are open, above %g%%",
+			usage.FileDescriptors, fds.Float64)
+	}
+	return exceeded
+}
+
+func hasGuardrails(o lib.Options) bool {
+	return o.GuardrailCPU.Valid || o.GuardrailMemory.Valid || o.GuardrailFileDescriptors.Valid
+}
+
+func (e *Engine) runGuardrails(ctx context.Context, abort func()) {
+	monitor := &usageMonitor{}
+	monitor.measure()
+
+	ticker := time.NewTicker(GuardrailsRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if e.processGuardrails(monitor.measure(), abort) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processGuardrails warns about newly exceeded guardrails and acts on them, and returns whether
+// the test was aborted.
+func (e *Engine) processGuardrails(usage resourceUsage, abort func()) bool {
+	exceeded := exceededGuardrails(e.Options, usage)
+	var fresh []string
+	for resource, msg := range exceeded {
+		if !e.guardrailsExceeded[resource] {
+			fresh = append(fresh, msg)
+		}
+	}
+	for resource := range e.guardrailsExceeded {
+		if _, ok := exceeded[resource]; !ok {
+			delete(e.guardrailsExceeded, resource)
+		}
+	}
+	if len(fresh) == 0 {
+		return false
+	}
+	for resource := range exceeded {
+		e.guardrailsExceeded[resource] = true
+	}
+
+	msg := "The load generator is saturated: " + strings.Join(fresh, ", ")
+	switch e.Options.GuardrailAction.String {
+	case "abort":
+		e.logger.Error(msg + "; aborting the test, since the latencies it measures can't be trusted")
+		e.guardrailsAborted = true
+		e.setRunStatus(lib.RunStatusAbortedSystem)
+		abort()
+		return true
+	case "cap":
+		vus := e.Executor.GetVUs()
+		if ceil := e.Executor.GetVUsCeiling(); !ceil.Valid || ceil.Int64 > vus {
+			e.Executor.SetVUsCeiling(null.IntFrom(vus))
+		}
+		e.logger.WithField("vus", vus).Warn(msg + "; no more VUs will be added")
+	default:
+		e.logger.Warn(msg + "; the latencies it measures may be too high")
+	}
+	return false
+}
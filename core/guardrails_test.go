@@ -0,0 +1,95 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestUsageMonitor(t *testing.T) {
+	m := &usageMonitor{}
+	usage := m.measure()
+	assert.Equal(t, -1.0, usage.CPU, "the first measurement has no CPU usage")
+	assert.True(t, usage.Memory > 0)
+
+	usage = m.measure()
+	if runtime.GOOS != "windows" {
+		assert.True(t, usage.CPU >= 0)
+		assert.True(t, usage.FileDescriptors > 0 && usage.FileDescriptors <= 100, usage.FileDescriptors)
+	}
+}
+
+func TestEngine_processGuardrails(t *testing.T) {
+	saturated := resourceUsage{CPU: 95, Memory: 100, FileDescriptors: 10}
+	opts := lib.Options{
+		VUs:          null.IntFrom(5),
+		VUsMax:       null.IntFrom(10),
+		GuardrailCPU: null.FloatFrom(90),
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		e, err, hook := newTestEngine(nil, opts)
+		require.NoError(t, err)
+		aborted := false
+		assert.False(t, e.processGuardrails(saturated, func() { aborted = true }))
+		assert.False(t, e.processGuardrails(saturated, func() { aborted = true }))
+		assert.False(t, aborted)
+		if assert.Len(t, hook.Entries, 1, "only newly exceeded guardrails are logged") {
+			assert.Contains(t, hook.Entries[0].Message, "CPU usage is 95.0%, above 90%")
+		}
+
+		// Once the usage drops, it's logged again when it's exceeded again
+		e.processGuardrails(resourceUsage{CPU: 50}, nil)
+		e.processGuardrails(saturated, nil)
+		assert.Len(t, hook.Entries, 2)
+	})
+
+	t.Run("cap", func(t *testing.T) {
+		opts := opts
+		opts.GuardrailAction = null.StringFrom("cap")
+		e, err, _ := newTestEngine(nil, opts)
+		require.NoError(t, err)
+		assert.False(t, e.processGuardrails(saturated, nil))
+		assert.Equal(t, null.IntFrom(5), e.Executor.GetVUsCeiling())
+		assert.NoError(t, e.Executor.SetVUs(10))
+		assert.Equal(t, int64(5), e.Executor.GetVUs())
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		opts := opts
+		opts.GuardrailAction = null.StringFrom("abort")
+		opts.GuardrailMemory = null.IntFrom(1000)
+		e, err, _ := newTestEngine(nil, opts)
+		require.NoError(t, err)
+		aborted := false
+		assert.False(t, e.processGuardrails(resourceUsage{CPU: 10, Memory: 500}, func() { aborted = true }))
+		assert.False(t, e.IsSaturated())
+		assert.True(t, e.processGuardrails(saturated, func() { aborted = true }))
+		assert.True(t, aborted)
+		assert.True(t, e.IsSaturated())
+	})
+}
@@ -23,6 +23,8 @@ package local
 import (
 	"context"
 	"fmt"
+	"math"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -51,12 +53,13 @@ type vuHandle struct {
 	cancel context.CancelFunc
 }
 
-func (h *vuHandle) run(logger *log.Logger, flow <-chan int64, iterDone chan<- struct{}) {
+// run runs iterations whenever the flow allows it, and stops after the limit if it's not negative.
+func (h *vuHandle) run(logger *log.Logger, flow <-chan int64, iterDone chan<- struct{}, limit int64) {
 	h.RLock()
 	ctx := h.ctx
 	h.RUnlock()
 
-	for {
+	for done := int64(0); limit < 0 || done < limit; done++ {
 		select {
 		case _, ok := <-flow:
 			if !ok {
@@ -100,10 +103,17 @@ type Executor struct {
 	numVUs    int64
 	numVUsMax int64
 	nextVUID  int64
+	vusCeil   int64 // Higher VU counts are capped to this, unless it's negative
+
+	// How many of the VUs are initialized when the max is raised, all of them if it's negative;
+	// the others are initialized when the test first needs them. VUs that are stopped are kept,
+	// and reused when more are needed again.
+	preAllocatedVUs int64
 
-	iters     int64 // Completed iterations
-	partIters int64 // Partial, incomplete iterations
-	endIters  int64 // End test at this many iterations
+	iters         int64 // Completed iterations
+	partIters     int64 // Partial, incomplete iterations
+	endIters      int64 // End test at this many iterations
+	endItersPerVU int64 // Each VU stops after this many iterations, the test when all do
 
 	time    int64 // Current time
 	endTime int64 // End test at this timestamp
@@ -136,14 +146,18 @@ func New(r lib.Runner) *Executor {
 	}
 
 	return &Executor{
-		Runner:      r,
-		Logger:      log.StandardLogger(),
-		runSetup:    true,
-		runTeardown: true,
-		endIters:    -1,
-		endTime:     -1,
-		vuOut:       make(chan stats.SampleContainer, bufferSize),
-		iterDone:    make(chan struct{}),
+		Runner:        r,
+		Logger:        log.StandardLogger(),
+		runSetup:      true,
+		runTeardown:   true,
+		endIters:      -1,
+		endItersPerVU: -1,
+		endTime:       -1,
+		vusCeil:       -1,
+		vuOut:         make(chan stats.SampleContainer, bufferSize),
+		iterDone:      make(chan struct{}),
+
+		preAllocatedVUs: -1,
 	}
 }
 
@@ -227,6 +241,11 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 	ticker := time.NewTicker(1 * time.Millisecond)
 	defer ticker.Stop()
 
+	// If the stages have rates, iterations are only started when they're due, and those that are
+	// still due at the next tick, because no VU was free to run them, are dropped.
+	rateMode := e.stages != nil && ProcessStageRates(e.stages, 0).Valid
+	due := 0.0
+
 	lastTick := time.Now()
 	for {
 		// If the test is paused, sleep until either the pause or the test ends.
@@ -256,11 +275,17 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 		if end >= 0 && partials >= end {
 			flow = nil
 		}
+		if rateMode && due < 1 {
+			flow = nil
+		}
 
 		select {
 		case flow <- partials:
 			// Start an iteration if there's a VU waiting. See also: the big comment block above.
 			atomic.AddInt64(&e.partIters, 1)
+			if rateMode {
+				due--
+			}
 		case t := <-ticker.C:
 			// Every tick, increment the clock, see if we passed the end point, and process stages.
 			// If the test ends this way, set a cutoff point; any samples collected past the cutoff
@@ -289,6 +314,20 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 						return err
 					}
 				}
+
+				rate := ProcessStageRates(stages, at)
+				rateMode = rate.Valid
+				if rateMode {
+					if dropped := math.Floor(due); dropped > 0 {
+						due -= dropped
+						var tags *stats.SampleTags
+						if e.Runner != nil {
+							tags = e.Runner.GetOptions().RunTags
+						}
+						engineOut <- stats.Sample{Time: t, Metric: metrics.DroppedIterations, Value: dropped, Tags: tags}
+					}
+					due += rate.Float64 * d.Seconds()
+				}
 			}
 		case sampleContainer := <-vuOut:
 			engineOut <- sampleContainer
@@ -312,6 +351,11 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 				e.Logger.WithFields(log.Fields{"at": at, "end": end}).Debug("Local: Hit iteration limit")
 				return nil
 			}
+			perVU := atomic.LoadInt64(&e.endItersPerVU)
+			if perVU >= 0 && at >= perVU*atomic.LoadInt64(&e.numVUs) {
+				e.Logger.WithFields(log.Fields{"at": at, "perVU": perVU}).Debug("Local: All VUs are done")
+				return nil
+			}
 		case <-ctx.Done():
 			// If the test is cancelled, just set the cutoff point to now and proceed down the same
 			// logic as if the time limit was hit.
@@ -332,6 +376,12 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 	flow := e.flow
 	iterDone := e.iterDone
 	e.lock.RUnlock()
+	limit := atomic.LoadInt64(&e.endItersPerVU)
+
+	// Initialize the VUs that weren't preallocated, if they're needed now
+	if err := e.initVUs(e.vus[:lib.Min(num, int64(len(e.vus)))]); err != nil {
+		return err
+	}
 
 	for i, handle := range e.vus {
 		handle := handle
@@ -355,7 +405,7 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 
 				e.wg.Add(1)
 				go func() {
-					handle.run(e.Logger, flow, iterDone)
+					handle.run(e.Logger, flow, iterDone, limit)
 					e.wg.Done()
 				}()
 			}
@@ -417,6 +467,22 @@ func (e *Executor) SetEndIterations(i null.Int) {
 	atomic.StoreInt64(&e.endIters, i.Int64)
 }
 
+func (e *Executor) GetEndIterationsPerVU() null.Int {
+	v := atomic.LoadInt64(&e.endItersPerVU)
+	if v < 0 {
+		return null.Int{}
+	}
+	return null.IntFrom(v)
+}
+
+func (e *Executor) SetEndIterationsPerVU(i null.Int) {
+	if !i.Valid {
+		i.Int64 = -1
+	}
+	e.Logger.WithField("i", i.Int64).Debug("Local: Setting end iterations per VU")
+	atomic.StoreInt64(&e.endItersPerVU, i.Int64)
+}
+
 func (e *Executor) GetTime() time.Duration {
 	return time.Duration(atomic.LoadInt64(&e.time))
 }
@@ -465,6 +531,12 @@ func (e *Executor) SetVUs(num int64) error {
 		return errors.New("vu count can't be negative")
 	}
 
+	if ceil := atomic.LoadInt64(&e.vusCeil); ceil >= 0 && num > ceil {
+		e.Logger.WithFields(log.Fields{"vus": num, "ceiling": ceil}).Debug("Local: Capping VUs")
+		// VUs above the ceiling stay, but aren't joined by more
+		num = lib.Max(ceil, lib.Min(num, atomic.LoadInt64(&e.numVUs)))
+	}
+
 	if atomic.LoadInt64(&e.numVUs) == num {
 		return nil
 	}
@@ -486,6 +558,22 @@ func (e *Executor) SetVUs(num int64) error {
 	return nil
 }
 
+func (e *Executor) GetVUsCeiling() null.Int {
+	v := atomic.LoadInt64(&e.vusCeil)
+	if v < 0 {
+		return null.Int{}
+	}
+	return null.IntFrom(v)
+}
+
+func (e *Executor) SetVUsCeiling(c null.Int) {
+	if !c.Valid {
+		c.Int64 = -1
+	}
+	e.Logger.WithField("ceiling", c.Int64).Debug("Local: Setting the VU ceiling")
+	atomic.StoreInt64(&e.vusCeil, c.Int64)
+}
+
 func (e *Executor) GetVUsMax() int64 {
 	return atomic.LoadInt64(&e.numVUsMax)
 }
@@ -512,32 +600,77 @@ func (e *Executor) SetVUsMax(max int64) error {
 		return nil
 	}
 
-	e.lock.RLock()
-	vuOut := e.vuOut
-	e.lock.RUnlock()
-
 	e.vusLock.Lock()
 	defer e.vusLock.Unlock()
 
-	vus := e.vus
-	for i := numVUsMax; i < max; i++ {
-		var handle vuHandle
-		if e.Runner != nil {
-			vu, err := e.Runner.NewVU(vuOut)
-			if err != nil {
-				return err
-			}
-			handle.vu = vu
-		}
-		vus = append(vus, &handle)
+	// Only the preallocated VUs are initialized now, the others when they're first needed
+	handles := make([]*vuHandle, max-numVUsMax)
+	for i := range handles {
+		handles[i] = &vuHandle{}
+	}
+	preallocated := handles
+	if pre := atomic.LoadInt64(&e.preAllocatedVUs); pre >= 0 {
+		preallocated = handles[:lib.Max(0, lib.Min(pre-numVUsMax, int64(len(handles))))]
 	}
-	e.vus = vus
+	if err := e.initVUs(preallocated); err != nil {
+		return err
+	}
+	e.vus = append(e.vus, handles...)
 
 	atomic.StoreInt64(&e.numVUsMax, max)
 
 	return nil
 }
 
+func (e *Executor) GetPreAllocatedVUs() null.Int {
+	v := atomic.LoadInt64(&e.preAllocatedVUs)
+	if v < 0 {
+		return null.Int{}
+	}
+	return null.IntFrom(v)
+}
+
+func (e *Executor) SetPreAllocatedVUs(n null.Int) {
+	if !n.Valid {
+		n.Int64 = -1
+	}
+	atomic.StoreInt64(&e.preAllocatedVUs, n.Int64)
+}
+
+// initVUs gives the handles that don't have a VU yet a new one. Initializing VUs is CPU-bound,
+// mostly running their init code, so it's done in parallel.
+func (e *Executor) initVUs(handles []*vuHandle) error {
+	if e.Runner == nil {
+		return nil
+	}
+
+	e.lock.RLock()
+	vuOut := e.vuOut
+	e.lock.RUnlock()
+
+	errs := make([]error, len(handles))
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, handle := range handles {
+		if handle.vu != nil {
+			continue
+		}
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(i int, handle *vuHandle) {
+			defer func() { <-workers; wg.Done() }()
+			handle.vu, errs[i] = e.Runner.NewVU(vuOut)
+		}(i, handle)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *Executor) SetRunSetup(r bool) {
 	e.runSetup = r
 }
@@ -186,6 +186,68 @@ func TestExecutorStages(t *testing.T) {
 	}
 }
 
+func TestExecutorStageRates(t *testing.T) {
+	var iters int64
+	e := New(&lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			atomic.AddInt64(&iters, 1)
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+		Options: lib.Options{
+			MetricSamplesBufferSize: null.IntFrom(500),
+		},
+	})
+	assert.NoError(t, e.SetVUsMax(5))
+	assert.NoError(t, e.SetVUs(5))
+	e.SetStages([]lib.Stage{
+		{Duration: types.NullDurationFrom(0), Rate: null.FloatFrom(100)},
+		{Duration: types.NullDurationFrom(1 * time.Second), Rate: null.FloatFrom(300)},
+	})
+
+	samples := make(chan stats.SampleContainer, 1000)
+	assert.NoError(t, e.Run(context.Background(), samples))
+	close(samples)
+
+	// 200 iterations are due on average, with 5 VUs that can run up to 500/s
+	assert.InDelta(t, 200, atomic.LoadInt64(&iters), 30)
+	for sc := range samples {
+		for _, sample := range sc.GetSamples() {
+			assert.NotEqual(t, metrics.DroppedIterations, sample.Metric)
+		}
+	}
+
+	t.Run("dropped", func(t *testing.T) {
+		e := New(&lib.MiniRunner{
+			Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			},
+			Options: lib.Options{
+				MetricSamplesBufferSize: null.IntFrom(500),
+			},
+		})
+		assert.NoError(t, e.SetVUsMax(1))
+		assert.NoError(t, e.SetVUs(1))
+		e.SetStages([]lib.Stage{{Duration: types.NullDurationFrom(500 * time.Millisecond), Rate: null.FloatFrom(100)}})
+
+		samples := make(chan stats.SampleContainer, 1000)
+		assert.NoError(t, e.Run(context.Background(), samples))
+		close(samples)
+
+		dropped := 0.0
+		for sc := range samples {
+			for _, sample := range sc.GetSamples() {
+				if sample.Metric == metrics.DroppedIterations {
+					dropped += sample.Value
+				}
+			}
+		}
+		// 25 iterations are due, and the only VU can run at most 5 of them
+		assert.InDelta(t, 20, dropped, 3)
+	})
+}
+
 func TestExecutorEndTime(t *testing.T) {
 	e := New(&lib.MiniRunner{
 		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
@@ -288,6 +350,41 @@ func TestExecutorEndIterations(t *testing.T) {
 	}
 }
 
+func TestExecutorEndIterationsPerVU(t *testing.T) {
+	r, err := js.New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+		import { sleep } from "k6";
+		export let options = { systemTags: ["vu"] };
+		export default function() { sleep(__VU === 1 ? 0 : 0.01); }
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	e := New(r)
+	assert.NoError(t, e.SetVUsMax(3))
+	assert.NoError(t, e.SetVUs(3))
+	e.SetEndIterationsPerVU(null.IntFrom(5))
+	assert.Equal(t, null.IntFrom(5), e.GetEndIterationsPerVU())
+	assert.Equal(t, null.Int{}, e.GetEndIterations())
+
+	samples := make(chan stats.SampleContainer, 1000)
+	assert.NoError(t, e.Run(context.Background(), samples))
+	close(samples)
+	assert.Equal(t, int64(15), e.GetIterations())
+
+	// Even though VU 1 is much faster, it doesn't run the iterations of the others
+	perVU := make(map[string]int)
+	for sc := range samples {
+		for _, sample := range sc.GetSamples() {
+			if sample.Metric == metrics.IterationDuration {
+				perVU[sample.Tags.CloneTags()["vu"]]++
+			}
+		}
+	}
+	assert.Equal(t, map[string]int{"1": 5, "2": 5, "3": 5}, perVU)
+}
+
 func TestExecutorIsRunning(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	e := New(nil)
@@ -322,6 +419,15 @@ func TestExecutorSetVUsMax(t *testing.T) {
 		})
 	})
 
+	t.Run("Runner", func(t *testing.T) {
+		e := New(&lib.MiniRunner{})
+		assert.NoError(t, e.SetVUsMax(100))
+		assert.Len(t, e.vus, 100)
+		for _, handle := range e.vus {
+			assert.NotNil(t, handle.vu)
+		}
+	})
+
 	t.Run("TooLow", func(t *testing.T) {
 		e := New(nil)
 		e.ctx = context.Background()
@@ -430,6 +536,62 @@ func TestExecutorSetVUs(t *testing.T) {
 	})
 }
 
+func TestExecutorPreAllocatedVUs(t *testing.T) {
+	e := New(&lib.MiniRunner{})
+	e.ctx = context.Background()
+	assert.Equal(t, null.Int{}, e.GetPreAllocatedVUs())
+	e.SetPreAllocatedVUs(null.IntFrom(10))
+	assert.Equal(t, null.IntFrom(10), e.GetPreAllocatedVUs())
+
+	initialized := func() (num int) {
+		for _, handle := range e.vus {
+			if handle.vu != nil {
+				num++
+			}
+		}
+		return num
+	}
+
+	assert.NoError(t, e.SetVUsMax(100))
+	assert.Equal(t, int64(100), e.GetVUsMax())
+	assert.Len(t, e.vus, 100)
+	assert.Equal(t, 10, initialized())
+
+	assert.NoError(t, e.SetVUs(50))
+	assert.Equal(t, 50, initialized())
+	first := e.vus[0].vu
+
+	// Stopped VUs are reused, with a new ID
+	assert.NoError(t, e.SetVUs(20))
+	assert.NoError(t, e.SetVUs(60))
+	assert.Equal(t, 60, initialized())
+	assert.True(t, first == e.vus[0].vu)
+	assert.Equal(t, int64(80), e.vus[49].vu.(*lib.MiniRunnerVU).ID)
+}
+
+func TestExecutorSetVUsCeiling(t *testing.T) {
+	e := New(nil)
+	assert.False(t, e.GetVUsCeiling().Valid)
+	assert.NoError(t, e.SetVUsMax(100))
+	assert.NoError(t, e.SetVUs(30))
+
+	e.SetVUsCeiling(null.IntFrom(40))
+	assert.Equal(t, null.IntFrom(40), e.GetVUsCeiling())
+	assert.NoError(t, e.SetVUs(60))
+	assert.Equal(t, int64(40), e.GetVUs())
+
+	// VUs above the ceiling aren't removed, but can still be scaled down
+	e.SetVUsCeiling(null.IntFrom(20))
+	assert.NoError(t, e.SetVUs(60))
+	assert.Equal(t, int64(40), e.GetVUs())
+	assert.NoError(t, e.SetVUs(10))
+	assert.Equal(t, int64(10), e.GetVUs())
+
+	e.SetVUsCeiling(null.Int{})
+	assert.NoError(t, e.SetVUs(60))
+	assert.Equal(t, int64(60), e.GetVUs())
+}
+
 func TestRealTimeAndSetupTeardownMetrics(t *testing.T) {
 	t.Parallel()
 	script := []byte(`
@@ -62,3 +62,36 @@ func ProcessStages(startVUs int64, stages []lib.Stage, t time.Duration) (null.In
 	}
 	return vus, false
 }
+
+// ProcessStageRates returns the rate at which iterations should be started at the specified time,
+// which isn't Valid if none of the stages up to then have a rate.
+func ProcessStageRates(stages []lib.Stage, t time.Duration) null.Float {
+	var rate null.Float
+
+	var start time.Duration
+	for _, stage := range stages {
+		if !stage.Duration.Valid {
+			if stage.Rate.Valid {
+				rate = stage.Rate
+			}
+			return rate
+		}
+
+		end := start + time.Duration(stage.Duration.Duration)
+		if end <= t {
+			if stage.Rate.Valid {
+				rate = stage.Rate
+			}
+			start = end
+			continue
+		}
+
+		// Interpolate from the rate at the end of the previous stage, or 0 for the first one.
+		if stage.Rate.Valid {
+			prog := lib.Clampf(float64(t-start)/float64(stage.Duration.Duration), 0.0, 1.0)
+			rate = null.FloatFrom(rate.Float64 + (stage.Rate.Float64-rate.Float64)*prog)
+		}
+		return rate
+	}
+	return rate
+}
@@ -291,3 +291,55 @@ func TestProcessStages(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessStageRates(t *testing.T) {
+	d := types.NullDurationFrom
+	testdata := map[string]struct {
+		Stages      []lib.Stage
+		Checkpoints map[time.Duration]null.Float
+	}{
+		"none": {
+			[]lib.Stage{{Duration: d(10 * time.Second), Target: null.IntFrom(10)}},
+			map[time.Duration]null.Float{0: {}, 5 * time.Second: {}, 20 * time.Second: {}},
+		},
+		"ramp from 0": {
+			[]lib.Stage{{Duration: d(10 * time.Second), Rate: null.FloatFrom(100)}},
+			map[time.Duration]null.Float{
+				0:                null.FloatFrom(0),
+				5 * time.Second:  null.FloatFrom(50),
+				10 * time.Second: null.FloatFrom(100),
+				20 * time.Second: null.FloatFrom(100),
+			},
+		},
+		"start rate": {
+			[]lib.Stage{
+				{Duration: d(0), Rate: null.FloatFrom(100)},
+				{Duration: d(10 * time.Minute), Rate: null.FloatFrom(1000)},
+				{Duration: d(1 * time.Minute)},
+			},
+			map[time.Duration]null.Float{
+				0:                null.FloatFrom(100),
+				5 * time.Minute:  null.FloatFrom(550),
+				10 * time.Minute: null.FloatFrom(1000),
+				11 * time.Minute: null.FloatFrom(1000),
+			},
+		},
+		"infinite": {
+			[]lib.Stage{
+				{Duration: d(10 * time.Second), Rate: null.FloatFrom(10)},
+				{Rate: null.FloatFrom(20)},
+			},
+			map[time.Duration]null.Float{
+				5 * time.Second: null.FloatFrom(5),
+				24 * time.Hour:  null.FloatFrom(20),
+			},
+		},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			for at, rate := range data.Checkpoints {
+				assert.Equal(t, rate, ProcessStageRates(data.Stages, at), at.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	log "github.com/sirupsen/logrus"
+)
+
+// An outputBuffer sits between the engine and a collector: the engine adds samples to it without
+// ever blocking, and it hands them to the collector in batches, every flush interval. Samples that
+// don't fit, because the collector can't keep up, are dropped and counted instead.
+//
+// It also isolates the collector from the rest of the test: if it panics, it's disabled, and its
+// samples are dropped from then on, but the test and the other collectors carry on.
+type outputBuffer struct {
+	name          string
+	collector     lib.Collector
+	logger        *log.Logger
+	flushInterval time.Duration
+	maxSamples    int64 // 0 means no limit
+
+	mutex    sync.Mutex
+	buffered []stats.SampleContainer
+	size     int64 // The number of buffered samples, rather than containers
+	dropped  int64
+	failed   bool
+}
+
+func newOutputBuffer(
+	name string, collector lib.Collector, logger *log.Logger, flushInterval time.Duration, maxSamples int64,
+) *outputBuffer {
+	return &outputBuffer{
+		name:          name,
+		collector:     collector,
+		logger:        logger,
+		flushInterval: flushInterval,
+		maxSamples:    maxSamples,
+	}
+}
+
+// add buffers sample containers, or drops them if the buffer is full.
+func (b *outputBuffer) add(sampleContainers []stats.SampleContainer) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, sc := range sampleContainers {
+		n := int64(len(sc.GetSamples()))
+		if b.failed || (b.maxSamples > 0 && b.size+n > b.maxSamples) {
+			b.dropped += n
+			continue
+		}
+		b.buffered = append(b.buffered, sc)
+		b.size += n
+	}
+}
+
+// takeDropped returns the number of samples that were dropped since the last call.
+func (b *outputBuffer) takeDropped() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	dropped := b.dropped
+	b.dropped = 0
+	return dropped
+}
+
+// runCollector runs the collector until the context is done.
+func (b *outputBuffer) runCollector(ctx context.Context) {
+	b.safely("running", func() { b.collector.Run(ctx) })
+}
+
+// setRunStatus passes the run status on to the collector, unless it failed.
+func (b *outputBuffer) setRunStatus(status lib.RunStatus) {
+	b.mutex.Lock()
+	failed := b.failed
+	b.mutex.Unlock()
+
+	if !failed {
+		b.safely("setting the run status", func() { b.collector.SetRunStatus(status) })
+	}
+}
+
+// thresholdChanged passes a threshold event on to the collector, if it wants them and didn't fail.
+func (b *outputBuffer) thresholdChanged(event lib.ThresholdEvent) {
+	tc, ok := b.collector.(lib.ThresholdCollector)
+	if !ok {
+		return
+	}
+	b.mutex.Lock()
+	failed := b.failed
+	b.mutex.Unlock()
+
+	if !failed {
+		b.safely("handling a threshold event", func() { tc.ThresholdChanged(event) })
+	}
+}
+
+// run flushes the buffer every flush interval, unless the collector is busy, until the context is
+// done; then everything that's left is flushed, busy or not.
+func (b *outputBuffer) run(ctx context.Context) {
+	bc, hasBackpressure := b.collector.(lib.BackpressureCollector)
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if hasBackpressure && bc.Busy() {
+				continue
+			}
+			b.flush()
+		case <-ctx.Done():
+			b.flush()
+			return
+		}
+	}
+}
+
+func (b *outputBuffer) flush() {
+	b.mutex.Lock()
+	batch := b.buffered
+	b.buffered = nil
+	b.size = 0
+	b.mutex.Unlock()
+
+	if len(batch) > 0 {
+		b.safely("collecting samples", func() { b.collector.Collect(batch) })
+	}
+}
+
+// safely calls fn, and disables the output if it panics.
+func (b *outputBuffer) safely(action string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.fail(fmt.Errorf("%v", r), action)
+		}
+	}()
+	fn()
+}
+
+func (b *outputBuffer) fail(err error, action string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.failed {
+		return
+	}
+
+	b.failed = true
+	b.dropped += b.size
+	b.buffered = nil
+	b.size = 0
+	b.logger.WithError(err).WithField("output", b.name).Errorf(
+		"Output failed while %s, the test will continue without it", action)
+}
@@ -0,0 +1,174 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/stats/dummy"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+// A collector that signals backpressure while busy is set.
+type busyCollector struct {
+	dummy.Collector
+	busy    int32
+	batches int32
+}
+
+func (c *busyCollector) Busy() bool { return atomic.LoadInt32(&c.busy) == 1 }
+
+func (c *busyCollector) Collect(scs []stats.SampleContainer) {
+	atomic.AddInt32(&c.batches, 1)
+	c.Collector.Collect(scs)
+}
+
+var _ lib.BackpressureCollector = &busyCollector{}
+
+func TestOutputBuffer(t *testing.T) {
+	testMetric := stats.New("test_metric", stats.Counter)
+	sample := stats.Sample{Metric: testMetric, Value: 1}
+	pair := stats.ConnectedSamples{Samples: []stats.Sample{sample, sample}}
+
+	c := &busyCollector{busy: 1}
+	b := newOutputBuffer("busy", c, log.StandardLogger(), 10*time.Millisecond, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+
+	b.add([]stats.SampleContainer{sample, pair})
+	b.add([]stats.SampleContainer{pair, sample})
+	assert.Equal(t, int64(2), b.takeDropped())
+	assert.Equal(t, int64(0), b.takeDropped())
+
+	// Nothing is handed over while the collector is busy...
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.batches))
+
+	// ...and everything at once afterwards.
+	atomic.StoreInt32(&c.busy, 0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.batches))
+
+	// The last flush ignores backpressure.
+	atomic.StoreInt32(&c.busy, 1)
+	b.add([]stats.SampleContainer{sample})
+	cancel()
+	<-done
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.batches))
+	assert.Len(t, c.Samples, 5)
+}
+
+func TestEngineDroppedSamples(t *testing.T) {
+	testMetric := stats.New("test_metric", stats.Counter)
+	e, err, hook := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		for i := 0; i < 5; i++ {
+			out <- stats.Sample{Metric: testMetric, Value: 1, Time: time.Now()}
+		}
+		return nil
+	}), lib.Options{
+		VUs:              null.IntFrom(1),
+		VUsMax:           null.IntFrom(1),
+		Iterations:       null.IntFrom(1),
+		OutputBufferSize: null.IntFrom(1),
+	})
+	require.NoError(t, err)
+
+	c := &busyCollector{busy: 1}
+	e.Collectors = []lib.Collector{c}
+	require.NoError(t, e.Run(context.Background()))
+
+	assert.Len(t, c.Samples, 1)
+	dropped := e.Metrics[metrics.DroppedSamples.Name]
+	if assert.NotNil(t, dropped) {
+		assert.True(t, dropped.Sink.(*stats.CounterSink).Value >= 4)
+	}
+	if assert.NotEmpty(t, hook.Entries) {
+		assert.Contains(t, hook.LastEntry().Message, "can't keep up")
+	}
+}
+
+// A collector that panics when it's handed samples.
+type panickingCollector struct {
+	dummy.Collector
+}
+
+func (c *panickingCollector) Collect(scs []stats.SampleContainer) {
+	panic("the backend is down")
+}
+
+func TestEngineFailingOutput(t *testing.T) {
+	testMetric := stats.New("test_metric", stats.Counter)
+	e, err, hook := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		out <- stats.Sample{Metric: testMetric, Value: 1, Time: time.Now()}
+		time.Sleep(CollectRate + 50*time.Millisecond)
+		out <- stats.Sample{Metric: testMetric, Value: 1, Time: time.Now()}
+		return nil
+	}), lib.Options{
+		VUs:                 null.IntFrom(1),
+		VUsMax:              null.IntFrom(1),
+		Iterations:          null.IntFrom(1),
+		OutputFlushInterval: types.NullDurationFrom(10 * time.Millisecond),
+	})
+	require.NoError(t, err)
+
+	failing, working := &panickingCollector{}, &dummy.Collector{}
+	e.Collectors = []lib.Collector{failing, working}
+	e.CollectorNames = []string{"failing"}
+	require.NoError(t, e.Run(context.Background()))
+
+	found := 0
+	for _, s := range working.Samples {
+		if s.Metric == testMetric {
+			found++
+		}
+	}
+	assert.Equal(t, 2, found)
+
+	// Samples for the failed output are dropped from then on
+	dropped := e.Metrics[metrics.DroppedSamples.Name]
+	if assert.NotNil(t, dropped) {
+		assert.True(t, dropped.Sink.(*stats.CounterSink).Value >= 1)
+	}
+
+	var logged bool
+	for _, entry := range hook.Entries {
+		if entry.Data["output"] == "failing" {
+			logged = true
+			assert.Equal(t, "Output failed while collecting samples, the test will continue without it", entry.Message)
+			assert.EqualError(t, entry.Data[log.ErrorKey].(error), "the backend is down")
+		}
+	}
+	assert.True(t, logged)
+}
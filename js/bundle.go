@@ -23,14 +23,22 @@ package js
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/js/compiler"
 	jslib "github.com/loadimpact/k6/js/lib"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/secrets"
+	"github.com/loadimpact/k6/lib/shared"
 	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
 
@@ -45,6 +53,12 @@ type Bundle struct {
 	BaseInitContext *InitContext
 
 	Env map[string]string
+
+	// Secrets has the secrets that scripts get with k6/secrets, from the --secret-source flags
+	Secrets *secrets.Store
+
+	// Shared is the k6/shared store that the VUs of the bundle share
+	Shared *shared.Store
 }
 
 // A BundleInstance is a self-contained instance of a Bundle.
@@ -52,10 +66,19 @@ type BundleInstance struct {
 	Runtime *goja.Runtime
 	Context *context.Context
 	Default goja.Callable
+
+	EventLoop *common.EventLoop
 }
 
 // Creates a new bundle from a source file and a filesystem.
 func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bundle, error) {
+	return newBundle(src, fs, rtOpts, func(data []byte, opts lib.Options) error {
+		return checkOptions(data, opts, rtOpts.StrictOptions.Bool)
+	})
+}
+
+// newBundle creates a new bundle, with checkOpts deciding what to do about the script's options.
+func newBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions, checkOpts func([]byte, lib.Options) error) (*Bundle, error) {
 	compiler, err := compiler.New()
 	if err != nil {
 		return nil, err
@@ -74,6 +97,11 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 	mirrorFS := afero.NewMemMapFs()
 	cachedFS := afero.NewCacheOnReadFs(fs, mirrorFS, 0)
 
+	store, err := secrets.NewStore(rtOpts.SecretSources)
+	if err != nil {
+		return nil, err
+	}
+
 	// Make a bundle, instantiate it into a throwaway VM to populate caches.
 	rt := goja.New()
 	bundle := Bundle{
@@ -82,8 +110,10 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 		Program:         pgm,
 		BaseInitContext: NewInitContext(rt, compiler, new(context.Context), cachedFS, loader.Dir(src.Filename)),
 		Env:             rtOpts.Env,
+		Secrets:         store,
+		Shared:          shared.NewStore(),
 	}
-	if err := bundle.instantiate(rt, bundle.BaseInitContext); err != nil {
+	if _, err := bundle.instantiate(rt, bundle.BaseInitContext); err != nil {
 		return nil, err
 	}
 
@@ -116,6 +146,9 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 			if err := json.Unmarshal(data, &bundle.Options); err != nil {
 				return nil, err
 			}
+			if err := checkOpts(data, bundle.Options); err != nil {
+				return nil, err
+			}
 		case "setup":
 			if _, ok := goja.AssertFunction(v); !ok {
 				return nil, errors.New("exported 'setup' must be a function")
@@ -130,6 +163,71 @@ func NewBundle(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) (*Bu
 	return &bundle, nil
 }
 
+// checkOptions looks for unknown options, and for thresholds that can't work, e.g. because they
+// use values that their metric's type doesn't have. Both would be ignored silently otherwise, so
+// they're errors with strict options, and warnings without.
+func checkOptions(data []byte, opts lib.Options, strict bool) error {
+	problems, err := optionProblems(data, opts)
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	if strict {
+		return errors.Errorf("invalid options:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	for _, problem := range problems {
+		log.Warnf("%s (an error with --strict-options)", problem)
+	}
+	return nil
+}
+
+func optionProblems(data []byte, opts lib.Options) ([]string, error) {
+	unknown, err := lib.UnknownOptions(data)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, key := range unknown {
+		problems = append(problems, "unknown option "+key)
+	}
+
+	names := make([]string, 0, len(opts.Thresholds))
+	for name := range opts.Thresholds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parent, sm := stats.NewSubmetric(name)
+		if strings.Contains(name, "{") && !strings.HasSuffix(name, "}") {
+			problems = append(problems, fmt.Sprintf("invalid threshold metric '%s', the tags have to be in {}", name))
+			continue
+		}
+		for tag, value := range sm.Tags.CloneTags() {
+			if value == "" {
+				problems = append(problems, fmt.Sprintf("invalid threshold metric '%s', tag '%s' has no value", name, tag))
+			}
+		}
+
+		// The types of custom metrics aren't known before they're used, any will do for them
+		types := []stats.MetricType{stats.Counter, stats.Gauge, stats.Trend, stats.Rate}
+		if m := metrics.Builtin(parent); m != nil {
+			types = []stats.MetricType{m.Type}
+		}
+		for _, th := range opts.Thresholds[name].Thresholds {
+			var err error
+			for _, typ := range types {
+				if err = th.Validate(typ); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("invalid threshold '%s' for %s: %s", th.Source, name, err))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
 func NewBundleFromArchive(arc *lib.Archive, rtOpts lib.RuntimeOptions) (*Bundle, error) {
 	compiler, err := compiler.New()
 	if err != nil {
@@ -165,6 +263,11 @@ func NewBundleFromArchive(arc *lib.Archive, rtOpts lib.RuntimeOptions) (*Bundle,
 		env[k] = v
 	}
 
+	store, err := secrets.NewStore(rtOpts.SecretSources)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Bundle{
 		Filename:        arc.Filename,
 		Source:          string(arc.Data),
@@ -172,6 +275,8 @@ func NewBundleFromArchive(arc *lib.Archive, rtOpts lib.RuntimeOptions) (*Bundle,
 		Options:         arc.Options,
 		BaseInitContext: initctx,
 		Env:             env,
+		Secrets:         store,
+		Shared:          shared.NewStore(),
 	}, nil
 }
 
@@ -203,7 +308,8 @@ func (b *Bundle) Instantiate() (*BundleInstance, error) {
 	// runtime, but no state, to allow module-provided types to function within the init context.
 	rt := goja.New()
 	init := newBoundInitContext(b.BaseInitContext, ctxPtr, rt)
-	if err := b.instantiate(rt, init); err != nil {
+	loop, err := b.instantiate(rt, init)
+	if err != nil {
 		return nil, err
 	}
 
@@ -215,20 +321,28 @@ func (b *Bundle) Instantiate() (*BundleInstance, error) {
 	}
 
 	return &BundleInstance{
-		Runtime: rt,
-		Context: ctxPtr,
-		Default: def,
+		Runtime:   rt,
+		Context:   ctxPtr,
+		Default:   def,
+		EventLoop: loop,
 	}, nil
 }
 
 // Instantiates the bundle into an existing runtime. Not public because it also messes with a bunch
 // of other things, will potentially thrash data and makes a mess in it if the operation fails.
-func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
+// It returns the runtime's event loop, which has to be run after every call into it.
+func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) (*common.EventLoop, error) {
 	rt.SetFieldNameMapper(common.FieldNameMapper{})
-	rt.SetRandSource(common.NewRandSource())
+	rt.SetRandSource(b.newRandSource(0))
 
+	// core-js uses setImmediate() to run the reactions of Promises, if it's defined before it runs.
+	loop := common.NewEventLoop(rt)
+	loop.Bind()
 	if _, err := rt.RunProgram(jslib.GetCoreJS()); err != nil {
-		return err
+		return nil, err
+	}
+	if _, err := rt.RunProgram(jslib.GetRegeneratorRuntime()); err != nil {
+		return nil, err
 	}
 
 	exports := rt.NewObject()
@@ -238,16 +352,32 @@ func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
 	rt.Set("module", module)
 
 	rt.Set("__ENV", b.Env)
+	rt.Set("__filename", b.Filename)
+	rt.Set("__dirname", b.BaseInitContext.pwd)
 
-	*init.ctxPtr = common.WithRuntime(context.Background(), rt)
+	*init.ctxPtr = common.WithEventLoop(common.WithRuntime(context.Background(), rt), loop)
+	*init.ctxPtr = common.WithSecrets(*init.ctxPtr, b.Secrets)
+	*init.ctxPtr = common.WithShared(*init.ctxPtr, b.Shared)
 	unbindInit := common.BindToGlobal(rt, common.Bind(rt, init, init.ctxPtr))
 	if _, err := rt.RunProgram(b.Program); err != nil {
-		return err
+		return nil, err
+	}
+	if err := loop.Run(context.Background()); err != nil {
+		return nil, err
 	}
 	unbindInit()
 	*init.ctxPtr = nil
 
-	rt.SetRandSource(common.NewRandSource())
+	rt.SetRandSource(b.newRandSource(0))
 
-	return nil
+	return loop, nil
+}
+
+// newRandSource returns the source of Math.random() for the VU with the given ID, which is
+// seeded with the seed option plus the ID, if it's set, and random otherwise.
+func (b *Bundle) newRandSource(id int64) goja.RandSource {
+	if b.Options.Seed.Valid {
+		return common.NewSeededRandSource(b.Options.Seed.Int64 + id)
+	}
+	return common.NewRandSource()
 }
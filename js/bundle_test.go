@@ -111,6 +111,41 @@ func TestNewBundle(t *testing.T) {
 			}
 		})
 
+		t.Run("Unknown", func(t *testing.T) {
+			src := `
+				export let options = {
+					vus: 2,
+					thresold: { http_req_duration: ["p(95)<500"] },
+					thresholds: {
+						http_req_duration: ["p95<500"],
+						checks: ["avg>0.9", "rate>0.9"],
+						"http_req_duration{status:200": ["p(99)<1000"],
+						"http_req_duration{scenario}": ["p(99)<1000"],
+						my_trend: ["p(95)<100"],
+					},
+				};
+				export default function() {};
+			`
+			b, err := getSimpleBundle("/script.js", src)
+			if assert.NoError(t, err) {
+				assert.Equal(t, null.IntFrom(2), b.Options.VUs)
+			}
+
+			_, err = NewBundle(&lib.SourceData{Filename: "/script.js", Data: []byte(src)},
+				afero.NewMemMapFs(), lib.RuntimeOptions{StrictOptions: null.BoolFrom(true)})
+			if assert.Error(t, err) {
+				msg := err.Error()
+				assert.Contains(t, msg, "invalid options:\n\t")
+				assert.Contains(t, msg, "unknown option thresold (did you mean thresholds?)")
+				assert.Contains(t, msg, "invalid threshold 'avg>0.9' for checks: ReferenceError: avg is not defined")
+				assert.Contains(t, msg, "invalid threshold 'p95<500' for http_req_duration: ReferenceError: p95 is not defined")
+				assert.Contains(t, msg, "invalid threshold metric 'http_req_duration{status:200', the tags have to be in {}")
+				assert.Contains(t, msg, "invalid threshold metric 'http_req_duration{scenario}', tag 'scenario' has no value")
+				assert.NotContains(t, msg, "rate>0.9")
+				assert.NotContains(t, msg, "my_trend")
+			}
+		})
+
 		t.Run("Paused", func(t *testing.T) {
 			b, err := getSimpleBundle("/script.js", `
 				export let options = {
@@ -404,6 +439,51 @@ func TestNewBundleFromArchive(t *testing.T) {
 	assert.Equal(t, "hi!", v2.Export())
 }
 
+func TestNewBundleTypeScript(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, fs.MkdirAll("/path/to", 0755))
+	assert.NoError(t, afero.WriteFile(fs, "/path/to/exclaim.ts", []byte(`
+		export interface Options { times: number }
+		export default function(s: string, opts: Options = { times: 1 }): string {
+			return s + "!".repeat(opts.times);
+		}
+	`), 0644))
+
+	src := &lib.SourceData{
+		Filename: "/path/to/script.ts",
+		Data: []byte(`
+			import exclaim, { type Options } from "./exclaim.ts";
+			import type { Response } from "k6/http";
+			export let options = { vus: 12 as number };
+			const opts: Options = { times: 2 };
+			export default function(): string { return exclaim("hi", opts); };
+		`),
+	}
+	b, err := NewBundle(src, fs, lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, lib.Options{VUs: null.IntFrom(12)}, b.Options)
+
+	// Archives keep the TypeScript sources, and strip their types when they're run
+	arc := b.MakeArchive()
+	assert.Equal(t, string(src.Data), string(arc.Data))
+	b2, err := NewBundleFromArchive(arc, lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, b := range []*Bundle{b, b2} {
+		bi, err := b.Instantiate()
+		if !assert.NoError(t, err) {
+			return
+		}
+		v, err := bi.Default(goja.Undefined())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "hi!!", v.Export())
+		}
+	}
+}
+
 func TestBundleInstantiate(t *testing.T) {
 	b, err := getSimpleBundle("/script.js", `
 		let val = true;
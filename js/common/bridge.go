@@ -108,6 +108,14 @@ func Bind(rt *goja.Runtime, v interface{}, ctxPtr *context.Context) map[string]i
 
 	val := reflect.ValueOf(v)
 	typ := val.Type()
+
+	// Calls are profiled as e.g. "http.get", with the name of the package.
+	pkg := typ
+	if pkg.Kind() == reflect.Ptr {
+		pkg = pkg.Elem()
+	}
+	pkgName := pkg.PkgPath()[strings.LastIndex(pkg.PkgPath(), "/")+1:]
+
 	for i := 0; i < typ.NumMethod(); i++ {
 		meth := typ.Method(i)
 		name := MethodName(typ, meth)
@@ -132,7 +140,14 @@ func Bind(rt *goja.Runtime, v interface{}, ctxPtr *context.Context) map[string]i
 		if hasError || wantsContext || wantsContextPtr {
 			isVariadic := fnT.IsVariadic()
 			realFn := fn
+			profileName := pkgName + "." + name
 			fn = reflect.ValueOf(func(call goja.FunctionCall) goja.Value {
+				if ctxPtr != nil && *ctxPtr != nil {
+					if state := GetState(*ctxPtr); state != nil && state.Profile != nil {
+						defer state.Profile.Call(profileName)()
+					}
+				}
+
 				// Number of arguments: the higher number between the function's required arguments
 				// and the number of arguments actually given.
 				args := make([]reflect.Value, numIn)
@@ -242,9 +257,18 @@ func Bind(rt *goja.Runtime, v interface{}, ctxPtr *context.Context) map[string]i
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
 		name := FieldName(typ, field)
-		if name != "" {
-			exports[name] = val.Field(i).Interface()
+		if name == "" {
+			continue
+		}
+		// Objects of the same package, e.g. crypto.subtle, are bound too, so that their methods
+		// get the context like the ones of the module itself.
+		fv := val.Field(i)
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() &&
+			fv.Elem().Kind() == reflect.Struct && fv.Elem().Type().PkgPath() == typ.PkgPath() {
+			exports[name] = Bind(rt, fv.Interface(), ctxPtr)
+			continue
 		}
+		exports[name] = fv.Interface()
 	}
 
 	return exports
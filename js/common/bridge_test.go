@@ -152,6 +152,10 @@ func (bridgeTestContextAddWithErrorType) ContextAddWithError(ctx context.Context
 	return res, nil
 }
 
+type bridgeTestNestedType struct {
+	Inner *bridgeTestContextAddType `js:"inner"`
+}
+
 type bridgeTestContextInjectType struct {
 	ctx context.Context
 }
@@ -519,6 +523,20 @@ func TestBind(t *testing.T) {
 				})
 			})
 		}},
+		{"Nested", bridgeTestNestedType{&bridgeTestContextAddType{}}, func(t *testing.T, obj interface{}, rt *goja.Runtime) {
+			_, err := RunString(rt, `obj.inner.contextAdd(1, 2)`)
+			assert.EqualError(t, err, "GoError: contextAdd() can only be called from within default()")
+
+			t.Run("Valid", func(t *testing.T) {
+				*ctxPtr = context.Background()
+				defer func() { *ctxPtr = nil }()
+
+				v, err := RunString(rt, `obj.inner.contextAdd(1, 2)`)
+				if assert.NoError(t, err) {
+					assert.Equal(t, int64(3), v.Export())
+				}
+			})
+		}},
 		{"ContextInject", bridgeTestContextInjectType{}, func(t *testing.T, obj interface{}, rt *goja.Runtime) {
 			_, err := RunString(rt, `obj.contextInject()`)
 			switch impl := obj.(type) {
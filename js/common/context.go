@@ -24,6 +24,8 @@ import (
 	"context"
 
 	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/lib/secrets"
+	"github.com/loadimpact/k6/lib/shared"
 )
 
 type ctxKey int
@@ -31,6 +33,9 @@ type ctxKey int
 const (
 	ctxKeyState ctxKey = iota
 	ctxKeyRuntime
+	ctxKeyEventLoop
+	ctxKeySecrets
+	ctxKeyShared
 )
 
 func WithState(ctx context.Context, state *State) context.Context {
@@ -56,3 +61,39 @@ func GetRuntime(ctx context.Context) *goja.Runtime {
 	}
 	return v.(*goja.Runtime)
 }
+
+func WithEventLoop(ctx context.Context, loop *EventLoop) context.Context {
+	return context.WithValue(ctx, ctxKeyEventLoop, loop)
+}
+
+func GetEventLoop(ctx context.Context) *EventLoop {
+	v := ctx.Value(ctxKeyEventLoop)
+	if v == nil {
+		return nil
+	}
+	return v.(*EventLoop)
+}
+
+func WithSecrets(ctx context.Context, store *secrets.Store) context.Context {
+	return context.WithValue(ctx, ctxKeySecrets, store)
+}
+
+func GetSecrets(ctx context.Context) *secrets.Store {
+	v := ctx.Value(ctxKeySecrets)
+	if v == nil {
+		return nil
+	}
+	return v.(*secrets.Store)
+}
+
+func WithShared(ctx context.Context, store *shared.Store) context.Context {
+	return context.WithValue(ctx, ctxKeyShared, store)
+}
+
+func GetShared(ctx context.Context) *shared.Store {
+	v := ctx.Value(ctxKeyShared)
+	if v == nil {
+		return nil
+	}
+	return v.(*shared.Store)
+}
@@ -0,0 +1,342 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+)
+
+// An EventLoop runs a VU's callbacks: the ones of its timers (setTimeout() and setInterval()),
+// of setImmediate(), which is also what resolves Promises, and of the asynchronous APIs of
+// modules, which do their work on other goroutines. All of them run on the VU's goroutine, one
+// at a time, so they can use the runtime like any other JS code.
+type EventLoop struct {
+	rt *goja.Runtime
+
+	// The callbacks that are ready to run and the number of registered ones that aren't yet,
+	// which can be queued from other goroutines. The epoch changes when the loop is reset, so
+	// that the callbacks of an interrupted iteration don't run in the next one.
+	mutex   sync.Mutex
+	queue   []loopCallback
+	pending int
+	epoch   int
+	wakeup  chan struct{}
+
+	// Only used on the VU's goroutine.
+	timers []*loopTimer // By the time they're due
+	nextID int64
+}
+
+type loopCallback struct {
+	id int64 // Of the setImmediate() call, or 0
+	fn func() error
+}
+
+type loopTimer struct {
+	id       int64
+	due      time.Time
+	interval time.Duration // Zero for timeouts
+	fn       goja.Callable
+	args     []goja.Value
+}
+
+// NewEventLoop returns the event loop of a runtime; Bind adds its timer functions to it.
+func NewEventLoop(rt *goja.Runtime) *EventLoop {
+	return &EventLoop{rt: rt, wakeup: make(chan struct{}, 1)}
+}
+
+// Bind adds setTimeout(), setInterval(), setImmediate() and their clear*() functions to the
+// runtime's global object.
+func (l *EventLoop) Bind() {
+	l.rt.Set("setTimeout", func(fn goja.Callable, delay goja.Value, args ...goja.Value) int64 {
+		return l.addTimer(fn, toDelay(delay), false, args)
+	})
+	l.rt.Set("setInterval", func(fn goja.Callable, delay goja.Value, args ...goja.Value) int64 {
+		return l.addTimer(fn, toDelay(delay), true, args)
+	})
+	l.rt.Set("setImmediate", func(fn goja.Callable, args ...goja.Value) int64 {
+		l.nextID++
+		l.enqueue(loopCallback{l.nextID, func() error {
+			_, err := fn(goja.Undefined(), args...)
+			return err
+		}})
+		return l.nextID
+	})
+	// Like in browsers, timers and immediates share their IDs, so any of these clears any of them
+	for _, name := range []string{"clearTimeout", "clearInterval", "clearImmediate"} {
+		l.rt.Set(name, l.clear)
+	}
+}
+
+func toDelay(v goja.Value) time.Duration {
+	if v == nil {
+		return 0
+	}
+	ms := v.ToFloat()
+	if math.IsNaN(ms) || ms < 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (l *EventLoop) addTimer(fn goja.Callable, delay time.Duration, repeat bool, args []goja.Value) int64 {
+	l.nextID++
+	t := &loopTimer{id: l.nextID, fn: fn, args: args}
+	if repeat {
+		// Intervals of 0 would keep the loop from ever waiting for anything else
+		if delay < time.Millisecond {
+			delay = time.Millisecond
+		}
+		t.interval = delay
+	}
+	l.schedule(t, time.Now().Add(delay))
+	return t.id
+}
+
+func (l *EventLoop) schedule(t *loopTimer, due time.Time) {
+	t.due = due
+	i := len(l.timers)
+	for i > 0 && l.timers[i-1].due.After(due) {
+		i--
+	}
+	l.timers = append(l.timers, nil)
+	copy(l.timers[i+1:], l.timers[i:])
+	l.timers[i] = t
+}
+
+func (l *EventLoop) clear(id int64) {
+	for i, t := range l.timers {
+		if t.id == id {
+			l.timers = append(l.timers[:i], l.timers[i+1:]...)
+			return
+		}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i, c := range l.queue {
+		if c.id == id {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (l *EventLoop) enqueue(c loopCallback) {
+	l.mutex.Lock()
+	l.queue = append(l.queue, c)
+	l.mutex.Unlock()
+}
+
+// RegisterCallback keeps the loop running until the returned function is called with a callback,
+// which then runs on the loop. It's how asynchronous APIs call back into JS when they're done:
+// register a callback on the VU's goroutine, do the work on another one, and call the returned
+// function from there. It has to be called exactly once.
+func (l *EventLoop) RegisterCallback() func(func() error) {
+	l.mutex.Lock()
+	l.pending++
+	epoch := l.epoch
+	l.mutex.Unlock()
+
+	var once sync.Once
+	return func(fn func() error) {
+		once.Do(func() {
+			l.mutex.Lock()
+			if epoch == l.epoch {
+				l.pending--
+				l.queue = append(l.queue, loopCallback{fn: fn})
+			}
+			l.mutex.Unlock()
+
+			select {
+			case l.wakeup <- struct{}{}:
+			default:
+			}
+		})
+	}
+}
+
+// Run runs callbacks until there are no more timers or pending callbacks, or until one of them
+// throws, or the context is done.
+func (l *EventLoop) Run(ctx context.Context) error {
+	return l.run(ctx, nil)
+}
+
+// RunUntil runs callbacks until the done channel is closed, or one of them throws. It's for the
+// APIs that block the script, like ws.connect(), so that timers and Promises keep working, and
+// they can run their own callbacks with RegisterCallback.
+func (l *EventLoop) RunUntil(done <-chan struct{}) error {
+	return l.run(context.Background(), done)
+}
+
+func (l *EventLoop) run(ctx context.Context, done <-chan struct{}) error {
+	for {
+		if done != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+			}
+		}
+
+		if fn := l.next(); fn != nil {
+			if err := fn(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Nothing is ready to run, wait for a timer or a callback
+		l.mutex.Lock()
+		pending := l.pending
+		l.mutex.Unlock()
+		if done == nil && pending == 0 && len(l.timers) == 0 {
+			return nil
+		}
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if len(l.timers) > 0 {
+			timer = time.NewTimer(time.Until(l.timers[0].due))
+			timerC = timer.C
+		}
+		select {
+		case <-timerC:
+		case <-l.wakeup:
+		case <-done:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// next returns the next callback to run: a queued one, or the one of the earliest due timer.
+func (l *EventLoop) next() func() error {
+	l.mutex.Lock()
+	if len(l.queue) > 0 {
+		c := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mutex.Unlock()
+		return c.fn
+	}
+	l.mutex.Unlock()
+
+	if len(l.timers) == 0 || l.timers[0].due.After(time.Now()) {
+		return nil
+	}
+	t := l.timers[0]
+	l.timers = l.timers[1:]
+	if t.interval > 0 {
+		l.schedule(t, time.Now().Add(t.interval))
+	}
+	return func() error {
+		_, err := t.fn(goja.Undefined(), t.args...)
+		return err
+	}
+}
+
+// Reset drops all timers and callbacks, including the ones that are still pending; it's for
+// when an iteration is over, but not everything it started is.
+func (l *EventLoop) Reset() {
+	l.timers = nil
+	l.mutex.Lock()
+	l.queue = nil
+	l.pending = 0
+	l.epoch++
+	l.mutex.Unlock()
+}
+
+// Await runs the loop, and if v is a Promise (or another thenable), returns what it resolves to,
+// or its rejection as an error. Other values are returned as they are.
+func (l *EventLoop) Await(ctx context.Context, v goja.Value) (goja.Value, error) {
+	var then goja.Callable
+	var obj *goja.Object
+	if v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		if obj = v.ToObject(l.rt); obj != nil {
+			then, _ = goja.AssertFunction(obj.Get("then"))
+		}
+	}
+	if then == nil {
+		return v, l.Run(ctx)
+	}
+
+	var result, rejection goja.Value
+	settled := false
+	onFulfilled := l.rt.ToValue(func(value goja.Value) {
+		result, settled = value, true
+	})
+	onRejected := l.rt.ToValue(func(reason goja.Value) {
+		rejection, settled = reason, true
+	})
+	if _, err := then(obj, onFulfilled, onRejected); err != nil {
+		return nil, err
+	}
+	if err := l.Run(ctx); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !settled:
+		return nil, errors.New("the promise was never resolved or rejected")
+	case rejection != nil:
+		return nil, Rejection(l.rt, rejection)
+	}
+	return result, nil
+}
+
+// Rejection turns the reason of a rejected Promise into the error that throwing it would be.
+func Rejection(rt *goja.Runtime, reason goja.Value) error {
+	throw, _ := goja.AssertFunction(rt.ToValue(func(goja.FunctionCall) goja.Value { panic(reason) }))
+	_, err := throw(goja.Undefined())
+	return err
+}
+
+var newPromise = goja.MustCompile("promise.js", `(function() {
+	var p = {};
+	p.promise = new Promise(function(resolve, reject) { p.resolve = resolve; p.reject = reject; });
+	return p;
+})()`, true)
+
+// NewPromise returns a new Promise, and the functions that resolve and reject it, which have to
+// be called on the VU's goroutine, e.g. from a callback of RegisterCallback. Errors are rejected
+// as GoErrors.
+func NewPromise(rt *goja.Runtime) (promise *goja.Object, resolve func(interface{}), reject func(error)) {
+	v, err := rt.RunProgram(newPromise)
+	if err != nil {
+		panic(err)
+	}
+	p := v.ToObject(rt)
+	resolveFn, _ := goja.AssertFunction(p.Get("resolve"))
+	rejectFn, _ := goja.AssertFunction(p.Get("reject"))
+	return p.Get("promise").ToObject(rt),
+		func(value interface{}) { _, _ = resolveFn(goja.Undefined(), rt.ToValue(value)) },
+		func(err error) { _, _ = rejectFn(goja.Undefined(), rt.NewGoError(err)) }
+}
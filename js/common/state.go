@@ -27,6 +27,8 @@ import (
 
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/profile"
+	"github.com/loadimpact/k6/lib/tracing"
 	"github.com/loadimpact/k6/stats"
 	"github.com/oxtoacart/bpool"
 	log "github.com/sirupsen/logrus"
@@ -53,6 +55,12 @@ type State struct {
 	// Rate limits.
 	RPSLimit *rate.Limiter
 
+	// Trace context propagation and span export for HTTP requests; nil if tracing is disabled.
+	Tracing *tracing.Client
+
+	// Times the calls to k6 functions of the current iteration; nil if the test isn't profiled.
+	Profile *profile.Iteration
+
 	// Sample channel, possibly buffered
 	Samples chan<- stats.SampleContainer
 
@@ -60,4 +68,7 @@ type State struct {
 	BPool *bpool.BufferPool
 
 	Vu, Iteration int64
+
+	// The scenario that the VU runs, if there are scenarios.
+	Scenario string
 }
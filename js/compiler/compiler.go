@@ -30,12 +30,14 @@ import (
 	"github.com/dop251/goja"
 	"github.com/dop251/goja/parser"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
 	DefaultOpts = map[string]interface{}{
 		"presets":       []string{"latest"},
+		"plugins":       []string{"transform-object-rest-spread"},
 		"ast":           false,
 		"sourceMaps":    false,
 		"babelrc":       false,
@@ -48,7 +50,8 @@ var (
 	once             sync.Once
 )
 
-// A Compiler uses Babel to compile ES6 code into something ES5-compatible.
+// A Compiler uses Babel to compile ES2015-ES2017 code, and object rest/spread properties, into
+// something ES5-compatible.
 type Compiler struct {
 	vm *goja.Runtime
 
@@ -96,6 +99,10 @@ func (c *Compiler) Transform(src, filename string) (code string, srcmap SourceMa
 		opts[k] = v
 	}
 	opts["filename"] = filename
+	if IsTypeScript(filename) {
+		// TypeScript classes usually declare fields, which are stripped down to plain class fields
+		opts["plugins"] = []string{"transform-object-rest-spread", "transform-class-properties"}
+	}
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -123,8 +130,16 @@ func (c *Compiler) Transform(src, filename string) (code string, srcmap SourceMa
 	return code, srcmap, nil
 }
 
-// Compiles the program, first trying ES5, then ES6.
+// Compiles the program, first trying ES5, then ES6. The types of TypeScript files are stripped
+// first, see StripTypes.
 func (c *Compiler) Compile(src, filename string, pre, post string, strict bool) (*goja.Program, string, error) {
+	if IsTypeScript(filename) {
+		code, err := StripTypes(src)
+		if err != nil {
+			return nil, src, errors.Wrap(err, filename)
+		}
+		src = code
+	}
 	return c.compile(src, filename, pre, post, strict, true)
 }
 
@@ -75,6 +75,11 @@ func TestTransform(t *testing.T) {
 		// assert.Equal(t, "test.js", srcmap.File)
 		// assert.Equal(t, "aAAA,SAASA,GAAT,CAAaC,CAAb,EAAgBC,CAAhB,EAAmB;AACf,WAAOD,IAAIC,CAAX;AACH;;AAED,IAAIC,MAAMH,IAAI,CAAJ,EAAO,CAAP,CAAV", srcmap.Mappings)
 	})
+	t.Run("object rest spread", func(t *testing.T) {
+		src, _, err := c.Transform(`let { a, ...rest } = { ...b, c: 1 };`, "test.js")
+		assert.NoError(t, err)
+		assert.Contains(t, src, `var _b$c = _extends({}, b, { c: 1 }),a = _b$c.a,rest = _objectWithoutProperties(_b$c, ["a"]);`)
+	})
 }
 
 func TestCompile(t *testing.T) {
@@ -159,4 +164,36 @@ func TestCompile(t *testing.T) {
 > 1 | 1+(=>2)()`)
 		})
 	})
+	t.Run("TypeScript", func(t *testing.T) {
+		src := strings.Join([]string{
+			`interface User { name: string; age?: number }`,
+			`type Greeter = (u: User) => string;`,
+			`class Counter<T> {`,
+			`  private count: number = 0;`,
+			`  readonly items: T[] = [];`,
+			`  label?: string;`,
+			`  add(item: T): number { this.items.push(item); return ++this.count; }`,
+			`}`,
+			`const greet: Greeter = (u: User): string => "hi " + u.name + (u.age! > 0 ? "!" : "");`,
+			`const c = new Counter<User>();`,
+			`c.add({ name: "a", age: 1 } as User);`,
+			`greet(c.items[0]) + " " + c.add({ name: "b" });`,
+		}, "\n")
+		pgm, _, err := c.Compile(src, "script.ts", "", "", true)
+		if !assert.NoError(t, err) {
+			return
+		}
+		v, err := goja.New().RunProgram(pgm)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "hi a! 2", v.Export())
+		}
+
+		t.Run("Invalid", func(t *testing.T) {
+			_, _, err := c.Compile("let a = 1;\nenum A { B }", "script.ts", "", "", true)
+			assert.EqualError(t, err, "script.ts: line 2: TypeScript enums aren't supported, only types can be stripped; use an object instead")
+
+			_, _, err = c.Compile("let a: number = ;", "script.ts", "", "", true)
+			assert.Contains(t, err.Error(), "SyntaxError: script.ts: Unexpected token (1:16)")
+		})
+	})
 }
@@ -0,0 +1,993 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// IsTypeScript tells whether a file is a TypeScript file, judging by its extension.
+func IsTypeScript(filename string) bool {
+	return strings.HasSuffix(filename, ".ts") || strings.HasSuffix(filename, ".mts")
+}
+
+// StripTypes turns TypeScript code into JavaScript by replacing its type annotations, type
+// declarations and other TypeScript-only syntax with whitespace, so that the lines and columns in
+// errors and stack traces still match the original code.
+//
+// Like Node's type stripping, this doesn't type-check anything, and TypeScript features that need
+// code to be generated (enums, namespaces and parameter properties) aren't supported.
+func StripTypes(src string) (string, error) {
+	s := &typeStripper{src: src}
+	if err := s.tokenize(); err != nil {
+		return "", err
+	}
+	if err := s.strip(); err != nil {
+		return "", err
+	}
+	return s.output(), nil
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokPunct
+	tokString
+	tokNumber
+	tokTemplate
+	tokRegexp
+	tokEOF
+)
+
+type token struct {
+	kind       tokenKind
+	text       string
+	start, end int
+	newline    bool // Whether there's a line break between the previous token and this one
+}
+
+// Longest first, so that the first match is the right one.
+var punctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "**", "<<", ">>",
+}
+
+// Keywords after which an expression starts, rather than ends.
+var exprKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true,
+	"delete": true, "void": true, "throw": true, "case": true, "do": true, "else": true,
+	"yield": true, "await": true, "extends": true, "if": true, "while": true, "for": true,
+	"switch": true, "with": true, "catch": true, "let": true, "const": true, "var": true,
+	"function": true, "class": true, "export": true, "import": true, "default": true,
+}
+
+// Keywords that can be followed by another name in a type.
+var typeKeywords = map[string]bool{
+	"extends": true, "keyof": true, "typeof": true, "infer": true, "is": true, "unique": true,
+	"readonly": true, "asserts": true, "new": true,
+}
+
+// Modifiers of class members and constructor parameters that only TypeScript has.
+var tsModifiers = map[string]bool{
+	"public": true, "private": true, "protected": true, "readonly": true, "abstract": true,
+	"declare": true, "override": true,
+}
+
+type typeStripper struct {
+	src     string
+	tokens  []token
+	blank   []bool // Per byte of src
+	removed []bool // Per token
+}
+
+func (s *typeStripper) tokenize() error {
+	src := s.src
+	newline := false
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n' || c == '\r':
+			newline = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			i++
+			continue
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(src[i:])
+			if r == '\u2028' || r == '\u2029' {
+				newline = true
+				i += size
+				continue
+			}
+			if unicode.IsSpace(r) || r == '\ufeff' {
+				i += size
+				continue
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return errors.New("unterminated comment")
+			}
+			if strings.ContainsAny(src[i:i+2+end], "\n\r") {
+				newline = true
+			}
+			i += end + 4
+			continue
+		}
+
+		t := token{start: i, newline: newline}
+		newline = false
+		switch {
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(src) && isIdentPart(src[j]) {
+				j++
+			}
+			t.kind, t.end = tokIdent, j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i + 1
+			for j < len(src) && (isIdentPart(src[j]) || src[j] == '.' ||
+				(src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			t.kind, t.end = tokNumber, j
+		case c == '"' || c == '\'':
+			j, err := skipString(src, i)
+			if err != nil {
+				return err
+			}
+			t.kind, t.end = tokString, j
+		case c == '`':
+			j, err := skipTemplate(src, i)
+			if err != nil {
+				return err
+			}
+			t.kind, t.end = tokTemplate, j
+		case c == '/' && s.regexpAllowed():
+			j, err := skipRegexp(src, i)
+			if err != nil {
+				return err
+			}
+			t.kind, t.end = tokRegexp, j
+		default:
+			t.kind, t.end = tokPunct, i+1
+			for _, p := range punctuators {
+				if strings.HasPrefix(src[i:], p) {
+					t.end = i + len(p)
+					break
+				}
+			}
+			// `?.` is optional chaining, unless it's a ternary followed by a number like `.5`
+			if strings.HasPrefix(src[i:], "?.") && !(i+2 < len(src) && src[i+2] >= '0' && src[i+2] <= '9') {
+				t.end = i + 2
+			}
+		}
+		t.text = src[t.start:t.end]
+		s.tokens = append(s.tokens, t)
+		i = t.end
+	}
+	s.tokens = append(s.tokens, token{kind: tokEOF, start: len(src), end: len(src), newline: true})
+	return nil
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == '$' || c == '#' || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+func skipString(src string, i int) (int, error) {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, nil
+		case '\n':
+			return 0, errors.New("unterminated string")
+		}
+	}
+	return 0, errors.New("unterminated string")
+}
+
+func skipTemplate(src string, i int) (int, error) {
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case src[j] == '\\':
+			j++
+		case src[j] == '`':
+			return j + 1, nil
+		case strings.HasPrefix(src[j:], "${"):
+			// Skip the expression, which can have strings and templates of its own
+			depth := 0
+			for j += 2; j < len(src); j++ {
+				var err error
+				switch src[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				case '"', '\'':
+					j, err = skipString(src, j)
+					j--
+				case '`':
+					j, err = skipTemplate(src, j)
+					j--
+				}
+				if err != nil {
+					return 0, err
+				}
+				if depth < 0 {
+					break
+				}
+			}
+		}
+	}
+	return 0, errors.New("unterminated template literal")
+}
+
+func skipRegexp(src string, i int) (int, error) {
+	inClass := false
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0, errors.New("unterminated regular expression")
+		case '/':
+			if !inClass {
+				for j++; j < len(src) && isIdentPart(src[j]); j++ {
+				}
+				return j, nil
+			}
+		}
+	}
+	return 0, errors.New("unterminated regular expression")
+}
+
+// regexpAllowed tells whether a slash starts a regular expression rather than being a division,
+// judging by the previous token.
+func (s *typeStripper) regexpAllowed() bool {
+	if len(s.tokens) == 0 {
+		return true
+	}
+	return !isExprEnd(s.tokens[len(s.tokens)-1])
+}
+
+// isExprEnd tells whether a token can be the end of an expression.
+func isExprEnd(t token) bool {
+	switch t.kind {
+	case tokIdent:
+		return !exprKeywords[t.text]
+	case tokNumber, tokString, tokTemplate, tokRegexp:
+		return true
+	case tokPunct:
+		return t.text == ")" || t.text == "]" || t.text == "}"
+	}
+	return false
+}
+
+func (s *typeStripper) output() string {
+	if s.blank == nil {
+		return s.src
+	}
+	b := []byte(s.src)
+	for i, blank := range s.blank {
+		if blank && b[i] != '\n' && b[i] != '\r' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// blankTokens replaces the tokens from..to (exclusive) with whitespace, and what's between them.
+func (s *typeStripper) blankTokens(from, to int) {
+	if from >= to {
+		return
+	}
+	if s.blank == nil {
+		s.blank = make([]bool, len(s.src))
+	}
+	if s.removed == nil {
+		s.removed = make([]bool, len(s.tokens))
+	}
+	for i := s.tokens[from].start; i < s.tokens[to-1].end; i++ {
+		s.blank[i] = true
+	}
+	for i := from; i < to; i++ {
+		s.removed[i] = true
+	}
+}
+
+// prev returns the index of the token before i that hasn't been stripped.
+func (s *typeStripper) prev(i int) int {
+	for i--; i >= 0 && s.removed != nil && s.removed[i]; i-- {
+	}
+	return i
+}
+
+func (s *typeStripper) is(i int, texts ...string) bool {
+	if i < 0 || i >= len(s.tokens) || s.tokens[i].kind == tokString || s.tokens[i].kind == tokTemplate {
+		return false
+	}
+	for _, text := range texts {
+		if s.tokens[i].text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *typeStripper) isIdent(i int) bool {
+	return i >= 0 && i < len(s.tokens) && s.tokens[i].kind == tokIdent
+}
+
+// matching returns the index of the bracket that closes the one at i, or -1.
+func (s *typeStripper) matching(i int) int {
+	depth := 0
+	for j := i; j < len(s.tokens); j++ {
+		switch {
+		case s.is(j, "(", "[", "{"):
+			depth++
+		case s.is(j, ")", "]", "}"):
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// angleDelta returns how much a token changes the nesting of angle brackets in a type.
+func (s *typeStripper) angleDelta(i int) int {
+	t := s.tokens[i]
+	if t.kind != tokPunct {
+		return 0
+	}
+	switch t.text {
+	case "<":
+		return 1
+	case ">", ">>", ">>>":
+		return -len(t.text)
+	}
+	return 0
+}
+
+// matchingAngle returns the index of the `>` that closes the type parameters or arguments that
+// start at i, or -1 if what's there doesn't look like types, e.g. because it's a comparison.
+func (s *typeStripper) matchingAngle(i int, params bool) int {
+	angles, depth := 0, 0
+	for j := i; j < len(s.tokens) && j < i+256; j++ {
+		t := s.tokens[j]
+		if t.kind == tokEOF {
+			return -1
+		}
+		if d := s.angleDelta(j); d != 0 {
+			angles += d
+			if angles == 0 && depth == 0 {
+				return j
+			}
+			if angles < 0 {
+				return -1 // e.g. `a < b >> c`
+			}
+			continue
+		}
+		if t.kind != tokPunct {
+			continue
+		}
+		switch t.text {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			if depth--; depth < 0 {
+				return -1
+			}
+		case ",", ".", "|", "&", "?", ":", "=>", "...":
+		case "=":
+			// Only the type parameters of declarations can have defaults
+			if !params && depth == 0 {
+				return -1
+			}
+		case ";":
+			if depth == 0 {
+				return -1
+			}
+		default:
+			return -1
+		}
+	}
+	return -1
+}
+
+// continuesType tells whether a type goes on after a line break between the tokens at i-1 and i.
+func (s *typeStripper) continuesType(i int) bool {
+	return s.is(i-1, "|", "&", ",", "=>", ":", ".", "<", "?") || typeKeywords[s.tokens[i-1].text] ||
+		s.is(i, "|", "&", ".", "=>", "extends")
+}
+
+// skipType returns the index of the first token after the type that starts at i. With
+// stopAtArrow, a `=>` at the top level ends the type, as in the return type of an arrow function.
+func (s *typeStripper) skipType(i int, stopAtArrow bool) int {
+	depth := 0
+	for j := i; j < len(s.tokens); j++ {
+		t := s.tokens[j]
+		if t.kind == tokEOF {
+			return j
+		}
+		first := j == i
+		if depth > 0 {
+			if d := s.angleDelta(j); d != 0 {
+				depth += d
+				if depth < 0 {
+					return j // Part of a `>>` closes an outer bracket
+				}
+				continue
+			}
+			switch {
+			case s.is(j, "(", "[", "{"):
+				depth++
+			case s.is(j, ")", "]", "}"):
+				depth--
+			}
+			continue
+		}
+
+		if !first && t.newline && !s.continuesType(j) {
+			return j
+		}
+		switch t.kind {
+		case tokIdent:
+			if !first && s.isIdent(j-1) && !typeKeywords[t.text] && !typeKeywords[s.tokens[j-1].text] {
+				return j
+			}
+			continue
+		case tokString, tokNumber, tokTemplate:
+			if !first && !s.is(j-1, "|", "&", "keyof", "typeof", "-") {
+				return j
+			}
+			continue
+		case tokRegexp:
+			return j
+		}
+
+		switch t.text {
+		case "(", "[", "<":
+			depth++
+		case "{":
+			if !first && !s.is(j-1, "|", "&", ":", "=>", ",", "<", "?", "keyof", "readonly") {
+				return j
+			}
+			depth++
+		case "|", "&", ".", "-":
+		case "=>":
+			if stopAtArrow || !s.is(j-1, ")") {
+				return j
+			}
+		default:
+			return j
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+// skipStatementType returns the index after a type-level statement, like a type alias, that
+// starts at i: after its `;`, or where the next statement starts.
+func (s *typeStripper) skipStatementType(i int) int {
+	depth := 0
+	for j := i + 1; j < len(s.tokens); j++ {
+		t := s.tokens[j]
+		if t.kind == tokEOF {
+			return j
+		}
+		if depth == 0 && t.newline && !s.continuesType(j) && !s.is(j-1, "=", "type", "interface") {
+			return j
+		}
+		switch {
+		case s.angleDelta(j) != 0:
+			depth += s.angleDelta(j)
+		case s.is(j, "(", "[", "{"):
+			depth++
+		case s.is(j, ")", "]", "}"):
+			depth--
+		case s.is(j, ";") && depth == 0:
+			return j + 1
+		}
+		if depth < 0 {
+			return j
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+// statementStart tells whether the token at i can start a statement.
+func (s *typeStripper) statementStart(i int) bool {
+	return i == 0 || s.tokens[i].newline || s.is(i-1, ";", "{", "}")
+}
+
+// skipStatementWithBody returns the index after a declaration that ends with a body in braces,
+// like an interface, or after its `;` if it doesn't have one.
+func (s *typeStripper) skipStatementWithBody(i int) int {
+	for j := i; j < len(s.tokens) && s.tokens[j].kind != tokEOF; j++ {
+		if s.is(j, "{") {
+			if end := s.matching(j); end >= 0 {
+				return end + 1
+			}
+			return len(s.tokens) - 1
+		}
+		if s.is(j, ";") || j > i+1 && s.tokens[j].newline && !s.continuesType(j) && !s.is(j-1, "=") {
+			return s.skipStatementType(i)
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+// skipModuleStatement returns the index after an import or export statement that starts at i.
+func (s *typeStripper) skipModuleStatement(i int) int {
+	for j := i + 1; j < len(s.tokens) && s.tokens[j].kind != tokEOF; j++ {
+		if s.tokens[j].kind == tokString && s.is(j-1, "from") {
+			if s.is(j+1, ";") {
+				return j + 2
+			}
+			return j + 1
+		}
+		if s.is(j, ";") {
+			return j + 1
+		}
+		if s.is(j, "}") && !s.is(j+1, "from") {
+			return j + 1
+		}
+	}
+	return len(s.tokens) - 1
+}
+
+type contextRole int
+
+const (
+	roleOther contextRole = iota
+	roleParams
+	roleClass
+	roleSpecifiers
+)
+
+// A context is a bracket that's open at some point of the code.
+type context struct {
+	role        contextRole
+	open        int // The index of the bracket
+	ternaries   int // `?` without their `:` yet
+	declStart   int // For the parameters of declarations, where the declaration starts, or -1
+	memberStart int // For class bodies, where the current member starts
+}
+
+func (s *typeStripper) strip() error {
+	var (
+		stack = []*context{{role: roleOther, declStart: -1}}
+
+		classDepth = -1 // The depth of a class header, if we're in one
+		varDepth   = -1 // The depth of a variable declaration, if we're in one
+		atBinding  bool // Whether a variable name or a destructuring pattern comes next
+		bindingEnd = -1 // The index of the last token of the current variable binding
+	)
+
+	for i := 0; i < len(s.tokens); i++ {
+		t := s.tokens[i]
+		if t.kind == tokEOF {
+			break
+		}
+		ctx := stack[len(stack)-1]
+
+		if ctx.role == roleClass && s.memberStart(i, ctx) {
+			ctx.memberStart = i
+			if end, ok := s.stripClassMember(i); ok {
+				if end < 0 {
+					return errors.Errorf("line %d: index signatures or declared members without an end", s.line(i))
+				}
+				i = end - 1
+				continue
+			}
+		}
+
+		if s.statementStart(i) && t.kind == tokIdent {
+			end, err := s.stripTypeStatement(i)
+			if err != nil {
+				return err
+			}
+			if end > i {
+				i = end - 1
+				continue
+			}
+		}
+
+		switch t.kind {
+		case tokIdent:
+			switch {
+			case (t.text == "let" || t.text == "const" || t.text == "var") && (s.isIdent(i+1) || s.is(i+1, "{", "[")):
+				varDepth, atBinding = len(stack), true
+				continue
+			case atBinding && varDepth == len(stack):
+				bindingEnd, atBinding = i, false
+			case t.text == "class" && !s.is(i-1, "."):
+				classDepth = len(stack)
+			case t.text == "implements" && classDepth == len(stack):
+				end := i + 1
+				for end < len(s.tokens) && !s.is(end, "{") && s.tokens[end].kind != tokEOF {
+					end++
+				}
+				s.blankTokens(i, end)
+				i = end - 1
+				continue
+			case (t.text == "as" || t.text == "satisfies") && isExprEnd(s.tokens[i-1]) && !s.is(i-1, "*") &&
+				ctx.role != roleSpecifiers && !s.is(i+1, ",", ")", "}", "=", ";"):
+				end := s.skipType(i+1, false)
+				s.blankTokens(i, end)
+				i = end - 1
+				continue
+			case ctx.role == roleParams && tsModifiers[t.text] && (s.isIdent(i+1) || s.is(i+1, "{", "[")) &&
+				s.is(i-1, "(", ","):
+				return errors.Errorf("line %d: TypeScript parameter properties like '%s %s' aren't supported, only types can be stripped",
+					s.line(i), t.text, s.tokens[i+1].text)
+			case ctx.role == roleParams && t.text == "this" && s.is(i-1, "(", ",") && s.is(i+1, ":"):
+				end := s.skipType(i+2, false)
+				if s.is(end, ",") {
+					end++
+				}
+				s.blankTokens(i, end)
+				i = end - 1
+				continue
+			case ctx.role == roleSpecifiers && t.text == "type" && s.isIdent(i+1) && s.is(i-1, "{", ","):
+				end := i + 2
+				if s.is(end, "as") {
+					end += 2
+				}
+				if s.is(end, ",") {
+					end++
+				}
+				s.blankTokens(i, end)
+				i = end - 1
+				continue
+			}
+		case tokPunct:
+			switch t.text {
+			case "<":
+				if !(s.isIdent(i-1) && !exprKeywords[s.tokens[i-1].text] || s.is(i-1, "]")) {
+					break
+				}
+				params := s.is(i-2, "function", "class", "interface") || ctx.role == roleClass
+				end := s.matchingAngle(i, params)
+				if end < 0 {
+					break
+				}
+				if s.is(end+1, "(") || s.is(end+1, "`") ||
+					classDepth == len(stack) && s.is(end+1, "{", "extends", "implements") {
+					s.blankTokens(i, end+1)
+					i = end
+					continue
+				}
+			case "?":
+				// Optional parameters and members, e.g. `(a?: string)` or `name?: string;`
+				if (ctx.role == roleParams || ctx.role == roleClass) && s.is(i+1, ":", ",", ")", ";", "=") &&
+					(s.isIdent(i-1) || s.is(i-1, "]", "}")) {
+					s.blankTokens(i, i+1)
+					continue
+				}
+				ctx.ternaries++
+			case "!":
+				// Non-null assertions, e.g. `user!.name`, and definite assignments, e.g. `let a!: T`
+				if isExprEnd(s.tokens[i-1]) && !s.is(i-1, "}") && !t.newline {
+					s.blankTokens(i, i+1)
+					if bindingEnd == i-1 {
+						bindingEnd = i
+					}
+					continue
+				}
+			case ":":
+				if ctx.ternaries > 0 {
+					ctx.ternaries--
+					break
+				}
+				if bindingEnd == i-1 && varDepth == len(stack) {
+					end := s.skipType(i+1, false)
+					s.blankTokens(i, end)
+					i = end - 1
+					continue
+				}
+				if ctx.role == roleParams {
+					end := s.skipType(i+1, false)
+					s.blankTokens(i, end)
+					i = end - 1
+					continue
+				}
+				if ctx.role == roleClass {
+					end := s.skipType(i+1, false)
+					if s.is(end, ";", "}") || s.tokens[end].newline && !s.is(end, "=") {
+						// Fields without initializers are only declarations
+						if s.is(end, ";") {
+							end++
+						}
+						s.blankTokens(ctx.memberStart, end)
+					} else {
+						s.blankTokens(i, end)
+					}
+					i = end - 1
+					continue
+				}
+			case ",":
+				if varDepth == len(stack) {
+					atBinding = true
+				}
+			case ";":
+				if varDepth == len(stack) {
+					varDepth, atBinding = -1, false
+				}
+			case "(", "[", "{":
+				c := &context{role: roleOther, open: i, declStart: -1, memberStart: i + 1}
+				switch {
+				case t.text == "(" && s.isParams(i):
+					c.role = roleParams
+					c.declStart = s.declarationStart(i, ctx)
+				case t.text == "{" && classDepth == len(stack):
+					c.role = roleClass
+					classDepth = -1
+				case t.text == "{" && (s.is(i-1, "import", "export", "type") || s.is(i-1, ",") && s.isImportDefault(i-2)):
+					c.role = roleSpecifiers
+				}
+				stack = append(stack, c)
+				continue
+			case ")", "]", "}":
+				if len(stack) == 1 {
+					break
+				}
+				stack = stack[:len(stack)-1]
+				if varDepth > len(stack) {
+					varDepth, atBinding = -1, false
+				}
+				if atBinding && varDepth == len(stack) {
+					bindingEnd, atBinding = i, false
+				}
+				if ctx.role == roleParams {
+					end, err := s.stripReturnType(i, ctx)
+					if err != nil {
+						return err
+					}
+					if end > i+1 {
+						i = end - 1
+					}
+				}
+				continue
+			}
+		}
+	}
+	return nil
+}
+
+func (s *typeStripper) line(i int) int {
+	return strings.Count(s.src[:s.tokens[i].start], "\n") + 1
+}
+
+// isImportDefault tells whether the token at i is the default import in `import a, { b } from`.
+func (s *typeStripper) isImportDefault(i int) bool {
+	return s.isIdent(i) && s.is(i-1, "import")
+}
+
+// isParams tells whether the parenthesis at i starts the parameters of a function.
+func (s *typeStripper) isParams(i int) bool {
+	p := s.prev(i)
+	if s.is(p, "function") || (s.isIdent(p) || s.is(p, "*")) && s.is(s.prev(p), "function") {
+		return true
+	}
+	end := s.matching(i)
+	if end < 0 {
+		return false
+	}
+	if s.is(end+1, "=>") {
+		return true
+	}
+	// Methods, e.g. `get name() {` in classes and objects
+	isName := s.isIdent(p) && !exprKeywords[s.tokens[p].text] || s.is(p, "]") || p >= 0 && s.tokens[p].kind == tokString
+	if s.is(end+1, "{") && isName {
+		return true
+	}
+	// Return types, e.g. `(a: number): number => a * 2`, but not `a ? (b) : c`
+	if s.is(end+1, ":") && !s.is(p, "?", "case") && !(s.isIdent(p) && exprKeywords[s.tokens[p].text]) {
+		after := s.skipType(end+2, true)
+		return s.is(after, "=>") || s.is(after, "{") && (isName || s.is(p, "async")) ||
+			isName && s.is(after, ";")
+	}
+	// Overload signatures and abstract methods, e.g. `parse(s: string);`
+	return false
+}
+
+// declarationStart returns where the declaration that the parameters at i belong to starts, if
+// it could be a declaration without a body, or -1.
+func (s *typeStripper) declarationStart(i int, parent *context) int {
+	if parent.role == roleClass {
+		return parent.memberStart
+	}
+	j := s.prev(i)
+	if s.isIdent(j) && !s.is(j, "function") {
+		j--
+	}
+	if !s.is(j, "function") || !s.statementStart(j) && !s.is(j-1, "export", "async", "default") {
+		return -1
+	}
+	for s.is(j-1, "export", "async", "default", "declare") {
+		j--
+	}
+	return j
+}
+
+// stripReturnType strips the return type after the parameters that end at i, or the whole
+// declaration if it doesn't have a body, and returns the index after what it stripped.
+func (s *typeStripper) stripReturnType(i int, params *context) (int, error) {
+	end := i + 1
+	if s.is(end, ":") {
+		end = s.skipType(i+2, true)
+		s.blankTokens(i+1, end)
+	}
+	if params.declStart >= 0 && !s.is(end, "{", "=>") {
+		if s.is(end, ";") {
+			end++
+		}
+		s.blankTokens(params.declStart, end)
+	}
+	return end, nil
+}
+
+// memberStart tells whether the token at i starts a member of the class whose body is ctx.
+func (s *typeStripper) memberStart(i int, ctx *context) bool {
+	if i == ctx.open+1 || s.is(i-1, ";") && ctx.memberStart != i {
+		return true
+	}
+	// After the body of a method, which is the only `}` that can be followed by a member
+	if s.is(i-1, "}") && s.tokens[i-1].start != s.tokens[ctx.open].start {
+		return true
+	}
+	return s.tokens[i].newline && (isExprEnd(s.tokens[i-1]) || s.is(i-1, ";"))
+}
+
+// stripClassMember strips the TypeScript modifiers of a class member that starts at i, or the
+// whole member if it's TypeScript-only. It returns where to go on, and whether it did anything.
+func (s *typeStripper) stripClassMember(i int) (int, bool) {
+	// Index signatures, e.g. `[key: string]: number;`
+	if s.is(i, "[") && s.isIdent(i+1) && s.is(i+2, ":") {
+		end := s.matching(i)
+		if end < 0 {
+			return -1, true
+		}
+		end = s.skipStatementType(end)
+		s.blankTokens(i, end)
+		return end, true
+	}
+
+	j := i
+	stripped := false
+	for ; s.isIdent(j); j++ {
+		text := s.tokens[j].text
+		if text == "static" || text == "async" {
+			continue
+		}
+		// A modifier is followed by a name, rather than being the name itself
+		if !tsModifiers[text] || !(s.isIdent(j+1) || s.is(j+1, "[", "*") || s.tokens[j+1].kind == tokString) ||
+			s.tokens[j+1].newline {
+			break
+		}
+		if text == "declare" {
+			end := s.skipStatementType(j)
+			s.blankTokens(i, end)
+			return end, true
+		}
+		s.blankTokens(j, j+1)
+		stripped = true
+	}
+	if stripped {
+		return j, true
+	}
+	return 0, false
+}
+
+// stripTypeStatement strips a TypeScript-only statement at i, like an interface or a type alias,
+// and returns the index after it, or i if there's none there.
+func (s *typeStripper) stripTypeStatement(i int) (int, error) {
+	j := i
+	if s.is(j, "export") {
+		j++
+		if s.is(j, "default") && s.is(j+1, "interface") {
+			j++
+		}
+	}
+	next := func(k int) string {
+		if s.tokens[k].kind == tokIdent {
+			return s.tokens[k].text
+		}
+		return ""
+	}
+
+	switch next(j) {
+	case "interface":
+		if s.isIdent(j + 1) {
+			end := s.skipStatementWithBody(j)
+			s.blankTokens(i, end)
+			return end, nil
+		}
+	case "type":
+		if s.is(j+1, "{", "*") && j > i {
+			end := s.skipModuleStatement(j)
+			s.blankTokens(i, end)
+			return end, nil
+		}
+		if s.isIdent(j+1) && s.is(j+2, "=", "<") {
+			end := s.skipStatementType(j)
+			s.blankTokens(i, end)
+			return end, nil
+		}
+	case "declare":
+		if s.isIdent(j+1) && !s.tokens[j+1].newline {
+			end := s.skipStatementType(j)
+			if s.is(j+1, "module", "namespace", "global", "class", "abstract", "enum", "interface") {
+				end = s.skipStatementWithBody(j)
+			}
+			s.blankTokens(i, end)
+			return end, nil
+		}
+	case "abstract":
+		if s.is(j+1, "class") {
+			s.blankTokens(j, j+1)
+		}
+	case "enum":
+		if s.isIdent(j + 1) {
+			return 0, errors.Errorf("line %d: TypeScript enums aren't supported, only types can be stripped; use an object instead", s.line(j))
+		}
+	case "const":
+		if s.is(j+1, "enum") {
+			return 0, errors.Errorf("line %d: TypeScript enums aren't supported, only types can be stripped; use an object instead", s.line(j))
+		}
+	case "namespace", "module":
+		if s.isIdent(j+1) && s.is(j+2, "{", ".") {
+			return 0, errors.Errorf("line %d: TypeScript namespaces aren't supported, only types can be stripped; use modules instead", s.line(j))
+		}
+	case "import":
+		if s.is(j+1, "type") && (s.isIdent(j+2) && !s.is(j+2, "from") || s.is(j+2, "{", "*")) {
+			end := s.skipModuleStatement(j)
+			s.blankTokens(i, end)
+			return end, nil
+		}
+	}
+	return i, nil
+}
@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package compiler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripTypes(t *testing.T) {
+	spaces := regexp.MustCompile(`\s+`)
+	testdata := map[string]string{
+		// Annotations
+		`let a: number = 1;`: `let a = 1;`,
+		`const a: Array<string> = [], b: Map<string, number[]> = new Map();`:     `const a = [], b = new Map();`,
+		`let {a, b}: Props = props;`:                                             `let {a, b} = props;`,
+		`let a!: string;`:                                                        `let a;`,
+		`let a = b ? c : d;`:                                                     `let a = b ? c : d;`,
+		`const o = {a: 1, b: c ? 2 : 3};`:                                        `const o = {a: 1, b: c ? 2 : 3};`,
+		`function f(a: string, b?: number, ...c: any[]): void {}`:                `function f(a, b, ...c) {}`,
+		`function f<T extends {id: string}>(a: T = x): Promise<T[]> {}`:          `function f(a = x) {}`,
+		`function f(this: Window, {a, b}: {a: string; b: number}) {}`:            `function f({a, b}) {}`,
+		`const f = (a: number, b = c ? 1 : 2): string => a + b;`:                 `const f = (a, b = c ? 1 : 2) => a + b;`,
+		`const f = async (a: number): Promise<void> => {};`:                      `const f = async (a) => {};`,
+		`const f = function (cb: (err: Error, data?: string) => void): void {};`: `const f = function (cb) {};`,
+		`x = y ? (z) : w;`:                                                       `x = y ? (z) : w;`,
+		`switch (a) { case (b): break; }`:                                        `switch (a) { case (b): break; }`,
+		`if (a) { b(); }`:                                                        `if (a) { b(); }`,
+		`foo: for (;;) { break foo; }`:                                           `foo: for (;;) { break foo; }`,
+		`catch_(e: unknown) {}`:                                                  `catch_(e) {}`,
+		// Expressions
+		`let a = b as unknown as string;`:           `let a = b;`,
+		`let a = (b as any).c;`:                     `let a = (b).c;`,
+		`let a = {x: 1} as const;`:                  `let a = {x: 1};`,
+		`let a = b satisfies C;`:                    `let a = b;`,
+		`let a = b!.c!.d;`:                          `let a = b.c.d;`,
+		`let a = !b; if (a !== !c) {}`:              `let a = !b; if (a !== !c) {}`,
+		`let m = new Map<string, Array<number>>();`: `let m = new Map();`,
+		`let a = f<string>(b);`:                     `let a = f(b);`,
+		`let a = b < c && d > (e);`:                 `let a = b < c && d > (e);`,
+		`let a = b < c, d = e > (f);`:               `let a = b < c, d = e > (f);`,
+		`let r = /a: b/.test(s) ? 1 : 2;`:           `let r = /a: b/.test(s) ? 1 : 2;`,
+		"let s = `a: ${b as string}`;":              "let s = `a: ${b as string}`;",
+		// Declarations
+		"interface A extends B { a: string; b(): void }\nlet a = 1;":       `let a = 1;`,
+		"export interface A<T> {\n  a: T\n}\nlet a = 1;":                   `let a = 1;`,
+		"type A = string | number;\nlet a = 1;":                            `let a = 1;`,
+		"type A<T> =\n  | { a: T }\n  | { b: T }\nlet a = 1;":              `let a = 1;`,
+		"export type A = B\nexport let a = 1;":                             `export let a = 1;`,
+		"let type = 1; type = 2;":                                          `let type = 1; type = 2;`,
+		"declare const __ENV: {[k: string]: string};\nlet a = 1;":          `let a = 1;`,
+		"declare module 'x' { export const a: number; }\nlet a = 1;":       `let a = 1;`,
+		"import type { A } from './a';\nlet a = 1;":                        `let a = 1;`,
+		"import type A from './a'\nlet a = 1;":                             `let a = 1;`,
+		"import { type A, b } from './a';":                                 `import { b } from './a';`,
+		"import { a as b } from './a';":                                    `import { a as b } from './a';`,
+		"import * as c from './a';":                                        `import * as c from './a';`,
+		"export type { A } from './a';\nlet a = 1;":                        `let a = 1;`,
+		"function f(a: string): string;\nfunction f(a: any) { return a; }": `function f(a) { return a; }`,
+		"export function f(a: string): string;\nexport function f(a) {}":   `export function f(a) {}`,
+		// Classes
+		`abstract class A<T> extends B<T> implements C, D<T> { }`: `class A extends B { }`,
+		"class A {\n  private a: string;\n  b?: number\n  readonly c = 1;\n  static d: number = 2;\n  public constructor(e: string) {}\n  protected get f(): number { return 1; }\n  g<T>(h: T): T { return h; }\n  [key: string]: any;\n  abstract i(): void;\n  declare j: string;\n  k(a: string): void;\n  k(a) {}\n  l = a ? b : c;\n}": "class A { c = 1; static d = 2; constructor(e) {} get f() { return 1; } g(h) { return h; } k(a) {} l = a ? b : c; }",
+		"class A { readonly = 1; private() {} }": "class A { readonly = 1; private() {} }",
+	}
+	for src, expected := range testdata {
+		t.Run(src, func(t *testing.T) {
+			code, err := StripTypes(src)
+			require.NoError(t, err)
+			assert.Equal(t, len(src), len(code), "the positions are kept")
+			assert.Equal(t, strings.Count(src, "\n"), strings.Count(code, "\n"), "the lines are kept")
+			normalize := func(s string) string {
+				s = spaces.ReplaceAllString(strings.TrimSpace(s), " ")
+				s = regexp.MustCompile(` ([,;)}(]|\.)`).ReplaceAllString(s, "$1")
+				return strings.Replace(s, "( ", "(", -1)
+			}
+			assert.Equal(t, normalize(expected), normalize(code))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			"let a = 1;\nenum A { B, C }":                   "line 2: TypeScript enums aren't supported",
+			"const enum A { B }":                            "TypeScript enums aren't supported",
+			"namespace A { }":                               "TypeScript namespaces aren't supported",
+			"class A { constructor(private a: string) {} }": "parameter properties like 'private a' aren't supported",
+			"let a = 'abc":                                  "unterminated string",
+			"let a = `abc":                                  "unterminated template literal",
+		}
+		for src, msg := range testdata {
+			_, err := StripTypes(src)
+			if assert.Error(t, err, src) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+}
@@ -23,17 +23,25 @@ package js
 import (
 	"context"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
 	log "github.com/sirupsen/logrus"
 )
 
 type Console struct {
 	Logger *log.Logger
+
+	// Shared by the consoles of all of a test's VUs, so that a message logged by every iteration
+	// doesn't flood the logs; nil if identical messages aren't limited.
+	limiter *logLimiter
 }
 
 func NewConsole() *Console {
-	return &Console{log.StandardLogger()}
+	return &Console{Logger: log.StandardLogger()}
 }
 
 func (c Console) log(ctx *context.Context, level log.Level, msgobj goja.Value, args ...goja.Value) {
@@ -49,7 +57,34 @@ func (c Console) log(ctx *context.Context, level log.Level, msgobj goja.Value, a
 	for i, arg := range args {
 		fields[strconv.Itoa(i)] = arg.String()
 	}
-	msg := msgobj.ToString()
+	msg := msgobj.ToString().String()
+
+	if c.limiter != nil {
+		key := make([]string, 0, len(args)+2)
+		key = append(key, level.String(), msg)
+		for _, arg := range args {
+			key = append(key, arg.String())
+		}
+		ok, suppressed := c.limiter.allow(strings.Join(key, "\x00"), time.Now())
+		if !ok {
+			return
+		}
+		if suppressed > 0 {
+			fields["suppressed"] = suppressed
+		}
+	}
+
+	// Where the message comes from, outside of the init context
+	if ctx != nil && *ctx != nil {
+		if state := common.GetState(*ctx); state != nil {
+			fields["vu"] = state.Vu
+			fields["iter"] = state.Iteration
+			if state.Scenario != "" {
+				fields["scenario"] = state.Scenario
+			}
+		}
+	}
+
 	e := c.Logger.WithFields(fields)
 	switch level {
 	case log.DebugLevel:
@@ -82,3 +117,50 @@ func (c Console) Warn(ctx *context.Context, msg goja.Value, args ...goja.Value)
 func (c Console) Error(ctx *context.Context, msg goja.Value, args ...goja.Value) {
 	c.log(ctx, log.ErrorLevel, msg, args...)
 }
+
+// Identical messages are logged at most once per logLimitInterval; the first one after that has
+// the number of the ones that weren't logged in a "suppressed" field.
+const logLimitInterval = time.Second
+
+// The number of different messages that a logLimiter remembers, before it forgets the ones that
+// it would allow again anyway.
+const logLimiterSize = 1000
+
+type logLimiter struct {
+	mutex    sync.Mutex
+	messages map[string]*limitedMessage
+}
+
+type limitedMessage struct {
+	logged     time.Time
+	suppressed int
+}
+
+// allow returns whether a message can be logged now, and how many identical ones weren't before.
+func (l *logLimiter) allow(key string, now time.Time) (bool, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	m, ok := l.messages[key]
+	if !ok {
+		if l.messages == nil {
+			l.messages = make(map[string]*limitedMessage)
+		}
+		if len(l.messages) >= logLimiterSize {
+			for k, m := range l.messages {
+				if now.Sub(m.logged) >= logLimitInterval {
+					delete(l.messages, k)
+				}
+			}
+		}
+		l.messages[key] = &limitedMessage{logged: now}
+		return true, 0
+	}
+	if now.Sub(m.logged) < logLimitInterval {
+		m.suppressed++
+		return false, 0
+	}
+	suppressed := m.suppressed
+	m.logged, m.suppressed = now, 0
+	return true, suppressed
+}
@@ -24,6 +24,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
@@ -33,6 +34,7 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConsoleContext(t *testing.T) {
@@ -41,7 +43,7 @@ func TestConsoleContext(t *testing.T) {
 
 	ctxPtr := new(context.Context)
 	logger, hook := logtest.NewNullLogger()
-	rt.Set("console", common.Bind(rt, &Console{logger}, ctxPtr))
+	rt.Set("console", common.Bind(rt, &Console{Logger: logger}, ctxPtr))
 
 	_, err := common.RunString(rt, `console.log("a")`)
 	assert.NoError(t, err)
@@ -111,9 +113,9 @@ func TestConsole(t *testing.T) {
 						assert.Equal(t, level, entry.Level)
 						assert.Equal(t, result.Message, entry.Message)
 
-						data := result.Data
-						if data == nil {
-							data = make(log.Fields)
+						data := log.Fields{"vu": int64(0), "iter": int64(0)}
+						for k, v := range result.Data {
+							data[k] = v
 						}
 						assert.Equal(t, data, entry.Data)
 					}
@@ -122,3 +124,52 @@ func TestConsole(t *testing.T) {
 		})
 	}
 }
+
+func TestConsoleLimit(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script",
+		Data: []byte(`export default function() {
+			for (let i = 0; i < 10; i++) {
+				console.log("same");
+				console.warn("same");
+				console.log("different", i);
+			}
+		}`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	logger, hook := logtest.NewNullLogger()
+	for i := 0; i < 2; i++ {
+		vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		vu.Console.Logger = logger
+		require.NoError(t, vu.RunOnce(context.Background()))
+	}
+
+	// The VUs share the limits
+	var same, different int
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "same" {
+			same++
+		} else {
+			different++
+		}
+	}
+	assert.Equal(t, 2, same)
+	assert.Equal(t, 10, different)
+
+	l := &logLimiter{}
+	now := time.Now()
+	ok, _ := l.allow("a", now)
+	assert.True(t, ok)
+	for i := 0; i < 5; i++ {
+		ok, _ = l.allow("a", now.Add(time.Duration(i)*100*time.Millisecond))
+		assert.False(t, ok)
+	}
+	ok, suppressed := l.allow("a", now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 5, suppressed)
+	ok, suppressed = l.allow("a", now.Add(2*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 0, suppressed)
+}
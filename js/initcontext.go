@@ -23,6 +23,7 @@ package js
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
@@ -54,6 +55,14 @@ type InitContext struct {
 	// Cache of loaded programs and files.
 	programs map[string]programWithSource
 	files    map[string][]byte
+
+	// The text of the files that were opened, shared with the VUs' init contexts; goja strings
+	// are immutable, so all VUs can use the same ones instead of each having its own copy.
+	texts *sharedTexts
+
+	// The modules that were required in this runtime, by filename, so that every module is only
+	// run once, and modules that require each other get each other's (partial) exports.
+	modules map[string]*goja.Object
 }
 
 func NewInitContext(rt *goja.Runtime, compiler *compiler.Compiler, ctxPtr *context.Context, fs afero.Fs, pwd string) *InitContext {
@@ -66,6 +75,8 @@ func NewInitContext(rt *goja.Runtime, compiler *compiler.Compiler, ctxPtr *conte
 
 		programs: make(map[string]programWithSource),
 		files:    make(map[string][]byte),
+		texts:    &sharedTexts{values: make(map[string]goja.Value)},
+		modules:  make(map[string]*goja.Object),
 	}
 }
 
@@ -79,6 +90,8 @@ func newBoundInitContext(base *InitContext, ctxPtr *context.Context, rt *goja.Ru
 
 		programs: base.programs,
 		files:    base.files,
+		texts:    base.texts,
+		modules:  make(map[string]*goja.Object),
 	}
 }
 
@@ -103,7 +116,7 @@ func (i *InitContext) Require(arg string) goja.Value {
 }
 
 func (i *InitContext) requireModule(name string) (goja.Value, error) {
-	mod, ok := modules.Index[name]
+	mod, ok := modules.Get(name)
 	if !ok {
 		return nil, errors.Errorf("unknown builtin module: %s", name)
 	}
@@ -113,26 +126,19 @@ func (i *InitContext) requireModule(name string) (goja.Value, error) {
 func (i *InitContext) requireFile(name string) (goja.Value, error) {
 	// Resolve the file path, push the target directory as pwd to make relative imports work.
 	pwd := i.pwd
-	filename := loader.Resolve(pwd, name)
+	filename := loader.ResolveModule(moduleFiles{i}, pwd, name)
 	i.pwd = loader.Dir(filename)
 	defer func() { i.pwd = pwd }()
 
-	// Swap the importing scope's exports out, then put it back again.
-	oldExports := i.runtime.Get("exports")
-	defer i.runtime.Set("exports", oldExports)
-	oldModule := i.runtime.Get("module")
-	defer i.runtime.Set("module", oldModule)
-	exports := i.runtime.NewObject()
-	i.runtime.Set("exports", exports)
-	module := i.runtime.NewObject()
-	_ = module.Set("exports", exports)
-	i.runtime.Set("module", module)
+	if module, ok := i.modules[filename]; ok {
+		return module.Get("exports"), nil
+	}
 
 	// First, check if we have a cached program already.
 	pgm, ok := i.programs[filename]
 	if !ok {
 		// Load the sources; the loader takes care of remote loading, etc.
-		data, err := loader.Load(i.fs, pwd, name)
+		data, err := loader.Load(i.fs, pwd, filename)
 		if err != nil {
 			return goja.Undefined(), err
 		}
@@ -149,19 +155,75 @@ func (i *InitContext) requireFile(name string) (goja.Value, error) {
 		i.programs[filename] = pgm
 	}
 
-	// Run the program.
-	if _, err := i.runtime.RunProgram(pgm.pgm); err != nil {
+	// Swap the importing scope's module variables out, then put them back again. The module is
+	// cached first, for modules that require each other.
+	module := i.runtime.NewObject()
+	exports := i.runtime.NewObject()
+	_ = module.Set("exports", exports)
+	i.modules[filename] = module
+	globals := map[string]interface{}{
+		"module":     module,
+		"exports":    exports,
+		"__filename": filename,
+		"__dirname":  i.pwd,
+	}
+	for name, value := range globals {
+		old := i.runtime.Get(name)
+		i.runtime.Set(name, value)
+		defer i.runtime.Set(name, old)
+	}
+
+	// Run the program, which evaluates to the module's function, with the exports as `this`.
+	v, err := i.runtime.RunProgram(pgm.pgm)
+	if err == nil {
+		fn, _ := goja.AssertFunction(v)
+		_, err = fn(exports)
+	}
+	if err != nil {
+		delete(i.modules, filename)
 		return goja.Undefined(), err
 	}
 
 	return module.Get("exports"), nil
 }
 
+// compileImport compiles a module into a function; JSON files are modules that export their data.
 func (i *InitContext) compileImport(src, filename string) (*goja.Program, error) {
-	pgm, _, err := i.compiler.Compile(src, filename, "(function(){", "})()", true)
+	if strings.HasSuffix(filename, ".json") {
+		src = "module.exports = " + src + ";"
+	}
+	pgm, _, err := i.compiler.Compile(src, filename, "(function(){", "\n})", true)
 	return pgm, err
 }
 
+// moduleFiles looks for modules on the init context's filesystem or, if it doesn't have one, like
+// the bound init contexts of VUs, among the scripts and files that its base loaded or archived.
+type moduleFiles struct {
+	i *InitContext
+}
+
+func (f moduleFiles) IsFile(filename string) bool {
+	if f.i.fs == nil {
+		_, ok := f.i.programs[filename]
+		return ok
+	}
+	info, err := f.i.fs.Stat(filename)
+	return err == nil && !info.IsDir()
+}
+
+func (f moduleFiles) ReadFile(filename string) ([]byte, bool) {
+	if data, ok := f.i.files[filename]; ok || f.i.fs == nil {
+		return data, ok
+	}
+	data, err := afero.ReadFile(f.i.fs, filename)
+	if err != nil {
+		return nil, false
+	}
+	// Keep the package.json, so that resolving the module works the same way from an archive
+	f.i.files[filename] = data
+	return data, true
+}
+
 func (i *InitContext) Open(name string, args ...string) (goja.Value, error) {
 	filename := loader.Resolve(i.pwd, name)
 	data, ok := i.files[filename]
@@ -177,5 +239,22 @@ func (i *InitContext) Open(name string, args ...string) (goja.Value, error) {
 	if len(args) > 0 && args[0] == "b" {
 		return i.runtime.ToValue(data), nil
 	}
-	return i.runtime.ToValue(string(data)), nil
+	return i.texts.get(i.runtime, filename, data), nil
+}
+
+// sharedTexts is a cache of the JS strings of files, which VUs instantiate concurrently.
+type sharedTexts struct {
+	mutex  sync.Mutex
+	values map[string]goja.Value
+}
+
+func (t *sharedTexts) get(rt *goja.Runtime, filename string, data []byte) goja.Value {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	v, ok := t.values[filename]
+	if !ok {
+		v = rt.ToValue(string(data))
+		t.values[filename] = v
+	}
+	return v
 }
@@ -28,11 +28,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/js/modules"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
@@ -42,6 +44,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+type testExtension struct{}
+
+func (testExtension) Greet(name string) string { return "hello, " + name }
+
 func TestInitContextRequire(t *testing.T) {
 	t.Run("Modules", func(t *testing.T) {
 		t.Run("Nonexistent", func(t *testing.T) {
@@ -49,6 +55,19 @@ func TestInitContextRequire(t *testing.T) {
 			assert.EqualError(t, err, "GoError: unknown builtin module: k6/NONEXISTENT")
 		})
 
+		t.Run("Extension", func(t *testing.T) {
+			modules.Register("k6/x/initcontext-test", testExtension{})
+			b, err := getSimpleBundle("/script.js", `
+					import { greet } from "k6/x/initcontext-test";
+					if (greet("k6") !== "hello, k6") { throw new Error("wrong greeting"); }
+					export default function() {}
+			`)
+			if assert.NoError(t, err) {
+				_, err = b.Instantiate()
+				assert.NoError(t, err)
+			}
+		})
+
 		t.Run("k6", func(t *testing.T) {
 			b, err := getSimpleBundle("/script.js", `
 					import k6 from "k6";
@@ -241,6 +260,67 @@ func TestInitContextRequire(t *testing.T) {
 	})
 }
 
+func TestInitContextRequireModules(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"/path/node_modules/leftpad/package.json":        `{ "name": "leftpad", "main": "lib/leftpad" }`,
+		"/path/node_modules/leftpad/lib/leftpad.js":      `module.exports = function(s, n) { while (s.length < n) { s = " " + s; } return s; };`,
+		"/path/to/node_modules/@scope/pkg/package.json":  `{ "exports": { ".": { "import": "./esm.js", "require": "./cjs.js" } } }`,
+		"/path/to/node_modules/@scope/pkg/cjs.js":        `exports.name = "cjs"; exports.file = __filename;`,
+		"/path/to/node_modules/@scope/pkg/esm.js":        `export const name = "esm";`,
+		"/path/to/node_modules/@scope/pkg/util/index.js": `export default function() { return "util"; }`,
+		"/path/to/lib/index.js":                          `import { b } from "./b"; export const a = "a"; export function getB() { return b; }`,
+		"/path/to/lib/b.js":                              `import { a } from "./index"; export const b = "b"; export const aInB = () => a;`,
+		"/path/to/lib/data.json":                         `{ "values": [1, 2, 3] }`,
+		"/path/to/lib/counter.js":                        `let count = 0; module.exports = { inc: () => ++count };`,
+	}
+	for name, data := range files {
+		assert.NoError(t, afero.WriteFile(fs, name, []byte(data), 0644))
+	}
+
+	b1, err := NewBundle(&lib.SourceData{
+		Filename: "/path/to/script.js",
+		Data: []byte(`
+		import leftpad from "leftpad";
+		import { name, file } from "@scope/pkg";
+		import util from "@scope/pkg/util";
+		import { a, getB } from "./lib";
+		import { aInB } from "./lib/b.js";
+		import data from "./lib/data.json";
+		const counter = require("./lib/counter");
+		counter.inc();
+		const sameCounter = require("./lib/counter.js");
+		export default function() {
+			const results = [leftpad("x", 3), name, file, util(), a, getB(), aInB(), data.values.length, sameCounter.inc()];
+			const expected = ["  x", "cjs", "/path/to/node_modules/@scope/pkg/cjs.js", "util", "a", "b", "a", 3, 2];
+			if (JSON.stringify(results) !== JSON.stringify(expected)) {
+				throw new Error("wrong results: " + JSON.stringify(results));
+			}
+		}
+		`),
+	}, fs, lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, b1.BaseInitContext.files, "/path/node_modules/leftpad/package.json")
+
+	b2, err := NewBundleFromArchive(b1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for name, b := range map[string]*Bundle{"Source": b1, "Archive": b2} {
+		t.Run(name, func(t *testing.T) {
+			bi, err := b.Instantiate()
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = bi.Default(goja.Undefined())
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func TestInitContextOpen(t *testing.T) {
 	fs := afero.NewMemMapFs()
 	assert.NoError(t, fs.MkdirAll("/path/to", 0755))
@@ -272,6 +352,30 @@ func TestInitContextOpen(t *testing.T) {
 		})
 	}
 
+	t.Run("Shared", func(t *testing.T) {
+		b, err := NewBundle(&lib.SourceData{
+			Filename: "/path/to/script.js",
+			Data:     []byte(`export let data = open("./file.txt"); export default function() {}`),
+		}, fs, lib.RuntimeOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// Instances are created concurrently, and get the string that the bundle's init read
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bi, err := b.Instantiate()
+				if assert.NoError(t, err) {
+					assert.Equal(t, b.BaseInitContext.texts.values["/path/to/file.txt"], bi.Runtime.Get("data"))
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
 	t.Run("Nonexistent", func(t *testing.T) {
 		_, err := NewBundle(&lib.SourceData{
 			Filename: "/script.js",
@@ -23,14 +23,41 @@
 package lib
 
 import (
+	"sync"
+
 	"github.com/GeertJohan/go.rice"
 	"github.com/dop251/goja"
 )
 
+var (
+	coreJS      *goja.Program
+	coreJSOnce  sync.Once
+	regenerator *goja.Program
+	regenOnce   sync.Once
+)
+
+// GetCoreJS returns the core-js polyfills of the ES2015+ standard library. It's compiled once and
+// shared by all VUs, since goja programs can be run in any number of runtimes.
 func GetCoreJS() *goja.Program {
-	return goja.MustCompile(
-		"core-js/shim.min.js",
-		rice.MustFindBox("core-js").MustString("shim.min.js"),
-		true,
-	)
+	coreJSOnce.Do(func() {
+		coreJS = goja.MustCompile(
+			"core-js/shim.min.js",
+			rice.MustFindBox("core-js").MustString("shim.min.js"),
+			true,
+		)
+	})
+	return coreJS
+}
+
+// GetRegeneratorRuntime returns the runtime of generators and async functions compiled by Babel.
+// It has to be run after core-js, which provides the Symbol.iterator it uses.
+func GetRegeneratorRuntime() *goja.Program {
+	regenOnce.Do(func() {
+		regenerator = goja.MustCompile(
+			"regenerator/runtime.js",
+			rice.MustFindBox("regenerator").MustString("runtime.js"),
+			false,
+		)
+	})
+	return regenerator
 }
@@ -39,3 +39,38 @@ func init() {
 		},
 	})
 }
+
+func init() {
+
+	// define files
+	file4 := &embedded.EmbeddedFile{
+		Filename:    "runtime.js",
+		FileModTime: time.Unix(1792038630, 0),
+		Content:     string("/**\n * The runtime of generator functions compiled by Babel's regenerator transform, which turns them\n * into state machines that call `regeneratorRuntime.mark()`, `regeneratorRuntime.wrap()` and the\n * methods of a context object. Async functions are compiled to generators, so they use it too.\n *\n * It implements the API of facebook/regenerator's runtime (MIT licensed), minus its own async\n * function support, which Babel's async-to-generator transform doesn't use.\n */\n(function(global) {\n    \"use strict\";\n\n    var hasOwn = Object.prototype.hasOwnProperty;\n    var iteratorSymbol = (typeof Symbol === \"function\" && Symbol.iterator) || \"@@iterator\";\n    var toStringTagSymbol = (typeof Symbol === \"function\" && Symbol.toStringTag) || \"@@toStringTag\";\n\n    var SuspendedStart = \"suspendedStart\";\n    var SuspendedYield = \"suspendedYield\";\n    var Executing = \"executing\";\n    var Completed = \"completed\";\n\n    // Returned by the context methods to make the state machine jump to context.next.\n    var ContinueSentinel = {};\n\n    function Generator() {}\n    function GeneratorFunction() {}\n    function GeneratorFunctionPrototype() {}\n\n    var IteratorPrototype = {};\n    IteratorPrototype[iteratorSymbol] = function() { return this; };\n\n    var Gp = GeneratorFunctionPrototype.prototype = Generator.prototype = Object.create(IteratorPrototype);\n    GeneratorFunction.prototype = Gp.constructor = GeneratorFunctionPrototype;\n    GeneratorFunctionPrototype.constructor = GeneratorFunction;\n    GeneratorFunctionPrototype[toStringTagSymbol] = GeneratorFunction.displayName = \"GeneratorFunction\";\n\n    [\"next\", \"throw\", \"return\"].forEach(function(method) {\n        Gp[method] = function(arg) { return this._invoke(method, arg); };\n    });\n    Gp[toStringTagSymbol] = \"Generator\";\n    Gp.toString = function() { return \"[object Generator]\"; };\n\n    function mark(genFun) {\n        if (Object.setPrototypeOf) {\n            Object.setPrototypeOf(genFun, GeneratorFunctionPrototype);\n        } else {\n            genFun.__proto__ = GeneratorFunctionPrototype;\n        }\n        genFun.prototype = Object.create(Gp);\n        return genFun;\n    }\n\n    function isGeneratorFunction(genFun) {\n        var ctor = typeof genFun === \"function\" && genFun.constructor;\n        return ctor ? ctor === GeneratorFunction || (ctor.displayName || ctor.name) === \"GeneratorFunction\" : false;\n    }\n\n    function wrap(innerFn, outerFn, self, tryLocsList) {\n        var protoGenerator = outerFn && outerFn.prototype instanceof Generator ? outerFn : Generator;\n        var generator = Object.create(protoGenerator.prototype);\n        var context = new Context(tryLocsList || []);\n        generator._invoke = makeInvokeMethod(innerFn, self, context);\n        return generator;\n    }\n\n    function tryCatch(fn, obj, arg) {\n        try {\n            return { type: \"normal\", arg: fn.call(obj, arg) };\n        } catch (err) {\n            return { type: \"throw\", arg: err };\n        }\n    }\n\n    function doneResult() {\n        return { value: undefined, done: true };\n    }\n\n    function makeInvokeMethod(innerFn, self, context) {\n        var state = SuspendedStart;\n\n        return function invoke(method, arg) {\n            if (state === Executing) {\n                throw new Error(\"Generator is already running\");\n            }\n            if (state === Completed) {\n                if (method === \"throw\") {\n                    throw arg;\n                }\n                return doneResult();\n            }\n\n            context.method = method;\n            context.arg = arg;\n\n            while (true) {\n                if (context.delegate) {\n                    var delegateResult = maybeInvokeDelegate(context.delegate, context);\n                    if (delegateResult === ContinueSentinel) {\n                        continue;\n                    }\n                    if (delegateResult) {\n                        return delegateResult;\n                    }\n                }\n\n                if (context.method === \"next\") {\n                    context.sent = context._sent = context.arg;\n                } else if (context.method === \"throw\") {\n                    if (state === SuspendedStart) {\n                        state = Completed;\n                        throw context.arg;\n                    }\n                    context.dispatchException(context.arg);\n                } else if (context.method === \"return\") {\n                    context.abrupt(\"return\", context.arg);\n                }\n\n                state = Executing;\n                var record = tryCatch(innerFn, self, context);\n                if (record.type === \"normal\") {\n                    state = context.done ? Completed : SuspendedYield;\n                    if (record.arg === ContinueSentinel) {\n                        continue;\n                    }\n                    return { value: record.arg, done: context.done };\n                }\n\n                // Dispatch the exception in the next loop, to the catch and finally blocks.\n                state = Completed;\n                context.method = \"throw\";\n                context.arg = record.arg;\n            }\n        };\n    }\n\n    // Forwards a next(), throw() or return() call to the iterator of a `yield*`, and returns its\n    // result, or ContinueSentinel if the generator has to carry on by itself.\n    function maybeInvokeDelegate(delegate, context) {\n        var method = delegate.iterator[context.method];\n        if (method === undefined) {\n            context.delegate = null;\n            if (context.method === \"throw\") {\n                if (delegate.iterator[\"return\"]) {\n                    context.method = \"return\";\n                    context.arg = undefined;\n                    maybeInvokeDelegate(delegate, context);\n                    if (context.method === \"throw\") {\n                        return ContinueSentinel;\n                    }\n                }\n                context.method = \"throw\";\n                context.arg = new TypeError(\"The iterator does not provide a 'throw' method\");\n            }\n            return ContinueSentinel;\n        }\n\n        var record = tryCatch(method, delegate.iterator, context.arg);\n        if (record.type === \"throw\") {\n            context.method = \"throw\";\n            context.arg = record.arg;\n            context.delegate = null;\n            return ContinueSentinel;\n        }\n\n        var info = record.arg;\n        if (!info) {\n            context.method = \"throw\";\n            context.arg = new TypeError(\"iterator result is not an object\");\n            context.delegate = null;\n            return ContinueSentinel;\n        }\n        if (!info.done) {\n            return info;\n        }\n\n        context[delegate.resultName] = info.value;\n        context.next = delegate.nextLoc;\n        if (context.method !== \"return\") {\n            context.method = \"next\";\n            context.arg = undefined;\n        }\n        context.delegate = null;\n        return ContinueSentinel;\n    }\n\n    function pushTryEntry(locs) {\n        var entry = { tryLoc: locs[0] };\n        if (1 in locs) {\n            entry.catchLoc = locs[1];\n        }\n        if (2 in locs) {\n            entry.finallyLoc = locs[2];\n            entry.afterLoc = locs[3];\n        }\n        this.tryEntries.push(entry);\n    }\n\n    function resetTryEntry(entry) {\n        var record = entry.completion || {};\n        record.type = \"normal\";\n        delete record.arg;\n        entry.completion = record;\n    }\n\n    // The state of a generator: the next location to jump to, its try statements, the value that\n    // was sent in, and its temporary variables (t0, t1, ...).\n    function Context(tryLocsList) {\n        this.tryEntries = [{ tryLoc: \"root\" }];\n        tryLocsList.forEach(pushTryEntry, this);\n        this.reset(true);\n    }\n\n    Context.prototype = {\n        constructor: Context,\n\n        reset: function(skipTempReset) {\n            this.prev = 0;\n            this.next = 0;\n            this.sent = this._sent = undefined;\n            this.done = false;\n            this.delegate = null;\n            this.method = \"next\";\n            this.arg = undefined;\n            this.tryEntries.forEach(resetTryEntry);\n\n            if (!skipTempReset) {\n                for (var name in this) {\n                    if (name.charAt(0) === \"t\" && hasOwn.call(this, name) && !isNaN(+name.slice(1))) {\n                        this[name] = undefined;\n                    }\n                }\n            }\n        },\n\n        stop: function() {\n            this.done = true;\n            var rootRecord = this.tryEntries[0].completion;\n            if (rootRecord.type === \"throw\") {\n                throw rootRecord.arg;\n            }\n            return this.rval;\n        },\n\n        dispatchException: function(exception) {\n            if (this.done) {\n                throw exception;\n            }\n\n            var context = this;\n            var record;\n            function handle(loc, caught) {\n                record.type = \"throw\";\n                record.arg = exception;\n                context.next = loc;\n                if (caught) {\n                    context.method = \"next\";\n                    context.arg = undefined;\n                }\n                return !!caught;\n            }\n\n            for (var i = this.tryEntries.length - 1; i >= 0; --i) {\n                var entry = this.tryEntries[i];\n                record = entry.completion;\n                if (entry.tryLoc === \"root\") {\n                    return handle(\"end\");\n                }\n                if (entry.tryLoc <= this.prev) {\n                    var hasCatch = hasOwn.call(entry, \"catchLoc\");\n                    var hasFinally = hasOwn.call(entry, \"finallyLoc\");\n                    if (!hasCatch && !hasFinally) {\n                        throw new Error(\"try statement without catch or finally\");\n                    }\n                    if (hasCatch && this.prev < entry.catchLoc) {\n                        return handle(entry.catchLoc, true);\n                    }\n                    if (hasFinally && this.prev < entry.finallyLoc) {\n                        return handle(entry.finallyLoc);\n                    }\n                }\n            }\n        },\n\n        abrupt: function(type, arg) {\n            var finallyEntry;\n            for (var i = this.tryEntries.length - 1; i >= 0; --i) {\n                var entry = this.tryEntries[i];\n                if (entry.tryLoc <= this.prev && hasOwn.call(entry, \"finallyLoc\") && this.prev < entry.finallyLoc) {\n                    finallyEntry = entry;\n                    break;\n                }\n            }\n            if (finallyEntry && (type === \"break\" || type === \"continue\") &&\n                finallyEntry.tryLoc <= arg && arg <= finallyEntry.finallyLoc) {\n                // A break or continue inside the try statement doesn't run its finally block.\n                finallyEntry = null;\n            }\n\n            var record = finallyEntry ? finallyEntry.completion : {};\n            record.type = type;\n            record.arg = arg;\n\n            if (finallyEntry) {\n                this.method = \"next\";\n                this.next = finallyEntry.finallyLoc;\n                return ContinueSentinel;\n            }\n            return this.complete(record);\n        },\n\n        complete: function(record, afterLoc) {\n            if (record.type === \"throw\") {\n                throw record.arg;\n            }\n\n            if (record.type === \"break\" || record.type === \"continue\") {\n                this.next = record.arg;\n            } else if (record.type === \"return\") {\n                this.rval = this.arg = record.arg;\n                this.method = \"return\";\n                this.next = \"end\";\n            } else if (record.type === \"normal\" && afterLoc) {\n                this.next = afterLoc;\n            }\n            return ContinueSentinel;\n        },\n\n        finish: function(finallyLoc) {\n            for (var i = this.tryEntries.length - 1; i >= 0; --i) {\n                var entry = this.tryEntries[i];\n                if (entry.finallyLoc === finallyLoc) {\n                    this.complete(entry.completion, entry.afterLoc);\n                    resetTryEntry(entry);\n                    return ContinueSentinel;\n                }\n            }\n        },\n\n        \"catch\": function(tryLoc) {\n            for (var i = this.tryEntries.length - 1; i >= 0; --i) {\n                var entry = this.tryEntries[i];\n                if (entry.tryLoc === tryLoc) {\n                    var record = entry.completion;\n                    var thrown;\n                    if (record.type === \"throw\") {\n                        thrown = record.arg;\n                        resetTryEntry(entry);\n                    }\n                    return thrown;\n                }\n            }\n            throw new Error(\"illegal catch attempt\");\n        },\n\n        delegateYield: function(iterable, resultName, nextLoc) {\n            this.delegate = { iterator: values(iterable), resultName: resultName, nextLoc: nextLoc };\n            if (this.method === \"next\") {\n                this.arg = undefined;\n            }\n            return ContinueSentinel;\n        }\n    };\n\n    // Returns an iterator over the keys of a `for...in` loop, skipping the deleted ones.\n    function keys(object) {\n        var names = [];\n        for (var name in object) {\n            names.push(name);\n        }\n        names.reverse();\n\n        return function next() {\n            while (names.length) {\n                var name = names.pop();\n                if (name in object) {\n                    next.value = name;\n                    next.done = false;\n                    return next;\n                }\n            }\n            next.done = true;\n            return next;\n        };\n    }\n\n    function values(iterable) {\n        if (iterable) {\n            var iteratorMethod = iterable[iteratorSymbol];\n            if (iteratorMethod) {\n                return iteratorMethod.call(iterable);\n            }\n            if (typeof iterable.next === \"function\") {\n                return iterable;\n            }\n            if (!isNaN(iterable.length)) {\n                var i = -1;\n                var next = function next() {\n                    while (++i < iterable.length) {\n                        if (hasOwn.call(iterable, i)) {\n                            next.value = iterable[i];\n                            next.done = false;\n                            return next;\n                        }\n                    }\n                    next.value = undefined;\n                    next.done = true;\n                    return next;\n                };\n                return next.next = next;\n            }\n        }\n        return { next: doneResult };\n    }\n\n    global.regeneratorRuntime = {\n        mark: mark,\n        wrap: wrap,\n        isGeneratorFunction: isGeneratorFunction,\n        keys: keys,\n        values: values\n    };\n})(this);\n"),
+	}
+
+	// define dirs
+	dir3 := &embedded.EmbeddedDir{
+		Filename:   "",
+		DirModTime: time.Unix(1792038630, 0),
+		ChildFiles: []*embedded.EmbeddedFile{
+			file4, // "runtime.js"
+
+		},
+	}
+
+	// link ChildDirs
+	dir3.ChildDirs = []*embedded.EmbeddedDir{}
+
+	// register embeddedBox
+	embedded.RegisterEmbeddedBox(`regenerator`, &embedded.EmbeddedBox{
+		Name: `regenerator`,
+		Time: time.Unix(1792038630, 0),
+		Dirs: map[string]*embedded.EmbeddedDir{
+			"": dir3,
+		},
+		Files: map[string]*embedded.EmbeddedFile{
+			"runtime.js": file4,
+		},
+	})
+}
@@ -0,0 +1,144 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package js
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/lib"
+	"github.com/spf13/afero"
+)
+
+// A LintWarning is something in a script that works, but likely not the way it was meant to.
+type LintWarning struct {
+	Function string // The exported function it's in, or "options"
+	Message  string
+}
+
+func (w LintWarning) String() string {
+	if w.Function == "options" {
+		return "options: " + w.Message
+	}
+	return fmt.Sprintf("%s(): %s", w.Function, w.Message)
+}
+
+var (
+	// Imported functions are called like `(0, _k6.sleep)(1)` after Babel's transform
+	lintSleepCall = regexp.MustCompile(`\bsleep\s*\)?\s*\(`)
+	lintNewMetric = regexp.MustCompile(`\bnew\s+(?:[\w$]+\.)*(Counter|Gauge|Rate|Trend)\s*\(`)
+	lintOpenCall  = regexp.MustCompile(`(?:^|[^\w$.])open\s*\(`)
+)
+
+// Lint looks for common mistakes in a script. The script is compiled and its init context is run,
+// as for a test run, so syntax errors, exceptions in the init context and a missing default
+// function are returned as errors. The warnings are about the options, like unknown ones,
+// thresholds that can't work or scenarios that run functions that don't exist, and about the code
+// of the exported functions: sleep() in setup() or teardown(), which delays the test for all VUs,
+// and metrics created or files opened in any of them, which only works in the init context. Only
+// the exported functions' own code is checked, not the code of the functions that they call.
+func Lint(src *lib.SourceData, fs afero.Fs, rtOpts lib.RuntimeOptions) ([]LintWarning, error) {
+	var warnings []LintWarning
+	b, err := newBundle(src, fs, rtOpts, func(data []byte, opts lib.Options) error {
+		problems, err := optionProblems(data, opts)
+		for _, problem := range problems {
+			warnings = append(warnings, LintWarning{"options", problem})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	bi, err := b.Instantiate()
+	if err != nil {
+		return nil, err
+	}
+	rt := bi.Runtime
+	exports := rt.Get("exports").ToObject(rt)
+
+	names := make([]string, 0, len(b.Options.Scenarios))
+	for name := range b.Options.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		exec := b.Options.Scenarios[name].GetExec()
+		if _, ok := goja.AssertFunction(exports.Get(exec)); !ok {
+			warnings = append(warnings, LintWarning{"options", fmt.Sprintf("scenario '%s' runs '%s', which isn't an exported function", name, exec)})
+		}
+	}
+
+	fns := exports.Keys()
+	sort.Strings(fns)
+	for _, name := range fns {
+		v := exports.Get(name)
+		if _, ok := goja.AssertFunction(v); !ok {
+			continue
+		}
+		code := stripStringsAndComments(v.String())
+		if (name == "setup" || name == "teardown") && lintSleepCall.MatchString(code) {
+			warnings = append(warnings, LintWarning{name, "it calls sleep(), which delays the test for all VUs, since they wait for " + name + "() to finish"})
+		}
+		if m := lintNewMetric.FindStringSubmatch(code); m != nil {
+			warnings = append(warnings, LintWarning{name, fmt.Sprintf("it creates a %s metric, but metrics can only be created in the init context, outside of the exported functions", m[1])})
+		}
+		if lintOpenCall.MatchString(code) {
+			warnings = append(warnings, LintWarning{name, "it calls open(), which only works in the init context; open the file outside of the exported functions"})
+		}
+	}
+	return warnings, nil
+}
+
+// stripStringsAndComments blanks out the string literals and comments of JS code, so that what's
+// in them isn't mistaken for code.
+func stripStringsAndComments(code string) string {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			b.WriteString(`""`)
+			for i++; i < len(code) && code[i] != c; i++ {
+				if code[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(code) && code[i+1] == '/':
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+			b.WriteByte('\n')
+		case c == '/' && i+1 < len(code) && code[i+1] == '*':
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 3
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package js
+
+import (
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/data.txt", []byte("data"), 0644))
+	lint := func(data string) ([]string, error) {
+		warnings, err := Lint(&lib.SourceData{Filename: "/script.js", Data: []byte(data)}, fs, lib.RuntimeOptions{})
+		if err != nil {
+			return nil, err
+		}
+		s := make([]string, len(warnings))
+		for i, w := range warnings {
+			s[i] = w.String()
+		}
+		return s, nil
+	}
+
+	t.Run("Clean", func(t *testing.T) {
+		warnings, err := lint(`
+		import { sleep } from "k6";
+		import { Trend } from "k6/metrics";
+		let trend = new Trend("my_trend");
+		let data = open("/data.txt");
+		export function setup() {
+			// sleep(1) isn't called, new Trend("x") isn't created
+			return { msg: "sleep(1) and open('x')" };
+		}
+		export default function() { trend.add(1); sleep(1); }
+		`)
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("Mistakes", func(t *testing.T) {
+		warnings, err := lint(`
+		import { sleep } from "k6";
+		import { Counter } from "k6/metrics";
+		import * as metrics from "k6/metrics";
+		export let options = { vus: 1, vu: 2, scenarios: { browse: { exec: "browse" }, search: { exec: "search" } } };
+		export function setup() { sleep(10); }
+		export function browse() { let data = open("/data.json"); }
+		export default function() { new Counter("per_iteration").add(1); }
+		export function teardown() { new metrics.Rate("rate"); }
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"options: unknown option vu",
+			"options: scenario 'search' runs 'search', which isn't an exported function",
+			"browse(): it calls open(), which only works in the init context; open the file outside of the exported functions",
+			"default(): it creates a Counter metric, but metrics can only be created in the init context, outside of the exported functions",
+			"setup(): it calls sleep(), which delays the test for all VUs, since they wait for setup() to finish",
+			"teardown(): it creates a Rate metric, but metrics can only be created in the init context, outside of the exported functions",
+		}, warnings)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := lint(`export function setup() {}`)
+		assert.EqualError(t, err, "script must export a default function")
+		_, err = lint(`export default function( {}`)
+		assert.Error(t, err)
+	})
+}
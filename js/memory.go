@@ -0,0 +1,86 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package js
+
+import (
+	"reflect"
+
+	"github.com/dop251/goja"
+)
+
+// Rough sizes of JS values in goja, in bytes, for estimating a VU's memory usage.
+const (
+	valueSize    = 16 // Numbers, booleans and references
+	objectSize   = 64
+	propertySize = 48 // Plus the length of the key
+)
+
+// heapSize estimates the memory that the values reachable from a runtime's global object take,
+// in bytes, and stops counting once it's above max. It follows the enumerable own properties of
+// objects, so what's only reachable from closures or non-enumerable properties isn't counted;
+// leaky scripts usually keep what they leak in global or exported variables, which are.
+func heapSize(rt *goja.Runtime, max int64) (size int64) {
+	seen := make(map[*goja.Object]bool)
+	stack := []goja.Value{rt.GlobalObject()}
+	walk := func(goja.FunctionCall) goja.Value {
+		for len(stack) > 0 && size <= max {
+			v := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			obj, ok := v.(*goja.Object)
+			if !ok {
+				size += valueSize
+				if t := v.ExportType(); t != nil && t.Kind() == reflect.String {
+					size += int64(len(v.String()))
+				}
+				continue
+			}
+			if seen[obj] {
+				continue
+			}
+			seen[obj] = true
+
+			// Byte slices from Go, e.g. from open(..., "b"), would have a property for every byte
+			if t := obj.ExportType(); t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+				size += objectSize + int64(reflect.ValueOf(obj.Export()).Len())
+				continue
+			}
+			size += objectSize
+			for _, key := range obj.Keys() {
+				size += propertySize + int64(len(key))
+				if pv := obj.Get(key); pv != nil {
+					stack = append(stack, pv)
+				}
+			}
+		}
+		return goja.Undefined()
+	}
+
+	// The walk runs as a JS function, since getters can throw; if one does, it goes on with the
+	// rest, without the other properties of the getter's object.
+	fn, _ := goja.AssertFunction(rt.ToValue(walk))
+	for len(stack) > 0 && size <= max {
+		if _, err := fn(goja.Undefined()); err == nil {
+			break
+		}
+	}
+	return size
+}
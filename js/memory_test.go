@@ -0,0 +1,54 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package js
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeapSize(t *testing.T) {
+	rt := goja.New()
+	empty := heapSize(rt, 1<<30)
+
+	rt.Set("text", strings.Repeat("a", 1<<20))
+	rt.Set("bytes", make([]byte, 1<<20))
+	_, err := rt.RunString(`
+		var list = [];
+		for (var i = 0; i < 1000; i++) { list.push({ id: i, name: "item " + i }); }
+		var cycle = { list: list };
+		cycle.self = cycle;
+		Object.defineProperty(this, "broken", { enumerable: true, get: function() { throw new Error("nope"); } });
+	`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	size := heapSize(rt, 1<<30)
+	assert.True(t, size-empty > 2<<20, "size %d", size)
+	assert.True(t, size-empty < 3<<20, "size %d", size)
+
+	// It stops counting once it's above the max
+	assert.True(t, heapSize(rt, 1024) < 1<<20)
+}
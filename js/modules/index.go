@@ -21,22 +21,85 @@
 package modules
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/loadimpact/k6/js/modules/k6"
+	"github.com/loadimpact/k6/js/modules/k6/aws"
+	"github.com/loadimpact/k6/js/modules/k6/browser"
 	"github.com/loadimpact/k6/js/modules/k6/crypto"
+	"github.com/loadimpact/k6/js/modules/k6/data"
 	"github.com/loadimpact/k6/js/modules/k6/encoding"
+	"github.com/loadimpact/k6/js/modules/k6/expect"
+	"github.com/loadimpact/k6/js/modules/k6/faker"
 	"github.com/loadimpact/k6/js/modules/k6/html"
 	"github.com/loadimpact/k6/js/modules/k6/http"
 	"github.com/loadimpact/k6/js/modules/k6/metrics"
+	"github.com/loadimpact/k6/js/modules/k6/mqtt"
+	"github.com/loadimpact/k6/js/modules/k6/net"
+	"github.com/loadimpact/k6/js/modules/k6/redis"
+	"github.com/loadimpact/k6/js/modules/k6/secrets"
+	"github.com/loadimpact/k6/js/modules/k6/shared"
+	"github.com/loadimpact/k6/js/modules/k6/socketio"
+	"github.com/loadimpact/k6/js/modules/k6/sse"
 	"github.com/loadimpact/k6/js/modules/k6/ws"
+	"github.com/loadimpact/k6/js/modules/k6/xml"
 )
 
-// Index of module implementations.
+// ExtensionPrefix is the prefix that the names of modules registered by extensions have to have.
+const ExtensionPrefix = "k6/x/"
+
+// Index of module implementations. Extensions add to it with Register, which is safe to call
+// from init(); everything else should look modules up with Get.
 var Index = map[string]interface{}{
 	"k6":          k6.New(),
+	"k6/aws":      aws.New(),
+	"k6/browser":  browser.New(),
 	"k6/crypto":   crypto.New(),
+	"k6/data":     data.New(),
 	"k6/encoding": encoding.New(),
+	"k6/expect":   expect.New(),
+	"k6/faker":    faker.New(),
 	"k6/http":     http.New(),
 	"k6/metrics":  metrics.New(),
+	"k6/mqtt":     mqtt.New(),
 	"k6/html":     html.New(),
+	"k6/net":      net.New(),
+	"k6/redis":    redis.New(),
+	"k6/secrets":  secrets.New(),
+	"k6/shared":   shared.New(),
+	"k6/socketio": socketio.New(),
+	"k6/sse":      sse.New(),
 	"k6/ws":       ws.New(),
+	"k6/xml":      xml.New(),
+}
+
+var indexMutex sync.RWMutex
+
+// Register makes a module importable by scripts. It's meant to be called from the init() of
+// extension packages, which are compiled into k6 with k6-build. The name has to start with
+// "k6/x/", so extensions can't shadow builtin modules; registering a name twice panics.
+func Register(name string, mod interface{}) {
+	if !strings.HasPrefix(name, ExtensionPrefix) || len(name) == len(ExtensionPrefix) {
+		panic(fmt.Sprintf("invalid module name %q: extension modules have to be named %s<name>", name, ExtensionPrefix))
+	}
+	if mod == nil {
+		panic(fmt.Sprintf("module %s is nil", name))
+	}
+
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	if _, ok := Index[name]; ok {
+		panic(fmt.Sprintf("module %s is already registered", name))
+	}
+	Index[name] = mod
+}
+
+// Get returns the module with the given name, builtin or registered by an extension.
+func Get(name string) (interface{}, bool) {
+	indexMutex.RLock()
+	defer indexMutex.RUnlock()
+	mod, ok := Index[name]
+	return mod, ok
 }
@@ -0,0 +1,46 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package modules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	mod := struct{}{}
+	Register("k6/x/register-test", mod)
+	v, ok := Get("k6/x/register-test")
+	assert.True(t, ok)
+	assert.Equal(t, mod, v)
+
+	_, ok = Get("k6/x/missing")
+	assert.False(t, ok)
+
+	assert.PanicsWithValue(t, "module k6/x/register-test is already registered", func() {
+		Register("k6/x/register-test", mod)
+	})
+	for _, name := range []string{"k6/http", "k6/x/", "x/test", "test"} {
+		assert.Panics(t, func() { Register(name, mod) }, name)
+	}
+	assert.Panics(t, func() { Register("k6/x/nil", nil) })
+}
@@ -0,0 +1,289 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package aws
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	amzDayFormat     = "20060102"
+)
+
+type AWS struct{}
+
+func New() *AWS {
+	return &AWS{}
+}
+
+// Request is what gets signed, see SignRequest.
+type Request struct {
+	Method  string
+	URL     *url.URL
+	Headers map[string]string
+	Body    []byte
+}
+
+// Credentials and the scope to sign requests for.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Region          string
+	Service         string
+}
+
+// SignRequest signs a request with AWS Signature Version 4, and returns the headers it should be
+// sent with - the given ones, plus Authorization, X-Amz-Date and, if needed, X-Amz-Security-Token
+// and X-Amz-Content-Sha256.
+//
+// request has the method, url, headers and body of the request; credentials has accessKeyId,
+// secretAccessKey, an optional sessionToken, and the region and service to sign for. The signing
+// time can be given as a timestamp (in ms since the epoch) in credentials, for reproducibility.
+func (*AWS) SignRequest(request map[string]interface{}, credentials map[string]interface{}) (map[string]string, error) {
+	req := Request{Method: "GET", Headers: make(map[string]string)}
+	var rawurl string
+	for k, v := range request {
+		switch k {
+		case "method":
+			req.Method = strings.ToUpper(fmt.Sprint(v))
+		case "url":
+			rawurl = fmt.Sprint(v)
+		case "headers":
+			headers, ok := v.(map[string]interface{})
+			if !ok && v != nil {
+				return nil, errors.New("headers must be an object")
+			}
+			for name, value := range headers {
+				req.Headers[name] = fmt.Sprint(value)
+			}
+		case "body":
+			switch body := v.(type) {
+			case nil:
+			case []byte:
+				req.Body = body
+			case string:
+				req.Body = []byte(body)
+			default:
+				return nil, errors.Errorf("unsupported body type %T", v)
+			}
+		}
+	}
+	if rawurl == "" {
+		return nil, errors.New("the request needs a url")
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	req.URL = u
+
+	var creds Credentials
+	signingTime := time.Now()
+	for k, v := range credentials {
+		switch k {
+		case "accessKeyId":
+			creds.AccessKeyID = fmt.Sprint(v)
+		case "secretAccessKey":
+			creds.SecretAccessKey = fmt.Sprint(v)
+		case "sessionToken":
+			if v != nil {
+				creds.SessionToken = fmt.Sprint(v)
+			}
+		case "region":
+			creds.Region = fmt.Sprint(v)
+		case "service":
+			creds.Service = fmt.Sprint(v)
+		case "timestamp":
+			switch ts := v.(type) {
+			case int64:
+				signingTime = time.Unix(0, ts*int64(time.Millisecond))
+			case float64:
+				signingTime = time.Unix(0, int64(ts*float64(time.Millisecond)))
+			default:
+				return nil, errors.New("timestamp must be a number of milliseconds")
+			}
+		}
+	}
+	switch {
+	case creds.AccessKeyID == "" || creds.SecretAccessKey == "":
+		return nil, errors.New("accessKeyId and secretAccessKey are required")
+	case creds.Region == "" || creds.Service == "":
+		return nil, errors.New("region and service are required")
+	}
+
+	return Sign(req, creds, signingTime), nil
+}
+
+// Sign signs a request with AWS Signature Version 4, see SignRequest.
+func Sign(req Request, creds Credentials, signingTime time.Time) map[string]string {
+	signingTime = signingTime.UTC()
+	amzDate := signingTime.Format(amzDateFormat)
+	scope := strings.Join([]string{signingTime.Format(amzDayFormat), creds.Region, creds.Service, "aws4_request"}, "/")
+
+	headers := make(map[string]string, len(req.Headers)+4)
+	for name, value := range req.Headers {
+		headers[name] = value
+	}
+	setHeader(headers, "X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		setHeader(headers, "X-Amz-Security-Token", creds.SessionToken)
+	}
+	payloadHash := hexSHA256(req.Body)
+	// Only S3 wants (and requires) the payload hash as a header.
+	if creds.Service == "s3" {
+		setHeader(headers, "X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// The canonical headers always include the host, which net/http sets on its own.
+	canonical := map[string]string{"host": req.URL.Host}
+	for name, value := range headers {
+		canonical[strings.ToLower(name)] = strings.Join(strings.Fields(value), " ")
+	}
+	names := make([]string, 0, len(canonical))
+	for name := range canonical {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + canonical[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL, creds.Service),
+		canonicalQuery(req.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		signingAlgorithm,
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), signingTime.Format(amzDayFormat))
+	key = hmacSHA256(key, creds.Region)
+	key = hmacSHA256(key, creds.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	setHeader(headers, "Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signingAlgorithm, creds.AccessKeyID, scope, signedHeaders, signature))
+	return headers
+}
+
+// setHeader sets a header, replacing any existing one regardless of how its name is cased.
+func setHeader(headers map[string]string, name, value string) {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+	headers[name] = value
+}
+
+// canonicalURI normalizes and encodes the path; S3 wants it as it is and encoded once, every
+// other service wants it normalized and encoded twice.
+func canonicalURI(u *url.URL, service string) string {
+	if service == "s3" {
+		p := u.Path
+		if p == "" {
+			p = "/"
+		}
+		return uriEncode(p, false)
+	}
+
+	p := u.EscapedPath()
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return uriEncode(cleaned, false)
+}
+
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	pairs := make([][2]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, [2]string{uriEncode(key, true), uriEncode(value, true)})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	encoded := make([]string, len(pairs))
+	for i, pair := range pairs {
+		encoded[i] = pair[0] + "=" + pair[1]
+	}
+	return strings.Join(encoded, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters, and slashes unless asked to.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
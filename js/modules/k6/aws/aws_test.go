@@ -0,0 +1,156 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package aws
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// From the AWS Signature Version 4 test suite.
+var testCredentials = Credentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	Region:          "us-east-1",
+	Service:         "service",
+}
+
+var testTime = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+func TestSign(t *testing.T) {
+	testdata := map[string]struct {
+		method, url, authorization string
+	}{
+		"get-vanilla": {
+			"GET", "https://example.amazonaws.com/",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		"get-vanilla-query-order-key-case": {
+			"GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		"post-vanilla": {
+			"POST", "https://example.amazonaws.com/",
+			"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			u, err := url.Parse(data.url)
+			require.NoError(t, err)
+			headers := Sign(Request{Method: data.method, URL: u}, testCredentials, testTime)
+			assert.Equal(t, map[string]string{
+				"X-Amz-Date":    "20150830T123600Z",
+				"Authorization": data.authorization,
+			}, headers)
+		})
+	}
+
+	t.Run("session token and s3", func(t *testing.T) {
+		u, err := url.Parse("https://examplebucket.s3.amazonaws.com/my%20file.txt")
+		require.NoError(t, err)
+		creds := testCredentials
+		creds.Service = "s3"
+		creds.SessionToken = "token"
+		headers := Sign(Request{
+			Method:  "PUT",
+			URL:     u,
+			Headers: map[string]string{"Content-Type": "text/plain", "x-amz-date": "overwritten"},
+			Body:    []byte("hello"),
+		}, creds, testTime)
+
+		assert.Equal(t, "20150830T123600Z", headers["X-Amz-Date"])
+		assert.Equal(t, "token", headers["X-Amz-Security-Token"])
+		assert.Equal(t, "text/plain", headers["Content-Type"])
+		assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", headers["X-Amz-Content-Sha256"])
+		assert.NotContains(t, headers, "x-amz-date")
+		assert.Contains(t, headers["Authorization"],
+			"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=")
+	})
+}
+
+func TestCanonicalURI(t *testing.T) {
+	testdata := map[string][2]string{
+		"https://example.com":                  {"/", "/"},
+		"https://example.com/a/./b/../c":       {"/a/c", "/a/./b/../c"},
+		"https://example.com/a//b/":            {"/a/b/", "/a//b/"},
+		"https://example.com/my%20file.txt":    {"/my%2520file.txt", "/my%20file.txt"},
+		"https://example.com/%E1%88%B4/x~y_z-": {"/%25E1%2588%25B4/x~y_z-", "/%E1%88%B4/x~y_z-"},
+	}
+	for rawurl, expected := range testdata {
+		t.Run(rawurl, func(t *testing.T) {
+			u, err := url.Parse(rawurl)
+			require.NoError(t, err)
+			assert.Equal(t, expected[0], canonicalURI(u, "service"))
+			assert.Equal(t, expected[1], canonicalURI(u, "s3"))
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("aws", common.Bind(rt, New(), &ctx))
+
+	t.Run("get-vanilla", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		aws.signRequest({ method: "get", url: "https://example.amazonaws.com/" }, {
+			accessKeyId: "AKIDEXAMPLE",
+			secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			region: "us-east-1",
+			service: "service",
+			timestamp: Date.UTC(2015, 7, 30, 12, 36, 0),
+		});
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"X-Amz-Date":    "20150830T123600Z",
+			"Authorization": "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		}, v.Export())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`aws.signRequest({}, {})`: "the request needs a url",
+			`aws.signRequest({ url: "https://example.com" }, { region: "us-east-1", service: "s3" })`:       "accessKeyId and secretAccessKey are required",
+			`aws.signRequest({ url: "https://example.com" }, { accessKeyId: "a", secretAccessKey: "b" })`:   "region and service are required",
+			`aws.signRequest({ url: "https://example.com", headers: "nope" }, {})`:                          "headers must be an object",
+			`aws.signRequest({ url: "https://example.com", body: 123 }, {})`:                                "unsupported body type int64",
+			`aws.signRequest({ url: "https://example.com" }, { accessKeyId: "a", timestamp: "yesterday" })`: "timestamp must be a number of milliseconds",
+		}
+		for code, msg := range testdata {
+			t.Run(code, func(t *testing.T) {
+				_, err := common.RunString(rt, code)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), msg)
+			})
+		}
+	})
+}
@@ -0,0 +1,302 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package browser
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// The executables that launch() looks for in the PATH, unless it's given one.
+var executableNames = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"}
+
+type Browser struct{}
+
+func New() *Browser {
+	return &Browser{}
+}
+
+// Instance is a running browser, as returned by browser.launch() or browser.connect().
+type Instance struct {
+	ctx     context.Context
+	conn    *cdpConn
+	timeout time.Duration
+	done    chan struct{}
+
+	// Only set for browsers started by launch().
+	cmd         *exec.Cmd
+	exited      chan struct{}
+	userDataDir string
+}
+
+type options struct {
+	executablePath string
+	headless       bool
+	args           []string
+	timeout        time.Duration
+}
+
+func parseOptions(rt *goja.Runtime, v goja.Value) (options, error) {
+	opts := options{headless: true, timeout: defaultTimeout}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return opts, nil
+	}
+	params := v.ToObject(rt)
+	for _, k := range params.Keys() {
+		v := params.Get(k)
+		if goja.IsUndefined(v) || goja.IsNull(v) {
+			continue
+		}
+		switch k {
+		case "executablePath":
+			opts.executablePath = v.String()
+		case "headless":
+			opts.headless = v.ToBoolean()
+		case "args":
+			if err := rt.ExportTo(v, &opts.args); err != nil {
+				return opts, errors.Wrap(err, "args must be an array of strings")
+			}
+		case "timeout":
+			opts.timeout = time.Duration(v.ToFloat() * float64(time.Millisecond))
+		}
+	}
+	return opts, nil
+}
+
+// Launch starts a Chromium based browser and connects to it. The optional params can contain the
+// executablePath of the browser, whether it's headless (the default), extra command line args,
+// and the timeout in milliseconds for launching, navigating and waiting for selectors.
+func (*Browser) Launch(ctx context.Context, params goja.Value) (*Instance, error) {
+	if common.GetState(ctx) == nil {
+		return nil, errors.New("Browsers can't be launched in the init context")
+	}
+	opts, err := parseOptions(common.GetRuntime(ctx), params)
+	if err != nil {
+		return nil, err
+	}
+
+	path := opts.executablePath
+	if path == "" {
+		path = os.Getenv("K6_BROWSER_EXECUTABLE_PATH")
+	}
+	if path == "" {
+		for _, name := range executableNames {
+			if path, err = exec.LookPath(name); err == nil {
+				break
+			}
+		}
+		if path == "" {
+			return nil, errors.Errorf("Couldn't find a browser to launch; install one of %s, or pass its executablePath",
+				strings.Join(executableNames, ", "))
+		}
+	}
+
+	userDataDir, err := ioutil.TempDir("", "k6-browser-")
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"--remote-debugging-port=0",
+		"--user-data-dir=" + userDataDir,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-background-networking",
+		"--disable-extensions",
+		"--mute-audio",
+	}
+	if opts.headless {
+		args = append(args, "--headless=new", "--hide-scrollbars")
+	}
+	args = append(append(args, opts.args...), "about:blank")
+
+	cmd := exec.Command(path, args...)
+	stderr, err := cmd.StderrPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		_ = os.RemoveAll(userDataDir)
+		return nil, errors.Wrap(err, "couldn't launch the browser")
+	}
+	b := &Instance{ctx: ctx, timeout: opts.timeout, cmd: cmd, exited: make(chan struct{}), userDataDir: userDataDir}
+	go func() {
+		_ = cmd.Wait()
+		close(b.exited)
+	}()
+
+	// The browser prints the URL of its DevTools endpoint once it's ready
+	urlChan := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "DevTools listening on ") {
+				urlChan <- strings.TrimSpace(strings.TrimPrefix(line, "DevTools listening on "))
+				break
+			}
+		}
+		_, _ = io.Copy(ioutil.Discard, stderr)
+	}()
+
+	var wsURL string
+	select {
+	case wsURL = <-urlChan:
+	case <-b.exited:
+		b.cleanUp()
+		return nil, errors.New("The browser exited before it was ready")
+	case <-time.After(opts.timeout):
+		_ = cmd.Process.Kill()
+		b.cleanUp()
+		return nil, errors.Errorf("The browser wasn't ready after %s", opts.timeout)
+	}
+
+	if err := b.connect(wsURL); err != nil {
+		_ = cmd.Process.Kill()
+		b.cleanUp()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Connect connects to an already running browser, through the DevTools websocket URL that it
+// prints on startup. The optional params can contain the timeout, like for launch().
+func (*Browser) Connect(ctx context.Context, wsURL string, params goja.Value) (*Instance, error) {
+	if common.GetState(ctx) == nil {
+		return nil, errors.New("Browsers can't be connected to in the init context")
+	}
+	opts, err := parseOptions(common.GetRuntime(ctx), params)
+	if err != nil {
+		return nil, err
+	}
+	b := &Instance{ctx: ctx, timeout: opts.timeout}
+	if err := b.connect(wsURL); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Instance) connect(wsURL string) error {
+	state := common.GetState(b.ctx)
+	wsd := websocket.Dialer{
+		NetDial: func(network, address string) (net.Conn, error) {
+			return state.Dialer.DialContext(b.ctx, network, address)
+		},
+		HandshakeTimeout: b.timeout,
+	}
+	ws, _, err := wsd.Dial(wsURL, nil)
+	if err != nil {
+		return errors.Wrap(err, "couldn't connect to the browser")
+	}
+	b.conn = newCDPConn(ws)
+	b.done = make(chan struct{})
+
+	// Don't leave browsers running past the end of the test.
+	go func() {
+		select {
+		case <-b.ctx.Done():
+			b.shutdown()
+		case <-b.done:
+		}
+	}()
+	return nil
+}
+
+// NewPage opens a new tab.
+func (b *Instance) NewPage() (*Page, error) {
+	ctx, cancel := b.callContext()
+	defer cancel()
+
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := b.conn.call(ctx, "", "Target.createTarget", map[string]interface{}{"url": "about:blank"}, &target); err != nil {
+		return nil, err
+	}
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	params := map[string]interface{}{"targetId": target.TargetID, "flatten": true}
+	if err := b.conn.call(ctx, "", "Target.attachToTarget", params, &session); err != nil {
+		return nil, err
+	}
+
+	p := &Page{browser: b, targetID: target.TargetID, sessionID: session.SessionID}
+	if err := p.call(ctx, "Page.enable", nil, nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close closes the browser, or disconnects from it if it wasn't launched by k6.
+func (b *Instance) Close() {
+	select {
+	case <-b.done:
+		return
+	default:
+	}
+	close(b.done)
+	b.shutdown()
+}
+
+func (b *Instance) shutdown() {
+	if b.cmd != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = b.conn.call(ctx, "", "Browser.close", nil, nil)
+		cancel()
+	}
+	b.conn.close(errors.New("the browser was closed"))
+	b.cleanUp()
+}
+
+// cleanUp makes sure that a launched browser is gone, along with its profile.
+func (b *Instance) cleanUp() {
+	if b.cmd == nil {
+		return
+	}
+	select {
+	case <-b.exited:
+	case <-time.After(5 * time.Second):
+		_ = b.cmd.Process.Kill()
+		<-b.exited
+	}
+	_ = os.RemoveAll(b.userDataDir)
+}
+
+// callContext returns the context for a call that shouldn't take longer than the timeout.
+func (b *Instance) callContext() (context.Context, context.CancelFunc) {
+	if b.timeout <= 0 {
+		return context.WithCancel(b.ctx)
+	}
+	return context.WithTimeout(b.ctx, b.timeout)
+}
@@ -0,0 +1,287 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBrowser answers the DevTools Protocol calls that the module makes, like a browser with a
+// single simple page would.
+type fakeBrowser struct {
+	mu      sync.Mutex
+	url     string
+	methods []string
+	polls   int
+}
+
+func (b *fakeBrowser) serve(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	for {
+		var req struct {
+			ID        int64                  `json:"id"`
+			SessionID string                 `json:"sessionId"`
+			Method    string                 `json:"method"`
+			Params    map[string]interface{} `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.methods = append(b.methods, req.Method)
+		b.mu.Unlock()
+
+		result, events := b.handle(req.Method, req.Params)
+		_ = conn.WriteJSON(map[string]interface{}{"id": req.ID, "sessionId": req.SessionID, "result": result})
+		for _, event := range events {
+			_ = conn.WriteJSON(map[string]interface{}{"method": event, "sessionId": req.SessionID, "params": map[string]interface{}{}})
+		}
+		if req.Method == "Browser.close" {
+			return
+		}
+	}
+}
+
+func (b *fakeBrowser) handle(method string, params map[string]interface{}) (interface{}, []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	evaluated := func(value interface{}) map[string]interface{} {
+		return map[string]interface{}{"result": map[string]interface{}{"type": "object", "value": value}}
+	}
+
+	switch method {
+	case "Target.createTarget":
+		return map[string]interface{}{"targetId": "target-1"}, nil
+	case "Target.attachToTarget":
+		return map[string]interface{}{"sessionId": "session-1"}, nil
+	case "Page.navigate":
+		url := params["url"].(string)
+		if strings.Contains(url, "unresolvable") {
+			return map[string]interface{}{"errorText": "net::ERR_NAME_NOT_RESOLVED"}, nil
+		}
+		b.url = url
+		return map[string]interface{}{"frameId": "frame-1"}, []string{"Page.loadEventFired"}
+	case "Runtime.evaluate":
+		expression := params["expression"].(string)
+		switch {
+		case strings.HasPrefix(expression, "new Promise"):
+			return evaluated(map[string]interface{}{
+				"url": b.url, "status": 200, "ttfb": 12.5, "fcp": 40, "lcp": 80.5, "load": nil,
+			}), nil
+		case strings.Contains(expression, `"#missing"`):
+			return map[string]interface{}{
+				"result": map[string]interface{}{"type": "object"},
+				"exceptionDetails": map[string]interface{}{
+					"text":      "Uncaught",
+					"exception": map[string]interface{}{"description": "Error: No element matches the selector #missing"},
+				},
+			}, nil
+		case strings.Contains(expression, "el.click()"):
+			b.url += "next"
+			return map[string]interface{}{"result": map[string]interface{}{"type": "undefined"}}, []string{"Page.loadEventFired"}
+		case strings.Contains(expression, "el.textContent"):
+			return evaluated("Hello"), nil
+		case strings.Contains(expression, `"#late") !== null`):
+			b.polls++
+			return evaluated(b.polls > 2), nil
+		case expression == "document.title":
+			return evaluated("Test page"), nil
+		default:
+			return evaluated(map[string]interface{}{"sum": 3}), nil
+		}
+	default:
+		return map[string]interface{}{}, nil
+	}
+}
+
+func newRuntime(t *testing.T) (*goja.Runtime, chan stats.SampleContainer) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:  root,
+		Dialer: netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "status", "group"),
+		},
+		Samples: samples,
+	}
+
+	ctx := common.WithRuntime(context.Background(), rt)
+	ctx = common.WithState(ctx, state)
+	rt.Set("browser", common.Bind(rt, New(), &ctx))
+	return rt, samples
+}
+
+func TestPage(t *testing.T) {
+	fake := &fakeBrowser{}
+	srv := httptest.NewServer(http.HandlerFunc(fake.serve))
+	defer srv.Close()
+
+	rt, samples := newRuntime(t)
+	rt.Set("WS_URL", "ws"+strings.TrimPrefix(srv.URL, "http"))
+
+	_, err := common.RunString(rt, `
+	let b = browser.connect(WS_URL, { timeout: 5000 });
+	let page = b.newPage();
+	let nav = page.goto("https://test.k6.io/");
+	if (nav.status !== 200 || nav.ttfb !== 12.5 || nav.fcp !== 40 || nav.lcp !== 80.5 || nav.load !== null) {
+		throw new Error("wrong timings: " + JSON.stringify(nav));
+	}
+	page.fill("input[name='login']", "admin");
+	if (page.click("#submit") !== null) { throw new Error("a navigation without waiting for it"); }
+	nav = page.click("a[href='/next']", { waitForNavigation: true });
+	if (nav.url !== "https://test.k6.io/nextnext") { throw new Error("wrong url: " + nav.url); }
+	if (page.textContent("h1") !== "Hello") { throw new Error("wrong text"); }
+	if (page.title() !== "Test page") { throw new Error("wrong title"); }
+	if (page.evaluate("({ sum: 1 + 2 })").sum !== 3) { throw new Error("wrong evaluation"); }
+	page.waitForSelector("#late");
+	page.close();
+	b.close();
+	`)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Target.createTarget", "Target.attachToTarget", "Page.enable",
+		"Page.navigate", "Runtime.evaluate",
+	}, fake.methods[:5])
+	assert.Contains(t, fake.methods, "Target.closeTarget")
+	assert.NotContains(t, fake.methods, "Browser.close") // Connected browsers are left running
+
+	seen := map[string][]string{}
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, sample := range sc.GetSamples() {
+			url, _ := sample.Tags.Get("url")
+			seen[sample.Metric.Name] = append(seen[sample.Metric.Name], url)
+			assert.Equal(t, "200", sample.Tags.CloneTags()["status"])
+		}
+	}
+	urls := []string{"https://test.k6.io/", "https://test.k6.io/nextnext"}
+	assert.Equal(t, map[string][]string{
+		metrics.BrowserTTFB.Name: urls,
+		metrics.BrowserFCP.Name:  urls,
+		metrics.BrowserLCP.Name:  urls,
+	}, seen)
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`page.goto("https://unresolvable.example")`:                 "Navigating to https://unresolvable.example failed: net::ERR_NAME_NOT_RESOLVED",
+			`page.click("#missing")`:                                    "Error: No element matches the selector #missing",
+			`browser.connect("ws://127.0.0.1:1/devtools")`:              "couldn't connect to the browser",
+			`browser.launch({ executablePath: "/nonexistent/chrome" })`: "couldn't launch the browser",
+		}
+		_, err := common.RunString(rt, `page = browser.connect(WS_URL).newPage();`)
+		require.NoError(t, err)
+		for code, msg := range testdata {
+			_, err := common.RunString(rt, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+}
+
+func TestLaunch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake browser is a shell script")
+	}
+	fake := &fakeBrowser{}
+	srv := httptest.NewServer(http.HandlerFunc(fake.serve))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "k6-browser-test")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// The fake browser prints the endpoint of the fake DevTools server, like Chromium does
+	executable := filepath.Join(dir, "chrome")
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n" +
+		"echo 'DevTools listening on ws" + strings.TrimPrefix(srv.URL, "http") + "/devtools/browser/1' >&2\nsleep 1\n"
+	require.NoError(t, ioutil.WriteFile(executable, []byte(script), 0755))
+
+	rt, _ := newRuntime(t)
+	rt.Set("EXECUTABLE", executable)
+	_, err = common.RunString(rt, `
+	let b = browser.launch({ executablePath: EXECUTABLE, args: ["--window-size=800,600"] });
+	b.newPage();
+	b.close();
+	`)
+	require.NoError(t, err)
+	assert.Contains(t, fake.methods, "Browser.close")
+
+	args, err := ioutil.ReadFile(argsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "--remote-debugging-port=0")
+	assert.Contains(t, string(args), "--headless=new")
+	assert.Contains(t, string(args), "--window-size=800,600 about:blank")
+
+	// The profile is removed along with the browser
+	userDataDir := strings.Fields(strings.SplitN(string(args), "--user-data-dir=", 2)[1])[0]
+	_, err = os.Stat(userDataDir)
+	assert.True(t, os.IsNotExist(err))
+
+	t.Run("exits early", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(executable, []byte("#!/bin/sh\nexit 1\n"), 0755))
+		_, err := common.RunString(rt, `browser.launch({ executablePath: EXECUTABLE })`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "The browser exited before it was ready")
+		}
+	})
+}
+
+func TestCDPError(t *testing.T) {
+	var msg cdpMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"id":1,"error":{"code":-32000,"message":"Cannot find context","data":"details"}}`), &msg))
+	assert.EqualError(t, msg.Error, "Cannot find context: details")
+}
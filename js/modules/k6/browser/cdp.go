@@ -0,0 +1,194 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// cdpMessage is a message from the browser: either the response to a call, or an event.
+type cdpMessage struct {
+	ID        int64           `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdpError       `json:"error,omitempty"`
+}
+
+type cdpRequest struct {
+	ID        int64       `json:"id"`
+	SessionID string      `json:"sessionId,omitempty"`
+	Method    string      `json:"method"`
+	Params    interface{} `json:"params,omitempty"`
+}
+
+type cdpError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *cdpError) Error() string {
+	if e.Data != "" {
+		return e.Message + ": " + e.Data
+	}
+	return e.Message
+}
+
+// cdpConn is a connection to the Chrome DevTools Protocol endpoint of a browser. Calls can be
+// made on the browser itself, or on the sessions of the pages attached to it.
+type cdpConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	lastID    int64
+	pending   map[int64]chan cdpMessage
+	listeners map[*cdpListener]bool
+	err       error // Why the connection was closed.
+	closed    chan struct{}
+}
+
+type cdpListener struct {
+	sessionID string
+	method    string
+	events    chan cdpMessage
+}
+
+func newCDPConn(ws *websocket.Conn) *cdpConn {
+	c := &cdpConn{
+		ws:        ws,
+		pending:   make(map[int64]chan cdpMessage),
+		listeners: make(map[*cdpListener]bool),
+		closed:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *cdpConn) readLoop() {
+	for {
+		_, data, err := c.ws.ReadMessage()
+		if err != nil {
+			c.close(errors.Wrap(err, "the connection to the browser was closed"))
+			return
+		}
+		var msg cdpMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if msg.ID != 0 {
+			if ch, ok := c.pending[msg.ID]; ok {
+				delete(c.pending, msg.ID)
+				ch <- msg
+			}
+		} else {
+			for l := range c.listeners {
+				if l.method == msg.Method && l.sessionID == msg.SessionID {
+					// Listeners only care about the first few events, the rest are dropped
+					select {
+					case l.events <- msg:
+					default:
+					}
+				}
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// call calls a method and unmarshals its result into result, if it's not nil.
+func (c *cdpConn) call(ctx context.Context, sessionID, method string, params, result interface{}) error {
+	ch := make(chan cdpMessage, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.lastID++
+	id := c.lastID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	data, err := json.Marshal(cdpRequest{ID: id, SessionID: sessionID, Method: method, Params: params})
+	if err == nil {
+		c.writeMu.Lock()
+		err = c.ws.WriteMessage(websocket.TextMessage, data)
+		c.writeMu.Unlock()
+	}
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return errors.Wrapf(msg.Error, "%s failed", method)
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return errors.Wrapf(ctx.Err(), "%s was interrupted", method)
+	}
+}
+
+// listen returns a channel for the events of a session with the given method, and a function
+// to stop listening. Listening has to start before the call that triggers the events.
+func (c *cdpConn) listen(sessionID, method string) (<-chan cdpMessage, func()) {
+	l := &cdpListener{sessionID: sessionID, method: method, events: make(chan cdpMessage, 16)}
+	c.mu.Lock()
+	c.listeners[l] = true
+	c.mu.Unlock()
+	return l.events, func() {
+		c.mu.Lock()
+		delete(c.listeners, l)
+		c.mu.Unlock()
+	}
+}
+
+func (c *cdpConn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.closed)
+	_ = c.ws.Close()
+}
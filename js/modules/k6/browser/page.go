@@ -0,0 +1,303 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+// How often waitForSelector() checks for the element.
+const pollInterval = 100 * time.Millisecond
+
+// Collects the timings of the current document, in milliseconds since the navigation started.
+// LCP is only reported through a PerformanceObserver, which is given a moment to deliver it.
+const vitalsScript = `new Promise(function(resolve) {
+	var nav = performance.getEntriesByType("navigation")[0];
+	var fcp = performance.getEntriesByName("first-contentful-paint")[0];
+	var lcp = null;
+	try {
+		new PerformanceObserver(function(list) {
+			var entries = list.getEntries();
+			lcp = entries[entries.length - 1].startTime;
+		}).observe({ type: "largest-contentful-paint", buffered: true });
+	} catch (e) {}
+	setTimeout(function() {
+		resolve({
+			url: location.href,
+			status: nav && nav.responseStatus ? nav.responseStatus : 0,
+			ttfb: nav ? nav.responseStart : null,
+			fcp: fcp ? fcp.startTime : null,
+			lcp: lcp,
+			load: nav && nav.loadEventEnd ? nav.loadEventEnd : null
+		});
+	}, 0);
+})`
+
+// Page is a browser tab, as returned by browser.newPage().
+type Page struct {
+	browser   *Instance
+	targetID  string
+	sessionID string
+}
+
+// Navigation has the results of a navigation, with its timings in milliseconds; timings that the
+// browser didn't report are null.
+type Navigation struct {
+	URL    string      `js:"url" json:"url"`
+	Status int         `js:"status" json:"status"`
+	TTFB   interface{} `js:"ttfb" json:"ttfb"`
+	FCP    interface{} `js:"fcp" json:"fcp"`
+	LCP    interface{} `js:"lcp" json:"lcp"`
+	Load   interface{} `js:"load" json:"load"`
+}
+
+func (p *Page) call(ctx context.Context, method string, params, result interface{}) error {
+	return p.browser.conn.call(ctx, p.sessionID, method, params, result)
+}
+
+// evaluate runs a JS expression in the page, waits for it if it's a promise, and unmarshals its
+// value into result, if it's not nil.
+func (p *Page) evaluate(ctx context.Context, expression string, result interface{}) error {
+	var res struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+	params := map[string]interface{}{"expression": expression, "returnByValue": true, "awaitPromise": true}
+	if err := p.call(ctx, "Runtime.evaluate", params, &res); err != nil {
+		return err
+	}
+	if details := res.ExceptionDetails; details != nil {
+		if details.Exception != nil && details.Exception.Description != "" {
+			return errors.New(details.Exception.Description)
+		}
+		return errors.New(details.Text)
+	}
+	if result != nil && len(res.Result.Value) > 0 {
+		return json.Unmarshal(res.Result.Value, result)
+	}
+	return nil
+}
+
+// Goto navigates to a URL, waits for the page to load, and returns its timings, which are also
+// emitted as the browser_ttfb, browser_fcp, browser_lcp and browser_load metrics.
+func (p *Page) Goto(url string) (*Navigation, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	return p.navigate(ctx, func() error {
+		var res struct {
+			ErrorText string `json:"errorText"`
+		}
+		if err := p.call(ctx, "Page.navigate", map[string]interface{}{"url": url}, &res); err != nil {
+			return err
+		}
+		if res.ErrorText != "" {
+			return errors.Errorf("Navigating to %s failed: %s", url, res.ErrorText)
+		}
+		return nil
+	})
+}
+
+// navigate does something that makes the page navigate, and waits for the new page to load.
+func (p *Page) navigate(ctx context.Context, fn func() error) (*Navigation, error) {
+	loaded, stop := p.browser.conn.listen(p.sessionID, "Page.loadEventFired")
+	defer stop()
+
+	if err := fn(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-loaded:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "waiting for the page to load")
+	}
+
+	nav := &Navigation{}
+	if err := p.evaluate(ctx, vitalsScript, nav); err != nil {
+		return nil, err
+	}
+	p.emitSamples(nav)
+	return nav, nil
+}
+
+func (p *Page) emitSamples(nav *Navigation) {
+	state := common.GetState(p.browser.ctx)
+	now := time.Now()
+	tags := state.Options.RunTags.CloneTags()
+	if state.Options.SystemTags["url"] {
+		tags["url"] = nav.URL
+	}
+	if state.Options.SystemTags["status"] {
+		tags["status"] = strconv.Itoa(nav.Status)
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+	sampleTags := stats.IntoSampleTags(&tags)
+
+	var samples []stats.Sample
+	for metric, value := range map[*stats.Metric]interface{}{
+		metrics.BrowserTTFB: nav.TTFB,
+		metrics.BrowserFCP:  nav.FCP,
+		metrics.BrowserLCP:  nav.LCP,
+		metrics.BrowserLoad: nav.Load,
+	} {
+		if value, ok := value.(float64); ok {
+			samples = append(samples, stats.Sample{Metric: metric, Time: now, Tags: sampleTags, Value: value})
+		}
+	}
+	if len(samples) > 0 {
+		state.Samples <- stats.ConnectedSamples{Samples: samples, Tags: sampleTags, Time: now}
+	}
+}
+
+// Click clicks the first element that matches a CSS selector. If the click navigates to another
+// page, pass `{ waitForNavigation: true }` to wait for it to load and get its timings.
+func (p *Page) Click(selector string, params goja.Value) (*Navigation, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	click := func() error {
+		return p.evaluate(ctx, `(function(el) {
+			if (!el) { throw new Error("No element matches the selector " + `+quote(selector)+`); }
+			el.click();
+		})(document.querySelector(`+quote(selector)+`))`, nil)
+	}
+
+	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
+		rt := common.GetRuntime(p.browser.ctx)
+		if v := params.ToObject(rt).Get("waitForNavigation"); v != nil && v.ToBoolean() {
+			return p.navigate(ctx, click)
+		}
+	}
+	return nil, click()
+}
+
+// Fill sets the value of an input, and fires its input and change events.
+func (p *Page) Fill(selector, value string) error {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	return p.evaluate(ctx, `(function(el) {
+		if (!el) { throw new Error("No element matches the selector " + `+quote(selector)+`); }
+		el.focus();
+		el.value = `+quote(value)+`;
+		el.dispatchEvent(new Event("input", { bubbles: true }));
+		el.dispatchEvent(new Event("change", { bubbles: true }));
+	})(document.querySelector(`+quote(selector)+`))`, nil)
+}
+
+// TextContent returns the text of the first element that matches a CSS selector, or null.
+func (p *Page) TextContent(selector string) (interface{}, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	var text *string
+	err := p.evaluate(ctx, `(function(el) { return el ? el.textContent : null; })(document.querySelector(`+quote(selector)+`))`, &text)
+	if err != nil || text == nil {
+		return nil, err
+	}
+	return *text, nil
+}
+
+// WaitForSelector waits until an element matches a CSS selector.
+func (p *Page) WaitForSelector(selector string) error {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	for {
+		var found bool
+		if err := p.evaluate(ctx, `document.querySelector(`+quote(selector)+`) !== null`, &found); err != nil {
+			return err
+		}
+		if found {
+			return nil
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return errors.Errorf("No element matched the selector %s after %s", selector, p.browser.timeout)
+		}
+	}
+}
+
+// Evaluate runs a JS expression in the page and returns its value, which has to be serializable
+// to JSON. Promises are waited for.
+func (p *Page) Evaluate(expression string) (interface{}, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	var result interface{}
+	if err := p.evaluate(ctx, expression, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Title returns the title of the page.
+func (p *Page) Title() (string, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	var title string
+	err := p.evaluate(ctx, `document.title`, &title)
+	return title, err
+}
+
+// Content returns the HTML of the page.
+func (p *Page) Content() (string, error) {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	var content string
+	err := p.evaluate(ctx, `document.documentElement.outerHTML`, &content)
+	return content, err
+}
+
+// Close closes the tab.
+func (p *Page) Close() error {
+	ctx, cancel := p.browser.callContext()
+	defer cancel()
+
+	return p.browser.conn.call(ctx, "", "Target.closeTarget", map[string]interface{}{"targetId": p.targetID}, nil)
+}
+
+// quote returns s as a JS string literal.
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
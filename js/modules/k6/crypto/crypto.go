@@ -24,6 +24,7 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -31,14 +32,18 @@ import (
 	"encoding/hex"
 	"errors"
 	"hash"
+	"strconv"
 
 	"golang.org/x/crypto/md4"
 	"golang.org/x/crypto/ripemd160"
 
+	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
 )
 
-type Crypto struct{}
+type Crypto struct {
+	Subtle *SubtleCrypto `js:"subtle"`
+}
 
 type Hasher struct {
 	ctx context.Context
@@ -47,7 +52,52 @@ type Hasher struct {
 }
 
 func New() *Crypto {
-	return &Crypto{}
+	return &Crypto{Subtle: &SubtleCrypto{}}
+}
+
+// RandomBytes returns size cryptographically secure random bytes.
+func (*Crypto) RandomBytes(ctx context.Context, size int) []byte {
+	if size < 1 {
+		common.Throw(common.GetRuntime(ctx), errors.New("Invalid size: the number of random bytes must be positive"))
+	}
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+	return bytes
+}
+
+// GetRandomValues fills an ArrayBuffer or array with random bytes, like in WebCrypto.
+func (*Crypto) GetRandomValues(ctx context.Context, array goja.Value) goja.Value {
+	rt := common.GetRuntime(ctx)
+
+	if data, ok := array.Export().([]byte); ok {
+		if len(data) > 65536 {
+			common.Throw(rt, errors.New("QuotaExceededError: can't generate more than 65536 random bytes at once"))
+		}
+		if _, err := rand.Read(data); err != nil {
+			common.Throw(rt, err)
+		}
+		return array
+	}
+
+	obj := array.ToObject(rt)
+	length := obj.Get("length")
+	if length == nil || goja.IsUndefined(length) {
+		common.Throw(rt, errors.New("TypeMismatchError: an ArrayBuffer or an array is required"))
+	}
+	n := int(length.ToInteger())
+	if n > 65536 {
+		common.Throw(rt, errors.New("QuotaExceededError: can't generate more than 65536 random bytes at once"))
+	}
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		common.Throw(rt, err)
+	}
+	for i, b := range data {
+		_ = obj.Set(strconv.Itoa(i), b)
+	}
+	return array
 }
 
 func (c *Crypto) Md4(ctx context.Context, input []byte, outputEncoding string) string {
@@ -68,6 +118,12 @@ func (c *Crypto) Sha1(ctx context.Context, input []byte, outputEncoding string)
 	return hasher.Digest(outputEncoding)
 }
 
+func (c *Crypto) Sha224(ctx context.Context, input []byte, outputEncoding string) string {
+	hasher := c.CreateHash(ctx, "sha224")
+	hasher.Update(input)
+	return hasher.Digest(outputEncoding)
+}
+
 func (c *Crypto) Sha256(ctx context.Context, input []byte, outputEncoding string) string {
 	hasher := c.CreateHash(ctx, "sha256")
 	hasher.Update(input)
@@ -115,6 +171,8 @@ func (*Crypto) CreateHash(ctx context.Context, algorithm string) *Hasher {
 		hasher.hash = md5.New()
 	case "sha1":
 		hasher.hash = sha1.New()
+	case "sha224":
+		hasher.hash = sha256.New224()
 	case "sha256":
 		hasher.hash = sha256.New()
 	case "sha384":
@@ -127,6 +185,9 @@ func (*Crypto) CreateHash(ctx context.Context, algorithm string) *Hasher {
 		hasher.hash = sha512.New()
 	case "ripemd160":
 		hasher.hash = ripemd160.New()
+	default:
+		err := errors.New("Invalid algorithm: " + algorithm)
+		common.Throw(common.GetRuntime(hasher.ctx), err)
 	}
 
 	return &hasher
@@ -175,6 +236,8 @@ func (c Crypto) CreateHMAC(ctx context.Context, algorithm string, key string) *H
 		hasher.hash = hmac.New(md5.New, keyBuffer)
 	case "sha1":
 		hasher.hash = hmac.New(sha1.New, keyBuffer)
+	case "sha224":
+		hasher.hash = hmac.New(sha256.New224, keyBuffer)
 	case "sha256":
 		hasher.hash = hmac.New(sha256.New, keyBuffer)
 	case "sha384":
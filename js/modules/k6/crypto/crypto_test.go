@@ -73,6 +73,17 @@ func TestCryptoAlgorithms(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("SHA224", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		const correct = "2f05477fc24bb4faefd86517156dafdecec45b8ad3cf2522a563582b";
+		let hash = crypto.sha224("hello world", "hex");
+		if (hash !== correct) {
+			throw new Error("Hash mismatch: " + hash);
+		}`)
+
+		assert.NoError(t, err)
+	})
+
 	t.Run("SHA256", func(t *testing.T) {
 		_, err := common.RunString(rt, `
 		const correct = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9";
@@ -138,6 +149,34 @@ func TestCryptoAlgorithms(t *testing.T) {
 
 		assert.NoError(t, err)
 	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, `crypto.createHash("md6");`)
+		assert.EqualError(t, err, "GoError: Invalid algorithm: md6")
+	})
+}
+
+func TestRandomBytes(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("crypto", common.Bind(rt, New(), &ctx))
+
+	t.Run("Valid", func(t *testing.T) {
+		v, err := common.RunString(rt, `crypto.randomBytes(32)`)
+		if assert.NoError(t, err) {
+			bytes, ok := v.Export().([]byte)
+			assert.True(t, ok)
+			assert.Len(t, bytes, 32)
+			assert.NotEqual(t, make([]byte, 32), bytes)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, `crypto.randomBytes(-1)`)
+		assert.EqualError(t, err, "GoError: Invalid size: the number of random bytes must be positive")
+	})
 }
 
 func TestStreamingApi(t *testing.T) {
@@ -287,6 +326,7 @@ func TestHMac(t *testing.T) {
 		"md4":        "92d8f5c302cf04cca0144d7a9feb1596",
 		"md5":        "e04f2ec05c8b12e19e46936b171c9d03",
 		"sha1":       "c113b62711ff5d8e8100bbb17b998591af81dc24",
+		"sha224":     "dfae8b073a433700980541ea3e68ab7a105db772be5d7ff664d45824",
 		"sha256":     "7fd04df92f636fd450bc841c9418e5825c17f33ad9c87c518115a45971f7f77e",
 		"sha384":     "d331e169e2dcfc742e80a3bf4dcc76d0e6425ab3777a3ac217ac6b2552aad5529ed4d40135b06e53a495ac7425d1e462",
 		"sha512_224": "bac4e6256bdbf81d029aec48af4fdd4b14001db6721f07c429a80817",
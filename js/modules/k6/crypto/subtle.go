@@ -0,0 +1,421 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// SubtleCrypto is a subset of the WebCrypto SubtleCrypto interface: digest, HMAC and AES-GCM.
+// Like in browsers, every method returns a Promise of its result.
+type SubtleCrypto struct{}
+
+// Digest returns a Promise of the SHA-1, SHA-256, SHA-384 or SHA-512 hash of data.
+func (*SubtleCrypto) Digest(ctx context.Context, algorithm interface{}, data interface{}) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return digest(algorithm, data) })
+}
+
+// ImportKey returns a Promise of a raw HMAC or AES-GCM key.
+func (*SubtleCrypto) ImportKey(
+	ctx context.Context, format string, keyData interface{}, algorithm interface{}, extractable bool, usages []string,
+) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) {
+		return importKey(format, keyData, algorithm, extractable, usages)
+	})
+}
+
+// GenerateKey returns a Promise of a random HMAC or AES-GCM key.
+func (*SubtleCrypto) GenerateKey(
+	ctx context.Context, algorithm interface{}, extractable bool, usages []string,
+) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return generateKey(algorithm, extractable, usages) })
+}
+
+// ExportKey returns a Promise of the raw bytes of an extractable key.
+func (*SubtleCrypto) ExportKey(ctx context.Context, format string, key *CryptoKey) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return exportKey(format, key) })
+}
+
+// Sign returns a Promise of the HMAC signature of data.
+func (*SubtleCrypto) Sign(ctx context.Context, algorithm interface{}, key *CryptoKey, data interface{}) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return sign(algorithm, key, data) })
+}
+
+// Verify returns a Promise of whether signature is the HMAC signature of data.
+func (*SubtleCrypto) Verify(
+	ctx context.Context, algorithm interface{}, key *CryptoKey, signature interface{}, data interface{},
+) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return verify(algorithm, key, signature, data) })
+}
+
+// Encrypt returns a Promise of data encrypted with AES-GCM.
+func (*SubtleCrypto) Encrypt(ctx context.Context, algorithm interface{}, key *CryptoKey, data interface{}) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return encrypt(algorithm, key, data) })
+}
+
+// Decrypt returns a Promise of AES-GCM encrypted data, decrypted and authenticated.
+func (*SubtleCrypto) Decrypt(ctx context.Context, algorithm interface{}, key *CryptoKey, data interface{}) *goja.Object {
+	return newPromise(ctx, func() (interface{}, error) { return decrypt(algorithm, key, data) })
+}
+
+// newPromise returns a Promise that's settled with what fn returns. The operations are quick, so
+// fn runs right away on the VU's goroutine, but the Promise's reactions still run asynchronously.
+func newPromise(ctx context.Context, fn func() (interface{}, error)) *goja.Object {
+	promise, resolve, reject := common.NewPromise(common.GetRuntime(ctx))
+	if result, err := fn(); err != nil {
+		reject(err)
+	} else {
+		resolve(result)
+	}
+	return promise
+}
+
+// CryptoKey is a secret key for HMAC or AES-GCM, see SubtleCrypto.ImportKey.
+type CryptoKey struct {
+	Type        string                 `js:"type"`
+	Extractable bool                   `js:"extractable"`
+	Algorithm   map[string]interface{} `js:"algorithm"`
+	Usages      []string               `js:"usages"`
+
+	hash func() hash.Hash
+	raw  []byte
+}
+
+func (k *CryptoKey) use(algorithm, usage string) error {
+	if k == nil {
+		return errors.New("InvalidAccessError: a key is required")
+	}
+	if name := k.Algorithm["name"]; name != algorithm {
+		return errors.Errorf("InvalidAccessError: the key is for %s, not %s", name, algorithm)
+	}
+	for _, u := range k.Usages {
+		if u == usage {
+			return nil
+		}
+	}
+	return errors.Errorf("InvalidAccessError: the key can't be used to %s", usage)
+}
+
+// digest hashes data with SHA-1, SHA-256, SHA-384 or SHA-512.
+func digest(algorithm interface{}, data interface{}) ([]byte, error) {
+	name, _ := normalizeAlgorithm(algorithm)
+	h, err := hashByName(name)
+	if err != nil {
+		return nil, err
+	}
+	input, err := toBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	hasher := h()
+	_, _ = hasher.Write(input)
+	return hasher.Sum(nil), nil
+}
+
+// importKey imports a raw HMAC or AES-GCM key.
+func importKey(
+	format string, keyData interface{}, algorithm interface{}, extractable bool, usages []string,
+) (*CryptoKey, error) {
+	if format != "raw" {
+		return nil, errors.Errorf("NotSupportedError: unsupported key format '%s', only raw keys are supported", format)
+	}
+	raw, err := toBytes(keyData)
+	if err != nil {
+		return nil, err
+	}
+	return newCryptoKey(raw, algorithm, extractable, usages)
+}
+
+// generateKey generates a random HMAC or AES-GCM key.
+func generateKey(algorithm interface{}, extractable bool, usages []string) (*CryptoKey, error) {
+	name, params := normalizeAlgorithm(algorithm)
+	length := toInt(params["length"])
+	switch name {
+	case "AES-GCM":
+		if length == 0 {
+			return nil, errors.New("OperationError: AES-GCM keys need a length of 128, 192 or 256 bits")
+		}
+	case "HMAC":
+		if length == 0 {
+			h, err := hashByName(nameOf(params["hash"]))
+			if err != nil {
+				return nil, err
+			}
+			length = h().BlockSize() * 8
+		}
+	}
+	if length <= 0 || length%8 != 0 {
+		return nil, errors.Errorf("OperationError: invalid key length %d", length)
+	}
+	raw := make([]byte, length/8)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	return newCryptoKey(raw, algorithm, extractable, usages)
+}
+
+// exportKey exports an extractable key in the raw format.
+func exportKey(format string, key *CryptoKey) ([]byte, error) {
+	if format != "raw" {
+		return nil, errors.Errorf("NotSupportedError: unsupported key format '%s', only raw keys are supported", format)
+	}
+	if key == nil || !key.Extractable {
+		return nil, errors.New("InvalidAccessError: the key isn't extractable")
+	}
+	return append([]byte{}, key.raw...), nil
+}
+
+// sign signs data with an HMAC key.
+func sign(algorithm interface{}, key *CryptoKey, data interface{}) ([]byte, error) {
+	name, _ := normalizeAlgorithm(algorithm)
+	if name != "HMAC" {
+		return nil, errors.Errorf("NotSupportedError: can't sign with %s, only HMAC is supported", name)
+	}
+	if err := key.use(name, "sign"); err != nil {
+		return nil, err
+	}
+	input, err := toBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(key.hash, key.raw)
+	_, _ = mac.Write(input)
+	return mac.Sum(nil), nil
+}
+
+// verify checks an HMAC signature of data.
+func verify(algorithm interface{}, key *CryptoKey, signature interface{}, data interface{}) (bool, error) {
+	name, _ := normalizeAlgorithm(algorithm)
+	if name != "HMAC" {
+		return false, errors.Errorf("NotSupportedError: can't verify with %s, only HMAC is supported", name)
+	}
+	if err := key.use(name, "verify"); err != nil {
+		return false, err
+	}
+	sig, err := toBytes(signature)
+	if err != nil {
+		return false, err
+	}
+	input, err := toBytes(data)
+	if err != nil {
+		return false, err
+	}
+	mac := hmac.New(key.hash, key.raw)
+	_, _ = mac.Write(input)
+	return hmac.Equal(sig, mac.Sum(nil)), nil
+}
+
+// encrypt encrypts data with AES-GCM; the authentication tag is appended to the ciphertext.
+func encrypt(algorithm interface{}, key *CryptoKey, data interface{}) ([]byte, error) {
+	aead, iv, additionalData, err := newGCM(algorithm, key, "encrypt")
+	if err != nil {
+		return nil, err
+	}
+	input, err := toBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, iv, input, additionalData), nil
+}
+
+// decrypt decrypts and authenticates AES-GCM encrypted data.
+func decrypt(algorithm interface{}, key *CryptoKey, data interface{}) ([]byte, error) {
+	aead, iv, additionalData, err := newGCM(algorithm, key, "decrypt")
+	if err != nil {
+		return nil, err
+	}
+	input, err := toBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, input, additionalData)
+	if err != nil {
+		return nil, errors.Wrap(err, "OperationError")
+	}
+	return plaintext, nil
+}
+
+func newCryptoKey(raw []byte, algorithm interface{}, extractable bool, usages []string) (*CryptoKey, error) {
+	name, params := normalizeAlgorithm(algorithm)
+	key := &CryptoKey{
+		Type:        "secret",
+		Extractable: extractable,
+		Algorithm:   map[string]interface{}{"name": name, "length": len(raw) * 8},
+		Usages:      usages,
+		raw:         raw,
+	}
+
+	var allowedUsages []string
+	switch name {
+	case "HMAC":
+		hashName := nameOf(params["hash"])
+		h, err := hashByName(hashName)
+		if err != nil {
+			return nil, err
+		}
+		key.hash = h
+		key.Algorithm["hash"] = map[string]interface{}{"name": hashName}
+		allowedUsages = []string{"sign", "verify"}
+	case "AES-GCM":
+		if l := len(raw); l != 16 && l != 24 && l != 32 {
+			return nil, errors.Errorf("DataError: invalid AES key length of %d bits", l*8)
+		}
+		allowedUsages = []string{"encrypt", "decrypt"}
+	default:
+		return nil, errors.Errorf("NotSupportedError: unsupported algorithm '%s', use HMAC or AES-GCM", name)
+	}
+
+	for _, usage := range usages {
+		allowed := false
+		for _, a := range allowedUsages {
+			allowed = allowed || usage == a
+		}
+		if !allowed {
+			return nil, errors.Errorf("SyntaxError: %s keys can't be used to %s", name, usage)
+		}
+	}
+	return key, nil
+}
+
+func newGCM(algorithm interface{}, key *CryptoKey, usage string) (cipher.AEAD, []byte, []byte, error) {
+	name, params := normalizeAlgorithm(algorithm)
+	if name != "AES-GCM" {
+		return nil, nil, nil, errors.Errorf("NotSupportedError: can't %s with %s, only AES-GCM is supported", usage, name)
+	}
+	if err := key.use(name, usage); err != nil {
+		return nil, nil, nil, err
+	}
+	iv, err := toBytes(params["iv"])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(iv) == 0 {
+		return nil, nil, nil, errors.New("OperationError: AES-GCM needs an iv")
+	}
+	additionalData, err := toBytes(params["additionalData"])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tagLength := 128
+	if l := toInt(params["tagLength"]); l != 0 {
+		tagLength = l
+	}
+
+	block, err := aes.NewCipher(key.raw)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var aead cipher.AEAD
+	switch {
+	case len(iv) == 12 && tagLength == 128:
+		aead, err = cipher.NewGCM(block)
+	case len(iv) == 12:
+		aead, err = newGCMWithTagSize(block, tagLength/8)
+	case tagLength == 128:
+		aead, err = cipher.NewGCMWithNonceSize(block, len(iv))
+	default:
+		err = errors.New("OperationError: custom tag lengths need a 96-bit iv")
+	}
+	return aead, iv, additionalData, err
+}
+
+// normalizeAlgorithm returns the upper-cased name of an algorithm, which can be given either as a
+// string or as an object with a name, and its parameters.
+func normalizeAlgorithm(algorithm interface{}) (string, map[string]interface{}) {
+	params, ok := algorithm.(map[string]interface{})
+	if !ok {
+		params = map[string]interface{}{}
+	}
+	return nameOf(algorithm), params
+}
+
+func nameOf(algorithm interface{}) string {
+	switch a := algorithm.(type) {
+	case string:
+		return strings.ToUpper(a)
+	case map[string]interface{}:
+		if name, ok := a["name"].(string); ok {
+			return strings.ToUpper(name)
+		}
+	}
+	return ""
+}
+
+func hashByName(name string) (func() hash.Hash, error) {
+	switch name {
+	case "SHA-1":
+		return sha1.New, nil
+	case "SHA-256":
+		return sha256.New, nil
+	case "SHA-384":
+		return sha512.New384, nil
+	case "SHA-512":
+		return sha512.New, nil
+	default:
+		return nil, errors.Errorf("NotSupportedError: unsupported hash algorithm '%s'", name)
+	}
+}
+
+// toBytes converts strings, ArrayBuffers and arrays of numbers to bytes.
+func toBytes(data interface{}) ([]byte, error) {
+	switch d := data.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return d, nil
+	case string:
+		return []byte(d), nil
+	case []interface{}:
+		b := make([]byte, len(d))
+		for i, v := range d {
+			b[i] = byte(toInt(v))
+		}
+		return b, nil
+	default:
+		return nil, errors.Errorf("unsupported data type %T", data)
+	}
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case int:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,34 @@
+// +build !go1.11
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"crypto/cipher"
+
+	"github.com/pkg/errors"
+)
+
+// newGCMWithTagSize fails before Go 1.11, which only has AES-GCM with the default 128-bit tags.
+func newGCMWithTagSize(block cipher.Block, tagSize int) (cipher.AEAD, error) {
+	return nil, errors.New("NotSupportedError: tag lengths other than 128 need k6 built with Go 1.11 or newer")
+}
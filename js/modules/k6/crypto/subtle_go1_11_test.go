@@ -0,0 +1,44 @@
+// +build go1.11
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtleCryptoTagLength(t *testing.T) {
+	key, err := importKey("raw", make([]byte, 16), "AES-GCM", false, []string{"encrypt", "decrypt"})
+	require.NoError(t, err)
+	params := map[string]interface{}{"name": "AES-GCM", "iv": make([]byte, 12), "tagLength": int64(96)}
+
+	ciphertext, err := encrypt(params, key, "secret message")
+	require.NoError(t, err)
+	assert.Len(t, ciphertext, 14+12)
+
+	plaintext, err := decrypt(params, key, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "secret message", string(plaintext))
+}
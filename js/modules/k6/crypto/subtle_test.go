@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package crypto
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	jslib "github.com/loadimpact/k6/js/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtleCrypto(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	loop := common.NewEventLoop(rt)
+	loop.Bind()
+	_, err := rt.RunProgram(jslib.GetCoreJS())
+	require.NoError(t, err)
+	_, err = rt.RunProgram(jslib.GetRegeneratorRuntime())
+	require.NoError(t, err)
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("crypto", common.Bind(rt, New(), &ctx))
+	rt.Set("hex", func(b []byte) string { return hex.EncodeToString(b) })
+	_, err = common.RunString(rt, `
+	function zeros(n) {
+		let arr = [];
+		for (let i = 0; i < n; i++) { arr.push(0); }
+		return arr;
+	}`)
+	require.NoError(t, err)
+
+	// run runs code that evaluates to a Promise, and returns what it resolves to
+	run := func(code string) (goja.Value, error) {
+		v, err := common.RunString(rt, code)
+		if err != nil {
+			return nil, err
+		}
+		return loop.Await(ctx, v)
+	}
+
+	t.Run("promises", func(t *testing.T) {
+		v, err := common.RunString(rt, `crypto.subtle.digest("SHA-256", "hello world") instanceof Promise`)
+		require.NoError(t, err)
+		assert.True(t, v.ToBoolean())
+
+		v, err = run(`crypto.subtle.digest("SHA-256", "hello world").then(hex)`)
+		require.NoError(t, err)
+		assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", v.String())
+	})
+
+	t.Run("digest", func(t *testing.T) {
+		v, err := run(`(async function() { return hex(await crypto.subtle.digest("SHA-256", "hello world")); })()`)
+		require.NoError(t, err)
+		assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", v.String())
+
+		v, err = run(`(async function() { return hex(await crypto.subtle.digest({ name: "sha-1" }, [104, 105])); })()`)
+		require.NoError(t, err)
+		assert.Equal(t, "c22b5f9178342609428d6f51b2c5af4c0bde6a42", v.String())
+	})
+
+	t.Run("HMAC", func(t *testing.T) {
+		v, err := run(`(async function() {
+			let key = await crypto.subtle.importKey("raw", "a secret", { name: "HMAC", hash: "SHA-256" }, false, ["sign", "verify"]);
+			let signature = await crypto.subtle.sign("HMAC", key, "some data to hash");
+			if (!await crypto.subtle.verify({ name: "HMAC" }, key, signature, "some data to hash")) {
+				throw new Error("the signature wasn't verified");
+			}
+			if (await crypto.subtle.verify("HMAC", key, signature, "some other data")) {
+				throw new Error("a wrong signature was verified");
+			}
+			if (key.algorithm.hash.name !== "SHA-256" || key.algorithm.length !== 64) {
+				throw new Error("wrong algorithm: " + JSON.stringify(key.algorithm));
+			}
+			return hex(signature);
+		})()`)
+		require.NoError(t, err)
+		assert.Equal(t, "7fd04df92f636fd450bc841c9418e5825c17f33ad9c87c518115a45971f7f77e", v.String())
+	})
+
+	t.Run("AES-GCM", func(t *testing.T) {
+		v, err := run(`(async function() {
+			let key = await crypto.subtle.generateKey({ name: "AES-GCM", length: 256 }, true, ["encrypt", "decrypt"]);
+			let iv = crypto.getRandomValues(zeros(12));
+			let params = { name: "AES-GCM", iv: iv, additionalData: "header" };
+			let ciphertext = await crypto.subtle.encrypt(params, key, "secret message");
+			if (ciphertext.length !== 14 + 16) {
+				throw new Error("wrong ciphertext length: " + ciphertext.length);
+			}
+			if ((await crypto.subtle.exportKey("raw", key)).length !== 32) {
+				throw new Error("wrong key length");
+			}
+			return String.fromCharCode.apply(null, await crypto.subtle.decrypt(params, key, ciphertext));
+		})()`)
+		require.NoError(t, err)
+		assert.Equal(t, "secret message", v.String())
+
+		// From the NIST GCM test vectors
+		v, err = run(`(async function() {
+			let key = await crypto.subtle.importKey("raw", zeros(16), "AES-GCM", false, ["encrypt"]);
+			return hex(await crypto.subtle.encrypt({ name: "AES-GCM", iv: zeros(12) }, key, zeros(16)));
+		})()`)
+		require.NoError(t, err)
+		assert.Equal(t, "0388dace60b6a392f328c2b971b2fe78ab6e47d42cec13bdf53a67b21257bddf", v.String())
+	})
+
+	t.Run("getRandomValues", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let arr = crypto.getRandomValues(zeros(64));
+		arr.some(function(b) { return b !== 0; }) && arr.every(function(b) { return b >= 0 && b < 256; });
+		`)
+		require.NoError(t, err)
+		assert.True(t, v.ToBoolean())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`crypto.subtle.digest("MD5", "data")`:                                                                                                                                                       "NotSupportedError: unsupported hash algorithm 'MD5'",
+			`crypto.subtle.importKey("jwk", "key", "AES-GCM", false, [])`:                                                                                                                               "NotSupportedError: unsupported key format 'jwk'",
+			`crypto.subtle.importKey("raw", "short", "AES-GCM", false, [])`:                                                                                                                             "DataError: invalid AES key length of 40 bits",
+			`crypto.subtle.importKey("raw", "key", { name: "HMAC", hash: "SHA-256" }, false, ["encrypt"])`:                                                                                              "SyntaxError: HMAC keys can't be used to encrypt",
+			`crypto.subtle.importKey("raw", "k", { name: "HMAC", hash: "SHA-1" }, false, ["verify"]).then(k => crypto.subtle.sign("HMAC", k, "x"))`:                                                     "InvalidAccessError: the key can't be used to sign",
+			`crypto.subtle.generateKey({ name: "AES-GCM", length: 128 }, false, []).then(k => crypto.subtle.exportKey("raw", k))`:                                                                       "InvalidAccessError: the key isn't extractable",
+			`crypto.subtle.generateKey({ name: "AES-GCM", length: 128 }, false, ["decrypt"]).then(k => crypto.subtle.decrypt({ name: "AES-GCM", iv: "123456789012" }, k, "garbage data that is long"))`: "OperationError",
+		}
+		for code, msg := range testdata {
+			t.Run(code, func(t *testing.T) {
+				_, err := run(code)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), msg)
+			})
+		}
+
+		t.Run("getRandomValues", func(t *testing.T) {
+			_, err := common.RunString(rt, `crypto.getRandomValues(zeros(65537))`)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "QuotaExceededError")
+		})
+	})
+}
@@ -0,0 +1,268 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+type Data struct {
+	mutex   sync.Mutex
+	sources map[sourceKey]*source
+}
+
+func New() *Data {
+	return &Data{sources: make(map[sourceKey]*source)}
+}
+
+// A Stream hands out the records of a CSV or JSONL file one at a time, without loading the whole
+// file into memory. The VUs that create a stream for the same file with the same options share it,
+// so every record goes to only one VU, no matter which one asks for the next one.
+type Stream struct {
+	ctx *context.Context
+	src *source
+}
+
+// XStream creates a stream for a file, with the options format ("csv" or "jsonl", by default
+// based on the file extension), header (whether the first CSV record has the column names, true
+// by default), delimiter (of CSV fields, "," by default), loop (whether to start over at the end
+// of the file, false by default) and partition (e.g. "2/3" for every third record, starting with
+// the second, so that each instance of a distributed run reads a different part of the file).
+// Relative paths are relative to the working directory, and the file isn't put in archives.
+func (d *Data) XStream(ctxPtr *context.Context, path string, options goja.Value) (*Stream, error) {
+	rt := common.GetRuntime(*ctxPtr)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return nil, err
+	}
+
+	key := sourceKey{path: abs, header: true, delimiter: ',', part: 1, parts: 1}
+	switch strings.ToLower(filepath.Ext(abs)) {
+	case ".csv":
+		key.format = "csv"
+	case ".jsonl", ".ndjson":
+		key.format = "jsonl"
+	}
+	if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+		params := options.ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			switch k {
+			case "format":
+				key.format = v.String()
+			case "header":
+				key.header = v.ToBoolean()
+			case "delimiter":
+				delimiter := []rune(v.String())
+				if len(delimiter) != 1 {
+					return nil, errors.Errorf("invalid delimiter '%s', it has to be a single character", v.String())
+				}
+				key.delimiter = delimiter[0]
+			case "loop":
+				key.loop = v.ToBoolean()
+			case "partition":
+				if key.part, key.parts, err = parsePartition(v.String()); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, errors.Errorf("unknown stream option '%s'", k)
+			}
+		}
+	}
+	if key.format != "csv" && key.format != "jsonl" {
+		return nil, errors.Errorf("unknown format of '%s', it has to be 'csv' or 'jsonl'", path)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	src, ok := d.sources[key]
+	if !ok {
+		src = &source{sourceKey: key}
+		d.sources[key] = src
+	}
+	return &Stream{ctx: ctxPtr, src: src}, nil
+}
+
+// Next returns the next record, or null if there are no more. CSV records are objects with the
+// column names as keys, or arrays of strings if there's no header; JSONL records are whatever
+// their lines are.
+func (s *Stream) Next() (goja.Value, error) {
+	rt := common.GetRuntime(*s.ctx)
+	fields, line, err := s.src.next()
+	switch {
+	case err != nil:
+		return nil, err
+	case fields == nil && line == "":
+		return goja.Null(), nil
+	case s.src.format == "jsonl":
+		return parseJSON(rt, line)
+	case s.src.header:
+		record := rt.NewObject()
+		for i, name := range s.src.columns {
+			if i < len(fields) {
+				_ = record.Set(name, fields[i])
+			}
+		}
+		return record, nil
+	default:
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		return parseJSON(rt, string(data))
+	}
+}
+
+// parseJSON parses JSON in JS, so that the value is a plain JS object or array, not a wrapped Go one.
+func parseJSON(rt *goja.Runtime, data string) (goja.Value, error) {
+	parse, ok := goja.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+	if !ok {
+		return nil, errors.New("JSON.parse isn't a function")
+	}
+	return parse(goja.Undefined(), rt.ToValue(data))
+}
+
+// parsePartition parses a partition like "2/3", the second of three.
+func parsePartition(s string) (int64, int64, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 {
+		part, err1 := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		count, err2 := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err1 == nil && err2 == nil && part >= 1 && part <= count {
+			return part, count, nil
+		}
+	}
+	return 0, 0, errors.Errorf("invalid partition '%s', it has to be like '2/3', for the second of three", s)
+}
+
+// What makes streams the same, so that they share a source.
+type sourceKey struct {
+	path        string
+	format      string
+	header      bool
+	delimiter   rune
+	loop        bool
+	part, parts int64
+}
+
+// A source reads the records of a file, for all of the streams of it.
+type source struct {
+	sourceKey
+
+	mutex   sync.Mutex
+	file    *os.File
+	csv     *csv.Reader
+	lines   *bufio.Reader
+	columns []string
+	index   int64 // Of the next record in the file, for the partitioning
+	read    bool  // Whether a record was read since the file was opened
+	done    bool
+}
+
+// next returns the next record of the partition, as CSV fields or a JSONL line; both are empty if
+// there are no more records.
+func (s *source) next() ([]string, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for !s.done {
+		if s.file == nil {
+			if err := s.open(); err != nil {
+				return nil, "", err
+			}
+		}
+
+		fields, line, err := s.readRecord()
+		if err == io.EOF {
+			_ = s.file.Close()
+			s.file = nil
+			// Don't loop over files without records in the partition forever
+			s.done = !s.loop || !s.read
+			continue
+		}
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "can't read '%s'", s.path)
+		}
+
+		s.index++
+		if (s.index-1)%s.parts == s.part-1 {
+			s.read = true
+			return fields, line, nil
+		}
+	}
+	return nil, "", nil
+}
+
+func (s *source) open() error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	s.file, s.index, s.read = file, 0, false
+
+	if s.format == "jsonl" {
+		s.lines = bufio.NewReader(file)
+		return nil
+	}
+	s.csv = csv.NewReader(file)
+	s.csv.Comma = s.delimiter
+	s.csv.FieldsPerRecord = -1
+	if s.header {
+		columns, err := s.csv.Read()
+		if err != nil && err != io.EOF {
+			return errors.Wrapf(err, "can't read the header of '%s'", s.path)
+		}
+		s.columns = columns
+	}
+	return nil
+}
+
+func (s *source) readRecord() ([]string, string, error) {
+	if s.format == "csv" {
+		fields, err := s.csv.Read()
+		return fields, "", err
+	}
+	for {
+		line, err := s.lines.ReadString('\n')
+		if line = strings.TrimSpace(line); line != "" {
+			return nil, line, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package data
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime(d *Data, dir string) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("data", common.Bind(rt, d, &ctx))
+	rt.Set("dir", dir)
+	return rt
+}
+
+func TestStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k6-data")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	files := map[string]string{
+		"users.csv":    "name,password\nalice,a1\nbob,b2\ncarol,c3\n",
+		"users.tsv":    "alice\ta1\nbob\tb2\n",
+		"orders.jsonl": "{\"id\":1,\"items\":[\"a\"]}\n\n{\"id\":2}\n{\"id\":3}\n",
+		"empty.csv":    "name\n",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	t.Run("shared by VUs", func(t *testing.T) {
+		d := New()
+		rt1, rt2 := newRuntime(d, dir), newRuntime(d, dir)
+		_, err := common.RunString(rt1, `let users = new data.Stream(dir + "/users.csv");`)
+		require.NoError(t, err)
+		_, err = common.RunString(rt2, `let users = new data.Stream(dir + "/users.csv");`)
+		require.NoError(t, err)
+
+		v, err := common.RunString(rt1, `JSON.stringify(users.next())`)
+		require.NoError(t, err)
+		assert.Equal(t, `{"name":"alice","password":"a1"}`, v.String())
+		v, err = common.RunString(rt2, `JSON.stringify([users.next(), users.next(), users.next()])`)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"name":"bob","password":"b2"},{"name":"carol","password":"c3"},null]`, v.String())
+	})
+
+	t.Run("options", func(t *testing.T) {
+		rt := newRuntime(New(), dir)
+		testdata := map[string]string{
+			`let s = new data.Stream(dir + "/users.tsv", { format: "csv", delimiter: "\t", header: false, loop: true });
+			return JSON.stringify([s.next(), s.next(), s.next()]);`: `[["alice","a1"],["bob","b2"],["alice","a1"]]`,
+			`let s = new data.Stream(dir + "/orders.jsonl");
+			return JSON.stringify([s.next(), s.next(), s.next(), s.next()]);`: `[{"id":1,"items":["a"]},{"id":2},{"id":3},null]`,
+			`let s = new data.Stream(dir + "/orders.jsonl", { partition: "2/2", loop: true });
+			return JSON.stringify([s.next(), s.next()]);`: `[{"id":2},{"id":2}]`,
+			`let s = new data.Stream(dir + "/users.csv", { partition: "1/2" });
+			return JSON.stringify([s.next().name, s.next().name, s.next()]);`: `["alice","carol",null]`,
+			`let s = new data.Stream(dir + "/empty.csv", { loop: true });
+			return JSON.stringify([s.next()]);`: `[null]`,
+		}
+		for code, expected := range testdata {
+			v, err := common.RunString(rt, `(function() { `+code+` })()`)
+			if assert.NoError(t, err, code) {
+				assert.Equal(t, expected, v.Export().(string), code)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		rt := newRuntime(New(), dir)
+		testdata := map[string]string{
+			`new data.Stream(dir + "/missing.csv")`:                           "no such file or directory",
+			`new data.Stream(dir + "/users.tsv")`:                             "it has to be 'csv' or 'jsonl'",
+			`new data.Stream(dir + "/users.csv", { delimiter: ";;" })`:        "invalid delimiter ';;'",
+			`new data.Stream(dir + "/users.csv", { partition: "3/2" })`:       "invalid partition '3/2'",
+			`new data.Stream(dir + "/users.csv", { shuffle: true })`:          "unknown stream option 'shuffle'",
+			`new data.Stream(dir + "/users.csv", { format: "jsonl" }).next()`: "SyntaxError",
+		}
+		for code, msg := range testdata {
+			_, err := common.RunString(rt, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg, code)
+			}
+		}
+	})
+}
@@ -23,6 +23,7 @@ package encoding
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 
 	"github.com/loadimpact/k6/js/common"
 )
@@ -71,3 +72,16 @@ func (e *Encoding) B64decode(ctx context.Context, input string, encoding string)
 
 	return string(output)
 }
+
+func (e *Encoding) HexEncode(ctx context.Context, input []byte) string {
+	return hex.EncodeToString(input)
+}
+
+func (e *Encoding) HexDecode(ctx context.Context, input string) string {
+	output, err := hex.DecodeString(input)
+	if err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+
+	return string(output)
+}
@@ -150,4 +150,28 @@ func TestEncodingAlgorithms(t *testing.T) {
 			assert.NoError(t, err)
 		})
 	})
+	t.Run("Hex", func(t *testing.T) {
+		t.Run("Enc", func(t *testing.T) {
+			_, err := common.RunString(rt, `
+			const correct = "68656c6c6f20e4b896e7958c";
+			let encoded = encoding.hexEncode("hello 世界");
+			if (encoded !== correct) {
+				throw new Error("Encoding mismatch: " + encoded);
+			}`)
+			assert.NoError(t, err)
+		})
+		t.Run("Dec", func(t *testing.T) {
+			_, err := common.RunString(rt, `
+			const correct = "hello 世界";
+			let decoded = encoding.hexDecode("68656C6C6F20e4b896e7958c");
+			if (decoded !== correct) {
+				throw new Error("Decoding mismatch: " + decoded);
+			}`)
+			assert.NoError(t, err)
+		})
+		t.Run("InvalidDec", func(t *testing.T) {
+			_, err := common.RunString(rt, `encoding.hexDecode("6g")`)
+			assert.EqualError(t, err, "GoError: encoding/hex: invalid byte: U+0067 'g'")
+		})
+	})
 }
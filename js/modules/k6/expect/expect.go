@@ -0,0 +1,326 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package expect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/js/modules/k6"
+)
+
+// Words that only make assertions readable, like `expect(x).to.be.above(1)`.
+var chainWords = []string{
+	"to", "be", "been", "is", "that", "which", "and", "has", "have", "with", "at", "of", "same",
+	"does", "still",
+}
+
+type Expect struct{}
+
+func New() *Expect {
+	return &Expect{}
+}
+
+// Expect starts a chain of assertions about a value, like Chai's expect(). Every assertion is also
+// a check, named after what it asserts, e.g. "status to equal 200", so failures are counted in
+// the checks metric; a failing one throws an AssertionError, which fails the iteration. The
+// message describes the value, in the checks' names and the errors; it's "value" by default.
+func (*Expect) Expect(ctx context.Context, value goja.Value, message ...string) *goja.Object {
+	subject := "value"
+	if len(message) > 0 && message[0] != "" {
+		subject = message[0]
+	}
+	return newAssertion(ctx, value, subject, false)
+}
+
+type assertion struct {
+	ctx     context.Context
+	rt      *goja.Runtime
+	value   goja.Value
+	subject string
+	negate  bool
+	obj     *goja.Object
+}
+
+func newAssertion(ctx context.Context, value goja.Value, subject string, negate bool) *goja.Object {
+	rt := common.GetRuntime(ctx)
+	a := &assertion{ctx: ctx, rt: rt, value: value, subject: subject, negate: negate, obj: rt.NewObject()}
+
+	getter := func(name string, fn func() goja.Value) {
+		_ = a.obj.DefineAccessorProperty(name,
+			rt.ToValue(func(goja.FunctionCall) goja.Value { return fn() }), nil, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	}
+	method := func(fn func(goja.FunctionCall) goja.Value, names ...string) {
+		for _, name := range names {
+			_ = a.obj.Set(name, fn)
+		}
+	}
+
+	for _, word := range chainWords {
+		getter(word, func() goja.Value { return a.obj })
+	}
+	getter("not", func() goja.Value { return newAssertion(ctx, value, subject, !negate) })
+
+	// Assertions without arguments are properties, like in Chai: `expect(x).to.be.true`
+	properties := []struct {
+		name, what string
+		test       func() bool
+	}{
+		{"ok", "be ok", func() bool { return value.ToBoolean() }},
+		{"true", "be true", func() bool { return value.StrictEquals(rt.ToValue(true)) }},
+		{"false", "be false", func() bool { return value.StrictEquals(rt.ToValue(false)) }},
+		{"null", "be null", func() bool { return goja.IsNull(value) }},
+		{"undefined", "be undefined", func() bool { return goja.IsUndefined(value) }},
+		{"exist", "exist", func() bool { return !goja.IsNull(value) && !goja.IsUndefined(value) }},
+		{"NaN", "be NaN", func() bool { return typeOf(value) == "number" && math.IsNaN(value.ToFloat()) }},
+		{"empty", "be empty", a.isEmpty},
+	}
+	for _, p := range properties {
+		p := p
+		getter(p.name, func() goja.Value { return a.assert(p.test(), p.what) })
+	}
+
+	method(func(c goja.FunctionCall) goja.Value {
+		expected := c.Argument(0)
+		return a.assert(value.StrictEquals(expected), "equal "+describe(expected))
+	}, "equal", "equals", "eq")
+	method(func(c goja.FunctionCall) goja.Value {
+		expected := c.Argument(0)
+		return a.assert(describe(value) == describe(expected), "deeply equal "+describe(expected))
+	}, "eql", "eqls")
+	method(func(c goja.FunctionCall) goja.Value {
+		n := c.Argument(0)
+		return a.assert(value.ToFloat() > n.ToFloat(), "be above "+describe(n))
+	}, "above", "gt", "greaterThan")
+	method(func(c goja.FunctionCall) goja.Value {
+		n := c.Argument(0)
+		return a.assert(value.ToFloat() >= n.ToFloat(), "be at least "+describe(n))
+	}, "least", "gte")
+	method(func(c goja.FunctionCall) goja.Value {
+		n := c.Argument(0)
+		return a.assert(value.ToFloat() < n.ToFloat(), "be below "+describe(n))
+	}, "below", "lt", "lessThan")
+	method(func(c goja.FunctionCall) goja.Value {
+		n := c.Argument(0)
+		return a.assert(value.ToFloat() <= n.ToFloat(), "be at most "+describe(n))
+	}, "most", "lte")
+	method(func(c goja.FunctionCall) goja.Value {
+		lo, hi := c.Argument(0), c.Argument(1)
+		v := value.ToFloat()
+		return a.assert(v >= lo.ToFloat() && v <= hi.ToFloat(), fmt.Sprintf("be within %s..%s", describe(lo), describe(hi)))
+	}, "within")
+	for _, article := range []string{"a", "an"} {
+		article := article
+		method(func(c goja.FunctionCall) goja.Value {
+			typ := c.Argument(0).String()
+			return a.assert(typeOf(value) == strings.ToLower(typ), "be "+article+" "+typ)
+		}, article)
+	}
+	method(func(c goja.FunctionCall) goja.Value {
+		expected := c.Argument(0)
+		return a.assert(a.includes(expected), "include "+describe(expected))
+	}, "include", "includes", "contain", "contains")
+	method(func(c goja.FunctionCall) goja.Value {
+		name := c.Argument(0).String()
+		v := a.get(name)
+		if len(c.Arguments) < 2 {
+			return a.assert(!goja.IsUndefined(v), "have the property "+name)
+		}
+		expected := c.Argument(1)
+		return a.assert(v.StrictEquals(expected), fmt.Sprintf("have the property %s equal to %s", name, describe(expected)))
+	}, "property")
+	method(func(c goja.FunctionCall) goja.Value {
+		n := c.Argument(0)
+		return a.assert(a.get("length").StrictEquals(n), "have a length of "+describe(n))
+	}, "lengthOf")
+	method(func(c goja.FunctionCall) goja.Value {
+		re := c.Argument(0)
+		var matched bool
+		if typeOf(re) == "object" {
+			if test, ok := goja.AssertFunction(re.ToObject(rt).Get("test")); ok {
+				v, err := test(re, value)
+				if err != nil {
+					common.Throw(rt, err)
+				}
+				matched = v.ToBoolean()
+			}
+		}
+		return a.assert(matched, "match "+re.String())
+	}, "match", "matches")
+	method(func(c goja.FunctionCall) goja.Value {
+		list := c.Argument(0)
+		found := false
+		a.forEach(list, func(v goja.Value) { found = found || value.StrictEquals(v) })
+		return a.assert(found, "be one of "+describe(list))
+	}, "oneOf")
+	method(func(c goja.FunctionCall) goja.Value {
+		fn, ok := goja.AssertFunction(c.Argument(0))
+		if !ok {
+			panic(rt.NewTypeError("satisfy() needs a function"))
+		}
+		desc := "satisfy the condition"
+		if len(c.Arguments) > 1 {
+			desc = "satisfy " + c.Argument(1).String()
+		}
+		v, err := fn(goja.Undefined(), value)
+		if err != nil {
+			common.Throw(rt, err)
+		}
+		return a.assert(v.ToBoolean(), desc)
+	}, "satisfy", "satisfies")
+
+	return a.obj
+}
+
+// assert records a check for an assertion, and throws an AssertionError if it failed.
+func (a *assertion) assert(passed bool, what string) goja.Value {
+	name := a.subject + " to "
+	if a.negate {
+		name += "not "
+		passed = !passed
+	}
+	name += what
+
+	// Outside of VU code there are no checks, but assertions can still fail
+	if common.GetState(a.ctx) != nil {
+		checks := a.rt.NewObject()
+		_ = checks.Set(name, passed)
+		if _, err := k6.New().Check(a.ctx, a.value, checks); err != nil {
+			common.Throw(a.rt, err)
+		}
+	}
+	if !passed {
+		newError, _ := goja.AssertFunction(a.rt.Get("Error"))
+		e, err := newError(goja.Undefined(), a.rt.ToValue(fmt.Sprintf("expected %s, but it was %s", name, describe(a.value))))
+		if err != nil {
+			common.Throw(a.rt, err)
+		}
+		obj := e.ToObject(a.rt)
+		_ = obj.Set("name", "AssertionError")
+		panic(obj)
+	}
+	return a.obj
+}
+
+// get returns a property of the value, or undefined if it doesn't have it or isn't an object.
+func (a *assertion) get(name string) goja.Value {
+	if goja.IsNull(a.value) || goja.IsUndefined(a.value) {
+		return goja.Undefined()
+	}
+	if v := a.value.ToObject(a.rt).Get(name); v != nil {
+		return v
+	}
+	return goja.Undefined()
+}
+
+// forEach calls fn for every element of an array.
+func (a *assertion) forEach(list goja.Value, fn func(goja.Value)) {
+	if typeOf(list) != "array" {
+		return
+	}
+	obj := list.ToObject(a.rt)
+	length := obj.Get("length").ToInteger()
+	for i := int64(0); i < length; i++ {
+		fn(obj.Get(fmt.Sprint(i)))
+	}
+}
+
+func (a *assertion) includes(expected goja.Value) bool {
+	switch typeOf(a.value) {
+	case "string":
+		return strings.Contains(a.value.String(), expected.String())
+	case "array":
+		found := false
+		a.forEach(a.value, func(v goja.Value) { found = found || v.StrictEquals(expected) })
+		return found
+	case "object":
+		if typeOf(expected) != "object" {
+			return false
+		}
+		// Objects include others whose properties they all have
+		obj := expected.ToObject(a.rt)
+		for _, k := range obj.Keys() {
+			if !a.get(k).StrictEquals(obj.Get(k)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func (a *assertion) isEmpty() bool {
+	switch typeOf(a.value) {
+	case "string", "array":
+		return a.get("length").ToInteger() == 0
+	case "object":
+		return len(a.value.ToObject(a.rt).Keys()) == 0
+	default:
+		return false
+	}
+}
+
+// typeOf is like JS's typeof, but with "null" and "array" too.
+func typeOf(v goja.Value) string {
+	if goja.IsUndefined(v) {
+		return "undefined"
+	}
+	if goja.IsNull(v) {
+		return "null"
+	}
+	if _, ok := goja.AssertFunction(v); ok {
+		return "function"
+	}
+	switch v.Export().(type) {
+	case string:
+		return "string"
+	case int64, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// describe returns a value as JSON, for checks' names and errors.
+func describe(v goja.Value) string {
+	switch typeOf(v) {
+	case "undefined", "function":
+		return typeOf(v)
+	case "number":
+		if f := v.ToFloat(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return v.String()
+		}
+	}
+	if data, err := json.Marshal(v.Export()); err == nil {
+		return string(data)
+	}
+	return v.String()
+}
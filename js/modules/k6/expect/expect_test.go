@@ -0,0 +1,109 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package expect
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpect(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	samples := make(chan stats.SampleContainer, 1000)
+	ctx := common.WithRuntime(context.Background(), rt)
+	ctx = common.WithState(ctx, &common.State{
+		Group:   root,
+		Options: lib.Options{SystemTags: lib.GetTagSet(lib.DefaultSystemTagList...)},
+		Samples: samples,
+	})
+	rt.Set("expect", common.Bind(rt, New(), &ctx)["expect"])
+
+	t.Run("passing", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let res = { status: 200, body: "hello world", json: { items: [1, 2, 3], user: { name: "k6" } } };
+		expect(res.status, "status").to.equal(200).and.be.a("number").and.not.equal(404);
+		expect(res.status).to.be.within(200, 299).and.above(199).and.at.most(200);
+		expect(res.body).to.include("world").and.match(/^hello/).and.have.lengthOf(11);
+		expect(res.json).to.have.property("items").and.include({ user: res.json.user });
+		expect(res.json.items).to.be.an("array").and.include(2).and.eql([1, 2, 3]);
+		expect(res.json.user).to.have.property("name", "k6");
+		expect(true).to.be.true;
+		expect(null).to.be.null.and.not.exist;
+		expect("").to.be.empty;
+		expect([]).to.be.empty.and.ok;
+		expect(0).to.not.be.ok;
+		expect(res.status).to.be.oneOf([200, 201]);
+		expect(res.status).to.satisfy(function(s) { return s % 100 === 0; }, "a round number");
+		`)
+		require.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 24)
+		for _, sc := range bufSamples {
+			sample := sc.(stats.Sample)
+			assert.Equal(t, metrics.Checks, sample.Metric)
+			assert.Equal(t, 1.0, sample.Value)
+		}
+		assert.Contains(t, root.Checks, "status to equal 200")
+		assert.Contains(t, root.Checks, "status to not equal 404")
+		assert.Contains(t, root.Checks, "value to be within 200..299")
+		assert.Contains(t, root.Checks, "value to satisfy a round number")
+		assert.Contains(t, root.Checks, "value to not be ok")
+		assert.Contains(t, root.Checks, "value to be an array")
+		assert.Equal(t, int64(1), root.Checks["status to equal 200"].Passes)
+	})
+
+	t.Run("failing", func(t *testing.T) {
+		testdata := map[string]string{
+			`expect(404, "status").to.equal(200)`:                      "AssertionError: expected status to equal 200, but it was 404",
+			`expect("abc").not.to.include("b")`:                        "AssertionError: expected value to not include \"b\", but it was \"abc\"",
+			`expect({ a: 1 }).to.eql({ a: 2 })`:                        "AssertionError: expected value to deeply equal {\"a\":2}, but it was {\"a\":1}",
+			`expect({}).to.have.property("a")`:                         "AssertionError: expected value to have the property a, but it was {}",
+			`expect(undefined).to.exist`:                               "AssertionError: expected value to exist, but it was undefined",
+			`expect(1).to.be.a("string")`:                              "AssertionError: expected value to be a string, but it was 1",
+			`expect(1).to.satisfy(1)`:                                  "TypeError: satisfy() needs a function",
+			`try { expect(1).to.be.false } catch (e) { throw e.name }`: "AssertionError",
+		}
+		for code, msg := range testdata {
+			t.Run(code, func(t *testing.T) {
+				_, err := common.RunString(rt, code)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), msg)
+			})
+		}
+
+		assert.Equal(t, int64(1), root.Checks["status to equal 200"].Fails)
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			assert.Equal(t, 0.0, sc.(stats.Sample).Value)
+		}
+	})
+}
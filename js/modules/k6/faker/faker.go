@@ -0,0 +1,261 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	firstNames = []string{
+		"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry", "Isabel", "Jack",
+		"Karen", "Liam", "Maria", "Noah", "Olivia", "Peter", "Quinn", "Rosa", "Samuel", "Tina",
+		"Umar", "Vera", "William", "Xenia", "Yusuf", "Zoe", "Ahmed", "Chen", "Ingrid", "Kenji",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Garcia", "Müller", "Rossi", "Martin", "Kowalski", "Nielsen", "Silva",
+		"Tanaka", "Kim", "Novak", "Dubois", "Jansen", "Larsen", "Andersson", "Horvat", "Popescu",
+		"Ivanov", "Khan", "Nguyen", "Okafor", "Papadopoulos", "Schmidt", "Taylor", "Wilson",
+	}
+	// Reserved for documentation, so that generated addresses never reach anyone
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+	loremWords   = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+		tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud
+		exercitation ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in
+		reprehenderit voluptate velit esse cillum eu fugiat nulla pariatur excepteur sint occaecat
+		cupidatat non proident sunt culpa qui officia deserunt mollit anim id est laborum`)
+)
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Faker generates test data in Go, which is much faster than building it in JS. The random numbers
+// come from Math.random(), so the seed option and randomSeed() make the data reproducible.
+type Faker struct{}
+
+func New() *Faker {
+	return &Faker{}
+}
+
+// random returns a function that calls the runtime's Math.random().
+func random(ctx context.Context) (func() float64, error) {
+	rt := common.GetRuntime(ctx)
+	fn, ok := goja.AssertFunction(rt.Get("Math").ToObject(rt).Get("random"))
+	if !ok {
+		return nil, errors.New("Math.random isn't a function")
+	}
+	return func() float64 {
+		v, _ := fn(goja.Undefined())
+		return v.ToFloat()
+	}, nil
+}
+
+func pick(rnd func() float64, items []string) string {
+	return items[int(rnd()*float64(len(items)))%len(items)]
+}
+
+// FirstName returns a random first name.
+func (*Faker) FirstName(ctx context.Context) (string, error) {
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	return pick(rnd, firstNames), nil
+}
+
+// LastName returns a random last name.
+func (*Faker) LastName(ctx context.Context) (string, error) {
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	return pick(rnd, lastNames), nil
+}
+
+// Name returns a random full name.
+func (*Faker) Name(ctx context.Context) (string, error) {
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	return pick(rnd, firstNames) + " " + pick(rnd, lastNames), nil
+}
+
+// Email returns a random email address, at one of the domains reserved for examples.
+func (*Faker) Email(ctx context.Context) (string, error) {
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	user := strings.ToLower(pick(rnd, firstNames) + "." + pick(rnd, lastNames))
+	user = strings.Replace(user, "ü", "ue", -1)
+	return fmt.Sprintf("%s%d@%s", user, int(rnd()*1000), pick(rnd, emailDomains)), nil
+}
+
+// Uuid returns a random (version 4) UUID.
+func (*Faker) Uuid(ctx context.Context) (string, error) {
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b [16]byte
+	for i := 0; i < len(b); i += 4 {
+		n := uint32(rnd() * (1 << 32))
+		b[i], b[i+1], b[i+2], b[i+3] = byte(n>>24), byte(n>>16), byte(n>>8), byte(n)
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // Variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Lorem returns a number of lorem ipsum words (10 by default), as a sentence.
+func (*Faker) Lorem(ctx context.Context, words ...int) (string, error) {
+	n := 10
+	if len(words) > 0 {
+		n = words[0]
+	}
+	if n < 0 {
+		return "", errors.Errorf("invalid number of lorem words %d", n)
+	}
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		word := pick(rnd, loremWords)
+		if i == 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		} else {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(word)
+	}
+	if n > 0 {
+		sb.WriteByte('.')
+	}
+	return sb.String(), nil
+}
+
+// String returns a random string of a length, with the characters of a charset, which are letters
+// and digits by default.
+func (*Faker) String(ctx context.Context, length int, charset ...string) (string, error) {
+	chars := []rune(alphanumeric)
+	if len(charset) > 0 {
+		chars = []rune(charset[0])
+	}
+	if length < 0 || len(chars) == 0 {
+		return "", errors.Errorf("invalid string length %d or empty charset", length)
+	}
+	rnd, err := random(ctx)
+	if err != nil {
+		return "", err
+	}
+	s := make([]rune, length)
+	for i := range s {
+		s[i] = chars[int(rnd()*float64(len(chars)))%len(chars)]
+	}
+	return string(s), nil
+}
+
+// Int returns a random integer between min and max, both included.
+func (*Faker) Int(ctx context.Context, min, max int64) (int64, error) {
+	if max < min {
+		return 0, errors.Errorf("invalid range [%d, %d], max can't be less than min", min, max)
+	}
+	rnd, err := random(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return min + int64(math.Floor(rnd()*float64(max-min+1))), nil
+}
+
+// Float returns a random number between min (included) and max (excluded).
+func (*Faker) Float(ctx context.Context, min, max float64) (float64, error) {
+	if max < min {
+		return 0, errors.Errorf("invalid range [%v, %v], max can't be less than min", min, max)
+	}
+	rnd, err := random(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return min + rnd()*(max-min), nil
+}
+
+// Choice returns a random item of an array, with the same chances for all of them, or in
+// proportion to their weights, if there are any.
+func (*Faker) Choice(ctx context.Context, items goja.Value, weightsV goja.Value) (goja.Value, error) {
+	rt := common.GetRuntime(ctx)
+	if items == nil || goja.IsUndefined(items) || goja.IsNull(items) {
+		return nil, errors.New("choice needs an array of items")
+	}
+	var weights []float64
+	if weightsV != nil && !goja.IsUndefined(weightsV) && !goja.IsNull(weightsV) {
+		if err := rt.ExportTo(weightsV, &weights); err != nil {
+			return nil, errors.Wrap(err, "the weights have to be an array of numbers")
+		}
+	}
+	arr := items.ToObject(rt)
+	n := int(arr.Get("length").ToInteger())
+	if n == 0 {
+		return nil, errors.New("can't choose from an empty array")
+	}
+	if len(weights) > 0 && len(weights) != n {
+		return nil, errors.Errorf("there are %d weights for %d items, there has to be one for each", len(weights), n)
+	}
+	rnd, err := random(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(weights) == 0 {
+		return arr.Get(fmt.Sprint(int(rnd()*float64(n)) % n)), nil
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.Errorf("invalid weight %v, weights can't be negative", w)
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New("at least one weight has to be above 0")
+	}
+	x := rnd() * total
+	for i, w := range weights {
+		if x < w {
+			return arr.Get(fmt.Sprint(i)), nil
+		}
+		x -= w
+	}
+	// Rounding errors; the last item with a weight
+	for i := n - 1; ; i-- {
+		if weights[i] > 0 {
+			return arr.Get(fmt.Sprint(i)), nil
+		}
+	}
+}
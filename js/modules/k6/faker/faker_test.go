@@ -0,0 +1,106 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package faker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaker(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("faker", common.Bind(rt, New(), &ctx))
+
+	t.Run("values", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		function check(name, value, re) {
+			if (!re.test(value)) { throw new Error(name + " doesn't match " + re + ": " + value); }
+		}
+		for (let i = 0; i < 100; i++) {
+			check("name", faker.name(), /^[A-Z][a-z]+ [A-Z]\S+$/);
+			check("firstName", faker.firstName(), /^[A-Z][a-z]+$/);
+			check("lastName", faker.lastName(), /^[A-Z]\S+$/);
+			check("email", faker.email(), /^[a-z]+\.[a-z]+\d+@example\.(com|org|net)$/);
+			check("uuid", faker.uuid(), /^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$/);
+			check("lorem", faker.lorem(), /^[A-Z][a-z]*( [a-z]+){9}\.$/);
+			check("lorem(3)", faker.lorem(3), /^[A-Z][a-z]*( [a-z]+){2}\.$/);
+			check("string", faker.string(12), /^[a-zA-Z0-9]{12}$/);
+			check("string(5, 'ab')", faker.string(5, "ab"), /^[ab]{5}$/);
+			check("int", faker.int(-2, 2), /^-?[0-2]$/);
+			let f = faker.float(1.5, 2);
+			if (f < 1.5 || f >= 2) { throw new Error("float out of range: " + f); }
+			check("choice", faker.choice(["x", "y"]), /^[xy]$/);
+			check("weighted choice", faker.choice(["x", "y", "z"], [0, 1, 0]), /^y$/);
+		}
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("distribution", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		let counts = { a: 0, b: 0, ints: {} };
+		for (let i = 0; i < 4000; i++) {
+			counts[faker.choice(["a", "b"], [3, 1])]++;
+			let n = faker.int(1, 3);
+			counts.ints[n] = (counts.ints[n] || 0) + 1;
+		}
+		counts.a / 4000 > 0.7 && counts.a / 4000 < 0.8 && Object.keys(counts.ints).sort().join(",") === "1,2,3";
+		`)
+		require.NoError(t, err)
+		assert.True(t, v.ToBoolean())
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		rt.SetRandSource(common.NewSeededRandSource(42))
+		a, err := common.RunString(rt, `[faker.uuid(), faker.name(), faker.int(0, 1000)].join()`)
+		require.NoError(t, err)
+		rt.SetRandSource(common.NewSeededRandSource(42))
+		b, err := common.RunString(rt, `[faker.uuid(), faker.name(), faker.int(0, 1000)].join()`)
+		require.NoError(t, err)
+		assert.Equal(t, a.String(), b.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`faker.int(2, 1)`:                  "invalid range [2, 1]",
+			`faker.lorem(-1)`:                  "invalid number of lorem words -1",
+			`faker.string(5, "")`:              "invalid string length 5 or empty charset",
+			`faker.choice([])`:                 "can't choose from an empty array",
+			`faker.choice(["a"], [1, 2])`:      "there are 2 weights for 1 items",
+			`faker.choice(["a", "b"], [0, 0])`: "at least one weight has to be above 0",
+			`faker.choice(["a"], [-1])`:        "weights can't be negative",
+		}
+		for code, msg := range testdata {
+			_, err := common.RunString(rt, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg, code)
+			}
+		}
+	})
+}
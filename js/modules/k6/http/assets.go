@@ -0,0 +1,183 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// The types of assets that fetchAssets() fetches, with their selectors and URL attributes.
+var assetTypes = []struct{ name, selector, attr string }{
+	{"css", `link[rel="stylesheet"][href]`, "href"},
+	{"js", "script[src]", "src"},
+	{"img", "img[src]", "src"},
+}
+
+var defaultAssetTypes = []string{"css", "js", "img"}
+
+// FetchAssets fetches the images, stylesheets and scripts that an HTML response refers to, in
+// parallel like http.batch(), to approximate the weight of loading a page without a browser. The
+// requests are tagged with static_asset=true; the responses are returned in document order, and
+// every URL is only fetched once. Its argument can have the types of assets to fetch, "css", "js"
+// and "img", all of them by default, and the params of the requests.
+func (res *HTTPResponse) FetchAssets(args ...goja.Value) ([]*HTTPResponse, error) {
+	rt := common.GetRuntime(res.ctx)
+
+	types := defaultAssetTypes
+	var params *goja.Object
+	if len(args) > 0 && !goja.IsUndefined(args[0]) && !goja.IsNull(args[0]) {
+		opts := args[0].ToObject(rt)
+		for _, k := range opts.Keys() {
+			v := opts.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "types":
+				types = nil
+				if err := rt.ExportTo(v, &types); err != nil {
+					return nil, errors.Wrap(err, "types has to be an array")
+				}
+				for _, t := range types {
+					if !isAssetType(t) {
+						return nil, errors.Errorf("unsupported asset type '%s', use css, js or img", t)
+					}
+				}
+			case "params":
+				params = v.ToObject(rt)
+			}
+		}
+	}
+
+	urls, err := res.assetURLs(types)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return []*HTTPResponse{}, nil
+	}
+
+	reqParams := rt.NewObject()
+	tags := map[string]interface{}{"static_asset": "true"}
+	if params != nil {
+		for _, k := range params.Keys() {
+			if k != "tags" {
+				_ = reqParams.Set(k, params.Get(k))
+				continue
+			}
+			if v := params.Get(k); !goja.IsUndefined(v) && !goja.IsNull(v) {
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+	_ = reqParams.Set("tags", tags)
+
+	reqs := make([]interface{}, len(urls))
+	for i, u := range urls {
+		reqs[i] = map[string]interface{}{"method": HTTP_METHOD_GET, "url": u, "params": reqParams}
+	}
+	batch, err := New().Batch(res.ctx, rt.ToValue(reqs))
+	if err != nil {
+		return nil, err
+	}
+
+	results := batch.ToObject(rt)
+	responses := make([]*HTTPResponse, len(urls))
+	for i := range urls {
+		responses[i], _ = results.Get(strconv.Itoa(i)).Export().(*HTTPResponse)
+	}
+	return responses, nil
+}
+
+func isAssetType(name string) bool {
+	for _, t := range assetTypes {
+		if t.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assetURLs returns the absolute URLs of the assets of the given types, without duplicates and
+// data: URLs, resolved against the <base> of the document if it has one.
+func (res *HTTPResponse) assetURLs(types []string) ([]string, error) {
+	rt := common.GetRuntime(res.ctx)
+
+	base, err := url.Parse(res.URL)
+	if err != nil {
+		return nil, err
+	}
+	if href := res.Html("base[href]").Attr("href"); !goja.IsUndefined(href) {
+		if baseHref, err := url.Parse(href.String()); err == nil {
+			base = base.ResolveReference(baseHref)
+		}
+	}
+
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+	var selectors []string
+	for _, t := range assetTypes {
+		if wanted[t.name] {
+			selectors = append(selectors, t.selector)
+		}
+	}
+
+	var urls []string
+	seen := make(map[string]bool)
+	// A single selector keeps the assets in document order
+	for _, el := range res.Html(strings.Join(selectors, ", ")).ToArray() {
+		var ref string
+		for _, t := range assetTypes {
+			if wanted[t.name] && el.Is(rt.ToValue(t.selector)) {
+				ref = strings.TrimSpace(el.Attr(t.attr).String())
+				break
+			}
+		}
+		if ref == "" || strings.HasPrefix(strings.ToLower(ref), "data:") {
+			continue
+		}
+		refURL, err := url.Parse(ref)
+		if err != nil {
+			continue // Browsers skip invalid URLs as well
+		}
+		u := base.ResolveReference(refURL)
+		u.Fragment = ""
+		if u.Scheme != "http" && u.Scheme != "https" {
+			continue
+		}
+		if s := u.String(); !seen[s] {
+			seen[s] = true
+			urls = append(urls, s)
+		}
+	}
+	return urls, nil
+}
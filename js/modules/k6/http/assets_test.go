@@ -0,0 +1,100 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAssetsPage = `<!DOCTYPE html>
+<html>
+<head>
+	<link rel="stylesheet" href="/static/style.css">
+	<link rel="icon" href="/favicon.ico">
+	<script src="app.js"></script>
+	<script>console.log("inline");</script>
+</head>
+<body>
+	<img src="img/logo.png#top">
+	<img src="/static/img/logo.png">
+	<img src="data:image/png;base64,iVBORw0KGgo=">
+	<img src="HTTPBIN_URL/static/img/photo.jpg">
+	<script src="/static/app.js"></script>
+</body>
+</html>`
+
+func TestFetchAssets(t *testing.T) {
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+
+	tb.Mux.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/static/" {
+			_, _ = fmt.Fprint(w, tb.Replacer.Replace(testAssetsPage))
+			return
+		}
+		_, _ = fmt.Fprint(w, r.URL.Path)
+	})
+
+	_, err := common.RunString(rt, tb.Replacer.Replace(`
+	let res = http.get("HTTPBIN_URL/static/");
+	let assets = res.fetchAssets();
+	let urls = assets.map(function(a) { return a.url.replace("HTTPBIN_URL", ""); }).join(",");
+	if (urls !== "/static/style.css,/static/app.js,/static/img/logo.png,/static/img/photo.jpg") {
+		throw new Error("wrong assets: " + urls);
+	}
+	if (assets[0].body !== "/static/style.css") { throw new Error("wrong body: " + assets[0].body); }
+
+	assets = res.fetchAssets({ types: ["img"], params: { tags: { page: "home" } } });
+	if (assets.length !== 2) { throw new Error("wrong number of images: " + assets.length); }
+	`))
+	require.NoError(t, err)
+
+	var assets, home int
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.HTTPReqs {
+				continue
+			}
+			tags := s.Tags.CloneTags()
+			if tags["static_asset"] == "true" {
+				assets++
+			}
+			if tags["page"] == "home" {
+				home++
+			}
+		}
+	}
+	assert.Equal(t, 6, assets)
+	assert.Equal(t, 2, home)
+
+	_, err = common.RunString(rt, tb.Replacer.Replace(`http.get("HTTPBIN_URL/static/").fetchAssets({ types: ["font"] })`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unsupported asset type 'font'")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// countingReader counts the bytes read through it, to know the size of a response body before
+// it's decompressed.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// errUnsupportedEncoding is returned by wrapDecompressionReader for content encodings that k6
+// can't decode; the body is left as it is in that case.
+type errUnsupportedEncoding string
+
+func (e errUnsupportedEncoding) Error() string {
+	return "unsupported content encoding '" + string(e) + "', the body wasn't decompressed"
+}
+
+// wrapDecompressionReader returns a reader that undoes the given Content-Encoding, which can be
+// a comma-separated list of encodings, applied in order.
+func wrapDecompressionReader(r io.Reader, contentEncoding string) (io.Reader, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	// The encodings were applied in the listed order, so they have to be undone in reverse.
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var err error
+		switch enc := strings.ToLower(strings.TrimSpace(encodings[i])); enc {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(r)
+		case "deflate":
+			r, err = newDeflateReader(r)
+		default:
+			return nil, errUnsupportedEncoding(enc)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "couldn't decompress the %s body", encodings[i])
+		}
+	}
+	return r, nil
+}
+
+// newDeflateReader handles both the zlib-wrapped "deflate" from the spec, and the raw DEFLATE
+// streams that some servers send instead.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// A zlib header has CM=8 in the low nibble of the first byte, and is a multiple of 31.
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// compressBody compresses the body with the given comma-separated list of algorithms, applied
+// in order, and returns it along with the matching Content-Encoding header value.
+func compressBody(algorithms string, body *bytes.Buffer) (*bytes.Buffer, string, error) {
+	var contentEncoding []string
+	for _, algorithm := range strings.Split(algorithms, ",") {
+		algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+		if algorithm == "" {
+			continue
+		}
+
+		buf := new(bytes.Buffer)
+		var w io.WriteCloser
+		switch algorithm {
+		case "gzip":
+			w = gzip.NewWriter(buf)
+		case "deflate":
+			w = zlib.NewWriter(buf)
+		case "br", "zstd":
+			// There are no brotli and zstd encoders among the dependencies yet
+			return nil, "", errors.Errorf("compression algorithm '%s' isn't supported yet, use gzip or deflate", algorithm)
+		default:
+			return nil, "", errors.Errorf("unsupported compression algorithm '%s', use gzip or deflate", algorithm)
+		}
+		if _, err := io.Copy(w, body); err != nil {
+			return nil, "", err
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", err
+		}
+		body = buf
+		contentEncoding = append(contentEncoding, algorithm)
+	}
+	return body, strings.Join(contentEncoding, ", "), nil
+}
@@ -0,0 +1,117 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	graphQLComment   = regexp.MustCompile(`#[^\n\r]*`)
+	graphQLOperation = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+)
+
+// Graphql sends a GraphQL query, with optional variables and request params, as a JSON POST
+// request. Its samples are tagged with the name of the operation, so that the operations that are
+// all sent to the same URL can be told apart, and whether the response has GraphQL errors, which
+// come with a 200 status, is the graphql_req_failed metric. The operation name is the one of the
+// first operation in the query, unless the operationName param says otherwise.
+func (h *HTTP) Graphql(ctx context.Context, url goja.Value, query string, args ...goja.Value) (*HTTPResponse, error) {
+	rt := common.GetRuntime(ctx)
+	u, err := ToURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var variables interface{}
+	if len(args) > 0 && !goja.IsUndefined(args[0]) && !goja.IsNull(args[0]) {
+		variables = args[0].Export()
+	}
+	var params goja.Value
+	if len(args) > 1 {
+		params = args[1]
+	}
+
+	operationName := graphQLOperationName(query)
+	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
+		if v := params.ToObject(rt).Get("operationName"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			operationName = v.String()
+		}
+	}
+
+	body, err := json.Marshal(struct {
+		Query         string      `json:"query"`
+		Variables     interface{} `json:"variables,omitempty"`
+		OperationName string      `json:"operationName,omitempty"`
+	}{query, variables, operationName})
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't encode the GraphQL variables")
+	}
+
+	req, err := h.parseRequest(ctx, HTTP_METHOD_POST, u, string(body), params)
+	if err != nil {
+		return nil, err
+	}
+	for header, value := range map[string]string{"Content-Type": "application/json", "Accept": "application/json"} {
+		if req.req.Header.Get(header) == "" {
+			req.req.Header.Set(header, value)
+		}
+	}
+	if _, ok := req.tags["operation"]; !ok && operationName != "" {
+		req.tags["operation"] = operationName
+	}
+	req.graphQL = true
+
+	res, err := h.request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, runAfterResponse(rt, res)
+}
+
+// graphQLOperationName returns the name of the first operation in a GraphQL document, or an empty
+// string if it's anonymous, like `{ user(id: 1) { name } }`.
+func graphQLOperationName(query string) string {
+	match := graphQLOperation.FindStringSubmatch(graphQLComment.ReplaceAllString(query, ""))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// graphQLFailed returns whether a response isn't a successful GraphQL response, because it has
+// errors, or because it's not a GraphQL response at all, e.g. from a proxy.
+func graphQLFailed(body string) bool {
+	var res struct {
+		Data   json.RawMessage   `json:"data"`
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		return true
+	}
+	return len(res.Errors) > 0 || res.Data == nil
+}
@@ -0,0 +1,98 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLOperationName(t *testing.T) {
+	testdata := map[string]string{
+		`query GetUser($id: ID!) { user(id: $id) { name } }`:    "GetUser",
+		`mutation AddUser { addUser(name: "k6") { id } }`:       "AddUser",
+		"# query Commented\nsubscription OnMessage { message }": "OnMessage",
+		`{ user(id: 1) { name } }`:                              "",
+		`query { user(id: 1) { name } }`:                        "",
+	}
+	for query, name := range testdata {
+		assert.Equal(t, name, graphQLOperationName(query), query)
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+
+	tb.Mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query         string                 `json:"query"`
+			Variables     map[string]interface{} `json:"variables"`
+			OperationName string                 `json:"operationName"`
+		}
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.Header().Set("Content-Type", "application/json")
+		switch req.OperationName {
+		case "GetUser":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"user": map[string]interface{}{"id": req.Variables["id"], "name": "k6"}},
+			})
+		default:
+			_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "unknown operation"}]}`))
+		}
+	})
+
+	_, err := common.RunString(rt, tb.Replacer.Replace(`
+	let res = http.graphql("HTTPBIN_URL/graphql", "query GetUser($id: ID!) { user(id: $id) { name } }", { id: "1" });
+	if (res.status !== 200 || res.json("data.user.name") !== "k6" || res.json("data.user.id") !== "1") {
+		throw new Error("wrong response: " + res.body);
+	}
+	res = http.graphql("HTTPBIN_URL/graphql", "{ user(id: 1) { name } }", null, { tags: { tag: "value" } });
+	if (res.json("errors.0.message") !== "unknown operation") { throw new Error("wrong response: " + res.body); }
+	`))
+	require.NoError(t, err)
+
+	failed := map[string]float64{}
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.GraphQLReqFailed {
+				continue
+			}
+			tags := s.Tags.CloneTags()
+			assert.Equal(t, "POST", tags["method"])
+			if operation, ok := tags["operation"]; ok {
+				failed[operation] = s.Value
+			} else {
+				assert.Equal(t, "value", tags["tag"])
+				failed["anonymous"] = s.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"GetUser": 0, "anonymous": 1}, failed)
+}
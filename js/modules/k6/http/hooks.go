@@ -0,0 +1,192 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// Like the response callback, the hooks are per VU and usually added in the init context, so
+// they're kept in a hidden property of the VU's runtime.
+const hooksProperty = "__k6_http_hooks"
+
+type hooks struct {
+	beforeRequest []goja.Callable
+	afterResponse []goja.Callable
+
+	// Requests sent by the hooks themselves don't run the hooks again, so that they don't recurse.
+	running bool
+}
+
+// BeforeRequest adds a function that's called with every request of the VU before it's sent, with
+// its method, url, headers, body and tags, which it can change, e.g. to sign the request; null
+// removes all of them. The hooks run in the order they were added.
+func (*HTTP) BeforeRequest(ctx context.Context, hook goja.Value) {
+	rt := common.GetRuntime(ctx)
+	h := getHooks(rt)
+	if hook == nil || goja.IsUndefined(hook) || goja.IsNull(hook) {
+		h.beforeRequest = nil
+		return
+	}
+	fn, ok := goja.AssertFunction(hook)
+	if !ok {
+		common.Throw(rt, errors.New("beforeRequest() needs a function"))
+	}
+	h.beforeRequest = append(h.beforeRequest, fn)
+}
+
+// AfterResponse adds a function that's called with every response of the VU, e.g. to check it or
+// to add metrics of its own; null removes all of them. Responses of requests that threw because of
+// the throw option don't get to the hooks.
+func (*HTTP) AfterResponse(ctx context.Context, hook goja.Value) {
+	rt := common.GetRuntime(ctx)
+	h := getHooks(rt)
+	if hook == nil || goja.IsUndefined(hook) || goja.IsNull(hook) {
+		h.afterResponse = nil
+		return
+	}
+	fn, ok := goja.AssertFunction(hook)
+	if !ok {
+		common.Throw(rt, errors.New("afterResponse() needs a function"))
+	}
+	h.afterResponse = append(h.afterResponse, fn)
+}
+
+func getHooks(rt *goja.Runtime) *hooks {
+	if v := rt.GlobalObject().Get(hooksProperty); v != nil {
+		if h, ok := v.Export().(*hooks); ok {
+			return h
+		}
+	}
+	h := &hooks{}
+	err := rt.GlobalObject().DefineDataProperty(hooksProperty, rt.ToValue(h), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	return h
+}
+
+// runBeforeRequest runs the beforeRequest hooks on a parsed request, before its body is compressed,
+// and applies their changes to it.
+func runBeforeRequest(rt *goja.Runtime, preq *parsedHTTPRequest) error {
+	h := getHooks(rt)
+	if len(h.beforeRequest) == 0 || h.running {
+		return nil
+	}
+	h.running = true
+	defer func() { h.running = false }()
+
+	headers := rt.NewObject()
+	for k, vs := range preq.req.Header {
+		_ = headers.Set(k, strings.Join(vs, ", "))
+	}
+	if preq.req.Host != "" {
+		_ = headers.Set("Host", preq.req.Host)
+	}
+	tags := rt.NewObject()
+	for k, v := range preq.tags {
+		_ = tags.Set(k, v)
+	}
+	body := goja.Null()
+	if preq.body != nil {
+		body = rt.ToValue(preq.body.String())
+	}
+
+	req := rt.NewObject()
+	_ = req.Set("method", preq.req.Method)
+	_ = req.Set("url", preq.url.URLString)
+	_ = req.Set("headers", headers)
+	_ = req.Set("body", body)
+	_ = req.Set("tags", tags)
+	for _, hook := range h.beforeRequest {
+		if _, err := hook(goja.Undefined(), req); err != nil {
+			return err
+		}
+	}
+
+	preq.req.Method = strings.ToUpper(req.Get("method").String())
+	if u := req.Get("url").String(); u != preq.url.URLString {
+		reqURL, err := ToURL(u)
+		if err != nil {
+			return errors.Wrap(err, "invalid url set by a beforeRequest hook")
+		}
+		preq.url = &reqURL
+		preq.req.URL = reqURL.URL
+	}
+
+	preq.req.Header, preq.req.Host = make(http.Header), ""
+	if v := req.Get("headers"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		headers := v.ToObject(rt)
+		for _, key := range headers.Keys() {
+			str := headers.Get(key).String()
+			switch strings.ToLower(key) {
+			case "host":
+				preq.req.Host = str
+			default:
+				preq.req.Header.Set(key, str)
+			}
+		}
+	}
+
+	preq.tags = make(map[string]string)
+	if v := req.Get("tags"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		tags := v.ToObject(rt)
+		for _, key := range tags.Keys() {
+			preq.tags[key] = tags.Get(key).String()
+		}
+	}
+
+	// Bodies that weren't changed are kept as they are, since binary ones don't survive being
+	// turned into a JS string and back
+	switch v := req.Get("body"); {
+	case v == nil || goja.IsUndefined(v) || goja.IsNull(v):
+		preq.body = nil
+	case !v.StrictEquals(body):
+		preq.body = bytes.NewBufferString(v.String())
+	}
+	return nil
+}
+
+// runAfterResponse runs the afterResponse hooks on a response; it has to be called on the VU's
+// goroutine, unlike request().
+func runAfterResponse(rt *goja.Runtime, res *HTTPResponse) error {
+	h := getHooks(rt)
+	if len(h.afterResponse) == 0 || h.running || res == nil {
+		return nil
+	}
+	h.running = true
+	defer func() { h.running = false }()
+
+	v := rt.ToValue(res)
+	for _, hook := range h.afterResponse {
+		if _, err := hook(goja.Undefined(), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks(t *testing.T) {
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+
+	tb.Mux.HandleFunc("/signed", func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, r.ContentLength)
+		_, _ = r.Body.Read(body)
+		if r.Header.Get("X-Signature") != r.Method+":"+string(body) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-Request-Id", "42")
+		_, _ = w.Write(body)
+	})
+
+	_, err := common.RunString(rt, tb.Replacer.Replace(`
+	let seen = [];
+	http.beforeRequest(function(req) {
+		req.url = req.url.replace("/unsigned", "/signed");
+		req.headers["X-Signature"] = req.method + ":" + (req.body || "");
+		req.tags.hooked = "yes";
+	});
+	http.beforeRequest(function(req) {
+		if (req.body === "rewrite me") { req.body = "rewritten"; req.headers["X-Signature"] = "POST:rewritten"; }
+	});
+	http.afterResponse(function(res) {
+		// Requests in hooks don't run the hooks again
+		if (res.status === 200) { http.get("HTTPBIN_URL/unsigned"); }
+		seen.push(res.status + " " + res.headers["X-Request-Id"]);
+	});
+
+	let res = http.post("HTTPBIN_URL/unsigned", "data");
+	if (res.status !== 200 || res.body !== "data") { throw new Error("wrong response: " + res.status + " " + res.body); }
+	res = http.post("HTTPBIN_URL/signed", "rewrite me");
+	if (res.body !== "rewritten") { throw new Error("the body wasn't changed: " + res.body); }
+	http.batch([["GET", "HTTPBIN_URL/signed"], ["GET", "HTTPBIN_URL/signed"]]);
+	if (seen.join(",") !== "200 42,200 42,200 42,200 42") { throw new Error("wrong responses: " + seen.join(",")); }
+
+	http.beforeRequest(null);
+	http.afterResponse(null);
+	res = http.get("HTTPBIN_URL/signed");
+	if (res.status !== 403 || seen.length !== 4) { throw new Error("the hooks weren't removed"); }
+	`))
+	require.NoError(t, err)
+
+	hooked, requests := 0, 0
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.HTTPReqs {
+				continue
+			}
+			requests++
+			tags := s.Tags.CloneTags()
+			if tags["hooked"] == "yes" {
+				hooked++
+				assert.Equal(t, tb.Replacer.Replace("HTTPBIN_URL/signed"), tags["url"])
+			}
+		}
+	}
+	assert.Equal(t, 9, requests) // Including the unsigned ones of the afterResponse hook
+	assert.Equal(t, 4, hooked)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := common.RunString(rt, `http.beforeRequest("nope");`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "beforeRequest() needs a function")
+		}
+		_, err = common.RunString(rt, tb.Replacer.Replace(`
+		http.afterResponse(function(res) { throw new Error("rejected " + res.status); });
+		http.get("HTTPBIN_URL/signed");
+		`))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "rejected 403")
+		}
+	})
+}
@@ -21,14 +21,18 @@
 package http
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/cookiejar"
+	"strings"
 
 	"fmt"
 	"net/http/httputil"
 
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -58,6 +62,7 @@ const (
 	TLS_1_0                            = "tls1.0"
 	TLS_1_1                            = "tls1.1"
 	TLS_1_2                            = "tls1.2"
+	TLS_1_3                            = "tls1.3"
 )
 
 type HTTPCookie struct {
@@ -77,6 +82,7 @@ type HTTP struct {
 	TLS_1_0                            string `js:"TLS_1_0"`
 	TLS_1_1                            string `js:"TLS_1_1"`
 	TLS_1_2                            string `js:"TLS_1_2"`
+	TLS_1_3                            string `js:"TLS_1_3"`
 	OCSP_STATUS_GOOD                   string `js:"OCSP_STATUS_GOOD"`
 	OCSP_STATUS_REVOKED                string `js:"OCSP_STATUS_REVOKED"`
 	OCSP_STATUS_SERVER_FAILED          string `js:"OCSP_STATUS_SERVER_FAILED"`
@@ -99,6 +105,7 @@ func New() *HTTP {
 		TLS_1_0:                            TLS_1_0,
 		TLS_1_1:                            TLS_1_1,
 		TLS_1_2:                            TLS_1_2,
+		TLS_1_3:                            TLS_1_3,
 		OCSP_STATUS_GOOD:                   OCSP_STATUS_GOOD,
 		OCSP_STATUS_REVOKED:                OCSP_STATUS_REVOKED,
 		OCSP_STATUS_SERVER_FAILED:          OCSP_STATUS_SERVER_FAILED,
@@ -148,26 +155,125 @@ func (*HTTP) setRequestCookies(req *http.Request, reqCookies map[string][]*HTTPR
 	}
 }
 
+// With --http-debug=full, bodies are cut to this many bytes, unless the limit is changed.
+const defaultHTTPDebugBodyLimit = 1024
+
+// The headers whose values are replaced in the dumps, so that credentials don't end up in logs.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization"}
+
 func (*HTTP) debugRequest(state *common.State, req *http.Request, description string) {
-	if state.Options.HttpDebug.String != "" {
-		dump, err := httputil.DumpRequestOut(req, state.Options.HttpDebug.String == "full")
+	if state.Options.HttpDebug.String != "" && matchesHTTPDebugURLs(state.Options, req.URL.String()) {
+		dump, err := httputil.DumpRequestOut(req, httpDebugBodies(state.Options))
 		if err != nil {
 			log.Fatal(err)
 		}
-		logDump(description, dump)
+		logDump(state.Options, description, dump)
 	}
 }
 
 func (*HTTP) debugResponse(state *common.State, res *http.Response, description string) {
-	if state.Options.HttpDebug.String != "" && res != nil {
-		dump, err := httputil.DumpResponse(res, state.Options.HttpDebug.String == "full")
+	if state.Options.HttpDebug.String != "" && res != nil &&
+		(res.Request == nil || matchesHTTPDebugURLs(state.Options, res.Request.URL.String())) {
+		dump, err := httputil.DumpResponse(res, httpDebugBodies(state.Options))
 		if err != nil {
 			log.Fatal(err)
 		}
-		logDump(description, dump)
+		logDump(state.Options, description, dump)
+	}
+}
+
+func logDump(opts lib.Options, description string, dump []byte) {
+	if opts.HttpDebug.String == "pretty" {
+		dump = prettyJSONBody(dump)
+	}
+	fmt.Printf("%s:\n%s\n", description, formatDump(dump, httpDebugBodyLimit(opts)))
+}
+
+// httpDebugBodies returns whether the bodies are dumped too: with "full", and with "pretty", which
+// also indents JSON bodies.
+func httpDebugBodies(opts lib.Options) bool {
+	return opts.HttpDebug.String == "full" || opts.HttpDebug.String == "pretty"
+}
+
+func httpDebugBodyLimit(opts lib.Options) int64 {
+	if opts.HttpDebugBodyLimit.Valid {
+		return opts.HttpDebugBodyLimit.Int64
+	}
+	return defaultHTTPDebugBodyLimit
+}
+
+// matchesHTTPDebugURLs returns whether a request's URL matches one of the --http-debug-urls, if
+// there are any.
+func matchesHTTPDebugURLs(opts lib.Options, url string) bool {
+	if len(opts.HttpDebugURLs) == 0 {
+		return true
+	}
+	for _, pattern := range opts.HttpDebugURLs {
+		if matchGlob(pattern, url) {
+			return true
+		}
 	}
+	return false
 }
 
-func logDump(description string, dump []byte) {
-	fmt.Printf("%s:\n%s\n", description, dump)
+// matchGlob returns whether s matches a pattern in which * matches any characters, / included.
+func matchGlob(pattern, s string) bool {
+	// Backtracks to the last *, which absorbs one more character of s every time.
+	p, i, star, starI := 0, 0, -1, 0
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, starI = p, i
+			p++
+		case p < len(pattern) && pattern[p] == s[i]:
+			p++
+			i++
+		case star >= 0:
+			starI++
+			p, i = star+1, starI
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// prettyJSONBody indents the body of a dump, if it's JSON.
+func prettyJSONBody(dump []byte) []byte {
+	i := bytes.Index(dump, []byte("\r\n\r\n"))
+	if i < 0 {
+		return dump
+	}
+	var body bytes.Buffer
+	if err := json.Indent(&body, bytes.TrimSpace(dump[i+4:]), "", "  "); err != nil {
+		return dump
+	}
+	return append(append([]byte{}, dump[:i+4]...), body.Bytes()...)
+}
+
+// formatDump redacts the credentials in the headers of a dump, and cuts its body to the limit.
+func formatDump(dump []byte, bodyLimit int64) []byte {
+	header, body := dump, []byte(nil)
+	if i := bytes.Index(dump, []byte("\r\n\r\n")); i >= 0 {
+		header, body = dump[:i+4], dump[i+4:]
+	}
+
+	lines := bytes.Split(header, []byte("\r\n"))
+	for i, line := range lines {
+		for _, name := range redactedHeaders {
+			if len(line) > len(name) && line[len(name)] == ':' && strings.EqualFold(string(line[:len(name)]), name) {
+				lines[i] = []byte(name + ": [REDACTED]")
+			}
+		}
+	}
+	result := bytes.Join(lines, []byte("\r\n"))
+
+	if bodyLimit > 0 && int64(len(body)) > bodyLimit {
+		result = append(result, body[:bodyLimit]...)
+		return append(result, fmt.Sprintf("... (%d more bytes)", int64(len(body))-bodyLimit)...)
+	}
+	return append(result, body...)
 }
@@ -22,8 +22,6 @@ package http
 
 import (
 	"bytes"
-	"compress/gzip"
-	"compress/zlib"
 	"context"
 	"fmt"
 	"io"
@@ -43,12 +41,18 @@ import (
 	digest "github.com/Soontao/goHttpDigestClient"
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/tracing"
 	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	null "gopkg.in/guregu/null.v3"
 )
 
+// The timeout of requests without a timeout param, if the httpTimeout option isn't set.
+const defaultTimeout = 60 * time.Second
+
 type HTTPRequest struct {
 	Method  string
 	URL     string
@@ -110,7 +114,63 @@ func (h *HTTP) Request(ctx context.Context, method string, url goja.Value, args
 		return nil, err
 	}
 
-	return h.request(ctx, req)
+	res, err := h.request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return res, runAfterResponse(common.GetRuntime(ctx), res)
+}
+
+// AsyncRequest is like Request, but sends the request on another goroutine and returns a Promise
+// of its response, so that the script can do other things, like running timers or sending other
+// requests, in the meantime.
+func (h *HTTP) AsyncRequest(ctx context.Context, method string, url goja.Value, args ...goja.Value) (*goja.Object, error) {
+	rt := common.GetRuntime(ctx)
+	if common.GetState(ctx) == nil {
+		return nil, errors.New("asyncRequest() can't be used in the init context")
+	}
+	loop := common.GetEventLoop(ctx)
+	if loop == nil {
+		return nil, errors.New("asyncRequest() needs the event loop of a VU")
+	}
+
+	u, err := ToURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	var body interface{}
+	var params goja.Value
+
+	if len(args) > 0 {
+		body = args[0].Export()
+	}
+	if len(args) > 1 {
+		params = args[1]
+	}
+
+	req, err := h.parseRequest(ctx, method, u, body, params)
+	if err != nil {
+		return nil, err
+	}
+
+	promise, resolve, reject := common.NewPromise(rt)
+	callback := loop.RegisterCallback()
+	go func() {
+		res, err := h.request(ctx, req)
+		callback(func() error {
+			if err == nil {
+				err = runAfterResponse(rt, res)
+			}
+			if err != nil {
+				reject(err)
+			} else {
+				resolve(res)
+			}
+			return nil
+		})
+	}()
+	return promise, nil
 }
 
 type parsedHTTPRequest struct {
@@ -118,6 +178,7 @@ type parsedHTTPRequest struct {
 	body          *bytes.Buffer
 	req           *http.Request
 	timeout       time.Duration
+	unixSocket    string
 	auth          string
 	throw         bool
 	redirects     null.Int
@@ -125,6 +186,11 @@ type parsedHTTPRequest struct {
 	cookies       map[string]*HTTPRequestCookie
 	mergedCookies map[string][]*HTTPRequestCookie
 	tags          map[string]string
+
+	responseCallback *ExpectedStatuses
+	retry            *retryPolicy
+	digestUser       *url.Userinfo
+	graphQL          bool // Whether the response is checked for GraphQL errors
 }
 
 func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body interface{}, params goja.Value) (*parsedHTTPRequest, error) {
@@ -138,11 +204,17 @@ func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body
 			URL:    reqURL.URL,
 			Header: make(http.Header),
 		},
-		timeout:   60 * time.Second,
+		timeout:   defaultTimeout,
 		throw:     state.Options.Throw.Bool,
 		redirects: state.Options.MaxRedirects,
 		cookies:   make(map[string]*HTTPRequestCookie),
 		tags:      make(map[string]string),
+
+		responseCallback: getResponseCallback(rt),
+	}
+
+	if state.Options.HTTPTimeout.Valid {
+		result.timeout = time.Duration(state.Options.HTTPTimeout.Duration)
 	}
 
 	formatFormVal := func(v interface{}) string {
@@ -234,11 +306,6 @@ func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body
 		}
 	}
 
-	if result.body != nil {
-		result.req.Body = ioutil.NopCloser(result.body)
-		result.req.ContentLength = int64(result.body.Len())
-	}
-
 	if userAgent := state.Options.UserAgent; userAgent.String != "" {
 		result.req.Header.Set("User-Agent", userAgent.String)
 	}
@@ -247,6 +314,7 @@ func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body
 		result.activeJar = state.CookieJar
 	}
 
+	var compression string
 	// TODO: ditch goja.Value, reflections and Object and use a simple go map and type assertions?
 	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
 		params := params.ToObject(rt)
@@ -323,16 +391,60 @@ func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body
 				for _, key := range tagObj.Keys() {
 					result.tags[key] = tagObj.Get(key).String()
 				}
+			case "compression":
+				compression = params.Get(k).String()
+			case "unixSocket":
+				result.unixSocket = params.Get(k).String()
 			case "auth":
 				result.auth = params.Get(k).String()
 			case "timeout":
-				result.timeout = time.Duration(params.Get(k).ToFloat() * float64(time.Millisecond))
+				timeout, err := parseTimeout(params.Get(k))
+				if err != nil {
+					return nil, err
+				}
+				result.timeout = timeout
 			case "throw":
 				result.throw = params.Get(k).ToBoolean()
+			case "retry":
+				retry, err := parseRetryPolicy(rt, params.Get(k))
+				if err != nil {
+					return nil, err
+				}
+				result.retry = retry
+			case "responseCallback":
+				callback, err := toResponseCallback(params.Get(k))
+				if err != nil {
+					return nil, err
+				}
+				result.responseCallback = callback
 			}
 		}
 	}
 
+	if err := runBeforeRequest(rt, result); err != nil {
+		return nil, err
+	}
+
+	if result.body != nil {
+		if compression != "" {
+			var contentEncoding string
+			var err error
+			if result.body, contentEncoding, err = compressBody(compression, result.body); err != nil {
+				return nil, err
+			}
+			if contentEncoding != "" {
+				result.req.Header.Set("Content-Encoding", contentEncoding)
+			}
+		}
+		// GetBody lets the body be sent again, for digest auth and 307/308 redirects
+		bodyBytes := result.body.Bytes()
+		result.req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(bodyBytes)), nil
+		}
+		result.req.Body, _ = result.req.GetBody()
+		result.req.ContentLength = int64(len(bodyBytes))
+	}
+
 	if result.activeJar != nil {
 		result.mergedCookies = h.mergeCookies(result.req, result.activeJar, result.cookies)
 		h.setRequestCookies(result.req, result.mergedCookies)
@@ -341,9 +453,52 @@ func (h *HTTP) parseRequest(ctx context.Context, method string, reqURL URL, body
 	return result, nil
 }
 
+// parseTimeout parses the timeout request param, which is either a number of milliseconds or a
+// duration string like "30s".
+func parseTimeout(v goja.Value) (time.Duration, error) {
+	var timeout time.Duration
+	switch v.ExportType() {
+	case reflect.TypeOf(""):
+		var err error
+		if timeout, err = time.ParseDuration(v.String()); err != nil {
+			return 0, errors.Errorf("invalid timeout '%s', it has to be a number of milliseconds or a duration like '30s'", v)
+		}
+	default:
+		timeout = time.Duration(v.ToFloat() * float64(time.Millisecond))
+	}
+	if timeout <= 0 {
+		return 0, errors.Errorf("invalid timeout '%s', it has to be positive", v)
+	}
+	return timeout, nil
+}
+
 // request() shouldn't mess with the goja runtime or other thread-unsafe
 // things because it's called concurrently by Batch()
 func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPResponse, error) {
+	if preq.retry == nil {
+		return h.send(ctx, preq, 0)
+	}
+
+	for retry := 0; ; retry++ {
+		resp, err := h.send(ctx, preq, retry)
+		if retry+1 >= preq.retry.attempts || !preq.retry.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, err
+		case <-time.After(preq.retry.delay(retry + 1)):
+		}
+		// The body was used up by the previous attempt
+		if preq.req.GetBody != nil {
+			preq.req.Body, _ = preq.req.GetBody()
+		}
+	}
+}
+
+// send sends the request once; retry is the number of times it was sent before.
+func (h *HTTP) send(ctx context.Context, preq *parsedHTTPRequest, retry int) (*HTTPResponse, error) {
 	state := common.GetState(ctx)
 
 	respReq := &HTTPRequest{
@@ -364,11 +519,13 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 	if state.Options.SystemTags["method"] {
 		tags["method"] = preq.req.Method
 	}
-	if state.Options.SystemTags["url"] {
+
+	// Only set the url and name system tags if the user didn't explicitly set them beforehand, so
+	// that URLs with IDs in them can be tagged with something that doesn't have a value per ID
+	_, hasURLTag := tags["url"]
+	if !hasURLTag && state.Options.SystemTags["url"] {
 		tags["url"] = preq.url.URLString
 	}
-
-	// Only set the name system tag if the user didn't explicitly set it beforehand
 	if _, ok := tags["name"]; !ok && state.Options.SystemTags["name"] {
 		tags["name"] = preq.url.Name
 	}
@@ -381,6 +538,12 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 	if state.Options.SystemTags["iter"] {
 		tags["iter"] = strconv.FormatInt(state.Iteration, 10)
 	}
+	if retry > 0 {
+		tags["retry"] = strconv.Itoa(retry)
+		state.Samples <- stats.Sample{
+			Metric: metrics.HTTPReqRetries, Time: time.Now(), Tags: stats.NewSampleTags(tags), Value: 1,
+		}
+	}
 
 	// Check rate limit *after* we've prepared a request; no need to wait with that part.
 	if rpsLimit := state.RPSLimit; rpsLimit != nil {
@@ -422,10 +585,23 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 		},
 	}
 
+	if preq.unixSocket != "" {
+		ctx = netext.WithUnixSocket(ctx, preq.unixSocket)
+	}
+
+	var span *tracing.Span
+	if state.Tracing != nil {
+		span = state.Tracing.StartSpan(preq.req)
+	}
+
 	// if digest authentication option is passed, make an initial request to get the authentication params to compute the authorization header
 	if preq.auth == "digest" {
-		username := preq.url.URL.User.Username()
-		password, _ := preq.url.URL.User.Password()
+		// The credentials are removed from the URL below, so they're kept for retries
+		if preq.digestUser == nil {
+			preq.digestUser = preq.url.URL.User
+		}
+		username := preq.digestUser.Username()
+		password, _ := preq.digestUser.Password()
 
 		// removing user from URL to avoid sending the authorization header fo basic auth
 		preq.req.URL.User = nil
@@ -433,13 +609,13 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 		tracer := netext.Tracer{}
 		h.debugRequest(state, preq.req, "DigestRequest")
 		res, err := client.Do(preq.req.WithContext(netext.WithTracer(ctx, &tracer)))
-		h.debugRequest(state, preq.req, "DigestResponse")
+		h.debugResponse(state, res, "DigestResponse")
 		if err != nil {
 			// Do *not* log errors about the contex being cancelled.
 			select {
 			case <-ctx.Done():
 			default:
-				state.Logger.WithField("error", res).Warn("Digest request failed")
+				state.Logger.WithField("error", err).Warn("Digest request failed")
 			}
 
 			if preq.throw {
@@ -460,16 +636,28 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 			challenge.ComputeResponse(preq.req.Method, preq.req.URL.RequestURI(), body, username, password)
 			authorization := challenge.ToAuthorizationStr()
 			preq.req.Header.Set(digest.KEY_AUTHORIZATION, authorization)
+		} else {
+			_, _ = io.Copy(ioutil.Discard, res.Body)
 		}
+		_ = res.Body.Close()
 		trail := tracer.Done()
 
-		if state.Options.SystemTags["ip"] && trail.ConnRemoteAddr != nil {
-			if ip, _, err := net.SplitHostPort(trail.ConnRemoteAddr.String()); err == nil {
-				tags["ip"] = ip
-			}
+		// The challenge request already consumed the body, so it has to be sent anew
+		if preq.req.GetBody != nil {
+			preq.req.Body, _ = preq.req.GetBody()
+		}
+
+		// The challenge's connection may not be the one of the request itself, so its tags are a copy
+		challengeTags := make(map[string]string, len(tags)+2)
+		for k, v := range tags {
+			challengeTags[k] = v
+		}
+		state.Options.SystemTags.SetRemoteAddrTags(challengeTags, trail.ConnRemoteAddr)
+		// The challenge is always expected to be unauthorized
+		if preq.responseCallback != nil {
+			trail.Failed = null.BoolFrom(res.StatusCode != http.StatusUnauthorized && !preq.responseCallback.Expected(res.StatusCode))
 		}
-		trail.SaveSamples(stats.NewSampleTags(tags))
-		delete(tags, "ip")
+		trail.SaveSamples(stats.IntoSampleTags(&challengeTags))
 		state.Samples <- trail
 	}
 
@@ -481,23 +669,29 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 	h.debugRequest(state, preq.req, "Request")
 	res, resErr := client.Do(preq.req.WithContext(netext.WithTracer(ctx, &tracer)))
 	h.debugResponse(state, res, "Response")
+	var encodingErr error
 	if resErr == nil && res != nil {
-		switch res.Header.Get("Content-Encoding") {
-		case "deflate":
-			res.Body, resErr = zlib.NewReader(res.Body)
-		case "gzip":
-			res.Body, resErr = gzip.NewReader(res.Body)
+		encoded := &countingReader{Reader: res.Body}
+		var body io.Reader = encoded
+		if contentEncoding := res.Header.Get("Content-Encoding"); contentEncoding != "" {
+			body, resErr = wrapDecompressionReader(encoded, contentEncoding)
+			if _, ok := resErr.(errUnsupportedEncoding); ok {
+				state.Logger.WithField("url", res.Request.URL.String()).Warn(resErr)
+				body, resErr, encodingErr = encoded, nil, resErr
+			}
+		}
+		if resErr == nil {
+			buf := state.BPool.Get()
+			buf.Reset()
+			defer state.BPool.Put(buf)
+			_, err := io.Copy(buf, body)
+			if err != nil && err != io.EOF {
+				resErr = err
+			}
+			resp.Body = buf.String()
+			resp.BodySize = buf.Len()
+			resp.EncodedBodySize = int(encoded.n)
 		}
-	}
-	if resErr == nil && res != nil {
-		buf := state.BPool.Get()
-		buf.Reset()
-		defer state.BPool.Put(buf)
-		_, err := io.Copy(buf, res.Body)
-		if err != nil && err != io.EOF {
-			resErr = err
-		}
-		resp.Body = buf.String()
 		_ = res.Body.Close()
 	}
 	trail := tracer.Done()
@@ -510,21 +704,23 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 	resp.Timings = HTTPResponseTimings{
 		Duration:       stats.D(trail.Duration),
 		Blocked:        stats.D(trail.Blocked),
+		Queued:         stats.D(trail.Queued),
+		LookingUp:      stats.D(trail.LookingUp),
 		Connecting:     stats.D(trail.Connecting),
 		TLSHandshaking: stats.D(trail.TLSHandshaking),
 		Sending:        stats.D(trail.Sending),
 		Waiting:        stats.D(trail.Waiting),
 		Receiving:      stats.D(trail.Receiving),
+		Continue:       stats.D(trail.Continue),
+		EarlyHints:     stats.D(trail.EarlyHints),
 	}
 
 	if resErr != nil {
 		resp.Error = resErr.Error()
+		resp.ErrorCode = int(netext.ClassifyError(resErr))
 		if state.Options.SystemTags["error"] {
 			tags["error"] = resp.Error
 		}
-
-		//TODO: expand/replace this so we can recognize the different non-HTTP
-		// errors, probably by using a type switch for resErr
 		if state.Options.SystemTags["status"] {
 			tags["status"] = "0"
 		}
@@ -537,9 +733,16 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 
 		resp.URL = res.Request.URL.String()
 		resp.Status = res.StatusCode
+		resp.ErrorCode = int(netext.StatusErrorCode(res.StatusCode))
+		if encodingErr != nil {
+			// The request didn't fail, but the script should be able to tell that the body is still encoded
+			resp.Error = encodingErr.Error()
+		}
 		resp.Proto = res.Proto
+		resp.HeaderSize = headerSize(res)
+		trail.HeaderSize, trail.BodySize = int64(resp.HeaderSize), int64(resp.EncodedBodySize)
 
-		if state.Options.SystemTags["url"] {
+		if !hasURLTag && state.Options.SystemTags["url"] {
 			tags["url"] = resp.URL
 		}
 		if state.Options.SystemTags["status"] {
@@ -580,6 +783,21 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 		}
 	}
 
+	if span != nil {
+		attributes := map[string]interface{}{
+			"http.method": preq.req.Method,
+			"http.url":    resp.URL,
+			"k6.name":     preq.url.Name,
+		}
+		if resp.Status != 0 {
+			attributes["http.status_code"] = resp.Status
+		}
+		if resp.RemoteIP != "" {
+			attributes["net.peer.ip"] = resp.RemoteIP
+		}
+		state.Tracing.EndSpan(span, time.Now(), attributes, resErr != nil || resp.Status >= 400)
+	}
+
 	if resErr != nil {
 		// Do *not* log errors about the contex being cancelled.
 		select {
@@ -593,12 +811,21 @@ func (h *HTTP) request(ctx context.Context, preq *parsedHTTPRequest) (*HTTPRespo
 		}
 	}
 
-	if state.Options.SystemTags["ip"] && trail.ConnRemoteAddr != nil {
-		if ip, _, err := net.SplitHostPort(trail.ConnRemoteAddr.String()); err == nil {
-			tags["ip"] = ip
-		}
+	if resp.ErrorCode != 0 && state.Options.SystemTags["error_code"] {
+		tags["error_code"] = strconv.Itoa(resp.ErrorCode)
+	}
+	state.Options.SystemTags.SetRemoteAddrTags(tags, trail.ConnRemoteAddr)
+	if preq.responseCallback != nil {
+		trail.Failed = null.BoolFrom(!preq.responseCallback.Expected(resp.Status))
 	}
 	trail.SaveSamples(stats.IntoSampleTags(&tags))
+	if preq.graphQL {
+		failed := 0.0
+		if resErr != nil || graphQLFailed(resp.Body) {
+			failed = 1
+		}
+		trail.Samples = append(trail.Samples, stats.Sample{Metric: metrics.GraphQLReqFailed, Time: trail.EndTime, Tags: trail.Tags, Value: failed})
+	}
 	state.Samples <- trail
 	return resp, nil
 }
@@ -721,7 +948,19 @@ func (h *HTTP) Batch(ctx context.Context, reqsV goja.Value) (goja.Value, error)
 			err = e
 		}
 	}
-	return retval, err
+	if err != nil {
+		return retval, err
+	}
+
+	// The hooks can only run once all of the requests are done, since they need the runtime
+	for _, key := range keys {
+		if v := retval.Get(key); v != nil {
+			if err := runAfterResponse(rt, v.Export().(*HTTPResponse)); err != nil {
+				return retval, err
+			}
+		}
+	}
+	return retval, nil
 }
 
 func requestContainsFile(data map[string]interface{}) bool {
@@ -22,11 +22,14 @@ package http
 
 import (
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
 )
 
 func TestTagURL(t *testing.T) {
@@ -53,3 +56,55 @@ func TestTagURL(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchGlob(t *testing.T) {
+	testdata := map[string]bool{
+		"https://api.example.com/*|https://api.example.com/v2/users":   true,
+		"https://api.example.com/*|https://www.example.com/v2/users":   false,
+		"*/login|https://example.com/login":                            true,
+		"*/login|https://example.com/login?next=/":                     false,
+		"*/login*|https://example.com/login?next=/":                    true,
+		"https://*.example.com/*/items|https://a.example.com/v1/items": true,
+		"https://*.example.com/*/items|https://a.example.com/v1/item":  false,
+		"*|anything":    true,
+		"exact|exact":   true,
+		"exact|exactly": false,
+	}
+	for data, matches := range testdata {
+		parts := strings.SplitN(data, "|", 2)
+		assert.Equal(t, matches, matchGlob(parts[0], parts[1]), data)
+	}
+}
+
+func TestFormatDump(t *testing.T) {
+	dump := []byte("POST /login HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer secret\r\n" +
+		"proxy-authorization: Basic c2VjcmV0\r\nX-Authorization-Hint: public\r\n\r\n0123456789")
+
+	assert.Equal(t, "POST /login HTTP/1.1\r\nHost: example.com\r\nAuthorization: [REDACTED]\r\n"+
+		"Proxy-Authorization: [REDACTED]\r\nX-Authorization-Hint: public\r\n\r\n0123... (6 more bytes)",
+		string(formatDump(dump, 4)))
+	assert.True(t, strings.HasSuffix(string(formatDump(dump, 0)), "\r\n\r\n0123456789"))
+	assert.True(t, strings.HasSuffix(string(formatDump(dump, 10)), "\r\n\r\n0123456789"))
+
+	headers := []byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n")
+	assert.Equal(t, string(headers), string(formatDump(headers, 4)))
+}
+
+func TestPrettyJSONBody(t *testing.T) {
+	dump := []byte("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\"id\":1,\"tags\":[\"a\"]}")
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n\r\n{\n  \"id\": 1,\n  \"tags\": [\n    \"a\"\n  ]\n}",
+		string(prettyJSONBody(dump)))
+
+	notJSON := []byte("HTTP/1.1 200 OK\r\n\r\n<html></html>")
+	assert.Equal(t, string(notJSON), string(prettyJSONBody(notJSON)))
+}
+
+func TestMatchesHTTPDebugURLs(t *testing.T) {
+	assert.True(t, matchesHTTPDebugURLs(lib.Options{}, "https://example.com/"))
+	opts := lib.Options{HttpDebugURLs: []string{"*/api/*", "https://auth.example.com/*"}}
+	assert.True(t, matchesHTTPDebugURLs(opts, "https://example.com/api/users"))
+	assert.True(t, matchesHTTPDebugURLs(opts, "https://auth.example.com/token"))
+	assert.False(t, matchesHTTPDebugURLs(opts, "https://example.com/static/app.js"))
+	assert.Equal(t, int64(defaultHTTPDebugBodyLimit), httpDebugBodyLimit(opts))
+	assert.Equal(t, int64(0), httpDebugBodyLimit(lib.Options{HttpDebugBodyLimit: null.IntFrom(0)}))
+}
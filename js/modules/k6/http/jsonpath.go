@@ -0,0 +1,91 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseJSONSelector splits a selector like `$.items[0].name`, `items.0.name` or `data["a.b"]`
+// into its keys. The `$` root and the leading dot are optional, and `#` gives an array's length.
+func parseJSONSelector(selector string) ([]string, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(selector), "$")
+	var keys []string
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid JSON selector '%s': missing ']'", selector)
+			}
+			key := strings.TrimSpace(s[1:end])
+			if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+				key = key[1 : len(key)-1]
+			} else if _, err := strconv.Atoi(key); err != nil {
+				return nil, errors.Errorf("invalid JSON selector '%s': '%s' isn't an index or a quoted key", selector, key)
+			}
+			keys = append(keys, key)
+			s = s[end+1:]
+			continue
+		}
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			continue
+		}
+		keys = append(keys, s[:end])
+		s = s[end:]
+	}
+	return keys, nil
+}
+
+// selectJSON returns the value at the selector's keys, and false if there's nothing there.
+func selectJSON(v interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		switch data := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = data[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			if key == "#" {
+				v = len(data)
+				continue
+			}
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(data) {
+				return nil, false
+			}
+			v = data[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
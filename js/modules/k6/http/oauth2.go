@@ -0,0 +1,270 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	OAUTH2_GRANT_CLIENT_CREDENTIALS = "client_credentials"
+	OAUTH2_GRANT_PASSWORD           = "password"
+	oauth2GrantRefreshToken         = "refresh_token"
+
+	// Tokens are refreshed this long before they expire by default, so that they don't expire in flight.
+	oauth2DefaultRefreshBefore = 30 * time.Second
+)
+
+// OAuth2Config is what an OAuth2Client is created with.
+type OAuth2Config struct {
+	TokenURL      string
+	GrantType     string
+	ClientID      string
+	ClientSecret  string
+	ClientAuth    string // "body" (the default) or "basic"
+	Username      string
+	Password      string
+	Scope         string
+	RefreshBefore time.Duration
+	Tags          map[string]string
+}
+
+// OAuth2Client acquires access tokens with the client credentials or password grants and caches
+// them until they're about to expire. They're then refreshed with the refresh token, if the
+// server gave one, or acquired anew.
+type OAuth2Client struct {
+	h      *HTTP
+	ctx    *context.Context
+	config OAuth2Config
+
+	accessToken  string
+	tokenType    string
+	refreshToken string
+	expires      time.Time
+}
+
+type oauth2TokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	RefreshToken     string      `json:"refresh_token"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+func (h *HTTP) XOAuth2Client(ctxPtr *context.Context, configV goja.Value) (*OAuth2Client, error) {
+	rt := common.GetRuntime(*ctxPtr)
+
+	config := OAuth2Config{
+		GrantType:     OAUTH2_GRANT_CLIENT_CREDENTIALS,
+		ClientAuth:    "body",
+		RefreshBefore: oauth2DefaultRefreshBefore,
+		Tags:          map[string]string{"oauth2": "token"},
+	}
+	if configV == nil || goja.IsUndefined(configV) || goja.IsNull(configV) {
+		return nil, errors.New("an OAuth2 client needs a config")
+	}
+	configObj := configV.ToObject(rt)
+	for _, k := range configObj.Keys() {
+		v := configObj.Get(k)
+		switch k {
+		case "tokenUrl":
+			config.TokenURL = v.String()
+		case "grantType":
+			config.GrantType = v.String()
+		case "clientId":
+			config.ClientID = v.String()
+		case "clientSecret":
+			config.ClientSecret = v.String()
+		case "clientAuth":
+			config.ClientAuth = v.String()
+		case "username":
+			config.Username = v.String()
+		case "password":
+			config.Password = v.String()
+		case "scope":
+			config.Scope = v.String()
+		case "refreshBefore":
+			config.RefreshBefore = time.Duration(v.ToFloat() * float64(time.Millisecond))
+		case "tags":
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			tagObj := v.ToObject(rt)
+			for _, key := range tagObj.Keys() {
+				config.Tags[key] = tagObj.Get(key).String()
+			}
+		}
+	}
+
+	switch {
+	case config.TokenURL == "":
+		return nil, errors.New("tokenUrl is required")
+	case config.GrantType != OAUTH2_GRANT_CLIENT_CREDENTIALS && config.GrantType != OAUTH2_GRANT_PASSWORD:
+		return nil, errors.Errorf("unsupported grantType '%s', use client_credentials or password", config.GrantType)
+	case config.GrantType == OAUTH2_GRANT_PASSWORD && config.Username == "":
+		return nil, errors.New("the password grant needs a username")
+	case config.ClientAuth != "body" && config.ClientAuth != "basic":
+		return nil, errors.Errorf("unsupported clientAuth '%s', use body or basic", config.ClientAuth)
+	}
+
+	return &OAuth2Client{h: h, ctx: ctxPtr, config: config}, nil
+}
+
+// Token returns a valid access token, acquiring or refreshing it first if needed.
+func (c *OAuth2Client) Token() (string, error) {
+	if c.accessToken != "" && (c.expires.IsZero() || time.Now().Add(c.config.RefreshBefore).Before(c.expires)) {
+		return c.accessToken, nil
+	}
+
+	if c.refreshToken != "" {
+		err := c.fetch(url.Values{"grant_type": {oauth2GrantRefreshToken}, "refresh_token": {c.refreshToken}})
+		if err == nil {
+			return c.accessToken, nil
+		}
+		// The refresh token may have expired or been revoked, so a new grant is worth a try.
+		common.GetState(*c.ctx).Logger.WithError(err).Debug("OAuth2 token refresh failed")
+		c.refreshToken = ""
+	}
+
+	form := url.Values{"grant_type": {c.config.GrantType}}
+	if c.config.GrantType == OAUTH2_GRANT_PASSWORD {
+		form.Set("username", c.config.Username)
+		form.Set("password", c.config.Password)
+	}
+	if c.config.Scope != "" {
+		form.Set("scope", c.config.Scope)
+	}
+	if err := c.fetch(form); err != nil {
+		return "", err
+	}
+	return c.accessToken, nil
+}
+
+// Headers returns the Authorization header for a valid access token, to be used in request params.
+func (c *OAuth2Client) Headers() (map[string]string, error) {
+	token, err := c.Token()
+	if err != nil {
+		return nil, err
+	}
+	tokenType := c.tokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return map[string]string{"Authorization": tokenType + " " + token}, nil
+}
+
+// Invalidate drops the cached tokens, so that the next Token() call acquires new ones.
+func (c *OAuth2Client) Invalidate() {
+	c.accessToken, c.tokenType, c.refreshToken = "", "", ""
+	c.expires = time.Time{}
+}
+
+func (c *OAuth2Client) fetch(form url.Values) error {
+	ctx := *c.ctx
+	state := common.GetState(ctx)
+	if state == nil {
+		return errors.New("OAuth2 tokens can't be acquired in the init context")
+	}
+	rt := common.GetRuntime(ctx)
+
+	headers := map[string]interface{}{"Accept": "application/json"}
+	switch c.config.ClientAuth {
+	case "basic":
+		credentials := url.QueryEscape(c.config.ClientID) + ":" + url.QueryEscape(c.config.ClientSecret)
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	default:
+		form.Set("client_id", c.config.ClientID)
+		if c.config.ClientSecret != "" {
+			form.Set("client_secret", c.config.ClientSecret)
+		}
+	}
+	body := make(map[string]interface{}, len(form))
+	for k := range form {
+		body[k] = form.Get(k)
+	}
+
+	tags := make(map[string]interface{}, len(c.config.Tags))
+	for k, v := range c.config.Tags {
+		tags[k] = v
+	}
+	params := rt.ToValue(map[string]interface{}{"headers": headers, "tags": tags, "throw": true})
+
+	u, err := ToURL(c.config.TokenURL)
+	if err != nil {
+		return err
+	}
+	preq, err := c.h.parseRequest(ctx, HTTP_METHOD_POST, u, body, params)
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	res, err := c.h.request(ctx, preq)
+	if err != nil {
+		return err
+	}
+	if err := runAfterResponse(rt, res); err != nil {
+		return err
+	}
+
+	var token oauth2TokenResponse
+	if err := json.Unmarshal([]byte(res.Body), &token); err != nil && res.Status < 300 {
+		return errors.Wrap(err, "couldn't parse the OAuth2 token response")
+	}
+	if res.Status >= 300 || token.Error != "" {
+		msg := token.Error
+		if token.ErrorDescription != "" {
+			msg += ": " + token.ErrorDescription
+		}
+		if msg == "" {
+			msg = res.Body
+		}
+		return errors.Errorf("OAuth2 token request failed with status %d: %s", res.Status, msg)
+	}
+	if token.AccessToken == "" {
+		return errors.New("the OAuth2 token response has no access_token")
+	}
+
+	c.accessToken = token.AccessToken
+	c.tokenType = token.TokenType
+	if token.RefreshToken != "" {
+		c.refreshToken = token.RefreshToken
+	}
+	c.expires = time.Time{}
+	if token.ExpiresIn != "" {
+		expiresIn, err := strconv.ParseFloat(string(token.ExpiresIn), 64)
+		if err != nil {
+			return errors.Wrap(err, "invalid expires_in in the OAuth2 token response")
+		}
+		c.expires = started.Add(time.Duration(expiresIn * float64(time.Second)))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2Client(t *testing.T) {
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+
+	var grants, refreshes int64
+	tb.Mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if clientID != "k6" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = fmt.Fprint(w, `{"error": "invalid_client", "error_description": "bad credentials"}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.PostForm.Get("grant_type") {
+		case "client_credentials":
+			n := atomic.AddInt64(&grants, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token": "cc%d", "token_type": "bearer", "expires_in": 3600}`, n)
+		case "password":
+			if r.PostForm.Get("username") != "bob" || r.PostForm.Get("password") != "pass" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprint(w, `{"error": "invalid_grant"}`)
+				return
+			}
+			n := atomic.AddInt64(&grants, 1)
+			// Already expired, as far as the client is concerned
+			_, _ = fmt.Fprintf(w, `{"access_token": "pw%d", "expires_in": 10, "refresh_token": "refresh"}`, n)
+		case "refresh_token":
+			n := atomic.AddInt64(&refreshes, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token": "refreshed%d", "expires_in": 10}`, n)
+		}
+	})
+
+	t.Run("client credentials", func(t *testing.T) {
+		_, err := common.RunString(rt, tb.Replacer.Replace(`
+		let client = new http.OAuth2Client({ tokenUrl: "HTTPBIN_URL/oauth2/token", clientId: "k6", clientSecret: "secret" });
+		let token = client.token();
+		if (token !== "cc1") { throw new Error("wrong token: " + token); }
+		if (client.token() !== "cc1") { throw new Error("the token wasn't cached"); }
+		if (client.headers().Authorization !== "Bearer cc1") { throw new Error("wrong headers: " + JSON.stringify(client.headers())); }
+		client.invalidate();
+		if (client.token() !== "cc2") { throw new Error("the token wasn't acquired anew"); }
+		`))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&grants))
+
+		for _, container := range stats.GetBufferedSamples(samples) {
+			for _, sample := range container.GetSamples() {
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, "token", tags["oauth2"])
+				assert.Equal(t, "POST", tags["method"])
+			}
+		}
+	})
+
+	t.Run("basic client auth and tags", func(t *testing.T) {
+		atomic.StoreInt64(&grants, 0)
+		_, err := common.RunString(rt, tb.Replacer.Replace(`
+		let client = new http.OAuth2Client({
+			tokenUrl: "HTTPBIN_URL/oauth2/token", clientId: "k6", clientSecret: "secret",
+			clientAuth: "basic", tags: { oauth2: "login", name: "token" },
+		});
+		if (client.token() !== "cc1") { throw new Error("wrong token"); }
+		`))
+		assert.NoError(t, err)
+
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.NotEmpty(t, bufSamples)
+		tags := bufSamples[0].GetSamples()[0].Tags.CloneTags()
+		assert.Equal(t, "login", tags["oauth2"])
+		assert.Equal(t, "token", tags["name"])
+	})
+
+	t.Run("password and refresh", func(t *testing.T) {
+		atomic.StoreInt64(&grants, 0)
+		_, err := common.RunString(rt, tb.Replacer.Replace(`
+		let client = new http.OAuth2Client({
+			tokenUrl: "HTTPBIN_URL/oauth2/token", clientId: "k6", clientSecret: "secret",
+			grantType: "password", username: "bob", password: "pass", refreshBefore: 30000,
+		});
+		if (client.token() !== "pw1") { throw new Error("wrong token"); }
+		let refreshed = client.token();
+		if (refreshed !== "refreshed1") { throw new Error("wrong refreshed token: " + refreshed); }
+		`))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), atomic.LoadInt64(&grants))
+		assert.Equal(t, int64(1), atomic.LoadInt64(&refreshes))
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`new http.OAuth2Client()`:   "an OAuth2 client needs a config",
+			`new http.OAuth2Client({})`: "tokenUrl is required",
+			`new http.OAuth2Client({ tokenUrl: "HTTPBIN_URL", grantType: "magic" })`:                                         "unsupported grantType 'magic'",
+			`new http.OAuth2Client({ tokenUrl: "HTTPBIN_URL", grantType: "password" })`:                                      "the password grant needs a username",
+			`new http.OAuth2Client({ tokenUrl: "HTTPBIN_URL", clientAuth: "jwt" })`:                                          "unsupported clientAuth 'jwt'",
+			`new http.OAuth2Client({ tokenUrl: "HTTPBIN_URL/oauth2/token", clientId: "k6", clientSecret: "wrong" }).token()`: "OAuth2 token request failed with status 401: invalid_client: bad credentials",
+		}
+		for code, msg := range testdata {
+			t.Run(code, func(t *testing.T) {
+				_, err := common.RunString(rt, tb.Replacer.Replace(code))
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), msg)
+			})
+		}
+		stats.GetBufferedSamples(samples)
+	})
+}
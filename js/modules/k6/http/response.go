@@ -26,12 +26,15 @@ import (
 	"encoding/json"
 
 	"fmt"
+	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/js/modules/k6/html"
+	"github.com/loadimpact/k6/js/modules/k6/xml"
 	"github.com/loadimpact/k6/lib"
 	"golang.org/x/crypto/ocsp"
 )
@@ -43,42 +46,55 @@ type OCSP struct {
 }
 
 type HTTPResponseTimings struct {
-	Duration, Blocked, LookingUp, Connecting, TLSHandshaking, Sending, Waiting, Receiving float64
+	Duration, Blocked, Queued, LookingUp, Connecting, TLSHandshaking, Sending, Waiting, Receiving float64
+
+	// The time from sending the request headers to a 100 Continue or the first 103 Early Hints
+	// response, or 0 if there wasn't one.
+	Continue, EarlyHints float64
 }
 
 type HTTPResponse struct {
 	ctx context.Context
 
-	RemoteIP       string
-	RemotePort     int
-	URL            string
-	Status         int
-	Proto          string
-	Headers        map[string]string
-	Cookies        map[string][]*HTTPCookie
-	Body           string
-	Timings        HTTPResponseTimings
-	TLSVersion     string
-	TLSCipherSuite string
-	OCSP           OCSP `js:"ocsp"`
-	Error          string
-	Request        HTTPRequest
+	RemoteIP        string
+	RemotePort      int
+	URL             string
+	Status          int
+	Proto           string
+	Headers         map[string]string
+	Cookies         map[string][]*HTTPCookie
+	Body            string
+	HeaderSize      int
+	BodySize        int
+	EncodedBodySize int
+	Timings         HTTPResponseTimings
+	TLSVersion      string
+	TLSCipherSuite  string
+	OCSP            OCSP `js:"ocsp"`
+	Error           string
+	ErrorCode       int
+	Request         HTTPRequest
 
+	parsedJSON interface{}
 	cachedJSON goja.Value
+	cachedHTML *html.Selection
+	cachedXML  *xml.Element
 }
 
-func (res *HTTPResponse) setTLSInfo(tlsState *tls.ConnectionState) {
-	switch tlsState.Version {
-	case tls.VersionSSL30:
-		res.TLSVersion = SSL_3_0
-	case tls.VersionTLS10:
-		res.TLSVersion = TLS_1_0
-	case tls.VersionTLS11:
-		res.TLSVersion = TLS_1_1
-	case tls.VersionTLS12:
-		res.TLSVersion = TLS_1_2
+// headerSize returns the size of a response's status line and headers, as they're sent with
+// HTTP/1.1; with HTTP/2, they're compressed on the wire, so this is their uncompressed size.
+func headerSize(res *http.Response) int {
+	size := len(res.Proto) + len(" ") + len(res.Status) + len("\r\n")
+	for k, vs := range res.Header {
+		for _, v := range vs {
+			size += len(k) + len(": ") + len(v) + len("\r\n")
+		}
 	}
+	return size + len("\r\n")
+}
 
+func (res *HTTPResponse) setTLSInfo(tlsState *tls.ConnectionState) {
+	res.TLSVersion = lib.SupportedTLSVersionsToString[lib.TLSVersion(tlsState.Version)]
 	res.TLSCipherSuite = lib.SupportedTLSCipherSuitesToString[tlsState.CipherSuite]
 	ocspStapledRes := OCSP{Status: OCSP_STATUS_UNKNOWN}
 
@@ -124,29 +140,72 @@ func (res *HTTPResponse) setTLSInfo(tlsState *tls.ConnectionState) {
 	res.OCSP = ocspStapledRes
 }
 
-func (res *HTTPResponse) Json() goja.Value {
+// Json parses the body the first time it's called, later calls reuse the parsed value. If a
+// selector like "items[0].name" is given, only the value at it is returned, or undefined if
+// there's none.
+func (res *HTTPResponse) Json(selector ...string) goja.Value {
+	rt := common.GetRuntime(res.ctx)
 	if res.cachedJSON == nil {
-		var v interface{}
-		if err := json.Unmarshal([]byte(res.Body), &v); err != nil {
-			common.Throw(common.GetRuntime(res.ctx), err)
+		if err := json.Unmarshal([]byte(res.Body), &res.parsedJSON); err != nil {
+			common.Throw(rt, err)
 		}
-		res.cachedJSON = common.GetRuntime(res.ctx).ToValue(v)
+		res.cachedJSON = rt.ToValue(res.parsedJSON)
 	}
-	return res.cachedJSON
+	if len(selector) == 0 {
+		return res.cachedJSON
+	}
+
+	keys, err := parseJSONSelector(selector[0])
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	v, ok := selectJSON(res.parsedJSON, keys)
+	if !ok {
+		return goja.Undefined()
+	}
+	return rt.ToValue(v)
 }
 
+// Html parses the body the first time it's called, later calls (and SubmitForm()) reuse the document.
 func (res *HTTPResponse) Html(selector ...string) html.Selection {
-	sel, err := html.HTML{}.ParseHTML(res.ctx, res.Body)
-	if err != nil {
-		common.Throw(common.GetRuntime(res.ctx), err)
+	if res.cachedHTML == nil {
+		doc, err := html.HTML{}.ParseHTML(res.ctx, res.Body)
+		if err != nil {
+			common.Throw(common.GetRuntime(res.ctx), err)
+		}
+		doc.URL = res.URL
+		res.cachedHTML = &doc
 	}
-	sel.URL = res.URL
+	sel := *res.cachedHTML
 	if len(selector) > 0 {
 		sel = sel.Find(selector[0])
 	}
 	return sel
 }
 
+// SelectXML parses the body as XML the first time it's called, and returns the root element, or
+// what an XPath selects if one is given. See the k6/xml module for the supported subset of XPath.
+// (It can't be called Xml, since methods with an X prefix are constructors.)
+func (res *HTTPResponse) SelectXML(path ...string) interface{} {
+	rt := common.GetRuntime(res.ctx)
+	if res.cachedXML == nil {
+		root, err := xml.Parse(res.Body)
+		if err != nil {
+			common.Throw(rt, err)
+		}
+		res.cachedXML = root
+	}
+	if len(path) == 0 {
+		return res.cachedXML
+	}
+
+	results, err := res.cachedXML.Find(path[0])
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	return results
+}
+
 func (res *HTTPResponse) SubmitForm(args ...goja.Value) (*HTTPResponse, error) {
 	rt := common.GetRuntime(res.ctx)
 
@@ -231,12 +290,16 @@ func (res *HTTPResponse) SubmitForm(args ...goja.Value) (*HTTPResponse, error) {
 	return New().Request(res.ctx, requestMethod, rt.ToValue(requestUrl.String()), rt.ToValue(values), requestParams)
 }
 
+// ClickLink sends a GET request to the href of the first element that a selector matches, the
+// first link by default. Its argument is either the selector or { selector, params }.
 func (res *HTTPResponse) ClickLink(args ...goja.Value) (*HTTPResponse, error) {
 	rt := common.GetRuntime(res.ctx)
 
 	selector := "a[href]"
 	requestParams := goja.Null()
-	if len(args) > 0 {
+	if len(args) > 0 && args[0].ExportType() == reflect.TypeOf("") {
+		selector = args[0].String()
+	} else if len(args) > 0 {
 		params := args[0].ToObject(rt)
 		for _, k := range params.Keys() {
 			switch k {
@@ -0,0 +1,121 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// The response callback is per VU and usually set in the init context, where there's no VU state
+// yet, so it's kept in a hidden property of the VU's runtime instead.
+const responseCallbackProperty = "__k6_http_responseCallback"
+
+// By default, only 2xx and 3xx responses are expected.
+var defaultResponseCallback = &ExpectedStatuses{ranges: [][2]int{{200, 399}}}
+
+// ExpectedStatuses is a response callback that considers responses with any of its statuses
+// expected, and everything else, including requests that got no response at all, failed.
+type ExpectedStatuses struct {
+	ranges [][2]int
+}
+
+// Expected returns whether a response with the status is expected.
+func (e *ExpectedStatuses) Expected(status int) bool {
+	for _, r := range e.ranges {
+		if status >= r[0] && status <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedStatuses returns a response callback for setResponseCallback() and the responseCallback
+// request param. Its arguments are either statuses or { min, max } ranges of statuses.
+func (*HTTP) ExpectedStatuses(ctx context.Context, statuses ...goja.Value) (*ExpectedStatuses, error) {
+	rt := common.GetRuntime(ctx)
+
+	if len(statuses) == 0 {
+		return nil, errors.New("expectedStatuses needs at least one status or { min, max } range")
+	}
+	result := &ExpectedStatuses{ranges: make([][2]int, 0, len(statuses))}
+	for i, v := range statuses {
+		if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+			return nil, errors.Errorf("argument %d of expectedStatuses is empty", i+1)
+		}
+		if status, ok := v.Export().(int64); ok {
+			result.ranges = append(result.ranges, [2]int{int(status), int(status)})
+			continue
+		}
+
+		obj := v.ToObject(rt)
+		min, max := obj.Get("min"), obj.Get("max")
+		if min == nil || max == nil || goja.IsUndefined(min) || goja.IsUndefined(max) {
+			return nil, errors.Errorf("argument %d of expectedStatuses has to be a status or a { min, max } range, not %s", i+1, v)
+		}
+		r := [2]int{int(min.ToInteger()), int(max.ToInteger())}
+		if r[0] > r[1] {
+			return nil, errors.Errorf("argument %d of expectedStatuses has a min greater than its max", i+1)
+		}
+		result.ranges = append(result.ranges, r)
+	}
+	return result, nil
+}
+
+// SetResponseCallback sets the response callback that decides which responses are counted as
+// failed in the http_req_failed metric for all of the VU's requests; null disables the metric.
+func (*HTTP) SetResponseCallback(ctx context.Context, callback goja.Value) {
+	rt := common.GetRuntime(ctx)
+
+	if _, err := toResponseCallback(callback); err != nil {
+		common.Throw(rt, err)
+	}
+	if callback == nil || goja.IsUndefined(callback) {
+		callback = goja.Null()
+	}
+	err := rt.GlobalObject().DefineDataProperty(responseCallbackProperty, callback, goja.FLAG_FALSE, goja.FLAG_TRUE, goja.FLAG_FALSE)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+}
+
+// getResponseCallback returns the VU's response callback; nil means that it was set to null.
+func getResponseCallback(rt *goja.Runtime) *ExpectedStatuses {
+	v := rt.GlobalObject().Get(responseCallbackProperty)
+	if v == nil || goja.IsUndefined(v) {
+		return defaultResponseCallback
+	}
+	callback, _ := toResponseCallback(v)
+	return callback
+}
+
+func toResponseCallback(v goja.Value) (*ExpectedStatuses, error) {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, nil
+	}
+	if callback, ok := v.Export().(*ExpectedStatuses); ok {
+		return callback, nil
+	}
+	return nil, errors.New("the response callback has to be created with http.expectedStatuses()")
+}
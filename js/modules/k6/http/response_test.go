@@ -30,6 +30,7 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const testGetFormHTML = `
@@ -113,6 +114,15 @@ func TestResponse(t *testing.T) {
 				`))
 				assert.NoError(t, err)
 			})
+
+			t.Run("cached", func(t *testing.T) {
+				_, err := common.RunString(rt, `
+					let h1 = res.html("h1");
+					if (h1.end().find("p").size() != 1) { throw new Error("wrong document: " + h1.end().html()); }
+					if (res.html().find("h1").size() != 1) { throw new Error("the cached document was modified"); }
+				`)
+				assert.NoError(t, err)
+			})
 		})
 
 		t.Run("group", func(t *testing.T) {
@@ -146,6 +156,61 @@ func TestResponse(t *testing.T) {
 			_, err := common.RunString(rt, sr(`http.request("GET", "HTTPBIN_URL/html").json();`))
 			assert.EqualError(t, err, "GoError: invalid character '<' looking for beginning of value")
 		})
+
+		t.Run("Selector", func(t *testing.T) {
+			tb.Mux.HandleFunc("/json-selector", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprint(w, `{"items": [{"id": 1, "name": "a"}, {"id": 2, "tags": ["x", "y"]}], "a.b": {"c": true}}`)
+			})
+			_, err := common.RunString(rt, sr(`
+				let res = http.get("HTTPBIN_URL/json-selector");
+				let checks = {
+					"items.0.name": "a",
+					"$.items[1].id": 2,
+					"items[1].tags.#": 2,
+					"items.#": 2,
+					"items[1]['tags'][1]": "y",
+					"['a.b'].c": true,
+					"items.5.id": undefined,
+					"items[0].name.length": undefined,
+					"missing": undefined,
+				};
+				for (let selector in checks) {
+					let value = res.json(selector);
+					if (value !== checks[selector]) { throw new Error("wrong value for " + selector + ": " + value); }
+				}
+				if (res.json("items")[1].tags[0] !== "x") { throw new Error("wrong nested value"); }
+			`))
+			assert.NoError(t, err)
+
+			_, err = common.RunString(rt, sr(`http.get("HTTPBIN_URL/json-selector").json("items[first]");`))
+			assert.EqualError(t, err, "GoError: invalid JSON selector 'items[first]': 'first' isn't an index or a quoted key")
+			stats.GetBufferedSamples(samples)
+		})
+	})
+
+	t.Run("Xml", func(t *testing.T) {
+		tb.Mux.HandleFunc("/soap", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/xml")
+			_, _ = fmt.Fprint(w, `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">`+
+				`<soap:Body><Result code="ok">42</Result></soap:Body></soap:Envelope>`)
+		})
+		_, err := common.RunString(rt, sr(`
+			let res = http.post("HTTPBIN_URL/soap", "<request/>");
+			if (res.selectXML().name !== "Envelope") { throw new Error("wrong root: " + res.selectXML().name); }
+			if (res.selectXML("//soap:Body/Result")[0].text() !== "42") { throw new Error("wrong result"); }
+			if (res.selectXML("//Result/@code")[0] !== "ok") { throw new Error("wrong code"); }
+		`))
+		assert.NoError(t, err)
+
+		_, err = common.RunString(rt, sr(`http.get("HTTPBIN_URL/soap").selectXML("//Result[");`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unbalanced brackets or quotes")
+
+		_, err = common.RunString(rt, sr(`http.get("HTTPBIN_URL/get").selectXML();`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid XML")
+		stats.GetBufferedSamples(samples)
 	})
 
 	t.Run("SubmitForm", func(t *testing.T) {
@@ -270,6 +335,17 @@ func TestResponse(t *testing.T) {
 			assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/links/10/4"), "", 200, "")
 		})
 
+		t.Run("withSelectorString", func(t *testing.T) {
+			_, err := common.RunString(rt, sr(`
+				let res = http.request("GET", "HTTPBIN_URL/links/10/0");
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+				res = res.clickLink('a:nth-child(6)')
+				if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+			`))
+			assert.NoError(t, err)
+			assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/links/10/6"), "", 200, "")
+		})
+
 		t.Run("withNonExistentLink", func(t *testing.T) {
 			_, err := common.RunString(rt, sr(`
 				let res = http.request("GET", "HTTPBIN_URL/links/10/0");
@@ -0,0 +1,122 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+)
+
+// retryPolicy is what the retry request param is parsed into; requests without one are only sent
+// once, since retrying requests that aren't idempotent is rarely what a test wants.
+type retryPolicy struct {
+	attempts   int          // Including the first one.
+	statuses   map[int]bool // Responses with these statuses are retried.
+	errors     bool         // Whether requests that got no response, e.g. because of a timeout, are retried.
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryPolicy(attempts int) *retryPolicy {
+	return &retryPolicy{
+		attempts:   attempts,
+		statuses:   map[int]bool{502: true, 503: true, 504: true},
+		errors:     true,
+		backoff:    100 * time.Millisecond,
+		maxBackoff: 10 * time.Second,
+	}
+}
+
+// parseRetryPolicy parses the retry param, which is either the number of attempts, or an object
+// like { attempts: 3, statuses: [503], errors: false, backoff: "200ms", maxBackoff: "5s" }.
+func parseRetryPolicy(rt *goja.Runtime, v goja.Value) (*retryPolicy, error) {
+	if goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil, nil
+	}
+	if _, ok := v.Export().(map[string]interface{}); !ok {
+		return validRetryPolicy(newRetryPolicy(int(v.ToInteger())))
+	}
+
+	obj := v.ToObject(rt)
+	policy := newRetryPolicy(3)
+	for _, k := range obj.Keys() {
+		value := obj.Get(k)
+		switch k {
+		case "attempts":
+			policy.attempts = int(value.ToInteger())
+		case "statuses":
+			var statuses []int
+			if err := rt.ExportTo(value, &statuses); err != nil {
+				return nil, errors.Errorf("invalid retry statuses '%s', they have to be an array of statuses", value)
+			}
+			policy.statuses = make(map[int]bool, len(statuses))
+			for _, status := range statuses {
+				policy.statuses[status] = true
+			}
+		case "errors":
+			policy.errors = value.ToBoolean()
+		case "backoff", "maxBackoff":
+			d, err := parseTimeout(value)
+			if err != nil {
+				return nil, errors.Errorf("invalid retry %s '%s', it has to be a number of milliseconds or a duration like '1s'", k, value)
+			}
+			if k == "backoff" {
+				policy.backoff = d
+			} else {
+				policy.maxBackoff = d
+			}
+		default:
+			return nil, errors.Errorf("unknown retry option '%s'", k)
+		}
+	}
+	return validRetryPolicy(policy)
+}
+
+func validRetryPolicy(policy *retryPolicy) (*retryPolicy, error) {
+	if policy.attempts < 1 {
+		return nil, errors.Errorf("invalid number of retry attempts %d, there has to be at least 1", policy.attempts)
+	}
+	return policy, nil
+}
+
+// shouldRetry returns whether a request that got resp and err should be sent again.
+func (p *retryPolicy) shouldRetry(resp *HTTPResponse, err error) bool {
+	// Responses that did arrive can still have an error, e.g. for a body that couldn't be decoded,
+	// but sending them again wouldn't change that
+	if err != nil || resp == nil || (resp.Error != "" && resp.Status == 0) {
+		return p.errors
+	}
+	return p.statuses[resp.Status]
+}
+
+// delay returns how long to wait before a retry, which doubles with every one, up to maxBackoff.
+func (p *retryPolicy) delay(retry int) time.Duration {
+	d := p.backoff
+	for i := 1; i < retry && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		return p.maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,218 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+// Session sends requests relative to a base URL, with default params, so that the same URL prefix,
+// headers, tags and auth don't have to be repeated in every request. Sessions have a cookie jar of
+// their own, unless they're given one with the jar param.
+type Session struct {
+	BaseURL string            `js:"baseUrl"`
+	Headers map[string]string `js:"headers"`
+	Tags    map[string]string `js:"tags"`
+	Jar     *HTTPCookieJar    `js:"jar"`
+
+	h      *HTTP
+	ctx    *context.Context
+	params map[string]goja.Value // The other default params, e.g. auth or timeout
+}
+
+func (h *HTTP) XSession(ctxPtr *context.Context, configV goja.Value) (*Session, error) {
+	rt := common.GetRuntime(*ctxPtr)
+
+	s := &Session{
+		Headers: make(map[string]string),
+		Tags:    make(map[string]string),
+		h:       h,
+		ctx:     ctxPtr,
+		params:  make(map[string]goja.Value),
+	}
+	if configV != nil && !goja.IsUndefined(configV) && !goja.IsNull(configV) {
+		configObj := configV.ToObject(rt)
+		for _, k := range configObj.Keys() {
+			v := configObj.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "baseUrl":
+				s.BaseURL = v.String()
+			case "headers", "tags":
+				m := s.Headers
+				if k == "tags" {
+					m = s.Tags
+				}
+				obj := v.ToObject(rt)
+				for _, key := range obj.Keys() {
+					m[key] = obj.Get(key).String()
+				}
+			case "jar":
+				jar, ok := v.Export().(*HTTPCookieJar)
+				if !ok {
+					return nil, errors.New("the jar of a session has to be created with http.cookieJar()")
+				}
+				s.Jar = jar
+			default:
+				s.params[k] = v
+			}
+		}
+	}
+	if s.BaseURL != "" && !strings.Contains(s.BaseURL, "://") {
+		return nil, errors.Errorf("invalid baseUrl '%s', it has to be an absolute URL", s.BaseURL)
+	}
+	if s.Jar == nil {
+		s.Jar = newCookieJar(ctxPtr)
+	}
+	return s, nil
+}
+
+func (s *Session) Get(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	// The body argument is always undefined for GETs and HEADs.
+	args = append([]goja.Value{goja.Undefined()}, args...)
+	return s.Request(HTTP_METHOD_GET, url, args...)
+}
+
+func (s *Session) Head(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	args = append([]goja.Value{goja.Undefined()}, args...)
+	return s.Request(HTTP_METHOD_HEAD, url, args...)
+}
+
+func (s *Session) Post(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	return s.Request(HTTP_METHOD_POST, url, args...)
+}
+
+func (s *Session) Put(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	return s.Request(HTTP_METHOD_PUT, url, args...)
+}
+
+func (s *Session) Patch(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	return s.Request(HTTP_METHOD_PATCH, url, args...)
+}
+
+func (s *Session) Del(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	return s.Request(HTTP_METHOD_DELETE, url, args...)
+}
+
+func (s *Session) Options(url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	return s.Request(HTTP_METHOD_OPTIONS, url, args...)
+}
+
+// Request sends a request like http.request(), to a URL relative to the base URL, with the
+// session's params merged with the request's; the headers and tags of the request are added to
+// the session's, and its other params replace the session's.
+func (s *Session) Request(method string, url goja.Value, args ...goja.Value) (*HTTPResponse, error) {
+	ctx := *s.ctx
+	if common.GetState(ctx) == nil {
+		return nil, errors.New("sessions can't send requests in the init context")
+	}
+	rt := common.GetRuntime(ctx)
+
+	u, err := ToURL(url)
+	if err != nil {
+		return nil, err
+	}
+	u = s.resolve(u)
+
+	body := goja.Undefined()
+	if len(args) > 0 {
+		body = args[0]
+	}
+	var params goja.Value
+	if len(args) > 1 {
+		params = args[1]
+	}
+	return s.h.Request(ctx, method, rt.ToValue(u), body, s.mergeParams(rt, params))
+}
+
+// resolve returns a URL relative to the base URL, unless it's absolute; relative URLs are appended
+// to the base URL's path, even if they start with a slash.
+func (s *Session) resolve(u URL) URL {
+	if s.BaseURL == "" || strings.Contains(u.URLString, "://") {
+		return u
+	}
+	join := func(rel string) string {
+		return strings.TrimSuffix(s.BaseURL, "/") + "/" + strings.TrimPrefix(rel, "/")
+	}
+	resolved, err := ToURL(join(u.URLString))
+	if err != nil {
+		return u // The base URL is checked, so it's the request's URL that's invalid
+	}
+	resolved.Name = join(u.Name)
+	return resolved
+}
+
+func (s *Session) mergeParams(rt *goja.Runtime, reqParams goja.Value) goja.Value {
+	params := rt.NewObject()
+	for k, v := range s.params {
+		_ = params.Set(k, v)
+	}
+	headers := make(map[string]interface{}, len(s.Headers))
+	for k, v := range s.Headers {
+		headers[k] = v
+	}
+	tags := make(map[string]interface{}, len(s.Tags))
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	_ = params.Set("jar", s.Jar)
+
+	if reqParams != nil && !goja.IsUndefined(reqParams) && !goja.IsNull(reqParams) {
+		reqObj := reqParams.ToObject(rt)
+		for _, k := range reqObj.Keys() {
+			v := reqObj.Get(k)
+			switch k {
+			case "headers", "tags":
+				if goja.IsUndefined(v) || goja.IsNull(v) {
+					continue
+				}
+				m := headers
+				if k == "tags" {
+					m = tags
+				}
+				obj := v.ToObject(rt)
+				for _, key := range obj.Keys() {
+					if k == "headers" {
+						// Header names aren't case sensitive, so the request's replace the session's
+						for name := range m {
+							if strings.EqualFold(name, key) {
+								delete(m, name)
+							}
+						}
+					}
+					m[key] = obj.Get(key).String()
+				}
+			default:
+				_ = params.Set(k, v)
+			}
+		}
+	}
+	_ = params.Set("headers", headers)
+	_ = params.Set("tags", tags)
+	return params
+}
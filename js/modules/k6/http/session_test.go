@@ -0,0 +1,109 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession(t *testing.T) {
+	tb, state, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	state.CookieJar = jar
+
+	tb.Mux.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t", Path: "/"})
+	})
+	tb.Mux.HandleFunc("/api/v1/users/1", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(r.Method + " " + r.Header.Get("Authorization") + " " + r.Header.Get("X-Client")))
+	})
+
+	_, err = common.RunString(rt, tb.Replacer.Replace(`
+	let session = new http.Session({
+		baseUrl: "HTTPBIN_URL/api/v1/",
+		headers: { "Authorization": "Bearer 1", "X-Client": "k6" },
+		tags: { api: "v1" },
+		timeout: "10s",
+	});
+	if (session.baseUrl !== "HTTPBIN_URL/api/v1/") { throw new Error("wrong baseUrl: " + session.baseUrl); }
+
+	let res = session.get("/users/1");
+	if (res.status !== 401) { throw new Error("the request had a cookie: " + res.status); }
+	session.post("login");
+	if (Object.keys(session.jar.cookiesForURL("HTTPBIN_URL/")).length !== 1) { throw new Error("no cookie in the session's jar"); }
+	if (Object.keys(http.cookieJar().cookiesForURL("HTTPBIN_URL/")).length !== 0) { throw new Error("the session used the VU's jar"); }
+
+	res = session.get("users/1", { headers: { "authorization": "Bearer 2" }, tags: { endpoint: "user" } });
+	if (res.body !== "GET Bearer 2 k6") { throw new Error("wrong headers: " + res.body); }
+	session.headers["Authorization"] = "Bearer 3";
+	res = session.del(http.url`+"`users/${1}`"+`);
+	if (res.body !== "DELETE Bearer 3 k6") { throw new Error("wrong headers: " + res.body); }
+	res = session.get("HTTPBIN_URL/get");
+	if (res.status !== 200) { throw new Error("absolute URLs aren't used as they are: " + res.status); }
+	`))
+	require.NoError(t, err)
+
+	var names []string
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.HTTPReqs {
+				continue
+			}
+			tags := s.Tags.CloneTags()
+			assert.Equal(t, "v1", tags["api"])
+			names = append(names, tags["name"]+" "+tags["endpoint"])
+		}
+	}
+	sr := tb.Replacer.Replace
+	assert.Equal(t, []string{
+		sr("HTTPBIN_URL/api/v1/users/1 "),
+		sr("HTTPBIN_URL/api/v1/login "),
+		sr("HTTPBIN_URL/api/v1/users/1 user"),
+		sr("HTTPBIN_URL/api/v1/users/${} "),
+		sr("HTTPBIN_URL/get "),
+	}, names)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := common.RunString(rt, `new http.Session({ baseUrl: "example.com" })`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "invalid baseUrl 'example.com'")
+		}
+		_, err = common.RunString(rt, `new http.Session({ jar: {} })`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "has to be created with http.cookieJar()")
+		}
+	})
+}
@@ -22,7 +22,7 @@ package k6
 
 import (
 	"context"
-	"math/rand"
+	"math"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -30,6 +30,7 @@ import (
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
 )
@@ -53,11 +54,47 @@ func (*K6) Sleep(ctx context.Context, secs float64) {
 	}
 }
 
-func (*K6) RandomSeed(ctx context.Context, seed int64) {
-	randSource := rand.New(rand.NewSource(seed)).Float64
+// RandomSleep sleeps for a random number of seconds between min and max, and returns it. The
+// distribution is either "uniform", the default, or "normal", which is centered between min and
+// max, with 99.7% of the values within them (3 standard deviations); the rest are clamped to them.
+// The numbers come from Math.random(), so randomSeed() makes them reproducible.
+func (k *K6) RandomSleep(ctx context.Context, min, max float64, distribution ...string) (float64, error) {
+	rt := common.GetRuntime(ctx)
+	if min < 0 || max < min {
+		return 0, errors.Errorf("invalid randomSleep range [%v, %v], it has to be 0 <= min <= max", min, max)
+	}
+	random, ok := goja.AssertFunction(rt.Get("Math").ToObject(rt).Get("random"))
+	if !ok {
+		return 0, errors.New("Math.random isn't a function")
+	}
+	uniform := func() float64 {
+		v, _ := random(goja.Undefined())
+		return v.ToFloat()
+	}
 
+	dist := "uniform"
+	if len(distribution) > 0 && distribution[0] != "" {
+		dist = distribution[0]
+	}
+	var secs float64
+	switch dist {
+	case "uniform":
+		secs = min + uniform()*(max-min)
+	case "normal":
+		// Box-Muller transform; 1-u keeps the logarithm finite
+		z := math.Sqrt(-2*math.Log(1-uniform())) * math.Cos(2*math.Pi*uniform())
+		secs = math.Min(max, math.Max(min, (min+max)/2+z*(max-min)/6))
+	default:
+		return 0, errors.Errorf("unknown randomSleep distribution '%s', it has to be 'uniform' or 'normal'", dist)
+	}
+
+	k.Sleep(ctx, secs)
+	return secs, nil
+}
+
+func (*K6) RandomSeed(ctx context.Context, seed int64) {
 	rt := common.GetRuntime(ctx)
-	rt.SetRandSource(randSource)
+	rt.SetRandSource(common.NewSeededRandSource(seed))
 }
 
 func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value, error) {
@@ -103,15 +140,28 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 
 	// Prepare tags, make sure the `group` tag can't be overwritten.
 	commonTags := state.Options.RunTags.CloneTags()
-	if state.Options.SystemTags["group"] {
-		commonTags["group"] = state.Group.Path
-	}
-	if len(extras) > 0 {
+	if len(extras) > 0 && !goja.IsUndefined(extras[0]) && !goja.IsNull(extras[0]) {
 		obj := extras[0].ToObject(rt)
 		for _, k := range obj.Keys() {
 			commonTags[k] = obj.Get(k).String()
 		}
 	}
+
+	// The options after the tags are a severity and/or a threshold, for all of the checks.
+	var severity, threshold string
+	hasOptions := len(extras) > 1 && !goja.IsUndefined(extras[1]) && !goja.IsNull(extras[1])
+	if hasOptions {
+		obj := extras[1].ToObject(rt)
+		if v := obj.Get("severity"); v != nil && !goja.IsUndefined(v) {
+			severity = v.String()
+		}
+		if v := obj.Get("threshold"); v != nil && !goja.IsUndefined(v) {
+			threshold = v.String()
+		}
+	}
+	if state.Options.SystemTags["group"] {
+		commonTags["group"] = state.Group.Path
+	}
 	if state.Options.SystemTags["vu"] {
 		commonTags["vu"] = strconv.FormatInt(state.Vu, 10)
 	}
@@ -137,6 +187,17 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 		if state.Options.SystemTags["check"] {
 			tags["check"] = check.Name
 		}
+		if hasOptions {
+			changed, err := check.SetOptions(severity, threshold)
+			if err != nil {
+				return false, err
+			}
+			if _, th := check.Options(); changed {
+				if _, err := stats.NewThreshold(th, nil, false, types.NullDuration{}); err != nil {
+					return false, errors.Wrapf(err, "invalid threshold '%s' for the check '%s'", th, check.Name)
+				}
+			}
+		}
 
 		// Resolve callables into values.
 		fn, ok := goja.AssertFunction(val)
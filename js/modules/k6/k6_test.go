@@ -84,6 +84,52 @@ func TestSleep(t *testing.T) {
 	})
 }
 
+func TestRandomSleep(t *testing.T) {
+	rt := goja.New()
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("k6", common.Bind(rt, New(), &ctx))
+
+	t.Run("uniform", func(t *testing.T) {
+		startTime := time.Now()
+		v, err := common.RunString(rt, `k6.randomSleep(0.1, 0.2)`)
+		require.NoError(t, err)
+		assert.InDelta(t, 0.15, v.ToFloat(), 0.05)
+		assert.True(t, time.Since(startTime) >= time.Duration(v.ToFloat()*float64(time.Second)), "did not sleep long enough")
+	})
+
+	t.Run("normal", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		k6.randomSeed(12345);
+		let sum = 0;
+		for (let i = 0; i < 200; i++) {
+			let secs = k6.randomSleep(0, 0.0001, "normal");
+			if (secs < 0 || secs > 0.0001) { throw new Error("out of range: " + secs); }
+			sum += secs;
+		}
+		if (Math.abs(sum / 200 - 0.00005) > 0.00001) { throw new Error("wrong mean: " + sum / 200); }
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("seeded", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+		k6.randomSeed(42);
+		let a = k6.randomSleep(0, 0.001);
+		k6.randomSeed(42);
+		a === k6.randomSleep(0, 0.001);
+		`)
+		require.NoError(t, err)
+		assert.True(t, v.ToBoolean())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := common.RunString(rt, `k6.randomSleep(2, 1)`)
+		assert.EqualError(t, err, "GoError: invalid randomSleep range [2, 1], it has to be 0 <= min <= max")
+		_, err = common.RunString(rt, `k6.randomSleep(0, 1, "poisson")`)
+		assert.EqualError(t, err, "GoError: unknown randomSleep distribution 'poisson', it has to be 'uniform' or 'normal'")
+	})
+}
+
 func TestRandSeed(t *testing.T) {
 	rt := goja.New()
 
@@ -343,7 +389,7 @@ func TestCheck(t *testing.T) {
 		state, samples := getState()
 		*ctx = common.WithState(baseCtx, state)
 
-		v, err := common.RunString(rt, `k6.check(null, {"check": true}, {a: 1, b: "2"})`)
+		v, err := common.RunString(rt, `k6.check(null, {"check": true}, {a: 1, b: "2", group: "not the group"})`)
 		if assert.NoError(t, err) {
 			assert.Equal(t, true, v.Export())
 		}
@@ -364,4 +410,30 @@ func TestCheck(t *testing.T) {
 			}, sample.Tags.CloneTags())
 		}
 	})
+
+	t.Run("Options", func(t *testing.T) {
+		state, samples := getState()
+		*ctx = common.WithState(baseCtx, state)
+
+		_, err := common.RunString(rt, `
+		k6.check(null, {"login": true}, null, {severity: "error", threshold: "rate>0.99"});
+		k6.check(null, {"cosmetic": false}, {a: 1}, {severity: "warn"});
+		`)
+		require.NoError(t, err)
+		assert.Len(t, stats.GetBufferedSamples(samples), 2)
+
+		severity, threshold := root.Checks["login"].Options()
+		assert.Equal(t, lib.CheckSeverityError, severity)
+		assert.Equal(t, "rate>0.99", threshold)
+		severity, threshold = root.Checks["cosmetic"].Options()
+		assert.Equal(t, lib.CheckSeverityWarn, severity)
+		assert.Equal(t, lib.DefaultCheckThreshold, threshold)
+		severity, _ = root.Checks["check"].Options()
+		assert.Equal(t, "", severity)
+
+		_, err = common.RunString(rt, `k6.check(null, {"bad": true}, null, {severity: "fatal"})`)
+		assert.Contains(t, err.Error(), "invalid severity 'fatal' for the check 'bad'")
+		_, err = common.RunString(rt, `k6.check(null, {"worse": true}, null, {threshold: "rate >"})`)
+		assert.Contains(t, err.Error(), "invalid threshold 'rate >' for the check 'worse'")
+	})
 }
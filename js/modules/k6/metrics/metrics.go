@@ -52,15 +52,14 @@ func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]str
 	state := common.GetState(ctx)
 
 	tags := state.Options.RunTags.CloneTags()
-	if state.Options.SystemTags["group"] {
-		tags["group"] = state.Group.Path
-	}
-
 	for _, ts := range addTags {
 		for k, v := range ts {
 			tags[k] = v
 		}
 	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
 
 	vfloat := v.ToFloat()
 	if vfloat == 0 && v.ToBoolean() {
@@ -0,0 +1,802 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout   = 60 * time.Second
+	defaultKeepAlive = 60 // In seconds, as it's sent in CONNECT.
+	writeWait        = 10 * time.Second
+)
+
+// The reasons for refused connections, by CONNACK return code.
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+type MQTT struct{}
+
+// transport is what MQTT packets are exchanged over: a TCP or TLS connection, or a websocket.
+type transport interface {
+	io.ReadWriteCloser
+	RemoteAddr() net.Addr
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+}
+
+// Client is a connection to an MQTT broker, as passed to the function given to mqtt.connect().
+type Client struct {
+	ctx           context.Context
+	conn          transport
+	tags          *stats.SampleTags
+	eventHandlers map[string][]goja.Callable
+	loop          *common.EventLoop
+	done          chan struct{}
+	closed        bool
+
+	lastPacketID  uint16
+	inflight      map[uint16]time.Time // Sent QoS 1 and 2 messages that weren't acknowledged yet.
+	subscriptions map[uint16][]string  // Sent subscriptions that weren't acknowledged yet.
+	received      map[uint16]bool      // Received QoS 2 messages that weren't released yet.
+}
+
+// Message is a message received from the broker.
+type Message struct {
+	Topic   string `js:"topic"`
+	Payload string `js:"payload"`
+	QoS     int    `js:"qos"`
+	Retain  bool   `js:"retain"`
+	Dup     bool   `js:"dup"`
+}
+
+func New() *MQTT {
+	return &MQTT{}
+}
+
+// Connect connects to the broker at url (tcp://, ssl://, ws:// or wss://) and calls the given
+// function with the client, so it can set up its event handlers. It blocks until the connection
+// is closed by either side.
+func (*MQTT) Connect(ctx context.Context, url string, args ...goja.Value) {
+	if err := connect(ctx, url, args...); err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+}
+
+func connect(ctx context.Context, url string, args ...goja.Value) error {
+	rt := common.GetRuntime(ctx)
+	state := common.GetState(ctx)
+	if state == nil {
+		return errors.New("MQTT connections can't be made in the init context")
+	}
+
+	// The params argument is optional
+	var callableV, paramsV goja.Value
+	switch len(args) {
+	case 2:
+		paramsV = args[0]
+		callableV = args[1]
+	case 1:
+		paramsV = goja.Undefined()
+		callableV = args[0]
+	default:
+		return errors.New("Invalid number of arguments to mqtt.connect")
+	}
+
+	setupFn, isFunc := goja.AssertFunction(callableV)
+	if !isFunc {
+		return errors.New("Last argument to mqtt.connect must be a function")
+	}
+
+	opts := connectOptions{cleanSession: true, keepAlive: defaultKeepAlive}
+	timeout := defaultTimeout
+	tags := state.Options.RunTags.CloneTags()
+	if !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
+		params := paramsV.ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "clientId":
+				opts.clientID = v.String()
+			case "username":
+				opts.username, opts.hasUsername = v.String(), true
+			case "password":
+				opts.password, opts.hasPassword = v.String(), true
+			case "cleanSession":
+				opts.cleanSession = v.ToBoolean()
+			case "keepAlive":
+				keepAlive := v.ToInteger()
+				if keepAlive < 0 || keepAlive > 65535 {
+					return errors.Errorf("Invalid keepAlive %d, must be between 0 and 65535 seconds", keepAlive)
+				}
+				opts.keepAlive = uint16(keepAlive)
+			case "timeout":
+				timeout = time.Duration(v.ToFloat() * float64(time.Millisecond))
+			case "will":
+				will := v.ToObject(rt)
+				if topicV := will.Get("topic"); topicV != nil {
+					opts.willTopic = topicV.String()
+				}
+				if opts.willTopic == "" || strings.ContainsAny(opts.willTopic, "+#") {
+					return errors.Errorf("Invalid will topic '%s', it can't be empty or have wildcards", opts.willTopic)
+				}
+				opts.willPayload = toBytes(will.Get("payload"))
+				if qosV := will.Get("qos"); qosV != nil && !goja.IsUndefined(qosV) {
+					qos, err := toQoS(qosV)
+					if err != nil {
+						return err
+					}
+					opts.willQoS = qos
+				}
+				if retainV := will.Get("retain"); retainV != nil {
+					opts.willRetain = retainV.ToBoolean()
+				}
+			case "tags":
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+	if opts.clientID == "" {
+		// Brokers only accept empty client IDs for clean sessions, so one is always made up
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+		opts.clientID = "k6-" + hex.EncodeToString(id)
+	}
+
+	if state.Options.SystemTags["url"] {
+		tags["url"] = url
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	// The connection's packets and timers are forwarded to the VU's event loop, which runs their
+	// handlers between the callbacks of the script's timers and Promises, until the connection is
+	// closed. All JS code (including error handlers) runs on the loop, to avoid race conditions.
+	loop := common.GetEventLoop(ctx)
+	if loop == nil {
+		loop = common.NewEventLoop(rt)
+	}
+	client := Client{
+		ctx:           ctx,
+		eventHandlers: make(map[string][]goja.Callable),
+		loop:          loop,
+		done:          make(chan struct{}),
+		inflight:      make(map[uint16]time.Time),
+		subscriptions: make(map[uint16][]string),
+		received:      make(map[uint16]bool),
+	}
+
+	start := time.Now()
+	conn, connErr := dial(ctx, state, url, timeout)
+	if connErr == nil {
+		connErr = handshake(conn, opts, timeout)
+		if connErr != nil {
+			_ = conn.Close()
+		}
+	}
+	connectionDuration := stats.D(time.Since(start))
+
+	// Run the user-provided set up function
+	if _, err := setupFn(goja.Undefined(), rt.ToValue(&client)); err != nil {
+		if connErr == nil {
+			_ = conn.Close()
+		}
+		return err
+	}
+
+	if connErr != nil {
+		// Pass the error to the user script before exiting immediately
+		client.handleEvent("error", rt.ToValue(connErr))
+
+		return connErr
+	}
+	defer func() { _ = conn.Close() }()
+
+	state.Options.SystemTags.SetRemoteAddrTags(tags, conn.RemoteAddr())
+	client.conn = conn
+	client.tags = stats.IntoSampleTags(&tags)
+
+	// The connection is now established, emit the event
+	client.handleEvent("connect")
+
+	packetChan := make(chan packet)
+	readErrChan := make(chan error)
+	go readPump(conn, packetChan, readErrChan, client.done)
+
+	// Brokers disconnect clients that are silent for longer than the keep alive
+	var pingChan <-chan time.Time
+	if opts.keepAlive > 0 {
+		ticker := time.NewTicker(time.Duration(opts.keepAlive) * time.Second)
+		defer ticker.Stop()
+		pingChan = ticker.C
+	}
+
+	go func() {
+		ctxDone := ctx.Done()
+		for {
+			var fn func() error
+			select {
+			case p := <-packetChan:
+				fn = func() error {
+					if err := client.handlePacket(p); err != nil {
+						client.handleEvent("error", rt.ToValue(err))
+					}
+					return nil
+				}
+
+			case readErr := <-readErrChan:
+				// The connection was either closed by the broker, or broken
+				fn = func() error {
+					if readErr != io.EOF {
+						client.handleEvent("error", rt.ToValue(readErr))
+					}
+					client.closeConnection(false)
+					return nil
+				}
+
+			case <-pingChan:
+				fn = func() error {
+					if err := client.write(packet{typ: packetPingreq}); err != nil {
+						client.handleEvent("error", rt.ToValue(err))
+					}
+					return nil
+				}
+
+			case <-ctxDone:
+				// VU is shutting down during an interrupt
+				ctxDone = nil
+				fn = func() error {
+					client.closeConnection(true)
+					return nil
+				}
+
+			case <-client.done:
+				return
+			}
+			client.post(fn)
+		}
+	}()
+
+	// closeConnection closes the done channel, which is the normal exit point
+	if err := loop.RunUntil(client.done); err != nil {
+		if !client.closed {
+			client.closed = true
+			close(client.done)
+		}
+		return err
+	}
+
+	state.Samples <- stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.MQTTSessions, Time: start, Tags: client.tags, Value: 1},
+			{Metric: metrics.MQTTConnecting, Time: start, Tags: client.tags, Value: connectionDuration},
+			{Metric: metrics.MQTTSessionDuration, Time: start, Tags: client.tags, Value: stats.D(time.Since(start))},
+		},
+		Tags: client.tags,
+		Time: start,
+	}
+	return nil
+}
+
+// post runs fn on the event loop, unless the connection is closed by then.
+func (c *Client) post(fn func() error) {
+	c.loop.RegisterCallback()(func() error {
+		select {
+		case <-c.done:
+			return nil
+		default:
+			return fn()
+		}
+	})
+}
+
+// dial opens the connection that MQTT packets will be sent over.
+func dial(ctx context.Context, state *common.State, url string, timeout time.Duration) (transport, error) {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+
+	dialCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var tlsConfig *tls.Config
+	if state.TLSConfig != nil {
+		tlsConfig = state.TLSConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{}
+	}
+
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		return state.Dialer.DialContext(dialCtx, "tcp", hostPort(u, "1883"))
+	case "ssl", "tls", "mqtts":
+		conn, err := state.Dialer.DialContext(dialCtx, "tcp", hostPort(u, "8883"))
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		if deadline, ok := dialCtx.Deadline(); ok {
+			_ = tlsConn.SetDeadline(deadline)
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		return tlsConn, nil
+	case "ws", "wss":
+		// Overriding the NextProtos to avoid talking http2
+		tlsConfig.NextProtos = []string{"http/1.1"}
+		wsd := websocket.Dialer{
+			NetDial: func(network, address string) (net.Conn, error) {
+				return state.Dialer.DialContext(dialCtx, network, address)
+			},
+			TLSClientConfig:  tlsConfig,
+			HandshakeTimeout: timeout,
+			Subprotocols:     []string{"mqtt"},
+		}
+		conn, res, err := wsd.Dial(url, nil)
+		if err != nil {
+			if res != nil {
+				err = errors.Wrapf(err, "websocket handshake failed with status %d", res.StatusCode)
+			}
+			return nil, err
+		}
+		return &wsConn{Conn: conn}, nil
+	default:
+		return nil, errors.Errorf("Unsupported MQTT URL scheme '%s', must be tcp, ssl, ws or wss", u.Scheme)
+	}
+}
+
+func hostPort(u *neturl.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// handshake sends CONNECT and waits for the CONNACK.
+func handshake(conn transport, opts connectOptions, timeout time.Duration) error {
+	if timeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(timeout))
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		defer func() {
+			_ = conn.SetWriteDeadline(time.Time{})
+			_ = conn.SetReadDeadline(time.Time{})
+		}()
+	}
+	if _, err := conn.Write(connectPacket(opts).encode()); err != nil {
+		return err
+	}
+
+	// The reader isn't buffered, so that it doesn't consume what comes after the CONNACK
+	p, err := readPacket(bufio.NewReaderSize(io.LimitReader(conn, 4), 16))
+	if err != nil {
+		return errors.Wrap(err, "couldn't read the MQTT CONNACK")
+	}
+	if p.typ != packetConnack || len(p.body) != 2 {
+		return errors.Errorf("unexpected MQTT packet of type %d instead of CONNACK", p.typ)
+	}
+	if code := p.body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = "return code " + strconv.Itoa(int(code))
+		}
+		return errors.Errorf("MQTT connection refused: %s", reason)
+	}
+	return nil
+}
+
+// handlePacket takes care of a packet received from the broker, acknowledging it if needed.
+func (c *Client) handlePacket(p packet) error {
+	rt := common.GetRuntime(c.ctx)
+	state := common.GetState(c.ctx)
+
+	switch p.typ {
+	case packetPublish:
+		m, err := p.message()
+		if err != nil {
+			return err
+		}
+		switch m.qos {
+		case 1:
+			if err := c.write(ackPacket(packetPuback, m.packetID)); err != nil {
+				return err
+			}
+		case 2:
+			if err := c.write(ackPacket(packetPubrec, m.packetID)); err != nil {
+				return err
+			}
+			// Only the first delivery of the message is passed on, until it's released
+			if c.received[m.packetID] {
+				return nil
+			}
+			c.received[m.packetID] = true
+		}
+
+		state.Samples <- stats.Sample{Metric: metrics.MQTTMessagesReceived, Time: time.Now(), Tags: c.tags, Value: 1}
+		c.handleEvent("message", rt.ToValue(&Message{
+			Topic:   m.topic,
+			Payload: string(m.payload),
+			QoS:     int(m.qos),
+			Retain:  m.retain,
+			Dup:     m.dup,
+		}))
+
+	case packetPuback, packetPubcomp:
+		id, err := p.packetID()
+		if err != nil {
+			return err
+		}
+		if sent, ok := c.inflight[id]; ok {
+			now := time.Now()
+			state.Samples <- stats.Sample{Metric: metrics.MQTTPublishDuration, Time: now, Tags: c.tags, Value: stats.D(now.Sub(sent))}
+			delete(c.inflight, id)
+		}
+
+	case packetPubrec:
+		id, err := p.packetID()
+		if err != nil {
+			return err
+		}
+		return c.write(ackPacket(packetPubrel, id))
+
+	case packetPubrel:
+		id, err := p.packetID()
+		if err != nil {
+			return err
+		}
+		delete(c.received, id)
+		return c.write(ackPacket(packetPubcomp, id))
+
+	case packetSuback:
+		id, err := p.packetID()
+		if err != nil {
+			return err
+		}
+		topics := c.subscriptions[id]
+		delete(c.subscriptions, id)
+		for i, code := range p.body[2:] {
+			if code == 0x80 && i < len(topics) {
+				return errors.Errorf("subscription to '%s' refused by the broker", topics[i])
+			}
+		}
+
+	case packetUnsuback, packetPingresp:
+		// Nothing to do
+
+	default:
+		return errors.Errorf("unexpected MQTT packet of type %d", p.typ)
+	}
+	return nil
+}
+
+// On registers a handler for an event: "connect", "message", "error" or "close".
+func (c *Client) On(event string, handler goja.Value) {
+	if handler, ok := goja.AssertFunction(handler); ok {
+		c.eventHandlers[event] = append(c.eventHandlers[event], handler)
+	}
+}
+
+func (c *Client) handleEvent(event string, args ...goja.Value) {
+	if handlers, ok := c.eventHandlers[event]; ok {
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), args...); err != nil {
+				common.Throw(common.GetRuntime(c.ctx), err)
+			}
+		}
+	}
+}
+
+// Publish sends a message, which can be a string or an array of bytes, to a topic. The optional
+// params can contain the qos (0, 1 or 2) and whether the broker should retain the message.
+func (c *Client) Publish(topic string, payload goja.Value, params goja.Value) {
+	rt := common.GetRuntime(c.ctx)
+	state := common.GetState(c.ctx)
+
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		common.Throw(rt, errors.Errorf("Invalid topic '%s' to publish to, it can't be empty or have wildcards", topic))
+	}
+	m := message{topic: topic, payload: toBytes(payload)}
+	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
+		paramsObj := params.ToObject(rt)
+		if qosV := paramsObj.Get("qos"); qosV != nil && !goja.IsUndefined(qosV) {
+			qos, err := toQoS(qosV)
+			if err != nil {
+				common.Throw(rt, err)
+			}
+			m.qos = qos
+		}
+		if retainV := paramsObj.Get("retain"); retainV != nil {
+			m.retain = retainV.ToBoolean()
+		}
+	}
+	if m.qos > 0 {
+		m.packetID = c.nextPacketID()
+	}
+
+	if err := c.write(publishPacket(m)); err != nil {
+		c.handleEvent("error", rt.ToValue(err))
+		return
+	}
+	now := time.Now()
+	if m.qos > 0 {
+		c.inflight[m.packetID] = now
+	}
+	state.Samples <- stats.Sample{Metric: metrics.MQTTMessagesSent, Time: now, Tags: c.tags, Value: 1}
+}
+
+// Subscribe subscribes to a topic filter, or to an array of them, with the given maximum QoS.
+func (c *Client) Subscribe(topics goja.Value, qosV goja.Value) {
+	rt := common.GetRuntime(c.ctx)
+	filters := toTopics(rt, topics)
+	qos := byte(0)
+	if qosV != nil && !goja.IsUndefined(qosV) {
+		var err error
+		if qos, err = toQoS(qosV); err != nil {
+			common.Throw(rt, err)
+		}
+	}
+
+	id := c.nextPacketID()
+	if err := c.write(subscribePacket(id, filters, qos)); err != nil {
+		c.handleEvent("error", rt.ToValue(err))
+		return
+	}
+	c.subscriptions[id] = filters
+}
+
+// Unsubscribe unsubscribes from a topic filter, or from an array of them.
+func (c *Client) Unsubscribe(topics goja.Value) {
+	rt := common.GetRuntime(c.ctx)
+	if err := c.write(unsubscribePacket(c.nextPacketID(), toTopics(rt, topics))); err != nil {
+		c.handleEvent("error", rt.ToValue(err))
+	}
+}
+
+// SetTimeout calls fn on the event loop after the timeout, unless the connection is closed by then.
+func (c *Client) SetTimeout(fn goja.Callable, timeoutMs int) {
+	go func() {
+		timer := time.NewTimer(time.Duration(timeoutMs) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			c.post(func() error {
+				_, err := fn(goja.Undefined())
+				return err
+			})
+		case <-c.done:
+		}
+	}()
+}
+
+// SetInterval calls fn on the event loop every interval, until the connection is closed.
+func (c *Client) SetInterval(fn goja.Callable, intervalMs int) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.post(func() error {
+					_, err := fn(goja.Undefined())
+					return err
+				})
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() {
+	c.closeConnection(true)
+}
+
+// Closes the connection, if that wasn't done already, and stops the main control loop
+func (c *Client) closeConnection(disconnect bool) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	if disconnect {
+		// DISCONNECT tells the broker not to publish the will
+		_ = c.write(packet{typ: packetDisconnect})
+	}
+	_ = c.conn.Close()
+	c.handleEvent("close")
+	close(c.done)
+}
+
+func (c *Client) write(p packet) error {
+	if len(p.body) > maxRemainingLength {
+		return errors.Errorf("MQTT packet too large: %d bytes", len(p.body))
+	}
+	if c.closed {
+		return errors.New("the MQTT connection is closed")
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	_, err := c.conn.Write(p.encode())
+	return err
+}
+
+// nextPacketID returns an identifier that isn't used by any in-flight packet; 0 isn't valid.
+func (c *Client) nextPacketID() uint16 {
+	for {
+		c.lastPacketID++
+		id := c.lastPacketID
+		if _, ok := c.inflight[id]; id == 0 || ok {
+			continue
+		}
+		if _, ok := c.subscriptions[id]; ok {
+			continue
+		}
+		return id
+	}
+}
+
+// Reads packets in a channel
+func readPump(conn io.Reader, packetChan chan<- packet, errorChan chan<- error, done <-chan struct{}) {
+	reader := bufio.NewReader(conn)
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			select {
+			case errorChan <- err:
+			case <-done:
+			}
+			return
+		}
+		select {
+		case packetChan <- p:
+		case <-done:
+			return
+		}
+	}
+}
+
+// wsConn sends MQTT packets as binary websocket messages, as described by the MQTT spec.
+type wsConn struct {
+	*websocket.Conn
+	reader io.Reader
+}
+
+func (c *wsConn) Read(b []byte) (int, error) {
+	for {
+		if c.reader == nil {
+			_, reader, err := c.NextReader()
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return 0, io.EOF
+			}
+			if err != nil {
+				return 0, err
+			}
+			c.reader = reader
+		}
+		// Packets can span messages, so they are read as one stream
+		n, err := c.reader.Read(b)
+		if err == io.EOF {
+			c.reader = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *wsConn) Write(b []byte) (int, error) {
+	if err := c.WriteMessage(websocket.BinaryMessage, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func toQoS(v goja.Value) (byte, error) {
+	qos := v.ToInteger()
+	if qos < 0 || qos > 2 {
+		return 0, errors.Errorf("Invalid QoS %d, must be 0, 1 or 2", qos)
+	}
+	return byte(qos), nil
+}
+
+func toTopics(rt *goja.Runtime, v goja.Value) []string {
+	var topics []string
+	if topicsV, ok := v.Export().([]interface{}); ok {
+		for _, topic := range topicsV {
+			topics = append(topics, rt.ToValue(topic).String())
+		}
+	} else if v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+		topics = []string{v.String()}
+	}
+	for _, topic := range topics {
+		if topic == "" {
+			common.Throw(rt, errors.New("Invalid topic filter, it can't be empty"))
+		}
+	}
+	if len(topics) == 0 {
+		common.Throw(rt, errors.New("No topic filters given"))
+	}
+	return topics
+}
+
+// toBytes converts a string or an array of bytes (e.g. from open(file, "b")) to bytes.
+func toBytes(v goja.Value) []byte {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	switch data := v.Export().(type) {
+	case []byte:
+		return data
+	case []interface{}:
+		b := make([]byte, len(data))
+		for i, e := range data {
+			n, _ := e.(int64)
+			b[i] = byte(n)
+		}
+		return b
+	default:
+		return []byte(v.String())
+	}
+}
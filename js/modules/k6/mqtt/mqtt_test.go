@@ -0,0 +1,319 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackets(t *testing.T) {
+	for _, size := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		p := publishPacket(message{topic: "a/b", payload: make([]byte, size), qos: 1, packetID: 42, retain: true})
+		decoded, err := readPacket(bufio.NewReader(bytes.NewReader(p.encode())))
+		require.NoError(t, err)
+		assert.Equal(t, p, decoded)
+
+		m, err := decoded.message()
+		require.NoError(t, err)
+		assert.Equal(t, message{topic: "a/b", payload: make([]byte, size), qos: 1, packetID: 42, retain: true}, m)
+	}
+
+	_, err := readPacket(bufio.NewReader(bytes.NewReader([]byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x7f})))
+	assert.EqualError(t, err, "malformed MQTT packet: the remaining length is too long")
+	_, err = packet{typ: packetPublish, flags: 0x06, body: []byte{0, 1, 'a'}}.message()
+	assert.EqualError(t, err, "malformed MQTT PUBLISH packet: invalid QoS 3")
+}
+
+// topicMatches tells if a topic matches a subscription's filter, with + and # wildcards.
+func topicMatches(filter, topic string) bool {
+	filterLevels, topicLevels := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// serveMQTT is a minimal broker that sends published messages back to the client, if it's
+// subscribed to them. It refuses the password "wrong" and subscriptions to "forbidden".
+func serveMQTT(conn transport) {
+	defer func() { _ = conn.Close() }()
+	write := func(p packet) { _, _ = conn.Write(p.encode()) }
+
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil || p.typ != packetConnect {
+		return
+	}
+	if bytes.HasSuffix(p.body, []byte("wrong")) {
+		write(packet{typ: packetConnack, body: []byte{0, 4}})
+		return
+	}
+	write(packet{typ: packetConnack, body: []byte{0, 0}})
+
+	subscriptions := map[string]byte{}
+	var lastID uint16
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.typ {
+		case packetSubscribe:
+			id, _ := p.packetID()
+			codes := appendUint16(nil, id)
+			for body := p.body[2:]; len(body) > 0; {
+				n := int(body[0])<<8 | int(body[1])
+				topic, qos := string(body[2:2+n]), body[2+n]
+				body = body[3+n:]
+				if topic == "forbidden" {
+					codes = append(codes, 0x80)
+					continue
+				}
+				subscriptions[topic] = qos
+				codes = append(codes, qos)
+			}
+			write(packet{typ: packetSuback, body: codes})
+		case packetPublish:
+			m, _ := p.message()
+			switch m.qos {
+			case 1:
+				write(ackPacket(packetPuback, m.packetID))
+			case 2:
+				write(ackPacket(packetPubrec, m.packetID))
+			}
+			for filter, qos := range subscriptions {
+				if topicMatches(filter, m.topic) {
+					forwarded := m
+					if qos < forwarded.qos {
+						forwarded.qos = qos
+					}
+					lastID++
+					forwarded.packetID = lastID
+					write(publishPacket(forwarded))
+				}
+			}
+		case packetPubrec:
+			id, _ := p.packetID()
+			write(ackPacket(packetPubrel, id))
+		case packetPubrel:
+			id, _ := p.packetID()
+			write(ackPacket(packetPubcomp, id))
+		case packetPingreq:
+			write(packet{typ: packetPingresp})
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func TestConnect(t *testing.T) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = tcpListener.Close() }()
+	go func() {
+		for {
+			conn, err := tcpListener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMQTT(conn)
+		}
+	}()
+
+	wsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{Subprotocols: []string{"mqtt"}}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		serveMQTT(&wsConn{Conn: conn})
+	}))
+	defer wsServer.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:  root,
+		Dialer: netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "group"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("mqtt", common.Bind(rt, New(), &ctx))
+	rt.Set("TCP_URL", "tcp://"+tcpListener.Addr().String())
+	rt.Set("WS_URL", "ws"+strings.TrimPrefix(wsServer.URL, "http"))
+
+	t.Run("init context", func(t *testing.T) {
+		_, err := common.RunString(rt, `mqtt.connect(TCP_URL, function(client) {})`)
+		assert.EqualError(t, err, "GoError: MQTT connections can't be made in the init context")
+	})
+
+	ctx = common.WithState(ctx, state)
+
+	for _, url := range []string{"TCP_URL", "WS_URL"} {
+		t.Run(url, func(t *testing.T) {
+			_, err := common.RunString(rt, `
+			let received = [], connected = false, closed = false;
+			let will = { topic: "devices/1/status", payload: "offline", qos: 1, retain: true };
+			mqtt.connect(`+url+`, { clientId: "device-1", keepAlive: 1, will: will }, function(client) {
+				client.on("connect", function() {
+					connected = true;
+					client.subscribe(["devices/+/cmd", "other"], 2);
+					client.publish("devices/1/cmd", "qos0");
+					client.publish("devices/1/cmd", "qos1", { qos: 1 });
+					client.publish("devices/1/cmd", [113, 111, 115, 50], { qos: 2 });
+					client.publish("devices/1/telemetry", "not subscribed");
+				});
+				client.on("message", function(msg) {
+					received.push(msg.topic + "=" + msg.payload + "@" + msg.qos);
+					if (received.length == 3) {
+						// Leave time for the last acks
+						client.setTimeout(function() { client.close(); }, 50);
+					}
+				});
+				client.on("close", function() { closed = true; });
+				client.on("error", function(e) { throw new Error("unexpected error: " + e); });
+			});
+			if (!connected || !closed) { throw new Error("wasn't connected and closed"); }
+			if (received.join(",") !== "devices/1/cmd=qos0@0,devices/1/cmd=qos1@1,devices/1/cmd=qos2@2") {
+				throw new Error("wrong messages: " + received);
+			}
+			`)
+			assert.NoError(t, err)
+
+			seen := map[string]float64{}
+			for _, sc := range stats.GetBufferedSamples(samples) {
+				for _, sample := range sc.GetSamples() {
+					assert.Equal(t, rt.Get(url).String(), sample.Tags.CloneTags()["url"])
+					if sample.Metric.Type == stats.Counter {
+						seen[sample.Metric.Name] += sample.Value
+					} else {
+						seen[sample.Metric.Name]++
+					}
+				}
+			}
+			assert.Equal(t, map[string]float64{
+				metrics.MQTTSessions.Name:         1,
+				metrics.MQTTConnecting.Name:       1,
+				metrics.MQTTSessionDuration.Name:  1,
+				metrics.MQTTMessagesSent.Name:     4,
+				metrics.MQTTMessagesReceived.Name: 3,
+				metrics.MQTTPublishDuration.Name:  2,
+			}, seen)
+		})
+	}
+
+	t.Run("refused", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let error = null;
+		try {
+			mqtt.connect(TCP_URL, { username: "user", password: "wrong" }, function(client) {
+				client.on("error", function(e) { error = e; });
+			});
+		} catch (e) {
+			if (!error) { throw new Error("the error handler wasn't called"); }
+			throw e;
+		}
+		`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "GoError: MQTT connection refused: bad user name or password")
+		}
+	})
+
+	t.Run("forbidden subscription", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let error = null;
+		mqtt.connect(TCP_URL, function(client) {
+			client.on("connect", function() { client.subscribe("forbidden", 1); });
+			client.on("error", function(e) { error = e; client.close(); });
+		});
+		if (!error) { throw new Error("no error"); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		testdata := map[string]string{
+			`mqtt.connect("http://localhost", function(client) {})`:                                                  "Unsupported MQTT URL scheme 'http', must be tcp, ssl, ws or wss",
+			`mqtt.connect(TCP_URL, { keepAlive: 70000 }, function(client) {})`:                                       "Invalid keepAlive 70000, must be between 0 and 65535 seconds",
+			`mqtt.connect(TCP_URL, function(c) { c.on("connect", function() { c.publish("a/#"); }) })`:               "Invalid topic 'a/#' to publish to",
+			`mqtt.connect(TCP_URL, function(c) { c.on("connect", function() { c.publish("a", "", { qos: 3 }); }) })`: "Invalid QoS 3, must be 0, 1 or 2",
+		}
+		for code, msg := range testdata {
+			_, err := common.RunString(rt, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+
+	t.Run("event loop", func(t *testing.T) {
+		loop := common.NewEventLoop(rt)
+		loop.Bind()
+		ctx = common.WithEventLoop(ctx, loop)
+
+		_, err := common.RunString(rt, `
+		let ticks = 0;
+		let heartbeat = setInterval(function() { ticks++; }, 1);
+		mqtt.connect(TCP_URL, function(client) {
+			client.on("connect", function() { setTimeout(function() { client.close(); }, 20); });
+			client.on("error", function(e) { throw new Error("unexpected error: " + e); });
+		});
+		clearInterval(heartbeat);
+		if (ticks < 2) { throw new Error("the global timers didn't run: " + ticks); }
+		`)
+		assert.NoError(t, err)
+		assert.NoError(t, loop.Run(context.Background()))
+		stats.GetBufferedSamples(samples)
+	})
+}
@@ -0,0 +1,251 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Control packet types, from the MQTT 3.1.1 spec.
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+const maxRemainingLength = 268435455
+
+// A packet is an MQTT control packet, with its fixed header split into type and flags.
+type packet struct {
+	typ   byte
+	flags byte
+	body  []byte
+}
+
+// connectOptions are the fields of a CONNECT packet.
+type connectOptions struct {
+	clientID     string
+	username     string
+	password     string
+	hasUsername  bool
+	hasPassword  bool
+	cleanSession bool
+	keepAlive    uint16 // In seconds.
+
+	willTopic   string
+	willPayload []byte
+	willQoS     byte
+	willRetain  bool
+}
+
+// A message is the content of a PUBLISH packet.
+type message struct {
+	topic    string
+	payload  []byte
+	qos      byte
+	retain   bool
+	dup      bool
+	packetID uint16
+}
+
+// encode returns the packet with its fixed header, ready to be written.
+func (p packet) encode() []byte {
+	b := make([]byte, 1, 5+len(p.body))
+	b[0] = p.typ<<4 | p.flags
+	n := len(p.body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	return append(b, p.body...)
+}
+
+// readPacket reads the next packet from r.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return packet{}, errors.New("malformed MQTT packet: the remaining length is too long")
+		}
+		multiplier *= 128
+	}
+	p := packet{typ: header >> 4, flags: header & 0x0f, body: make([]byte, length)}
+	if _, err := io.ReadFull(r, p.body); err != nil {
+		return packet{}, err
+	}
+	return p, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b, s []byte) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func connectPacket(o connectOptions) packet {
+	flags := byte(0)
+	if o.cleanSession {
+		flags |= 0x02
+	}
+	if o.willTopic != "" {
+		flags |= 0x04 | o.willQoS<<3
+		if o.willRetain {
+			flags |= 0x20
+		}
+	}
+	if o.hasPassword {
+		flags |= 0x40
+	}
+	if o.hasUsername {
+		flags |= 0x80
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 is MQTT 3.1.1
+	body = appendUint16(body, o.keepAlive)
+	body = appendString(body, o.clientID)
+	if o.willTopic != "" {
+		body = appendString(body, o.willTopic)
+		body = appendBytes(body, o.willPayload)
+	}
+	if o.hasUsername {
+		body = appendString(body, o.username)
+	}
+	if o.hasPassword {
+		body = appendString(body, o.password)
+	}
+	return packet{typ: packetConnect, body: body}
+}
+
+func publishPacket(m message) packet {
+	flags := m.qos << 1
+	if m.retain {
+		flags |= 0x01
+	}
+	if m.dup {
+		flags |= 0x08
+	}
+	body := appendString(nil, m.topic)
+	if m.qos > 0 {
+		body = appendUint16(body, m.packetID)
+	}
+	return packet{typ: packetPublish, flags: flags, body: append(body, m.payload...)}
+}
+
+// ackPacket returns a PUBACK, PUBREC, PUBREL, PUBCOMP or UNSUBACK packet.
+func ackPacket(typ byte, packetID uint16) packet {
+	flags := byte(0)
+	if typ == packetPubrel {
+		flags = 0x02
+	}
+	return packet{typ: typ, flags: flags, body: appendUint16(nil, packetID)}
+}
+
+func subscribePacket(packetID uint16, topics []string, qos byte) packet {
+	body := appendUint16(nil, packetID)
+	for _, topic := range topics {
+		body = appendString(body, topic)
+		body = append(body, qos)
+	}
+	return packet{typ: packetSubscribe, flags: 0x02, body: body}
+}
+
+func unsubscribePacket(packetID uint16, topics []string) packet {
+	body := appendUint16(nil, packetID)
+	for _, topic := range topics {
+		body = appendString(body, topic)
+	}
+	return packet{typ: packetUnsubscribe, flags: 0x02, body: body}
+}
+
+// packetID returns the packet identifier at the start of the variable header of acks.
+func (p packet) packetID() (uint16, error) {
+	if len(p.body) < 2 {
+		return 0, errors.Errorf("malformed MQTT packet of type %d: it's missing the packet identifier", p.typ)
+	}
+	return binary.BigEndian.Uint16(p.body), nil
+}
+
+// message parses a PUBLISH packet.
+func (p packet) message() (message, error) {
+	m := message{qos: (p.flags >> 1) & 0x03, retain: p.flags&0x01 != 0, dup: p.flags&0x08 != 0}
+	if m.qos > 2 {
+		return m, errors.New("malformed MQTT PUBLISH packet: invalid QoS 3")
+	}
+	if len(p.body) < 2 {
+		return m, errors.New("malformed MQTT PUBLISH packet: it's missing the topic")
+	}
+	n := int(binary.BigEndian.Uint16(p.body))
+	rest := p.body[2:]
+	if len(rest) < n {
+		return m, errors.New("malformed MQTT PUBLISH packet: the topic is truncated")
+	}
+	m.topic, rest = string(rest[:n]), rest[n:]
+	if m.qos > 0 {
+		if len(rest) < 2 {
+			return m, errors.New("malformed MQTT PUBLISH packet: it's missing the packet identifier")
+		}
+		m.packetID, rest = binary.BigEndian.Uint16(rest), rest[2:]
+	}
+	m.payload = rest
+	return m, nil
+}
@@ -0,0 +1,244 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package net
+
+import (
+	"context"
+	"io"
+	gonet "net"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout  = 60 * time.Second
+	defaultReadSize = 4096
+)
+
+type Net struct{}
+
+func New() *Net {
+	return &Net{}
+}
+
+// Conn is a raw TCP or UDP connection, as returned by net.connect().
+type Conn struct {
+	ctx     context.Context
+	conn    gonet.Conn
+	timeout time.Duration
+	tags    *stats.SampleTags
+	done    chan struct{}
+
+	// When the last write that wasn't yet answered by a read happened, for the RTT metric.
+	lastWrite time.Time
+}
+
+// Connect opens a TCP or UDP connection to addr ("host:port"). The optional params can contain a
+// timeout in milliseconds, which applies to connecting and to every read and write, and tags.
+func (*Net) Connect(ctx context.Context, network, addr string, params goja.Value) (*Conn, error) {
+	rt := common.GetRuntime(ctx)
+	state := common.GetState(ctx)
+	if state == nil {
+		return nil, errors.New("Connections can't be made in the init context")
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+	default:
+		return nil, errors.Errorf("Unsupported network '%s', must be tcp or udp", network)
+	}
+
+	timeout := defaultTimeout
+	tags := state.Options.RunTags.CloneTags()
+	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
+		params := params.ToObject(rt)
+		for _, k := range params.Keys() {
+			switch k {
+			case "timeout":
+				timeout = time.Duration(params.Get(k).ToFloat() * float64(time.Millisecond))
+			case "tags":
+				tagsV := params.Get(k)
+				if goja.IsUndefined(tagsV) || goja.IsNull(tagsV) {
+					continue
+				}
+				tagObj := tagsV.ToObject(rt)
+				if tagObj == nil {
+					continue
+				}
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+
+	if state.Options.SystemTags["proto"] {
+		tags["proto"] = network
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+	if state.Options.SystemTags["vu"] {
+		tags["vu"] = strconv.FormatInt(state.Vu, 10)
+	}
+	if state.Options.SystemTags["iter"] {
+		tags["iter"] = strconv.FormatInt(state.Iteration, 10)
+	}
+
+	dialCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	conn, err := state.Dialer.DialContext(dialCtx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	connectionDuration := stats.D(time.Since(start))
+
+	state.Options.SystemTags.SetRemoteAddrTags(tags, conn.RemoteAddr())
+
+	sampleTags := stats.IntoSampleTags(&tags)
+	state.Samples <- stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.NetConnections, Time: start, Tags: sampleTags, Value: 1},
+			{Metric: metrics.NetConnecting, Time: start, Tags: sampleTags, Value: connectionDuration},
+		},
+		Tags: sampleTags,
+		Time: start,
+	}
+
+	c := &Conn{ctx: ctx, conn: conn, timeout: timeout, tags: sampleTags, done: make(chan struct{})}
+
+	// Don't leave connections open past the end of the test.
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-c.done:
+		}
+	}()
+
+	return c, nil
+}
+
+// Write sends data, which can be a string or an array of bytes (e.g. from open(file, "b")), and
+// returns the number of bytes written.
+func (c *Conn) Write(data goja.Value) int {
+	rt := common.GetRuntime(c.ctx)
+
+	var b []byte
+	switch v := data.Export().(type) {
+	case []byte:
+		b = v
+	case []interface{}:
+		b = make([]byte, len(v))
+		for i, e := range v {
+			n, ok := e.(int64)
+			if !ok || n < 0 || n > 255 {
+				common.Throw(rt, errors.Errorf("Invalid byte at index %d: %v", i, e))
+			}
+			b[i] = byte(n)
+		}
+	default:
+		b = []byte(data.String())
+	}
+
+	c.setDeadline()
+	n, err := c.conn.Write(b)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	if c.lastWrite.IsZero() {
+		c.lastWrite = time.Now()
+	}
+	return n
+}
+
+// Read waits for data and returns up to size bytes of it (4096 by default). Like open(), it
+// returns a string, or an array of bytes if "b" is passed as the second argument. An empty
+// result means that the connection was closed by the other side.
+func (c *Conn) Read(size int, args ...string) goja.Value {
+	rt := common.GetRuntime(c.ctx)
+	if size <= 0 {
+		size = defaultReadSize
+	}
+
+	c.setDeadline()
+	buf := make([]byte, size)
+	n, err := c.conn.Read(buf)
+	if err != nil && !(err == io.EOF && n == 0) {
+		common.Throw(rt, err)
+	}
+	buf = buf[:n]
+
+	if n > 0 && !c.lastWrite.IsZero() {
+		now := time.Now()
+		state := common.GetState(c.ctx)
+		state.Samples <- stats.Sample{
+			Metric: metrics.NetRTT,
+			Time:   now,
+			Tags:   c.tags,
+			Value:  stats.D(now.Sub(c.lastWrite)),
+		}
+		c.lastWrite = time.Time{}
+	}
+
+	if len(args) > 0 && args[0] == "b" {
+		return rt.ToValue(buf)
+	}
+	return rt.ToValue(string(buf))
+}
+
+// SetTimeout changes the timeout for subsequent reads and writes, in milliseconds; 0 disables it.
+func (c *Conn) SetTimeout(timeoutMs float64) {
+	c.timeout = time.Duration(timeoutMs * float64(time.Millisecond))
+}
+
+// Close closes the connection; closing it more than once does nothing.
+func (c *Conn) Close() {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	close(c.done)
+	if err := c.conn.Close(); err != nil {
+		common.Throw(common.GetRuntime(c.ctx), err)
+	}
+}
+
+func (c *Conn) setDeadline() {
+	var deadline time.Time
+	if c.timeout > 0 {
+		deadline = time.Now().Add(c.timeout)
+	}
+	_ = c.conn.SetDeadline(deadline)
+}
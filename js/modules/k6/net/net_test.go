@@ -0,0 +1,184 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package net
+
+import (
+	"context"
+	"io"
+	gonet "net"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnect(t *testing.T) {
+	tcpListener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = tcpListener.Close() }()
+	go func() {
+		for {
+			conn, err := tcpListener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+
+	udpConn, err := gonet.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = udpConn.Close() }()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := udpConn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = udpConn.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	dialer := netext.NewDialer(gonet.Dialer{Timeout: 10 * time.Second})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:  root,
+		Dialer: dialer,
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("proto", "ip", "port"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = common.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+
+	rt.Set("net", common.Bind(rt, New(), &ctx))
+	rt.Set("TCP_ADDR", tcpListener.Addr().String())
+	rt.Set("UDP_ADDR", udpConn.LocalAddr().String())
+
+	_, tcpPort, err := gonet.SplitHostPort(tcpListener.Addr().String())
+	require.NoError(t, err)
+
+	t.Run("tcp", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = net.connect("tcp", TCP_ADDR, { tags: { tag: "value" } });
+		if (conn.write("hello") !== 5) { throw new Error("wrong number of written bytes"); }
+		let data = conn.read();
+		if (data !== "hello") { throw new Error("wrong data: " + data); }
+
+		conn.write([0, 1, 255]);
+		data = conn.read(10, "b");
+		if (data.length !== 3 || data[0] !== 0 || data[1] !== 1 || data[2] !== 255) {
+			throw new Error("wrong binary data: " + JSON.stringify(data));
+		}
+		conn.close();
+		conn.close();
+		`)
+		assert.NoError(t, err)
+
+		var seenConnecting, seenConnections, seenRTT int
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, sample := range sc.GetSamples() {
+				switch sample.Metric {
+				case metrics.NetConnecting:
+					seenConnecting++
+				case metrics.NetConnections:
+					seenConnections++
+				case metrics.NetRTT:
+					seenRTT++
+				default:
+					continue
+				}
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, map[string]string{"proto": "tcp", "ip": "127.0.0.1", "port": tcpPort, "tag": "value"}, tags)
+			}
+		}
+		assert.Equal(t, 1, seenConnecting)
+		assert.Equal(t, 1, seenConnections)
+		assert.Equal(t, 2, seenRTT)
+	})
+
+	t.Run("udp", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = net.connect("udp", UDP_ADDR);
+		conn.write("ping");
+		let data = conn.read();
+		if (data !== "ping") { throw new Error("wrong data: " + data); }
+		conn.close();
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("read timeout", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let conn = net.connect("udp", UDP_ADDR, { timeout: 1 });
+		conn.read();
+		`)
+		assert.Contains(t, err.Error(), "i/o timeout")
+	})
+
+	t.Run("invalid byte", func(t *testing.T) {
+		_, err := common.RunString(rt, `net.connect("tcp", TCP_ADDR).write([256]);`)
+		assert.Contains(t, err.Error(), "Invalid byte at index 0: 256")
+	})
+
+	t.Run("unsupported network", func(t *testing.T) {
+		_, err := common.RunString(rt, `net.connect("unix", "/tmp/k6.sock");`)
+		assert.Contains(t, err.Error(), "Unsupported network 'unix', must be tcp or udp")
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		l, err := gonet.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		_, err = common.RunString(rt, `net.connect("tcp", "`+addr+`");`)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+
+	t.Run("init context", func(t *testing.T) {
+		initCtx := common.WithRuntime(context.Background(), rt)
+		rt.Set("net", common.Bind(rt, New(), &initCtx))
+		defer rt.Set("net", common.Bind(rt, New(), &ctx))
+
+		_, err := common.RunString(rt, `net.connect("tcp", TCP_ADDR);`)
+		assert.Contains(t, err.Error(), "Connections can't be made in the init context")
+	})
+}
@@ -0,0 +1,106 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package redis
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const slotCount = 16384
+
+// Commands that don't have a key as their first argument, so they can go to any node.
+var keylessCommands = map[string]bool{
+	"PING": true, "ECHO": true, "INFO": true, "TIME": true, "DBSIZE": true, "FLUSHDB": true,
+	"FLUSHALL": true, "CLUSTER": true, "CONFIG": true, "CLIENT": true, "SCRIPT": true,
+	"AUTH": true, "SELECT": true, "RANDOMKEY": true, "SCAN": true, "KEYS": true, "WAIT": true,
+}
+
+// keySlot returns the cluster slot of a key. Only the part between the first { and the next } is
+// hashed, if there's one, so that related keys can be put in the same slot with "hash tags".
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// crc16 is the CRC-16/XMODEM checksum, which Redis Cluster uses for key slots.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// commandSlot returns the slot of a command's key, or -1 if it doesn't have one.
+func commandSlot(args [][]byte) int {
+	if len(args) < 2 || keylessCommands[strings.ToUpper(string(args[0]))] {
+		return -1
+	}
+	return keySlot(string(args[1]))
+}
+
+// parseSlots parses the reply to CLUSTER SLOTS, sent to the node at from, into the address of the
+// master of every slot.
+func parseSlots(reply interface{}, from string) ([]string, error) {
+	ranges, ok := reply.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected reply to CLUSTER SLOTS: %v", reply)
+	}
+	slots := make([]string, slotCount)
+	for _, r := range ranges {
+		fields, ok := r.([]interface{})
+		if !ok || len(fields) < 3 {
+			return nil, errors.Errorf("unexpected slot range in CLUSTER SLOTS: %v", r)
+		}
+		start, ok1 := fields[0].(int64)
+		end, ok2 := fields[1].(int64)
+		master, ok3 := fields[2].([]interface{})
+		if !ok1 || !ok2 || !ok3 || len(master) < 2 || start < 0 || end >= slotCount || start > end {
+			return nil, errors.Errorf("unexpected slot range in CLUSTER SLOTS: %v", r)
+		}
+		host, _ := master[0].(string)
+		if host == "" || host == "?" {
+			// Nodes that don't know their own address report it like this
+			host, _, _ = net.SplitHostPort(from)
+		}
+		port, _ := master[1].(int64)
+		addr := net.JoinHostPort(host, strconv.FormatInt(port, 10))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = addr
+		}
+	}
+	return slots, nil
+}
@@ -0,0 +1,607 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	maxRedirects   = 5
+)
+
+type Redis struct{}
+
+func New() *Redis {
+	return &Redis{}
+}
+
+// Client is a Redis client, as returned by `new redis.Client(options)`. It keeps a connection to
+// every node it talks to, which is reused across iterations.
+type Client struct {
+	ctx *context.Context
+
+	addrs       []string
+	username    string
+	password    string
+	hasUsername bool
+	db          int
+	cluster     bool
+	useTLS      bool
+	timeout     time.Duration
+	tags        map[string]string
+
+	conns map[string]*conn
+	slots []string // The master of every slot, in cluster mode; fetched on first use.
+}
+
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	done   chan struct{}
+}
+
+// XClient creates a client from either a URL (redis:// or rediss://) or an object with addrs,
+// username, password, db, cluster, tls, timeout (in milliseconds) and tags. Clients can be
+// created in the init context; they only connect when they are first used.
+func (*Redis) XClient(ctxPtr *context.Context, options goja.Value) (*Client, error) {
+	rt := common.GetRuntime(*ctxPtr)
+	c := &Client{
+		ctx:     ctxPtr,
+		timeout: defaultTimeout,
+		tags:    make(map[string]string),
+		conns:   make(map[string]*conn),
+	}
+
+	if options == nil || goja.IsUndefined(options) || goja.IsNull(options) {
+		return nil, errors.New("Redis client options are required")
+	}
+	if _, ok := options.Export().(string); ok {
+		if err := c.parseURL(options.String()); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	params := options.ToObject(rt)
+	for _, k := range params.Keys() {
+		v := params.Get(k)
+		if goja.IsUndefined(v) || goja.IsNull(v) {
+			continue
+		}
+		switch k {
+		case "url":
+			if err := c.parseURL(v.String()); err != nil {
+				return nil, err
+			}
+		case "addrs":
+			var addrs []string
+			if err := rt.ExportTo(v, &addrs); err != nil {
+				return nil, errors.Wrap(err, "addrs must be an array of host:port strings")
+			}
+			c.addrs = append(c.addrs, addrs...)
+		case "username":
+			c.username, c.hasUsername = v.String(), true
+		case "password":
+			c.password = v.String()
+		case "db":
+			c.db = int(v.ToInteger())
+		case "cluster":
+			c.cluster = v.ToBoolean()
+		case "tls":
+			c.useTLS = v.ToBoolean()
+		case "timeout":
+			c.timeout = time.Duration(v.ToFloat() * float64(time.Millisecond))
+		case "tags":
+			tagObj := v.ToObject(rt)
+			for _, key := range tagObj.Keys() {
+				c.tags[key] = tagObj.Get(key).String()
+			}
+		}
+	}
+	if len(c.addrs) == 0 {
+		return nil, errors.New("Redis client options must have either a url or addrs")
+	}
+	if c.cluster && c.db != 0 {
+		return nil, errors.New("Redis Cluster only supports db 0")
+	}
+	return c, nil
+}
+
+func (c *Client) parseURL(s string) error {
+	u, err := neturl.Parse(s)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		c.useTLS = true
+	default:
+		return errors.Errorf("Unsupported Redis URL scheme '%s', must be redis or rediss", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	c.addrs = append(c.addrs, addr)
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			c.password = password
+			if name := u.User.Username(); name != "" {
+				c.username, c.hasUsername = name, true
+			}
+		} else {
+			c.password = u.User.Username()
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if c.db, err = strconv.Atoi(path); err != nil {
+			return errors.Errorf("Invalid Redis database '%s' in the URL", path)
+		}
+	}
+	return nil
+}
+
+// Do runs any command, e.g. `client.do("ZADD", "scores", 1, "a")`, and returns its reply.
+func (c *Client) Do(command string, args ...goja.Value) (interface{}, error) {
+	return c.run(command, args...)
+}
+
+// Get returns the value of a key, or null if there's no such key.
+func (c *Client) Get(key string) (interface{}, error) {
+	return c.run("GET", common.GetRuntime(*c.ctx).ToValue(key))
+}
+
+// Set sets the value of a key, optionally expiring it after the given number of seconds.
+func (c *Client) Set(key string, value goja.Value, expiration int64) (interface{}, error) {
+	rt := common.GetRuntime(*c.ctx)
+	if expiration > 0 {
+		return c.run("SET", rt.ToValue(key), value, rt.ToValue("EX"), rt.ToValue(expiration))
+	}
+	return c.run("SET", rt.ToValue(key), value)
+}
+
+// Del deletes keys, and returns how many of them existed.
+func (c *Client) Del(keys ...goja.Value) (interface{}, error) {
+	return c.run("DEL", keys...)
+}
+
+// Exists returns how many of the keys exist.
+func (c *Client) Exists(keys ...goja.Value) (interface{}, error) {
+	return c.run("EXISTS", keys...)
+}
+
+// Expire sets a key to expire after the given number of seconds.
+func (c *Client) Expire(key string, seconds int64) (interface{}, error) {
+	rt := common.GetRuntime(*c.ctx)
+	return c.run("EXPIRE", rt.ToValue(key), rt.ToValue(seconds))
+}
+
+// Incr increments a number, and returns the new value.
+func (c *Client) Incr(key string) (interface{}, error) {
+	return c.run("INCR", common.GetRuntime(*c.ctx).ToValue(key))
+}
+
+// IncrBy increments a number by the given amount, and returns the new value.
+func (c *Client) IncrBy(key string, increment int64) (interface{}, error) {
+	rt := common.GetRuntime(*c.ctx)
+	return c.run("INCRBY", rt.ToValue(key), rt.ToValue(increment))
+}
+
+// Decr decrements a number, and returns the new value.
+func (c *Client) Decr(key string) (interface{}, error) {
+	return c.run("DECR", common.GetRuntime(*c.ctx).ToValue(key))
+}
+
+// Hget returns the value of a field of a hash, or null.
+func (c *Client) Hget(key, field string) (interface{}, error) {
+	rt := common.GetRuntime(*c.ctx)
+	return c.run("HGET", rt.ToValue(key), rt.ToValue(field))
+}
+
+// Hset sets the value of a field of a hash.
+func (c *Client) Hset(key, field string, value goja.Value) (interface{}, error) {
+	rt := common.GetRuntime(*c.ctx)
+	return c.run("HSET", rt.ToValue(key), rt.ToValue(field), value)
+}
+
+// Hgetall returns all of the fields of a hash, as an object.
+func (c *Client) Hgetall(key string) (map[string]interface{}, error) {
+	reply, err := c.run("HGETALL", common.GetRuntime(*c.ctx).ToValue(key))
+	if err != nil {
+		return nil, err
+	}
+	values, _ := reply.([]interface{})
+	fields := make(map[string]interface{}, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := values[i].(string)
+		fields[name] = values[i+1]
+	}
+	return fields, nil
+}
+
+// Lpush prepends values to a list, and returns its new length.
+func (c *Client) Lpush(key string, values ...goja.Value) (interface{}, error) {
+	return c.run("LPUSH", append([]goja.Value{common.GetRuntime(*c.ctx).ToValue(key)}, values...)...)
+}
+
+// Rpop removes and returns the last value of a list, or null if it's empty.
+func (c *Client) Rpop(key string) (interface{}, error) {
+	return c.run("RPOP", common.GetRuntime(*c.ctx).ToValue(key))
+}
+
+// Llen returns the length of a list.
+func (c *Client) Llen(key string) (interface{}, error) {
+	return c.run("LLEN", common.GetRuntime(*c.ctx).ToValue(key))
+}
+
+// Pipeline sends several commands, each an array like `["SET", "key", "value"]`, without waiting
+// for the replies in between, and returns their replies. It throws if any of them failed.
+func (c *Client) Pipeline(commands [][]goja.Value) ([]interface{}, error) {
+	if len(commands) == 0 {
+		return []interface{}{}, nil
+	}
+	cmds := make([][][]byte, len(commands))
+	for i, command := range commands {
+		if len(command) == 0 {
+			return nil, errors.Errorf("Command %d of the pipeline is empty", i+1)
+		}
+		cmds[i] = toArgs(command[0].String(), command[1:])
+	}
+
+	start := time.Now()
+	replies, err := c.pipeline(cmds)
+	c.emitSamples(start, "pipeline")
+	if err != nil {
+		return nil, err
+	}
+	for i, reply := range replies {
+		if e, ok := reply.(replyError); ok {
+			return nil, errors.Errorf("Command %d of the pipeline (%s) failed: %s", i+1, cmds[i][0], e)
+		}
+	}
+	return replies, nil
+}
+
+// Close closes all the connections of the client; it reconnects if it's used again.
+func (c *Client) Close() {
+	for addr, cn := range c.conns {
+		cn.close()
+		delete(c.conns, addr)
+	}
+}
+
+// run runs a command, emits its metrics and turns error replies into errors.
+func (c *Client) run(command string, args ...goja.Value) (interface{}, error) {
+	if command == "" {
+		return nil, errors.New("A Redis command is required")
+	}
+	start := time.Now()
+	reply, err := c.do(toArgs(command, args))
+	c.emitSamples(start, strings.ToLower(command))
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(replyError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+func (c *Client) emitSamples(start time.Time, command string) {
+	state := common.GetState(*c.ctx)
+	if state == nil {
+		return
+	}
+	tags := state.Options.RunTags.CloneTags()
+	for k, v := range c.tags {
+		tags[k] = v
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+	tags["command"] = command
+	sampleTags := stats.IntoSampleTags(&tags)
+
+	state.Samples <- stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.RedisCommands, Time: start, Tags: sampleTags, Value: 1},
+			{Metric: metrics.RedisCommandDuration, Time: start, Tags: sampleTags, Value: stats.D(time.Since(start))},
+		},
+		Tags: sampleTags,
+		Time: start,
+	}
+}
+
+// do sends a command to the right node, following cluster redirections, and returns its reply.
+func (c *Client) do(args [][]byte) (interface{}, error) {
+	addr, err := c.nodeFor(commandSlot(args))
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for i := 0; i <= maxRedirects; i++ {
+		cmds := [][][]byte{args}
+		if asking {
+			cmds = [][][]byte{{[]byte("ASKING")}, args}
+		}
+		replies, err := c.roundTrip(addr, cmds)
+		if err != nil {
+			return nil, err
+		}
+		reply := replies[len(replies)-1]
+
+		e, ok := reply.(replyError)
+		if !ok || !c.cluster {
+			return reply, nil
+		}
+		kind, slot, to, ok := e.redirect()
+		if !ok {
+			return reply, nil
+		}
+		// MOVED means that the slot has a new master, ASK that it's moving to it.
+		if kind == "MOVED" {
+			c.slots[slot] = to
+		}
+		addr, asking = to, kind == "ASK"
+	}
+	return nil, errors.Errorf("Too many Redis Cluster redirections for %s", args[0])
+}
+
+// pipeline sends commands in batches, one for each node, and returns their replies in order.
+func (c *Client) pipeline(cmds [][][]byte) ([]interface{}, error) {
+	var order []string
+	batches := make(map[string][]int)
+	for i, args := range cmds {
+		addr, err := c.nodeFor(commandSlot(args))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := batches[addr]; !ok {
+			order = append(order, addr)
+		}
+		batches[addr] = append(batches[addr], i)
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for _, addr := range order {
+		batch := make([][][]byte, len(batches[addr]))
+		for j, i := range batches[addr] {
+			batch[j] = cmds[i]
+		}
+		batchReplies, err := c.roundTrip(addr, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range batches[addr] {
+			replies[i] = batchReplies[j]
+		}
+	}
+
+	// Commands for slots that changed hands are retried one by one
+	if c.cluster {
+		for i, reply := range replies {
+			if e, ok := reply.(replyError); ok {
+				if _, _, _, ok := e.redirect(); ok {
+					var err error
+					if replies[i], err = c.do(cmds[i]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return replies, nil
+}
+
+// nodeFor returns the address of the node that commands for the slot should be sent to.
+func (c *Client) nodeFor(slot int) (string, error) {
+	if !c.cluster {
+		return c.addrs[0], nil
+	}
+	if c.slots == nil {
+		var lastErr error
+		for _, addr := range c.addrs {
+			replies, err := c.roundTrip(addr, [][][]byte{{[]byte("CLUSTER"), []byte("SLOTS")}})
+			if err == nil {
+				if e, ok := replies[0].(replyError); ok {
+					err = e
+				} else {
+					c.slots, err = parseSlots(replies[0], addr)
+				}
+			}
+			if err == nil {
+				break
+			}
+			lastErr = err
+		}
+		if c.slots == nil {
+			return "", errors.Wrap(lastErr, "couldn't get the Redis Cluster slots")
+		}
+	}
+	if slot < 0 || c.slots[slot] == "" {
+		return c.addrs[0], nil
+	}
+	return c.slots[slot], nil
+}
+
+// roundTrip writes commands to a node and reads their replies.
+func (c *Client) roundTrip(addr string, cmds [][][]byte) ([]interface{}, error) {
+	cn, err := c.getConn(addr)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := cn.roundTrip(cmds, c.timeout)
+	if err != nil {
+		// The connection can't be trusted anymore, the next command will reconnect
+		cn.close()
+		delete(c.conns, addr)
+		return nil, err
+	}
+	return replies, nil
+}
+
+func (c *Client) getConn(addr string) (*conn, error) {
+	if cn, ok := c.conns[addr]; ok {
+		return cn, nil
+	}
+
+	ctx := *c.ctx
+	state := common.GetState(ctx)
+	if state == nil {
+		return nil, errors.New("Redis commands can't be run in the init context")
+	}
+
+	dialCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	netConn, err := state.Dialer.DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.useTLS {
+		var tlsConfig *tls.Config
+		if state.TLSConfig != nil {
+			tlsConfig = state.TLSConfig.Clone()
+		} else {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		tlsConn := tls.Client(netConn, tlsConfig)
+		if deadline, ok := dialCtx.Deadline(); ok {
+			_ = tlsConn.SetDeadline(deadline)
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+		netConn = tlsConn
+	}
+
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn), done: make(chan struct{})}
+
+	var setup [][][]byte
+	if c.password != "" {
+		if c.hasUsername {
+			setup = append(setup, [][]byte{[]byte("AUTH"), []byte(c.username), []byte(c.password)})
+		} else {
+			setup = append(setup, [][]byte{[]byte("AUTH"), []byte(c.password)})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(c.db))})
+	}
+	if len(setup) > 0 {
+		replies, err := cn.roundTrip(setup, c.timeout)
+		if err == nil {
+			for i, reply := range replies {
+				if e, ok := reply.(replyError); ok {
+					err = errors.Errorf("Redis %s failed: %s", setup[i][0], e)
+					break
+				}
+			}
+		}
+		if err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+	}
+
+	// Don't leave connections open past the end of the test.
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = cn.Conn.Close()
+		case <-cn.done:
+		}
+	}()
+
+	c.conns[addr] = cn
+	return cn, nil
+}
+
+func (cn *conn) roundTrip(cmds [][][]byte, timeout time.Duration) ([]interface{}, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	_ = cn.SetDeadline(deadline)
+
+	var buf []byte
+	for _, args := range cmds {
+		buf = appendCommand(buf, args)
+	}
+	if _, err := cn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i := range replies {
+		var err error
+		if replies[i], err = readReply(cn.reader); err != nil {
+			return nil, err
+		}
+	}
+	return replies, nil
+}
+
+func (cn *conn) close() {
+	close(cn.done)
+	_ = cn.Conn.Close()
+}
+
+// toArgs converts a command and its arguments to bulk strings; arrays of bytes (e.g. from
+// open(file, "b")) are sent as they are, and everything else as strings.
+func toArgs(command string, args []goja.Value) [][]byte {
+	result := make([][]byte, 0, len(args)+1)
+	result = append(result, []byte(command))
+	for _, arg := range args {
+		if b, ok := arg.Export().([]byte); ok {
+			result = append(result, b)
+			continue
+		}
+		result = append(result, []byte(arg.String()))
+	}
+	return result
+}
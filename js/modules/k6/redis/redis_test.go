@@ -0,0 +1,403 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+	assert.Equal(t, 12182, keySlot("foo"))
+	assert.Equal(t, keySlot("user1000"), keySlot("{user1000}.following"))
+	assert.Equal(t, keySlot("{user1000}.following"), keySlot("{user1000}.followers"))
+	assert.NotEqual(t, keySlot("{}.a"), keySlot("{}.b"))
+	assert.Equal(t, -1, commandSlot([][]byte{[]byte("ping"), []byte("foo")}))
+}
+
+func TestReadReply(t *testing.T) {
+	reply, err := readReply(bufio.NewReader(strings.NewReader(
+		"*5\r\n+OK\r\n:42\r\n$5\r\nhe\r\no\r\n$-1\r\n*1\r\n-ERR wrong\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"OK", int64(42), "he\r\no", nil, []interface{}{replyError("ERR wrong")}}, reply)
+
+	_, err = readReply(bufio.NewReader(strings.NewReader("?what\r\n")))
+	assert.EqualError(t, err, `unknown Redis reply type '?'`)
+
+	kind, slot, addr, ok := replyError("MOVED 3999 127.0.0.1:6381").redirect()
+	assert.True(t, ok)
+	assert.Equal(t, "MOVED", kind)
+	assert.Equal(t, 3999, slot)
+	assert.Equal(t, "127.0.0.1:6381", addr)
+}
+
+type status string
+
+func appendReply(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "$-1\r\n"...)
+	case status:
+		return append(b, "+"+string(v)+"\r\n"...)
+	case replyError:
+		return append(b, "-"+string(v)+"\r\n"...)
+	case int:
+		return append(b, ":"+strconv.Itoa(v)+"\r\n"...)
+	case string:
+		return append(b, fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)...)
+	case []interface{}:
+		b = append(b, fmt.Sprintf("*%d\r\n", len(v))...)
+		for _, e := range v {
+			b = appendReply(b, e)
+		}
+		return b
+	default:
+		panic(fmt.Sprintf("unsupported reply %#v", v))
+	}
+}
+
+// fakeNode is a Redis server with just enough commands for the tests. In cluster mode, it only
+// accepts the keys of the slots it owns, and redirects the others to its peer with MOVED.
+type fakeNode struct {
+	listener net.Listener
+	password string
+	owns     func(slot int) bool
+	peer     string
+	slots    []interface{}
+
+	mu       sync.Mutex
+	data     map[string]string
+	hashes   map[string]map[string]string
+	lists    map[string][]string
+	commands []string
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	n := &fakeNode{
+		listener: l,
+		data:     make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		lists:    make(map[string][]string),
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n
+}
+
+func (n *fakeNode) addr() string {
+	return n.listener.Addr().String()
+}
+
+func (n *fakeNode) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	authenticated := n.password == ""
+	for {
+		req, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range req.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		command := strings.ToUpper(args[0])
+
+		var reply interface{}
+		switch {
+		case command == "AUTH":
+			authenticated = args[len(args)-1] == n.password
+			reply = status("OK")
+			if !authenticated {
+				reply = replyError("WRONGPASS invalid username-password pair")
+			}
+		case !authenticated:
+			reply = replyError("NOAUTH Authentication required.")
+		case n.owns != nil && len(args) > 1 && !keylessCommands[command] && !n.owns(keySlot(args[1])):
+			reply = replyError(fmt.Sprintf("MOVED %d %s", keySlot(args[1]), n.peer))
+		default:
+			reply = n.run(command, args[1:])
+		}
+		if _, err := conn.Write(appendReply(nil, reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (n *fakeNode) run(command string, args []string) interface{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.commands = append(n.commands, strings.Join(append([]string{command}, args...), " "))
+
+	switch command {
+	case "PING":
+		return status("PONG")
+	case "SELECT":
+		return status("OK")
+	case "CLUSTER":
+		return n.slots
+	case "SET":
+		n.data[args[0]] = args[1]
+		return status("OK")
+	case "GET":
+		if v, ok := n.data[args[0]]; ok {
+			return v
+		}
+		return nil
+	case "INCR", "INCRBY", "DECR":
+		by := 1
+		if command == "INCRBY" {
+			by, _ = strconv.Atoi(args[1])
+		} else if command == "DECR" {
+			by = -1
+		}
+		v, err := strconv.Atoi(n.data[args[0]])
+		if err != nil && n.data[args[0]] != "" {
+			return replyError("ERR value is not an integer or out of range")
+		}
+		n.data[args[0]] = strconv.Itoa(v + by)
+		return v + by
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args {
+			if _, ok := n.data[key]; ok {
+				count++
+				if command == "DEL" {
+					delete(n.data, key)
+				}
+			}
+		}
+		return count
+	case "EXPIRE":
+		return 1
+	case "HSET":
+		if n.hashes[args[0]] == nil {
+			n.hashes[args[0]] = make(map[string]string)
+		}
+		n.hashes[args[0]][args[1]] = args[2]
+		return 1
+	case "HGET":
+		if v, ok := n.hashes[args[0]][args[1]]; ok {
+			return v
+		}
+		return nil
+	case "HGETALL":
+		var fields []interface{}
+		for k, v := range n.hashes[args[0]] {
+			fields = append(fields, k, v)
+		}
+		return fields
+	case "LPUSH":
+		for _, v := range args[1:] {
+			n.lists[args[0]] = append([]string{v}, n.lists[args[0]]...)
+		}
+		return len(n.lists[args[0]])
+	case "RPOP":
+		list := n.lists[args[0]]
+		if len(list) == 0 {
+			return nil
+		}
+		n.lists[args[0]] = list[:len(list)-1]
+		return list[len(list)-1]
+	case "LLEN":
+		return len(n.lists[args[0]])
+	default:
+		return replyError("ERR unknown command '" + command + "'")
+	}
+}
+
+func newRuntime(t *testing.T) (*goja.Runtime, *context.Context, *common.State, chan stats.SampleContainer) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:  root,
+		Dialer: netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("group"),
+		},
+		Samples: samples,
+	}
+
+	ctx := new(context.Context)
+	*ctx = common.WithRuntime(context.Background(), rt)
+	rt.Set("redis", common.Bind(rt, New(), ctx))
+	return rt, ctx, state, samples
+}
+
+func TestClient(t *testing.T) {
+	node := newFakeNode(t)
+	defer func() { _ = node.listener.Close() }()
+	node.password = "secret"
+
+	rt, ctx, state, samples := newRuntime(t)
+	rt.Set("REDIS_URL", "redis://:secret@"+node.addr()+"/1")
+
+	// Clients are created in the init context, but can't be used there
+	_, err := common.RunString(rt, `let client = new redis.Client(REDIS_URL);`)
+	require.NoError(t, err)
+	_, err = common.RunString(rt, `client.get("key")`)
+	assert.Contains(t, err.Error(), "Redis commands can't be run in the init context")
+
+	*ctx = common.WithState(*ctx, state)
+
+	t.Run("commands", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		function assertEqual(expected, actual) {
+			if (JSON.stringify(expected) !== JSON.stringify(actual)) {
+				throw new Error("expected " + JSON.stringify(expected) + ", got " + JSON.stringify(actual));
+			}
+		}
+		assertEqual("OK", client.set("greeting", "hello"));
+		assertEqual("OK", client.set("counter", 10, 60));
+		assertEqual("hello", client.get("greeting"));
+		assertEqual(null, client.get("missing"));
+		assertEqual(11, client.incr("counter"));
+		assertEqual(16, client.incrBy("counter", 5));
+		assertEqual(15, client.decr("counter"));
+		assertEqual(1, client.expire("counter", 10));
+		assertEqual(2, client.exists("greeting", "counter", "missing"));
+		assertEqual(1, client.del("greeting"));
+		assertEqual(1, client.hset("user:1", "name", "k6"));
+		assertEqual("k6", client.hget("user:1", "name"));
+		assertEqual({ name: "k6" }, client.hgetall("user:1"));
+		assertEqual(2, client.lpush("queue", "a", "b"));
+		assertEqual("a", client.rpop("queue"));
+		assertEqual(1, client.llen("queue"));
+		assertEqual("PONG", client.do("PING"));
+		assertEqual(["OK", 1, "1"], client.pipeline([["SET", "p", 0], ["INCR", "p"], ["GET", "p"]]));
+		`)
+		assert.NoError(t, err)
+		assert.Contains(t, node.commands, "SELECT 1")
+
+		counts := map[string]float64{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, sample := range sc.GetSamples() {
+				if sample.Metric == metrics.RedisCommands {
+					command, _ := sample.Tags.Get("command")
+					counts[command] += sample.Value
+				}
+			}
+		}
+		assert.Equal(t, 2.0, counts["set"])
+		assert.Equal(t, 1.0, counts["pipeline"])
+		assert.Equal(t, 1.0, counts["ping"])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`client.do("NOPE")`: "ERR unknown command 'NOPE'",
+			`client.incr("user:1:missing") && client.set("s", "x") && client.incr("s")`: "ERR value is not an integer or out of range",
+			`client.pipeline([["SET", "s", "x"], ["INCR", "s"]])`:                       "Command 2 of the pipeline (INCR) failed: ERR value is not an integer",
+			`new redis.Client("redis://:wrong@` + node.addr() + `").get("a")`:           "Redis AUTH failed: WRONGPASS",
+			`new redis.Client("http://localhost")`:                                      "Unsupported Redis URL scheme 'http'",
+			`new redis.Client({})`:                                                      "Redis client options must have either a url or addrs",
+		}
+		for code, msg := range testdata {
+			_, err := common.RunString(rt, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("reconnect", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		client.close();
+		if (client.get("p") !== "1") { throw new Error("not reconnected"); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+}
+
+func TestCluster(t *testing.T) {
+	a, b := newFakeNode(t), newFakeNode(t)
+	defer func() { _ = a.listener.Close() }()
+	defer func() { _ = b.listener.Close() }()
+
+	a.owns = func(slot int) bool { return slot < 8192 }
+	b.owns = func(slot int) bool { return slot >= 8192 }
+	a.peer, b.peer = b.addr(), a.addr()
+
+	// The slots are out of date, as if they just moved to b
+	host, port, _ := net.SplitHostPort(a.addr())
+	portNum, _ := strconv.Atoi(port)
+	a.slots = []interface{}{[]interface{}{0, 16383, []interface{}{host, portNum}}}
+
+	rt, ctx, state, samples := newRuntime(t)
+	*ctx = common.WithState(*ctx, state)
+	rt.Set("ADDR", a.addr())
+
+	require.True(t, keySlot("foo") >= 8192)
+	require.True(t, keySlot("bar") < 8192)
+	_, err := common.RunString(rt, `
+	let client = new redis.Client({ addrs: [ADDR], cluster: true, tags: { cache: "sessions" } });
+	client.set("foo", "1");
+	client.set("bar", "2");
+	if (client.get("foo") !== "1" || client.get("bar") !== "2") { throw new Error("wrong values"); }
+	let replies = client.pipeline([["INCR", "foo"], ["INCR", "bar"], ["INCR", "{foo}.other"]]);
+	if (replies.join(",") !== "2,3,1") { throw new Error("wrong replies: " + replies); }
+	`)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"bar": "3"}, a.data)
+	assert.Equal(t, map[string]string{"foo": "2", "{foo}.other": "1"}, b.data)
+	// Only the first command for foo was redirected, the rest went straight to b
+	assert.Equal(t, []string{"SET foo 1", "GET foo", "INCR foo", "INCR {foo}.other"}, b.commands)
+
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, sample := range sc.GetSamples() {
+			cache, _ := sample.Tags.Get("cache")
+			assert.Equal(t, "sessions", cache)
+		}
+	}
+}
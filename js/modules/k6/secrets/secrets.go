@@ -0,0 +1,51 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"context"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/pkg/errors"
+)
+
+type Secrets struct{}
+
+func New() *Secrets {
+	return &Secrets{}
+}
+
+// Get returns a secret from the first of the test's secret sources that has it. It can be called
+// in the init context too, and the secrets it returns are redacted from k6's logs.
+func (*Secrets) Get(ctx context.Context, key string) (string, error) {
+	store := common.GetSecrets(ctx)
+	if store == nil || !store.HasSources() {
+		return "", errors.New("there are no secret sources, they're added with --secret-source")
+	}
+	value, ok, err := store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.Errorf("there's no secret '%s' in any of the secret sources", key)
+	}
+	return value, nil
+}
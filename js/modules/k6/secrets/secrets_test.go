@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("secrets", common.Bind(rt, New(), &ctx))
+
+	t.Run("No Sources", func(t *testing.T) {
+		_, err := common.RunString(rt, `secrets.get("TOKEN")`)
+		assert.Contains(t, err.Error(), "there are no secret sources, they're added with --secret-source")
+	})
+
+	store, err := secrets.NewStore([]string{"env=K6_TEST_SECRET_"})
+	require.NoError(t, err)
+	ctx = common.WithSecrets(ctx, store)
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := common.RunString(rt, `secrets.get("TOKEN")`)
+		assert.Contains(t, err.Error(), "there's no secret 'TOKEN' in any of the secret sources")
+	})
+	t.Run("Found", func(t *testing.T) {
+		require.NoError(t, os.Setenv("K6_TEST_SECRET_TOKEN", "s3cr3t-value"))
+		defer func() { _ = os.Unsetenv("K6_TEST_SECRET_TOKEN") }()
+		v, err := common.RunString(rt, `secrets.get("TOKEN")`)
+		require.NoError(t, err)
+		assert.Equal(t, "s3cr3t-value", v.String())
+		assert.Equal(t, "token=***", store.Redact("token=s3cr3t-value"))
+	})
+}
@@ -0,0 +1,140 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shared
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/shared"
+	"github.com/pkg/errors"
+)
+
+// Shared is a key-value store that all VUs of a test run share, e.g. for unique IDs or to
+// coordinate the VUs, without an external store like Redis. Its operations are atomic. The values
+// are kept as JSON, so every VU gets its own copy of them, and changing one doesn't change the
+// stored value. Every test run has its own store, and in distributed runs, so does each instance.
+type Shared struct{}
+
+func New() *Shared {
+	return &Shared{}
+}
+
+// getStore returns the store of the test run that the VU belongs to.
+func getStore(ctx context.Context) (*shared.Store, error) {
+	store := common.GetShared(ctx)
+	if store == nil {
+		return nil, errors.New("there's no shared store in this context")
+	}
+	return store, nil
+}
+
+// Get returns the value of a key, or null if the key isn't set.
+func (*Shared) Get(ctx context.Context, key string) (goja.Value, error) {
+	store, err := getStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := store.Get(key)
+	if !ok {
+		return goja.Null(), nil
+	}
+	// Parsed in JS, so that the value is a plain JS object or array, not a wrapped Go one
+	rt := common.GetRuntime(ctx)
+	parse, ok := goja.AssertFunction(rt.Get("JSON").ToObject(rt).Get("parse"))
+	if !ok {
+		return nil, errors.New("JSON.parse isn't a function")
+	}
+	return parse(goja.Undefined(), rt.ToValue(string(data)))
+}
+
+// Set sets the value of a key, which has to be serializable to JSON.
+func (*Shared) Set(ctx context.Context, key string, value goja.Value) {
+	rt := common.GetRuntime(ctx)
+	store, err := getStore(ctx)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	data, err := marshal(key, value)
+	if err != nil {
+		common.Throw(rt, err)
+	}
+	store.Set(key, data)
+}
+
+// SetIfAbsent sets the value of a key only if it isn't set yet, and returns whether it did, e.g.
+// so that only the first VU that gets there does something.
+func (*Shared) SetIfAbsent(ctx context.Context, key string, value goja.Value) (bool, error) {
+	store, err := getStore(ctx)
+	if err != nil {
+		return false, err
+	}
+	data, err := marshal(key, value)
+	if err != nil {
+		return false, err
+	}
+	return store.SetIfAbsent(key, data), nil
+}
+
+// Add adds a number (1 by default) to the value of a key, which starts at 0, and returns the new
+// value. Every call gets a different value, so it's a counter for unique IDs across the VUs.
+func (*Shared) Add(ctx context.Context, key string, delta ...int64) (int64, error) {
+	store, err := getStore(ctx)
+	if err != nil {
+		return 0, err
+	}
+	d := int64(1)
+	if len(delta) > 0 {
+		d = delta[0]
+	}
+	return store.Add(key, d)
+}
+
+// Delete deletes a key, and returns whether it was set.
+func (*Shared) Delete(ctx context.Context, key string) (bool, error) {
+	store, err := getStore(ctx)
+	if err != nil {
+		return false, err
+	}
+	return store.Delete(key), nil
+}
+
+// Keys returns the keys that are set, in alphabetical order.
+func (*Shared) Keys(ctx context.Context) ([]string, error) {
+	store, err := getStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.Keys(), nil
+}
+
+func marshal(key string, value goja.Value) ([]byte, error) {
+	if value == nil || goja.IsUndefined(value) {
+		return nil, errors.Errorf("the shared value of '%s' can't be undefined", key)
+	}
+	data, err := json.Marshal(value.Export())
+	if err != nil {
+		return nil, errors.Wrapf(err, "the shared value of '%s' has to be serializable to JSON", key)
+	}
+	return data, nil
+}
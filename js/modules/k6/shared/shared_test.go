@@ -0,0 +1,94 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRuntime(store *shared.Store) *goja.Runtime {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	if store != nil {
+		ctx = common.WithShared(ctx, store)
+	}
+	rt.Set("shared", common.Bind(rt, New(), &ctx))
+	return rt
+}
+
+func TestShared(t *testing.T) {
+	store := shared.NewStore()
+	rt1, rt2 := newRuntime(store), newRuntime(store)
+
+	_, err := common.RunString(rt1, `
+	shared.set("config", { users: ["a", "b"], n: 1 });
+	let config = shared.get("config");
+	config.users.push("c");
+	if (shared.get("config").users.length !== 2) { throw new Error("the stored value was changed"); }
+	if (shared.get("missing") !== null) { throw new Error("a missing key has a value"); }
+	if (!shared.setIfAbsent("leader", 1) || shared.setIfAbsent("leader", 2)) { throw new Error("wrong setIfAbsent"); }
+	`)
+	require.NoError(t, err)
+
+	v, err := common.RunString(rt2, `JSON.stringify([shared.get("config"), shared.get("leader"), shared.keys()])`)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"n":1,"users":["a","b"]},1,["config","leader"]]`, v.String())
+
+	v, err = common.RunString(rt2, `[shared.add("id"), shared.add("id"), shared.add("id", 10), shared.delete("id"), shared.delete("id"), shared.add("id")].join(",")`)
+	require.NoError(t, err)
+	assert.Equal(t, "1,2,12,true,false,1", v.String())
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`shared.set("x", undefined)`:             "the shared value of 'x' can't be undefined",
+			`shared.add("config")`:                   "can't add to shared value 'config', it isn't an integer",
+			`shared.setIfAbsent("y", function() {})`: "the shared value of 'y' has to be serializable to JSON",
+		}
+		for code, msg := range testdata {
+			_, err := common.RunString(rt1, code)
+			if assert.Error(t, err, code) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+
+	t.Run("other store", func(t *testing.T) {
+		v, err := common.RunString(newRuntime(shared.NewStore()), `JSON.stringify([shared.get("config"), shared.keys()])`)
+		require.NoError(t, err)
+		assert.Equal(t, `[null,[]]`, v.String())
+	})
+
+	t.Run("no store", func(t *testing.T) {
+		_, err := common.RunString(newRuntime(nil), `shared.add("id")`)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "there's no shared store in this context")
+		}
+	})
+}
@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package socketio
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Engine.IO packet types, the first character of every websocket message.
+const (
+	engineOpen    = '0'
+	engineClose   = '1'
+	enginePing    = '2'
+	enginePong    = '3'
+	engineMessage = '4'
+)
+
+// Socket.IO packet types, the first character of Engine.IO messages.
+const (
+	packetConnect = iota
+	packetDisconnect
+	packetEvent
+	packetAck
+	packetConnectError
+	packetBinaryEvent
+	packetBinaryAck
+)
+
+// A Socket.IO packet, e.g. `42/chat,7["message","hi"]` is an event in the /chat namespace, which
+// the receiver has to acknowledge with the ID 7.
+type packet struct {
+	typ       int
+	namespace string
+	id        int // -1 if there's none
+	data      json.RawMessage
+}
+
+// encode returns the packet as an Engine.IO message.
+func (p packet) encode() string {
+	var b strings.Builder
+	b.WriteByte(engineMessage)
+	b.WriteString(strconv.Itoa(p.typ))
+	if p.namespace != "/" && p.namespace != "" {
+		b.WriteString(p.namespace)
+		b.WriteByte(',')
+	}
+	if p.id >= 0 {
+		b.WriteString(strconv.Itoa(p.id))
+	}
+	b.Write(p.data)
+	return b.String()
+}
+
+// decodePacket parses the Socket.IO packet in an Engine.IO message, without the message type.
+func decodePacket(s string) (packet, error) {
+	if s == "" {
+		return packet{}, errors.New("empty Socket.IO packet")
+	}
+	p := packet{typ: int(s[0] - '0'), namespace: "/", id: -1}
+	switch {
+	case p.typ < packetConnect || p.typ > packetBinaryAck:
+		return p, errors.Errorf("invalid Socket.IO packet type '%c'", s[0])
+	case p.typ == packetBinaryEvent || p.typ == packetBinaryAck:
+		return p, errors.New("binary Socket.IO packets aren't supported")
+	}
+	s = s[1:]
+
+	if strings.HasPrefix(s, "/") {
+		if i := strings.IndexByte(s, ','); i >= 0 {
+			p.namespace, s = s[:i], s[i+1:]
+		} else {
+			p.namespace, s = s, ""
+		}
+	}
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > 0 {
+		p.id, _ = strconv.Atoi(s[:i])
+		s = s[i:]
+	}
+	if s != "" {
+		if !json.Valid([]byte(s)) {
+			return p, errors.Errorf("invalid Socket.IO packet data '%s'", s)
+		}
+		p.data = json.RawMessage(s)
+	}
+	return p, nil
+}
@@ -0,0 +1,587 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package socketio
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPath    = "/socket.io/"
+	defaultTimeout = 60 * time.Second
+	writeWait      = 10 * time.Second
+)
+
+// SocketIO is the k6/socketio module, a Socket.IO client for its websocket transport.
+type SocketIO struct{}
+
+// Socket is a connection to a Socket.IO namespace.
+type Socket struct {
+	ctx           context.Context
+	conn          *websocket.Conn
+	namespace     string
+	tags          *stats.SampleTags
+	eventHandlers map[string][]goja.Callable
+	connected     bool
+	done          chan struct{}
+	shutdownOnce  sync.Once
+
+	// The callbacks of the emitted events that asked for an acknowledgement, by their IDs.
+	acks      map[int]pendingAck
+	nextAckID int
+}
+
+type pendingAck struct {
+	fn   goja.Callable
+	sent time.Time
+}
+
+func New() *SocketIO {
+	return &SocketIO{}
+}
+
+// Connect connects to the Socket.IO server at url (http://, https://, ws:// or wss://) and calls
+// the given function with the socket, so it can set up its event handlers. It blocks until the
+// socket is closed by either side. Like with the official client, the path of the URL is the
+// namespace to connect to, unless the namespace param says otherwise.
+func (*SocketIO) Connect(ctx context.Context, url string, args ...goja.Value) {
+	if err := connect(ctx, url, args...); err != nil {
+		common.Throw(common.GetRuntime(ctx), err)
+	}
+}
+
+func connect(ctx context.Context, url string, args ...goja.Value) error {
+	rt := common.GetRuntime(ctx)
+	state := common.GetState(ctx)
+	if state == nil {
+		return errors.New("Socket.IO connections can't be made in the init context")
+	}
+
+	// The params argument is optional
+	var callableV, paramsV goja.Value
+	switch len(args) {
+	case 2:
+		paramsV = args[0]
+		callableV = args[1]
+	case 1:
+		paramsV = goja.Undefined()
+		callableV = args[0]
+	default:
+		return errors.New("Invalid number of arguments to socketio.connect")
+	}
+
+	setupFn, isFunc := goja.AssertFunction(callableV)
+	if !isFunc {
+		return errors.New("Last argument to socketio.connect must be a function")
+	}
+
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return err
+	}
+	namespace := u.Path
+	if namespace == "" {
+		namespace = "/"
+	}
+	path := defaultPath
+	timeout := defaultTimeout
+	query := neturl.Values{}
+	var header http.Header
+	var auth json.RawMessage
+	tags := state.Options.RunTags.CloneTags()
+	if !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
+		params := paramsV.ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "namespace":
+				namespace = v.String()
+				if !strings.HasPrefix(namespace, "/") {
+					return errors.Errorf("Invalid namespace '%s', it has to start with a /", namespace)
+				}
+			case "path":
+				path = v.String()
+			case "auth":
+				if auth, err = json.Marshal(v.Export()); err != nil {
+					return errors.Wrap(err, "couldn't encode the auth payload")
+				}
+			case "query":
+				queryObj := v.ToObject(rt)
+				for _, key := range queryObj.Keys() {
+					query.Set(key, queryObj.Get(key).String())
+				}
+			case "headers":
+				header = http.Header{}
+				headersObj := v.ToObject(rt)
+				for _, key := range headersObj.Keys() {
+					header.Set(key, headersObj.Get(key).String())
+				}
+			case "timeout":
+				timeout = time.Duration(v.ToFloat() * float64(time.Millisecond))
+			case "tags":
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+
+	if state.Options.SystemTags["url"] {
+		tags["url"] = url
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	wsURL, err := engineURL(u, path, query)
+	if err != nil {
+		return err
+	}
+
+	// Overriding the NextProtos to avoid talking http2
+	var tlsConfig *tls.Config
+	if state.TLSConfig != nil {
+		tlsConfig = state.TLSConfig.Clone()
+		tlsConfig.NextProtos = []string{"http/1.1"}
+	}
+	wsd := websocket.Dialer{
+		NetDial: func(network, address string) (net.Conn, error) {
+			return state.Dialer.DialContext(ctx, network, address)
+		},
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: timeout,
+	}
+
+	start := time.Now()
+	conn, res, connErr := wsd.Dial(wsURL, header)
+	var silence time.Duration
+	if connErr == nil {
+		if silence, connErr = readOpen(conn, timeout); connErr != nil {
+			_ = conn.Close()
+		}
+	} else if res != nil {
+		connErr = errors.Wrapf(connErr, "websocket handshake failed with status %d", res.StatusCode)
+	}
+	connectionDuration := stats.D(time.Since(start))
+
+	socket := Socket{
+		ctx:           ctx,
+		conn:          conn,
+		namespace:     namespace,
+		eventHandlers: make(map[string][]goja.Callable),
+		done:          make(chan struct{}),
+		acks:          make(map[int]pendingAck),
+	}
+
+	// Run the user-provided set up function
+	if _, err := setupFn(goja.Undefined(), rt.ToValue(&socket)); err != nil {
+		if connErr == nil {
+			_ = conn.Close()
+		}
+		return err
+	}
+
+	if connErr != nil {
+		// Pass the error to the user script before exiting immediately
+		if err := socket.handleEvent("error", rt.ToValue(connErr)); err != nil {
+			return err
+		}
+		return connErr
+	}
+	defer func() { _ = conn.Close() }()
+
+	if state.Options.SystemTags["status"] {
+		tags["status"] = strconv.Itoa(res.StatusCode)
+	}
+	state.Options.SystemTags.SetRemoteAddrTags(tags, conn.RemoteAddr())
+	socket.tags = stats.IntoSampleTags(&tags)
+
+	if err := socket.write(packet{typ: packetConnect, namespace: namespace, id: -1, data: auth}.encode()); err != nil {
+		return err
+	}
+
+	readDataChan := make(chan string)
+	readErrChan := make(chan error)
+	go readPump(conn, silence, readDataChan, readErrChan, socket.done)
+
+	// Like with ws.connect(), the socket's events are forwarded to the VU's event loop, so that
+	// timers and Promises keep working while it's open.
+	loop := common.GetEventLoop(ctx)
+	if loop == nil {
+		loop = common.NewEventLoop(rt)
+	}
+	go func() {
+		ctxDone := ctx.Done()
+		for {
+			var fn func() error
+			select {
+			case data := <-readDataChan:
+				fn = func() error { return socket.handleMessage(data) }
+
+			case readErr := <-readErrChan:
+				fn = func() error {
+					reason := "transport close"
+					if !websocket.IsCloseError(readErr, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+						reason = "transport error"
+						if netErr, ok := readErr.(net.Error); ok && netErr.Timeout() {
+							reason, readErr = "ping timeout", errors.New("the server stopped sending pings")
+						}
+						if err := socket.handleEvent("error", rt.ToValue(readErr)); err != nil {
+							return err
+						}
+					}
+					return socket.closeConnection(reason, false)
+				}
+
+			case <-ctxDone:
+				// VU is shutting down during an interrupt
+				ctxDone = nil
+				fn = func() error { return socket.closeConnection("io client disconnect", true) }
+
+			case <-socket.done:
+				return
+			}
+			socket.post(loop, fn)
+		}
+	}()
+
+	// closeConnection closes the done channel, which is the normal exit point
+	if err := loop.RunUntil(socket.done); err != nil {
+		socket.shutdownOnce.Do(func() { close(socket.done) })
+		return err
+	}
+
+	// Socket.IO sessions are websocket sessions, and have their metrics
+	state.Samples <- stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: metrics.WSSessions, Time: start, Tags: socket.tags, Value: 1},
+			{Metric: metrics.WSConnecting, Time: start, Tags: socket.tags, Value: connectionDuration},
+			{Metric: metrics.WSSessionDuration, Time: start, Tags: socket.tags, Value: stats.D(time.Since(start))},
+		},
+		Tags: socket.tags,
+		Time: start,
+	}
+	return nil
+}
+
+// engineURL returns the URL of the Engine.IO websocket endpoint of a server.
+func engineURL(u *neturl.URL, path string, query neturl.Values) (string, error) {
+	eu := *u
+	switch u.Scheme {
+	case "http", "ws":
+		eu.Scheme = "ws"
+	case "https", "wss":
+		eu.Scheme = "wss"
+	default:
+		return "", errors.Errorf("Unsupported Socket.IO URL scheme '%s', must be http, https, ws or wss", u.Scheme)
+	}
+	eu.Path = path
+	q := u.Query()
+	for k, vs := range query {
+		q[k] = vs
+	}
+	q.Set("EIO", "4")
+	q.Set("transport", "websocket")
+	eu.RawQuery = q.Encode()
+	return eu.String(), nil
+}
+
+// readOpen reads the Engine.IO handshake, and returns how long the connection can be silent
+// before it's considered broken: the server pings every pingInterval, and the client waits
+// pingTimeout more for a ping.
+func readOpen(conn *websocket.Conn, timeout time.Duration) (time.Duration, error) {
+	if timeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+		defer func() { _ = conn.SetReadDeadline(time.Time{}) }()
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return 0, errors.Wrap(err, "couldn't read the Engine.IO handshake")
+	}
+	if len(data) == 0 || data[0] != engineOpen {
+		return 0, errors.Errorf("unexpected Engine.IO packet '%s' instead of the handshake", data)
+	}
+	var handshake struct {
+		PingInterval int64 `json:"pingInterval"`
+		PingTimeout  int64 `json:"pingTimeout"`
+	}
+	if err := json.Unmarshal(data[1:], &handshake); err != nil {
+		return 0, errors.Wrap(err, "invalid Engine.IO handshake")
+	}
+	return time.Duration(handshake.PingInterval+handshake.PingTimeout) * time.Millisecond, nil
+}
+
+// handleMessage takes care of an Engine.IO message from the server.
+func (s *Socket) handleMessage(data string) error {
+	rt := common.GetRuntime(s.ctx)
+	if data == "" {
+		return nil
+	}
+	switch data[0] {
+	case enginePing:
+		if err := s.write(string(enginePong)); err != nil {
+			return s.handleEvent("error", rt.ToValue(err))
+		}
+		return nil
+	case engineClose:
+		return s.closeConnection("transport close", false)
+	case engineMessage:
+	default:
+		// Noops and upgrades don't matter for the websocket transport
+		return nil
+	}
+
+	p, err := decodePacket(data[1:])
+	if err != nil {
+		return s.handleEvent("error", rt.ToValue(err))
+	}
+	if p.namespace != s.namespace {
+		return nil
+	}
+
+	switch p.typ {
+	case packetConnect:
+		s.connected = true
+		return s.handleEvent("connect")
+
+	case packetConnectError:
+		var connectErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(p.data, &connectErr)
+		if err := s.handleEvent("error", rt.ToValue(errors.Errorf("Socket.IO connection refused: %s", connectErr.Message))); err != nil {
+			return err
+		}
+		return s.closeConnection("io server disconnect", false)
+
+	case packetDisconnect:
+		return s.closeConnection("io server disconnect", false)
+
+	case packetEvent:
+		var args []interface{}
+		if err := json.Unmarshal(p.data, &args); err != nil || len(args) == 0 {
+			return s.handleEvent("error", rt.ToValue(errors.Errorf("invalid Socket.IO event %s", p.data)))
+		}
+		event, ok := args[0].(string)
+		if !ok {
+			return s.handleEvent("error", rt.ToValue(errors.Errorf("invalid Socket.IO event name %v", args[0])))
+		}
+		s.sample(metrics.WSMessagesReceived, 1)
+		values := toValues(rt, args[1:])
+		if p.id >= 0 {
+			// The server wants an acknowledgement, which the handler sends by calling the last argument
+			id := p.id
+			values = append(values, rt.ToValue(func(call goja.FunctionCall) goja.Value {
+				if err := s.sendAck(id, call.Arguments); err != nil {
+					common.Throw(rt, err)
+				}
+				return goja.Undefined()
+			}))
+		}
+		return s.handleEvent(event, values...)
+
+	case packetAck:
+		ack, ok := s.acks[p.id]
+		if !ok {
+			return nil
+		}
+		delete(s.acks, p.id)
+		s.sample(metrics.SocketIOAckDuration, stats.D(time.Since(ack.sent)))
+		var args []interface{}
+		if len(p.data) > 0 {
+			if err := json.Unmarshal(p.data, &args); err != nil {
+				return s.handleEvent("error", rt.ToValue(errors.Errorf("invalid Socket.IO acknowledgement %s", p.data)))
+			}
+		}
+		_, err := ack.fn(goja.Undefined(), toValues(rt, args)...)
+		return err
+	}
+	return nil
+}
+
+func toValues(rt *goja.Runtime, args []interface{}) []goja.Value {
+	values := make([]goja.Value, len(args))
+	for i, arg := range args {
+		values[i] = rt.ToValue(arg)
+	}
+	return values
+}
+
+// On registers a handler for an event: "connect", "disconnect" (with the reason), "error", or one
+// that the server emits, which gets the event's arguments.
+func (s *Socket) On(event string, handler goja.Value) {
+	if handler, ok := goja.AssertFunction(handler); ok {
+		s.eventHandlers[event] = append(s.eventHandlers[event], handler)
+	}
+}
+
+func (s *Socket) handleEvent(event string, args ...goja.Value) error {
+	for _, handler := range s.eventHandlers[event] {
+		if _, err := handler(goja.Undefined(), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Emit sends an event with any number of arguments that can be encoded as JSON. If the last
+// argument is a function, the server is asked to acknowledge the event, and the function is
+// called with the arguments of its acknowledgement.
+func (s *Socket) Emit(event string, args ...goja.Value) {
+	rt := common.GetRuntime(s.ctx)
+	if !s.connected {
+		common.Throw(rt, errors.Errorf("can't emit '%s' before the socket is connected, emit it in a connect handler", event))
+	}
+
+	p := packet{typ: packetEvent, namespace: s.namespace, id: -1}
+	if len(args) > 0 {
+		if fn, ok := goja.AssertFunction(args[len(args)-1]); ok {
+			p.id = s.nextAckID
+			s.nextAckID++
+			s.acks[p.id] = pendingAck{fn: fn, sent: time.Now()}
+			args = args[:len(args)-1]
+		}
+	}
+	data := []interface{}{event}
+	for _, arg := range args {
+		data = append(data, arg.Export())
+	}
+	var err error
+	if p.data, err = json.Marshal(data); err != nil {
+		common.Throw(rt, errors.Wrapf(err, "couldn't encode the arguments of '%s'", event))
+	}
+
+	if err := s.write(p.encode()); err != nil {
+		delete(s.acks, p.id)
+		if err := s.handleEvent("error", rt.ToValue(err)); err != nil {
+			common.Throw(rt, err)
+		}
+		return
+	}
+	s.sample(metrics.WSMessagesSent, 1)
+}
+
+func (s *Socket) sendAck(id int, args []goja.Value) error {
+	data := make([]interface{}, len(args))
+	for i, arg := range args {
+		data[i] = arg.Export()
+	}
+	p := packet{typ: packetAck, namespace: s.namespace, id: id}
+	var err error
+	if p.data, err = json.Marshal(data); err != nil {
+		return errors.Wrap(err, "couldn't encode the acknowledgement")
+	}
+	return s.write(p.encode())
+}
+
+// Close disconnects from the namespace and closes the connection.
+func (s *Socket) Close() {
+	if err := s.closeConnection("io client disconnect", true); err != nil {
+		common.Throw(common.GetRuntime(s.ctx), err)
+	}
+}
+
+// closeConnection closes the connection, after telling the server that the client disconnects if
+// it's the one closing it, and emits the disconnect event with the reason.
+func (s *Socket) closeConnection(reason string, disconnect bool) error {
+	var err error
+	s.shutdownOnce.Do(func() {
+		if disconnect && s.connected {
+			_ = s.write(packet{typ: packetDisconnect, namespace: s.namespace, id: -1}.encode())
+		}
+		_ = s.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(writeWait),
+		)
+		_ = s.conn.Close()
+		s.connected = false
+
+		err = s.handleEvent("disconnect", common.GetRuntime(s.ctx).ToValue(reason))
+
+		// Stops the main control loop
+		close(s.done)
+	})
+	return err
+}
+
+func (s *Socket) write(message string) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+func (s *Socket) sample(metric *stats.Metric, value float64) {
+	common.GetState(s.ctx).Samples <- stats.Sample{Metric: metric, Time: time.Now(), Tags: s.tags, Value: value}
+}
+
+// post runs fn on the event loop, unless the socket is closed by then.
+func (s *Socket) post(loop *common.EventLoop, fn func() error) {
+	loop.RegisterCallback()(func() error {
+		select {
+		case <-s.done:
+			return nil
+		default:
+			return fn()
+		}
+	})
+}
+
+// readPump wraps conn.ReadMessage in channels; a read that takes longer than silence means the
+// server stopped pinging.
+func readPump(conn *websocket.Conn, silence time.Duration, readChan chan string, errorChan chan error, done chan struct{}) {
+	for {
+		if silence > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(silence))
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case errorChan <- err:
+			case <-done:
+			}
+			return
+		}
+		select {
+		case readChan <- string(data):
+		case <-done:
+			return
+		}
+	}
+}
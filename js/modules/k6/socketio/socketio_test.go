@@ -0,0 +1,239 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackets(t *testing.T) {
+	testdata := map[string]packet{
+		`0`:                            {typ: packetConnect, namespace: "/", id: -1},
+		`0/chat,{"token":"k6"}`:        {typ: packetConnect, namespace: "/chat", id: -1, data: json.RawMessage(`{"token":"k6"}`)},
+		`1/chat,`:                      {typ: packetDisconnect, namespace: "/chat", id: -1},
+		`2["message","hi"]`:            {typ: packetEvent, namespace: "/", id: -1, data: json.RawMessage(`["message","hi"]`)},
+		`2/chat,12["message",{"a":1}]`: {typ: packetEvent, namespace: "/chat", id: 12, data: json.RawMessage(`["message",{"a":1}]`)},
+		`37[]`:                         {typ: packetAck, namespace: "/", id: 7, data: json.RawMessage(`[]`)},
+		`4{"message":"unauthorized"}`:  {typ: packetConnectError, namespace: "/", id: -1, data: json.RawMessage(`{"message":"unauthorized"}`)},
+	}
+	for s, expected := range testdata {
+		p, err := decodePacket(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, p, s)
+		assert.Equal(t, "4"+s, p.encode())
+	}
+
+	errors := map[string]string{
+		``:                  "empty Socket.IO packet",
+		`9`:                 "invalid Socket.IO packet type '9'",
+		`51-["file",{}]`:    "binary Socket.IO packets aren't supported",
+		`2["message",`:      "invalid Socket.IO packet data",
+		`2/chat,["message"`: "invalid Socket.IO packet data",
+	}
+	for s, msg := range errors {
+		_, err := decodePacket(s)
+		if assert.Error(t, err, s) {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+// newServer returns a Socket.IO server with a few events: "echo" is sent back, or acknowledged
+// with its arguments if it asks for it, "ask" makes the server emit "question", and then "answer"
+// with the acknowledgement, and "bye" makes it disconnect the client. The /chat namespace needs
+// a token. It counts the pongs it gets for its ping.
+func newServer(t *testing.T, pongs *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/socket.io/" || r.URL.Query().Get("EIO") != "4" || r.URL.Query().Get("transport") != "websocket" {
+			http.Error(w, "wrong endpoint: "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		write := func(p packet) { _ = conn.WriteMessage(websocket.TextMessage, []byte(p.encode())) }
+
+		_ = conn.WriteMessage(websocket.TextMessage, []byte(`0{"sid":"k6","upgrades":[],"pingInterval":100,"pingTimeout":1000}`))
+		_ = conn.WriteMessage(websocket.TextMessage, []byte("2"))
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(data) == "3" {
+				atomic.AddInt32(pongs, 1)
+				continue
+			}
+			p, err := decodePacket(string(data[1:]))
+			require.NoError(t, err)
+			switch p.typ {
+			case packetConnect:
+				if p.namespace == "/chat" && string(p.data) != `{"token":"k6"}` {
+					write(packet{typ: packetConnectError, namespace: p.namespace, id: -1, data: json.RawMessage(`{"message":"unauthorized"}`)})
+					continue
+				}
+				write(packet{typ: packetConnect, namespace: p.namespace, id: -1, data: json.RawMessage(`{"sid":"s"}`)})
+			case packetEvent:
+				var args []json.RawMessage
+				require.NoError(t, json.Unmarshal(p.data, &args))
+				switch event := strings.Trim(string(args[0]), `"`); {
+				case event == "echo" && p.id >= 0:
+					ack, _ := json.Marshal(args[1:])
+					write(packet{typ: packetAck, namespace: p.namespace, id: p.id, data: ack})
+				case event == "echo":
+					write(p)
+				case event == "ask":
+					write(packet{typ: packetEvent, namespace: p.namespace, id: 5, data: json.RawMessage(`["question","2+2"]`)})
+				case event == "bye":
+					write(packet{typ: packetDisconnect, namespace: p.namespace, id: -1})
+				}
+			case packetAck:
+				write(packet{typ: packetEvent, namespace: p.namespace, id: -1, data: json.RawMessage(`["answer",` + strings.Trim(string(p.data), "[]") + `]`)})
+			case packetDisconnect:
+				return
+			}
+		}
+	}))
+}
+
+func TestConnect(t *testing.T) {
+	var pongs int32
+	srv := newServer(t, &pongs)
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:   root,
+		Dialer:  netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{SystemTags: lib.GetTagSet("url", "status")},
+		Samples: samples,
+	}
+	ctx := common.WithRuntime(common.WithState(context.Background(), state), rt)
+	rt.Set("socketio", common.Bind(rt, New(), &ctx))
+	rt.Set("url", srv.URL)
+
+	t.Run("events and acknowledgements", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let events = [];
+		socketio.connect(url + "/chat", { auth: { token: "k6" } }, function(socket) {
+			socket.on("connect", function() {
+				socket.emit("echo", "hi", { n: 1 });
+			});
+			socket.on("echo", function(msg, obj) {
+				events.push("echo " + msg + " " + obj.n);
+				socket.emit("echo", "with ack", function(msg) {
+					events.push("ack " + msg);
+					socket.emit("ask");
+				});
+			});
+			socket.on("question", function(q, ack) {
+				events.push("question " + q);
+				ack(4);
+			});
+			socket.on("answer", function(a) {
+				events.push("answer " + a);
+				socket.close();
+			});
+			socket.on("disconnect", function(reason) {
+				events.push("disconnect " + reason);
+			});
+		});
+		let expected = "echo hi 1,ack with ack,question 2+2,answer 4,disconnect io client disconnect";
+		if (events.join() !== expected) { throw new Error("wrong events: " + events.join()); }
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&pongs))
+
+		seen := map[string]float64{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				assert.Equal(t, srv.URL+"/chat", s.Tags.CloneTags()["url"])
+				seen[s.Metric.Name] += s.Value
+			}
+		}
+		assert.Equal(t, 1.0, seen[metrics.WSSessions.Name])
+		assert.Equal(t, 3.0, seen[metrics.WSMessagesSent.Name])
+		assert.Equal(t, 3.0, seen[metrics.WSMessagesReceived.Name])
+		assert.Contains(t, seen, metrics.SocketIOAckDuration.Name)
+	})
+
+	t.Run("disconnected by the server", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let reason;
+		socketio.connect(url, function(socket) {
+			socket.on("connect", function() { socket.emit("bye"); });
+			socket.on("disconnect", function(r) { reason = r; });
+		});
+		if (reason !== "io server disconnect") { throw new Error("wrong reason: " + reason); }
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let error;
+		socketio.connect(url + "/chat", function(socket) {
+			socket.on("error", function(e) { error = e; });
+		});
+		if (error.error() !== "Socket.IO connection refused: unauthorized") { throw new Error("wrong error: " + error.error()); }
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			`socketio.connect(url, function(socket) { socket.emit("early"); });`: "can't emit 'early' before the socket is connected",
+			`socketio.connect("ftp://localhost", function(socket) {});`:          "Unsupported Socket.IO URL scheme 'ftp'",
+			`socketio.connect(url, { namespace: "chat" }, function(socket) {});`: "Invalid namespace 'chat'",
+			`socketio.connect(url, { path: "/wrong/" }, function(socket) {});`:   "websocket handshake failed with status 400",
+		}
+		for script, msg := range testdata {
+			_, err := common.RunString(rt, script)
+			if assert.Error(t, err, script) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+	})
+}
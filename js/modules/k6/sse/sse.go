@@ -0,0 +1,455 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+type SSE struct{}
+
+// Client is an open event stream, as passed to the function given to sse.open().
+type Client struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	eventHandlers map[string][]goja.Callable
+	loop          *common.EventLoop
+	done          chan struct{}
+	closed        bool
+
+	eventTimestamps []time.Time
+}
+
+// Event is a single event received from the server.
+type Event struct {
+	ID   string `js:"id"`
+	Name string `js:"name"`
+	Data string `js:"data"`
+}
+
+type SSEHTTPResponse struct {
+	URL     string
+	Status  int
+	Headers map[string]string
+	Error   string
+}
+
+func New() *SSE {
+	return &SSE{}
+}
+
+// Open connects to an event stream and calls the given function with the client, so it can set up
+// its event handlers. It blocks until the stream is closed by either side, and then returns the
+// response. The optional params can contain the method, body, headers and tags of the request.
+func (*SSE) Open(ctx context.Context, url string, args ...goja.Value) (*SSEHTTPResponse, error) {
+	rt := common.GetRuntime(ctx)
+	state := common.GetState(ctx)
+	if state == nil {
+		return nil, errors.New("Event streams can't be opened in the init context")
+	}
+
+	// The params argument is optional
+	var callableV, paramsV goja.Value
+	switch len(args) {
+	case 2:
+		paramsV = args[0]
+		callableV = args[1]
+	case 1:
+		paramsV = goja.Undefined()
+		callableV = args[0]
+	default:
+		return nil, errors.New("Invalid number of arguments to sse.open")
+	}
+
+	setupFn, isFunc := goja.AssertFunction(callableV)
+	if !isFunc {
+		return nil, errors.New("Last argument to sse.open must be a function")
+	}
+
+	method := http.MethodGet
+	var body io.Reader
+	header := http.Header{}
+	header.Set("Accept", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	tags := state.Options.RunTags.CloneTags()
+
+	if !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
+		params := paramsV.ToObject(rt)
+		for _, k := range params.Keys() {
+			v := params.Get(k)
+			if goja.IsUndefined(v) || goja.IsNull(v) {
+				continue
+			}
+			switch k {
+			case "method":
+				method = strings.ToUpper(v.String())
+			case "body":
+				body = strings.NewReader(v.String())
+			case "headers":
+				headersObj := v.ToObject(rt)
+				for _, key := range headersObj.Keys() {
+					header.Set(key, headersObj.Get(key).String())
+				}
+			case "tags":
+				tagObj := v.ToObject(rt)
+				for _, key := range tagObj.Keys() {
+					tags[key] = tagObj.Get(key).String()
+				}
+			}
+		}
+	}
+
+	if state.Options.SystemTags["url"] {
+		tags["url"] = url
+	}
+	if state.Options.SystemTags["group"] {
+		tags["group"] = state.Group.Path
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	// Canceled by client.close(), which also interrupts the read of the stream
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+
+	// The stream's events and timers are forwarded to the VU's event loop, which runs their
+	// handlers between the callbacks of the script's timers and Promises, until the stream is
+	// closed. All JS code (including error handlers) runs on the loop, to avoid race conditions.
+	loop := common.GetEventLoop(ctx)
+	if loop == nil {
+		loop = common.NewEventLoop(rt)
+	}
+	client := Client{
+		ctx:           ctx,
+		cancel:        cancel,
+		eventHandlers: make(map[string][]goja.Callable),
+		loop:          loop,
+		done:          make(chan struct{}),
+	}
+
+	httpClient := http.Client{Transport: state.HTTPTransport}
+	if state.CookieJar != nil {
+		httpClient.Jar = state.CookieJar
+	}
+	start := time.Now()
+	httpResponse, connErr := httpClient.Do(req)
+	connectionDuration := stats.D(time.Since(start))
+
+	// Run the user-provided set up function
+	if _, err := setupFn(goja.Undefined(), rt.ToValue(&client)); err != nil {
+		if connErr == nil {
+			_ = httpResponse.Body.Close()
+		}
+		return nil, err
+	}
+
+	if connErr != nil {
+		// Pass the error to the user script before exiting immediately
+		client.handleEvent("error", rt.ToValue(connErr))
+
+		return nil, connErr
+	}
+	defer func() { _ = httpResponse.Body.Close() }()
+
+	sseResponse := wrapHTTPResponse(httpResponse)
+	sseResponse.URL = url
+
+	if state.Options.SystemTags["status"] {
+		tags["status"] = strconv.Itoa(httpResponse.StatusCode)
+	}
+	sampleTags := stats.IntoSampleTags(&tags)
+
+	// Like EventSource, anything other than a 200 event stream fails the connection
+	contentType, _, _ := mime.ParseMediaType(httpResponse.Header.Get("Content-Type"))
+	if httpResponse.StatusCode != http.StatusOK || contentType != "text/event-stream" {
+		err := errors.Errorf("Unexpected response with status %d and content type '%s', expected 200 and 'text/event-stream'",
+			httpResponse.StatusCode, contentType)
+		sseResponse.Error = err.Error()
+		client.handleEvent("error", rt.ToValue(err))
+
+		state.Samples <- stats.ConnectedSamples{
+			Samples: []stats.Sample{
+				{Metric: metrics.SSESessions, Time: start, Tags: sampleTags, Value: 1},
+				{Metric: metrics.SSEConnecting, Time: start, Tags: sampleTags, Value: connectionDuration},
+			},
+			Tags: sampleTags,
+			Time: start,
+		}
+		return sseResponse, nil
+	}
+
+	// The stream is now open, emit the event
+	client.handleEvent("open")
+
+	eventChan := make(chan Event)
+	readErrChan := make(chan error)
+	go readPump(httpResponse.Body, eventChan, readErrChan, reqCtx.Done())
+
+	go func() {
+		ctxDone := ctx.Done()
+		for {
+			var fn func() error
+			select {
+			case event := <-eventChan:
+				received := time.Now()
+				fn = func() error {
+					client.eventTimestamps = append(client.eventTimestamps, received)
+					client.handleEvent("event", rt.ToValue(&event))
+					return nil
+				}
+
+			case readErr := <-readErrChan:
+				// The stream was either closed by the server, or broken
+				fn = func() error {
+					if readErr != io.EOF {
+						client.handleEvent("error", rt.ToValue(readErr))
+					}
+					client.closeConnection()
+					return nil
+				}
+
+			case <-ctxDone:
+				// VU is shutting down during an interrupt
+				ctxDone = nil
+				fn = func() error {
+					client.closeConnection()
+					return nil
+				}
+
+			case <-client.done:
+				return
+			}
+			client.post(fn)
+		}
+	}()
+
+	// closeConnection closes the done channel, which is the normal exit point
+	if err := loop.RunUntil(client.done); err != nil {
+		if !client.closed {
+			client.closed = true
+			cancel()
+			close(client.done)
+		}
+		return nil, err
+	}
+
+	// The session is over, emit its metrics
+	sessionDuration := stats.D(time.Since(start))
+	samples := []stats.Sample{
+		{Metric: metrics.SSESessions, Time: start, Tags: sampleTags, Value: 1},
+		{Metric: metrics.SSEConnecting, Time: start, Tags: sampleTags, Value: connectionDuration},
+		{Metric: metrics.SSESessionDuration, Time: start, Tags: sampleTags, Value: sessionDuration},
+	}
+	if len(client.eventTimestamps) > 0 {
+		samples = append(samples, stats.Sample{
+			Metric: metrics.SSETimeToFirstEvent,
+			Time:   start,
+			Tags:   sampleTags,
+			Value:  stats.D(client.eventTimestamps[0].Sub(start)),
+		})
+	}
+	state.Samples <- stats.ConnectedSamples{Samples: samples, Tags: sampleTags, Time: start}
+
+	for _, eventTimestamp := range client.eventTimestamps {
+		state.Samples <- stats.Sample{
+			Metric: metrics.SSEEventsReceived,
+			Time:   eventTimestamp,
+			Tags:   sampleTags,
+			Value:  1,
+		}
+	}
+
+	return sseResponse, nil
+}
+
+// post runs fn on the event loop, unless the stream is closed by then.
+func (c *Client) post(fn func() error) {
+	c.loop.RegisterCallback()(func() error {
+		select {
+		case <-c.done:
+			return nil
+		default:
+			return fn()
+		}
+	})
+}
+
+// On registers a handler for an event: "open", "event" (called with every event received from
+// the server, whatever its name), "error" or "close".
+func (c *Client) On(event string, handler goja.Value) {
+	if handler, ok := goja.AssertFunction(handler); ok {
+		c.eventHandlers[event] = append(c.eventHandlers[event], handler)
+	}
+}
+
+func (c *Client) handleEvent(event string, args ...goja.Value) {
+	if handlers, ok := c.eventHandlers[event]; ok {
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), args...); err != nil {
+				common.Throw(common.GetRuntime(c.ctx), err)
+			}
+		}
+	}
+}
+
+// SetTimeout calls fn on the event loop after the timeout, unless the stream is closed by then.
+func (c *Client) SetTimeout(fn goja.Callable, timeoutMs int) {
+	go func() {
+		timer := time.NewTimer(time.Duration(timeoutMs) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			c.post(func() error {
+				_, err := fn(goja.Undefined())
+				return err
+			})
+		case <-c.done:
+		}
+	}()
+}
+
+// SetInterval calls fn on the event loop every interval, until the stream is closed.
+func (c *Client) SetInterval(fn goja.Callable, intervalMs int) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.post(func() error {
+					_, err := fn(goja.Undefined())
+					return err
+				})
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+func (c *Client) Close() {
+	c.closeConnection()
+}
+
+// Closes the stream, if that wasn't done already, and stops the main control loop
+func (c *Client) closeConnection() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	c.cancel()
+	c.handleEvent("close")
+	close(c.done)
+}
+
+// Reads the stream and parses it into events, as described in
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+func readPump(body io.Reader, eventChan chan<- Event, errorChan chan<- error, done <-chan struct{}) {
+	reader := bufio.NewReader(body)
+	var event Event
+	var data strings.Builder
+	lastID := ""
+
+	for first := true; ; first = false {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// An incomplete event at the end of the stream is discarded
+			select {
+			case errorChan <- err:
+			case <-done:
+			}
+			return
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" {
+			// A blank line dispatches the event, if it has any data
+			if data.Len() > 0 {
+				event.ID = lastID
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Name == "" {
+					event.Name = "message"
+				}
+				select {
+				case eventChan <- event:
+				case <-done:
+					return
+				}
+			}
+			event = Event{}
+			data.Reset()
+			continue
+		}
+		if line[0] == ':' {
+			continue // A comment, often used as a keep-alive
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Name = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				lastID = value
+			}
+		}
+	}
+}
+
+// Wrap the raw HTTPResponse we received to a SSEHTTPResponse we can pass to the user
+func wrapHTTPResponse(httpResponse *http.Response) *SSEHTTPResponse {
+	sseResponse := SSEHTTPResponse{
+		Status:  httpResponse.StatusCode,
+		Headers: make(map[string]string, len(httpResponse.Header)),
+	}
+	for k, vs := range httpResponse.Header {
+		sseResponse.Headers[k] = strings.Join(vs, ", ")
+	}
+	return &sseResponse
+}
@@ -0,0 +1,231 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sse
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	stream := "\ufeff: a comment\n" +
+		"data: first\n\n" +
+		"event: update\r\nid: 1\r\ndata: {\"a\":1}\r\ndata:second line\r\n\r\n" +
+		"retry: 1000\nunknown: field\n\n" +
+		"data\n\n" +
+		"id: 2\nevent: incomplete\ndata: discarded"
+
+	eventChan := make(chan Event)
+	errChan := make(chan error)
+	go readPump(strings.NewReader(stream), eventChan, errChan, make(chan struct{}))
+
+	var events []Event
+	for {
+		select {
+		case event := <-eventChan:
+			events = append(events, event)
+			continue
+		case err := <-errChan:
+			assert.Equal(t, "EOF", err.Error())
+		}
+		break
+	}
+	assert.Equal(t, []Event{
+		{Name: "message", Data: "first"},
+		{ID: "1", Name: "update", Data: "{\"a\":1}\nsecond line"},
+		{ID: "1", Name: "message", Data: ""},
+	}, events)
+}
+
+func TestOpen(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Token", r.Header.Get("Authorization"))
+		for i := 1; i <= 3; i++ {
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: tick\ndata: %d\n\n", i, i)
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/forever", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		_, _ = fmt.Fprint(w, "data: hello\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, "{}")
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	dialer := netext.NewDialer(net.Dialer{Timeout: 10 * time.Second})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:         root,
+		Dialer:        dialer,
+		HTTPTransport: netext.NewHTTPTransport(&http.Transport{DialContext: dialer.DialContext}),
+		Options: lib.Options{
+			SystemTags: lib.GetTagSet("url", "group", "status"),
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("sse", common.Bind(rt, New(), &ctx))
+	rt.Set("SERVER_URL", srv.URL)
+
+	t.Run("init context", func(t *testing.T) {
+		_, err := common.RunString(rt, `sse.open(SERVER_URL + "/events", function(client) {})`)
+		assert.EqualError(t, err, "GoError: Event streams can't be opened in the init context")
+	})
+
+	ctx = common.WithState(ctx, state)
+
+	t.Run("events", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let events = [], opened = false, closed = false;
+		let res = sse.open(SERVER_URL + "/events", { method: "post", headers: { "Authorization": "Bearer token" } }, function(client) {
+			client.on("open", function() { opened = true; });
+			client.on("event", function(e) { events.push(e.id + ":" + e.name + ":" + e.data); });
+			client.on("close", function() { closed = true; });
+			client.on("error", function(e) { throw new Error("unexpected error: " + e); });
+		});
+		if (res.status !== 200) { throw new Error("wrong status: " + res.status); }
+		if (res.headers["X-Method"] !== "POST" || res.headers["X-Token"] !== "Bearer token") {
+			throw new Error("wrong request: " + JSON.stringify(res.headers));
+		}
+		if (!opened || !closed) { throw new Error("wasn't opened and closed"); }
+		if (events.join(",") !== "1:tick:1,2:tick:2,3:tick:3") { throw new Error("wrong events: " + events); }
+		`)
+		assert.NoError(t, err)
+
+		seen := map[*stats.Metric]float64{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, sample := range sc.GetSamples() {
+				tags := sample.Tags.CloneTags()
+				assert.Equal(t, srv.URL+"/events", tags["url"])
+				assert.Equal(t, "200", tags["status"])
+				if sample.Metric.Type == stats.Counter {
+					seen[sample.Metric] += sample.Value
+				} else {
+					seen[sample.Metric] = 1
+				}
+			}
+		}
+		assert.Equal(t, map[*stats.Metric]float64{
+			metrics.SSESessions:         1,
+			metrics.SSEConnecting:       1,
+			metrics.SSESessionDuration:  1,
+			metrics.SSETimeToFirstEvent: 1,
+			metrics.SSEEventsReceived:   3,
+		}, seen)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let received = 0;
+		sse.open(SERVER_URL + "/forever", function(client) {
+			client.on("event", function(e) {
+				received++;
+				client.setTimeout(function() { client.close(); }, 10);
+			});
+			client.on("error", function(e) { throw new Error("unexpected error: " + e); });
+		});
+		if (received !== 1) { throw new Error("wrong number of events: " + received); }
+		`)
+		assert.NoError(t, err)
+		stats.GetBufferedSamples(samples)
+	})
+
+	t.Run("not an event stream", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let error = null, opened = false;
+		let res = sse.open(SERVER_URL + "/json", function(client) {
+			client.on("open", function() { opened = true; });
+			client.on("error", function(e) { error = e; });
+		});
+		if (opened) { throw new Error("was opened"); }
+		if (!error || res.error.indexOf("expected 200 and 'text/event-stream'") < 0) { throw new Error("no error: " + res.error); }
+		`)
+		assert.NoError(t, err)
+
+		containers := stats.GetBufferedSamples(samples)
+		require.Len(t, containers, 1)
+		assert.Len(t, containers[0].GetSamples(), 2)
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let error = null;
+		try {
+			sse.open("http://127.0.0.1:1/events", function(client) {
+				client.on("error", function(e) { error = e; });
+			});
+		} catch (e) {}
+		if (!error) { throw new Error("no error"); }
+		`)
+		assert.NoError(t, err)
+	})
+
+	t.Run("event loop", func(t *testing.T) {
+		loop := common.NewEventLoop(rt)
+		loop.Bind()
+		ctx = common.WithEventLoop(ctx, loop)
+
+		_, err := common.RunString(rt, `
+		let ticks = 0;
+		let heartbeat = setInterval(function() { ticks++; }, 1);
+		sse.open(SERVER_URL + "/forever", function(client) {
+			client.on("event", function(e) { setTimeout(function() { client.close(); }, 20); });
+			client.on("error", function(e) { throw new Error("unexpected error: " + e); });
+		});
+		clearInterval(heartbeat);
+		if (ticks < 2) { throw new Error("the global timers didn't run: " + ticks); }
+		`)
+		assert.NoError(t, err)
+		assert.NoError(t, loop.Run(context.Background()))
+		stats.GetBufferedSamples(samples)
+	})
+}
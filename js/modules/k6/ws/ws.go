@@ -23,10 +23,10 @@ package ws
 import (
 	"context"
 	"crypto/tls"
-	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,7 +36,9 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
 )
 
 type WS struct{}
@@ -50,7 +52,7 @@ type Socket struct {
 	ctx           context.Context
 	conn          *websocket.Conn
 	eventHandlers map[string][]goja.Callable
-	scheduled     chan goja.Callable
+	loop          *common.EventLoop
 	done          chan struct{}
 	shutdownOnce  sync.Once
 
@@ -101,6 +103,8 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 
 	// Leave header to nil by default so we can pass it directly to the Dialer
 	var header http.Header
+	var subprotocols []string
+	enableCompression := false
 
 	tags := state.Options.RunTags.CloneTags()
 
@@ -122,6 +126,23 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 				for _, key := range headersObj.Keys() {
 					header.Set(key, headersObj.Get(key).String())
 				}
+			case "subprotocols":
+				subprotocolsV := params.Get(k)
+				if goja.IsUndefined(subprotocolsV) || goja.IsNull(subprotocolsV) {
+					continue
+				}
+				if err := rt.ExportTo(subprotocolsV, &subprotocols); err != nil {
+					return nil, errors.Wrap(err, "subprotocols have to be an array of strings")
+				}
+			case "compression":
+				// Like in browsers, permessage-deflate is the only extension that's negotiated
+				switch algorithm := params.Get(k).String(); algorithm {
+				case "deflate":
+					enableCompression = true
+				case "", "undefined", "null":
+				default:
+					return nil, errors.Errorf("unsupported compression algorithm '%s', only 'deflate' is supported", algorithm)
+				}
 			case "tags":
 				tagsV := params.Get(k)
 				if goja.IsUndefined(tagsV) || goja.IsNull(tagsV) {
@@ -159,10 +180,31 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 		tlsConfig.NextProtos = []string{"http/1.1"}
 	}
 
+	// gorilla/websocket can only CONNECT through plain HTTP proxies, so SOCKS5 ones are dialed here
+	proxyURL, err := getProxyURL(state, url)
+	if err != nil {
+		return nil, err
+	}
+	var proxy func(*http.Request) (*neturl.URL, error)
+	if proxyURL != nil {
+		switch proxyURL.Scheme {
+		case "http":
+			proxy = http.ProxyURL(proxyURL)
+		case "socks5":
+			netDial = func(network, address string) (net.Conn, error) {
+				return netext.DialSOCKS5(ctx, state.Dialer.DialContext, proxyURL, network, address)
+			}
+		default:
+			return nil, errors.New("Only http and socks5 proxies are supported for websockets")
+		}
+	}
+
 	wsd := websocket.Dialer{
-		NetDial:         netDial,
-		Proxy:           http.ProxyFromEnvironment,
-		TLSClientConfig: tlsConfig,
+		NetDial:           netDial,
+		Proxy:             proxy,
+		TLSClientConfig:   tlsConfig,
+		Subprotocols:      subprotocols,
+		EnableCompression: enableCompression,
 	}
 
 	start := time.Now()
@@ -170,20 +212,23 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 	connectionEnd := time.Now()
 	connectionDuration := stats.D(connectionEnd.Sub(start))
 
+	// The connection's events and timers are forwarded to the VU's event loop, which runs their
+	// handlers between the callbacks of the script's timers and Promises, until the socket is
+	// closed. All JS code (including error handlers) runs on the loop, to avoid race conditions.
+	loop := common.GetEventLoop(ctx)
+	if loop == nil {
+		loop = common.NewEventLoop(rt)
+	}
 	socket := Socket{
 		ctx:                ctx,
 		conn:               conn,
 		eventHandlers:      make(map[string][]goja.Callable),
+		loop:               loop,
 		pingSendTimestamps: make(map[string]time.Time),
-		scheduled:          make(chan goja.Callable),
 		done:               make(chan struct{}),
 	}
 
-	if state.Options.SystemTags["ip"] && conn.RemoteAddr() != nil {
-		if ip, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
-			tags["ip"] = ip
-		}
-	}
+	state.Options.SystemTags.SetRemoteAddrTags(tags, conn.RemoteAddr())
 
 	// Run the user-provided set up function
 	if _, err := setupFn(goja.Undefined(), rt.ToValue(&socket)); err != nil {
@@ -221,98 +266,141 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 	conn.SetPingHandler(func(msg string) error { pingChan <- msg; return nil })
 	conn.SetPongHandler(func(pingID string) error { pongChan <- pingID; return nil })
 
-	readDataChan := make(chan []byte)
+	readDataChan := make(chan message)
 	readCloseChan := make(chan int)
 	readErrChan := make(chan error)
 
 	// Wraps a couple of channels around conn.ReadMessage
 	go readPump(conn, readDataChan, readErrChan, readCloseChan)
 
-	// This is the main control loop. All JS code (including error handlers)
-	// should only be executed by this thread to avoid race conditions
-	for {
-		select {
-		case pingData := <-pingChan:
-			// Handle pings received from the server
-			// - trigger the `ping` event
-			// - reply with pong (needed when `SetPingHandler` is overwritten)
-			err := socket.conn.WriteControl(websocket.PongMessage, []byte(pingData), time.Now().Add(writeWait))
-			if err != nil {
-				socket.handleEvent("error", rt.ToValue(err))
-			}
-			socket.handleEvent("ping")
+	go func() {
+		ctxDone := ctx.Done()
+		for {
+			var fn func() error
+			select {
+			case pingData := <-pingChan:
+				// Handle pings received from the server
+				// - trigger the `ping` event
+				// - reply with pong (needed when `SetPingHandler` is overwritten)
+				fn = func() error {
+					err := socket.conn.WriteControl(websocket.PongMessage, []byte(pingData), time.Now().Add(writeWait))
+					if err != nil {
+						socket.handleEvent("error", rt.ToValue(err))
+					}
+					socket.handleEvent("ping")
+					return nil
+				}
 
-		case pingID := <-pongChan:
-			// Handle pong responses to our pings
-			socket.trackPong(pingID)
-			socket.handleEvent("pong")
+			case pingID := <-pongChan:
+				// Handle pong responses to our pings
+				fn = func() error {
+					socket.trackPong(pingID)
+					socket.handleEvent("pong")
+					return nil
+				}
 
-		case readData := <-readDataChan:
-			socket.msgReceivedTimestamps = append(socket.msgReceivedTimestamps, time.Now())
-			socket.handleEvent("message", rt.ToValue(string(readData)))
+			case readData := <-readDataChan:
+				received := time.Now()
+				fn = func() error {
+					socket.msgReceivedTimestamps = append(socket.msgReceivedTimestamps, received)
+					// Scripts that don't handle binary messages get them as text, like before
+					if readData.binary && len(socket.eventHandlers["binaryMessage"]) > 0 {
+						socket.handleEvent("binaryMessage", rt.ToValue(readData.data))
+					} else {
+						socket.handleEvent("message", rt.ToValue(string(readData.data)))
+					}
+					return nil
+				}
+
+			case readErr := <-readErrChan:
+				fn = func() error {
+					socket.handleEvent("error", rt.ToValue(readErr))
+					return nil
+				}
 
-		case readErr := <-readErrChan:
-			socket.handleEvent("error", rt.ToValue(readErr))
+			case readClose := <-readCloseChan:
+				// handle server close
+				fn = func() error {
+					socket.handleEvent("close", rt.ToValue(readClose))
+					return nil
+				}
 
-		case readClose := <-readCloseChan:
-			// handle server close
-			socket.handleEvent("close", rt.ToValue(readClose))
+			case <-ctxDone:
+				// VU is shutting down during an interrupt
+				// socket events will not be forwarded to the VU
+				ctxDone = nil
+				fn = func() error {
+					_ = socket.closeConnection(websocket.CloseGoingAway)
+					return nil
+				}
 
-		case scheduledFn := <-socket.scheduled:
-			if _, err := scheduledFn(goja.Undefined()); err != nil {
-				return nil, err
+			case <-socket.done:
+				return
 			}
+			socket.post(fn)
+		}
+	}()
 
-		case <-ctx.Done():
-			// VU is shutting down during an interrupt
-			// socket events will not be forwarded to the VU
-			_ = socket.closeConnection(websocket.CloseGoingAway)
-
-		case <-socket.done:
-			// This is the final exit point normally triggered by closeConnection
-			end := time.Now()
-			sessionDuration := stats.D(end.Sub(start))
+	// closeConnection closes the done channel, which is the normal exit point
+	if err := loop.RunUntil(socket.done); err != nil {
+		socket.shutdownOnce.Do(func() { close(socket.done) })
+		return nil, err
+	}
 
-			sampleTags := stats.IntoSampleTags(&tags)
+	// The session is over, emit its metrics
+	end := time.Now()
+	sessionDuration := stats.D(end.Sub(start))
 
-			state.Samples <- stats.ConnectedSamples{
-				[]stats.Sample{
-					{Metric: metrics.WSSessions, Time: start, Tags: sampleTags, Value: 1},
-					{Metric: metrics.WSConnecting, Time: start, Tags: sampleTags, Value: connectionDuration},
-					{Metric: metrics.WSSessionDuration, Time: start, Tags: sampleTags, Value: sessionDuration},
-				}, sampleTags, start,
-			}
+	sampleTags := stats.IntoSampleTags(&tags)
 
-			for _, msgSentTimestamp := range socket.msgSentTimestamps {
-				state.Samples <- stats.Sample{
-					Metric: metrics.WSMessagesSent,
-					Time:   msgSentTimestamp,
-					Tags:   sampleTags,
-					Value:  1,
-				}
-			}
+	state.Samples <- stats.ConnectedSamples{
+		[]stats.Sample{
+			{Metric: metrics.WSSessions, Time: start, Tags: sampleTags, Value: 1},
+			{Metric: metrics.WSConnecting, Time: start, Tags: sampleTags, Value: connectionDuration},
+			{Metric: metrics.WSSessionDuration, Time: start, Tags: sampleTags, Value: sessionDuration},
+		}, sampleTags, start,
+	}
 
-			for _, msgReceivedTimestamp := range socket.msgReceivedTimestamps {
-				state.Samples <- stats.Sample{
-					Metric: metrics.WSMessagesReceived,
-					Time:   msgReceivedTimestamp,
-					Tags:   sampleTags,
-					Value:  1,
-				}
-			}
+	for _, msgSentTimestamp := range socket.msgSentTimestamps {
+		state.Samples <- stats.Sample{
+			Metric: metrics.WSMessagesSent,
+			Time:   msgSentTimestamp,
+			Tags:   sampleTags,
+			Value:  1,
+		}
+	}
 
-			for _, pingDelta := range socket.pingTimestamps {
-				state.Samples <- stats.Sample{
-					Metric: metrics.WSPing,
-					Time:   pingDelta.pong,
-					Tags:   sampleTags,
-					Value:  stats.D(pingDelta.pong.Sub(pingDelta.ping)),
-				}
-			}
+	for _, msgReceivedTimestamp := range socket.msgReceivedTimestamps {
+		state.Samples <- stats.Sample{
+			Metric: metrics.WSMessagesReceived,
+			Time:   msgReceivedTimestamp,
+			Tags:   sampleTags,
+			Value:  1,
+		}
+	}
 
-			return wsResponse, nil
+	for _, pingDelta := range socket.pingTimestamps {
+		state.Samples <- stats.Sample{
+			Metric: metrics.WSPing,
+			Time:   pingDelta.pong,
+			Tags:   sampleTags,
+			Value:  stats.D(pingDelta.pong.Sub(pingDelta.ping)),
 		}
 	}
+
+	return wsResponse, nil
+}
+
+// post runs fn on the event loop, unless the socket is closed by then.
+func (s *Socket) post(fn func() error) {
+	s.loop.RegisterCallback()(func() error {
+		select {
+		case <-s.done:
+			return nil
+		default:
+			return fn()
+		}
+	})
 }
 
 func (s *Socket) On(event string, handler goja.Value) {
@@ -332,18 +420,42 @@ func (s *Socket) handleEvent(event string, args ...goja.Value) {
 }
 
 func (s *Socket) Send(message string) {
-	// NOTE: No binary message support for the time being since goja doesn't
-	// support typed arrays.
-	rt := common.GetRuntime(s.ctx)
+	s.write(websocket.TextMessage, []byte(message))
+}
 
-	writeData := []byte(message)
-	if err := s.conn.WriteMessage(websocket.TextMessage, writeData); err != nil {
-		s.handleEvent("error", rt.ToValue(err))
+// SendBinary sends a binary message, with the bytes of an array, e.g. from open(file, "b").
+func (s *Socket) SendBinary(data goja.Value) {
+	var bytes []byte
+	if data != nil && !goja.IsUndefined(data) && !goja.IsNull(data) {
+		switch d := data.Export().(type) {
+		case []byte:
+			bytes = d
+		case []interface{}:
+			bytes = make([]byte, len(d))
+			for i, e := range d {
+				n, _ := e.(int64)
+				bytes[i] = byte(n)
+			}
+		default:
+			common.Throw(common.GetRuntime(s.ctx), errors.Errorf("sendBinary() takes an array of bytes, not %T", d))
+		}
+	}
+	s.write(websocket.BinaryMessage, bytes)
+}
+
+func (s *Socket) write(messageType int, data []byte) {
+	if err := s.conn.WriteMessage(messageType, data); err != nil {
+		s.handleEvent("error", common.GetRuntime(s.ctx).ToValue(err))
 	}
 
 	s.msgSentTimestamps = append(s.msgSentTimestamps, time.Now())
 }
 
+// Subprotocol returns the subprotocol that the server picked from the requested ones, if any.
+func (s *Socket) Subprotocol() string {
+	return s.conn.Subprotocol()
+}
+
 func (s *Socket) Ping() {
 	rt := common.GetRuntime(s.ctx)
 	deadline := time.Now().Add(writeWait)
@@ -373,32 +485,34 @@ func (s *Socket) trackPong(pingID string) {
 	s.pingTimestamps = append(s.pingTimestamps, pingDelta{pingTimestamp, pongTimestamp})
 }
 
+// SetTimeout calls fn on the event loop after the timeout, unless the socket is closed by then.
 func (s *Socket) SetTimeout(fn goja.Callable, timeoutMs int) {
-	// Starts a goroutine, blocks once on the timeout and pushes the callable
-	// back to the main loop through the scheduled channel
 	go func() {
+		timer := time.NewTimer(time.Duration(timeoutMs) * time.Millisecond)
+		defer timer.Stop()
 		select {
-		case <-time.After(time.Duration(timeoutMs) * time.Millisecond):
-			s.scheduled <- fn
-
+		case <-timer.C:
+			s.post(func() error {
+				_, err := fn(goja.Undefined())
+				return err
+			})
 		case <-s.done:
-			return
 		}
 	}()
 }
 
+// SetInterval calls fn on the event loop every interval, until the socket is closed.
 func (s *Socket) SetInterval(fn goja.Callable, intervalMs int) {
-	// Starts a goroutine, blocks forever on the ticker and pushes the callable
-	// back to the main loop through the scheduled channel
 	go func() {
 		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
 		defer ticker.Stop()
-
 		for {
 			select {
 			case <-ticker.C:
-				s.scheduled <- fn
-
+				s.post(func() error {
+					_, err := fn(goja.Undefined())
+					return err
+				})
 			case <-s.done:
 				return
 			}
@@ -443,12 +557,18 @@ func (s *Socket) closeConnection(code int) error {
 	return err
 }
 
+// A message read from the connection.
+type message struct {
+	binary bool
+	data   []byte
+}
+
 // Wraps conn.ReadMessage in a channel
-func readPump(conn *websocket.Conn, readChan chan []byte, errorChan chan error, closeChan chan int) {
+func readPump(conn *websocket.Conn, readChan chan message, errorChan chan error, closeChan chan int) {
 	defer func() { _ = conn.Close() }()
 
 	for {
-		_, message, err := conn.ReadMessage()
+		messageType, data, err := conn.ReadMessage()
 		if err != nil {
 
 			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
@@ -463,7 +583,7 @@ func readPump(conn *websocket.Conn, readChan chan []byte, errorChan chan error,
 			return
 		}
 
-		readChan <- message
+		readChan <- message{binary: messageType == websocket.BinaryMessage, data: data}
 	}
 }
 
@@ -492,3 +612,23 @@ func wrapHTTPResponse(httpResponse *http.Response) (*WSHTTPResponse, error) {
 
 	return &wsResponse, nil
 }
+
+// getProxyURL returns the proxy that should be used to connect to the given websocket URL, if any.
+func getProxyURL(state *common.State, wsURL string) (*neturl.URL, error) {
+	proxy, err := netext.NewProxyFunc(state.Options.Proxy.String, state.Options.NoProxy.String)
+	if err != nil {
+		return nil, err
+	}
+	u, err := neturl.Parse(wsURL)
+	if err != nil {
+		return nil, err
+	}
+	// Proxies are picked by the http(s) scheme, like websocket.Dialer itself does.
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	return proxy(&http.Request{URL: u})
+}
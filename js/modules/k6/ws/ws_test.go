@@ -24,12 +24,14 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dop251/goja"
+	"github.com/gorilla/websocket"
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
@@ -467,3 +469,98 @@ func TestTLSConfig(t *testing.T) {
 	})
 	assertSessionMetricsEmitted(t, stats.GetBufferedSamples(samples), "", url, 101, "")
 }
+
+func TestSessionOptions(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	assert.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &common.State{
+		Group:   root,
+		Dialer:  netext.NewDialer(net.Dialer{Timeout: 10 * time.Second}),
+		Options: lib.Options{SystemTags: lib.GetTagSet("url", "status", "subproto")},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = common.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+
+	rt.Set("ws", common.Bind(rt, New(), &ctx))
+
+	tb := testutils.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	// Echoes messages with their type, and sends the picked extensions first
+	tb.Mux.HandleFunc("/ws-negotiate", func(w http.ResponseWriter, req *http.Request) {
+		upgrader := websocket.Upgrader{EnableCompression: true, Subprotocols: []string{"graphql-ws", "chat"}}
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+	url := makeWsProto(tb.ServerHTTP.URL) + "/ws-negotiate"
+
+	t.Run("compression and subprotocols", func(t *testing.T) {
+		_, err := common.RunString(rt, fmt.Sprintf(`
+		let text = new Array(101).join("k6 ");
+		let res = ws.connect("%s", { compression: "deflate", subprotocols: ["chat", "graphql-ws"] }, function(socket) {
+			if (socket.subprotocol() !== "graphql-ws") { throw new Error("wrong subprotocol: " + socket.subprotocol()); }
+			socket.on("open", function() {
+				socket.send(text);
+			});
+			socket.on("message", function(data) {
+				if (data !== text) { throw new Error("wrong message: " + data); }
+				socket.close();
+			});
+		});
+		if (res.headers["Sec-Websocket-Extensions"].indexOf("permessage-deflate") !== 0) {
+			throw new Error("compression wasn't negotiated: " + JSON.stringify(res.headers));
+		}
+		`, url))
+		assert.NoError(t, err)
+	})
+	assertSessionMetricsEmitted(t, stats.GetBufferedSamples(samples), "graphql-ws", url, 101, "")
+
+	t.Run("binary messages", func(t *testing.T) {
+		_, err := common.RunString(rt, fmt.Sprintf(`
+		let texts = [];
+		ws.connect("%s", function(socket) {
+			socket.on("open", function() {
+				socket.sendBinary([0, 1, 2, 255]);
+			});
+			socket.on("binaryMessage", function(data) {
+				if (data.length !== 4 || data[0] !== 0 || data[3] !== 255) { throw new Error("wrong data: " + data); }
+				socket.send("done");
+			});
+			socket.on("message", function(data) {
+				texts.push(data);
+				socket.close();
+			});
+		});
+		if (texts.join() !== "done") { throw new Error("wrong text messages: " + texts); }
+		`, url))
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := common.RunString(rt, fmt.Sprintf(`
+		ws.connect("%s", { compression: "br" }, function(socket) {});
+		`, url))
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "unsupported compression algorithm 'br'")
+		}
+	})
+}
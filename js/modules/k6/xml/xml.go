@@ -0,0 +1,167 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type XML struct{}
+
+func New() *XML {
+	return &XML{}
+}
+
+// ParseXML parses an XML document and returns its root element.
+func (XML) ParseXML(ctx context.Context, src string) (*Element, error) {
+	return Parse(src)
+}
+
+// An Element is an XML element; its methods are what scripts use to navigate documents.
+type Element struct {
+	Name       string            `js:"name"`
+	Namespace  string            `js:"namespace"`
+	Attributes map[string]string `js:"attributes"`
+
+	parent   *Element
+	children []*Element
+	text     strings.Builder // Including the text of all descendants.
+
+	// Maps the namespace prefixes in scope to their URLs; shared with the parent, unless the
+	// element declares prefixes of its own.
+	prefixes    map[string]string
+	ownPrefixes bool
+}
+
+// Parse parses an XML document and returns its root element.
+func Parse(src string) (*Element, error) {
+	decoder := xml.NewDecoder(strings.NewReader(src))
+	decoder.Entity = xml.HTMLEntity // HTML entities like &nbsp; are common in real-world XML
+
+	// The document node is above the root element, so that absolute paths can select the root.
+	doc := &Element{
+		prefixes:    map[string]string{"xml": "http://www.w3.org/XML/1998/namespace"},
+		ownPrefixes: true,
+	}
+	current := doc
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid XML")
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			el := &Element{
+				Name:       t.Name.Local,
+				Namespace:  t.Name.Space,
+				Attributes: make(map[string]string, len(t.Attr)),
+				parent:     current,
+				prefixes:   current.prefixes,
+			}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns":
+					el.declare(attr.Name.Local, attr.Value)
+				case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+					el.declare("", attr.Value)
+				default:
+					el.Attributes[attr.Name.Local] = attr.Value
+				}
+			}
+			current.children = append(current.children, el)
+			current = el
+		case xml.EndElement:
+			if current.parent == nil {
+				return nil, errors.Errorf("invalid XML: unexpected end element </%s>", t.Name.Local)
+			}
+			current = current.parent
+		case xml.CharData:
+			// Every element has the text of all of its descendants, in document order
+			for el := current; el != nil; el = el.parent {
+				el.text.Write(t)
+			}
+		}
+	}
+
+	if len(doc.children) == 0 {
+		return nil, errors.New("invalid XML: the document has no root element")
+	}
+	return doc.children[0], nil
+}
+
+// declare adds a namespace prefix to the element's scope, without changing its parent's.
+func (e *Element) declare(prefix, url string) {
+	if !e.ownPrefixes {
+		prefixes := make(map[string]string, len(e.prefixes)+1)
+		for k, v := range e.prefixes {
+			prefixes[k] = v
+		}
+		e.prefixes = prefixes
+		e.ownPrefixes = true
+	}
+	e.prefixes[prefix] = url
+}
+
+// Text returns the text of the element and all of its descendants.
+func (e *Element) Text() string {
+	return e.text.String()
+}
+
+// Attr returns the value of an attribute, or an empty string if there's no such attribute.
+func (e *Element) Attr(name string) string {
+	return e.Attributes[name]
+}
+
+// Children returns the child elements.
+func (e *Element) Children() []*Element {
+	return e.children
+}
+
+// Parent returns the parent element, or nil for the root element.
+func (e *Element) Parent() *Element {
+	if e.parent == nil || e.parent.parent == nil {
+		return nil
+	}
+	return e.parent
+}
+
+// Find returns what an XPath selects, see Select.
+func (e *Element) Find(path string) ([]interface{}, error) {
+	return Select(e, path)
+}
+
+// FindOne returns the first thing an XPath selects, or nil.
+func (e *Element) FindOne(path string) (interface{}, error) {
+	results, err := Select(e, path)
+	if err != nil || len(results) == 0 {
+		return nil, err
+	}
+	return results[0], nil
+}
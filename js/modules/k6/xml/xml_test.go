@@ -0,0 +1,140 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSOAP = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:m="http://example.com/stock">
+  <soap:Header/>
+  <soap:Body>
+    <m:GetStockPriceResponse>
+      <m:Price currency="USD">34.5</m:Price>
+      <m:Price currency="EUR">29.9</m:Price>
+      <m:Symbol>K6</m:Symbol>
+      <Note xmlns="http://example.com/notes">a <b>bold</b> move</Note>
+    </m:GetStockPriceResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestSelect(t *testing.T) {
+	root, err := Parse(testSOAP)
+	require.NoError(t, err)
+	assert.Equal(t, "Envelope", root.Name)
+	assert.Equal(t, "http://schemas.xmlsoap.org/soap/envelope/", root.Namespace)
+
+	texts := func(results []interface{}) []string {
+		var s []string
+		for _, r := range results {
+			switch v := r.(type) {
+			case *Element:
+				s = append(s, v.Name+"="+v.Text())
+			case string:
+				s = append(s, v)
+			}
+		}
+		return s
+	}
+
+	testdata := map[string][]string{
+		"/soap:Envelope/soap:Body/m:GetStockPriceResponse/m:Symbol": {"Symbol=K6"},
+		"//Price":                      {"Price=34.5", "Price=29.9"},
+		"//m:Price[2]":                 {"Price=29.9"},
+		"//Price[last()]":              {"Price=29.9"},
+		"//Price[@currency='EUR']":     {"Price=29.9"},
+		"//Price[@currency]/@currency": {"USD", "EUR"},
+		"//Price[.='34.5']/@currency":  {"USD"},
+		"//GetStockPriceResponse[Symbol=\"K6\"]/Symbol/text()": {"K6"},
+		"//Note":           {"Note=a bold move"},
+		"//Note/b/..":      {"Note=a bold move"},
+		"/soap:Envelope/*": {"Header=", "Body=\n    \n      34.5\n      29.9\n      K6\n      a bold move\n    \n  "},
+		"//soap:Price":     nil,
+		"//m:Note":         nil,
+		"Body/*/Symbol":    {"Symbol=K6"},
+		"//Missing":        nil,
+	}
+	for path, expected := range testdata {
+		t.Run(path, func(t *testing.T) {
+			results, err := Select(root, path)
+			require.NoError(t, err)
+			assert.Equal(t, expected, texts(results))
+		})
+	}
+
+	t.Run("relative to an element", func(t *testing.T) {
+		prices, err := Select(root, "//Price")
+		require.NoError(t, err)
+		results, err := Select(prices[0].(*Element), "../Symbol")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Symbol=K6"}, texts(results))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testdata := map[string]string{
+			"":                  "invalid XPath: it's empty",
+			"//Price[@currency": "unbalanced brackets or quotes",
+			"//Price[@a=USD]":   "values have to be quoted",
+			"//@currency/Price": "@currency has to be the last step",
+			"//Price//":         "it can't end with //",
+		}
+		for path, msg := range testdata {
+			_, err := Select(root, path)
+			if assert.Error(t, err, path) {
+				assert.Contains(t, err.Error(), msg)
+			}
+		}
+
+		_, err := Parse("<a><b></a>")
+		assert.Error(t, err)
+		_, err = Parse("just text")
+		assert.EqualError(t, err, "invalid XML: the document has no root element")
+	})
+}
+
+func TestParseXML(t *testing.T) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := context.Background()
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("xml", common.Bind(rt, New(), &ctx))
+	rt.Set("src", testSOAP)
+
+	_, err := common.RunString(rt, `
+	let doc = xml.parseXML(src);
+	if (doc.name !== "Envelope") { throw new Error("wrong root: " + doc.name); }
+	let price = doc.findOne("//Price[@currency='USD']");
+	if (price.text() !== "34.5") { throw new Error("wrong price: " + price.text()); }
+	if (price.attr("currency") !== "USD" || price.attributes.currency !== "USD") { throw new Error("wrong attributes"); }
+	if (price.parent().children().length !== 4) { throw new Error("wrong number of children"); }
+	if (doc.find("//Price/@currency").join(",") !== "USD,EUR") { throw new Error("wrong currencies"); }
+	if (doc.findOne("//Missing") !== null) { throw new Error("found something missing"); }
+	if (doc.parent() !== null) { throw new Error("the root has a parent"); }
+	`)
+	assert.NoError(t, err)
+}
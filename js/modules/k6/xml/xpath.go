@@ -0,0 +1,286 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package xml
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A step of an XPath, e.g. `//soap:Body` or `item[@id='1']`.
+type step struct {
+	descendant bool
+	test       string // ".", "..", "*", "name", "prefix:name", "@attr", "@*" or "text()"
+	predicates []string
+}
+
+// Select evaluates an XPath against an element, and returns the elements it selects, or strings
+// if it ends with an @attribute or text().
+//
+// Only a subset of XPath 1.0 is supported: absolute and relative location paths with the child
+// (`/`) and descendant (`//`) axes, `.`, `..`, `*`, and predicates with positions (`[2]`,
+// `[last()]`), attributes (`[@id]`, `[@id='1']`) and child element text (`[name='x']`).
+// Names without a prefix match elements in any namespace; names with a prefix match elements in
+// the namespace that the prefix is bound to in the document.
+func Select(e *Element, path string) ([]interface{}, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*Element{e}
+	if strings.HasPrefix(strings.TrimSpace(path), "/") {
+		for nodes[0].parent != nil {
+			nodes[0] = nodes[0].parent
+		}
+	}
+
+	for i, s := range steps {
+		if s.test == "text()" || strings.HasPrefix(s.test, "@") {
+			if i != len(steps)-1 {
+				return nil, errors.Errorf("invalid XPath '%s': %s has to be the last step", path, s.test)
+			}
+			return selectValues(nodes, s), nil
+		}
+
+		var next []*Element
+		seen := make(map[*Element]bool)
+		for _, node := range nodes {
+			var candidates []*Element
+			switch {
+			case s.test == ".":
+				candidates = []*Element{node}
+			case s.test == "..":
+				if node.parent != nil {
+					candidates = []*Element{node.parent}
+				}
+			case s.descendant:
+				candidates = node.descendants(nil)
+			default:
+				candidates = node.children
+			}
+
+			var matched []*Element
+			for _, c := range candidates {
+				if s.test == "." || s.test == ".." || c.matches(s.test) {
+					matched = append(matched, c)
+				}
+			}
+			for _, predicate := range s.predicates {
+				if matched, err = filter(matched, predicate); err != nil {
+					return nil, errors.Wrapf(err, "invalid XPath '%s'", path)
+				}
+			}
+			for _, m := range matched {
+				if !seen[m] {
+					seen[m] = true
+					next = append(next, m)
+				}
+			}
+		}
+		nodes = next
+	}
+
+	results := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		if node.parent == nil {
+			continue // The document node isn't an element
+		}
+		results = append(results, node)
+	}
+	return results, nil
+}
+
+func parsePath(path string) ([]step, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, errors.New("invalid XPath: it's empty")
+	}
+
+	var steps []step
+	descendant := false
+	for len(path) > 0 {
+		if strings.HasPrefix(path, "//") {
+			descendant = true
+			path = path[2:]
+			continue
+		}
+		if path[0] == '/' {
+			path = path[1:]
+			continue
+		}
+
+		// Find the end of the step, skipping over slashes in predicates
+		end, depth, quote := len(path), 0, byte(0)
+		for i := 0; i < len(path) && end == len(path); i++ {
+			switch c := path[i]; {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '\'' || c == '"':
+				quote = c
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+			case c == '/' && depth == 0:
+				end = i
+			}
+		}
+		if depth != 0 || quote != 0 {
+			return nil, errors.Errorf("invalid XPath step '%s': unbalanced brackets or quotes", path[:end])
+		}
+
+		s := step{descendant: descendant}
+		raw := path[:end]
+		if i := strings.IndexByte(raw, '['); i >= 0 {
+			s.test = strings.TrimSpace(raw[:i])
+			for _, p := range strings.Split(raw[i+1:len(raw)-1], "][") {
+				s.predicates = append(s.predicates, strings.TrimSpace(p))
+			}
+		} else {
+			s.test = strings.TrimSpace(raw)
+		}
+		if s.test == "" {
+			return nil, errors.Errorf("invalid XPath step '%s'", raw)
+		}
+		steps = append(steps, s)
+		descendant = false
+		path = path[end:]
+	}
+	if descendant {
+		return nil, errors.New("invalid XPath: it can't end with //")
+	}
+	return steps, nil
+}
+
+func (e *Element) descendants(result []*Element) []*Element {
+	for _, c := range e.children {
+		result = append(result, c)
+		result = c.descendants(result)
+	}
+	return result
+}
+
+func (e *Element) matches(test string) bool {
+	if test == "*" {
+		return true
+	}
+	i := strings.IndexByte(test, ':')
+	if i < 0 {
+		return e.Name == test
+	}
+	prefix, local := test[:i], test[i+1:]
+	if local != "*" && e.Name != local {
+		return false
+	}
+	if url, ok := e.prefixes[prefix]; ok {
+		return e.Namespace == url
+	}
+	// Undeclared prefixes are kept as the namespace by encoding/xml
+	return e.Namespace == prefix
+}
+
+func filter(nodes []*Element, predicate string) ([]*Element, error) {
+	if predicate == "last()" {
+		if len(nodes) == 0 {
+			return nil, nil
+		}
+		return nodes[len(nodes)-1:], nil
+	}
+	if n, err := strconv.Atoi(predicate); err == nil {
+		if n < 1 || n > len(nodes) {
+			return nil, nil
+		}
+		return nodes[n-1 : n], nil
+	}
+
+	left, right, hasValue := predicate, "", false
+	if i := strings.IndexByte(predicate, '='); i >= 0 {
+		left, right, hasValue = strings.TrimSpace(predicate[:i]), strings.TrimSpace(predicate[i+1:]), true
+		if len(right) < 2 || (right[0] != '\'' && right[0] != '"') || right[len(right)-1] != right[0] {
+			return nil, errors.Errorf("unsupported predicate [%s], values have to be quoted", predicate)
+		}
+		right = right[1 : len(right)-1]
+	}
+
+	var result []*Element
+	for _, node := range nodes {
+		var values []string
+		switch {
+		case strings.HasPrefix(left, "@"):
+			if v, ok := node.Attributes[left[1:]]; ok {
+				values = append(values, v)
+			}
+		case left == "text()" || left == ".":
+			values = append(values, node.Text())
+		default:
+			for _, c := range node.children {
+				if c.matches(left) {
+					values = append(values, c.Text())
+				}
+			}
+		}
+		for _, v := range values {
+			if !hasValue || v == right {
+				result = append(result, node)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func selectValues(nodes []*Element, s step) []interface{} {
+	var values []interface{}
+	for _, node := range nodes {
+		candidates := []*Element{node}
+		if s.descendant {
+			candidates = append(candidates, node.descendants(nil)...)
+		}
+		for _, c := range candidates {
+			switch {
+			case s.test == "text()":
+				if c.parent != nil {
+					values = append(values, c.Text())
+				}
+			case s.test == "@*":
+				names := make([]string, 0, len(c.Attributes))
+				for name := range c.Attributes {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					values = append(values, c.Attributes[name])
+				}
+			default:
+				if v, ok := c.Attributes[s.test[1:]]; ok {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	return values
+}
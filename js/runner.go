@@ -34,6 +34,8 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/profile"
+	"github.com/loadimpact/k6/lib/tracing"
 	"github.com/loadimpact/k6/stats"
 	"github.com/oxtoacart/bpool"
 	"github.com/pkg/errors"
@@ -57,6 +59,16 @@ type Runner struct {
 	BaseDialer net.Dialer
 	Resolver   *dnscache.Resolver
 	RPSLimit   *rate.Limiter
+	LocalIPs   *netext.LocalIPPool
+
+	// Exports the sampled HTTP requests as spans, if a tracing endpoint is set.
+	TraceExporter *tracing.Exporter
+
+	// Where the VUs spend their time, if the test is profiled.
+	Profile *profile.Profile
+
+	// Limits the identical messages that the VUs log with console.
+	consoleLimiter logLimiter
 
 	setupData interface{}
 }
@@ -129,16 +141,12 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 
 	tlsAuth := r.Bundle.Options.TLSAuth
 	certs := make([]tls.Certificate, len(tlsAuth))
-	nameToCert := make(map[string]*tls.Certificate)
 	for i, auth := range tlsAuth {
-		for _, name := range auth.Domains {
-			cert, err := auth.Certificate()
-			if err != nil {
-				return nil, err
-			}
-			certs[i] = *cert
-			nameToCert[name] = &certs[i]
+		cert, err := auth.Certificate()
+		if err != nil {
+			return nil, err
 		}
+		certs[i] = *cert
 	}
 
 	dialer := &netext.Dialer{
@@ -146,6 +154,7 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		Resolver:  r.Resolver,
 		Blacklist: r.Bundle.Options.BlacklistIPs,
 		Hosts:     r.Bundle.Options.Hosts,
+		LocalIPs:  r.LocalIPs,
 	}
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: r.Bundle.Options.InsecureSkipTLSVerify.Bool,
@@ -153,34 +162,75 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		MinVersion:         uint16(tlsVersions.Min),
 		MaxVersion:         uint16(tlsVersions.Max),
 		Certificates:       certs,
-		NameToCertificate:  nameToCert,
 		Renegotiation:      tls.RenegotiateFreelyAsClient,
 	}
+	proxy, err := netext.NewProxyFunc(r.Bundle.Options.Proxy.String, r.Bundle.Options.NoProxy.String)
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConns, maxIdleConnsPerHost := r.Bundle.Options.MaxIdleConns, r.Bundle.Options.MaxIdleConnsPerHost
+	if maxIdleConns.Int64 < 0 {
+		return nil, errors.Errorf("invalid maxIdleConns %d, it can't be negative", maxIdleConns.Int64)
+	}
+	if maxIdleConnsPerHost.Valid && maxIdleConnsPerHost.Int64 < 1 {
+		return nil, errors.Errorf("invalid maxIdleConnsPerHost %d, it has to be at least 1", maxIdleConnsPerHost.Int64)
+	}
+	maxConnsPerHost := r.Bundle.Options.MaxConnsPerHost
+	if maxConnsPerHost.Int64 < 0 {
+		return nil, errors.Errorf("invalid maxConnsPerHost %d, it can't be negative", maxConnsPerHost.Int64)
+	}
 	transport := &http.Transport{
-		Proxy:              http.ProxyFromEnvironment,
-		TLSClientConfig:    tlsConfig,
-		DialContext:        dialer.DialContext,
-		DisableCompression: true,
-		DisableKeepAlives:  r.Bundle.Options.NoConnectionReuse.Bool,
+		Proxy:               proxy,
+		TLSClientConfig:     tlsConfig,
+		DialContext:         dialer.DialContext,
+		DisableCompression:  true,
+		DisableKeepAlives:   r.Bundle.Options.NoConnectionReuse.Bool,
+		MaxIdleConns:        int(maxIdleConns.Int64),
+		MaxIdleConnsPerHost: int(maxIdleConnsPerHost.Int64),
 	}
 	_ = http2.ConfigureTransport(transport)
 
+	var tracingClient *tracing.Client
+	if opts := r.Bundle.Options; opts.TracingPropagator.String != "" || r.TraceExporter != nil {
+		if err := tracing.ValidatePropagator(opts.TracingPropagator.String); err != nil {
+			return nil, err
+		}
+		sampling := 1.0
+		if opts.TracingSampling.Valid {
+			sampling = opts.TracingSampling.Float64
+		}
+		if sampling < 0 || sampling > 1 {
+			return nil, errors.Errorf("invalid tracing sampling %g, it has to be between 0 and 1", sampling)
+		}
+		tracingClient = &tracing.Client{
+			Propagator: opts.TracingPropagator.String,
+			Sampling:   sampling,
+			Exporter:   r.TraceExporter,
+		}
+	}
+
+	httpTransport := netext.NewHTTPTransport(transport)
+	httpTransport.MaxConnsPerHost = int(maxConnsPerHost.Int64)
+	if len(tlsAuth) > 0 {
+		httpTransport.ClientCertificate = clientCertificate(tlsAuth)
+	}
+
 	vu := &VU{
 		BundleInstance: *bi,
 		Runner:         r,
-		HTTPTransport:  netext.NewHTTPTransport(transport),
+		HTTPTransport:  httpTransport,
 		Dialer:         dialer,
 		TLSConfig:      tlsConfig,
+		Tracing:        tracingClient,
 		Console:        NewConsole(),
 		BPool:          bpool.NewBufferPool(100),
 		Samples:        samplesOut,
 	}
-	vu.Runtime.Set("console", common.Bind(vu.Runtime, vu.Console, vu.Context))
-	common.BindToGlobal(vu.Runtime, map[string]interface{}{
-		"open": func() {
-			common.Throw(vu.Runtime, errors.New("\"open\" function is only available to the init code (aka global scope), see https://docs.k6.io/docs/test-life-cycle for more information"))
-		},
-	})
+	vu.Console.limiter = &r.consoleLimiter
+	vu.bindGlobals()
+	if err := vu.checkMemory(); err != nil {
+		return nil, errors.Wrap(err, "init")
+	}
 
 	// Give the VU an initial sense of identity.
 	if err := vu.Reconfigure(0); err != nil {
@@ -190,6 +240,16 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 	return vu, nil
 }
 
+// bindGlobals adds the globals that VUs have on top of the init context's.
+func (u *VU) bindGlobals() {
+	u.Runtime.Set("console", common.Bind(u.Runtime, u.Console, u.Context))
+	common.BindToGlobal(u.Runtime, map[string]interface{}{
+		"open": func() {
+			common.Throw(u.Runtime, errors.New("\"open\" function is only available to the init code (aka global scope), see https://docs.k6.io/docs/test-life-cycle for more information"))
+		},
+	})
+}
+
 func (r *Runner) Setup(ctx context.Context, out chan<- stats.SampleContainer) error {
 	setupCtx, setupCancel := context.WithTimeout(
 		ctx,
@@ -224,6 +284,9 @@ func (r *Runner) Teardown(ctx context.Context, out chan<- stats.SampleContainer)
 		time.Duration(r.Bundle.Options.TeardownTimeout.Duration),
 	)
 	defer teardownCancel()
+	if r.TraceExporter != nil {
+		defer r.TraceExporter.Flush()
+	}
 
 	_, err := r.runPart(teardownCtx, out, "teardown", r.setupData)
 	return err
@@ -244,6 +307,30 @@ func (r *Runner) SetOptions(opts lib.Options) {
 	if rps := opts.RPS; rps.Valid {
 		r.RPSLimit = rate.NewLimiter(rate.Limit(rps.Int64), 1)
 	}
+
+	r.LocalIPs = nil
+	if len(opts.LocalIPs) > 0 {
+		r.LocalIPs = netext.NewLocalIPPool(opts.LocalIPs)
+	}
+
+	r.TraceExporter = nil
+	if endpoint := opts.TracingEndpoint; endpoint.Valid && endpoint.String != "" {
+		r.TraceExporter = tracing.NewExporter(endpoint.String, r.Logger)
+	}
+}
+
+// clientCertificate returns a function that picks the client certificate for HTTP requests to a
+// host, based on the domains each certificate is configured for. Connections not made by the HTTP
+// module, e.g. WebSockets, present the first one of the certificates that the server accepts.
+func clientCertificate(tlsAuth []*lib.TLSAuth) func(host string) (*tls.Certificate, error) {
+	return func(host string) (*tls.Certificate, error) {
+		for _, auth := range tlsAuth {
+			if auth.MatchesHost(host) {
+				return auth.Certificate()
+			}
+		}
+		return nil, nil
+	}
 }
 
 // Runs an exported function in its own temporary VU, optionally with an argument. Execution is
@@ -273,7 +360,7 @@ func (r *Runner) runPart(ctx context.Context, out chan<- stats.SampleContainer,
 		return goja.Undefined(), err
 	}
 
-	v, _, err := vu.runFn(ctx, group, fn, vu.Runtime.ToValue(arg))
+	v, _, err := vu.runFn(ctx, group, name, fn, vu.Runtime.ToValue(arg))
 	cancel()
 	return v, err
 }
@@ -285,6 +372,7 @@ type VU struct {
 	HTTPTransport *netext.HTTPTransport
 	Dialer        *netext.Dialer
 	TLSConfig     *tls.Config
+	Tracing       *tracing.Client
 	ID            int64
 	Iteration     int64
 
@@ -295,6 +383,11 @@ type VU struct {
 
 	setupData goja.Value
 
+	// The scenario that the VU is assigned to, the function it runs and the tags for its samples.
+	scenario string
+	exec     goja.Callable
+	runTags  *stats.SampleTags
+
 	// A VU will track the last context it was called with for cancellation.
 	// Note that interruptTrackedCtx is the context that is currently being tracked, while
 	// interruptCancel cancels an unrelated context that terminates the tracking goroutine
@@ -313,6 +406,48 @@ func (u *VU) Reconfigure(id int64) error {
 	u.ID = id
 	u.Iteration = 0
 	u.Runtime.Set("__VU", u.ID)
+	if u.Runner.Bundle.Options.Seed.Valid {
+		u.Runtime.SetRandSource(u.Runner.Bundle.newRandSource(id))
+	}
+	return u.assignScenario()
+}
+
+// assignScenario makes the VU run the scenario that its ID is assigned to, if there are any. The
+// VUs for setup() and teardown() have the ID 0, and don't belong to a scenario.
+func (u *VU) assignScenario() error {
+	opts := u.Runner.Bundle.Options
+	u.scenario, u.exec, u.runTags = "", u.Default, opts.RunTags
+	if u.ID == 0 || len(opts.Scenarios) == 0 {
+		return nil
+	}
+	if err := lib.ValidateScenarios(opts.Scenarios); err != nil {
+		return err
+	}
+
+	name := lib.ScenarioForVU(opts.Scenarios, u.ID)
+	scenario := opts.Scenarios[name]
+	exec, ok := goja.AssertFunction(u.Runtime.Get("exports").ToObject(u.Runtime).Get(scenario.GetExec()))
+	if !ok {
+		return errors.Errorf("scenario '%s' runs '%s', which isn't an exported function", name, scenario.GetExec())
+	}
+
+	env := make(map[string]string, len(u.Runner.Bundle.Env)+len(scenario.Env))
+	for k, v := range u.Runner.Bundle.Env {
+		env[k] = v
+	}
+	for k, v := range scenario.Env {
+		env[k] = v
+	}
+	u.Runtime.Set("__ENV", env)
+
+	tags := opts.RunTags.CloneTags()
+	for k, v := range scenario.Tags {
+		tags[k] = v
+	}
+	if opts.SystemTags["scenario"] {
+		tags["scenario"] = name
+	}
+	u.scenario, u.exec, u.runTags = name, exec, stats.IntoSampleTags(&tags)
 	return nil
 }
 
@@ -325,11 +460,12 @@ func (u *VU) RunOnce(ctx context.Context) error {
 		}
 		u.interruptCancel = interCancel
 		u.interruptTrackedCtx = ctx
+		rt := u.Runtime
 		go func() {
 			select {
 			case <-interCtx.Done():
 			case <-ctx.Done():
-				u.Runtime.Interrupt(errInterrupt)
+				rt.Interrupt(errInterrupt)
 			}
 		}()
 	}
@@ -342,33 +478,107 @@ func (u *VU) RunOnce(ctx context.Context) error {
 		u.setupData = u.Runtime.ToValue(u.Runner.setupData)
 	}
 
-	// Call the default function.
-	_, _, err := u.runFn(ctx, u.Runner.defaultGroup, u.Default, u.setupData)
+	// Call the default function, or the one of the VU's scenario.
+	exec, execName := u.exec, "default"
+	if exec == nil {
+		exec = u.Default
+	}
+	if u.scenario != "" {
+		execName = u.Runner.Bundle.Options.Scenarios[u.scenario].GetExec()
+	}
+	startTime := time.Now()
+	_, _, err := u.runFn(ctx, u.Runner.defaultGroup, execName, exec, u.setupData)
+
+	// A VU that keeps too much between iterations starts over, instead of growing until the
+	// load generator runs out of memory.
+	if memErr := u.checkMemory(); memErr != nil {
+		if err := u.reset(); err != nil {
+			return err
+		}
+		err = errors.Wrap(memErr, "the VU was restarted with a fresh runtime")
+	}
+
+	// Pace the iterations, if they're supposed to take a minimum time.
+	if minDuration := u.Runner.Bundle.Options.MinIterationDuration; minDuration.Valid {
+		if rest := time.Duration(minDuration.Duration) - time.Since(startTime); rest > 0 {
+			timer := time.NewTimer(rest)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+	}
 	return err
 }
 
-func (u *VU) runFn(ctx context.Context, group *lib.Group, fn goja.Callable, args ...goja.Value) (goja.Value, *common.State, error) {
+// checkMemory returns an error if the VU keeps more JS values than the VU memory limit allows.
+func (u *VU) checkMemory() error {
+	limit := u.Runner.Bundle.Options.VUMemoryLimit
+	if !limit.Valid {
+		return nil
+	}
+	max := limit.Int64 * 1024 * 1024
+	if heapSize(u.Runtime, max) > max {
+		return errors.Errorf("the VU keeps more JS values than the VU memory limit of %dMB", limit.Int64)
+	}
+	return nil
+}
+
+// reset replaces the VU's runtime with a fresh instance of the bundle, which drops all of the JS
+// values that the script kept, but keeps the VU's ID, its iteration count and its connections.
+func (u *VU) reset() error {
+	bi, err := u.Runner.Bundle.Instantiate()
+	if err != nil {
+		return err
+	}
+	if u.interruptCancel != nil {
+		u.interruptCancel()
+	}
+	u.interruptTrackedCtx, u.interruptCancel = nil, nil
+	u.BundleInstance = *bi
+	u.setupData = nil
+	u.bindGlobals()
+
+	iteration := u.Iteration
+	if err := u.Reconfigure(u.ID); err != nil {
+		return err
+	}
+	u.Iteration = iteration
+	return nil
+}
+
+func (u *VU) runFn(ctx context.Context, group *lib.Group, name string, fn goja.Callable, args ...goja.Value) (goja.Value, *common.State, error) {
 	cookieJar, err := cookiejar.New(nil)
 	if err != nil {
 		return goja.Undefined(), nil, err
 	}
 
+	options := u.Runner.Bundle.Options
+	if u.scenario != "" {
+		options.RunTags = u.runTags
+	}
 	state := &common.State{
 		Logger:        u.Runner.Logger,
-		Options:       u.Runner.Bundle.Options,
+		Options:       options,
 		Group:         group,
 		HTTPTransport: u.HTTPTransport,
 		Dialer:        u.Dialer,
 		TLSConfig:     u.TLSConfig,
 		CookieJar:     cookieJar,
 		RPSLimit:      u.Runner.RPSLimit,
+		Tracing:       u.Tracing,
 		BPool:         u.BPool,
 		Vu:            u.ID,
 		Samples:       u.Samples,
 		Iteration:     u.Iteration,
+		Scenario:      u.scenario,
 	}
 
 	newctx := common.WithRuntime(ctx, u.Runtime)
+	newctx = common.WithEventLoop(newctx, u.EventLoop)
+	newctx = common.WithSecrets(newctx, u.Runner.Bundle.Secrets)
+	newctx = common.WithShared(newctx, u.Runner.Bundle.Shared)
 	newctx = common.WithState(newctx, state)
 	*u.Context = newctx
 
@@ -376,10 +586,25 @@ func (u *VU) runFn(ctx context.Context, group *lib.Group, fn goja.Callable, args
 	iter := u.Iteration
 	u.Iteration++
 
+	if u.Runner.Profile != nil {
+		state.Profile = u.Runner.Profile.StartIteration(u.ID, name)
+	}
+
 	startTime := time.Now()
 	v, err := fn(goja.Undefined(), args...) // Actually run the JS script
+	if err == nil {
+		// The iteration isn't over until its timers and async calls are, and Promises are settled
+		v, err = u.EventLoop.Await(newctx, v)
+	}
+	if err != nil {
+		u.EventLoop.Reset()
+	}
 	endTime := time.Now()
 
+	if state.Profile != nil {
+		state.Profile.End()
+	}
+
 	tags := state.Options.RunTags.CloneTags()
 	if state.Options.SystemTags["vu"] {
 		tags["vu"] = strconv.FormatInt(u.ID, 10)
@@ -21,25 +21,37 @@
 package js
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	stdlog "log"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/profile"
 	"github.com/loadimpact/k6/lib/testutils"
 	"github.com/loadimpact/k6/stats"
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 )
 
@@ -174,6 +186,223 @@ func TestSetupTeardown(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationModernSyntax(t *testing.T) {
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			function* range(n) { for (let i = 0; i < n; i++) { yield i; } }
+			const sleepy = (v) => new Promise((resolve) => setImmediate(() => resolve(v)));
+			let initValue;
+			(async () => { initValue = await sleepy("init"); })();
+
+			export async function setup() {
+				return { v: await sleepy(1) };
+			}
+			export default async function(data) {
+				const { v, ...rest } = { ...data, w: 2 };
+				const values = [...range(3)];
+				const sum = (await Promise.all(values.map(sleepy))).reduce((a, b) => a + b, 0);
+				try {
+					await Promise.reject(new Error("rejected"));
+				} catch (e) {
+					if (e.message !== "rejected") { throw e; }
+				}
+				if (v !== 1 || rest.w !== 2 || sum !== 3 || initValue !== "init") {
+					throw new Error("wrong values: " + JSON.stringify([v, rest, sum, initValue]));
+				}
+				if (__ITER === 1) {
+					await sleepy();
+					throw new Error("async failure");
+				}
+			}
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		samples := make(chan stats.SampleContainer, 100)
+		t.Run(name, func(t *testing.T) {
+			if !assert.NoError(t, r.Setup(context.Background(), samples)) {
+				return
+			}
+			assert.Equal(t, map[string]interface{}{"v": 1.0}, r.GetSetupData())
+
+			vu, err := r.NewVU(samples)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, vu.RunOnce(context.Background()))
+			err = vu.RunOnce(context.Background())
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "Error: async failure")
+			}
+		})
+	}
+}
+
+func TestVUIntegrationEventLoop(t *testing.T) {
+	tb := testutils.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(tb.Replacer.Replace(`
+			import http from "k6/http";
+			import ws from "k6/ws";
+
+			export default async function() {
+				if (__ITER === 1) {
+					setTimeout(() => { throw new Error("timer failure"); }, 1);
+					return;
+				}
+
+				let log = [];
+				setTimeout(() => log.push("timeout 20"), 20);
+				setTimeout(() => log.push("timeout 5"), 5);
+				let cleared = setTimeout(() => log.push("cleared"), 1);
+				clearTimeout(cleared);
+				setImmediate(() => log.push("immediate"));
+
+				let beats = 0;
+				let heartbeat = setInterval(() => beats++, 1);
+				let res = await http.asyncRequest("GET", "HTTPBIN_IP_URL/delay/1");
+				clearInterval(heartbeat);
+				if (res.status !== 200 || beats < 10) {
+					throw new Error("wrong response or heartbeats: " + res.status + ", " + beats);
+				}
+
+				let messages = [];
+				ws.connect("ws://HTTPBIN_IP:HTTPBIN_PORT/ws-echo", function(socket) {
+					socket.on("open", () => setTimeout(() => socket.send("delayed"), 5));
+					socket.on("message", (msg) => { messages.push(msg); socket.close(); });
+				});
+				if (messages.join() !== "delayed") { throw new Error("wrong messages: " + messages); }
+
+				await new Promise((resolve) => setTimeout(resolve, 30));
+				if (log.join() !== "immediate,timeout 5,timeout 20") { throw new Error("wrong order: " + log); }
+			}
+		`)),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	runners := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range runners {
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, vu.RunOnce(context.Background()))
+			err = vu.RunOnce(context.Background())
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "Error: timer failure")
+			}
+		})
+	}
+}
+
+func TestVUIntegrationShared(t *testing.T) {
+	src := &lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			import shared from "k6/shared";
+			export default function() { throw new Error("id " + shared.add("id")); }
+		`),
+	}
+
+	// Every runner, e.g. of every re-run with --watch, has its own store, which its VUs share
+	for _, name := range []string{"First", "Second"} {
+		t.Run(name, func(t *testing.T) {
+			r, err := New(src, afero.NewMemMapFs(), lib.RuntimeOptions{})
+			require.NoError(t, err)
+			for _, want := range []string{"id 1", "id 2"} {
+				vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+				require.NoError(t, err)
+				err = vu.RunOnce(context.Background())
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestVUIntegrationMemoryLimit(t *testing.T) {
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+			export let leak = [];
+			export default function() {
+				if (leak.length !== __ITER % 2) {
+					throw new Error("wrong leak length at iteration " + __ITER + ": " + leak.length);
+				}
+				leak.push("x".repeat(600 * 1024));
+			}
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	r1.SetOptions(lib.Options{VUMemoryLimit: null.IntFrom(1)})
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	runners := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range runners {
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			// The second iteration leaves more than 1MB, so the VU starts over after it
+			for i := 0; i < 4; i++ {
+				err := vu.RunOnce(context.Background())
+				if i%2 == 0 {
+					assert.NoError(t, err, "iteration %d", i)
+				} else if assert.Error(t, err, "iteration %d", i) {
+					assert.Equal(t, "the VU was restarted with a fresh runtime: "+
+						"the VU keeps more JS values than the VU memory limit of 1MB", err.Error())
+				}
+			}
+		})
+	}
+
+	t.Run("Init", func(t *testing.T) {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data: []byte(`
+				export let data = "x".repeat(2 * 1024 * 1024);
+				export default function() {}
+			`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		r.SetOptions(lib.Options{VUMemoryLimit: null.IntFrom(1)})
+		_, err = r.NewVU(make(chan stats.SampleContainer, 100))
+		assert.EqualError(t, err, "init: the VU keeps more JS values than the VU memory limit of 1MB")
+	})
+}
+
 func TestRunnerIntegrationImports(t *testing.T) {
 	t.Run("Modules", func(t *testing.T) {
 		modules := []string{
@@ -322,6 +551,205 @@ func TestVURunInterrupt(t *testing.T) {
 	}
 }
 
+func TestVUMinIterationDuration(t *testing.T) {
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+		export let options = { minIterationDuration: "300ms" };
+		export default function() { }
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			startTime := time.Now()
+			assert.NoError(t, vu.RunOnce(context.Background()))
+			assert.True(t, time.Since(startTime) >= 300*time.Millisecond, "the iteration wasn't paced")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			startTime = time.Now()
+			assert.NoError(t, vu.RunOnce(ctx))
+			assert.True(t, time.Since(startTime) < 250*time.Millisecond, "the pacing wasn't interrupted")
+		})
+	}
+}
+
+func TestVUSeed(t *testing.T) {
+	newRunner := func(options string) *Runner {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data: []byte(`
+			export let options = ` + options + `;
+			export let initRandom = Math.random();
+			export default function() { }
+			`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		require.NoError(t, err)
+		return r
+	}
+	randoms := func(r *Runner, id int64) string {
+		vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(id))
+		v, err := vu.Runtime.RunString(`[exports.initRandom, Math.random(), Math.random()].join(",")`)
+		require.NoError(t, err)
+		return v.String()
+	}
+
+	r1, r2 := newRunner(`{ seed: 42 }`), newRunner(`{ seed: 42 }`)
+	arc, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, randoms(r1, 1), randoms(r2, 1))
+	assert.Equal(t, randoms(r1, 2), randoms(arc, 2))
+	assert.NotEqual(t, randoms(r1, 1), randoms(r1, 2))
+
+	unseeded := newRunner(`{}`)
+	assert.NotEqual(t, randoms(unseeded, 1), randoms(unseeded, 1))
+}
+
+func TestVUProfile(t *testing.T) {
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+		import { group, sleep } from "k6";
+		export default function() {
+			group("g", function() { sleep(0.01); });
+		}
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+	r.Profile = profile.New()
+
+	vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+	require.NoError(t, err)
+	require.NoError(t, vu.Reconfigure(1))
+	require.NoError(t, vu.RunOnce(context.Background()))
+
+	var buf bytes.Buffer
+	r.Profile.WriteSummary(&buf, "")
+	assert.Contains(t, buf.String(), "profile: 1 VUs ran 1 iterations")
+	assert.Contains(t, buf.String(), "k6.group")
+	assert.Contains(t, buf.String(), "k6.sleep")
+}
+
+func TestVUConnLimits(t *testing.T) {
+	newVU := func(options string) (*VU, error) {
+		r, err := New(&lib.SourceData{
+			Filename: "/script.js",
+			Data:     []byte(`export let options = ` + options + `; export default function() { }`),
+		}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+		require.NoError(t, err)
+		return r.newVU(make(chan stats.SampleContainer, 100))
+	}
+
+	vu, err := newVU(`{}`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, vu.HTTPTransport.Transport.MaxIdleConns)
+	assert.Equal(t, 0, vu.HTTPTransport.Transport.MaxIdleConnsPerHost)
+
+	vu, err = newVU(`{ maxIdleConns: 50, maxIdleConnsPerHost: 10, maxConnsPerHost: 6 }`)
+	require.NoError(t, err)
+	assert.Equal(t, 50, vu.HTTPTransport.Transport.MaxIdleConns)
+	assert.Equal(t, 10, vu.HTTPTransport.Transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 6, vu.HTTPTransport.MaxConnsPerHost)
+
+	_, err = newVU(`{ maxIdleConnsPerHost: 0 }`)
+	assert.EqualError(t, err, "invalid maxIdleConnsPerHost 0, it has to be at least 1")
+	_, err = newVU(`{ maxIdleConns: -1 }`)
+	assert.EqualError(t, err, "invalid maxIdleConns -1, it can't be negative")
+	_, err = newVU(`{ maxConnsPerHost: -1 }`)
+	assert.EqualError(t, err, "invalid maxConnsPerHost -1, it can't be negative")
+}
+
+func TestVUScenarios(t *testing.T) {
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+		import { Counter } from "k6/metrics";
+		let runs = new Counter("runs");
+		export let options = {
+			systemTags: ["scenario"],
+			scenarios: {
+				browse: { exec: "browse", weight: 2, env: { PAGE: "home" }, tags: { team: "web" } },
+				checkout: { exec: "checkout" },
+			},
+		};
+		export function browse() {
+			if (__ENV.PAGE !== "home" || __ENV.GLOBAL !== "yes") { throw new Error("wrong env: " + JSON.stringify(__ENV)); }
+			runs.add(1);
+		}
+		export function checkout() {
+			if (__ENV.PAGE !== undefined) { throw new Error("the env of another scenario leaked: " + __ENV.PAGE); }
+			runs.add(1);
+		}
+		export default function() { throw new Error("the default function shouldn't run"); }
+		`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{Env: map[string]string{"GLOBAL": "yes"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		t.Run(name, func(t *testing.T) {
+			expected := map[int64]string{1: "browse", 2: "browse", 3: "checkout", 4: "browse"}
+			for id, scenario := range expected {
+				samples := make(chan stats.SampleContainer, 100)
+				vu, err := r.newVU(samples)
+				if !assert.NoError(t, err) {
+					return
+				}
+				require.NoError(t, vu.Reconfigure(id))
+				require.NoError(t, vu.RunOnce(context.Background()))
+				close(samples)
+
+				found := false
+				for container := range samples {
+					for _, sample := range container.GetSamples() {
+						assert.Equal(t, scenario, sample.Tags.CloneTags()["scenario"], "VU %d", id)
+						if sample.Metric.Name == "runs" {
+							found = true
+							if scenario == "browse" {
+								assert.Equal(t, "web", sample.Tags.CloneTags()["team"])
+							}
+						}
+					}
+				}
+				assert.True(t, found, "VU %d didn't run its scenario", id)
+			}
+		})
+	}
+
+	t.Run("Missing function", func(t *testing.T) {
+		r1.SetOptions(r1.GetOptions().Apply(lib.Options{
+			Scenarios: map[string]lib.Scenario{"search": {Exec: null.StringFrom("search")}},
+		}))
+		vu, err := r1.newVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		assert.EqualError(t, vu.Reconfigure(1), "scenario 'search' runs 'search', which isn't an exported function")
+	})
+}
+
 func TestVUIntegrationGroups(t *testing.T) {
 	r1, err := New(&lib.SourceData{
 		Filename: "/script.js",
@@ -585,6 +1013,117 @@ func TestVUIntegrationHosts(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationProxy(t *testing.T) {
+	tb := testutils.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	// A plain HTTP proxy receives absolute request URIs; just answer with them.
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "proxied %s", r.URL.String())
+	}))
+	defer proxySrv.Close()
+
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(tb.Replacer.Replace(`
+					import { check, fail } from "k6";
+					import http from "k6/http";
+					export default function() {
+						let res = http.get("http://proxied.test/path");
+						check(res, {
+							"is proxied": (r) => r.body === "proxied http://proxied.test/path"
+						}) || fail("request wasn't proxied: " + res.body);
+
+						res = http.get("HTTPBIN_IP_URL/get");
+						check(res, {
+							"is not proxied": (r) => r.status === 200 && r.json().url === "HTTPBIN_IP_URL/get"
+						}) || fail("request was proxied: " + res.body);
+					}
+				`)),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	r1.SetOptions(lib.Options{
+		Throw:   null.BoolFrom(true),
+		Proxy:   null.StringFrom(proxySrv.URL),
+		NoProxy: null.StringFrom("127.0.0.1"),
+	})
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	runners := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range runners {
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			require.NoError(t, err)
+			assert.NoError(t, vu.RunOnce(context.Background()))
+		})
+	}
+
+	t.Run("Invalid", func(t *testing.T) {
+		r1.SetOptions(lib.Options{Proxy: null.StringFrom("ftp://proxy.test")})
+		_, err := r1.NewVU(make(chan stats.SampleContainer, 100))
+		assert.EqualError(t, err, "unsupported proxy scheme 'ftp', use http, https or socks5")
+	})
+}
+
+func TestVUIntegrationTracing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Header.Get("traceparent"))
+	}))
+	defer srv.Close()
+
+	var mutex sync.Mutex
+	var exported []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		exported = append(exported, string(body))
+		mutex.Unlock()
+	}))
+	defer collector.Close()
+
+	r, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(`
+					import http from "k6/http";
+					export default function() {
+						let res = http.get("` + srv.URL + `");
+						if (!/^00-[0-9a-f]{32}-[0-9a-f]{16}-01$/.test(res.body)) {
+							throw new Error("wrong traceparent: " + res.body);
+						}
+						return res.body;
+					}
+				`),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	r.SetOptions(lib.Options{
+		TracingPropagator: null.StringFrom("w3c"),
+		TracingEndpoint:   null.StringFrom(collector.URL + "/v1/traces"),
+	})
+	vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+	require.NoError(t, err)
+	traceparent, _, err := vu.runFn(context.Background(), r.defaultGroup, "default", vu.Default)
+	require.NoError(t, err)
+	require.NoError(t, r.Teardown(context.Background(), make(chan stats.SampleContainer, 100)))
+
+	require.Len(t, exported, 1)
+	traceID := strings.Split(traceparent.String(), "-")[1]
+	assert.Contains(t, exported[0], `"traceId":"`+traceID+`"`)
+
+	t.Run("Invalid", func(t *testing.T) {
+		r.SetOptions(lib.Options{TracingPropagator: null.StringFrom("jaeger")})
+		_, err := r.NewVU(make(chan stats.SampleContainer, 100))
+		assert.EqualError(t, err, "invalid tracing propagator 'jaeger', it has to be 'w3c' or 'b3'")
+
+		r.SetOptions(lib.Options{TracingPropagator: null.StringFrom("b3"), TracingSampling: null.FloatFrom(2)})
+		_, err = r.NewVU(make(chan stats.SampleContainer, 100))
+		assert.EqualError(t, err, "invalid tracing sampling 2, it has to be between 0 and 1")
+	})
+}
+
 func TestVUIntegrationTLSConfig(t *testing.T) {
 	testdata := map[string]struct {
 		opts   lib.Options
@@ -948,3 +1487,93 @@ func TestVUIntegrationClientCerts(t *testing.T) {
 		}
 	})
 }
+
+func TestVUIntegrationClientCertsPerDomain(t *testing.T) {
+	genCert := func(cn string) (certPEM, keyPEM string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+			DNSNames:     []string{cn},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	}
+
+	serverCertPEM, serverKeyPEM := genCert("example.com")
+	serverCert, err := tls.X509KeyPair([]byte(serverCertPEM), []byte(serverKeyPEM))
+	require.NoError(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequestClientCert,
+	})
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(req.TLS.PeerCertificates) == 0 {
+				_, _ = fmt.Fprint(w, "none")
+				return
+			}
+			_, _ = fmt.Fprint(w, req.TLS.PeerCertificates[0].Subject.CommonName)
+		}),
+		ErrorLog: stdlog.New(ioutil.Discard, "", 0),
+	}
+	go func() { _ = srv.Serve(listener) }()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	r1, err := New(&lib.SourceData{
+		Filename: "/script.js",
+		Data: []byte(fmt.Sprintf(`
+			import http from "k6/http";
+			let expected = { "a.example.com": "client-a", "b.example.com": "client-b", "other.test": "none" };
+			export default function() {
+				for (let host in expected) {
+					let res = http.get("https://" + host + ":%s/");
+					if (res.body !== expected[host]) {
+						throw new Error(host + ": expected " + expected[host] + ", got " + res.body);
+					}
+				}
+			}
+		`, port)),
+	}, afero.NewMemMapFs(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	certA, keyA := genCert("client-a")
+	certB, keyB := genCert("client-b")
+	r1.SetOptions(lib.Options{
+		Throw:                 null.BoolFrom(true),
+		InsecureSkipTLSVerify: null.BoolFrom(true),
+		Hosts: map[string]net.IP{
+			"a.example.com": net.ParseIP("127.0.0.1"),
+			"b.example.com": net.ParseIP("127.0.0.1"),
+			"other.test":    net.ParseIP("127.0.0.1"),
+		},
+		TLSAuth: []*lib.TLSAuth{
+			{TLSAuthFields: lib.TLSAuthFields{Domains: []string{"a.example.com"}, Cert: certA, Key: keyA}},
+			{TLSAuthFields: lib.TLSAuthFields{Domains: []string{"*.example.com"}, Cert: certB, Key: keyB}},
+		},
+	})
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	runners := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range runners {
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			require.NoError(t, err)
+			assert.NoError(t, vu.RunOnce(context.Background()))
+		})
+	}
+}
@@ -22,7 +22,9 @@ package lib
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 )
 
@@ -46,6 +48,28 @@ const (
 	RunStatusAbortedThreshold   RunStatus = 8
 )
 
+var runStatusNames = map[RunStatus]string{
+	RunStatusCreated:            "created",
+	RunStatusValidated:          "validated",
+	RunStatusQueued:             "queued",
+	RunStatusInitializing:       "initializing",
+	RunStatusRunning:            "running",
+	RunStatusFinished:           "finished",
+	RunStatusTimedOut:           "timed_out",
+	RunStatusAbortedUser:        "aborted_user",
+	RunStatusAbortedSystem:      "aborted_system",
+	RunStatusAbortedScriptError: "aborted_script_error",
+	RunStatusAbortedThreshold:   "aborted_threshold",
+}
+
+// String returns the name of the status, e.g. aborted_threshold, for messages to other systems.
+func (s RunStatus) String() string {
+	if name, ok := runStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("RunStatus(%d)", int(s))
+}
+
 // A Collector abstracts the process of funneling samples to an external storage backend,
 // such as an InfluxDB instance.
 type Collector interface {
@@ -57,8 +81,10 @@ type Collector interface {
 	// at regular intervals and when the context is terminated.
 	Run(ctx context.Context)
 
-	// Collect receives a set of samples. This method is never called concurrently, and only while
-	// the context for Run() is valid, but should defer as much work as possible to Run().
+	// Collect receives a batch of the samples that were emitted since the last call, every
+	// flush interval (see Options.OutputFlushInterval). This method is never called concurrently,
+	// and only while the context for Run() is valid, but should defer as much work as possible
+	// to Run().
 	Collect(samples []stats.SampleContainer)
 
 	// Optionally return a link that is shown to the user.
@@ -70,3 +96,34 @@ type Collector interface {
 	// Set run status
 	SetRunStatus(status RunStatus)
 }
+
+// A BackpressureCollector is a Collector that can tell k6 that it can't keep up, e.g. because its
+// backend is slow. While it's busy, samples are buffered for it instead of being handed to it, and
+// once the buffer is full (see Options.OutputBufferSize), new samples are dropped and counted in
+// the dropped_samples metric, rather than slowing down or running the test out of memory.
+type BackpressureCollector interface {
+	Collector
+
+	// Busy returns true while the collector doesn't want any more samples. It's called from the
+	// same goroutine as Collect(), and the samples are handed over regardless at the end of a test.
+	Busy() bool
+}
+
+// A ThresholdEvent is a change of a threshold during a test: a threshold that starts failing, or
+// a threshold with a window that breaches or recovers.
+type ThresholdEvent struct {
+	Metric    string         `json:"metric"`
+	Threshold string         `json:"threshold"`
+	Breached  bool           `json:"breached"`
+	Time      types.Duration `json:"time"` // Since the start of the test
+}
+
+// A ThresholdCollector is a Collector that wants to know about threshold events as they happen,
+// e.g. to notify something outside of k6, rather than only about the run status at the end.
+type ThresholdCollector interface {
+	Collector
+
+	// ThresholdChanged is called from the goroutine that evaluates the thresholds, so it
+	// shouldn't block.
+	ThresholdChanged(event ThresholdEvent)
+}
@@ -58,6 +58,10 @@ type Executor interface {
 	GetEndIterations() null.Int
 	SetEndIterations(i null.Int)
 
+	// Get and set how many iterations each VU runs; the test ends when all VUs are done.
+	GetEndIterationsPerVU() null.Int
+	SetEndIterationsPerVU(i null.Int)
+
 	// Get time elapsed so far, accounting for pauses, get and set at what point to end the test.
 	GetTime() time.Duration
 	GetEndTime() types.NullDuration
@@ -74,6 +78,11 @@ type Executor interface {
 	GetVUs() int64
 	SetVUs(vus int64) error
 
+	// Get and set a ceiling for the number of active VUs, e.g. when the machine is saturated.
+	// Unlike MaxVUs, setting the VUs higher than it isn't an error, they're just capped.
+	GetVUsCeiling() null.Int
+	SetVUsCeiling(c null.Int)
+
 	// Get and set the number of allocated, available VUs.
 	// Please note that initialising new VUs is a very expensive operation, and doing it during a
 	// running test may skew metrics; if you're not sure how many you will need, it's generally
@@ -81,6 +90,11 @@ type Executor interface {
 	GetVUsMax() int64
 	SetVUsMax(max int64) error
 
+	// Get and set how many of the max VUs are initialized upfront; the others are initialized when
+	// the test first needs them, instead of before it starts. All of them if it's not set.
+	GetPreAllocatedVUs() null.Int
+	SetPreAllocatedVUs(n null.Int)
+
 	// Set whether or not to run setup/teardown phases. Default is to run all of them.
 	SetRunSetup(r bool)
 	SetRunTeardown(r bool)
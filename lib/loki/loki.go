@@ -0,0 +1,251 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// The entries are pushed when this many have been buffered, or when the push interval has
+	// passed since the last push, whichever comes first.
+	pushBatchSize = 1000
+	pushInterval  = time.Second
+
+	// Past this many entries waiting to be pushed, e.g. because Loki is down, new ones are dropped.
+	maxBufferedEntries = 100000
+)
+
+// The fields of entries that become labels of their streams, besides the level; the rest of them
+// are in the log lines. VU numbers make for a lot of streams, but that's what the logs of a VU
+// are usually looked for by.
+var labelFields = []string{"scenario", "vu"}
+
+// Hook is a logrus hook that pushes log entries to Grafana Loki, with their level, scenario and VU
+// as labels, in batches and in the background. It's safe for concurrent use.
+type Hook struct {
+	URL    string
+	Labels map[string]string // Added to all of the streams, e.g. the instance of a distributed test
+
+	// Where errors of pushes are written to, as they can't be logged; os.Stderr by default.
+	ErrorOutput io.Writer
+
+	client    *http.Client
+	formatter log.Formatter
+
+	lock     sync.Mutex
+	entries  []entry
+	dropped  int
+	lastPush time.Time
+	pushing  sync.WaitGroup
+}
+
+type entry struct {
+	time   time.Time
+	labels map[string]string
+	line   string
+}
+
+// New creates a hook from the value of --log-output=loki=..., which is the push URL, e.g.
+// http://localhost:3100/loki/api/v1/push, optionally followed by comma-separated labels for all of
+// the streams, e.g. `http://loki:3100/loki/api/v1/push,label.testid=123`. The instance label is
+// the host name by default.
+func New(config string) (*Hook, error) {
+	parts := strings.Split(config, ",")
+	if parts[0] == "" {
+		return nil, errors.New("the Loki log output needs a push URL, e.g. loki=http://localhost:3100/loki/api/v1/push")
+	}
+	if !strings.HasPrefix(parts[0], "http://") && !strings.HasPrefix(parts[0], "https://") {
+		return nil, errors.Errorf("invalid Loki push URL '%s'", parts[0])
+	}
+
+	h := &Hook{
+		URL:         parts[0],
+		Labels:      make(map[string]string),
+		ErrorOutput: os.Stderr,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		formatter:   &log.TextFormatter{DisableColors: true, DisableTimestamp: true},
+		lastPush:    time.Now(),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		h.Labels["instance"] = hostname
+	}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "label.") || kv[0] == "label." {
+			return nil, errors.Errorf("invalid Loki log output option '%s', it has to be label.<name>=<value>", part)
+		}
+		h.Labels[strings.TrimPrefix(kv[0], "label.")] = kv[1]
+	}
+	return h, nil
+}
+
+// Levels returns all of the levels; the logger's level filters the entries before hooks.
+func (h *Hook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire buffers an entry, and pushes the buffered ones if it's time to.
+func (h *Hook) Fire(e *log.Entry) error {
+	labels := make(map[string]string, len(h.Labels)+len(labelFields)+1)
+	for k, v := range h.Labels {
+		labels[k] = v
+	}
+	labels["level"] = e.Level.String()
+
+	// The line is the message with the fields that aren't labels, in logfmt
+	lineEntry := e.WithFields(nil)
+	lineEntry.Level, lineEntry.Message = e.Level, e.Message
+	for _, field := range labelFields {
+		if v, ok := lineEntry.Data[field]; ok {
+			labels[field] = fmt.Sprint(v)
+			delete(lineEntry.Data, field)
+		}
+	}
+	line, err := h.formatter.Format(lineEntry)
+	if err != nil {
+		return err
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.entries) >= maxBufferedEntries {
+		h.dropped++
+		return nil
+	}
+	h.entries = append(h.entries, entry{e.Time, labels, strings.TrimSuffix(string(line), "\n")})
+	if len(h.entries) < pushBatchSize && time.Since(h.lastPush) < pushInterval {
+		return nil
+	}
+	entries := h.entries
+	h.entries = nil
+	h.lastPush = time.Now()
+	h.pushing.Add(1)
+	go func() {
+		defer h.pushing.Done()
+		h.push(entries)
+	}()
+	return nil
+}
+
+// Flush pushes the buffered entries and waits for all pushes to finish.
+func (h *Hook) Flush() {
+	h.lock.Lock()
+	entries := h.entries
+	h.entries = nil
+	h.lastPush = time.Now()
+	dropped := h.dropped
+	h.dropped = 0
+	h.lock.Unlock()
+
+	if len(entries) > 0 {
+		h.push(entries)
+	}
+	h.pushing.Wait()
+	if dropped > 0 {
+		_, _ = fmt.Fprintf(h.ErrorOutput, "%d log entries weren't pushed to Loki, as too many were waiting\n", dropped)
+	}
+}
+
+func (h *Hook) push(entries []entry) {
+	body, err := json.Marshal(pushRequest(entries))
+	if err == nil {
+		err = h.post(body)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(h.ErrorOutput, "Couldn't push %d log entries to Loki: %s\n", len(entries), err)
+	}
+}
+
+func (h *Hook) post(body []byte) error {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6")
+
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+// pushRequest groups entries into streams by their labels, in the order of their first entries.
+func pushRequest(entries []entry) lokiPushRequest {
+	var req lokiPushRequest
+	streams := make(map[string]int)
+	for _, e := range entries {
+		keys := make([]string, 0, len(e.labels))
+		for k := range e.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var id strings.Builder
+		for _, k := range keys {
+			id.WriteString(k + "\x00" + e.labels[k] + "\x00")
+		}
+
+		i, ok := streams[id.String()]
+		if !ok {
+			i = len(req.Streams)
+			streams[id.String()] = i
+			req.Streams = append(req.Streams, lokiStream{Stream: e.labels})
+		}
+		req.Streams[i].Values = append(req.Streams[i].Values, [2]string{
+			strconv.FormatInt(e.time.UnixNano(), 10), e.line,
+		})
+	}
+	return req
+}
+
+// The JSON body of Loki's push API, see https://grafana.com/docs/loki/latest/api/#push-log-entries-to-loki
+
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
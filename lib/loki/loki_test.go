@@ -0,0 +1,118 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	h, err := New("http://localhost:3100/loki/api/v1/push,label.testid=123,label.team=qa")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:3100/loki/api/v1/push", h.URL)
+	assert.Equal(t, "123", h.Labels["testid"])
+	assert.Equal(t, "qa", h.Labels["team"])
+	assert.NotEmpty(t, h.Labels["instance"])
+
+	h, err = New("http://loki:3100/loki/api/v1/push,label.instance=gen-1")
+	require.NoError(t, err)
+	assert.Equal(t, "gen-1", h.Labels["instance"])
+
+	testdata := map[string]string{
+		"":                                   "the Loki log output needs a push URL, e.g. loki=http://localhost:3100/loki/api/v1/push",
+		"localhost:3100":                     "invalid Loki push URL 'localhost:3100'",
+		"http://loki:3100/push,testid=123":   "invalid Loki log output option 'testid=123', it has to be label.<name>=<value>",
+		"http://loki:3100/push,label.":       "invalid Loki log output option 'label.', it has to be label.<name>=<value>",
+		"http://loki:3100/push,label.=value": "invalid Loki log output option 'label.=value', it has to be label.<name>=<value>",
+	}
+	for config, msg := range testdata {
+		_, err := New(config)
+		assert.EqualError(t, err, msg, config)
+	}
+}
+
+func TestHook(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []lokiPushRequest
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if fail {
+			http.Error(w, "ingestion rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		var req lokiPushRequest
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &req))
+		requests = append(requests, req)
+	}))
+	defer srv.Close()
+
+	h, err := New(srv.URL + ",label.testid=123")
+	require.NoError(t, err)
+	h.Labels["instance"] = "gen-1"
+	errors := &bytes.Buffer{}
+	h.ErrorOutput = errors
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(h)
+	logger.WithFields(log.Fields{"vu": int64(1), "iter": int64(2), "scenario": "browse"}).Info("hello")
+	logger.WithFields(log.Fields{"vu": int64(1), "iter": int64(3), "scenario": "browse"}).Info("again")
+	logger.WithField("vu", int64(2)).Warn("careful")
+	logger.Error("oops")
+	h.Flush()
+
+	require.Len(t, requests, 1)
+	streams := requests[0].Streams
+	require.Len(t, streams, 3)
+	assert.Equal(t, map[string]string{
+		"instance": "gen-1", "testid": "123", "level": "info", "scenario": "browse", "vu": "1",
+	}, streams[0].Stream)
+	require.Len(t, streams[0].Values, 2)
+	assert.Equal(t, `level=info msg=hello iter=2`, streams[0].Values[0][1])
+	assert.Equal(t, `level=info msg=again iter=3`, streams[0].Values[1][1])
+	assert.Equal(t, map[string]string{
+		"instance": "gen-1", "testid": "123", "level": "warning", "vu": "2",
+	}, streams[1].Stream)
+	assert.Equal(t, map[string]string{"instance": "gen-1", "testid": "123", "level": "error"}, streams[2].Stream)
+	assert.Equal(t, `level=error msg=oops`, streams[2].Values[0][1])
+	assert.Empty(t, errors.String())
+
+	mutex.Lock()
+	fail = true
+	mutex.Unlock()
+	logger.Info("lost")
+	h.Flush()
+	assert.Equal(t, "Couldn't push 1 log entries to Loki: 429 Too Many Requests: ingestion rate limit exceeded\n", errors.String())
+}
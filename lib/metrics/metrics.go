@@ -26,37 +26,94 @@ import (
 
 //TODO: refactor this, using non thread-safe global variables seems like a bad idea for various reasons...
 
+var builtins = make(map[string]*stats.Metric)
+
+func builtin(name string, typ stats.MetricType, t ...stats.ValueType) *stats.Metric {
+	m := stats.New(name, typ, t...)
+	builtins[name] = m
+	return m
+}
+
+// Builtin returns the built-in metric with the name, or nil if there's no such metric.
+func Builtin(name string) *stats.Metric {
+	return builtins[name]
+}
+
 var (
 	// Engine-emitted.
-	VUs               = stats.New("vus", stats.Gauge)
-	VUsMax            = stats.New("vus_max", stats.Gauge)
-	Iterations        = stats.New("iterations", stats.Counter)
-	IterationDuration = stats.New("iteration_duration", stats.Trend, stats.Time)
-	Errors            = stats.New("errors", stats.Counter)
+	VUs               = builtin("vus", stats.Gauge)
+	VUsMax            = builtin("vus_max", stats.Gauge)
+	Iterations        = builtin("iterations", stats.Counter)
+	IterationDuration = builtin("iteration_duration", stats.Trend, stats.Time)
+	Errors            = builtin("errors", stats.Counter)
+	DroppedSamples    = builtin("dropped_samples", stats.Counter)
+	DroppedIterations = builtin("dropped_iterations", stats.Counter)
 
 	// Runner-emitted.
-	Checks        = stats.New("checks", stats.Rate)
-	GroupDuration = stats.New("group_duration", stats.Trend, stats.Time)
+	Checks        = builtin("checks", stats.Rate)
+	GroupDuration = builtin("group_duration", stats.Trend, stats.Time)
 
 	// HTTP-related.
-	HTTPReqs              = stats.New("http_reqs", stats.Counter)
-	HTTPReqDuration       = stats.New("http_req_duration", stats.Trend, stats.Time)
-	HTTPReqBlocked        = stats.New("http_req_blocked", stats.Trend, stats.Time)
-	HTTPReqConnecting     = stats.New("http_req_connecting", stats.Trend, stats.Time)
-	HTTPReqTLSHandshaking = stats.New("http_req_tls_handshaking", stats.Trend, stats.Time)
-	HTTPReqSending        = stats.New("http_req_sending", stats.Trend, stats.Time)
-	HTTPReqWaiting        = stats.New("http_req_waiting", stats.Trend, stats.Time)
-	HTTPReqReceiving      = stats.New("http_req_receiving", stats.Trend, stats.Time)
+	HTTPReqs              = builtin("http_reqs", stats.Counter)
+	HTTPReqDuration       = builtin("http_req_duration", stats.Trend, stats.Time)
+	HTTPReqBlocked        = builtin("http_req_blocked", stats.Trend, stats.Time)
+	HTTPReqQueued         = builtin("http_req_queued", stats.Trend, stats.Time)
+	HTTPReqConnecting     = builtin("http_req_connecting", stats.Trend, stats.Time)
+	HTTPReqTLSHandshaking = builtin("http_req_tls_handshaking", stats.Trend, stats.Time)
+	HTTPReqSending        = builtin("http_req_sending", stats.Trend, stats.Time)
+	HTTPReqWaiting        = builtin("http_req_waiting", stats.Trend, stats.Time)
+	HTTPReqReceiving      = builtin("http_req_receiving", stats.Trend, stats.Time)
+	HTTPReqEarlyHints     = builtin("http_req_early_hints", stats.Trend, stats.Time)
+	HTTPReqFailed         = builtin("http_req_failed", stats.Rate)
+	HTTPReqRetries        = builtin("http_req_retries", stats.Counter)
+	HTTPReqConnReused     = builtin("http_req_conn_reused", stats.Rate)
+	NewConnections        = builtin("new_connections", stats.Counter)
+	HTTPRespHeaderBytes   = builtin("http_resp_header_bytes", stats.Counter, stats.Data)
+	HTTPRespBodyBytes     = builtin("http_resp_body_bytes", stats.Counter, stats.Data)
+	GraphQLReqFailed      = builtin("graphql_req_failed", stats.Rate)
 
 	// Websocket-related
-	WSSessions         = stats.New("ws_sessions", stats.Counter)
-	WSMessagesSent     = stats.New("ws_msgs_sent", stats.Counter)
-	WSMessagesReceived = stats.New("ws_msgs_received", stats.Counter)
-	WSPing             = stats.New("ws_ping", stats.Trend)
-	WSSessionDuration  = stats.New("ws_session_duration", stats.Trend, stats.Time)
-	WSConnecting       = stats.New("ws_connecting", stats.Trend, stats.Time)
+	WSSessions         = builtin("ws_sessions", stats.Counter)
+	WSMessagesSent     = builtin("ws_msgs_sent", stats.Counter)
+	WSMessagesReceived = builtin("ws_msgs_received", stats.Counter)
+	WSPing             = builtin("ws_ping", stats.Trend)
+	WSSessionDuration  = builtin("ws_session_duration", stats.Trend, stats.Time)
+	WSConnecting       = builtin("ws_connecting", stats.Trend, stats.Time)
+
+	// Server-Sent Events-related (k6/sse)
+	SSESessions         = builtin("sse_sessions", stats.Counter)
+	SSEEventsReceived   = builtin("sse_events_received", stats.Counter)
+	SSESessionDuration  = builtin("sse_session_duration", stats.Trend, stats.Time)
+	SSEConnecting       = builtin("sse_connecting", stats.Trend, stats.Time)
+	SSETimeToFirstEvent = builtin("sse_time_to_first_event", stats.Trend, stats.Time)
+
+	// Socket.IO-related (k6/socketio), whose sessions have the websocket metrics
+	SocketIOAckDuration = builtin("socketio_ack_duration", stats.Trend, stats.Time)
+
+	// MQTT-related (k6/mqtt)
+	MQTTSessions         = builtin("mqtt_sessions", stats.Counter)
+	MQTTMessagesSent     = builtin("mqtt_msgs_sent", stats.Counter)
+	MQTTMessagesReceived = builtin("mqtt_msgs_received", stats.Counter)
+	MQTTPublishDuration  = builtin("mqtt_publish_duration", stats.Trend, stats.Time)
+	MQTTSessionDuration  = builtin("mqtt_session_duration", stats.Trend, stats.Time)
+	MQTTConnecting       = builtin("mqtt_connecting", stats.Trend, stats.Time)
+
+	// Redis-related (k6/redis)
+	RedisCommands        = builtin("redis_cmds", stats.Counter)
+	RedisCommandDuration = builtin("redis_cmd_duration", stats.Trend, stats.Time)
+
+	// Browser-related (k6/browser)
+	BrowserTTFB = builtin("browser_ttfb", stats.Trend, stats.Time)
+	BrowserFCP  = builtin("browser_fcp", stats.Trend, stats.Time)
+	BrowserLCP  = builtin("browser_lcp", stats.Trend, stats.Time)
+	BrowserLoad = builtin("browser_load", stats.Trend, stats.Time)
+
+	// Raw socket-related (k6/net)
+	NetConnections = builtin("net_connections", stats.Counter)
+	NetConnecting  = builtin("net_connecting", stats.Trend, stats.Time)
+	NetRTT         = builtin("net_rtt", stats.Trend, stats.Time)
 
 	// Network-related; used for future protocols as well.
-	DataSent     = stats.New("data_sent", stats.Counter, stats.Data)
-	DataReceived = stats.New("data_received", stats.Counter, stats.Data)
+	DataSent     = builtin("data_sent", stats.Counter, stats.Data)
+	DataReceived = builtin("data_received", stats.Counter, stats.Data)
 )
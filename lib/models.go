@@ -24,6 +24,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -54,6 +55,10 @@ type StageFields struct {
 
 	// If Valid, the VU count will be linearly interpolated towards this value.
 	Target null.Int `json:"target"`
+
+	// If Valid, iterations are started at a rate (per second) that will be linearly interpolated
+	// towards this value, instead of whenever a VU is free. The VUs are then a pool to run them.
+	Rate null.Float `json:"rate"`
 }
 
 // A Stage defines a step in a test's timeline.
@@ -69,13 +74,20 @@ func (s *Stage) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// MarshalJSON leaves out an unset rate, so that stages without one look like they did before.
 func (s Stage) MarshalJSON() ([]byte, error) {
+	if !s.Rate.Valid {
+		return json.Marshal(struct {
+			Duration types.NullDuration `json:"duration"`
+			Target   null.Int           `json:"target"`
+		}{s.Duration, s.Target})
+	}
 	return json.Marshal(StageFields(s))
 }
 
 func (s *Stage) UnmarshalText(b []byte) error {
 	var stage Stage
-	parts := strings.SplitN(string(b), ":", 2)
+	parts := strings.SplitN(string(b), ":", 3)
 	if len(parts) > 0 && parts[0] != "" {
 		d, err := time.ParseDuration(parts[0])
 		if err != nil {
@@ -90,10 +102,95 @@ func (s *Stage) UnmarshalText(b []byte) error {
 		}
 		stage.Target = null.IntFrom(t)
 	}
+	if len(parts) > 2 && parts[2] != "" {
+		r, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return err
+		}
+		stage.Rate = null.FloatFrom(r)
+	}
 	*s = stage
 	return nil
 }
 
+// A Scenario is one of the things that a test's VUs do, e.g. "browse", "search" or "checkout", so
+// that a single script can hold a mix of them. Each VU is assigned to a scenario, in proportion
+// to the scenarios' weights, and runs the scenario's exported function instead of the default one.
+type Scenario struct {
+	// Name of the exported function to run, "default" if not set.
+	Exec null.String `json:"exec"`
+
+	// Environment variables that the scenario's VUs see in __ENV, on top of the global ones.
+	Env map[string]string `json:"env"`
+
+	// Tags for all of the samples of the scenario's VUs, besides the `scenario` system tag.
+	Tags map[string]string `json:"tags"`
+
+	// Relative share of the VUs that run the scenario, 1 if not set.
+	Weight null.Int `json:"weight"`
+}
+
+// GetExec returns the name of the exported function that the scenario runs.
+func (s Scenario) GetExec() string {
+	if s.Exec.Valid && s.Exec.String != "" {
+		return s.Exec.String
+	}
+	return "default"
+}
+
+// GetWeight returns the scenario's share of the VUs.
+func (s Scenario) GetWeight() int64 {
+	if s.Weight.Valid {
+		return s.Weight.Int64
+	}
+	return 1
+}
+
+// ValidateScenarios checks that the weights of the scenarios make sense.
+func ValidateScenarios(scenarios map[string]Scenario) error {
+	var total int64
+	for name, s := range scenarios {
+		if name == "" {
+			return errors.New("scenarios have to have a name")
+		}
+		if s.GetWeight() < 0 {
+			return errors.Errorf("the weight of scenario '%s' can't be negative", name)
+		}
+		total += s.GetWeight()
+	}
+	if len(scenarios) > 0 && total == 0 {
+		return errors.New("at least one scenario has to have a weight above 0")
+	}
+	return nil
+}
+
+// ScenarioForVU returns the name of the scenario that the VU with the given ID runs, or "" if
+// there are no scenarios. The VUs are assigned in order of the scenario names, e.g. with the
+// weights browse=3 and checkout=1, VUs 1-3 browse, VU 4 checks out, VUs 5-7 browse, and so on.
+func ScenarioForVU(scenarios map[string]Scenario, id int64) string {
+	names := make([]string, 0, len(scenarios))
+	var total int64
+	for name, s := range scenarios {
+		if s.GetWeight() > 0 {
+			names = append(names, name)
+			total += s.GetWeight()
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	slot := ((id-1)%total + total) % total
+	for _, name := range names {
+		if slot < scenarios[name].GetWeight() {
+			return name
+		}
+		slot -= scenarios[name].GetWeight()
+	}
+	return ""
+}
+
 // A Group is an organisational block, that samples and checks may be tagged with.
 //
 // For more information, refer to the js/modules/k6.K6.Group() function.
@@ -182,6 +279,30 @@ func (g *Group) Check(name string) (*Check, error) {
 	return check, nil
 }
 
+// WalkChecks calls fn for every check in this group and all of its descendants.
+// This is safe to call from multiple goroutines simultaneously.
+func (g *Group) WalkChecks(fn func(*Check)) {
+	g.checkMutex.Lock()
+	checks := make([]*Check, 0, len(g.Checks))
+	for _, check := range g.Checks {
+		checks = append(checks, check)
+	}
+	g.checkMutex.Unlock()
+	for _, check := range checks {
+		fn(check)
+	}
+
+	g.groupMutex.Lock()
+	groups := make([]*Group, 0, len(g.Groups))
+	for _, group := range g.Groups {
+		groups = append(groups, group)
+	}
+	g.groupMutex.Unlock()
+	for _, group := range groups {
+		group.WalkChecks(fn)
+	}
+}
+
 // A Check stores a series of successful or failing tests against a value.
 //
 // For more information, refer to the js/modules/k6.K6.Check() function.
@@ -205,6 +326,50 @@ type Check struct {
 	// Counters for how many times this check has passed and failed respectively.
 	Passes int64 `json:"passes"`
 	Fails  int64 `json:"fails"`
+
+	optionsMutex sync.Mutex
+	severity     string
+	threshold    string
+}
+
+// Check severities: when the threshold of a check fails, an error fails the whole run, like a
+// failed threshold, while a warning only logs one.
+const (
+	CheckSeverityWarn  = "warn"
+	CheckSeverityError = "error"
+)
+
+// The threshold of checks with a severity but no threshold of their own: any failure crosses it.
+const DefaultCheckThreshold = "rate==1"
+
+// SetOptions sets the severity and the threshold on the rate of passes of a check, which are
+// evaluated by the engine like a threshold on `checks{check:<name>}`. Without a severity, the
+// threshold fails the run, and without a threshold, any failure counts. It returns whether they
+// changed.
+func (c *Check) SetOptions(severity, threshold string) (bool, error) {
+	switch severity {
+	case "":
+		severity = CheckSeverityError
+	case CheckSeverityWarn, CheckSeverityError:
+	default:
+		return false, errors.Errorf("invalid severity '%s' for the check '%s', it has to be 'warn' or 'error'", severity, c.Name)
+	}
+	if threshold == "" {
+		threshold = DefaultCheckThreshold
+	}
+
+	c.optionsMutex.Lock()
+	defer c.optionsMutex.Unlock()
+	changed := severity != c.severity || threshold != c.threshold
+	c.severity, c.threshold = severity, threshold
+	return changed, nil
+}
+
+// Options returns the severity and the threshold of the check, which are empty if it has none.
+func (c *Check) Options() (severity, threshold string) {
+	c.optionsMutex.Lock()
+	defer c.optionsMutex.Unlock()
+	return c.severity, c.threshold
 }
 
 // Creates a new check with the given name and parent group. The group may not be nil.
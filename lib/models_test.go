@@ -22,12 +22,14 @@ package lib
 
 import (
 	"encoding/json"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 )
 
@@ -41,6 +43,61 @@ func TestStageJSON(t *testing.T) {
 	var s2 Stage
 	assert.NoError(t, json.Unmarshal(data, &s2))
 	assert.Equal(t, s, s2)
+
+	t.Run("Rate", func(t *testing.T) {
+		s := Stage{Duration: types.NullDurationFrom(10 * time.Second), Rate: null.FloatFrom(2.5)}
+
+		data, err := json.Marshal(s)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"duration":"10s","target":null,"rate":2.5}`, string(data))
+
+		var s2 Stage
+		assert.NoError(t, json.Unmarshal(data, &s2))
+		assert.Equal(t, s, s2)
+	})
+}
+
+func TestStageText(t *testing.T) {
+	testdata := map[string]Stage{
+		"10s":        {Duration: types.NullDurationFrom(10 * time.Second)},
+		"10s:5":      {Duration: types.NullDurationFrom(10 * time.Second), Target: null.IntFrom(5)},
+		"1m:5:100.5": {Duration: types.NullDurationFrom(1 * time.Minute), Target: null.IntFrom(5), Rate: null.FloatFrom(100.5)},
+		"1m::100":    {Duration: types.NullDurationFrom(1 * time.Minute), Rate: null.FloatFrom(100)},
+	}
+	for text, stage := range testdata {
+		var s Stage
+		assert.NoError(t, s.UnmarshalText([]byte(text)), text)
+		assert.Equal(t, stage, s, text)
+	}
+
+	var s Stage
+	assert.Error(t, s.UnmarshalText([]byte("1m:5:fast")))
+}
+
+func TestScenarioForVU(t *testing.T) {
+	scenarios := map[string]Scenario{
+		"browse":   {Weight: null.IntFrom(3)},
+		"checkout": {},
+		"disabled": {Weight: null.IntFrom(0)},
+	}
+	assert.NoError(t, ValidateScenarios(scenarios))
+
+	var assigned []string
+	for id := int64(1); id <= 9; id++ {
+		assigned = append(assigned, ScenarioForVU(scenarios, id))
+	}
+	assert.Equal(t, []string{
+		"browse", "browse", "browse", "checkout",
+		"browse", "browse", "browse", "checkout",
+		"browse",
+	}, assigned)
+	assert.Equal(t, "", ScenarioForVU(nil, 1))
+
+	assert.EqualError(t, ValidateScenarios(map[string]Scenario{"a": {Weight: null.IntFrom(-1)}}),
+		"the weight of scenario 'a' can't be negative")
+	assert.EqualError(t, ValidateScenarios(map[string]Scenario{"a": {Weight: null.IntFrom(0)}}),
+		"at least one scenario has to have a weight above 0")
+	assert.EqualError(t, ValidateScenarios(map[string]Scenario{"": {}}), "scenarios have to have a name")
 }
 
 // Suggested by @nkovacs in https://github.com/loadimpact/k6/issues/207#issuecomment-330545467
@@ -88,3 +145,53 @@ func TestDataRaces(t *testing.T) {
 		assert.Equal(t, group1, group2, "Groups are the same")
 	})
 }
+
+func TestCheckSetOptions(t *testing.T) {
+	root, err := NewGroup("", nil)
+	require.NoError(t, err)
+	check, err := root.Check("login")
+	require.NoError(t, err)
+
+	severity, threshold := check.Options()
+	assert.Equal(t, "", severity)
+	assert.Equal(t, "", threshold)
+
+	changed, err := check.SetOptions("", "")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	severity, threshold = check.Options()
+	assert.Equal(t, CheckSeverityError, severity)
+	assert.Equal(t, DefaultCheckThreshold, threshold)
+
+	changed, err = check.SetOptions("error", "rate==1")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = check.SetOptions("warn", "rate>0.9")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	severity, threshold = check.Options()
+	assert.Equal(t, CheckSeverityWarn, severity)
+	assert.Equal(t, "rate>0.9", threshold)
+
+	_, err = check.SetOptions("fatal", "")
+	assert.EqualError(t, err, "invalid severity 'fatal' for the check 'login', it has to be 'warn' or 'error'")
+}
+
+func TestGroupWalkChecks(t *testing.T) {
+	root, err := NewGroup("", nil)
+	require.NoError(t, err)
+	inner, err := root.Group("inner")
+	require.NoError(t, err)
+	_, err = root.Check("a")
+	require.NoError(t, err)
+	_, err = inner.Check("b")
+	require.NoError(t, err)
+	_, err = inner.Check("c")
+	require.NoError(t, err)
+
+	var paths []string
+	root.WalkChecks(func(c *Check) { paths = append(paths, c.Path) })
+	sort.Strings(paths)
+	assert.Equal(t, []string{"::a", "::inner::b", "::inner::c"}, paths)
+}
@@ -30,6 +30,7 @@ type ctxKey int
 const (
 	ctxKeyTracer ctxKey = iota
 	ctxKeyAuth
+	ctxKeyUnixSocket
 )
 
 func WithTracer(ctx context.Context, tracer *Tracer) context.Context {
@@ -38,6 +39,15 @@ func WithTracer(ctx context.Context, tracer *Tracer) context.Context {
 	return ctx
 }
 
+// GetTracer returns the Tracer attached with WithTracer, or nil if there is none.
+func GetTracer(ctx context.Context) *Tracer {
+	v := ctx.Value(ctxKeyTracer)
+	if v == nil {
+		return nil
+	}
+	return v.(*Tracer)
+}
+
 func WithAuth(ctx context.Context, auth string) context.Context {
 	return context.WithValue(ctx, ctxKeyAuth, auth)
 }
@@ -49,3 +59,18 @@ func GetAuth(ctx context.Context) string {
 	}
 	return v.(string)
 }
+
+// WithUnixSocket makes HTTPTransport send the request over the Unix socket at the given path,
+// instead of connecting to the host in its URL.
+func WithUnixSocket(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, ctxKeyUnixSocket, path)
+}
+
+// GetUnixSocket returns the Unix socket path attached with WithUnixSocket, or "" if there is none.
+func GetUnixSocket(ctx context.Context) string {
+	v := ctx.Value(ctxKeyUnixSocket)
+	if v == nil {
+		return ""
+	}
+	return v.(string)
+}
@@ -42,6 +42,7 @@ type Dialer struct {
 	Resolver  *dnscache.Resolver
 	Blacklist []*net.IPNet
 	Hosts     map[string]net.IP
+	LocalIPs  *LocalIPPool
 
 	BytesRead    int64
 	BytesWritten int64
@@ -57,6 +58,15 @@ func NewDialer(dialer net.Dialer) *Dialer {
 
 // DialContext wraps the net.Dialer.DialContext and handles the k6 specifics
 func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn, error) {
+	// Unix sockets have no host to resolve or blacklist.
+	if proto == "unix" {
+		conn, err := d.Dialer.DialContext(ctx, proto, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &Conn{conn, &d.BytesRead, &d.BytesWritten}, nil
+	}
+
 	delimiter := strings.LastIndex(addr, ":")
 	host := addr[:delimiter]
 
@@ -64,7 +74,11 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 	ip, ok := d.Hosts[host]
 	if !ok {
 		var err error
+		start := time.Now()
 		ip, err = d.Resolver.FetchOne(host)
+		if tracer := GetTracer(ctx); tracer != nil {
+			tracer.LookedUp(time.Since(start))
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -72,14 +86,26 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 
 	for _, net := range d.Blacklist {
 		if net.Contains(ip) {
-			return nil, errors.Errorf("IP (%s) is in a blacklisted range (%s)", ip, net)
+			return nil, BlacklistedIPError{IP: ip, Net: net}
 		}
 	}
 	ipStr := ip.String()
 	if strings.ContainsRune(ipStr, ':') {
 		ipStr = "[" + ipStr + "]"
 	}
-	conn, err := d.Dialer.DialContext(ctx, proto, ipStr+":"+addr[delimiter+1:])
+	dialer := d.Dialer
+	if d.LocalIPs != nil {
+		localIP, err := d.LocalIPs.Next(ip)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(proto, "udp") {
+			dialer.LocalAddr = &net.UDPAddr{IP: localIP}
+		} else {
+			dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+		}
+	}
+	conn, err := dialer.DialContext(ctx, proto, ipStr+":"+addr[delimiter+1:])
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +113,34 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 	return conn, err
 }
 
+// A LocalIPPool hands out the local addresses that outgoing connections are made from, in turns.
+// It's shared by the dialers of all VUs, so that the addresses are used evenly.
+type LocalIPPool struct {
+	ips  []net.IP
+	next uint64
+}
+
+// NewLocalIPPool returns a pool of the given local IPs.
+func NewLocalIPPool(ips []net.IP) *LocalIPPool {
+	return &LocalIPPool{ips: ips}
+}
+
+// Next returns the next local IP that can connect to the remote IP, ie. is of the same family.
+func (p *LocalIPPool) Next(remote net.IP) (net.IP, error) {
+	remote4 := remote.To4() != nil
+	for range p.ips {
+		ip := p.ips[(atomic.AddUint64(&p.next, 1)-1)%uint64(len(p.ips))]
+		if (ip.To4() != nil) == remote4 {
+			return ip, nil
+		}
+	}
+	family := "IPv6"
+	if remote4 {
+		family = "IPv4"
+	}
+	return nil, errors.Errorf("can't connect to %s, none of the local IPs are %s addresses", remote, family)
+}
+
 // GetTrail creates a new NetTrail instance with the Dialer
 // sent and received data metrics and the supplied times and tags.
 func (d *Dialer) GetTrail(startTime, endTime time.Time, tags *stats.SampleTags) *NetTrail {
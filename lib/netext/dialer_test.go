@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalIPPool(t *testing.T) {
+	pool := NewLocalIPPool([]net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.2")})
+	var got []string
+	for i := 0; i < 4; i++ {
+		ip, err := pool.Next(net.ParseIP("198.51.100.1"))
+		require.NoError(t, err)
+		got = append(got, ip.String())
+	}
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2", "192.0.2.1", "192.0.2.2"}, got)
+
+	ip, err := pool.Next(net.ParseIP("2001:db8::2"))
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8::1", ip.String())
+
+	_, err = NewLocalIPPool([]net.IP{net.ParseIP("192.0.2.1")}).Next(net.ParseIP("::1"))
+	assert.EqualError(t, err, "can't connect to ::1, none of the local IPs are IPv6 addresses")
+}
+
+func TestDialerLocalIPs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux has all of 127.0.0.0/8 on the loopback interface")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	remotes := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			remotes <- conn.RemoteAddr().(*net.TCPAddr).IP.String()
+			_ = conn.Close()
+		}
+	}()
+
+	dialer := NewDialer(net.Dialer{})
+	dialer.LocalIPs = NewLocalIPPool([]net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")})
+	for _, expected := range []string{"127.0.0.2", "127.0.0.3"} {
+		conn, err := dialer.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		_ = conn.Close()
+		assert.Equal(t, expected, <-remotes)
+	}
+}
+
+func TestDialerLookingUp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	dialer := NewDialer(net.Dialer{})
+	dialer.Hosts = map[string]net.IP{"k6.test": net.ParseIP("127.0.0.1")}
+	for host, looksUp := range map[string]bool{"localhost": true, "k6.test": false} {
+		tracer := &Tracer{}
+		conn, err := dialer.DialContext(WithTracer(context.Background(), tracer), "tcp", host+":"+port)
+		require.NoError(t, err)
+		_ = conn.Close()
+		assert.Equal(t, looksUp, tracer.Done().LookingUp > 0, host)
+	}
+}
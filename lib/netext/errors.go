@@ -0,0 +1,182 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// ErrCode is a stable numeric code for the reason a request failed, so that failures can be
+// counted and thresholded on without parsing error messages, which change between Go versions.
+// The codes are grouped by where the failure happened:
+//
+//	1000-1099: general, e.g. timeouts or invalid URLs
+//	1100-1199: DNS resolution
+//	1200-1299: TCP, e.g. refused or reset connections
+//	1300-1399: TLS, e.g. invalid certificates
+//	1400-1599: HTTP 4xx and 5xx responses (1000 + the status code)
+type ErrCode uint32
+
+// The error codes; see ErrCode for how they are grouped.
+const (
+	DefaultErrorCode          ErrCode = 1000
+	NonTCPNetworkErrorCode    ErrCode = 1010
+	InvalidURLErrorCode       ErrCode = 1020
+	RequestTimeoutErrorCode   ErrCode = 1050
+	DNSErrorCode              ErrCode = 1100
+	DNSNoSuchHostErrorCode    ErrCode = 1101
+	BlacklistedIPErrorCode    ErrCode = 1110
+	TCPErrorCode              ErrCode = 1200
+	TCPBrokenPipeErrorCode    ErrCode = 1201
+	TCPDialErrorCode          ErrCode = 1210
+	TCPDialTimeoutErrorCode   ErrCode = 1211
+	TCPDialRefusedErrorCode   ErrCode = 1212
+	TCPResetByPeerErrorCode   ErrCode = 1220
+	TLSErrorCode              ErrCode = 1300
+	TLSHeaderErrorCode        ErrCode = 1301
+	X509UnknownAuthorityCode  ErrCode = 1310
+	X509HostnameErrorCode     ErrCode = 1311
+	X509InvalidCertErrorCode  ErrCode = 1312
+	HTTPStatusErrorCodeOffset ErrCode = 1000
+)
+
+// BlacklistedIPError is returned by the Dialer for IPs in one of the blacklisted ranges.
+type BlacklistedIPError struct {
+	IP  net.IP
+	Net *net.IPNet
+}
+
+func (e BlacklistedIPError) Error() string {
+	return fmt.Sprintf("IP (%s) is in a blacklisted range (%s)", e.IP, e.Net)
+}
+
+// StatusErrorCode returns the error code for an HTTP response status, or 0 if it's not an error.
+func StatusErrorCode(status int) ErrCode {
+	if status < 400 || status > 599 {
+		return 0
+	}
+	return HTTPStatusErrorCodeOffset + ErrCode(status)
+}
+
+// ClassifyError returns the error code for an error that a request failed with, looking through
+// the url.Error, net.OpError and os.SyscallError wrappers for the underlying cause.
+func ClassifyError(err error) ErrCode {
+	if err == nil {
+		return 0
+	}
+
+	for {
+		switch e := err.(type) {
+		case *url.Error:
+			if e.Timeout() {
+				return RequestTimeoutErrorCode
+			}
+			if e.Op == "parse" {
+				return InvalidURLErrorCode
+			}
+			err = e.Err
+			continue
+		case *net.OpError:
+			if e.Net != "tcp" && e.Net != "tcp4" && e.Net != "tcp6" {
+				return NonTCPNetworkErrorCode
+			}
+			if e.Op == "dial" {
+				return classifyDialError(e)
+			}
+			if code := classifyErrno(e.Err); code != 0 {
+				return code
+			}
+			if e.Timeout() {
+				return RequestTimeoutErrorCode
+			}
+			return TCPErrorCode
+		case *os.SyscallError:
+			if code := classifyErrno(e.Err); code != 0 {
+				return code
+			}
+			return TCPErrorCode
+		case *net.DNSError:
+			if strings.HasSuffix(e.Err, "no such host") {
+				return DNSNoSuchHostErrorCode
+			}
+			return DNSErrorCode
+		case BlacklistedIPError, *BlacklistedIPError:
+			return BlacklistedIPErrorCode
+		case tls.RecordHeaderError:
+			return TLSHeaderErrorCode
+		case x509.UnknownAuthorityError, *x509.UnknownAuthorityError:
+			return X509UnknownAuthorityCode
+		case x509.HostnameError, *x509.HostnameError:
+			return X509HostnameErrorCode
+		case x509.CertificateInvalidError, *x509.CertificateInvalidError:
+			return X509InvalidCertErrorCode
+		case interface{ Cause() error }:
+			err = e.Cause()
+			continue
+		case net.Error:
+			if e.Timeout() {
+				return RequestTimeoutErrorCode
+			}
+		}
+
+		if strings.Contains(err.Error(), "tls: ") {
+			return TLSErrorCode
+		}
+		return DefaultErrorCode
+	}
+}
+
+func classifyDialError(e *net.OpError) ErrCode {
+	switch inner := e.Err.(type) {
+	case *net.DNSError:
+		return ClassifyError(inner)
+	case *os.SyscallError:
+		if inner.Err == syscall.ECONNREFUSED {
+			return TCPDialRefusedErrorCode
+		}
+	}
+	if e.Timeout() {
+		return TCPDialTimeoutErrorCode
+	}
+	return TCPDialErrorCode
+}
+
+func classifyErrno(err error) ErrCode {
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	switch err {
+	case syscall.ECONNREFUSED:
+		return TCPDialRefusedErrorCode
+	case syscall.ECONNRESET:
+		return TCPResetByPeerErrorCode
+	case syscall.EPIPE:
+		return TCPBrokenPipeErrorCode
+	}
+	return 0
+}
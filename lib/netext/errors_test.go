@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	// Nothing listens on a port that was just closed, so connecting to it is refused
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+	_, refusedErr := http.Get("http://" + addr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	req, err := http.NewRequest("GET", "http://"+addr, nil)
+	require.NoError(t, err)
+	_, timeoutErr := http.DefaultClient.Do(req.WithContext(ctx))
+
+	testdata := map[string]struct {
+		err  error
+		code ErrCode
+	}{
+		"nil":                {nil, 0},
+		"generic":            {errors.New("something"), DefaultErrorCode},
+		"refused":            {refusedErr, TCPDialRefusedErrorCode},
+		"timeout":            {timeoutErr, RequestTimeoutErrorCode},
+		"invalid url":        {&url.Error{Op: "parse", URL: ":", Err: errors.New("missing protocol scheme")}, InvalidURLErrorCode},
+		"no such host":       {&url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "k6.invalid"}}}, DNSNoSuchHostErrorCode},
+		"dns":                {&net.DNSError{Err: "server misbehaving", Name: "k6.io"}, DNSErrorCode},
+		"blacklisted":        {errors.Wrap(BlacklistedIPError{IP: net.IPv4(10, 0, 0, 1)}, "dial"), BlacklistedIPErrorCode},
+		"unix":               {&net.OpError{Op: "dial", Net: "unix", Err: errors.New("no such file")}, NonTCPNetworkErrorCode},
+		"unknown authority":  {&url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, X509UnknownAuthorityCode},
+		"hostname mismatch":  {x509.HostnameError{Host: "k6.io", Certificate: &x509.Certificate{}}, X509HostnameErrorCode},
+		"other tls problems": {errors.New("remote error: tls: handshake failure"), TLSErrorCode},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, data.code, ClassifyError(data.err))
+		})
+	}
+}
+
+func TestStatusErrorCode(t *testing.T) {
+	assert.Equal(t, ErrCode(0), StatusErrorCode(200))
+	assert.Equal(t, ErrCode(0), StatusErrorCode(302))
+	assert.Equal(t, ErrCode(1404), StatusErrorCode(404))
+	assert.Equal(t, ErrCode(1503), StatusErrorCode(503))
+}
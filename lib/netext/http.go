@@ -2,15 +2,20 @@ package netext
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ThomsonReutersEikon/go-ntlm/ntlm"
 	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
 )
 
 type HTTPTransport struct {
@@ -19,19 +24,181 @@ type HTTPTransport struct {
 	mu          sync.Mutex
 	authCache   map[string]bool
 	enableCache bool
+
+	// Copies of Transport that dial a Unix socket instead, so their connections are pooled
+	// separately from the normal ones.
+	unixTransports map[string]*http.Transport
+
+	// If it's set, HTTPS requests present the client certificate that it returns for their host,
+	// or none if it returns nil, e.g. for the tlsAuth option. The certificate is picked through
+	// a copy of Transport for every host, since the TLS handshake doesn't know the host otherwise.
+	ClientCertificate func(host string) (*tls.Certificate, error)
+	hostTransports    map[string]*http.Transport
+
+	// If it's positive, requests to a host wait until fewer than this many connections to it are
+	// in use, ie. have a request whose response body isn't closed yet.
+	MaxConnsPerHost int
+	hostSlots       map[string]chan struct{}
 }
 
 func NewHTTPTransport(transport *http.Transport) *HTTPTransport {
 	return &HTTPTransport{
-		Transport:   transport,
-		authCache:   make(map[string]bool),
-		enableCache: true,
+		Transport:      transport,
+		authCache:      make(map[string]bool),
+		enableCache:    true,
+		unixTransports: make(map[string]*http.Transport),
+		hostTransports: make(map[string]*http.Transport),
+		hostSlots:      make(map[string]chan struct{}),
+	}
+}
+
+// acquireConn waits for a free connection to the request's host, if they're limited, and returns
+// the function that frees it again. The time it waited is reported to the request's Tracer.
+func (t *HTTPTransport) acquireConn(req *http.Request) (func(), error) {
+	if t.MaxConnsPerHost <= 0 {
+		return func() {}, nil
+	}
+
+	key := GetUnixSocket(req.Context()) + "|" + req.URL.Scheme + "://" + req.URL.Host
+	t.mu.Lock()
+	slots, ok := t.hostSlots[key]
+	if !ok {
+		slots = make(chan struct{}, t.MaxConnsPerHost)
+		t.hostSlots[key] = slots
+	}
+	t.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
 	}
+	if tracer := GetTracer(req.Context()); tracer != nil {
+		tracer.Queued(time.Since(start))
+	}
+
+	var once sync.Once
+	return func() { once.Do(func() { <-slots }) }, nil
+}
+
+// A releasingBody frees its connection for the next request when it's closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
 }
 
 func (t *HTTPTransport) CloseIdleConnections() {
 	t.enableCache = false
 	t.Transport.CloseIdleConnections()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, ut := range t.unixTransports {
+		ut.CloseIdleConnections()
+	}
+	for _, ht := range t.hostTransports {
+		ht.CloseIdleConnections()
+	}
+}
+
+// getTransport returns the transport a request should go through - the Unix socket attached to
+// its context with WithUnixSocket, if any, the one with the client certificate for its host, if
+// there are client certificates, or the normal one otherwise.
+func (t *HTTPTransport) getTransport(req *http.Request) *http.Transport {
+	path := GetUnixSocket(req.Context())
+	if path == "" {
+		if t.ClientCertificate == nil || req.URL.Scheme != "https" {
+			return t.Transport
+		}
+		return t.getHostTransport(req.URL.Hostname())
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ut, ok := t.unixTransports[path]
+	if !ok {
+		ut = cloneTransport(t.Transport)
+		dial := t.Transport.DialContext
+		if dial == nil {
+			var d net.Dialer
+			dial = d.DialContext
+		}
+		ut.Proxy = nil
+		ut.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dial(ctx, "unix", path)
+		}
+		t.unixTransports[path] = ut
+	}
+	return ut
+}
+
+// getHostTransport returns the copy of Transport whose TLS config presents the client
+// certificate for host.
+func (t *HTTPTransport) getHostTransport(host string) *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ht, ok := t.hostTransports[host]
+	if !ok {
+		ht = cloneTransport(t.Transport)
+		if ht.TLSClientConfig == nil {
+			ht.TLSClientConfig = &tls.Config{}
+		}
+		ht.TLSClientConfig.ServerName = host
+		clientCertificate := t.ClientCertificate
+		ht.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := clientCertificate(host)
+			if cert == nil && err == nil {
+				// An empty certificate means that none is sent; the server decides whether that's ok.
+				return &tls.Certificate{}, nil
+			}
+			return cert, err
+		}
+		t.hostTransports[host] = ht
+	}
+	return ht
+}
+
+// cloneTransport copies the settings of a transport to a new one; http.Transport.Clone() would
+// need Go 1.13.
+func cloneTransport(t *http.Transport) *http.Transport {
+	ut := &http.Transport{
+		Proxy:                  t.Proxy,
+		ProxyConnectHeader:     t.ProxyConnectHeader,
+		DialContext:            t.DialContext,
+		DialTLS:                t.DialTLS,
+		TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
+		DisableKeepAlives:      t.DisableKeepAlives,
+		DisableCompression:     t.DisableCompression,
+		MaxIdleConns:           t.MaxIdleConns,
+		MaxIdleConnsPerHost:    t.MaxIdleConnsPerHost,
+		IdleConnTimeout:        t.IdleConnTimeout,
+		ResponseHeaderTimeout:  t.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  t.ExpectContinueTimeout,
+		MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
+	}
+	if t.TLSClientConfig != nil {
+		ut.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	if _, ok := t.TLSNextProto["h2"]; ok {
+		// The HTTP/2 support that http2.ConfigureTransport() adds is tied to the connection pool of
+		// the transport it's added to, so the copy needs its own.
+		_ = http2.ConfigureTransport(ut)
+	} else if t.TLSNextProto != nil {
+		// An empty map disables HTTP/2
+		ut.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper, len(t.TLSNextProto))
+		for proto, upgrade := range t.TLSNextProto {
+			ut.TLSNextProto[proto] = upgrade
+		}
+	}
+	return ut
 }
 
 func (t *HTTPTransport) RoundTrip(req *http.Request) (res *http.Response, err error) {
@@ -39,16 +206,27 @@ func (t *HTTPTransport) RoundTrip(req *http.Request) (res *http.Response, err er
 		return nil, errors.New("no roundtrip defined")
 	}
 
+	release, err := t.acquireConn(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// checking if the request needs ntlm authentication
 	if GetAuth(req.Context()) == "ntlm" && req.URL.User != nil {
-		return t.roundtripWithNTLM(req)
+		res, err = t.roundtripWithNTLM(req)
+	} else {
+		res, err = t.getTransport(req).RoundTrip(req)
 	}
-
-	return t.Transport.RoundTrip(req)
+	if err != nil || res.Body == nil {
+		release()
+		return res, err
+	}
+	res.Body = releasingBody{res.Body, release}
+	return res, nil
 }
 
 func (t *HTTPTransport) roundtripWithNTLM(req *http.Request) (res *http.Response, err error) {
-	rt := t.Transport
+	rt := t.getTransport(req)
 
 	username := req.URL.User.Username()
 	password, _ := req.URL.User.Password()
@@ -0,0 +1,147 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func TestHTTPTransportUnixSocketHTTP2(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k6-unix-socket")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "h2.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	_ = srv.Listener.Close()
+	srv.Listener = listener
+	require.NoError(t, http2.ConfigureServer(srv.Config, nil))
+	srv.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	require.NoError(t, http2.ConfigureTransport(transport))
+	client := http.Client{Transport: NewHTTPTransport(transport)}
+
+	req, err := http.NewRequest("GET", "https://example.com/", nil)
+	require.NoError(t, err)
+	res, err := client.Do(req.WithContext(WithUnixSocket(context.Background(), path)))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, "HTTP/2.0", res.Proto)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
+
+func TestHTTPTransportMaxConnsPerHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	transport := NewHTTPTransport(&http.Transport{})
+	transport.MaxConnsPerHost = 1
+	client := http.Client{Transport: transport}
+
+	trails := make(chan *Trail, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			tracer := &Tracer{}
+			req, _ := http.NewRequest("GET", srv.URL, nil)
+			res, err := client.Do(req.WithContext(WithTracer(context.Background(), tracer)))
+			if assert.NoError(t, err) {
+				_, _ = ioutil.ReadAll(res.Body)
+				_ = res.Body.Close()
+			}
+			trails <- tracer.Done()
+		}()
+	}
+
+	first, second := <-trails, <-trails
+	assert.True(t, first.Queueing)
+	assert.True(t, first.Queued < 50*time.Millisecond, first.Queued)
+	assert.True(t, second.Queued >= 50*time.Millisecond, "the second request waited for the first: %s", second.Queued)
+
+	second.SaveSamples(nil)
+	var queued []float64
+	for _, s := range second.GetSamples() {
+		if s.Metric == metrics.HTTPReqQueued {
+			queued = append(queued, s.Value)
+		}
+	}
+	assert.Len(t, queued, 1)
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow, err := http.NewRequest("GET", srv.URL, nil)
+		require.NoError(t, err)
+		res, err := client.Do(slow)
+		require.NoError(t, err)
+
+		cancel()
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.NoError(t, err)
+		_, err = client.Do(req.WithContext(ctx))
+		assert.Error(t, err, "the connection is still in use by the unclosed response")
+
+		_ = res.Body.Close()
+		res, err = client.Get(srv.URL)
+		require.NoError(t, err)
+		_ = res.Body.Close()
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		tracer := &Tracer{}
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.NoError(t, err)
+		client := http.Client{Transport: NewHTTPTransport(&http.Transport{})}
+		resp, err := client.Do(req.WithContext(WithTracer(context.Background(), tracer)))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		trail := tracer.Done()
+		assert.False(t, trail.Queueing)
+		trail.SaveSamples(nil)
+		for _, s := range trail.GetSamples() {
+			assert.NotEqual(t, metrics.HTTPReqQueued, s.Metric)
+		}
+	})
+}
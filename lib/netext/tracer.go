@@ -30,6 +30,7 @@ import (
 
 	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
+	null "gopkg.in/guregu/null.v3"
 )
 
 // A Trail represents detailed information about an HTTP request.
@@ -45,17 +46,37 @@ type Trail struct {
 	Duration time.Duration
 
 	Blocked        time.Duration // Waiting to acquire a connection.
+	LookingUp      time.Duration // Looking up the host's IP, part of Blocked.
+	Queued         time.Duration // Waiting for a free connection, if there's a limit per host.
 	Connecting     time.Duration // Connecting to remote host.
 	TLSHandshaking time.Duration // Executing TLS handshake.
 	Sending        time.Duration // Writing request.
 	Waiting        time.Duration // Waiting for first byte.
 	Receiving      time.Duration // Receiving response.
 
+	// Informational responses, measured from when the request headers were written; the Got...
+	// fields say whether there was one, no http_req_early_hints sample is emitted if not.
+	Continue      time.Duration // Waiting for a 100 Continue, for requests with Expect: 100-continue.
+	EarlyHints    time.Duration // Waiting for the first 103 Early Hints.
+	GotContinue   bool
+	GotEarlyHints bool
+
 	// Detailed connection information.
 	ConnReused     bool
 	ConnRemoteAddr net.Addr
 	Errors         []error
 
+	// Whether the response was a failure according to the response callback; no http_req_failed
+	// sample is emitted if it's not set.
+	Failed null.Bool
+
+	// Whether the connections per host are limited; no http_req_queued sample is emitted if not.
+	Queueing bool
+
+	// The size of the response's status line and headers, and of its body as it was sent, i.e.
+	// before decompression; no samples are emitted for them if there was no response.
+	HeaderSize, BodySize int64
+
 	// Populated by SaveSamples()
 	Tags    *stats.SampleTags
 	Samples []stats.Sample
@@ -75,6 +96,34 @@ func (tr *Trail) SaveSamples(tags *stats.SampleTags) {
 		{Metric: metrics.HTTPReqWaiting, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Waiting)},
 		{Metric: metrics.HTTPReqReceiving, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Receiving)},
 	}
+	if tr.Queueing {
+		tr.Samples = append(tr.Samples, stats.Sample{Metric: metrics.HTTPReqQueued, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Queued)})
+	}
+	if tr.GotEarlyHints {
+		tr.Samples = append(tr.Samples, stats.Sample{Metric: metrics.HTTPReqEarlyHints, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.EarlyHints)})
+	}
+	if tr.Failed.Valid {
+		failed := 0.0
+		if tr.Failed.Bool {
+			failed = 1
+		}
+		tr.Samples = append(tr.Samples, stats.Sample{Metric: metrics.HTTPReqFailed, Time: tr.EndTime, Tags: tags, Value: failed})
+	}
+	if tr.HeaderSize > 0 {
+		tr.Samples = append(tr.Samples,
+			stats.Sample{Metric: metrics.HTTPRespHeaderBytes, Time: tr.EndTime, Tags: tags, Value: float64(tr.HeaderSize)},
+			stats.Sample{Metric: metrics.HTTPRespBodyBytes, Time: tr.EndTime, Tags: tags, Value: float64(tr.BodySize)},
+		)
+	}
+	// Requests that failed before getting a connection say nothing about keep-alive
+	if tr.ConnRemoteAddr != nil {
+		reused := 1.0
+		if !tr.ConnReused {
+			reused = 0
+			tr.Samples = append(tr.Samples, stats.Sample{Metric: metrics.NewConnections, Time: tr.EndTime, Tags: tags, Value: 1})
+		}
+		tr.Samples = append(tr.Samples, stats.Sample{Metric: metrics.HTTPReqConnReused, Time: tr.EndTime, Tags: tags, Value: reused})
+	}
 }
 
 // GetSamples implements the stats.SampleContainer interface.
@@ -107,28 +156,39 @@ type Tracer struct {
 	tlsHandshakeStart    int64
 	tlsHandshakeDone     int64
 	gotConn              int64
+	wroteHeaders         int64
 	wroteRequest         int64
 	gotFirstResponseByte int64
+	got100Continue       int64
+	gotEarlyHints        int64
 
 	connReused     bool
 	connRemoteAddr net.Addr
 
+	queueing  int32
+	queued    int64
+	lookingUp int64
+
 	protoErrorsMutex sync.Mutex
 	protoErrors      []error
 }
 
 // Trace returns a premade ClientTrace that calls all of the Tracer's hooks.
 func (t *Tracer) Trace() *httptrace.ClientTrace {
-	return &httptrace.ClientTrace{
+	trace := &httptrace.ClientTrace{
 		GetConn:              t.GetConn,
 		ConnectStart:         t.ConnectStart,
 		ConnectDone:          t.ConnectDone,
 		TLSHandshakeStart:    t.TLSHandshakeStart,
 		TLSHandshakeDone:     t.TLSHandshakeDone,
 		GotConn:              t.GotConn,
+		WroteHeaders:         t.WroteHeaders,
 		WroteRequest:         t.WroteRequest,
 		GotFirstResponseByte: t.GotFirstResponseByte,
+		Got100Continue:       t.Got100Continue,
 	}
+	t.traceInformationalResponses(trace)
+	return trace
 }
 
 // Add an error in a thread-safe way
@@ -235,6 +295,12 @@ func (t *Tracer) GotConn(info httptrace.GotConnInfo) {
 	}
 }
 
+// WroteHeaders is called after the Transport has written
+// all request headers.
+func (t *Tracer) WroteHeaders() {
+	atomic.CompareAndSwapInt64(&t.wroteHeaders, 0, now())
+}
+
 // WroteRequest is called with the result of writing the
 // request and any body. It may be called multiple times
 // in the case of retried requests.
@@ -254,6 +320,25 @@ func (t *Tracer) GotFirstResponseByte() {
 	atomic.CompareAndSwapInt64(&t.gotFirstResponseByte, 0, now())
 }
 
+// Got100Continue is called if the server replies with a "100
+// Continue" response.
+func (t *Tracer) Got100Continue() {
+	atomic.CompareAndSwapInt64(&t.got100Continue, 0, now())
+}
+
+// Queued is called by HTTPTransport when the connections per host are limited, with how long
+// the request waited for a free one. It's called again for every redirect.
+func (t *Tracer) Queued(d time.Duration) {
+	atomic.StoreInt32(&t.queueing, 1)
+	atomic.AddInt64(&t.queued, int64(d))
+}
+
+// LookedUp is called by Dialer with how long it took to look up the IP of the host it dials,
+// which the httptrace DNS hooks don't see, since it's not resolved by the net package.
+func (t *Tracer) LookedUp(d time.Duration) {
+	atomic.AddInt64(&t.lookingUp, int64(d))
+}
+
 // Done calculates all metrics and should be called when the request is finished.
 func (t *Tracer) Done() *Trail {
 	done := time.Now()
@@ -261,6 +346,9 @@ func (t *Tracer) Done() *Trail {
 	trail := Trail{
 		ConnReused:     t.connReused,
 		ConnRemoteAddr: t.connRemoteAddr,
+		Queueing:       atomic.LoadInt32(&t.queueing) != 0,
+		Queued:         time.Duration(atomic.LoadInt64(&t.queued)),
+		LookingUp:      time.Duration(atomic.LoadInt64(&t.lookingUp)),
 	}
 
 	if t.gotConn != 0 && t.getConn != 0 {
@@ -279,11 +367,21 @@ func (t *Tracer) Done() *Trail {
 	wroteRequest := atomic.LoadInt64(&t.wroteRequest)
 	gotFirstResponseByte := atomic.LoadInt64(&t.gotFirstResponseByte)
 
-	if connectDone != 0 && connectStart != 0 {
-		trail.Connecting = time.Duration(connectDone - connectStart)
+	// A phase that was started but never finished, because the request timed out or failed
+	// during it, lasted until now, so that the partial trail still adds up to the time it took.
+	if connectStart != 0 {
+		if connectDone == 0 {
+			trail.Connecting = done.Sub(time.Unix(0, connectStart))
+		} else {
+			trail.Connecting = time.Duration(connectDone - connectStart)
+		}
 	}
-	if tlsHandshakeDone != 0 && tlsHandshakeStart != 0 {
-		trail.TLSHandshaking = time.Duration(tlsHandshakeDone - tlsHandshakeStart)
+	if tlsHandshakeStart != 0 {
+		if tlsHandshakeDone == 0 {
+			trail.TLSHandshaking = done.Sub(time.Unix(0, tlsHandshakeStart))
+		} else {
+			trail.TLSHandshaking = time.Duration(tlsHandshakeDone - tlsHandshakeStart)
+		}
 	}
 	if wroteRequest != 0 {
 		trail.Sending = time.Duration(wroteRequest - connectDone)
@@ -295,11 +393,23 @@ func (t *Tracer) Done() *Trail {
 
 		if gotFirstResponseByte != 0 {
 			trail.Waiting = time.Duration(gotFirstResponseByte - wroteRequest)
+		} else {
+			trail.Waiting = done.Sub(time.Unix(0, wroteRequest))
 		}
 	}
 	if gotFirstResponseByte != 0 {
 		trail.Receiving = done.Sub(time.Unix(0, gotFirstResponseByte))
 	}
+	if wroteHeaders := atomic.LoadInt64(&t.wroteHeaders); wroteHeaders != 0 {
+		if got100Continue := atomic.LoadInt64(&t.got100Continue); got100Continue != 0 {
+			trail.GotContinue = true
+			trail.Continue = time.Duration(got100Continue - wroteHeaders)
+		}
+		if gotEarlyHints := atomic.LoadInt64(&t.gotEarlyHints); gotEarlyHints != 0 {
+			trail.GotEarlyHints = true
+			trail.EarlyHints = time.Duration(gotEarlyHints - wroteHeaders)
+		}
+	}
 
 	// Calculate total times using adjusted values.
 	trail.EndTime = done
@@ -0,0 +1,29 @@
+// +build !go1.11
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import "net/http/httptrace"
+
+// traceInformationalResponses does nothing before Go 1.11, which has no Got1xxResponse hook, so
+// 103 Early Hints aren't traced and there are no http_req_early_hints samples.
+func (t *Tracer) traceInformationalResponses(trace *httptrace.ClientTrace) {}
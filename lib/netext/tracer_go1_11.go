@@ -0,0 +1,49 @@
+// +build go1.11
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync/atomic"
+)
+
+// traceInformationalResponses hooks Got1xxResponse into the trace, which has it since Go 1.11.
+func (t *Tracer) traceInformationalResponses(trace *httptrace.ClientTrace) {
+	trace.Got1xxResponse = t.Got1xxResponse
+}
+
+// Got1xxResponse is called for each 1xx informational response
+// header returned before the final non-1xx response, including
+// "100 Continue", which is only passed to Got100Continue when
+// the Transport waits for it before sending the request body.
+func (t *Tracer) Got1xxResponse(code int, header textproto.MIMEHeader) error {
+	switch code {
+	case http.StatusContinue:
+		atomic.CompareAndSwapInt64(&t.got100Continue, 0, now())
+	case 103: // Early Hints; http.StatusEarlyHints needs Go 1.13
+		atomic.CompareAndSwapInt64(&t.gotEarlyHints, 0, now())
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// +build go1.19
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test server can only send informational responses since Go 1.19.
+func TestTracerEarlyHints(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(103)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tracer := &Tracer{}
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	require.NoError(t, err)
+	res, err := http.DefaultTransport.RoundTrip(req.WithContext(WithTracer(context.Background(), tracer)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NoError(t, res.Body.Close())
+	trail := tracer.Done()
+	assert.True(t, trail.GotEarlyHints)
+	assert.False(t, trail.GotContinue)
+	assert.True(t, trail.EarlyHints > 0)
+	assert.True(t, trail.Duration-trail.EarlyHints >= 50*time.Millisecond, "the final response came after the hints")
+
+	trail.SaveSamples(nil)
+	var hints []float64
+	for _, s := range trail.GetSamples() {
+		if s.Metric == metrics.HTTPReqEarlyHints {
+			hints = append(hints, s.Value)
+		}
+	}
+	assert.Equal(t, []float64{stats.D(trail.EarlyHints)}, hints)
+}
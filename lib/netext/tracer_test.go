@@ -94,7 +94,11 @@ func TestTracer(t *testing.T) {
 
 			assert.Equal(t, strings.TrimPrefix(srv.URL, "https://"), trail.ConnRemoteAddr.String())
 
-			assert.Len(t, samples, 8)
+			if isReuse {
+				assert.Len(t, samples, 9)
+			} else {
+				assert.Len(t, samples, 10)
+			}
 			seenMetrics := map[*stats.Metric]bool{}
 			for i, s := range samples {
 				assert.NotContains(t, seenMetrics, s.Metric)
@@ -115,6 +119,15 @@ func TestTracer(t *testing.T) {
 					fallthrough
 				case metrics.HTTPReqDuration, metrics.HTTPReqBlocked, metrics.HTTPReqSending, metrics.HTTPReqWaiting, metrics.HTTPReqReceiving:
 					assert.True(t, s.Value > 0.0, "%s is <= 0", s.Metric.Name)
+				case metrics.HTTPReqConnReused:
+					if isReuse {
+						assert.Equal(t, 1.0, s.Value)
+					} else {
+						assert.Equal(t, 0.0, s.Value)
+					}
+				case metrics.NewConnections:
+					assert.False(t, isReuse, "a reused connection isn't a new one")
+					assert.Equal(t, 1.0, s.Value)
 				default:
 					t.Errorf("unexpected metric: %s", s.Metric.Name)
 				}
@@ -182,6 +195,34 @@ func TestTracerNegativeHttpSendingValues(t *testing.T) {
 	}
 }
 
+func TestTracerContinue(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body makes the server send the 100 Continue
+		_, _ = io.Copy(ioutil.Discard, r.Body)
+	}))
+	defer srv.Close()
+	transport := &http.Transport{ExpectContinueTimeout: time.Second}
+
+	tracer := &Tracer{}
+	req, err := http.NewRequest("POST", srv.URL, strings.NewReader("body"))
+	require.NoError(t, err)
+	req.Header.Set("Expect", "100-continue")
+	res, err := transport.RoundTrip(req.WithContext(WithTracer(context.Background(), tracer)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NoError(t, res.Body.Close())
+	trail := tracer.Done()
+	assert.True(t, trail.GotContinue)
+	assert.False(t, trail.GotEarlyHints)
+	assert.True(t, trail.Continue > 0)
+
+	trail.SaveSamples(nil)
+	for _, s := range trail.GetSamples() {
+		assert.NotEqual(t, metrics.HTTPReqEarlyHints, s.Metric)
+	}
+}
+
 func TestTracerError(t *testing.T) {
 	t.Parallel()
 	srv := httptest.NewTLSServer(httpbin.NewHTTPBin().Handler())
@@ -25,6 +25,9 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"net"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
@@ -33,9 +36,16 @@ import (
 )
 
 // DefaultSystemTagList includes all of the system tags emitted with metrics by default.
-// Other tags that are not enabled by default include: iter, vu, ocsp_status, ip
+// Other tags that are not enabled by default include: iter, vu, ocsp_status, ip, port
 var DefaultSystemTagList = []string{
-	"proto", "subproto", "status", "method", "url", "name", "group", "check", "error", "tls_version",
+	"proto", "subproto", "status", "method", "url", "name", "group", "check", "error", "error_code",
+	"tls_version", "scenario",
+}
+
+// SystemTagList includes all of the system tags that k6 can emit with metrics.
+var SystemTagList = []string{
+	"proto", "subproto", "status", "method", "url", "name", "group", "check", "error", "error_code",
+	"tls_version", "scenario", "ocsp_status", "iter", "vu", "ip", "port",
 }
 
 // TagSet is a string to bool map (for lookup efficiency) that is used to keep track
@@ -51,6 +61,49 @@ func GetTagSet(tags ...string) TagSet {
 	return result
 }
 
+// ParseSystemTags is like GetTagSet, but it returns an error for names that aren't system tags,
+// so that typos don't silently drop tags from the metrics.
+func ParseSystemTags(tags ...string) (TagSet, error) {
+	result := TagSet{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		known := false
+		for _, t := range SystemTagList {
+			if t == tag {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, errors.Errorf("unknown system tag '%s', it has to be one of %s",
+				tag, strings.Join(SystemTagList, ", "))
+		}
+		result[tag] = true
+	}
+	return result, nil
+}
+
+// SetRemoteAddrTags sets the ip and port tags to those of the remote address of a connection, if
+// they're enabled, e.g. to see how evenly a load balancer spreads the load across its backends.
+func (t TagSet) SetRemoteAddrTags(tags map[string]string, addr net.Addr) {
+	if addr == nil || (!t["ip"] && !t["port"]) {
+		return
+	}
+	ip, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return
+	}
+	if t["ip"] {
+		tags["ip"] = ip
+	}
+	if t["port"] {
+		tags["port"] = port
+	}
+}
+
 // MarshalJSON converts the tags map to a list (JS array).
 func (t TagSet) MarshalJSON() ([]byte, error) {
 	var tags []string
@@ -66,9 +119,24 @@ func (t *TagSet) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &tags); err != nil {
 		return err
 	}
-	if len(tags) != 0 {
-		*t = GetTagSet(tags...)
+	if len(tags) == 0 {
+		return nil
+	}
+	set, err := ParseSystemTags(tags...)
+	if err != nil {
+		return err
+	}
+	*t = set
+	return nil
+}
+
+// Decode parses a comma-separated list of tags, e.g. from the K6_SYSTEM_TAGS environment variable.
+func (t *TagSet) Decode(value string) error {
+	set, err := ParseSystemTags(strings.Split(value, ",")...)
+	if err != nil || len(set) == 0 {
+		return err
 	}
+	*t = set
 	return nil
 }
 
@@ -121,9 +189,20 @@ func (v *TLSVersions) UnmarshalJSON(data []byte) error {
 
 // A list of TLS cipher suites.
 // Marshals and unmarshals from a list of names, eg. "TLS_ECDHE_RSA_WITH_RC4_128_SHA".
-// BUG: This currently doesn't marshal back to JSON properly!!
 type TLSCipherSuites []uint16
 
+func (s TLSCipherSuites) MarshalJSON() ([]byte, error) {
+	suiteNames := make([]string, 0, len(s))
+	for _, id := range s {
+		if suiteName, ok := SupportedTLSCipherSuitesToString[id]; ok {
+			suiteNames = append(suiteNames, suiteName)
+		} else {
+			return nil, errors.Errorf("Unknown cipher suite: 0x%04x", id)
+		}
+	}
+	return json.Marshal(suiteNames)
+}
+
 func (s *TLSCipherSuites) UnmarshalJSON(data []byte) error {
 	var suiteNames []string
 	if err := json.Unmarshal(data, &suiteNames); err != nil {
@@ -181,6 +260,126 @@ func (c *TLSAuth) Certificate() (*tls.Certificate, error) {
 	return c.certificate, nil
 }
 
+// MatchesHost returns whether the certificate should be presented to the given host. A domain
+// with a leading wildcard label, eg. "*.example.com", matches the host "api.example.com", but
+// neither "example.com" nor "v1.api.example.com".
+func (c *TLSAuth) MatchesHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range c.Domains {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if domain == host {
+			return true
+		}
+		if strings.HasPrefix(domain, "*.") {
+			if idx := strings.IndexRune(host, '.'); idx > 0 && host[idx:] == domain[1:] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The most addresses that a CIDR range of local IPs can have.
+const maxLocalIPRange = 1 << 16
+
+// LocalIPs are the local addresses that outgoing connections are made from, in turns. They're
+// given as IPs, CIDR ranges, eg. "10.0.0.0/28", or the names of network interfaces, eg. "eth0",
+// whose addresses are looked up when they're parsed.
+type LocalIPs []net.IP
+
+// ParseLocalIPs parses a comma-separated list of local IPs, CIDR ranges and interface names.
+func ParseLocalIPs(s string) (LocalIPs, error) {
+	var ips LocalIPs
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		entryIPs, err := parseLocalIPEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, entryIPs...)
+	}
+	return ips, nil
+}
+
+func parseLocalIPEntry(entry string) ([]net.IP, error) {
+	if ip := net.ParseIP(entry); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	if ip, ipNet, err := net.ParseCIDR(entry); err == nil {
+		ones, bits := ipNet.Mask.Size()
+		if bits-ones > 16 {
+			return nil, errors.Errorf("the local IP range '%s' is too large, it can have at most %d addresses", entry, maxLocalIPRange)
+		}
+		var ips []net.IP
+		for ip := ip.Mask(ipNet.Mask); ipNet.Contains(ip); ip = nextIP(ip) {
+			ips = append(ips, ip.To16())
+		}
+		// The network and broadcast addresses of IPv4 ranges can't be used
+		if ip.To4() != nil && len(ips) > 2 {
+			ips = ips[1 : len(ips)-1]
+		}
+		return ips, nil
+	}
+
+	iface, err := net.InterfaceByName(entry)
+	if err != nil {
+		return nil, errors.Errorf("invalid local IP '%s', it's not an IP, a CIDR range or a network interface", entry)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get the addresses of the network interface '%s'", entry)
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipNet.IP.To16())
+		}
+	}
+	if len(ips) == 0 {
+		return nil, errors.Errorf("the network interface '%s' has no usable addresses", entry)
+	}
+	return ips, nil
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+func (l *LocalIPs) UnmarshalText(text []byte) error {
+	ips, err := ParseLocalIPs(string(text))
+	if err != nil {
+		return err
+	}
+	*l = ips
+	return nil
+}
+
+// UnmarshalJSON accepts a comma-separated string, or an array of IPs, CIDR ranges and
+// interface names.
+func (l *LocalIPs) UnmarshalJSON(data []byte) error {
+	var entries []string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return errors.New("localIPs has to be a string or an array of strings")
+		}
+		entries = []string{s}
+	}
+	return l.UnmarshalText([]byte(strings.Join(entries, ",")))
+}
+
 type Options struct {
 	// Should the test start in a paused state?
 	Paused null.Bool `json:"paused" envconfig:"paused"`
@@ -193,16 +392,52 @@ type Options struct {
 	Iterations null.Int           `json:"iterations" envconfig:"iterations"`
 	Stages     []Stage            `json:"stages" envconfig:"stages"`
 
+	// Only this many of the VUsMax are initialized before the test starts, the others when it
+	// first needs them, e.g. when a stage ramps up to them.
+	PreAllocatedVUs null.Int `json:"preAllocatedVUs" envconfig:"pre_allocated_vus"`
+
+	// Each VU runs this many iterations, instead of the VUs sharing the Iterations between them.
+	IterationsPerVU null.Int `json:"iterationsPerVU" envconfig:"iterations_per_vu"`
+
+	// The mix of things that the VUs do, see Scenario. The VUs all run the default function if
+	// there are none. Can't be set through env vars.
+	Scenarios map[string]Scenario `json:"scenarios" ignored:"true"`
+
 	// Timeouts for the setup() and teardown() functions
 	SetupTimeout    types.NullDuration `json:"setupTimeout" envconfig:"setup_timeout"`
 	TeardownTimeout types.NullDuration `json:"teardownTimeout" envconfig:"teardown_timeout"`
 
+	// Run until stopped, with the VUs only scaled through the REST API, e.g. with `k6 scale`.
+	ExternallyControlled null.Bool `json:"externallyControlled" envconfig:"externally_controlled"`
+
+	// Seeds Math.random, and with it everything random in scripts, e.g. randomSleep(); each VU
+	// gets the seed plus its ID, so that two runs with the same seed make the same choices.
+	Seed null.Int `json:"seed" envconfig:"seed"`
+
+	// Limits on the load generator's own usage of CPU (in % of all CPUs), memory (in MB) and file
+	// descriptors (in % of the process's limit); past them, its latency numbers can't be trusted.
+	// The action is "warn" (the default), "cap", to stop adding VUs, or "abort".
+	GuardrailCPU             null.Float  `json:"guardrailCPU" envconfig:"guardrail_cpu"`
+	GuardrailMemory          null.Int    `json:"guardrailMemory" envconfig:"guardrail_memory"`
+	GuardrailFileDescriptors null.Float  `json:"guardrailFileDescriptors" envconfig:"guardrail_file_descriptors"`
+	GuardrailAction          null.String `json:"guardrailAction" envconfig:"guardrail_action"`
+
+	// Iterations that are faster than this are followed by a sleep for the rest of it, to pace them.
+	MinIterationDuration types.NullDuration `json:"minIterationDuration" envconfig:"min_iteration_duration"`
+
+	// The MB of JS values that a VU can keep between iterations; a VU that keeps more fails the
+	// iteration, and starts over with a fresh runtime.
+	VUMemoryLimit null.Int `json:"vuMemoryLimit" envconfig:"vu_memory_limit"`
+
 	// Limit HTTP requests per second.
 	RPS null.Int `json:"rps" envconfig:"rps"`
 
 	// How many HTTP redirects do we follow?
 	MaxRedirects null.Int `json:"maxRedirects" envconfig:"max_redirects"`
 
+	// Default timeout for HTTP requests, unless they have a timeout param.
+	HTTPTimeout types.NullDuration `json:"httpTimeout" envconfig:"http_timeout"`
+
 	// Default User Agent string for HTTP requests.
 	UserAgent null.String `json:"userAgent" envconfig:"user_agent"`
 
@@ -210,9 +445,15 @@ type Options struct {
 	Batch        null.Int `json:"batch" envconfig:"batch"`
 	BatchPerHost null.Int `json:"batchPerHost" envconfig:"batch_per_host"`
 
-	// Should all HTTP requests and responses be logged (excluding body)?
+	// Should all HTTP requests and responses be logged? "headers" excludes the bodies, "full"
+	// includes them, and "pretty" also indents the JSON ones.
 	HttpDebug null.String `json:"httpDebug" envconfig:"http_debug"`
 
+	// Only log the requests whose URLs match one of these patterns, where * matches anything,
+	// e.g. "https://api.example.com/*"; and cut the logged bodies to this many bytes.
+	HttpDebugURLs      []string `json:"httpDebugURLs" envconfig:"http_debug_urls"`
+	HttpDebugBodyLimit null.Int `json:"httpDebugBodyLimit" envconfig:"http_debug_body_limit"`
+
 	// Accept invalid or untrusted TLS certificates.
 	InsecureSkipTLSVerify null.Bool `json:"insecureSkipTLSVerify" envconfig:"insecure_skip_tls_verify"`
 
@@ -232,9 +473,17 @@ type Options struct {
 	// Blacklist IP ranges that tests may not contact. Mainly useful in hosted setups.
 	BlacklistIPs []*net.IPNet `json:"blacklistIPs" envconfig:"blacklist_ips"`
 
+	// Local addresses that outgoing connections are made from, in turns, instead of the default.
+	LocalIPs LocalIPs `json:"localIPs" envconfig:"local_ips"`
+
 	// Hosts overrides dns entries for given hosts
 	Hosts map[string]net.IP `json:"hosts" envconfig:"hosts"`
 
+	// Send requests through a proxy (http://, https:// or socks5://), except for the hosts in
+	// NoProxy. If not set, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used instead.
+	Proxy   null.String `json:"proxy" envconfig:"proxy"`
+	NoProxy null.String `json:"noProxy" envconfig:"no_proxy"`
+
 	// Disable keep-alive connections
 	NoConnectionReuse null.Bool `json:"noConnectionReuse" envconfig:"no_connection_reuse"`
 
@@ -243,6 +492,22 @@ type Options struct {
 	// errors about running out of file handles or sockets, or being unable to bind addresses.
 	NoVUConnectionReuse null.Bool `json:"noVUConnectionReuse" envconfig:"no_vu_connection_reuse"`
 
+	// How many idle keep-alive connections each VU keeps, in total (0 is no limit, the default)
+	// and per host (2 by default); connections past these are closed after their request.
+	MaxIdleConns        null.Int `json:"maxIdleConns" envconfig:"max_idle_conns"`
+	MaxIdleConnsPerHost null.Int `json:"maxIdleConnsPerHost" envconfig:"max_idle_conns_per_host"`
+
+	// How many connections to each host each VU can use at once; requests past that wait for a
+	// free one, for as long as the http_req_queued metric says.
+	MaxConnsPerHost null.Int `json:"maxConnsPerHost" envconfig:"max_conns_per_host"`
+
+	// Inject trace context headers ("w3c" for traceparent, "b3" for b3) into HTTP requests, for
+	// the given fraction of them to be sampled (1 by default), and export the sampled requests as
+	// spans to an OTLP/HTTP traces endpoint, if one is set.
+	TracingPropagator null.String `json:"tracingPropagator" envconfig:"tracing_propagator"`
+	TracingSampling   null.Float  `json:"tracingSampling" envconfig:"tracing_sampling"`
+	TracingEndpoint   null.String `json:"tracingEndpoint" envconfig:"tracing_endpoint"`
+
 	// These values are for third party collectors' benefit.
 	// Can't be set through env vars.
 	External map[string]json.RawMessage `json:"ext" ignored:"true"`
@@ -253,6 +518,10 @@ type Options struct {
 	// Summary time unit for summary metrics (response times) in CLI output
 	SummaryTimeUnit null.String `json:"summaryTimeUnit" envconfig:"summary_time_unit"`
 
+	// Which tags to break the summary's metrics down by as well, "scenario" and/or "group", to
+	// show e.g. the latency of a checkout scenario separately from that of a browsing one
+	SummaryBreakdown []string `json:"summaryBreakdown" envconfig:"summary_breakdown"`
+
 	// Which system tags to include with metrics ("method", "vu" etc.)
 	SystemTags TagSet `json:"systemTags" envconfig:"system_tags"`
 
@@ -261,6 +530,11 @@ type Options struct {
 
 	// Buffer size of the channel for metric samples; 0 means unbuffered
 	MetricSamplesBufferSize null.Int `json:"metricSamplesBufferSize" envconfig:"metric_samples_buffer_size"`
+
+	// How often outputs are handed the samples that were buffered for them, and how many samples
+	// can be buffered for each output before new ones are dropped; 0 means no limit
+	OutputFlushInterval types.NullDuration `json:"outputFlushInterval" envconfig:"output_flush_interval"`
+	OutputBufferSize    null.Int           `json:"outputBufferSize" envconfig:"output_buffer_size"`
 }
 
 // Returns the result of overwriting any fields with any that are set on the argument.
@@ -279,12 +553,18 @@ func (o Options) Apply(opts Options) Options {
 	if opts.VUsMax.Valid {
 		o.VUsMax = opts.VUsMax
 	}
+	if opts.PreAllocatedVUs.Valid {
+		o.PreAllocatedVUs = opts.PreAllocatedVUs
+	}
 	if opts.Duration.Valid {
 		o.Duration = opts.Duration
 	}
 	if opts.Iterations.Valid {
 		o.Iterations = opts.Iterations
 	}
+	if opts.IterationsPerVU.Valid {
+		o.IterationsPerVU = opts.IterationsPerVU
+	}
 	if len(opts.Stages) > 0 {
 		for _, s := range opts.Stages {
 			if s.Duration.Valid {
@@ -292,18 +572,48 @@ func (o Options) Apply(opts Options) Options {
 			}
 		}
 	}
+	if opts.Scenarios != nil {
+		o.Scenarios = opts.Scenarios
+	}
 	if opts.SetupTimeout.Valid {
 		o.SetupTimeout = opts.SetupTimeout
 	}
 	if opts.TeardownTimeout.Valid {
 		o.TeardownTimeout = opts.TeardownTimeout
 	}
+	if opts.ExternallyControlled.Valid {
+		o.ExternallyControlled = opts.ExternallyControlled
+	}
+	if opts.Seed.Valid {
+		o.Seed = opts.Seed
+	}
+	if opts.GuardrailCPU.Valid {
+		o.GuardrailCPU = opts.GuardrailCPU
+	}
+	if opts.GuardrailMemory.Valid {
+		o.GuardrailMemory = opts.GuardrailMemory
+	}
+	if opts.GuardrailFileDescriptors.Valid {
+		o.GuardrailFileDescriptors = opts.GuardrailFileDescriptors
+	}
+	if opts.GuardrailAction.Valid {
+		o.GuardrailAction = opts.GuardrailAction
+	}
+	if opts.MinIterationDuration.Valid {
+		o.MinIterationDuration = opts.MinIterationDuration
+	}
+	if opts.VUMemoryLimit.Valid {
+		o.VUMemoryLimit = opts.VUMemoryLimit
+	}
 	if opts.RPS.Valid {
 		o.RPS = opts.RPS
 	}
 	if opts.MaxRedirects.Valid {
 		o.MaxRedirects = opts.MaxRedirects
 	}
+	if opts.HTTPTimeout.Valid {
+		o.HTTPTimeout = opts.HTTPTimeout
+	}
 	if opts.UserAgent.Valid {
 		o.UserAgent = opts.UserAgent
 	}
@@ -316,6 +626,12 @@ func (o Options) Apply(opts Options) Options {
 	if opts.HttpDebug.Valid {
 		o.HttpDebug = opts.HttpDebug
 	}
+	if opts.HttpDebugURLs != nil {
+		o.HttpDebugURLs = opts.HttpDebugURLs
+	}
+	if opts.HttpDebugBodyLimit.Valid {
+		o.HttpDebugBodyLimit = opts.HttpDebugBodyLimit
+	}
 	if opts.InsecureSkipTLSVerify.Valid {
 		o.InsecureSkipTLSVerify = opts.InsecureSkipTLSVerify
 	}
@@ -337,15 +653,42 @@ func (o Options) Apply(opts Options) Options {
 	if opts.BlacklistIPs != nil {
 		o.BlacklistIPs = opts.BlacklistIPs
 	}
+	if opts.LocalIPs != nil {
+		o.LocalIPs = opts.LocalIPs
+	}
 	if opts.Hosts != nil {
 		o.Hosts = opts.Hosts
 	}
+	if opts.Proxy.Valid {
+		o.Proxy = opts.Proxy
+	}
+	if opts.NoProxy.Valid {
+		o.NoProxy = opts.NoProxy
+	}
 	if opts.NoConnectionReuse.Valid {
 		o.NoConnectionReuse = opts.NoConnectionReuse
 	}
 	if opts.NoVUConnectionReuse.Valid {
 		o.NoVUConnectionReuse = opts.NoVUConnectionReuse
 	}
+	if opts.MaxIdleConns.Valid {
+		o.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost.Valid {
+		o.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxConnsPerHost.Valid {
+		o.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.TracingPropagator.Valid {
+		o.TracingPropagator = opts.TracingPropagator
+	}
+	if opts.TracingSampling.Valid {
+		o.TracingSampling = opts.TracingSampling
+	}
+	if opts.TracingEndpoint.Valid {
+		o.TracingEndpoint = opts.TracingEndpoint
+	}
 	if opts.External != nil {
 		o.External = opts.External
 	}
@@ -355,15 +698,34 @@ func (o Options) Apply(opts Options) Options {
 	if opts.SummaryTimeUnit.Valid {
 		o.SummaryTimeUnit = opts.SummaryTimeUnit
 	}
+	if opts.SummaryBreakdown != nil {
+		o.SummaryBreakdown = opts.SummaryBreakdown
+	}
 	if opts.SystemTags != nil {
 		o.SystemTags = opts.SystemTags
 	}
+	// Tags are merged, rather than replaced, so that e.g. a --tag flag doesn't drop the tags in the
+	// script options; tags with the same name are overridden.
 	if !opts.RunTags.IsEmpty() {
-		o.RunTags = opts.RunTags
+		if o.RunTags.IsEmpty() {
+			o.RunTags = opts.RunTags
+		} else {
+			tags := o.RunTags.CloneTags()
+			for k, v := range opts.RunTags.CloneTags() {
+				tags[k] = v
+			}
+			o.RunTags = stats.IntoSampleTags(&tags)
+		}
 	}
 	if opts.MetricSamplesBufferSize.Valid {
 		o.MetricSamplesBufferSize = opts.MetricSamplesBufferSize
 	}
+	if opts.OutputFlushInterval.Valid {
+		o.OutputFlushInterval = opts.OutputFlushInterval
+	}
+	if opts.OutputBufferSize.Valid {
+		o.OutputBufferSize = opts.OutputBufferSize
+	}
 	return o
 }
 
@@ -393,3 +755,76 @@ func (o Options) GetPrettyJSON(prefix, indent string) ([]byte, error) {
 	}
 	return json.MarshalIndent(tmpMap, prefix, indent)
 }
+
+// UnknownOptions returns the keys of a JSON object of options that aren't options, with a
+// suggestion for the ones that look like misspelled options, e.g. `thresold (did you mean
+// thresholds?)`. They would be ignored silently otherwise.
+func UnknownOptions(data []byte) ([]string, error) {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+
+	var known []string
+	typ := reflect.TypeOf(Options{})
+	for i := 0; i < typ.NumField(); i++ {
+		if name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			known = append(known, name)
+		}
+	}
+
+	var unknown []string
+	for key := range keys {
+		suggestion, distance := "", len(key)/3+1
+		found := false
+		for _, name := range known {
+			if name == key {
+				found = true
+				break
+			}
+			if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < distance {
+				suggestion, distance = name, d
+			}
+		}
+		switch {
+		case found:
+		case suggestion != "":
+			unknown = append(unknown, key+" (did you mean "+suggestion+"?)")
+		default:
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
 )
 
@@ -52,6 +53,11 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.VUsMax.Valid)
 		assert.Equal(t, int64(12345), opts.VUsMax.Int64)
 	})
+	t.Run("PreAllocatedVUs", func(t *testing.T) {
+		opts := Options{}.Apply(Options{PreAllocatedVUs: null.IntFrom(5)})
+		assert.True(t, opts.PreAllocatedVUs.Valid)
+		assert.Equal(t, int64(5), opts.PreAllocatedVUs.Int64)
+	})
 	t.Run("Duration", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Duration: types.NullDurationFrom(2 * time.Minute)})
 		assert.True(t, opts.Duration.Valid)
@@ -62,6 +68,11 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.Iterations.Valid)
 		assert.Equal(t, int64(1234), opts.Iterations.Int64)
 	})
+	t.Run("IterationsPerVU", func(t *testing.T) {
+		opts := Options{}.Apply(Options{IterationsPerVU: null.IntFrom(5)})
+		assert.True(t, opts.IterationsPerVU.Valid)
+		assert.Equal(t, int64(5), opts.IterationsPerVU.Int64)
+	})
 	t.Run("Stages", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Stages: []Stage{{Duration: types.NullDurationFrom(1 * time.Second)}}})
 		assert.NotNil(t, opts.Stages)
@@ -70,6 +81,12 @@ func TestOptions(t *testing.T) {
 
 		assert.Nil(t, Options{}.Apply(Options{Stages: []Stage{{}}}).Stages)
 	})
+	t.Run("Scenarios", func(t *testing.T) {
+		scenarios := map[string]Scenario{"browse": {Exec: null.StringFrom("browse")}}
+		opts := Options{}.Apply(Options{Scenarios: scenarios})
+		assert.Equal(t, scenarios, opts.Scenarios)
+		assert.Equal(t, scenarios, opts.Apply(Options{}).Scenarios)
+	})
 	t.Run("RPS", func(t *testing.T) {
 		opts := Options{}.Apply(Options{RPS: null.IntFrom(12345)})
 		assert.True(t, opts.RPS.Valid)
@@ -80,6 +97,38 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.MaxRedirects.Valid)
 		assert.Equal(t, int64(12345), opts.MaxRedirects.Int64)
 	})
+	t.Run("Seed", func(t *testing.T) {
+		opts := Options{}.Apply(Options{Seed: null.IntFrom(42)})
+		assert.True(t, opts.Seed.Valid)
+		assert.Equal(t, int64(42), opts.Seed.Int64)
+	})
+	t.Run("Guardrails", func(t *testing.T) {
+		opts := Options{}.Apply(Options{
+			GuardrailCPU:             null.FloatFrom(90),
+			GuardrailMemory:          null.IntFrom(2048),
+			GuardrailFileDescriptors: null.FloatFrom(80),
+			GuardrailAction:          null.StringFrom("cap"),
+		})
+		assert.Equal(t, null.FloatFrom(90), opts.GuardrailCPU)
+		assert.Equal(t, null.IntFrom(2048), opts.GuardrailMemory)
+		assert.Equal(t, null.FloatFrom(80), opts.GuardrailFileDescriptors)
+		assert.Equal(t, null.StringFrom("cap"), opts.GuardrailAction)
+	})
+	t.Run("MinIterationDuration", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MinIterationDuration: types.NullDurationFrom(5 * time.Second)})
+		assert.True(t, opts.MinIterationDuration.Valid)
+		assert.Equal(t, types.Duration(5*time.Second), opts.MinIterationDuration.Duration)
+	})
+	t.Run("VUMemoryLimit", func(t *testing.T) {
+		opts := Options{}.Apply(Options{VUMemoryLimit: null.IntFrom(100)})
+		assert.True(t, opts.VUMemoryLimit.Valid)
+		assert.Equal(t, int64(100), opts.VUMemoryLimit.Int64)
+	})
+	t.Run("HTTPTimeout", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HTTPTimeout: types.NullDurationFrom(30 * time.Second)})
+		assert.True(t, opts.HTTPTimeout.Valid)
+		assert.Equal(t, types.Duration(30*time.Second), opts.HTTPTimeout.Duration)
+	})
 	t.Run("UserAgent", func(t *testing.T) {
 		opts := Options{}.Apply(Options{UserAgent: null.StringFrom("foo")})
 		assert.True(t, opts.UserAgent.Valid)
@@ -100,6 +149,14 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.HttpDebug.Valid)
 		assert.Equal(t, "foo", opts.HttpDebug.String)
 	})
+	t.Run("HttpDebugURLs", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HttpDebugURLs: []string{"*/api/*"}})
+		assert.Equal(t, []string{"*/api/*"}, opts.HttpDebugURLs)
+	})
+	t.Run("HttpDebugBodyLimit", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HttpDebugBodyLimit: null.IntFrom(100)})
+		assert.Equal(t, null.IntFrom(100), opts.HttpDebugBodyLimit)
+	})
 	t.Run("InsecureSkipTLSVerify", func(t *testing.T) {
 		opts := Options{}.Apply(Options{InsecureSkipTLSVerify: null.BoolFrom(true)})
 		assert.True(t, opts.InsecureSkipTLSVerify.Valid)
@@ -124,6 +181,15 @@ func TestOptions(t *testing.T) {
 				assert.NoError(t, json.Unmarshal([]byte(jsonStr), &opts))
 				assert.Equal(t, &TLSCipherSuites{tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA}, opts.TLSCipherSuites)
 			})
+			t.Run("Roundtrip", func(t *testing.T) {
+				suites := TLSCipherSuites{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_AES_256_CBC_SHA}
+				data, err := json.Marshal(suites)
+				assert.NoError(t, err)
+				assert.Equal(t, `["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256","TLS_RSA_WITH_AES_256_CBC_SHA"]`, string(data))
+				var suites2 TLSCipherSuites
+				assert.NoError(t, json.Unmarshal(data, &suites2))
+				assert.Equal(t, suites, suites2)
+			})
 			t.Run("Not a string", func(t *testing.T) {
 				var opts Options
 				jsonStr := `{"tlsCipherSuites":[1.2]}`
@@ -250,6 +316,16 @@ func TestOptions(t *testing.T) {
 			}
 		})
 
+		t.Run("MatchesHost", func(t *testing.T) {
+			assert.True(t, tlsAuth[0].MatchesHost("example.com"))
+			assert.True(t, tlsAuth[0].MatchesHost("EXAMPLE.com."))
+			assert.True(t, tlsAuth[0].MatchesHost("sub.example.com"))
+			assert.False(t, tlsAuth[0].MatchesHost("v1.sub.example.com"))
+			assert.False(t, tlsAuth[0].MatchesHost("example.org"))
+			assert.True(t, tlsAuth[1].MatchesHost("sub.example.com"))
+			assert.False(t, tlsAuth[1].MatchesHost("example.com"))
+		})
+
 		t.Run("Invalid JSON", func(t *testing.T) {
 			var opts Options
 			jsonStr := `{"tlsAuth":["invalid"]}`
@@ -272,6 +348,15 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.NoVUConnectionReuse.Valid)
 		assert.True(t, opts.NoVUConnectionReuse.Bool)
 	})
+	t.Run("MaxIdleConns", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxIdleConns: null.IntFrom(100), MaxIdleConnsPerHost: null.IntFrom(10)})
+		assert.Equal(t, null.IntFrom(100), opts.MaxIdleConns)
+		assert.Equal(t, null.IntFrom(10), opts.MaxIdleConnsPerHost)
+	})
+	t.Run("MaxConnsPerHost", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxConnsPerHost: null.IntFrom(6)})
+		assert.Equal(t, null.IntFrom(6), opts.MaxConnsPerHost)
+	})
 	t.Run("BlacklistIPs", func(t *testing.T) {
 		opts := Options{}.Apply(Options{
 			BlacklistIPs: []*net.IPNet{{
@@ -294,6 +379,21 @@ func TestOptions(t *testing.T) {
 		assert.Equal(t, "192.0.2.1", opts.Hosts["test.loadimpact.com"].String())
 	})
 
+	t.Run("LocalIPs", func(t *testing.T) {
+		opts := Options{}.Apply(Options{LocalIPs: LocalIPs{net.ParseIP("192.0.2.1")}})
+		assert.Equal(t, LocalIPs{net.ParseIP("192.0.2.1")}, opts.LocalIPs)
+	})
+
+	t.Run("Proxy", func(t *testing.T) {
+		opts := Options{}.Apply(Options{Proxy: null.StringFrom("socks5://proxy.example.com:1080")})
+		assert.True(t, opts.Proxy.Valid)
+		assert.Equal(t, "socks5://proxy.example.com:1080", opts.Proxy.String)
+	})
+	t.Run("NoProxy", func(t *testing.T) {
+		opts := Options{}.Apply(Options{NoProxy: null.StringFrom("localhost,.example.com")})
+		assert.True(t, opts.NoProxy.Valid)
+		assert.Equal(t, "localhost,.example.com", opts.NoProxy.String)
+	})
 	t.Run("Throws", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Throw: null.BoolFrom(true)})
 		assert.True(t, opts.Throw.Valid)
@@ -350,6 +450,14 @@ func TestOptions(t *testing.T) {
 				assert.NoError(t, json.Unmarshal([]byte(jsonStr), &opts))
 				assert.Nil(t, opts.SystemTags)
 			})
+			t.Run("Unknown", func(t *testing.T) {
+				var opts Options
+				jsonStr := `{"systemTags":["url","urll"]}`
+				err := json.Unmarshal([]byte(jsonStr), &opts)
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), "unknown system tag 'urll'")
+				}
+			})
 		})
 	})
 	t.Run("SummaryTrendStats", func(t *testing.T) {
@@ -361,6 +469,23 @@ func TestOptions(t *testing.T) {
 		tags := stats.IntoSampleTags(&map[string]string{"myTag": "hello"})
 		opts := Options{}.Apply(Options{RunTags: tags})
 		assert.Equal(t, tags, opts.RunTags)
+
+		t.Run("Merged", func(t *testing.T) {
+			opts := opts.Apply(Options{RunTags: stats.IntoSampleTags(&map[string]string{"myTag": "hi", "env": "staging"})})
+			assert.Equal(t, map[string]string{"myTag": "hi", "env": "staging"}, opts.RunTags.CloneTags())
+			assert.Equal(t, map[string]string{"myTag": "hello"}, tags.CloneTags())
+		})
+		t.Run("Env", func(t *testing.T) {
+			defer os.Clearenv()
+			os.Clearenv()
+			assert.NoError(t, os.Setenv("K6_TAGS", "env=staging, build=42"))
+			var opts Options
+			assert.NoError(t, envconfig.Process("k6", &opts))
+			assert.Equal(t, map[string]string{"env": "staging", "build": "42"}, opts.RunTags.CloneTags())
+
+			assert.NoError(t, os.Setenv("K6_TAGS", "staging"))
+			assert.Error(t, envconfig.Process("k6", &opts))
+		})
 	})
 }
 
@@ -404,6 +529,10 @@ func TestOptionsEnv(t *testing.T) {
 			"":    null.Int{},
 			"123": null.IntFrom(123),
 		},
+		{"HTTPTimeout", "K6_HTTP_TIMEOUT"}: {
+			"":    types.NullDuration{},
+			"30s": types.NullDurationFrom(30 * time.Second),
+		},
 		{"InsecureSkipTLSVerify", "K6_INSECURE_SKIP_TLS_VERIFY"}: {
 			"":      null.Bool{},
 			"true":  null.BoolFrom(true),
@@ -422,10 +551,50 @@ func TestOptionsEnv(t *testing.T) {
 			"true":  null.BoolFrom(true),
 			"false": null.BoolFrom(false),
 		},
+		{"MaxIdleConnsPerHost", "K6_MAX_IDLE_CONNS_PER_HOST"}: {
+			"":   null.Int{},
+			"10": null.IntFrom(10),
+		},
+		{"Proxy", "K6_PROXY"}: {
+			"":                      null.String{},
+			"http://localhost:3128": null.StringFrom("http://localhost:3128"),
+		},
+		{"NoProxy", "K6_NO_PROXY"}: {
+			"":                     null.String{},
+			"localhost,10.0.0.0/8": null.StringFrom("localhost,10.0.0.0/8"),
+		},
 		{"UserAgent", "K6_USER_AGENT"}: {
 			"":    null.String{},
 			"Hi!": null.StringFrom("Hi!"),
 		},
+		{"TracingPropagator", "K6_TRACING_PROPAGATOR"}: {
+			"":    null.String{},
+			"w3c": null.StringFrom("w3c"),
+		},
+		{"GuardrailCPU", "K6_GUARDRAIL_CPU"}: {
+			"":   null.Float{},
+			"90": null.FloatFrom(90),
+		},
+		{"GuardrailAction", "K6_GUARDRAIL_ACTION"}: {
+			"":      null.String{},
+			"abort": null.StringFrom("abort"),
+		},
+		{"TracingSampling", "K6_TRACING_SAMPLING"}: {
+			"":    null.Float{},
+			"0.1": null.FloatFrom(0.1),
+		},
+		{"TracingEndpoint", "K6_TRACING_ENDPOINT"}: {
+			"":                                null.String{},
+			"http://localhost:4318/v1/traces": null.StringFrom("http://localhost:4318/v1/traces"),
+		},
+		{"SystemTags", "K6_SYSTEM_TAGS"}: {
+			"":            TagSet(nil),
+			"url, status": GetTagSet("url", "status"),
+		},
+		{"LocalIPs", "K6_LOCAL_IPS"}: {
+			"":                      LocalIPs(nil),
+			"192.0.2.1, 192.0.2.10": LocalIPs{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.10")},
+		},
 		{"Throw", "K6_THROW"}: {
 			"":      null.Bool{},
 			"true":  null.BoolFrom(true),
@@ -448,3 +617,78 @@ func TestOptionsEnv(t *testing.T) {
 		})
 	}
 }
+
+func TestUnknownOptions(t *testing.T) {
+	unknown, err := UnknownOptions([]byte(`{
+		"vus": 10, "thresold": {}, "Duration": "1m", "stage": [], "insecureSkipTlsVerify": true,
+		"ext": {}, "foo": 1
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Duration (did you mean duration?)",
+		"foo",
+		"insecureSkipTlsVerify (did you mean insecureSkipTLSVerify?)",
+		"stage (did you mean stages?)",
+		"thresold (did you mean thresholds?)",
+	}, unknown)
+
+	_, err = UnknownOptions([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestParseLocalIPs(t *testing.T) {
+	ips, err := ParseLocalIPs("192.0.2.1, 10.0.0.0/30,2001:db8::1")
+	require.NoError(t, err)
+	assert.Equal(t, LocalIPs{
+		net.ParseIP("192.0.2.1"), net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("2001:db8::1"),
+	}, ips)
+
+	ips, err = ParseLocalIPs("lo")
+	require.NoError(t, err)
+	assert.Contains(t, ips, net.ParseIP("127.0.0.1"))
+
+	_, err = ParseLocalIPs("10.0.0.0/8")
+	assert.EqualError(t, err, "the local IP range '10.0.0.0/8' is too large, it can have at most 65536 addresses")
+	_, err = ParseLocalIPs("192.0.2.1,nope0")
+	assert.EqualError(t, err, "invalid local IP 'nope0', it's not an IP, a CIDR range or a network interface")
+
+	t.Run("JSON", func(t *testing.T) {
+		var opts Options
+		require.NoError(t, json.Unmarshal([]byte(`{"localIPs": ["192.0.2.1", "192.0.2.8/31"]}`), &opts))
+		assert.Equal(t, LocalIPs{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.8"), net.ParseIP("192.0.2.9")}, opts.LocalIPs)
+
+		data, err := json.Marshal(opts.LocalIPs)
+		require.NoError(t, err)
+		assert.JSONEq(t, `["192.0.2.1", "192.0.2.8", "192.0.2.9"]`, string(data))
+
+		require.NoError(t, json.Unmarshal([]byte(`{"localIPs": "192.0.2.1,192.0.2.2"}`), &opts))
+		assert.Equal(t, LocalIPs{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, opts.LocalIPs)
+		assert.Error(t, json.Unmarshal([]byte(`{"localIPs": 1}`), &opts))
+	})
+}
+
+func TestTagSetSetRemoteAddrTags(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 8443}
+	testdata := map[string]struct {
+		systemTags TagSet
+		expected   map[string]string
+	}{
+		"both":    {GetTagSet("ip", "port"), map[string]string{"ip": "192.0.2.1", "port": "8443"}},
+		"ip":      {GetTagSet("ip", "url"), map[string]string{"ip": "192.0.2.1"}},
+		"port":    {GetTagSet("port"), map[string]string{"port": "8443"}},
+		"neither": {GetTagSet("url"), map[string]string{}},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			tags := map[string]string{}
+			data.systemTags.SetRemoteAddrTags(tags, addr)
+			assert.Equal(t, data.expected, tags)
+		})
+	}
+
+	tags := map[string]string{}
+	GetTagSet("ip", "port").SetRemoteAddrTags(tags, nil)
+	assert.Empty(t, tags)
+	GetTagSet("ip", "port").SetRemoteAddrTags(tags, &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 80})
+	assert.Equal(t, map[string]string{"ip": "2001:db8::1", "port": "80"}, tags)
+}
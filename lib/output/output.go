@@ -0,0 +1,98 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package output lets extensions add outputs, which scripts' metrics can be sent to with
+// `k6 run --out <name>`, the same way that they can be sent to the builtin ones.
+package output
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/loadimpact/k6/lib"
+)
+
+// Params is what an output is created from.
+type Params struct {
+	// Arg is whatever follows the output's name in `--out name=arg`, if anything.
+	Arg string
+
+	// Options are the test's consolidated options, and Src its main script.
+	Options lib.Options
+	Src     *lib.SourceData
+
+	// Version is the version of k6 that runs the test.
+	Version string
+}
+
+// A Constructor creates an output. Outputs can read their own configuration from the
+// environment, with K6_<NAME>_* variables by convention.
+type Constructor func(params Params) (lib.Collector, error)
+
+// The names of the builtin outputs, which extensions can't take.
+var builtins = map[string]bool{
+	"json": true, "influxdb": true, "kafka": true, "cloud": true, "otlp": true, "postgres": true,
+	"elasticsearch": true, "web-dashboard": true,
+}
+
+var (
+	registry      = make(map[string]Constructor)
+	registryMutex sync.RWMutex
+)
+
+// Register makes an output available by name. It's meant to be called from the init() of
+// extension packages, which are compiled into k6 with k6-build; registering a name twice, or the
+// name of a builtin output, panics.
+func Register(name string, c Constructor) {
+	if name == "" || builtins[name] {
+		panic(fmt.Sprintf("invalid output name %q", name))
+	}
+	if c == nil {
+		panic(fmt.Sprintf("output %s has no constructor", name))
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("output %s is already registered", name))
+	}
+	registry[name] = c
+}
+
+// Get returns the constructor of a registered output.
+func Get(name string) (Constructor, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	c, ok := registry[name]
+	return c, ok
+}
+
+// Names returns the names of all registered outputs, sorted.
+func Names() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package output
+
+import (
+	"testing"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	c := func(params Params) (lib.Collector, error) { return nil, nil }
+	Register("register-test", c)
+	_, ok := Get("register-test")
+	assert.True(t, ok)
+	_, ok = Get("missing")
+	assert.False(t, ok)
+	assert.Contains(t, Names(), "register-test")
+
+	assert.PanicsWithValue(t, "output register-test is already registered", func() {
+		Register("register-test", c)
+	})
+	assert.PanicsWithValue(t, `invalid output name "influxdb"`, func() { Register("influxdb", c) })
+	assert.PanicsWithValue(t, `invalid output name ""`, func() { Register("", c) })
+	assert.Panics(t, func() { Register("nil", nil) })
+}
@@ -0,0 +1,218 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package profile finds out where the VUs spend their time: running JS, or waiting in the
+// functions of k6 modules, e.g. for HTTP responses or sleeps. If it's mostly JS, the CPU of the
+// load generator is what limits the throughput, not the system under test.
+package profile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// A Profile collects the times of the iterations and of the calls to k6 functions of all VUs.
+// It's safe for concurrent use.
+type Profile struct {
+	mutex     sync.Mutex
+	vus       map[int64]*times
+	functions map[string]*times
+	calls     map[string]*times
+}
+
+type times struct {
+	count int64
+	total time.Duration
+	js    time.Duration // Only for iterations
+}
+
+func New() *Profile {
+	return &Profile{
+		vus:       make(map[int64]*times),
+		functions: make(map[string]*times),
+		calls:     make(map[string]*times),
+	}
+}
+
+// StartIteration starts profiling a run of an exported function by a VU.
+func (p *Profile) StartIteration(vu int64, function string) *Iteration {
+	return &Iteration{profile: p, vu: vu, function: function, start: time.Now()}
+}
+
+func add(m map[string]*times, key string, total, js time.Duration) {
+	t, ok := m[key]
+	if !ok {
+		t = &times{}
+		m[key] = t
+	}
+	t.count++
+	t.total += total
+	t.js += js
+}
+
+// An Iteration profiles one run of an exported function. It's only used by the VU that runs it,
+// so it's not safe for concurrent use.
+type Iteration struct {
+	profile  *Profile
+	vu       int64
+	function string
+	start    time.Time
+	calls    []call
+	waited   time.Duration
+}
+
+type call struct {
+	name   string
+	start  time.Time
+	nested bool
+}
+
+// Call starts timing a call to a k6 function, and returns the function that ends it. The time of
+// calls that don't call other k6 functions, like http.get() or sleep(), isn't JS time; calls that
+// do, like group(), run JS in between, so only the time of the calls they make isn't.
+func (it *Iteration) Call(name string) func() {
+	if n := len(it.calls); n > 0 {
+		it.calls[n-1].nested = true
+	}
+	it.calls = append(it.calls, call{name: name, start: time.Now()})
+	return func() {
+		c := it.calls[len(it.calls)-1]
+		it.calls = it.calls[:len(it.calls)-1]
+		d := time.Since(c.start)
+		if !c.nested {
+			it.waited += d
+		}
+		it.profile.mutex.Lock()
+		add(it.profile.calls, c.name, d, 0)
+		it.profile.mutex.Unlock()
+	}
+}
+
+// End ends the iteration, and adds its times to the profile.
+func (it *Iteration) End() {
+	total := time.Since(it.start)
+	js := total - it.waited
+	p := it.profile
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	add(p.functions, it.function, total, js)
+	if it.vu == 0 {
+		return // setup() and teardown()
+	}
+	vu, ok := p.vus[it.vu]
+	if !ok {
+		vu = &times{}
+		p.vus[it.vu] = vu
+	}
+	vu.count++
+	vu.total += total
+	vu.js += js
+}
+
+// WriteSummary writes where the VUs spent their time: per exported function, per k6 function and
+// per VU, with the share of JS time, which is what limits the throughput if it's high.
+func (p *Profile) WriteSummary(w io.Writer, indent string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var total, js time.Duration
+	var iterations int64
+	var busiest int64
+	for id, vu := range p.vus {
+		total += vu.total
+		js += vu.js
+		iterations += vu.count
+		if b, ok := p.vus[busiest]; !ok || vu.js > b.js {
+			busiest = id
+		}
+	}
+	if iterations == 0 {
+		_, _ = fmt.Fprintf(w, "%sprofile: no iterations were profiled\n", indent)
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%sprofile: %d VUs ran %d iterations, %.1f%% of their time was JS\n",
+		indent, len(p.vus), iterations, share(js, total))
+	if b := p.vus[busiest]; len(p.vus) > 1 {
+		_, _ = fmt.Fprintf(w, "%s  the VU with the most JS time was VU %d, with %s (%.1f%%)\n",
+			indent, busiest, round(b.js), share(b.js, b.total))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "%s  function\truns\tavg time\tavg JS time\tJS\n", indent)
+	for _, name := range sortedKeys(p.functions, false) {
+		t := p.functions[name]
+		_, _ = fmt.Fprintf(tw, "%s  %s\t%d\t%s\t%s\t%.1f%%\n", indent, name, t.count,
+			round(t.total/time.Duration(t.count)), round(t.js/time.Duration(t.count)), share(t.js, t.total))
+	}
+	_, _ = fmt.Fprintf(tw, "%s  k6 call\tcalls\tavg time\ttotal time\t\n", indent)
+	for _, name := range sortedKeys(p.calls, true) {
+		t := p.calls[name]
+		_, _ = fmt.Fprintf(tw, "%s  %s\t%d\t%s\t%s\t\n", indent, name, t.count,
+			round(t.total/time.Duration(t.count)), round(t.total))
+	}
+	_ = tw.Flush()
+
+	if share(js, total) >= 50 {
+		_, _ = fmt.Fprintf(w, "%s  the VUs spent most of their time running JS, so the CPU of the load generator probably "+
+			"limited the throughput; make the slowest functions faster, or use more load generators\n", indent)
+	}
+}
+
+// sortedKeys returns the keys with the most total time first, and at most 20 of them if limited.
+func sortedKeys(m map[string]*times, limited bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]].total != m[keys[j]].total {
+			return m[keys[i]].total > m[keys[j]].total
+		}
+		return keys[i] < keys[j]
+	})
+	if limited && len(keys) > 20 {
+		keys = keys[:20]
+	}
+	return keys
+}
+
+func share(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d
+	}
+}
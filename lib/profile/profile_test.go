@@ -0,0 +1,67 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package profile
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	p := New()
+
+	it := p.StartIteration(1, "default")
+	endGroup := it.Call("k6.group")
+	endSleep := it.Call("k6.sleep")
+	time.Sleep(20 * time.Millisecond)
+	endSleep()
+	endGroup()
+	it.End()
+
+	setup := p.StartIteration(0, "setup")
+	setup.End()
+
+	assert.Len(t, p.vus, 1)
+	assert.Equal(t, int64(1), p.functions["setup"].count)
+	assert.Equal(t, int64(1), p.calls["k6.group"].count)
+	assert.Equal(t, int64(1), p.calls["k6.sleep"].count)
+
+	// Only the sleep is waited time; the group isn't counted twice
+	vu := p.vus[1]
+	assert.True(t, vu.total >= 20*time.Millisecond)
+	assert.True(t, vu.js < 10*time.Millisecond, vu.js)
+
+	var buf bytes.Buffer
+	p.WriteSummary(&buf, "")
+	out := buf.String()
+	assert.Contains(t, out, "profile: 1 VUs ran 1 iterations")
+	assert.Contains(t, out, "k6.sleep")
+	assert.NotContains(t, out, "the VUs spent most of their time running JS")
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		New().WriteSummary(&buf, "  ")
+		assert.Equal(t, "  profile: no iterations were profiled\n", buf.String())
+	})
+}
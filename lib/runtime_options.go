@@ -29,6 +29,13 @@ type RuntimeOptions struct {
 
 	// Environment variables passed onto the runner
 	Env map[string]string `json:"env" envconfig:"env"`
+
+	// Whether unknown options and invalid thresholds in the script's options are errors, instead
+	// of warnings
+	StrictOptions null.Bool `json:"strictOptions" envconfig:"strict_options"`
+
+	// Where the secrets that scripts get with k6/secrets come from, see secrets.ParseSource
+	SecretSources []string `json:"secretSources" envconfig:"secret_sources"`
 }
 
 // Apply overwrites the receiver RuntimeOptions' fields with any that are set
@@ -40,5 +47,11 @@ func (o RuntimeOptions) Apply(opts RuntimeOptions) RuntimeOptions {
 	if opts.Env != nil {
 		o.Env = opts.Env
 	}
+	if opts.StrictOptions.Valid {
+		o.StrictOptions = opts.StrictOptions
+	}
+	if opts.SecretSources != nil {
+		o.SecretSources = opts.SecretSources
+	}
 	return o
 }
@@ -0,0 +1,323 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package secrets gets the secrets that scripts use, like API tokens and passwords, from the
+// sources that a test is configured with, so that they don't have to be in the scripts or in
+// their environment variables. The secrets that scripts get are redacted from the logs.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Redacted replaces the secrets in redacted strings.
+const Redacted = "***"
+
+// DefaultEnvPrefix is the prefix of the environment variables with secrets, if the env source
+// isn't given one.
+const DefaultEnvPrefix = "K6_SECRET_"
+
+// A Source has secrets, by key.
+type Source interface {
+	// Get returns the secret with the key, and false if there's no such secret.
+	Get(key string) (string, bool, error)
+}
+
+// ParseSource parses the argument of --secret-source, which is one of:
+//
+//	file=<path>      an env file, with KEY=value lines
+//	env[=<prefix>]   the environment variables with the prefix, K6_SECRET_ by default
+//	vault=<url>      a secret of HashiCorp Vault's KV engine, read with the VAULT_TOKEN
+func ParseSource(arg string) (Source, error) {
+	kind, value := arg, ""
+	if i := strings.IndexByte(arg, '='); i >= 0 {
+		kind, value = arg[:i], arg[i+1:]
+	}
+	switch kind {
+	case "file":
+		if value == "" {
+			return nil, errors.New("the file secret source needs a path, e.g. file=secrets.env")
+		}
+		return NewFileSource(value)
+	case "env":
+		if value == "" {
+			value = DefaultEnvPrefix
+		}
+		return EnvSource{Prefix: value}, nil
+	case "vault":
+		return NewVaultSource(value, os.Getenv("VAULT_TOKEN"))
+	default:
+		return nil, errors.Errorf("invalid secret source '%s', it has to be file=<path>, env[=<prefix>] or vault=<url>", arg)
+	}
+}
+
+// A FileSource has the secrets of an env file, with a KEY=value line for each secret. Empty
+// lines and lines that start with # are ignored, and values can be in quotes.
+type FileSource map[string]string
+
+// NewFileSource reads an env file.
+func NewFileSource(filename string) (FileSource, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read the secrets file")
+	}
+	return ParseEnvFile(data)
+}
+
+// ParseEnvFile parses the contents of an env file.
+func ParseEnvFile(data []byte) (FileSource, error) {
+	s := make(FileSource)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return nil, errors.Errorf("invalid line %d in the secrets file, it has to be KEY=value", n)
+		}
+		key, value := strings.TrimSpace(strings.TrimPrefix(line[:i], "export ")), strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		s[key] = value
+	}
+	return s, scanner.Err()
+}
+
+func (s FileSource) Get(key string) (string, bool, error) {
+	value, ok := s[key]
+	return value, ok, nil
+}
+
+// An EnvSource has the secrets of the environment variables with a prefix, without the prefix in
+// their keys, e.g. K6_SECRET_API_TOKEN is API_TOKEN.
+type EnvSource struct {
+	Prefix string
+}
+
+func (s EnvSource) Get(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(s.Prefix + key)
+	return value, ok, nil
+}
+
+// A VaultSource has the keys of a secret in HashiCorp Vault's KV secrets engine, e.g.
+// https://vault.example.com:8200/v1/secret/data/k6 for the k6 secret of version 2 of the engine
+// at secret/. The secret is read the first time that one of its keys is needed.
+type VaultSource struct {
+	URL    string
+	Token  string
+	Client *http.Client
+
+	once   sync.Once
+	values map[string]string
+	err    error
+}
+
+// NewVaultSource returns a source for the secret at a URL.
+func NewVaultSource(secretURL, token string) (*VaultSource, error) {
+	u, err := url.Parse(secretURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.HasPrefix(u.Path, "/v1/") {
+		return nil, errors.Errorf("invalid Vault secret URL '%s', it has to be like https://<host>:8200/v1/<path>", secretURL)
+	}
+	if token == "" {
+		return nil, errors.New("the Vault secret source needs a token in the VAULT_TOKEN environment variable")
+	}
+	return &VaultSource{URL: secretURL, Token: token, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (s *VaultSource) Get(key string) (string, bool, error) {
+	s.once.Do(func() { s.values, s.err = s.read() })
+	if s.err != nil {
+		return "", false, s.err
+	}
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *VaultSource) read() (map[string]string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read the Vault secret")
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return nil, errors.Errorf("couldn't read the Vault secret: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	// Version 2 of the KV engine has the keys in data.data, version 1 in data
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "invalid Vault response")
+	}
+	data := body.Data
+	if raw, ok := data["data"]; ok && data["metadata"] != nil {
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return nil, errors.Wrap(err, "invalid Vault response")
+		}
+	}
+	values := make(map[string]string, len(data))
+	for key, raw := range data {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, errors.Wrap(err, "invalid Vault response")
+		}
+		if str, ok := value.(string); ok {
+			values[key] = str
+		} else {
+			values[key] = strings.TrimSpace(string(raw))
+		}
+	}
+	return values, nil
+}
+
+// A Store gets secrets from its sources, and redacts the secrets that it got from strings. It's
+// a logrus hook that redacts them from the logs.
+type Store struct {
+	sources []Source
+
+	mutex    sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+var _ log.Hook = &Store{}
+
+// NewStore returns a store for the sources, see ParseSource.
+func NewStore(sources []string) (*Store, error) {
+	s := &Store{secrets: make(map[string]bool)}
+	for _, arg := range sources {
+		source, err := ParseSource(arg)
+		if err != nil {
+			return nil, err
+		}
+		s.sources = append(s.sources, source)
+	}
+	return s, nil
+}
+
+// HasSources returns whether the store has any sources.
+func (s *Store) HasSources() bool {
+	return len(s.sources) > 0
+}
+
+// Get returns the secret with the key from the first source that has it, and false if none does.
+func (s *Store) Get(key string) (string, bool, error) {
+	for _, source := range s.sources {
+		value, ok, err := source.Get(key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			s.add(value)
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// Secrets shorter than this aren't redacted, since they'd redact too much that isn't secret.
+const minRedactedLength = 4
+
+func (s *Store) add(secret string) {
+	if len(secret) < minRedactedLength {
+		return
+	}
+	s.mutex.RLock()
+	known := s.secrets[secret]
+	s.mutex.RUnlock()
+	if known {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.secrets[secret] = true
+	// Longer secrets first, so that a secret that contains another one is redacted whole
+	secrets := make([]string, 0, len(s.secrets))
+	for secret := range s.secrets {
+		secrets = append(secrets, secret)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	pairs := make([]string, 0, 2*len(secrets))
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, Redacted)
+	}
+	s.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact replaces the secrets that the store got in a string.
+func (s *Store) Redact(str string) string {
+	s.mutex.RLock()
+	replacer := s.replacer
+	s.mutex.RUnlock()
+	if replacer == nil {
+		return str
+	}
+	return replacer.Replace(str)
+}
+
+func (s *Store) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire redacts the secrets from a log entry's message and fields.
+func (s *Store) Fire(entry *log.Entry) error {
+	entry.Message = s.Redact(entry.Message)
+	for k, v := range entry.Data {
+		switch v := v.(type) {
+		case string:
+			entry.Data[k] = s.Redact(v)
+		case error:
+			if msg := v.Error(); s.Redact(msg) != msg {
+				entry.Data[k] = s.Redact(msg)
+			}
+		case fmt.Stringer:
+			if str := v.String(); s.Redact(str) != str {
+				entry.Data[k] = s.Redact(str)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package secrets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	s, err := ParseEnvFile([]byte(`
+# Test credentials
+API_TOKEN=abc123=
+export PASSWORD = "p@ss word"
+SINGLE='quoted'
+EMPTY=
+`))
+	require.NoError(t, err)
+	assert.Equal(t, FileSource{"API_TOKEN": "abc123=", "PASSWORD": "p@ss word", "SINGLE": "quoted", "EMPTY": ""}, s)
+
+	_, err = ParseEnvFile([]byte("A=1\nnot a secret\n"))
+	assert.EqualError(t, err, "invalid line 2 in the secrets file, it has to be KEY=value")
+}
+
+func TestParseSource(t *testing.T) {
+	source, err := ParseSource("env")
+	require.NoError(t, err)
+	assert.Equal(t, EnvSource{Prefix: DefaultEnvPrefix}, source)
+	source, err = ParseSource("env=MY_")
+	require.NoError(t, err)
+	assert.Equal(t, EnvSource{Prefix: "MY_"}, source)
+
+	for arg, msg := range map[string]string{
+		"file":                  "the file secret source needs a path",
+		"file=/does/not/exist":  "couldn't read the secrets file",
+		"vault=ftp://vault/v1/": "invalid Vault secret URL",
+		"keychain":              "invalid secret source 'keychain'",
+	} {
+		_, err := ParseSource(arg)
+		if assert.Error(t, err, arg) {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	require.NoError(t, os.Setenv("K6_SECRET_TEST_TOKEN", "env-token"))
+	defer func() { _ = os.Unsetenv("K6_SECRET_TEST_TOKEN") }()
+
+	value, ok, err := EnvSource{Prefix: DefaultEnvPrefix}.Get("TEST_TOKEN")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "env-token", value)
+	_, ok, _ = EnvSource{Prefix: DefaultEnvPrefix}.Get("MISSING")
+	assert.False(t, ok)
+}
+
+func TestVaultSource(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/k6":
+			_, _ = w.Write([]byte(`{"data":{"data":{"password":"vault-pass","port":5432},"metadata":{"version":3}}}`))
+		case "/v1/kv/k6":
+			_, _ = w.Write([]byte(`{"data":{"password":"v1-pass"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("KV v2", func(t *testing.T) {
+		requests = 0
+		source, err := NewVaultSource(srv.URL+"/v1/secret/data/k6", "root")
+		require.NoError(t, err)
+		value, ok, err := source.Get("password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "vault-pass", value)
+		value, _, _ = source.Get("port")
+		assert.Equal(t, "5432", value)
+		_, ok, _ = source.Get("missing")
+		assert.False(t, ok)
+		assert.Equal(t, 1, requests)
+	})
+	t.Run("KV v1", func(t *testing.T) {
+		source, err := NewVaultSource(srv.URL+"/v1/kv/k6", "root")
+		require.NoError(t, err)
+		value, _, err := source.Get("password")
+		assert.NoError(t, err)
+		assert.Equal(t, "v1-pass", value)
+	})
+	t.Run("Forbidden", func(t *testing.T) {
+		source, err := NewVaultSource(srv.URL+"/v1/secret/data/k6", "wrong")
+		require.NoError(t, err)
+		_, _, err = source.Get("password")
+		assert.EqualError(t, err, `couldn't read the Vault secret: 403 Forbidden: {"errors":["permission denied"]}`)
+	})
+	t.Run("No Token", func(t *testing.T) {
+		_, err := NewVaultSource(srv.URL+"/v1/secret/data/k6", "")
+		assert.EqualError(t, err, "the Vault secret source needs a token in the VAULT_TOKEN environment variable")
+	})
+}
+
+func TestStore(t *testing.T) {
+	require.NoError(t, os.Setenv("K6_SECRET_PASSWORD", "from-env"))
+	defer func() { _ = os.Unsetenv("K6_SECRET_PASSWORD") }()
+
+	store, err := NewStore([]string{"env"})
+	require.NoError(t, err)
+	store.sources = append([]Source{FileSource{"TOKEN": "abcdef", "TOKEN_LONG": "abcdef-123", "PIN": "12"}}, store.sources...)
+	assert.True(t, store.HasSources())
+
+	assert.Equal(t, "abcdef abcdef-123 from-env", store.Redact("abcdef abcdef-123 from-env"))
+	for key, expected := range map[string]string{"TOKEN": "abcdef", "TOKEN_LONG": "abcdef-123", "PASSWORD": "from-env", "PIN": "12"} {
+		value, ok, err := store.Get(key)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, value)
+	}
+	_, ok, err := store.Get("MISSING")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.Equal(t, "*** *** *** 12", store.Redact("abcdef abcdef-123 from-env 12"))
+
+	entry := log.NewEntry(log.New())
+	entry.Message = "login with abcdef failed"
+	entry.Data = log.Fields{"password": "from-env", "error": errors.New("invalid token abcdef-123"), "n": 1}
+	require.NoError(t, store.Fire(entry))
+	assert.Equal(t, "login with *** failed", entry.Message)
+	assert.Equal(t, log.Fields{"password": "***", "error": "invalid token ***", "n": 1}, entry.Data)
+}
@@ -0,0 +1,105 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package shared has the key-value store that the VUs of a test run share with k6/shared. The
+// values are kept as JSON, so that every VU gets its own copy of them.
+package shared
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Store is the key-value store of a test run. Its operations are atomic.
+type Store struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+// NewStore returns an empty store.
+func NewStore() *Store {
+	return &Store{values: make(map[string][]byte)}
+}
+
+// Get returns the JSON value of a key, and false if the key isn't set.
+func (s *Store) Get(key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	data, ok := s.values[key]
+	return data, ok
+}
+
+// Set sets the JSON value of a key.
+func (s *Store) Set(key string, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[key] = data
+}
+
+// SetIfAbsent sets the JSON value of a key only if it isn't set yet, and returns whether it did.
+func (s *Store) SetIfAbsent(key string, data []byte) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false
+	}
+	s.values[key] = data
+	return true
+}
+
+// Add adds delta to the value of a key, which starts at 0, and returns the new value.
+func (s *Store) Add(key string, delta int64) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var n int64
+	if data, ok := s.values[key]; ok {
+		if err := json.Unmarshal(data, &n); err != nil {
+			return 0, errors.Errorf("can't add to shared value '%s', it isn't an integer: %s", key, data)
+		}
+	}
+	n += delta
+	s.values[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+// Delete deletes a key, and returns whether it was set.
+func (s *Store) Delete(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.values[key]
+	delete(s.values, key)
+	return ok
+}
+
+// Keys returns the keys that are set, in alphabetical order.
+func (s *Store) Keys() []string {
+	s.mutex.Lock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	s.mutex.Unlock()
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shared
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	s.Set("config", []byte(`{"n":1}`))
+	data, ok := s.Get("config")
+	assert.True(t, ok)
+	assert.Equal(t, `{"n":1}`, string(data))
+	_, ok = s.Get("missing")
+	assert.False(t, ok)
+
+	assert.True(t, s.SetIfAbsent("leader", []byte("1")))
+	assert.False(t, s.SetIfAbsent("leader", []byte("2")))
+	data, _ = s.Get("leader")
+	assert.Equal(t, "1", string(data))
+
+	n, err := s.Add("leader", 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), n)
+	_, err = s.Add("config", 1)
+	assert.EqualError(t, err, `can't add to shared value 'config', it isn't an integer: {"n":1}`)
+
+	assert.Equal(t, []string{"config", "leader"}, s.Keys())
+	assert.True(t, s.Delete("config"))
+	assert.False(t, s.Delete("config"))
+	assert.Equal(t, []string{"leader"}, s.Keys())
+
+	t.Run("concurrent adds", func(t *testing.T) {
+		var wg sync.WaitGroup
+		ids := make(chan int64, 1000)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					id, err := s.Add("concurrent", 1)
+					assert.NoError(t, err)
+					ids <- id
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[int64]bool)
+		for id := range ids {
+			assert.False(t, seen[id], fmt.Sprintf("duplicate ID %d", id))
+			seen[id] = true
+		}
+		assert.Len(t, seen, 1000)
+	})
+}
@@ -0,0 +1,36 @@
+// +build go1.12
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import "crypto/tls"
+
+func init() {
+	SupportedTLSVersions["tls1.3"] = tls.VersionTLS13
+	SupportedTLSVersionsToString[tls.VersionTLS13] = "tls1.3"
+
+	// TLS 1.3 cipher suites aren't configurable, so they can't be used in the tlsCipherSuites
+	// option, but they're still reported for responses.
+	SupportedTLSCipherSuitesToString[tls.TLS_AES_128_GCM_SHA256] = "TLS_AES_128_GCM_SHA256"
+	SupportedTLSCipherSuitesToString[tls.TLS_AES_256_GCM_SHA384] = "TLS_AES_256_GCM_SHA384"
+	SupportedTLSCipherSuitesToString[tls.TLS_CHACHA20_POLY1305_SHA256] = "TLS_CHACHA20_POLY1305_SHA256"
+}
@@ -0,0 +1,41 @@
+// +build go1.12
+
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsTLSVersion13(t *testing.T) {
+	var opts Options
+	jsonStr := `{"tlsVersion":{"min":"tls1.2","max":"tls1.3"}}`
+	assert.NoError(t, json.Unmarshal([]byte(jsonStr), &opts))
+	assert.Equal(t, &TLSVersions{
+		Min: TLSVersion(tls.VersionTLS12),
+		Max: TLSVersion(tls.VersionTLS13),
+	}, opts.TLSVersion)
+}
@@ -0,0 +1,233 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// The spans are pushed when this many have been buffered, or when the push interval has
+	// passed since the last push, whichever comes first.
+	exportBatchSize    = 512
+	exportPushInterval = time.Second
+
+	spanKindClient  = 3 // SPAN_KIND_CLIENT
+	statusCodeError = 2 // STATUS_CODE_ERROR
+)
+
+// Exporter pushes spans to an OTLP/HTTP traces endpoint, e.g. http://localhost:4318/v1/traces,
+// in batches and in the background. It's safe for concurrent use.
+type Exporter struct {
+	Endpoint string
+	Logger   *log.Logger
+
+	client *http.Client
+
+	lock     sync.Mutex
+	spans    []*Span
+	lastPush time.Time
+	pushing  sync.WaitGroup
+}
+
+// NewExporter creates a new exporter.
+func NewExporter(endpoint string, logger *log.Logger) *Exporter {
+	return &Exporter{
+		Endpoint: endpoint,
+		Logger:   logger,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		lastPush: time.Now(),
+	}
+}
+
+// Export buffers a span, and pushes the buffered ones if it's time to.
+func (e *Exporter) Export(span *Span) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.spans = append(e.spans, span)
+	if len(e.spans) < exportBatchSize && time.Since(e.lastPush) < exportPushInterval {
+		return
+	}
+	spans := e.spans
+	e.spans = nil
+	e.lastPush = time.Now()
+	e.pushing.Add(1)
+	go func() {
+		defer e.pushing.Done()
+		e.push(spans)
+	}()
+}
+
+// Flush pushes the buffered spans and waits for all pushes to finish.
+func (e *Exporter) Flush() {
+	e.lock.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.lastPush = time.Now()
+	e.lock.Unlock()
+
+	if len(spans) > 0 {
+		e.push(spans)
+	}
+	e.pushing.Wait()
+}
+
+func (e *Exporter) push(spans []*Span) {
+	body, err := json.Marshal(e.exportRequest(spans))
+	if err == nil {
+		err = e.post(body)
+	}
+	if err != nil {
+		e.Logger.WithError(err).WithField("spans", len(spans)).Warn("Couldn't export the request spans")
+	}
+}
+
+func (e *Exporter) post(body []byte) error {
+	req, err := http.NewRequest("POST", e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6")
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+func (e *Exporter) exportRequest(spans []*Span) otlpExportRequest {
+	result := make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		keys := make([]string, 0, len(s.Attributes))
+		for k := range s.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		attributes := make([]otlpKeyValue, 0, len(keys))
+		for _, k := range keys {
+			var value otlpAnyValue
+			switch v := s.Attributes[k].(type) {
+			case int:
+				value.IntValue = strconv.Itoa(v)
+			case string:
+				value.StringValue = v
+			default:
+				continue
+			}
+			attributes = append(attributes, otlpKeyValue{Key: k, Value: value})
+		}
+
+		result[i] = otlpSpan{
+			TraceID:           s.TraceIDString(),
+			SpanID:            s.SpanIDString(),
+			Name:              s.Name,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes,
+		}
+		if s.Failed {
+			result[i].Status.Code = statusCodeError
+		}
+	}
+
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpAnyValue{StringValue: "k6"}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "k6"},
+			Spans: result,
+		}},
+	}}}
+}
+
+// The JSON encoding of OTLP's ExportTraceServiceRequest, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+// Unlike other bytes fields, trace and span IDs are hex encoded.
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code int `json:"code,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []otlpExportRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var req otlpExportRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mutex.Lock()
+		requests = append(requests, req)
+		mutex.Unlock()
+	}))
+	defer srv.Close()
+
+	exporter := NewExporter(srv.URL+"/v1/traces", logrus.New())
+	client := &Client{Propagator: PropagatorW3C, Sampling: 1, Exporter: exporter}
+	req, _ := http.NewRequest("POST", srv.URL+"/login", nil)
+
+	span := client.StartSpan(req)
+	end := span.Start.Add(150 * time.Millisecond)
+	client.EndSpan(span, end, map[string]interface{}{
+		"http.method":      "POST",
+		"http.status_code": 503,
+	}, true)
+	client.EndSpan(client.StartSpan(req), end, nil, false)
+
+	unsampled := &Client{Sampling: 0, Exporter: exporter}
+	unsampled.EndSpan(unsampled.StartSpan(req), end, nil, false)
+
+	exporter.Flush()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].ResourceSpans, 1)
+	rs := requests[0].ResourceSpans[0]
+	assert.Equal(t, []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: "k6"}}}, rs.Resource.Attributes)
+	require.Len(t, rs.ScopeSpans, 1)
+	spans := rs.ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, otlpSpan{
+		TraceID:           span.TraceIDString(),
+		SpanID:            span.SpanIDString(),
+		Name:              "HTTP POST",
+		Kind:              spanKindClient,
+		StartTimeUnixNano: spans[0].StartTimeUnixNano,
+		EndTimeUnixNano:   spans[0].EndTimeUnixNano,
+		Attributes: []otlpKeyValue{
+			{Key: "http.method", Value: otlpAnyValue{StringValue: "POST"}},
+			{Key: "http.status_code", Value: otlpAnyValue{IntValue: "503"}},
+		},
+		Status: otlpStatus{Code: statusCodeError},
+	}, spans[0])
+	assert.Equal(t, otlpStatus{}, spans[1].Status)
+
+	t.Run("batches", func(t *testing.T) {
+		requests = nil
+		for i := 0; i < exportBatchSize+1; i++ {
+			client.EndSpan(client.StartSpan(req), end, nil, false)
+		}
+		exporter.Flush()
+		require.Len(t, requests, 2)
+		// The full batch is pushed in the background, so it can arrive after the flushed one
+		sizes := []int{
+			len(requests[0].ResourceSpans[0].ScopeSpans[0].Spans),
+			len(requests[1].ResourceSpans[0].ScopeSpans[0].Spans),
+		}
+		assert.ElementsMatch(t, []int{exportBatchSize, 1}, sizes)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no traces here", http.StatusNotFound)
+		}))
+		defer failing.Close()
+
+		logger, hook := logtest.NewNullLogger()
+		exporter := NewExporter(failing.URL, logger)
+		exporter.Export(span)
+		exporter.Flush()
+		require.Len(t, hook.Entries, 1)
+		assert.Equal(t, "Couldn't export the request spans", hook.LastEntry().Message)
+		assert.Contains(t, hook.LastEntry().Data["error"].(error).Error(), "404 Not Found: no traces here")
+	})
+}
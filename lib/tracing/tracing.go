@@ -0,0 +1,127 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package tracing propagates trace contexts to the systems under test, so that requests made by
+// k6 can be correlated with the backend traces they start, and optionally exports them as spans.
+package tracing
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The supported propagation formats.
+const (
+	PropagatorW3C = "w3c" // https://www.w3.org/TR/trace-context/
+	PropagatorB3  = "b3"  // https://github.com/openzipkin/b3-propagation, single header
+)
+
+// ValidatePropagator returns an error if the propagator isn't empty or one of the supported ones.
+func ValidatePropagator(propagator string) error {
+	switch propagator {
+	case "", PropagatorW3C, PropagatorB3:
+		return nil
+	default:
+		return errors.Errorf("invalid tracing propagator '%s', it has to be '%s' or '%s'",
+			propagator, PropagatorW3C, PropagatorB3)
+	}
+}
+
+// A Span is a single traced request.
+type Span struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+
+	Name       string
+	Start, End time.Time
+	Attributes map[string]interface{} // Strings or ints
+	Failed     bool
+}
+
+// TraceIDString returns the hex encoding of the trace ID, which is what backends show.
+func (s *Span) TraceIDString() string {
+	return hex.EncodeToString(s.TraceID[:])
+}
+
+// SpanIDString returns the hex encoding of the span ID.
+func (s *Span) SpanIDString() string {
+	return hex.EncodeToString(s.SpanID[:])
+}
+
+// Client starts spans for requests, injects their trace context into the requests' headers and
+// hands the sampled ones to the exporter, if there is one. It's safe for concurrent use.
+type Client struct {
+	Propagator string  // Empty if the headers shouldn't be injected
+	Sampling   float64 // The fraction of traces that are sampled, between 0 and 1
+	Exporter   *Exporter
+}
+
+// StartSpan starts a span for a request and injects its trace context into the request headers.
+func (c *Client) StartSpan(req *http.Request) *Span {
+	span := &Span{
+		Name:    "HTTP " + req.Method,
+		Start:   time.Now(),
+		Sampled: c.Sampling >= 1 || rand.Float64() < c.Sampling,
+	}
+	// Random IDs from crypto/rand, so they don't collide between k6 instances
+	_, _ = crand.Read(span.TraceID[:])
+	_, _ = crand.Read(span.SpanID[:])
+
+	switch c.Propagator {
+	case PropagatorW3C:
+		req.Header.Set("traceparent", traceparent(span))
+	case PropagatorB3:
+		req.Header.Set("b3", b3(span))
+	}
+	return span
+}
+
+// EndSpan ends a span and exports it, if it's sampled and there is an exporter.
+func (c *Client) EndSpan(span *Span, end time.Time, attributes map[string]interface{}, failed bool) {
+	span.End = end
+	span.Attributes = attributes
+	span.Failed = failed
+	if span.Sampled && c.Exporter != nil {
+		c.Exporter.Export(span)
+	}
+}
+
+func traceparent(span *Span) string {
+	flags := "00"
+	if span.Sampled {
+		flags = "01"
+	}
+	return strings.Join([]string{"00", span.TraceIDString(), span.SpanIDString(), flags}, "-")
+}
+
+func b3(span *Span) string {
+	sampled := "0"
+	if span.Sampled {
+		sampled = "1"
+	}
+	return strings.Join([]string{span.TraceIDString(), span.SpanIDString(), sampled}, "-")
+}
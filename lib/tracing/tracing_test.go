@@ -0,0 +1,85 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tracing
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePropagator(t *testing.T) {
+	assert.NoError(t, ValidatePropagator(""))
+	assert.NoError(t, ValidatePropagator("w3c"))
+	assert.NoError(t, ValidatePropagator("b3"))
+	assert.EqualError(t, ValidatePropagator("jaeger"), "invalid tracing propagator 'jaeger', it has to be 'w3c' or 'b3'")
+}
+
+func TestClient(t *testing.T) {
+	testdata := map[string]struct {
+		propagator, header string
+		sampling           float64
+		pattern            string
+	}{
+		"w3c":             {PropagatorW3C, "traceparent", 1, `^00-([0-9a-f]{32})-([0-9a-f]{16})-01$`},
+		"w3c not sampled": {PropagatorW3C, "traceparent", 0, `^00-([0-9a-f]{32})-([0-9a-f]{16})-00$`},
+		"b3":              {PropagatorB3, "b3", 1, `^([0-9a-f]{32})-([0-9a-f]{16})-1$`},
+		"b3 not sampled":  {PropagatorB3, "b3", 0, `^([0-9a-f]{32})-([0-9a-f]{16})-0$`},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			client := &Client{Propagator: data.propagator, Sampling: data.sampling}
+			req, _ := http.NewRequest("GET", "http://example.com/", nil)
+			span := client.StartSpan(req)
+			assert.Equal(t, "HTTP GET", span.Name)
+			assert.Equal(t, data.sampling == 1, span.Sampled)
+
+			match := regexp.MustCompile(data.pattern).FindStringSubmatch(req.Header.Get(data.header))
+			if assert.Len(t, match, 3) {
+				assert.Equal(t, span.TraceIDString(), match[1])
+				assert.Equal(t, span.SpanIDString(), match[2])
+			}
+		})
+	}
+
+	t.Run("unique IDs", func(t *testing.T) {
+		client := &Client{Sampling: 1}
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		a, b := client.StartSpan(req), client.StartSpan(req)
+		assert.NotEqual(t, a.TraceID, b.TraceID)
+		assert.NotEqual(t, a.SpanID, b.SpanID)
+		assert.Empty(t, req.Header)
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		client := &Client{Sampling: 0.25}
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		sampled := 0
+		for i := 0; i < 10000; i++ {
+			if client.StartSpan(req).Sampled {
+				sampled++
+			}
+		}
+		assert.InDelta(t, 2500, sampled, 300)
+	})
+}
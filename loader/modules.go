@@ -0,0 +1,165 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleFiles is what ResolveModule looks for modules in: a filesystem, or the files of an archive.
+type ModuleFiles interface {
+	// IsFile returns whether there's a file (and not a directory) with the given name.
+	IsFile(filename string) bool
+
+	// ReadFile returns the contents of a file, or false if there's no such file. It's only used
+	// to read package.json files.
+	ReadFile(filename string) ([]byte, bool)
+}
+
+// The extensions that are tried, in order, for module names without one.
+var moduleExtensions = []string{".js", ".ts", ".json"}
+
+// ResolveModule resolves the name of an imported or required module to the filename to load it
+// from, with Node's algorithm:
+//
+//   - Relative ("./lib", "../lib") and absolute ("/lib") names are looked for as a file, as-is and
+//     then with the .js, .ts and .json extensions, and then as a directory: its package.json's
+//     "exports" or "main", or its index file.
+//   - Other names ("lodash", "@scope/pkg/sub") are looked for in the node_modules directories of
+//     the importing directory and all of its parents, closest first.
+//
+// Names that aren't found, and all names imported by remote modules, are resolved as they used
+// to be, see Resolve, so that Load can fetch remote modules or report that a file doesn't exist.
+func ResolveModule(files ModuleFiles, pwd, name string) string {
+	if name == "" || !isLocal(pwd) {
+		return Resolve(pwd, name)
+	}
+
+	if name[0] == '.' || isLocal(name) {
+		if filename, ok := resolveFileOrDir(files, Resolve(pwd, name)); ok {
+			return filename
+		}
+		return Resolve(pwd, name)
+	}
+
+	for dir := filepath.ToSlash(pwd); ; dir = path.Dir(dir) {
+		if path.Base(dir) != "node_modules" {
+			if filename, ok := resolveFileOrDir(files, path.Join(dir, "node_modules", name)); ok {
+				return filename
+			}
+		}
+		if path.Dir(dir) == dir {
+			return name
+		}
+	}
+}
+
+func isLocal(name string) bool {
+	return strings.HasPrefix(name, "/") || filepath.VolumeName(name) != ""
+}
+
+func resolveFileOrDir(files ModuleFiles, name string) (string, bool) {
+	if filename, ok := resolveFile(files, name); ok {
+		return filename, true
+	}
+	return resolveDir(files, name)
+}
+
+func resolveFile(files ModuleFiles, name string) (string, bool) {
+	if files.IsFile(name) {
+		return name, true
+	}
+	for _, ext := range moduleExtensions {
+		if files.IsFile(name + ext) {
+			return name + ext, true
+		}
+	}
+	return "", false
+}
+
+func resolveDir(files ModuleFiles, dir string) (string, bool) {
+	if data, ok := files.ReadFile(dir + "/package.json"); ok {
+		var pkg struct {
+			Main    string          `json:"main"`
+			Exports json.RawMessage `json:"exports"`
+		}
+		// Invalid package.json files are ignored, like packages without an entry point
+		if json.Unmarshal(data, &pkg) == nil {
+			for _, entry := range []string{packageExport(pkg.Exports), pkg.Main} {
+				if entry == "" {
+					continue
+				}
+				if filename, ok := resolveFile(files, path.Join(dir, entry)); ok {
+					return filename, true
+				}
+				if filename, ok := resolveIndex(files, path.Join(dir, entry)); ok {
+					return filename, true
+				}
+			}
+		}
+	}
+	return resolveIndex(files, dir)
+}
+
+func resolveIndex(files ModuleFiles, dir string) (string, bool) {
+	for _, ext := range moduleExtensions {
+		if files.IsFile(dir + "/index" + ext) {
+			return dir + "/index" + ext, true
+		}
+	}
+	return "", false
+}
+
+// packageExport returns the main entry point of a package.json's "exports", which is either a
+// path, or an object with a path for ".", which is either a path or an object with conditions.
+// Scripts can both require() and import modules, so the "require", "default" and "import"
+// conditions are tried in that order.
+func packageExport(exports json.RawMessage) string {
+	if len(exports) == 0 {
+		return ""
+	}
+	var entry string
+	if json.Unmarshal(exports, &entry) == nil {
+		return entry
+	}
+
+	var subpaths map[string]json.RawMessage
+	if json.Unmarshal(exports, &subpaths) != nil {
+		return ""
+	}
+	if main, ok := subpaths["."]; ok {
+		if json.Unmarshal(main, &entry) == nil {
+			return entry
+		}
+		if json.Unmarshal(main, &subpaths) != nil {
+			return ""
+		}
+	}
+	for _, condition := range []string{"require", "default", "import"} {
+		if json.Unmarshal(subpaths[condition], &entry) == nil && entry != "" {
+			return entry
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package loader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testModuleFiles map[string]string
+
+func (f testModuleFiles) IsFile(filename string) bool {
+	_, ok := f[filename]
+	return ok
+}
+
+func (f testModuleFiles) ReadFile(filename string) ([]byte, bool) {
+	data, ok := f[filename]
+	return []byte(data), ok
+}
+
+func TestResolveModule(t *testing.T) {
+	files := testModuleFiles{
+		"/app/lib.js":                                   "",
+		"/app/lib/index.js":                             "",
+		"/app/util.ts":                                  "",
+		"/app/data.json":                                "",
+		"/app/dir/index.ts":                             "",
+		"/app/main/package.json":                        `{ "main": "./src/main" }`,
+		"/app/main/src/main.js":                         "",
+		"/app/broken/package.json":                      `{ "main": `,
+		"/app/broken/index.js":                          "",
+		"/app/node_modules/pkg/package.json":            `{ "exports": "./dist/pkg.js" }`,
+		"/app/node_modules/pkg/dist/pkg.js":             "",
+		"/app/node_modules/pkg/extra.js":                "",
+		"/app/node_modules/cond/package.json":           `{ "exports": { "import": "./esm.js", "default": "./cjs.js" } }`,
+		"/app/node_modules/cond/cjs.js":                 "",
+		"/app/sub/node_modules/pkg/index.js":            "",
+		"/node_modules/@scope/top/package.json":         `{ "main": "lib" }`,
+		"/node_modules/@scope/top/lib/index.js":         "",
+		"/app/node_modules/dep/node_modules/x/index.js": "",
+	}
+
+	testdata := map[string]struct{ pwd, name, filename string }{
+		"file":                  {"/app", "./lib.js", "/app/lib.js"},
+		"file before dir":       {"/app", "./lib", "/app/lib.js"},
+		"extension .ts":         {"/app/sub", "../util", "/app/util.ts"},
+		"extension .json":       {"/app", "/app/data", "/app/data.json"},
+		"index":                 {"/app", "./dir", "/app/dir/index.ts"},
+		"main":                  {"/app", "./main", "/app/main/src/main.js"},
+		"invalid package.json":  {"/app", "./broken", "/app/broken/index.js"},
+		"missing":               {"/app", "./missing", "/app/missing"},
+		"package exports":       {"/app", "pkg", "/app/node_modules/pkg/dist/pkg.js"},
+		"package file":          {"/app", "pkg/extra", "/app/node_modules/pkg/extra.js"},
+		"export conditions":     {"/app", "cond", "/app/node_modules/cond/cjs.js"},
+		"closest node_modules":  {"/app/sub/dir", "pkg", "/app/sub/node_modules/pkg/index.js"},
+		"parent node_modules":   {"/app/sub", "@scope/top", "/node_modules/@scope/top/lib/index.js"},
+		"nested node_modules":   {"/app/node_modules/dep", "x", "/app/node_modules/dep/node_modules/x/index.js"},
+		"remote":                {"/app", "example.com/lib.js", "example.com/lib.js"},
+		"relative to remote":    {"example.com/path", "./lib", "example.com/path/lib"},
+		"builtin-like not here": {"/app", "k6/x/missing", "k6/x/missing"},
+	}
+	for name, data := range testdata {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, data.filename, ResolveModule(files, data.pwd, data.name))
+		})
+	}
+}
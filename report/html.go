@@ -0,0 +1,120 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+const (
+	chartWidth  = 720
+	chartHeight = 180
+)
+
+// The colors of the lines of a chart, in order.
+var lineColors = []string{"#7d64ff", "#ff8c42", "#3fb68b"}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"points":   svgPoints,
+	"color":    func(i int) string { return lineColors[i%len(lineColors)] },
+	"humanize": func(c Chart, v float64) string { return c.Metric.HumanizeValue(v, "") },
+	"time":     func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"width":    func() int { return chartWidth },
+	"height":   func() int { return chartHeight },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>k6 report</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 800px; color: #333; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #ddd; font-size: 14px; }
+td.values { font-family: monospace; }
+.passed { color: #3fb68b; }
+.failed { color: #e5484d; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>k6 report</h1>
+<p>Started at {{time .Start}}, ran for {{.Duration}}.</p>
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+<tr><th></th><th>Metric</th><th>Threshold</th></tr>
+{{range .Thresholds}}<tr><td class="{{if .Passed}}passed">✓{{else}}failed">✗{{end}}</td><td>{{.Metric}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Checks}}
+<h2>Checks</h2>
+<table>
+<tr><th></th><th>Group</th><th>Check</th><th>Passes</th><th>Fails</th></tr>
+{{range .Checks}}<tr><td class="{{if .Fails}}failed">✗{{else}}passed">✓{{end}}</td><td>{{.Group}}</td><td>{{.Name}}</td><td>{{.Passes}}</td><td>{{.Fails}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Charts}}
+<h2>Over time</h2>
+{{range .Charts}}{{$chart := .}}
+<h3>{{.Metric.Name}}</h3>
+<p>{{range $i, $line := .Lines}}<span style="color: {{color $i}}">■</span> {{$line.Name}} {{end}}— up to {{humanize $chart $chart.Max}}, every {{.Interval}}</p>
+<svg width="{{width}}" height="{{height}}" viewBox="0 0 {{width}} {{height}}">
+{{range $i, $line := .Lines}}<polyline fill="none" stroke="{{color $i}}" stroke-width="2" points="{{points $line $chart.Max}}"/>
+{{end}}</svg>
+{{end}}{{end}}
+<h2>Metrics</h2>
+<table>
+<tr><th></th><th>Metric</th><th>Values</th></tr>
+{{range .Metrics}}<tr><td>{{if .Tainted.Valid}}{{if .Tainted.Bool}}<span class="failed">✗</span>{{else}}<span class="passed">✓</span>{{end}}{{end}}</td><td>{{.Name}}</td><td class="values">{{.Values}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// svgPoints returns the points of an SVG polyline of a line, scaled so that max is at the top.
+func svgPoints(line stats.TimeSeriesLine, max float64) string {
+	if max <= 0 {
+		max = 1
+	}
+	step := float64(chartWidth)
+	if len(line.Values) > 1 {
+		step = float64(chartWidth) / float64(len(line.Values)-1)
+	}
+	points := make([]string, 0, len(line.Values))
+	for i, v := range line.Values {
+		if math.IsNaN(v) {
+			continue
+		}
+		y := float64(chartHeight) - v/max*float64(chartHeight-10)
+		points = append(points, fmt.Sprintf("%.1f,%.1f", float64(i)*step, y))
+	}
+	return strings.Join(points, " ")
+}
+
+// WriteHTML writes the report as a self-contained HTML page, with SVG charts.
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlTemplate.Execute(w, r)
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/ui"
+)
+
+// escapeMarkdown escapes the characters that would break a Markdown table cell.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// WriteMarkdown writes the report as Markdown, with sparklines for charts.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# k6 report\n\nStarted at %s, ran for %s.\n", r.Start.UTC().Format(time.RFC3339), r.Duration)
+
+	if len(r.Thresholds) > 0 {
+		b.WriteString("\n## Thresholds\n\n| | Metric | Threshold |\n|---|---|---|\n")
+		for _, th := range r.Thresholds {
+			mark := "✓"
+			if !th.Passed {
+				mark = "✗"
+			}
+			fmt.Fprintf(&b, "| %s | %s | `%s` |\n", mark, escapeMarkdown(th.Metric), escapeMarkdown(th.Source))
+		}
+	}
+
+	if len(r.Checks) > 0 {
+		b.WriteString("\n## Checks\n\n| | Group | Check | Passes | Fails |\n|---|---|---|---|---|\n")
+		for _, c := range r.Checks {
+			mark := "✓"
+			if c.Fails > 0 {
+				mark = "✗"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %d | %d |\n", mark, escapeMarkdown(c.Group), escapeMarkdown(c.Name), c.Passes, c.Fails)
+		}
+	}
+
+	if len(r.Charts) > 0 {
+		fmt.Fprintf(&b, "\n## Over time, every %s\n\n| Metric | | Up to | |\n|---|---|---|---|\n", r.Charts[0].Interval)
+		for _, c := range r.Charts {
+			max := c.Max()
+			for _, l := range c.Lines {
+				fmt.Fprintf(&b, "| %s | %s | %s | `%s` |\n", c.Metric.Name, l.Name, c.Metric.HumanizeValue(max, ""), ui.ScaledSparkline(l.Values, max))
+			}
+		}
+	}
+
+	b.WriteString("\n## Metrics\n\n| | Metric | Values |\n|---|---|---|\n")
+	for _, m := range r.Metrics {
+		mark := ""
+		if m.Tainted.Valid {
+			mark = "✓"
+			if m.Tainted.Bool {
+				mark = "✗"
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", mark, escapeMarkdown(m.Name), escapeMarkdown(m.Values))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,143 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package report
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/ui"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// A Chart is the values of a metric over the time of the test, as one or more lines, with a
+// value for every interval; see stats.TimeSeries.
+type Chart struct {
+	Metric   *stats.Metric
+	Interval time.Duration
+	Lines    []stats.TimeSeriesLine
+}
+
+// MetricRow is a metric's values in the summary.
+type MetricRow struct {
+	Name, Values string
+	Tainted      null.Bool // Whether its thresholds failed, if it has any
+}
+
+// ThresholdRow is a threshold's outcome.
+type ThresholdRow struct {
+	Metric, Source string
+	Passed         bool
+}
+
+// CheckRow is a check's outcome.
+type CheckRow struct {
+	Group, Name   string
+	Passes, Fails int64
+}
+
+// Report is what the reports show about the results.
+type Report struct {
+	Start    time.Time
+	Duration time.Duration
+
+	Thresholds []ThresholdRow
+	Checks     []CheckRow
+	Metrics    []MetricRow
+	Charts     []Chart
+}
+
+// New returns the report of the results.
+func New(r *Results) *Report {
+	report := &Report{Start: r.Start, Duration: r.Duration()}
+
+	names := make([]string, 0, len(r.Metrics))
+	for name := range r.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := report.Duration
+	if t <= 0 {
+		t = time.Second
+	}
+	for _, name := range names {
+		m := r.Metrics[name]
+		for _, th := range m.Thresholds.Thresholds {
+			report.Thresholds = append(report.Thresholds, ThresholdRow{Metric: name, Source: th.Source, Passed: !th.Failed})
+		}
+		report.Metrics = append(report.Metrics, MetricRow{
+			Name:    name,
+			Values:  formatValues(m, t),
+			Tainted: m.Tainted,
+		})
+	}
+
+	groups := make([]string, 0, len(r.Checks))
+	for group := range r.Checks {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		checks := make([]string, 0, len(r.Checks[group]))
+		for name := range r.Checks[group] {
+			checks = append(checks, name)
+		}
+		sort.Strings(checks)
+		for _, name := range checks {
+			c := r.Checks[group][name]
+			report.Checks = append(report.Checks, CheckRow{Group: group, Name: name, Passes: c.Passes, Fails: c.Fails})
+		}
+	}
+
+	for _, name := range chartedMetrics {
+		if lines := r.series.Lines(name); lines != nil {
+			report.Charts = append(report.Charts, Chart{Metric: r.Metrics[name], Interval: r.series.Interval, Lines: lines})
+		}
+	}
+	return report
+}
+
+// formatValues returns a metric's values like the end-of-test summary shows them.
+func formatValues(m *stats.Metric, t time.Duration) string {
+	if sink, ok := m.Sink.(*stats.TrendSink); ok {
+		sink.Calc()
+		parts := make([]string, len(ui.TrendColumns))
+		for i, col := range ui.TrendColumns {
+			parts[i] = col.Key + "=" + m.HumanizeValue(col.Get(sink), "")
+		}
+		return strings.Join(parts, " ")
+	}
+	value, extra := ui.NonTrendMetricValueForSum(t, "", m)
+	return strings.Join(append([]string{value}, extra...), " ")
+}
+
+// Max returns the largest value of the chart's lines, or 0 if they have none.
+func (c Chart) Max() float64 {
+	max := 0.0
+	for _, l := range c.Lines {
+		max = math.Max(max, ui.MaxValue(l.Values))
+	}
+	return max
+}
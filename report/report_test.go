@@ -0,0 +1,73 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	results, err := ReadJSON(bytes.NewReader(writeTestResults(t)))
+	require.NoError(t, err)
+	r := New(results)
+
+	assert.Equal(t, []ThresholdRow{
+		{Metric: "http_req_duration", Source: "p(95)<800", Passed: false},
+		{Metric: "http_req_duration", Source: "avg<600", Passed: true},
+	}, r.Thresholds)
+	assert.Equal(t, []CheckRow{{Group: "::login", Name: "status is 200", Passes: 9, Fails: 1}}, r.Checks)
+	require.Len(t, r.Metrics, 3)
+	assert.Equal(t, "checks", r.Metrics[0].Name)
+	assert.Contains(t, r.Metrics[1].Values, "avg=550ms")
+
+	// VUs first, then the request durations, one bucket per second
+	require.Len(t, r.Charts, 2)
+	assert.Equal(t, "vus", r.Charts[0].Metric.Name)
+	assert.Equal(t, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, r.Charts[0].Lines[0].Values)
+	assert.Equal(t, 10.0, r.Charts[0].Max())
+	assert.Equal(t, "http_req_duration", r.Charts[1].Metric.Name)
+	assert.Equal(t, []string{"avg", "p(95)"}, []string{r.Charts[1].Lines[0].Name, r.Charts[1].Lines[1].Name})
+
+	t.Run("HTML", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, r.WriteHTML(&buf))
+		html := buf.String()
+		assert.Contains(t, html, "ran for 9s")
+		assert.Contains(t, html, "up to 10, every 1s")
+		assert.Contains(t, html, `<td class="failed">✗</td><td>http_req_duration</td><td>p(95)&lt;800</td>`)
+		assert.Contains(t, html, `<td>::login</td><td>status is 200</td><td>9</td><td>1</td>`)
+		assert.Equal(t, 3, strings.Count(html, "<polyline"))
+		assert.NotContains(t, html, "<script")
+	})
+	t.Run("Markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, r.WriteMarkdown(&buf))
+		md := buf.String()
+		assert.Contains(t, md, "| ✗ | http_req_duration | `p(95)<800` |")
+		assert.Contains(t, md, "| ✗ | ::login | status is 200 | 9 | 1 |")
+		assert.Contains(t, md, "| vus | max | 10 | `▁▂▃▃▄▅▅▆▇█` |")
+	})
+}
@@ -0,0 +1,311 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package report rebuilds the metrics of a test run from the samples that a JSON or CSV output
+// stored, and renders them as an HTML or Markdown report, with charts and the outcomes of the
+// thresholds, so that sharing the results of a test doesn't need a metrics stack.
+package report
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	null "gopkg.in/guregu/null.v3"
+)
+
+// The metrics that are charted over time, if the results have them.
+var chartedMetrics = []string{
+	metrics.VUs.Name,
+	metrics.HTTPReqs.Name,
+	metrics.HTTPReqDuration.Name,
+	metrics.IterationDuration.Name,
+}
+
+// Results are the metrics of a test run, rebuilt from its samples.
+type Results struct {
+	Start, End time.Time
+	Metrics    map[string]*stats.Metric
+
+	// The passes and fails of each check, by group path and name.
+	Checks map[string]map[string]*CheckResult
+
+	// The values of the charted metrics over time.
+	series *stats.TimeSeries
+}
+
+// A CheckResult is how many times a check passed and failed.
+type CheckResult struct {
+	Passes, Fails int64
+}
+
+// Duration returns how long the test ran, from its first sample to its last one.
+func (r *Results) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+func newResults() *Results {
+	return &Results{
+		Metrics: make(map[string]*stats.Metric),
+		Checks:  make(map[string]map[string]*CheckResult),
+		series:  stats.NewTimeSeries(chartedMetrics...),
+	}
+}
+
+// ReadFile reads the results from a file that the JSON output wrote, or a CSV file with the
+// metric_name, timestamp and metric_value columns, and a column for each tag; either can be
+// gzipped, with a .gz extension.
+func ReadFile(fs afero.Fs, filename string) (*Results, error) {
+	f, err := fs.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	name := strings.ToLower(filename)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, errors.Wrap(err, filename)
+		}
+		defer func() { _ = gz.Close() }()
+		r, name = gz, strings.TrimSuffix(name, ".gz")
+	}
+
+	var results *Results
+	if strings.HasSuffix(name, ".csv") {
+		results, err = ReadCSV(r)
+	} else {
+		results, err = ReadJSON(r)
+	}
+	return results, errors.Wrap(err, filename)
+}
+
+type jsonEnvelope struct {
+	Type   string          `json:"type"`
+	Metric string          `json:"metric"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type jsonMetric struct {
+	Type       stats.MetricType        `json:"type"`
+	Contains   stats.ValueType         `json:"contains"`
+	Thresholds []stats.ThresholdConfig `json:"thresholds"`
+}
+
+type jsonSample struct {
+	Time  time.Time         `json:"time"`
+	Value float64           `json:"value"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// ReadJSON reads the results from what the JSON output wrote: a Metric envelope for each metric,
+// with its thresholds, before the Point envelopes of its samples.
+func ReadJSON(r io.Reader) (*Results, error) {
+	results := newResults()
+	thresholds := make(map[string][]stats.ThresholdConfig)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var env jsonEnvelope
+		if err := json.Unmarshal(scanner.Bytes(), &env); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		switch env.Type {
+		case "Metric":
+			var data jsonMetric
+			if err := json.Unmarshal(env.Data, &data); err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			if _, ok := results.Metrics[env.Metric]; !ok {
+				results.Metrics[env.Metric] = stats.New(env.Metric, data.Type, data.Contains)
+			}
+			if len(data.Thresholds) > 0 {
+				thresholds[env.Metric] = data.Thresholds
+			}
+		case "Point":
+			m, ok := results.Metrics[env.Metric]
+			if !ok {
+				return nil, errors.Errorf("line %d: a sample of %s, which has no Metric envelope before it", line, env.Metric)
+			}
+			var data jsonSample
+			if err := json.Unmarshal(env.Data, &data); err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+			results.add(stats.Sample{Metric: m, Time: data.Time, Value: data.Value, Tags: stats.IntoSampleTags(&data.Tags)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := results.runThresholds(thresholds); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ReadCSV reads the results from a CSV file with a header, and the metric_name, timestamp (in
+// seconds since the epoch) and metric_value columns; all the other columns are tags. CSV files
+// don't say which type the metrics are, so the built-in metrics have their usual types, and the
+// custom ones are trends, and they have no thresholds.
+func ReadCSV(r io.Reader) (*Results, error) {
+	results := newResults()
+
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read the header")
+	}
+	nameCol, timeCol, valueCol := -1, -1, -1
+	for i, col := range header {
+		switch col {
+		case "metric_name":
+			nameCol = i
+		case "timestamp":
+			timeCol = i
+		case "metric_value":
+			valueCol = i
+		}
+	}
+	if nameCol < 0 || timeCol < 0 || valueCol < 0 {
+		return nil, errors.New("the header doesn't have the metric_name, timestamp and metric_value columns")
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := record[nameCol]
+		m, ok := results.Metrics[name]
+		if !ok {
+			if builtin := metrics.Builtin(name); builtin != nil {
+				m = stats.New(name, builtin.Type, builtin.Contains)
+			} else {
+				m = stats.New(name, stats.Trend)
+			}
+			results.Metrics[name] = m
+		}
+		ts, err := strconv.ParseFloat(record[timeCol], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid timestamp", line)
+		}
+		value, err := strconv.ParseFloat(record[valueCol], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d: invalid value", line)
+		}
+		tags := make(map[string]string)
+		for i, col := range header {
+			if i != nameCol && i != timeCol && i != valueCol && record[i] != "" {
+				tags[col] = record[i]
+			}
+		}
+		sec, frac := math.Modf(ts)
+		results.add(stats.Sample{
+			Metric: m,
+			Time:   time.Unix(int64(sec), int64(frac*1e9)),
+			Value:  value,
+			Tags:   stats.IntoSampleTags(&tags),
+		})
+	}
+	return results, nil
+}
+
+func (r *Results) add(s stats.Sample) {
+	if r.Start.IsZero() || s.Time.Before(r.Start) {
+		r.Start = s.Time
+	}
+	if s.Time.After(r.End) {
+		r.End = s.Time
+	}
+
+	s.Metric.Sink.Add(s)
+	r.series.Add(s)
+
+	if s.Metric.Name == metrics.Checks.Name {
+		name, _ := s.Tags.Get("check")
+		group, _ := s.Tags.Get("group")
+		if r.Checks[group] == nil {
+			r.Checks[group] = make(map[string]*CheckResult)
+		}
+		check, ok := r.Checks[group][name]
+		if !ok {
+			check = &CheckResult{}
+			r.Checks[group][name] = check
+		}
+		if s.Value != 0 {
+			check.Passes++
+		} else {
+			check.Fails++
+		}
+	}
+}
+
+// runThresholds evaluates the thresholds of the metrics, over the whole test; the ones with a
+// window too, since which windows were breached is lost with the order of the samples.
+func (r *Results) runThresholds(configs map[string][]stats.ThresholdConfig) error {
+	t := r.Duration()
+	if t <= 0 {
+		t = time.Second
+	}
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for i := range configs[name] {
+			configs[name][i].Window = 0
+		}
+		ths, err := stats.NewThresholdsWithConfig(configs[name])
+		if err != nil {
+			return errors.Wrapf(err, "the thresholds of %s", name)
+		}
+		m := r.Metrics[name]
+		m.Thresholds = ths
+		m.Thresholds.SetMetrics(r.Metrics, t)
+		succ, err := m.Thresholds.Run(m.Sink, t)
+		if err != nil {
+			return errors.Wrapf(err, "the thresholds of %s", name)
+		}
+		m.Tainted = null.BoolFrom(!succ)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package report
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	jsonout "github.com/loadimpact/k6/stats/json"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestResults writes the results of a 10s test like the JSON output does: 10 requests that
+// took 100-1000ms, a check of each that failed once, and the VUs ramping up.
+func writeTestResults(t *testing.T) []byte {
+	duration := stats.New(metrics.HTTPReqDuration.Name, stats.Trend, stats.Time)
+	var err error
+	duration.Thresholds, err = stats.NewThresholdsWithConfig([]stats.ThresholdConfig{
+		{Threshold: "p(95)<800"},
+		{Threshold: "avg<600", Window: types.Duration(10 * time.Second)},
+	})
+	require.NoError(t, err)
+	checks := stats.New(metrics.Checks.Name, stats.Rate)
+	vus := stats.New(metrics.VUs.Name, stats.Gauge)
+
+	var buf bytes.Buffer
+	write := func(env *jsonout.Envelope) {
+		data, err := json.Marshal(env)
+		require.NoError(t, err)
+		buf.Write(append(data, '\n'))
+	}
+	write(jsonout.WrapMetric(duration))
+	write(jsonout.WrapMetric(checks))
+	write(jsonout.WrapMetric(vus))
+
+	start := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		write(jsonout.WrapSample(&stats.Sample{Metric: duration, Time: now, Value: float64(100 * (i + 1))}))
+		checked := 1.0
+		if i == 3 {
+			checked = 0
+		}
+		write(jsonout.WrapSample(&stats.Sample{
+			Metric: checks, Time: now, Value: checked,
+			Tags: stats.IntoSampleTags(&map[string]string{"check": "status is 200", "group": "::login"}),
+		}))
+		write(jsonout.WrapSample(&stats.Sample{Metric: vus, Time: now, Value: float64(i + 1)}))
+	}
+	return buf.Bytes()
+}
+
+func TestReadJSON(t *testing.T) {
+	results, err := ReadJSON(bytes.NewReader(writeTestResults(t)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9*time.Second, results.Duration())
+	assert.Len(t, results.Metrics, 3)
+	duration := results.Metrics["http_req_duration"]
+	assert.Equal(t, stats.Time, duration.Contains)
+	assert.Equal(t, 550.0, duration.Sink.(*stats.TrendSink).Avg)
+
+	// The p95 is 955ms and the average 550ms, over the whole test, regardless of the window
+	require.Len(t, duration.Thresholds.Thresholds, 2)
+	assert.True(t, duration.Thresholds.Thresholds[0].Failed)
+	assert.False(t, duration.Thresholds.Thresholds[1].Failed)
+	assert.True(t, duration.Tainted.Bool)
+
+	assert.Equal(t, map[string]map[string]*CheckResult{
+		"::login": {"status is 200": {Passes: 9, Fails: 1}},
+	}, results.Checks)
+
+	_, err = ReadJSON(strings.NewReader(`{"type":"Point","metric":"missing","data":{"time":"2018-10-01T12:00:00Z","value":1}}`))
+	assert.EqualError(t, err, "line 1: a sample of missing, which has no Metric envelope before it")
+}
+
+func TestReadCSV(t *testing.T) {
+	results, err := ReadCSV(strings.NewReader(`metric_name,timestamp,metric_value,check,status
+http_req_duration,1538395200,120.5,,200
+http_req_duration,1538395201.5,80,,500
+http_reqs,1538395201.5,1,,500
+my_custom,1538395202,3,,
+checks,1538395202,1,has body,
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2*time.Second, results.Duration())
+	assert.Equal(t, stats.Time, results.Metrics["http_req_duration"].Contains)
+	assert.Equal(t, 100.25, results.Metrics["http_req_duration"].Sink.(*stats.TrendSink).Avg)
+	assert.Equal(t, stats.Counter, results.Metrics["http_reqs"].Type)
+	assert.Equal(t, stats.Trend, results.Metrics["my_custom"].Type)
+	assert.Equal(t, int64(1), results.Checks[""]["has body"].Passes)
+
+	_, err = ReadCSV(strings.NewReader("name,value\nvus,1\n"))
+	assert.EqualError(t, err, "the header doesn't have the metric_name, timestamp and metric_value columns")
+	_, err = ReadCSV(strings.NewReader("metric_name,timestamp,metric_value\nvus,yesterday,1\n"))
+	assert.Error(t, err)
+}
+
+func TestReadFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	data := writeTestResults(t)
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, afero.WriteFile(fs, "/results.json", data, 0644))
+	require.NoError(t, afero.WriteFile(fs, "/results.json.gz", gz.Bytes(), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/results.csv.gz", gz.Bytes(), 0644))
+
+	for _, name := range []string{"/results.json", "/results.json.gz"} {
+		results, err := ReadFile(fs, name)
+		if assert.NoError(t, err, name) {
+			assert.Len(t, results.Metrics, 3, name)
+		}
+	}
+	_, err = ReadFile(fs, "/results.csv.gz")
+	assert.Contains(t, err.Error(), "/results.csv.gz: couldn't read the header")
+	_, err = ReadFile(fs, "/missing.json")
+	assert.Error(t, err)
+}
@@ -23,6 +23,7 @@ package cloud
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"path/filepath"
 	"sync"
 	"time"
@@ -317,14 +318,18 @@ func (c *Collector) aggregateHTTPTrails(waitPeriod time.Duration) {
 				continue
 			}
 
-			connDurations := make(durations, trailCount)
-			reqDurations := make(durations, trailCount)
-			for i, trail := range httpTrails {
-				connDurations[i] = trail.ConnDuration
-				reqDurations[i] = trail.Duration
+			minConnDur, maxConnDur := time.Duration(math.MinInt64), time.Duration(math.MaxInt64)
+			minReqDur, maxReqDur := minConnDur, maxConnDur
+			if !c.config.AggregationSkipOutlierDetection.Bool {
+				connDurations := make(durations, trailCount)
+				reqDurations := make(durations, trailCount)
+				for i, trail := range httpTrails {
+					connDurations[i] = trail.ConnDuration
+					reqDurations[i] = trail.Duration
+				}
+				minConnDur, maxConnDur = connDurations.SelectGetNormalBounds(iqrRadius, iqrLowerCoef, iqrUpperCoef)
+				minReqDur, maxReqDur = reqDurations.SelectGetNormalBounds(iqrRadius, iqrLowerCoef, iqrUpperCoef)
 			}
-			minConnDur, maxConnDur := connDurations.SelectGetNormalBounds(iqrRadius, iqrLowerCoef, iqrUpperCoef)
-			minReqDur, maxReqDur := reqDurations.SelectGetNormalBounds(iqrRadius, iqrLowerCoef, iqrUpperCoef)
 
 			aggrData := &SampleDataAggregatedHTTPReqs{
 				Time: Timestamp(time.Unix(0, bucketID*aggrPeriod+aggrPeriod/2)),
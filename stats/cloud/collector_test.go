@@ -41,6 +41,7 @@ import (
 	"github.com/loadimpact/k6/lib/testutils"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
+	"gopkg.in/guregu/null.v3"
 )
 
 func getSampleChecker(t *testing.T, expSamples <-chan []Sample) http.HandlerFunc {
@@ -260,3 +261,34 @@ func TestCloudCollector(t *testing.T) {
 	cancel()
 	wg.Wait()
 }
+
+func TestCloudCollectorSkipOutlierDetection(t *testing.T) {
+	t.Parallel()
+	collector := &Collector{
+		config: NewConfig().Apply(Config{
+			AggregationPeriod:               types.NullDurationFrom(1 * time.Second),
+			AggregationMinSamples:           null.IntFrom(10),
+			AggregationSkipOutlierDetection: null.BoolFrom(true),
+		}),
+		aggrBuckets: map[int64]aggregationBucket{},
+	}
+
+	now := time.Now()
+	tags := stats.IntoSampleTags(&map[string]string{"name": "test"})
+	for i := 0; i < 10; i++ {
+		trail := &netext.Trail{EndTime: now, Duration: 100 * time.Millisecond, Tags: tags}
+		if i == 9 {
+			trail.Duration = 10 * time.Second
+		}
+		collector.bufferHTTPTrails = append(collector.bufferHTTPTrails, trail)
+	}
+	collector.aggregateHTTPTrails(0)
+
+	require.Len(t, collector.bufferSamples, 1)
+	aggrData, ok := collector.bufferSamples[0].Data.(*SampleDataAggregatedHTTPReqs)
+	require.True(t, ok)
+	assert.Equal(t, uint64(10), aggrData.Count)
+	assert.Equal(t, 10000.0, aggrData.Values.Duration.Max)
+	assert.Equal(t, 100.0, aggrData.Values.Duration.Histogram.Quantile(0.9))
+	assert.Equal(t, 10000.0, aggrData.Values.Duration.Histogram.Quantile(1))
+}
@@ -84,6 +84,11 @@ type Config struct {
 
 	// Connection or request times with how many IQRs above Q3 to consier as non-aggregatable outliers.
 	AggregationOutlierIqrCoefUpper null.Float `json:"aggregationOutlierIqrCoefUpper" envconfig:"CLOUD_AGGREGATION_OUTLIER_IQR_COEF_UPPER"`
+
+	// If enabled, outliers aren't looked for, and all HTTP trails are aggregated. Their times are
+	// still counted in the histograms of the aggregated metrics, so this trades the exact times of
+	// the outliers for sending even less data.
+	AggregationSkipOutlierDetection null.Bool `json:"aggregationSkipOutlierDetection" envconfig:"CLOUD_AGGREGATION_SKIP_OUTLIER_DETECTION"`
 }
 
 // NewConfig creates a new Config instance with default values for some fields.
@@ -151,5 +156,8 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.AggregationOutlierIqrCoefUpper.Valid {
 		c.AggregationOutlierIqrCoefUpper = cfg.AggregationOutlierIqrCoefUpper
 	}
+	if cfg.AggregationSkipOutlierDetection.Valid {
+		c.AggregationSkipOutlierDetection = cfg.AggregationSkipOutlierDetection
+	}
 	return c
 }
@@ -200,10 +200,16 @@ type AggregatedMetric struct {
 	Min float64 `json:"min"`
 	Max float64 `json:"max"`
 	Avg float64 `json:"avg"`
+	// Updated by Add(), so that percentiles can be calculated from the aggregated data
+	Histogram *Histogram `json:"histogram,omitempty"`
 }
 
-// Add the new duration to the internal sum and update Min and Max if necessary
+// Add the new duration to the internal sum and the histogram, and update Min and Max if necessary
 func (am *AggregatedMetric) Add(t time.Duration) {
+	if am.Histogram == nil {
+		am.Histogram = &Histogram{}
+	}
+	am.Histogram.Add(t)
 	if am.sumD == 0 || am.minD > t {
 		am.minD = t
 	}
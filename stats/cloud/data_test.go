@@ -170,6 +170,41 @@ func TestMetricAggregation(t *testing.T) {
 	assert.Equal(t, m.Min, stats.D(1*time.Second))
 	assert.Equal(t, m.Max, stats.D(10*time.Second))
 	assert.Equal(t, m.Avg, stats.D(4*time.Second))
+	assert.Equal(t, &Histogram{
+		Buckets: map[uint64]uint64{1000000: 2, 3000000: 1, 5000000: 1, 10000000: 1},
+		Count:   5,
+	}, m.Histogram)
+}
+
+func TestHistogram(t *testing.T) {
+	buckets := map[time.Duration]uint64{
+		0:                       0,
+		-1 * time.Second:        0,
+		500 * time.Nanosecond:   1,
+		99 * time.Microsecond:   99,
+		100 * time.Microsecond:  100,
+		1234 * time.Microsecond: 1300,
+		995 * time.Millisecond:  1000000,
+		1001 * time.Millisecond: 1100000,
+	}
+	for d, bucket := range buckets {
+		assert.Equal(t, bucket, histogramBucket(d), d.String())
+	}
+
+	h := Histogram{}
+	assert.Equal(t, 0.0, h.Quantile(0.5))
+	for i := 1; i <= 100; i++ {
+		h.Add(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, uint64(100), h.Count)
+	assert.Equal(t, 1.0, h.Quantile(0.01))
+	assert.Equal(t, 50.0, h.Quantile(0.5))
+	assert.Equal(t, 95.0, h.Quantile(0.95))
+	assert.Equal(t, 100.0, h.Quantile(1))
+
+	data, err := json.Marshal(Histogram{Buckets: map[uint64]uint64{1300: 2}, Count: 2})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"buckets":{"1300":2},"count":2}`, string(data))
 }
 
 // For more realistic request time distributions, import
@@ -0,0 +1,84 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cloud
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// Histogram counts the durations of an aggregated metric in buckets, so that percentiles can
+// still be calculated from aggregated data. Buckets are keyed by their upper bound in
+// microseconds, which is the duration rounded up to two significant digits, e.g. 1234µs is
+// counted in the 1300µs bucket; so every duration is off by less than 10%, and there are at most
+// 90 buckets for every order of magnitude that the durations span.
+type Histogram struct {
+	Buckets map[uint64]uint64 `json:"buckets"`
+	Count   uint64            `json:"count"`
+}
+
+// Add counts a duration in its bucket.
+func (h *Histogram) Add(d time.Duration) {
+	if h.Buckets == nil {
+		h.Buckets = make(map[uint64]uint64)
+	}
+	h.Buckets[histogramBucket(d)]++
+	h.Count++
+}
+
+// Quantile returns the upper bound of the bucket of the q-quantile (0 < q <= 1) of the durations,
+// in milliseconds, like the other values of aggregated metrics.
+func (h *Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	bounds := make([]uint64, 0, len(h.Buckets))
+	for bound := range h.Buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	rank := uint64(math.Ceil(q * float64(h.Count)))
+	var seen uint64
+	for _, bound := range bounds {
+		seen += h.Buckets[bound]
+		if seen >= rank {
+			return stats.D(time.Duration(bound) * time.Microsecond)
+		}
+	}
+	return stats.D(time.Duration(bounds[len(bounds)-1]) * time.Microsecond)
+}
+
+// histogramBucket returns the upper bound of a duration's bucket, in microseconds.
+func histogramBucket(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	us := uint64((d + time.Microsecond - 1) / time.Microsecond)
+	scale := uint64(1)
+	for us/scale >= 100 {
+		scale *= 10
+	}
+	return (us + scale - 1) / scale * scale
+}
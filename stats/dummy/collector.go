@@ -30,14 +30,15 @@ import (
 
 // Collector implements the lib.Collector interface and should be used only for testing
 type Collector struct {
-	RunStatus lib.RunStatus
+	RunStatus       lib.RunStatus
+	ThresholdEvents []lib.ThresholdEvent
 
 	SampleContainers []stats.SampleContainer
 	Samples          []stats.Sample
 }
 
-// Verify that Collector implements lib.Collector
-var _ lib.Collector = &Collector{}
+// Verify that Collector implements lib.ThresholdCollector
+var _ lib.ThresholdCollector = &Collector{}
 
 // Init does nothing, it's only included to satisfy the lib.Collector interface
 func (c *Collector) Init() error { return nil }
@@ -78,3 +79,8 @@ func (c *Collector) GetRequiredSystemTags() lib.TagSet {
 func (c *Collector) SetRunStatus(status lib.RunStatus) {
 	c.RunStatus = status
 }
+
+// ThresholdChanged just saves the passed event for later inspection
+func (c *Collector) ThresholdChanged(event lib.ThresholdEvent) {
+	c.ThresholdEvents = append(c.ThresholdEvents, event)
+}
@@ -0,0 +1,363 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package elasticsearch indexes samples and test run metadata into Elasticsearch or OpenSearch,
+// so that load tests can be analyzed with Kibana or OpenSearch Dashboards.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Larger batches are split into several bulk requests.
+const maxBulkSize = 10000
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// Collector indexes samples with the _bulk API every push interval. Every sample is a document:
+//
+//	{"@timestamp": ..., "metric": "http_req_duration", "metric_type": "trend", "value": 12.3,
+//	 "tags": {"method": "GET", ...}, "test_run_id": "..."}
+//
+// The test run also gets a document in the metadata index, with its script, options, start
+// and end times, and status.
+type Collector struct {
+	Config Config
+
+	client   *http.Client
+	template []byte
+	metadata testMetadata
+
+	buffer     []stats.Sample
+	bufferLock sync.Mutex
+	status     lib.RunStatus
+	statusLock sync.Mutex
+}
+
+type sampleDoc struct {
+	Timestamp  time.Time         `json:"@timestamp"`
+	Metric     string            `json:"metric"`
+	MetricType stats.MetricType  `json:"metric_type"`
+	Value      float64           `json:"value"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	TestRunID  string            `json:"test_run_id"`
+}
+
+type testMetadata struct {
+	TestRunID string     `json:"test_run_id"`
+	Script    string     `json:"script"`
+	Version   string     `json:"k6_version"`
+	Options   string     `json:"options"`
+	Started   time.Time  `json:"@timestamp"`
+	Ended     *time.Time `json:"ended,omitempty"`
+	Status    string     `json:"status"`
+}
+
+var runStatuses = map[lib.RunStatus]string{
+	lib.RunStatusRunning:            "running",
+	lib.RunStatusFinished:           "finished",
+	lib.RunStatusTimedOut:           "timed_out",
+	lib.RunStatusAbortedUser:        "aborted_user",
+	lib.RunStatusAbortedSystem:      "aborted_system",
+	lib.RunStatusAbortedScriptError: "aborted_script_error",
+	lib.RunStatusAbortedThreshold:   "aborted_threshold",
+}
+
+// New creates a new Elasticsearch collector.
+func New(conf Config, src *lib.SourceData, opts lib.Options, version string) (*Collector, error) {
+	if !conf.TestRunID.Valid || conf.TestRunID.String == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		conf.TestRunID.SetValid(hex.EncodeToString(id))
+	}
+	if time.Duration(conf.PushInterval.Duration) <= 0 {
+		return nil, errors.New("the Elasticsearch push interval has to be positive")
+	}
+
+	template := defaultTemplate(conf.Index.String)
+	if conf.TemplateFile.String != "" {
+		data, err := ioutil.ReadFile(conf.TemplateFile.String)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't read the Elasticsearch index template")
+		}
+		if !json.Valid(data) {
+			return nil, errors.Errorf("the Elasticsearch index template in '%s' isn't valid JSON", conf.TemplateFile.String)
+		}
+		template = data
+	}
+
+	options, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	script := ""
+	if src != nil {
+		script = filepath.Base(src.Filename)
+	}
+
+	return &Collector{
+		Config: conf,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: conf.Insecure.Bool},
+			},
+		},
+		template: template,
+		metadata: testMetadata{
+			TestRunID: conf.TestRunID.String,
+			Script:    script,
+			Version:   version,
+			Options:   string(options),
+			Status:    runStatuses[lib.RunStatusRunning],
+		},
+		status: lib.RunStatusFinished,
+	}, nil
+}
+
+// defaultTemplate returns an index template with mappings for the sample documents; all tags
+// are keywords, so that they can be used in aggregations.
+func defaultTemplate(index string) []byte {
+	template, _ := json.Marshal(map[string]interface{}{
+		"index_patterns": []string{index + "*"},
+		"template": map[string]interface{}{
+			"mappings": map[string]interface{}{
+				"dynamic_templates": []interface{}{
+					map[string]interface{}{"tags": map[string]interface{}{
+						"path_match": "tags.*",
+						"mapping":    map[string]string{"type": "keyword"},
+					}},
+				},
+				"properties": map[string]interface{}{
+					"@timestamp":  map[string]string{"type": "date"},
+					"metric":      map[string]string{"type": "keyword"},
+					"metric_type": map[string]string{"type": "keyword"},
+					"value":       map[string]string{"type": "double"},
+					"test_run_id": map[string]string{"type": "keyword"},
+				},
+			},
+		},
+	})
+	return template
+}
+
+// Init installs the index template, and indexes the test run's metadata.
+func (c *Collector) Init() error {
+	err := c.request("PUT", "/_index_template/"+c.Config.TemplateName.String, "application/json", c.template, nil)
+	if err != nil {
+		return errors.Wrap(err, "couldn't install the Elasticsearch index template")
+	}
+	c.metadata.Started = time.Now()
+	return c.indexMetadata()
+}
+
+// Run indexes the buffered samples every push interval, until the context is done; then it
+// updates the test run's metadata with the end time and status.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("test_run_id", c.Config.TestRunID.String).Debug("Elasticsearch: Running!")
+	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.commit()
+		case <-ctx.Done():
+			c.commit()
+
+			c.statusLock.Lock()
+			c.metadata.Status = runStatuses[c.status]
+			c.statusLock.Unlock()
+			ended := time.Now()
+			c.metadata.Ended = &ended
+			if err := c.indexMetadata(); err != nil {
+				log.WithError(err).Error("Elasticsearch: Couldn't update the test run metadata")
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until they're indexed.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+// Link returns the URL of the cluster.
+func (c *Collector) Link() string {
+	return c.Config.URL.String
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus saves the status, which is indexed in the metadata at the end of the test.
+func (c *Collector) SetRunStatus(status lib.RunStatus) {
+	c.statusLock.Lock()
+	c.status = status
+	c.statusLock.Unlock()
+}
+
+func (c *Collector) indexMetadata() error {
+	doc, err := json.Marshal(c.metadata)
+	if err != nil {
+		return err
+	}
+	return c.request("PUT", "/"+c.Config.MetadataIndex.String+"/_doc/"+c.metadata.TestRunID, "application/json", doc, nil)
+}
+
+func (c *Collector) commit() {
+	c.bufferLock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+
+	for len(samples) > 0 {
+		batch := samples
+		if len(batch) > maxBulkSize {
+			batch = batch[:maxBulkSize]
+		}
+		samples = samples[len(batch):]
+
+		log.WithField("samples", len(batch)).Debug("Elasticsearch: Indexing...")
+		startTime := time.Now()
+		if err := c.bulk(batch); err != nil {
+			log.WithError(err).Error("Elasticsearch: Couldn't index the samples")
+			continue
+		}
+		log.WithField("t", time.Since(startTime)).Debug("Elasticsearch: Samples indexed!")
+	}
+}
+
+func (c *Collector) bulk(samples []stats.Sample) error {
+	action, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": c.Config.Index.String}})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, s := range samples {
+		doc := sampleDoc{
+			Timestamp:  s.Time,
+			Metric:     s.Metric.Name,
+			MetricType: s.Metric.Type,
+			Value:      s.Value,
+			TestRunID:  c.Config.TestRunID.String,
+		}
+		if s.Tags != nil {
+			doc.Tags = s.Tags.CloneTags()
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		if err := encoder.Encode(doc); err != nil { // Encode adds the newline
+			return err
+		}
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := c.request("POST", "/_bulk", "application/x-ndjson", body.Bytes(), &result); err != nil {
+		return err
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	failed, reason := 0, ""
+	for _, item := range result.Items {
+		for _, res := range item {
+			if res.Error != nil {
+				if failed == 0 {
+					reason = res.Error.Type + ": " + res.Error.Reason
+				}
+				failed++
+			}
+		}
+	}
+	return errors.Errorf("%d of %d samples weren't indexed, e.g. because of %s", failed, len(samples), reason)
+}
+
+// Ping checks that Elasticsearch can be reached, with the config's credentials.
+func (c *Collector) Ping() error {
+	return c.request(http.MethodGet, "/", "application/json", nil, nil)
+}
+
+func (c *Collector) request(method, path, contentType string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.Config.URL.String, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	switch {
+	case c.Config.APIKey.String != "":
+		req.Header.Set("Authorization", "ApiKey "+c.Config.APIKey.String)
+	case c.Config.Username.String != "":
+		req.SetBasicAuth(c.Config.Username.String, c.Config.Password.String)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	if result == nil {
+		_, _ = io.Copy(ioutil.Discard, res.Body)
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
@@ -0,0 +1,214 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+type fakeCluster struct {
+	*httptest.Server
+
+	mutex     sync.Mutex
+	templates map[string]map[string]interface{}
+	docs      map[string]map[string]interface{}
+	indexed   []map[string]interface{}
+	rejected  bool
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	c := &fakeCluster{
+		templates: make(map[string]map[string]interface{}),
+		docs:      make(map[string]map[string]interface{}),
+	}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "elastic" || password != "secret" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/_bulk":
+			assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+			var items []string
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				var action map[string]map[string]string
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+				assert.Equal(t, "k6-metrics", action["index"]["_index"])
+				require.True(t, scanner.Scan())
+				var doc map[string]interface{}
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+				c.indexed = append(c.indexed, doc)
+				if c.rejected {
+					items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [value]"}}}`)
+				} else {
+					items = append(items, `{"index":{"status":201}}`)
+				}
+			}
+			_, _ = w.Write([]byte(`{"errors":` + map[bool]string{true: "true", false: "false"}[c.rejected] + `,"items":[` + strings.Join(items, ",") + `]}`))
+		case r.Method == "PUT":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			if filepath.Dir(r.URL.Path) == "/_index_template" {
+				c.templates[filepath.Base(r.URL.Path)] = body
+			} else {
+				c.docs[r.URL.Path] = body
+			}
+			_, _ = w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == "GET" && r.URL.Path == "/":
+			_, _ = w.Write([]byte(`{"version":{"number":"8.0.0"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return c
+}
+
+func TestCollector(t *testing.T) {
+	cluster := newFakeCluster(t)
+	defer cluster.Close()
+
+	config := NewConfig().Apply(Config{
+		URL:          null.StringFrom(cluster.URL),
+		Username:     null.StringFrom("elastic"),
+		Password:     null.StringFrom("secret"),
+		PushInterval: types.NullDurationFrom(10 * time.Millisecond),
+		TestRunID:    null.StringFrom("run1"),
+	})
+	src := &lib.SourceData{Filename: "/path/to/script.js"}
+	c, err := New(config, src, lib.Options{VUs: null.IntFrom(10)}, "0.22.0")
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	template := cluster.templates["k6-metrics"]
+	require.NotNil(t, template)
+	assert.Equal(t, []interface{}{"k6-metrics*"}, template["index_patterns"])
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	now := time.Date(2018, 10, 1, 12, 30, 15, 0, time.UTC)
+	c.Collect([]stats.SampleContainer{stats.Sample{
+		Metric: stats.New("my_metric", stats.Trend),
+		Time:   now,
+		Value:  1.5,
+		Tags:   stats.IntoSampleTags(&map[string]string{"method": "GET"}),
+	}})
+	time.Sleep(50 * time.Millisecond)
+	c.SetRunStatus(lib.RunStatusAbortedThreshold)
+	cancel()
+	<-done
+
+	cluster.mutex.Lock()
+	defer cluster.mutex.Unlock()
+	assert.Equal(t, []map[string]interface{}{{
+		"@timestamp":  "2018-10-01T12:30:15Z",
+		"metric":      "my_metric",
+		"metric_type": "trend",
+		"value":       1.5,
+		"tags":        map[string]interface{}{"method": "GET"},
+		"test_run_id": "run1",
+	}}, cluster.indexed)
+
+	metadata := cluster.docs["/k6-tests/_doc/run1"]
+	require.NotNil(t, metadata)
+	assert.Equal(t, "script.js", metadata["script"])
+	assert.Equal(t, "0.22.0", metadata["k6_version"])
+	assert.Equal(t, "aborted_threshold", metadata["status"])
+	assert.NotEmpty(t, metadata["ended"])
+	assert.Contains(t, metadata["options"], `"vus":10`)
+}
+
+func TestCollectorErrors(t *testing.T) {
+	cluster := newFakeCluster(t)
+	defer cluster.Close()
+
+	config := NewConfig().Apply(Config{
+		URL:      null.StringFrom(cluster.URL),
+		Username: null.StringFrom("elastic"),
+		Password: null.StringFrom("secret"),
+	})
+
+	t.Run("rejected documents", func(t *testing.T) {
+		c, err := New(config, nil, lib.Options{}, "0.22.0")
+		require.NoError(t, err)
+		cluster.rejected = true
+		err = c.bulk([]stats.Sample{
+			{Metric: stats.New("my_metric", stats.Counter), Time: time.Now(), Value: 1},
+			{Metric: stats.New("my_metric", stats.Counter), Time: time.Now(), Value: 2},
+		})
+		assert.EqualError(t, err, "2 of 2 samples weren't indexed, e.g. because of mapper_parsing_exception: failed to parse field [value]")
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		c, err := New(config.Apply(Config{Password: null.StringFrom("wrong")}), nil, lib.Options{}, "0.22.0")
+		require.NoError(t, err)
+		assert.EqualError(t, c.Init(), `couldn't install the Elasticsearch index template: 401 Unauthorized: {"error":"unauthorized"}`)
+		assert.EqualError(t, c.Ping(), `401 Unauthorized: {"error":"unauthorized"}`)
+
+		c, err = New(config, nil, lib.Options{}, "0.22.0")
+		require.NoError(t, err)
+		assert.NoError(t, c.Ping())
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "k6-elasticsearch")
+		require.NoError(t, err)
+		defer func() { _ = os.RemoveAll(dir) }()
+
+		path := filepath.Join(dir, "template.json")
+		require.NoError(t, ioutil.WriteFile(path, []byte(`{"index_patterns":["custom-*"]}`), 0644))
+		c, err := New(config.Apply(Config{TemplateName: null.StringFrom("custom"), TemplateFile: null.StringFrom(path)}), nil, lib.Options{}, "0.22.0")
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+		assert.Equal(t, []interface{}{"custom-*"}, cluster.templates["custom"]["index_patterns"])
+
+		require.NoError(t, ioutil.WriteFile(path, []byte(`{"index_patterns":`), 0644))
+		_, err = New(config.Apply(Config{TemplateFile: null.StringFrom(path)}), nil, lib.Options{}, "0.22.0")
+		assert.EqualError(t, err, "the Elasticsearch index template in '"+path+"' isn't valid JSON")
+	})
+}
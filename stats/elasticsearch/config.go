@@ -0,0 +1,131 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package elasticsearch
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Config is the config for the Elasticsearch collector.
+type Config struct {
+	// Connection; either the username and password, or an API key can be used.
+	URL      null.String `json:"url" envconfig:"ELASTICSEARCH_URL"`
+	Username null.String `json:"username,omitempty" envconfig:"ELASTICSEARCH_USERNAME"`
+	Password null.String `json:"password,omitempty" envconfig:"ELASTICSEARCH_PASSWORD"`
+	APIKey   null.String `json:"apiKey,omitempty" envconfig:"ELASTICSEARCH_API_KEY"`
+	Insecure null.Bool   `json:"insecure,omitempty" envconfig:"ELASTICSEARCH_INSECURE"`
+
+	// The index that samples are indexed into, and the one that gets a document per test run.
+	Index         null.String `json:"index" envconfig:"ELASTICSEARCH_INDEX"`
+	MetadataIndex null.String `json:"metadataIndex" envconfig:"ELASTICSEARCH_METADATA_INDEX"`
+
+	// The index template that's installed for the samples index. It's a default one with
+	// mappings for k6's documents, unless TemplateFile has a custom one, in the JSON format of
+	// the _index_template API.
+	TemplateName null.String `json:"templateName" envconfig:"ELASTICSEARCH_TEMPLATE_NAME"`
+	TemplateFile null.String `json:"templateFile,omitempty" envconfig:"ELASTICSEARCH_TEMPLATE_FILE"`
+
+	// How often the buffered samples are indexed, with the _bulk API.
+	PushInterval types.NullDuration `json:"pushInterval" envconfig:"ELASTICSEARCH_PUSH_INTERVAL"`
+
+	// Added to every document, to tell test runs apart; random by default.
+	TestRunID null.String `json:"testRunID" envconfig:"ELASTICSEARCH_TEST_RUN_ID"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		URL:           null.NewString("http://localhost:9200", false),
+		Index:         null.NewString("k6-metrics", false),
+		MetadataIndex: null.NewString("k6-tests", false),
+		TemplateName:  null.NewString("k6-metrics", false),
+		PushInterval:  types.NewNullDuration(1*time.Second, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if cfg.Username.Valid {
+		c.Username = cfg.Username
+	}
+	if cfg.Password.Valid {
+		c.Password = cfg.Password
+	}
+	if cfg.APIKey.Valid {
+		c.APIKey = cfg.APIKey
+	}
+	if cfg.Insecure.Valid {
+		c.Insecure = cfg.Insecure
+	}
+	if cfg.Index.Valid {
+		c.Index = cfg.Index
+	}
+	if cfg.MetadataIndex.Valid {
+		c.MetadataIndex = cfg.MetadataIndex
+	}
+	if cfg.TemplateName.Valid {
+		c.TemplateName = cfg.TemplateName
+	}
+	if cfg.TemplateFile.Valid {
+		c.TemplateFile = cfg.TemplateFile
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.TestRunID.Valid {
+		c.TestRunID = cfg.TestRunID
+	}
+	return c
+}
+
+// ParseArg takes the argument of `--out elasticsearch=...`, which is the URL of the cluster; the
+// username and password can be a part of it.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if arg == "" {
+		return c, nil
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil {
+		return c, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return c, errors.New("invalid Elasticsearch URL, it has to be an http:// or https:// URL")
+	}
+	if u.User != nil {
+		c.Username = null.StringFrom(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			c.Password = null.StringFrom(password)
+		}
+		u.User = nil
+	}
+	c.URL = null.StringFrom(u.String())
+	return c, nil
+}
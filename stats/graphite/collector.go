@@ -0,0 +1,319 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+
+	// The most datapoints in a pickled message; carbon rejects messages bigger than 1MB.
+	pickleBatchSize = 1000
+)
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// Collector sends metrics to carbon, Graphite's daemon. Graphite keeps a single value for each
+// series and period, so instead of the samples, it sends their aggregates over each push
+// interval, the same values that thresholds have, e.g. k6.http_req_duration.p95;status=200.
+type Collector struct {
+	Config Config
+
+	conn net.Conn
+
+	buffer     []stats.Sample
+	bufferLock sync.Mutex
+}
+
+// A datapoint of a series, at a time.
+type datapoint struct {
+	path  string
+	value float64
+	time  int64
+}
+
+// New creates a new Graphite collector; it doesn't connect until it's initialized.
+func New(conf Config) (*Collector, error) {
+	if conf.Protocol.String != protocolPlaintext && conf.Protocol.String != protocolPickle {
+		return nil, errors.Errorf("invalid Graphite protocol '%s', it has to be plaintext or pickle", conf.Protocol.String)
+	}
+	if _, _, err := net.SplitHostPort(conf.Addr.String); err != nil {
+		return nil, errors.Wrap(err, "invalid Graphite address")
+	}
+	if conf.PushInterval.Duration <= 0 {
+		return nil, errors.New("the Graphite push interval has to be positive")
+	}
+	return &Collector{Config: conf}, nil
+}
+
+// Init connects to carbon, so that a wrong address is an error before the test starts.
+func (c *Collector) Init() error {
+	conn, err := net.DialTimeout("tcp", c.Config.Addr.String, dialTimeout)
+	if err != nil {
+		return errors.Wrap(err, "couldn't connect to Graphite")
+	}
+	c.conn = conn
+	return nil
+}
+
+// Run sends the aggregates of the buffered samples every push interval, until the context is done.
+func (c *Collector) Run(ctx context.Context) {
+	log.Debug("Graphite: Running!")
+	interval := time.Duration(c.Config.PushInterval.Duration)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.commit(now, interval)
+		case <-ctx.Done():
+			c.commit(time.Now(), interval)
+			if c.conn != nil {
+				_ = c.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until they're aggregated.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+// Link returns the address of carbon.
+func (c *Collector) Link() string {
+	return c.Config.Protocol.String + "://" + c.Config.Addr.String
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus does nothing in the Graphite collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+func (c *Collector) commit(now time.Time, interval time.Duration) {
+	c.bufferLock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+
+	points := c.aggregate(samples, now, interval)
+	if len(points) == 0 {
+		return
+	}
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout("tcp", c.Config.Addr.String, dialTimeout)
+		if err != nil {
+			log.WithError(err).WithField("points", len(points)).Error("Graphite: Couldn't reconnect, the points are dropped")
+			return
+		}
+		c.conn = conn
+	}
+
+	log.WithField("points", len(points)).Debug("Graphite: Sending...")
+	var messages [][]byte
+	if c.Config.Protocol.String == protocolPickle {
+		for len(points) > 0 {
+			n := len(points)
+			if n > pickleBatchSize {
+				n = pickleBatchSize
+			}
+			messages = append(messages, formatPickle(points[:n]))
+			points = points[n:]
+		}
+	} else {
+		messages = [][]byte{formatPlaintext(points)}
+	}
+	for _, msg := range messages {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := c.conn.Write(msg); err != nil {
+			log.WithError(err).Error("Graphite: Couldn't send the points")
+			// The connection is broken, so a new one is made for the next interval
+			_ = c.conn.Close()
+			c.conn = nil
+			return
+		}
+	}
+}
+
+// aggregate returns the datapoints of the samples' series, sorted by their paths.
+func (c *Collector) aggregate(samples []stats.Sample, now time.Time, interval time.Duration) []datapoint {
+	sinks := make(map[string]stats.Sink)
+	tagCache := make(map[*stats.SampleTags]string)
+	for _, s := range samples {
+		tags, ok := tagCache[s.Tags]
+		if !ok {
+			tags = c.formatTags(s.Tags)
+			tagCache[s.Tags] = tags
+		}
+		key := sanitizePath(c.Config.Prefix.String+s.Metric.Name) + "\x00" + tags
+		sink, ok := sinks[key]
+		if !ok {
+			sink = stats.New(s.Metric.Name, s.Metric.Type).Sink
+			sinks[key] = sink
+		}
+		sink.Add(s)
+	}
+
+	points := make([]datapoint, 0, len(sinks)*2)
+	for key, sink := range sinks {
+		i := strings.IndexByte(key, 0)
+		name, tags := key[:i], key[i+1:]
+		for stat, value := range sink.Format(interval) {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+			stat = strings.NewReplacer("(", "", ")", "", ".", "_").Replace(stat)
+			points = append(points, datapoint{name + "." + stat + tags, value, now.Unix()})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].path < points[j].path })
+	return points
+}
+
+// formatTags returns the ;name=value tags of a series, sorted by name, or nothing if tags are off.
+func (c *Collector) formatTags(tags *stats.SampleTags) string {
+	if !c.Config.Tags.Bool || tags == nil {
+		return ""
+	}
+	all := tags.CloneTags()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		value := strings.Map(tagValueChar, all[name])
+		if value == "" || isExcluded(name, c.Config.ExcludedTags) {
+			continue // Graphite doesn't allow empty tag values
+		}
+		b.WriteString(";" + strings.Map(tagNameChar, name) + "=" + value)
+	}
+	return b.String()
+}
+
+func isExcluded(name string, excluded []string) bool {
+	for _, e := range excluded {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Graphite's tag names can't have any of ;!^=, and tag values can't have ; or start with ~.
+func tagNameChar(r rune) rune {
+	switch r {
+	case ';', '!', '^', '=', ' ':
+		return '_'
+	}
+	return r
+}
+
+func tagValueChar(r rune) rune {
+	switch r {
+	case ';', '~', ' ':
+		return '_'
+	}
+	return r
+}
+
+// sanitizePath replaces what would break the plaintext protocol, or a tagged path, in a path.
+func sanitizePath(path string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', ';':
+			return '_'
+		}
+		return r
+	}, path)
+}
+
+// formatPlaintext formats datapoints in the plaintext protocol, a `path value timestamp` line each.
+func formatPlaintext(points []datapoint) []byte {
+	var b bytes.Buffer
+	for _, p := range points {
+		b.WriteString(p.path)
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(p.value, 'f', -1, 64))
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(p.time, 10))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// formatPickle formats datapoints in the pickle protocol: a pickled list of
+// (path, (timestamp, value)) tuples, after its length as a 4 byte big-endian integer.
+func formatPickle(points []datapoint) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0x80, 2}) // PROTO 2
+	b.WriteByte(']')         // EMPTY_LIST
+	b.WriteByte('(')         // MARK
+	var buf [8]byte
+	for _, p := range points {
+		b.WriteByte('X') // BINUNICODE
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(p.path)))
+		b.Write(buf[:4])
+		b.WriteString(p.path)
+		b.WriteByte('J') // BININT
+		binary.LittleEndian.PutUint32(buf[:4], uint32(int32(p.time)))
+		b.Write(buf[:4])
+		b.WriteByte('G') // BINFLOAT
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(p.value))
+		b.Write(buf[:])
+		b.Write([]byte{0x86, 0x86}) // TUPLE2, TUPLE2
+	}
+	b.WriteByte('e') // APPENDS
+	b.WriteByte('.') // STOP
+
+	msg := make([]byte, 4, 4+b.Len())
+	binary.BigEndian.PutUint32(msg, uint32(b.Len()))
+	return append(msg, b.Bytes()...)
+}
@@ -0,0 +1,137 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func testSamples(now time.Time) stats.Samples {
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	reqs := stats.New("http_reqs", stats.Counter)
+	ok := stats.NewSampleTags(map[string]string{"status": "200", "vu": "1", "name": "http://example.com/a b"})
+	failed := stats.NewSampleTags(map[string]string{"status": "500", "vu": "2", "name": "http://example.com/a b"})
+	return stats.Samples{
+		{Metric: duration, Time: now, Tags: ok, Value: 100},
+		{Metric: duration, Time: now, Tags: ok, Value: 300},
+		{Metric: duration, Time: now, Tags: failed, Value: 50},
+		{Metric: reqs, Time: now, Tags: ok, Value: 1},
+		{Metric: reqs, Time: now, Tags: ok, Value: 1},
+		{Metric: reqs, Time: now, Tags: failed, Value: 1},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	now := time.Unix(1550000000, 0)
+	c, err := New(NewConfig())
+	require.NoError(t, err)
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(formatPlaintext(c.aggregate(testSamples(now), now, 2*time.Second))))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{
+		"k6.http_req_duration.avg;name=http://example.com/a_b;status=200 200 1550000000",
+		"k6.http_req_duration.avg;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_req_duration.max;name=http://example.com/a_b;status=200 300 1550000000",
+		"k6.http_req_duration.max;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_req_duration.med;name=http://example.com/a_b;status=200 200 1550000000",
+		"k6.http_req_duration.med;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_req_duration.min;name=http://example.com/a_b;status=200 100 1550000000",
+		"k6.http_req_duration.min;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_req_duration.p90;name=http://example.com/a_b;status=200 280 1550000000",
+		"k6.http_req_duration.p90;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_req_duration.p95;name=http://example.com/a_b;status=200 290 1550000000",
+		"k6.http_req_duration.p95;name=http://example.com/a_b;status=500 50 1550000000",
+		"k6.http_reqs.count;name=http://example.com/a_b;status=200 2 1550000000",
+		"k6.http_reqs.count;name=http://example.com/a_b;status=500 1 1550000000",
+		"k6.http_reqs.rate;name=http://example.com/a_b;status=200 1 1550000000",
+		"k6.http_reqs.rate;name=http://example.com/a_b;status=500 0.5 1550000000",
+	}, lines)
+
+	t.Run("Without Tags", func(t *testing.T) {
+		c, err := New(NewConfig().Apply(Config{Tags: null.BoolFrom(false), Prefix: null.StringFrom("")}))
+		require.NoError(t, err)
+		points := c.aggregate(testSamples(now), now, 2*time.Second)
+		assert.Equal(t, []datapoint{{"http_reqs.count", 3, 1550000000}, {"http_reqs.rate", 1.5, 1550000000}}, points[len(points)-2:])
+	})
+}
+
+func TestCollector(t *testing.T) {
+	for _, protocol := range []string{"plaintext", "pickle"} {
+		protocol := protocol
+		t.Run(protocol, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer func() { _ = listener.Close() }()
+			received := make(chan []byte, 1)
+			go func() {
+				conn, err := listener.Accept()
+				if !assert.NoError(t, err) {
+					return
+				}
+				data, _ := ioutil.ReadAll(conn)
+				received <- data
+			}()
+
+			c, err := New(NewConfig().Apply(Config{
+				Addr:         null.StringFrom(listener.Addr().String()),
+				Protocol:     null.StringFrom(protocol),
+				PushInterval: types.NullDurationFrom(time.Hour),
+			}))
+			require.NoError(t, err)
+			require.NoError(t, c.Init())
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.Run(ctx)
+				close(done)
+			}()
+			c.Collect([]stats.SampleContainer{testSamples(time.Now())})
+			cancel()
+			<-done
+
+			data := <-received
+			if protocol == "pickle" {
+				require.True(t, len(data) > 4)
+				assert.Equal(t, len(data)-4, int(binary.BigEndian.Uint32(data)))
+				assert.Equal(t, []byte{0x80, 2, ']', '('}, data[4:8])
+				assert.Contains(t, string(data), "k6.http_reqs.count;name=http://example.com/a_b;status=500")
+			} else {
+				assert.Contains(t, string(data), "k6.http_reqs.count;name=http://example.com/a_b;status=500 1 ")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+)
+
+const (
+	protocolPlaintext = "plaintext"
+	protocolPickle    = "pickle"
+)
+
+// Config is the config for the Graphite collector.
+type Config struct {
+	// The host:port of carbon's plaintext receiver, usually :2003, or pickle receiver, usually :2004.
+	Addr     null.String `json:"addr" envconfig:"GRAPHITE_ADDR"`
+	Protocol null.String `json:"protocol" envconfig:"GRAPHITE_PROTOCOL"`
+
+	// The prefix of all metric paths, e.g. "k6." for k6.http_req_duration.p95
+	Prefix null.String `json:"prefix" envconfig:"GRAPHITE_PREFIX"`
+
+	// Whether the samples' tags are sent as the tags of Graphite 1.1 tagged series, except for
+	// the excluded tags; without tags, the series of all samples of a metric are the same.
+	Tags         null.Bool `json:"tags" envconfig:"GRAPHITE_TAGS"`
+	ExcludedTags []string  `json:"excludedTags" envconfig:"GRAPHITE_EXCLUDED_TAGS"`
+
+	// How often the samples are aggregated and sent; it should match the resolution of the
+	// metrics' storage schema, since carbon keeps only one value per series for each period.
+	PushInterval types.NullDuration `json:"pushInterval" envconfig:"GRAPHITE_PUSH_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Addr:         null.NewString("localhost:2003", false),
+		Protocol:     null.NewString(protocolPlaintext, false),
+		Prefix:       null.NewString("k6.", false),
+		Tags:         null.NewBool(true, false),
+		ExcludedTags: []string{"vu", "iter", "url"},
+		PushInterval: types.NewNullDuration(10*time.Second, false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Addr.Valid {
+		c.Addr = cfg.Addr
+	}
+	if cfg.Protocol.Valid {
+		c.Protocol = cfg.Protocol
+	}
+	if cfg.Prefix.Valid {
+		c.Prefix = cfg.Prefix
+	}
+	if cfg.Tags.Valid {
+		c.Tags = cfg.Tags
+	}
+	if len(cfg.ExcludedTags) > 0 {
+		c.ExcludedTags = cfg.ExcludedTags
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	return c
+}
+
+// ParseArg takes the argument of `--out graphite=...`, which is the address of carbon, as
+// host:port or as a URL, e.g. pickle://graphite.example.com:2004?prefix=loadtests.&tags=false.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if arg == "" {
+		return c, nil
+	}
+	if !strings.Contains(arg, "://") {
+		c.Addr = null.StringFrom(arg)
+		return c, nil
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil {
+		return c, err
+	}
+	switch u.Scheme {
+	case "tcp", protocolPlaintext:
+		c.Protocol = null.StringFrom(protocolPlaintext)
+	case protocolPickle:
+		c.Protocol = null.StringFrom(protocolPickle)
+	default:
+		return c, errors.Errorf("invalid Graphite URL '%s', it has to start with tcp://, plaintext:// or pickle://", arg)
+	}
+	c.Addr = null.StringFrom(u.Host)
+	for k, vs := range u.Query() {
+		switch k {
+		case "prefix":
+			c.Prefix = null.StringFrom(vs[0])
+		case "tags":
+			tags, err := strconv.ParseBool(vs[0])
+			if err != nil {
+				return c, errors.Errorf("tags must be true or false, not %s", vs[0])
+			}
+			c.Tags = null.BoolFrom(tags)
+		case "pushInterval":
+			var d types.Duration
+			if err := d.UnmarshalText([]byte(vs[0])); err != nil {
+				return c, err
+			}
+			c.PushInterval = types.NullDurationFrom(time.Duration(d))
+		default:
+			return c, errors.Errorf("unknown query parameter: %s", k)
+		}
+	}
+	return c, nil
+}
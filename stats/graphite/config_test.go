@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package graphite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestParseArg(t *testing.T) {
+	testdata := map[string]Config{
+		"":                          {},
+		"graphite.example.com:2003": {Addr: null.StringFrom("graphite.example.com:2003")},
+		"tcp://localhost:2003":      {Addr: null.StringFrom("localhost:2003"), Protocol: null.StringFrom("plaintext")},
+		"pickle://localhost:2004?prefix=loadtests.&tags=false&pushInterval=1m": {
+			Addr:         null.StringFrom("localhost:2004"),
+			Protocol:     null.StringFrom("pickle"),
+			Prefix:       null.StringFrom("loadtests."),
+			Tags:         null.BoolFrom(false),
+			PushInterval: types.NullDurationFrom(time.Minute),
+		},
+	}
+	for arg, expected := range testdata {
+		t.Run(arg, func(t *testing.T) {
+			c, err := ParseArg(arg)
+			require.NoError(t, err)
+			assert.Equal(t, expected, c)
+		})
+	}
+
+	errors := map[string]string{
+		"udp://localhost:2003":            "invalid Graphite URL 'udp://localhost:2003', it has to start with tcp://, plaintext:// or pickle://",
+		"tcp://localhost:2003?tags=maybe": "tags must be true or false, not maybe",
+		"tcp://localhost:2003?db=k6":      "unknown query parameter: db",
+	}
+	for arg, msg := range errors {
+		_, err := ParseArg(arg)
+		assert.EqualError(t, err, msg, arg)
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	defer os.Clearenv()
+	os.Clearenv()
+	require.NoError(t, os.Setenv("K6_GRAPHITE_PREFIX", "perf."))
+	require.NoError(t, os.Setenv("K6_GRAPHITE_EXCLUDED_TAGS", "vu,iter,url,name"))
+
+	c := NewConfig()
+	require.NoError(t, envconfig.Process("k6", &c))
+	assert.Equal(t, null.StringFrom("perf."), c.Prefix)
+	assert.Equal(t, []string{"vu", "iter", "url", "name"}, c.ExcludedTags)
+	assert.Equal(t, "localhost:2003", c.Addr.String)
+	assert.Equal(t, "plaintext", c.Protocol.String)
+}
@@ -0,0 +1,111 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// The relative accuracy of a histogram: every value it counts is estimated to within this
+// fraction of itself, however large or small it is.
+const histogramAccuracy = 0.005
+
+var (
+	histogramGamma    = (1 + histogramAccuracy) / (1 - histogramAccuracy)
+	histogramLogGamma = math.Log(histogramGamma)
+)
+
+// A histogram counts values in buckets whose bounds grow exponentially, like HDR histograms, so
+// its size only depends on how many orders of magnitude the values span, not on how many there
+// are: a few thousand buckets cover everything from microseconds to hours.
+type histogram struct {
+	positive, negative map[int]uint64 // Keyed by the bucket index of the absolute value
+	zeros              uint64
+
+	// The buckets in ascending order of their values, for finding ranks; nil when outdated.
+	sorted []histogramBucket
+}
+
+type histogramBucket struct {
+	value float64
+	count uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{positive: make(map[int]uint64), negative: make(map[int]uint64)}
+}
+
+func (h *histogram) add(v float64) {
+	switch {
+	case v > 0:
+		h.positive[histogramIndex(v)]++
+	case v < 0:
+		h.negative[histogramIndex(-v)]++
+	default:
+		h.zeros++
+	}
+	h.sorted = nil
+}
+
+// histogramIndex returns the index of the bucket of a positive value, whose bounds are
+// (gamma^(i-1), gamma^i].
+func histogramIndex(v float64) int {
+	return int(math.Ceil(math.Log(v) / histogramLogGamma))
+}
+
+// histogramValue returns the estimate for the values in a bucket, which is off by at most
+// histogramAccuracy from both of its bounds.
+func histogramValue(i int) float64 {
+	return 2 * math.Pow(histogramGamma, float64(i)) / (histogramGamma + 1)
+}
+
+// value returns the estimate of the value with the given 0-based rank, in ascending order.
+func (h *histogram) value(rank uint64) float64 {
+	if h.sorted == nil {
+		h.sort()
+	}
+	var seen uint64
+	for _, b := range h.sorted {
+		seen += b.count
+		if rank < seen {
+			return b.value
+		}
+	}
+	if len(h.sorted) == 0 {
+		return 0
+	}
+	return h.sorted[len(h.sorted)-1].value
+}
+
+func (h *histogram) sort() {
+	h.sorted = make([]histogramBucket, 0, len(h.positive)+len(h.negative)+1)
+	for i, count := range h.negative {
+		h.sorted = append(h.sorted, histogramBucket{-histogramValue(i), count})
+	}
+	if h.zeros > 0 {
+		h.sorted = append(h.sorted, histogramBucket{0, h.zeros})
+	}
+	for i, count := range h.positive {
+		h.sorted = append(h.sorted, histogramBucket{histogramValue(i), count})
+	}
+	sort.Slice(h.sorted, func(i, j int) bool { return h.sorted[i].value < h.sorted[j].value })
+}
@@ -61,6 +61,11 @@ func New(conf Config) (*Collector, error) {
 }
 
 func (c *Collector) Init() error {
+	if c.Config.IsV2() {
+		// Buckets aren't created on the fly, and the v2 API has no InfluxQL to create them with
+		return nil
+	}
+
 	// Try to create the database if it doesn't exist. Failure to do so is USUALLY harmless; it
 	// usually means we're either a non-admin user to an existing DB or connecting over UDP.
 	_, err := c.Client.Query(client.NewQuery("CREATE DATABASE "+c.BatchConf.Database, "", ""))
@@ -40,6 +40,11 @@ type Config struct {
 	Insecure    null.Bool   `json:"insecure,omitempty" envconfig:"INFLUXDB_INSECURE"`
 	PayloadSize null.Int    `json:"payloadSize,omitempty" envconfig:"INFLUXDB_PAYLOAD_SIZE"`
 
+	// InfluxDB 2.x's API, which is used if there's a token; the bucket defaults to the DB.
+	Organization null.String `json:"organization,omitempty" envconfig:"INFLUXDB_ORGANIZATION"`
+	Bucket       null.String `json:"bucket,omitempty" envconfig:"INFLUXDB_BUCKET"`
+	Token        null.String `json:"token,omitempty" envconfig:"INFLUXDB_TOKEN"`
+
 	// Samples.
 	DB           null.String `json:"db" envconfig:"INFLUXDB_DB"`
 	Precision    null.String `json:"precision,omitempty" envconfig:"INFLUXDB_PRECISION"`
@@ -73,6 +78,15 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.PayloadSize.Valid && cfg.PayloadSize.Int64 > 0 {
 		c.PayloadSize = cfg.PayloadSize
 	}
+	if cfg.Organization.Valid {
+		c.Organization = cfg.Organization
+	}
+	if cfg.Bucket.Valid {
+		c.Bucket = cfg.Bucket
+	}
+	if cfg.Token.Valid {
+		c.Token = cfg.Token
+	}
 	if cfg.DB.Valid {
 		c.DB = cfg.DB
 	}
@@ -91,6 +105,11 @@ func (c Config) Apply(cfg Config) Config {
 	return c
 }
 
+// IsV2 returns whether the config is for InfluxDB 2.x's API, which needs a token.
+func (c Config) IsV2() bool {
+	return c.Token.String != ""
+}
+
 // ParseArg parses an argument string into a Config
 func ParseArg(arg string) (Config, error) {
 	c := Config{}
@@ -161,6 +180,12 @@ func ParseURL(text string) (Config, error) {
 			c.Retention = null.StringFrom(vs[0])
 		case "consistency":
 			c.Consistency = null.StringFrom(vs[0])
+		case "org", "organization":
+			c.Organization = null.StringFrom(vs[0])
+		case "bucket":
+			c.Bucket = null.StringFrom(vs[0])
+		case "token":
+			c.Token = null.StringFrom(vs[0])
 		case "tagsAsFields":
 			c.TagsAsFields = vs
 		default:
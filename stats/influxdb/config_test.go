@@ -65,6 +65,9 @@ func TestParseURL(t *testing.T) {
 		"?insecure=ture":   {Config{}, "insecure must be true or false, not ture"},
 		"?payload_size=69": {Config{PayloadSize: null.IntFrom(69)}, ""},
 		"?payload_size=a":  {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?org=k6io&bucket=tests&token=abc": {Config{
+			Organization: null.StringFrom("k6io"), Bucket: null.StringFrom("tests"), Token: null.StringFrom("abc"),
+		}, ""},
 	}
 	for str, data := range testdata {
 		t.Run(str, func(t *testing.T) {
@@ -0,0 +1,198 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	client "github.com/influxdata/influxdb/client/v2"
+	"github.com/pkg/errors"
+)
+
+// v2BatchSize is the most points that are written with a request to the v2 API, which InfluxDB
+// recommends to keep at around 5000 lines of line protocol.
+const v2BatchSize = 5000
+
+// v2Client writes to the v2 API of InfluxDB 2.x and InfluxDB Cloud, to a bucket of an
+// organization, with a token. It's a client.Client so that the collector doesn't have to care
+// which API it's writing to, but it can't query, since the v2 API only has Flux queries.
+type v2Client struct {
+	addr   string
+	org    string
+	bucket string
+	token  string
+	client *http.Client
+}
+
+var _ client.Client = &v2Client{}
+
+func newV2Client(conf Config) (*v2Client, error) {
+	addr := strings.TrimSuffix(conf.Addr.String, "/")
+	if _, err := url.Parse(addr); err != nil {
+		return nil, err
+	}
+	if conf.Organization.String == "" {
+		return nil, errors.New("InfluxDB v2 needs an organization, set it with ?org= or K6_INFLUXDB_ORGANIZATION")
+	}
+	bucket := conf.Bucket.String
+	if bucket == "" {
+		bucket = conf.DB.String
+	}
+	if bucket == "" {
+		bucket = "k6"
+	}
+	return &v2Client{
+		addr:   addr,
+		org:    conf.Organization.String,
+		bucket: bucket,
+		token:  conf.Token.String,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: conf.Insecure.Bool}, //nolint:gosec
+			},
+		},
+	}, nil
+}
+
+// v2Precision maps the precisions of the v1 API to the v2 API's.
+func v2Precision(precision string) (string, error) {
+	switch precision {
+	case "", "n", "ns":
+		return "ns", nil
+	case "u", "us":
+		return "us", nil
+	case "ms":
+		return "ms", nil
+	case "s":
+		return "s", nil
+	default:
+		return "", errors.Errorf("InfluxDB v2 doesn't support the precision '%s', it has to be ns, us, ms or s", precision)
+	}
+}
+
+func (c *v2Client) Ping(timeout time.Duration) (time.Duration, string, error) {
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodGet, c.addr+"/api/v2/buckets?limit=1&name="+url.QueryEscape(c.bucket), nil)
+	if err != nil {
+		return 0, "", err
+	}
+	res, err := c.do(req, timeout)
+	if err != nil {
+		return 0, "", err
+	}
+	return time.Since(start), res.Header.Get("X-Influxdb-Version"), nil
+}
+
+// Write writes the points in batches of v2BatchSize, as gzipped line protocol.
+func (c *v2Client) Write(bp client.BatchPoints) error {
+	precision, err := v2Precision(bp.Precision())
+	if err != nil {
+		return err
+	}
+	points := bp.Points()
+	for len(points) > 0 {
+		n := len(points)
+		if n > v2BatchSize {
+			n = v2BatchSize
+		}
+		if err := c.write(points[:n], bp.Precision(), precision); err != nil {
+			return err
+		}
+		points = points[n:]
+	}
+	return nil
+}
+
+func (c *v2Client) write(points []*client.Point, v1Precision, precision string) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	for _, p := range points {
+		if _, err := io.WriteString(gz, p.PrecisionString(v1Precision)+"\n"); err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("org", c.org)
+	params.Set("bucket", c.bucket)
+	params.Set("precision", precision)
+	req, err := http.NewRequest(http.MethodPost, c.addr+"/api/v2/write?"+params.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = c.do(req, 0)
+	return err
+}
+
+func (c *v2Client) do(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	req.Header.Set("User-Agent", "k6")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Token "+c.token)
+	}
+	httpClient := c.client
+	if timeout > 0 {
+		clientCopy := *c.client
+		clientCopy.Timeout = timeout
+		httpClient = &clientCopy
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = res.Body.Close() }()
+	data, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode >= 300 {
+		// Errors are JSON objects like {"code":"unauthorized","message":"unauthorized access"}
+		var apiErr struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return nil, errors.Errorf("InfluxDB: %s (%s)", apiErr.Message, res.Status)
+		}
+		return nil, errors.Errorf("InfluxDB: %s", res.Status)
+	}
+	return res, nil
+}
+
+func (c *v2Client) Query(q client.Query) (*client.Response, error) {
+	return nil, errors.New("InfluxDB v2 doesn't support InfluxQL queries")
+}
+
+func (c *v2Client) Close() error {
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package influxdb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+)
+
+func TestV2Collector(t *testing.T) {
+	var mutex sync.Mutex
+	var lines []string
+	var batches int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"unauthorized","message":"unauthorized access"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/buckets":
+			assert.Equal(t, "tests", r.URL.Query().Get("name"))
+			w.Header().Set("X-Influxdb-Version", "2.0.0")
+			_, _ = w.Write([]byte(`{"buckets":[]}`))
+		case "/api/v2/write":
+			assert.Equal(t, "k6io", r.URL.Query().Get("org"))
+			assert.Equal(t, "tests", r.URL.Query().Get("bucket"))
+			assert.Equal(t, "ms", r.URL.Query().Get("precision"))
+			assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			scanner := bufio.NewScanner(gz)
+			mutex.Lock()
+			batches++
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			mutex.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	conf := NewConfig().Apply(Config{
+		Addr:         null.StringFrom(srv.URL),
+		Organization: null.StringFrom("k6io"),
+		Bucket:       null.StringFrom("tests"),
+		Token:        null.StringFrom("s3cr3t"),
+		Precision:    null.StringFrom("ms"),
+	})
+	c, err := New(conf)
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+	_, version, err := c.Client.Ping(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", version)
+
+	metric := stats.New("test_metric", stats.Counter)
+	start := time.Unix(1550000000, 0)
+	samples := make(stats.Samples, v2BatchSize+1)
+	for i := range samples {
+		samples[i] = stats.Sample{
+			Metric: metric,
+			Time:   start.Add(time.Duration(i) * time.Millisecond),
+			Tags:   stats.NewSampleTags(map[string]string{"status": "200"}),
+			Value:  float64(i),
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	c.Collect([]stats.SampleContainer{samples})
+	cancel()
+	<-done
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, 2, batches)
+	require.Len(t, lines, v2BatchSize+1)
+	assert.Equal(t, fmt.Sprintf("test_metric,status=200 value=0 %d", start.UnixNano()/1e6), lines[0])
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		c, err := New(conf.Apply(Config{Token: null.StringFrom("wrong")}))
+		require.NoError(t, err)
+		_, _, err = c.Client.Ping(time.Second)
+		assert.EqualError(t, err, "InfluxDB: unauthorized access (401 Unauthorized)")
+	})
+	t.Run("No Organization", func(t *testing.T) {
+		_, err := New(Config{Token: null.StringFrom("s3cr3t")})
+		assert.EqualError(t, err, "InfluxDB v2 needs an organization, set it with ?org= or K6_INFLUXDB_ORGANIZATION")
+	})
+	t.Run("Precision", func(t *testing.T) {
+		_, err := v2Precision("h")
+		assert.EqualError(t, err, "InfluxDB v2 doesn't support the precision 'h', it has to be ns, us, ms or s")
+	})
+}
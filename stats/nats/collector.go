@@ -0,0 +1,243 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package nats
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	connectTimeout = 10 * time.Second
+	flushTimeout   = 10 * time.Second
+)
+
+// Verify that Collector implements lib.ThresholdCollector
+var _ lib.ThresholdCollector = &Collector{}
+
+// Collector publishes samples and test events to NATS. Every sample is published to
+// <subject>.samples.<metric>, in the JSON output's format, and events are published to
+// <subject>.events.<type>: started when the test starts, threshold when a threshold fails, or
+// breaches or recovers, and finished when the test ends, with the run status. Every message has
+// the ID of the run, so that the messages of concurrent tests can be told apart.
+type Collector struct {
+	Config Config
+	RunID  string
+
+	version string
+	conn    *conn
+
+	buffer     []stats.Sample
+	events     []event
+	bufferLock sync.Mutex
+	status     lib.RunStatus
+}
+
+// An event of the test's lifecycle.
+type event struct {
+	Type   string    `json:"type"`
+	Run    string    `json:"run"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status,omitempty"`
+
+	Threshold *lib.ThresholdEvent `json:"threshold,omitempty"`
+}
+
+// New creates a new NATS collector; it doesn't connect until it's initialized.
+func New(conf Config, version string) (*Collector, error) {
+	if _, err := url.Parse(conf.URL.String); err != nil {
+		return nil, errors.Wrap(err, "invalid NATS URL")
+	}
+	if !validSubject(conf.Subject.String) {
+		return nil, errors.Errorf("invalid NATS subject '%s'", conf.Subject.String)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Collector{
+		Config:  conf,
+		RunID:   hex.EncodeToString(id),
+		version: version,
+		status:  lib.RunStatusFinished,
+	}, nil
+}
+
+// Init connects to the server.
+func (c *Collector) Init() error {
+	cn, err := connect(c.Config.URL.String, c.version, connectTimeout)
+	if err != nil {
+		return errors.Wrap(err, "couldn't connect to NATS")
+	}
+	c.conn = cn
+	return nil
+}
+
+// Run publishes the started event, then the buffered samples and events every push interval,
+// and the finished event when the context is done.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("run", c.RunID).Debug("NATS: Running!")
+	c.addEvent(event{Type: "started"})
+	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.commit()
+		case <-ctx.Done():
+			c.bufferLock.Lock()
+			status := c.status.String()
+			c.bufferLock.Unlock()
+			c.addEvent(event{Type: "finished", Status: status})
+			c.commit()
+			if c.conn != nil {
+				_ = c.conn.close()
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until they're published.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+// ThresholdChanged buffers a threshold event until it's published.
+func (c *Collector) ThresholdChanged(ev lib.ThresholdEvent) {
+	c.addEvent(event{Type: "threshold", Threshold: &ev})
+}
+
+// Link returns the server URL, without the credentials.
+func (c *Collector) Link() string {
+	u, err := url.Parse(c.Config.URL.String)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus saves the status, which is published with the finished event.
+func (c *Collector) SetRunStatus(status lib.RunStatus) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	c.status = status
+}
+
+func (c *Collector) addEvent(ev event) {
+	ev.Run = c.RunID
+	ev.Time = time.Now()
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	c.events = append(c.events, ev)
+}
+
+func (c *Collector) commit() {
+	c.bufferLock.Lock()
+	samples, events := c.buffer, c.events
+	c.buffer, c.events = nil, nil
+	c.bufferLock.Unlock()
+
+	if len(samples) == 0 && len(events) == 0 {
+		return
+	}
+	if c.conn == nil {
+		cn, err := connect(c.Config.URL.String, c.version, connectTimeout)
+		if err != nil {
+			log.WithError(err).WithField("samples", len(samples)).Error("NATS: Couldn't reconnect, the samples are dropped")
+			return
+		}
+		c.conn = cn
+	}
+
+	log.WithFields(log.Fields{"samples": len(samples), "events": len(events)}).Debug("NATS: Publishing...")
+	prefix := c.Config.Subject.String
+	for _, ev := range events {
+		if err := c.publish(prefix+".events."+ev.Type, ev); err != nil {
+			return
+		}
+	}
+	for i := range samples {
+		msg := struct {
+			*jsonc.Envelope
+			Run string `json:"run"`
+		}{jsonc.WrapSample(&samples[i]), c.RunID}
+		if err := c.publish(prefix+".samples."+subjectToken(samples[i].Metric.Name), msg); err != nil {
+			return
+		}
+	}
+	if err := c.conn.flush(flushTimeout); err != nil {
+		c.reset(err)
+	}
+}
+
+func (c *Collector) publish(subject string, msg interface{}) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.WithError(err).Error("NATS: Couldn't encode a message")
+		return nil
+	}
+	if err := c.conn.publish(subject, payload); err != nil {
+		c.reset(err)
+		return err
+	}
+	return nil
+}
+
+// reset drops a broken connection, so that a new one is made for the next push.
+func (c *Collector) reset(err error) {
+	log.WithError(err).Error("NATS: Couldn't publish the samples")
+	_ = c.conn.close()
+	c.conn = nil
+}
+
+// subjectToken makes a metric name a single token of a subject.
+func subjectToken(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,234 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package nats
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// conn is a connection to a NATS server, which only publishes. It speaks just enough of NATS'
+// text protocol for that: it reads the server's INFO, sends CONNECT, answers the server's PINGs,
+// and sends PUBs; see https://docs.nats.io/reference/reference-protocols/nats-protocol.
+type conn struct {
+	conn net.Conn
+	info serverInfo
+
+	mutex  sync.Mutex // For w, and err
+	w      *bufio.Writer
+	err    error
+	pongs  chan struct{}
+	closed chan struct{}
+}
+
+type serverInfo struct {
+	MaxPayload   int  `json:"max_payload"`
+	TLSRequired  bool `json:"tls_required"`
+	AuthRequired bool `json:"auth_required"`
+}
+
+type connectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// connect connects to the server at a nats:// or tls:// URL, which can have a user and a password,
+// or a token as the user, and waits for the server to acknowledge the connection.
+func connect(serverURL, version string, timeout time.Duration) (*conn, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	netConn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = netConn.SetDeadline(time.Now().Add(timeout))
+
+	r := bufio.NewReader(netConn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		_ = netConn.Close()
+		return nil, errors.Errorf("expected INFO from the NATS server, got %q", strings.TrimSpace(line))
+	}
+	var info serverInfo
+	if err := json.Unmarshal([]byte(line[5:]), &info); err != nil {
+		_ = netConn.Close()
+		return nil, errors.Wrap(err, "invalid INFO from the NATS server")
+	}
+	if info.TLSRequired || u.Scheme == "tls" {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			_ = netConn.Close()
+			return nil, errors.Wrap(err, "TLS handshake with the NATS server failed")
+		}
+		netConn = tlsConn
+		r = bufio.NewReader(netConn)
+	}
+
+	opts := connectOptions{Name: "k6", Lang: "go", Version: version, Protocol: 0}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts.User, opts.Pass = u.User.Username(), pass
+		} else {
+			opts.Token = u.User.Username()
+		}
+	}
+	connectJSON, err := json.Marshal(opts)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	// The server answers the PING with a PONG only if the CONNECT was OK, or with an -ERR
+	if _, err := netConn.Write([]byte("CONNECT " + string(connectJSON) + "\r\nPING\r\n")); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			_ = netConn.Close()
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			_ = netConn.Close()
+			return nil, errors.Errorf("the NATS server refused the connection: %s", strings.TrimSpace(line[4:]))
+		}
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	c := &conn{
+		conn:   netConn,
+		info:   info,
+		w:      bufio.NewWriter(netConn),
+		pongs:  make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	go c.read(r)
+	return c, nil
+}
+
+// read handles what the server sends, until the connection is closed.
+func (c *conn) read(r *bufio.Reader) {
+	defer close(c.closed)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.mutex.Lock()
+			_, _ = c.w.WriteString("PONG\r\n")
+			_ = c.w.Flush()
+			c.mutex.Unlock()
+		case line == "PONG":
+			select {
+			case c.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			log.WithField("error", strings.TrimSpace(line[4:])).Warn("NATS: The server sent an error")
+		}
+	}
+}
+
+func (c *conn) fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// publish buffers a message to a subject; it's sent with the next flush, or when the buffer fills.
+func (c *conn) publish(subject string, payload []byte) error {
+	if c.info.MaxPayload > 0 && len(payload) > c.info.MaxPayload {
+		return errors.Errorf("the message for %s is bigger than the server's max_payload of %d bytes", subject, c.info.MaxPayload)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	_, _ = c.w.WriteString("PUB " + subject + " " + strconv.Itoa(len(payload)) + "\r\n")
+	_, _ = c.w.Write(payload)
+	_, c.err = c.w.WriteString("\r\n")
+	return c.err
+}
+
+// flush sends the buffered messages, and waits until the server has handled them.
+func (c *conn) flush(timeout time.Duration) error {
+	c.mutex.Lock()
+	if c.err == nil {
+		_, _ = c.w.WriteString("PING\r\n")
+		c.err = c.w.Flush()
+	}
+	err := c.err
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.pongs:
+		return nil
+	case <-c.closed:
+		return errors.New("the NATS server closed the connection")
+	case <-time.After(timeout):
+		return errors.New("timed out waiting for the NATS server")
+	}
+}
+
+func (c *conn) close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,353 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package otlp sends metrics to OpenTelemetry collectors and other backends that ingest OTLP
+// metrics, like New Relic and Dynatrace, with the JSON encoding of OTLP/HTTP.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The explicit bounds of the histograms that trends are sent as, the same as the OpenTelemetry
+// SDKs' default ones.
+var histogramBounds = []float64{5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+// Collector aggregates samples, and pushes the aggregates to an OTLP endpoint every push interval.
+//
+// k6 metrics are mapped to OTLP instruments like this, with the samples' tags as attributes:
+//   - counters are monotonic sums, with delta temporality,
+//   - gauges are gauges, with the last value of the push interval,
+//   - rates are gauges, with the fraction of the push interval's values that weren't zero,
+//   - trends are histograms, with delta temporality.
+type Collector struct {
+	Config Config
+
+	client    *http.Client
+	version   string
+	resource  resource
+	lastPush  time.Time
+	aggregate map[string]*metricPoints
+	lock      sync.Mutex
+}
+
+// The points of a metric since the last push, by their tags.
+type metricPoints struct {
+	metric *stats.Metric
+	points map[string]*point
+}
+
+type point struct {
+	attributes []keyValue
+
+	value        float64 // The sum of counters, and the last value of gauges
+	count        uint64
+	nonZero      uint64
+	sum          float64
+	min, max     float64
+	bucketCounts []uint64
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new OTLP collector.
+func New(conf Config, version string) (*Collector, error) {
+	if !conf.TestRunID.Valid || conf.TestRunID.String == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		conf.TestRunID.SetValid(hex.EncodeToString(id))
+	}
+	if time.Duration(conf.PushInterval.Duration) <= 0 {
+		return nil, errors.New("the OTLP push interval has to be positive")
+	}
+
+	attributes := map[string]string{
+		"service.name":    conf.ServiceName.String,
+		"service.version": version,
+		"k6.test_run_id":  conf.TestRunID.String,
+		"k6.scenario":     conf.Scenario.String,
+	}
+	for k, v := range conf.ResourceAttributes {
+		attributes[k] = v
+	}
+
+	return &Collector{
+		Config:    conf,
+		client:    &http.Client{Timeout: time.Duration(conf.PushInterval.Duration)},
+		version:   version,
+		resource:  resource{Attributes: toAttributes(attributes)},
+		lastPush:  time.Now(),
+		aggregate: make(map[string]*metricPoints),
+	}, nil
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Init() error { return nil }
+
+// Run pushes the aggregated metrics every push interval, until the context is done.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithFields(log.Fields{
+		"endpoint":    c.Config.Endpoint.String,
+		"test_run_id": c.Config.TestRunID.String,
+	}).Debug("OTLP: Running!")
+
+	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.pushMetrics()
+		case <-ctx.Done():
+			c.pushMetrics()
+			return
+		}
+	}
+}
+
+// Collect adds the samples to the aggregates of their metrics.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, sc := range scs {
+		for _, sample := range sc.GetSamples() {
+			mp, ok := c.aggregate[sample.Metric.Name]
+			if !ok {
+				mp = &metricPoints{metric: sample.Metric, points: make(map[string]*point)}
+				c.aggregate[sample.Metric.Name] = mp
+			}
+			tags := sample.Tags.CloneTags()
+			key := tagsKey(tags)
+			p, ok := mp.points[key]
+			if !ok {
+				p = &point{attributes: toAttributes(tags), min: math.Inf(1), max: math.Inf(-1)}
+				mp.points[key] = p
+			}
+			p.add(sample.Metric.Type, sample.Value)
+		}
+	}
+}
+
+func (p *point) add(typ stats.MetricType, value float64) {
+	p.count++
+	switch typ {
+	case stats.Counter:
+		p.value += value
+	case stats.Gauge:
+		p.value = value
+	case stats.Rate:
+		if value != 0 {
+			p.nonZero++
+		}
+	case stats.Trend:
+		p.sum += value
+		p.min = math.Min(p.min, value)
+		p.max = math.Max(p.max, value)
+		if p.bucketCounts == nil {
+			p.bucketCounts = make([]uint64, len(histogramBounds)+1)
+		}
+		// Buckets are (bound[i-1], bound[i]], and the last one is everything above the last bound
+		p.bucketCounts[sort.SearchFloat64s(histogramBounds, value)]++
+	}
+}
+
+// Link returns an empty string, since there's no UI to link to.
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus does nothing in the OTLP collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+func (c *Collector) pushMetrics() {
+	c.lock.Lock()
+	aggregate := c.aggregate
+	c.aggregate = make(map[string]*metricPoints)
+	start := c.lastPush
+	c.lastPush = time.Now()
+	end := c.lastPush
+	c.lock.Unlock()
+
+	if len(aggregate) == 0 {
+		return
+	}
+	body, err := json.Marshal(c.exportRequest(aggregate, start, end))
+	if err != nil {
+		log.WithError(err).Error("OTLP: Couldn't encode the metrics")
+		return
+	}
+
+	log.WithField("metrics", len(aggregate)).Debug("OTLP: Pushing metrics...")
+	if err := c.post(body); err != nil {
+		log.WithError(err).Warn("OTLP: Couldn't push the metrics")
+	}
+}
+
+func (c *Collector) post(body []byte) error {
+	req, err := http.NewRequest("POST", c.Config.Endpoint.String, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6/"+c.version)
+	for k, v := range c.Config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return errors.Errorf("%s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+func (c *Collector) exportRequest(aggregate map[string]*metricPoints, start, end time.Time) exportRequest {
+	startNano, endNano := nanos(start), nanos(end)
+
+	names := make([]string, 0, len(aggregate))
+	for name := range aggregate {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]metric, 0, len(names))
+	for _, name := range names {
+		mp := aggregate[name]
+		m := metric{Name: c.Config.MetricPrefix.String + name}
+		switch mp.metric.Contains {
+		case stats.Time:
+			m.Unit = "ms"
+		case stats.Data:
+			m.Unit = "By"
+		}
+
+		keys := make([]string, 0, len(mp.points))
+		for key := range mp.points {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		switch mp.metric.Type {
+		case stats.Counter:
+			m.Sum = &sum{AggregationTemporality: temporalityDelta, IsMonotonic: true}
+			for _, key := range keys {
+				p := mp.points[key]
+				m.Sum.DataPoints = append(m.Sum.DataPoints, numberDataPoint{
+					Attributes: p.attributes, StartTimeUnixNano: startNano, TimeUnixNano: endNano, AsDouble: p.value,
+				})
+			}
+		case stats.Gauge, stats.Rate:
+			m.Gauge = &gauge{}
+			for _, key := range keys {
+				p := mp.points[key]
+				value := p.value
+				if mp.metric.Type == stats.Rate {
+					value = float64(p.nonZero) / float64(p.count)
+				}
+				m.Gauge.DataPoints = append(m.Gauge.DataPoints, numberDataPoint{
+					Attributes: p.attributes, TimeUnixNano: endNano, AsDouble: value,
+				})
+			}
+		case stats.Trend:
+			m.Histogram = &histogram{AggregationTemporality: temporalityDelta}
+			for _, key := range keys {
+				p := mp.points[key]
+				counts := make([]string, len(p.bucketCounts))
+				for i, n := range p.bucketCounts {
+					counts[i] = strconv.FormatUint(n, 10)
+				}
+				m.Histogram.DataPoints = append(m.Histogram.DataPoints, histogramDataPoint{
+					Attributes:        p.attributes,
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      endNano,
+					Count:             strconv.FormatUint(p.count, 10),
+					Sum:               p.sum,
+					Min:               p.min,
+					Max:               p.max,
+					BucketCounts:      counts,
+					ExplicitBounds:    histogramBounds,
+				})
+			}
+		}
+		metrics = append(metrics, m)
+	}
+
+	return exportRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: c.resource,
+		ScopeMetrics: []scopeMetrics{{
+			Scope:   scope{Name: "k6", Version: c.version},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+func tagsKey(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\x00")
+}
+
+func toAttributes(m map[string]string) []keyValue {
+	attributes := make([]keyValue, 0, len(m))
+	for k, v := range m {
+		attributes = append(attributes, keyValue{Key: k, Value: anyValue{StringValue: v}})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].Key < attributes[j].Key })
+	return attributes
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,159 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestCollector(t *testing.T) {
+	requests := make(chan map[string]interface{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Api-Key"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &data))
+		requests <- data
+	}))
+	defer srv.Close()
+
+	argConfig, err := ParseArg(srv.URL)
+	require.NoError(t, err)
+	c, err := New(NewConfig().Apply(Config{
+		Headers:            map[string]string{"api-key": "secret"},
+		PushInterval:       types.NullDurationFrom(time.Hour),
+		TestRunID:          null.StringFrom("123"),
+		ResourceAttributes: map[string]string{"env": "staging"},
+	}).Apply(argConfig), "1.0")
+	require.NoError(t, err)
+
+	get := stats.IntoSampleTags(&map[string]string{"method": "GET"})
+	post := stats.IntoSampleTags(&map[string]string{"method": "POST"})
+	now := time.Now()
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Metric: metrics.HTTPReqs, Tags: get, Value: 1, Time: now},
+		stats.Sample{Metric: metrics.HTTPReqs, Tags: get, Value: 1, Time: now},
+		stats.Sample{Metric: metrics.HTTPReqs, Tags: post, Value: 1, Time: now},
+		stats.Sample{Metric: metrics.HTTPReqDuration, Tags: get, Value: 3, Time: now},
+		stats.Sample{Metric: metrics.HTTPReqDuration, Tags: get, Value: 120, Time: now},
+		stats.Sample{Metric: metrics.HTTPReqDuration, Tags: get, Value: 20000, Time: now},
+		stats.Sample{Metric: metrics.Checks, Value: 1, Time: now},
+		stats.Sample{Metric: metrics.Checks, Value: 0, Time: now},
+		stats.Sample{Metric: metrics.VUs, Value: 5, Time: now},
+		stats.Sample{Metric: metrics.VUs, Value: 10, Time: now},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	var data map[string]interface{}
+	select {
+	case data = <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no metrics were pushed")
+	}
+
+	// Round trip the expected data through JSON, so that numbers are float64s
+	expected := func(src string) interface{} {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(src), &v))
+		return v
+	}
+	rm := data["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, expected(`{"attributes": [
+		{"key": "env", "value": {"stringValue": "staging"}},
+		{"key": "k6.scenario", "value": {"stringValue": "default"}},
+		{"key": "k6.test_run_id", "value": {"stringValue": "123"}},
+		{"key": "service.name", "value": {"stringValue": "k6"}},
+		{"key": "service.version", "value": {"stringValue": "1.0"}}
+	]}`), rm["resource"])
+
+	sm := rm["scopeMetrics"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, expected(`{"name": "k6", "version": "1.0"}`), sm["scope"])
+
+	byName := make(map[string]map[string]interface{})
+	for _, m := range sm["metrics"].([]interface{}) {
+		m := m.(map[string]interface{})
+		byName[m["name"].(string)] = m
+	}
+	require.Len(t, byName, 4)
+
+	reqs := byName["k6_http_reqs"]["sum"].(map[string]interface{})
+	assert.Equal(t, true, reqs["isMonotonic"])
+	assert.Equal(t, 1.0, reqs["aggregationTemporality"])
+	points := reqs["dataPoints"].([]interface{})
+	require.Len(t, points, 2)
+	assert.Equal(t, 2.0, points[0].(map[string]interface{})["asDouble"])
+	assert.Equal(t, expected(`[{"key": "method", "value": {"stringValue": "GET"}}]`), points[0].(map[string]interface{})["attributes"])
+	assert.Equal(t, 1.0, points[1].(map[string]interface{})["asDouble"])
+
+	duration := byName["k6_http_req_duration"]
+	assert.Equal(t, "ms", duration["unit"])
+	hp := duration["histogram"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "3", hp["count"])
+	assert.Equal(t, 20123.0, hp["sum"])
+	assert.Equal(t, 3.0, hp["min"])
+	assert.Equal(t, 20000.0, hp["max"])
+	assert.Equal(t, expected(`["1","0","0","0","0","0","1","0","0","0","0","0","0","0","1"]`), hp["bucketCounts"])
+	assert.Len(t, hp["explicitBounds"], 14)
+
+	checks := byName["k6_checks"]["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	assert.Equal(t, 0.5, checks[0].(map[string]interface{})["asDouble"])
+	vus := byName["k6_vus"]["gauge"].(map[string]interface{})["dataPoints"].([]interface{})
+	assert.Equal(t, 10.0, vus[0].(map[string]interface{})["asDouble"])
+}
+
+func TestCollectorErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	c, err := New(NewConfig().Apply(Config{Endpoint: null.StringFrom(srv.URL)}), "1.0")
+	require.NoError(t, err)
+	assert.Len(t, c.Config.TestRunID.String, 16)
+	assert.EqualError(t, c.post([]byte("{}")), "401 Unauthorized: invalid api key")
+
+	_, err = New(NewConfig().Apply(Config{PushInterval: types.NullDurationFrom(0)}), "1.0")
+	assert.Error(t, err)
+}
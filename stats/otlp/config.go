@@ -0,0 +1,114 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Config is the config for the OTLP collector.
+type Config struct {
+	// The URL that metrics are POSTed to, and the headers that are sent with them, e.g. the
+	// API keys of hosted backends like New Relic (api-key) or Dynatrace (Authorization).
+	Endpoint null.String       `json:"endpoint" envconfig:"OTLP_ENDPOINT"`
+	Headers  map[string]string `json:"headers" envconfig:"OTLP_HEADERS"`
+
+	// How often metrics are aggregated and pushed; every push has the deltas since the last one.
+	PushInterval types.NullDuration `json:"pushInterval" envconfig:"OTLP_PUSH_INTERVAL"`
+
+	// Prepended to the names of k6's metrics, e.g. k6_http_reqs.
+	MetricPrefix null.String `json:"metricPrefix" envconfig:"OTLP_METRIC_PREFIX"`
+
+	// Resource attributes: service.name, k6.test_run_id (random by default), k6.scenario, and
+	// any other ones.
+	ServiceName        null.String       `json:"serviceName" envconfig:"OTLP_SERVICE_NAME"`
+	TestRunID          null.String       `json:"testRunID" envconfig:"OTLP_TEST_RUN_ID"`
+	Scenario           null.String       `json:"scenario" envconfig:"OTLP_SCENARIO"`
+	ResourceAttributes map[string]string `json:"resourceAttributes" envconfig:"OTLP_RESOURCE_ATTRIBUTES"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Endpoint:     null.NewString("http://localhost:4318/v1/metrics", false),
+		PushInterval: types.NewNullDuration(10*time.Second, false),
+		MetricPrefix: null.NewString("k6_", false),
+		ServiceName:  null.NewString("k6", false),
+		Scenario:     null.NewString("default", false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Endpoint.Valid {
+		c.Endpoint = cfg.Endpoint
+	}
+	if len(cfg.Headers) > 0 {
+		c.Headers = cfg.Headers
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.MetricPrefix.Valid {
+		c.MetricPrefix = cfg.MetricPrefix
+	}
+	if cfg.ServiceName.Valid {
+		c.ServiceName = cfg.ServiceName
+	}
+	if cfg.TestRunID.Valid {
+		c.TestRunID = cfg.TestRunID
+	}
+	if cfg.Scenario.Valid {
+		c.Scenario = cfg.Scenario
+	}
+	if len(cfg.ResourceAttributes) > 0 {
+		c.ResourceAttributes = cfg.ResourceAttributes
+	}
+	return c
+}
+
+// ParseArg takes the argument of `--out otlp=...`, which is the endpoint. If it has no path, the
+// standard /v1/metrics path of OTLP/HTTP is used.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if arg == "" {
+		return c, nil
+	}
+
+	u, err := url.Parse(arg)
+	if err != nil {
+		return c, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return c, errors.Errorf("invalid OTLP endpoint '%s', it has to be an http:// or https:// URL", arg)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = "/v1/metrics"
+	}
+	c.Endpoint = null.StringFrom(u.String())
+	return c, nil
+}
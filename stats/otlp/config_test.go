@@ -0,0 +1,65 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package otlp
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestParseArg(t *testing.T) {
+	testdata := map[string]string{
+		"":                                     "",
+		"http://localhost:4318":                "http://localhost:4318/v1/metrics",
+		"https://otlp.example.com/":            "https://otlp.example.com/v1/metrics",
+		"https://otlp.example.com/otlp/v1/met": "https://otlp.example.com/otlp/v1/met",
+	}
+	for arg, endpoint := range testdata {
+		c, err := ParseArg(arg)
+		require.NoError(t, err, arg)
+		assert.Equal(t, endpoint, c.Endpoint.String, arg)
+	}
+
+	_, err := ParseArg("localhost:4318")
+	assert.EqualError(t, err, "invalid OTLP endpoint 'localhost:4318', it has to be an http:// or https:// URL")
+}
+
+func TestConfigEnv(t *testing.T) {
+	defer os.Clearenv()
+	os.Clearenv()
+	require.NoError(t, os.Setenv("K6_OTLP_HEADERS", "api-key:secret,x-tenant:k6"))
+	require.NoError(t, os.Setenv("K6_OTLP_PUSH_INTERVAL", "5s"))
+	require.NoError(t, os.Setenv("K6_OTLP_SCENARIO", "checkout"))
+
+	c := NewConfig()
+	require.NoError(t, envconfig.Process("k6", &c))
+	assert.Equal(t, map[string]string{"api-key": "secret", "x-tenant": "k6"}, c.Headers)
+	assert.Equal(t, types.NullDurationFrom(5*time.Second), c.PushInterval)
+	assert.Equal(t, null.StringFrom("checkout"), c.Scenario)
+	assert.Equal(t, "http://localhost:4318/v1/metrics", c.Endpoint.String)
+}
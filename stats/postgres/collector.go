@@ -0,0 +1,267 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const connectTimeout = 10 * time.Second
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// Collector writes samples into a PostgreSQL table, with a row per sample:
+//
+//	ts timestamptz, metric text, value double precision, tags jsonb
+//
+// The samples are buffered, and written with a single COPY every push interval.
+type Collector struct {
+	Config Config
+
+	conn  *conn
+	table string // Quoted
+
+	buffer     []stats.Sample
+	bufferLock sync.Mutex
+}
+
+// New creates a new PostgreSQL collector; it doesn't connect until it's initialized.
+func New(conf Config) (*Collector, error) {
+	if conf.Table.String == "" {
+		return nil, errors.New("the PostgreSQL table name can't be empty")
+	}
+	if _, err := url.Parse(conf.URL.String); err != nil {
+		return nil, errors.Wrap(err, "invalid PostgreSQL URL")
+	}
+	return &Collector{
+		Config: conf,
+		table:  quoteTableName(conf.Table.String),
+	}, nil
+}
+
+// Init connects to the database and creates the table, if it doesn't exist. If the TimescaleDB
+// extension is installed, new tables are made hypertables, partitioned by time.
+func (c *Collector) Init() error {
+	cn, err := connect(c.Config.URL.String, connectTimeout)
+	if err != nil {
+		return errors.Wrap(err, "couldn't connect to PostgreSQL")
+	}
+	c.conn = cn
+
+	rows, err := cn.query("SELECT to_regclass(" + quoteLiteral(c.table) + ")")
+	if err != nil {
+		return err
+	}
+	if len(rows) == 1 && len(rows[0]) == 1 && rows[0][0] != "" {
+		return nil // The table exists
+	}
+
+	log.WithField("table", c.Config.Table.String).Debug("PostgreSQL: Creating the table")
+	if _, err := cn.query("CREATE TABLE IF NOT EXISTS " + c.table + " (" +
+		"ts timestamptz NOT NULL, metric text NOT NULL, value double precision NOT NULL, tags jsonb)"); err != nil {
+		return errors.Wrap(err, "couldn't create the table")
+	}
+	rows, err = cn.query("SELECT 1 FROM pg_extension WHERE extname = 'timescaledb'")
+	if err != nil {
+		return err
+	}
+	if len(rows) > 0 {
+		_, err = cn.query("SELECT create_hypertable(" + quoteLiteral(c.table) + ", 'ts', if_not_exists => TRUE)")
+		return errors.Wrap(err, "couldn't create the hypertable")
+	}
+	// Indexes are always in the schema of their table, so the name of one can't be qualified
+	index := c.Config.Table.String
+	if i := strings.LastIndex(index, "."); i >= 0 {
+		index = index[i+1:]
+	}
+	_, err = cn.query("CREATE INDEX IF NOT EXISTS " + quoteIdentifier(index+"_metric_ts_idx") +
+		" ON " + c.table + " (metric, ts)")
+	return errors.Wrap(err, "couldn't create the index")
+}
+
+// Run writes the buffered samples every push interval, until the context is done.
+func (c *Collector) Run(ctx context.Context) {
+	log.Debug("PostgreSQL: Running!")
+	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.commit()
+		case <-ctx.Done():
+			c.commit()
+			if c.conn != nil {
+				_ = c.conn.send('X', nil) // Terminate
+				_ = c.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until they're written.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+// Link returns the database URL, without the password.
+func (c *Collector) Link() string {
+	u, err := url.Parse(c.Config.URL.String)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus does nothing in the PostgreSQL collector
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+func (c *Collector) commit() {
+	c.bufferLock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+
+	if len(samples) == 0 {
+		return
+	}
+
+	if c.conn == nil {
+		cn, err := connect(c.Config.URL.String, connectTimeout)
+		if err != nil {
+			log.WithError(err).WithField("samples", len(samples)).Error("PostgreSQL: Couldn't reconnect, the samples are dropped")
+			return
+		}
+		c.conn = cn
+	}
+
+	log.WithField("samples", len(samples)).Debug("PostgreSQL: Writing...")
+	startTime := time.Now()
+	err := c.conn.copyIn("COPY "+c.table+" (ts, metric, value, tags) FROM STDIN", formatRows(samples))
+	if err != nil {
+		log.WithError(err).Error("PostgreSQL: Couldn't write the samples")
+		if _, ok := err.(serverError); !ok {
+			// The connection is broken, so a new one is made for the next batch
+			_ = c.conn.Close()
+			c.conn = nil
+		}
+		return
+	}
+	log.WithField("t", time.Since(startTime)).Debug("PostgreSQL: Samples written!")
+}
+
+// formatRows formats samples as rows in the text format of COPY.
+func formatRows(samples []stats.Sample) [][]byte {
+	rows := make([][]byte, len(samples))
+	for i, s := range samples {
+		tags := []byte("{}")
+		if s.Tags != nil {
+			if b, err := json.Marshal(s.Tags.CloneTags()); err == nil {
+				tags = b
+			}
+		}
+
+		row := make([]byte, 0, 64+len(s.Metric.Name)+len(tags))
+		row = append(row, s.Time.UTC().Format("2006-01-02 15:04:05.999999Z07:00")...)
+		row = append(row, '\t')
+		row = appendEscaped(row, s.Metric.Name)
+		row = append(row, '\t')
+		row = append(row, formatFloat(s.Value)...)
+		row = append(row, '\t')
+		row = appendEscaped(row, string(tags))
+		rows[i] = append(row, '\n')
+	}
+	return rows
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Infinity"
+	case math.IsInf(v, -1):
+		return "-Infinity"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// appendEscaped appends a column value, with the characters that COPY's text format treats
+// specially escaped.
+func appendEscaped(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b = append(b, '\\', '\\')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// quoteTableName quotes a table name that can be qualified with a schema, e.g. "k6.samples".
+func quoteTableName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
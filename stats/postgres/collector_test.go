@@ -0,0 +1,260 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+// fakeServer speaks just enough of the PostgreSQL protocol for the collector: it asks for an MD5
+// password, and records the queries it gets and the rows that are copied into it.
+type fakeServer struct {
+	listener  net.Listener
+	tables    map[string]bool
+	timescale bool
+
+	mutex   sync.Mutex
+	queries []string
+	copied  []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener, tables: map[string]bool{}}
+	go func() {
+		for {
+			nc, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(t, nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) URL() string {
+	return "postgres://k6:secret@" + s.listener.Addr().String() + "/k6"
+}
+
+func (s *fakeServer) serve(t *testing.T, nc net.Conn) {
+	defer func() { _ = nc.Close() }()
+	c := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	readStartup := func() []byte {
+		var size [4]byte
+		if _, err := io.ReadFull(c.r, size[:]); err != nil {
+			return nil
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(size[:])-4)
+		_, _ = io.ReadFull(c.r, msg)
+		return msg
+	}
+	msg := readStartup()
+	if binary.BigEndian.Uint32(msg) == 80877103 { // SSLRequest
+		_, _ = nc.Write([]byte{'N'})
+		msg = readStartup()
+	}
+	assert.Contains(t, string(msg), "user\x00k6\x00database\x00k6\x00")
+
+	salt := []byte{1, 2, 3, 4}
+	_ = c.send('R', []byte{0, 0, 0, 5, 1, 2, 3, 4})
+	_, password, err := c.receive()
+	if err != nil {
+		return
+	}
+	if string(password) != md5Password("k6", "secret", salt)+"\x00" {
+		_ = c.send('E', []byte("SFATAL\x00C28P01\x00Mpassword authentication failed\x00\x00"))
+		return
+	}
+	_ = c.send('R', []byte{0, 0, 0, 0})
+	_ = c.send('Z', []byte{'I'})
+
+	for {
+		msgType, payload, err := c.receive()
+		if err != nil || msgType == 'X' {
+			return
+		}
+		query := strings.TrimSuffix(string(payload), "\x00")
+		s.mutex.Lock()
+		s.queries = append(s.queries, query)
+		s.mutex.Unlock()
+
+		switch {
+		case strings.HasPrefix(query, "SELECT to_regclass"):
+			row := []byte{0, 1, 0xff, 0xff, 0xff, 0xff} // NULL
+			if s.tables[query] {
+				row = append([]byte{0, 1, 0, 0, 0, 1}, 'x')
+			}
+			_ = c.send('D', row)
+		case strings.Contains(query, "pg_extension") && s.timescale:
+			_ = c.send('D', []byte{0, 1, 0, 0, 0, 1, '1'})
+		case strings.HasPrefix(query, "COPY"):
+			_ = c.send('G', []byte{0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0})
+			for {
+				msgType, payload, err := c.receive()
+				if err != nil || msgType == 'c' {
+					break
+				}
+				s.mutex.Lock()
+				s.copied = append(s.copied, string(payload))
+				s.mutex.Unlock()
+			}
+		case strings.HasPrefix(query, "INVALID"):
+			_ = c.send('E', []byte("SERROR\x00C42601\x00Msyntax error\x00\x00"))
+		}
+		_ = c.send('C', []byte("OK\x00"))
+		_ = c.send('Z', []byte{'I'})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	srv := newFakeServer(t)
+	defer func() { _ = srv.listener.Close() }()
+
+	config := NewConfig().Apply(Config{
+		URL:          null.StringFrom(srv.URL()),
+		PushInterval: types.NullDurationFrom(10 * time.Millisecond),
+	})
+	c, err := New(config)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://"+srv.listener.Addr().String()+"/k6", c.Link())
+
+	require.NoError(t, c.Init())
+	assert.Equal(t, []string{
+		`SELECT to_regclass('"k6_samples"')`,
+		`CREATE TABLE IF NOT EXISTS "k6_samples" (ts timestamptz NOT NULL, metric text NOT NULL, value double precision NOT NULL, tags jsonb)`,
+		`SELECT 1 FROM pg_extension WHERE extname = 'timescaledb'`,
+		`CREATE INDEX IF NOT EXISTS "k6_samples_metric_ts_idx" ON "k6_samples" (metric, ts)`,
+	}, srv.queries)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	now := time.Date(2018, 10, 1, 12, 30, 15, 123456000, time.UTC)
+	metric := stats.New("my\tmetric", stats.Trend)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Metric: metric, Time: now, Value: 1.5, Tags: stats.IntoSampleTags(&map[string]string{"url": "http://x/\\"})},
+		stats.Sample{Metric: metric, Time: now, Value: 2},
+	})
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, `COPY "k6_samples" (ts, metric, value, tags) FROM STDIN`, srv.queries[len(srv.queries)-1])
+	assert.Equal(t, []string{
+		"2018-10-01 12:30:15.123456Z\tmy\\tmetric\t1.5\t{\"url\":\"http://x/\\\\\\\\\"}\n",
+		"2018-10-01 12:30:15.123456Z\tmy\\tmetric\t2\t{}\n",
+	}, srv.copied)
+
+	t.Run("TimescaleDB", func(t *testing.T) {
+		srv.queries, srv.timescale = nil, true
+		c, err := New(config)
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+		assert.Equal(t, `SELECT create_hypertable('"k6_samples"', 'ts', if_not_exists => TRUE)`, srv.queries[len(srv.queries)-1])
+	})
+
+	t.Run("existing table", func(t *testing.T) {
+		srv.queries, srv.tables[`SELECT to_regclass('"k6_samples"')`] = nil, true
+		c, err := New(config)
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+		assert.Len(t, srv.queries, 1)
+	})
+
+	t.Run("schema", func(t *testing.T) {
+		srv.queries, srv.timescale = nil, false
+		c, err := New(config.Apply(Config{Table: null.StringFrom("k6.samples")}))
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+		assert.Equal(t, []string{
+			`SELECT to_regclass('"k6"."samples"')`,
+			`CREATE TABLE IF NOT EXISTS "k6"."samples" (ts timestamptz NOT NULL, metric text NOT NULL, value double precision NOT NULL, tags jsonb)`,
+			`SELECT 1 FROM pg_extension WHERE extname = 'timescaledb'`,
+			`CREATE INDEX IF NOT EXISTS "samples_metric_ts_idx" ON "k6"."samples" (metric, ts)`,
+		}, srv.queries)
+
+		srv.queries, srv.timescale = nil, true
+		require.NoError(t, c.Init())
+		assert.Equal(t, `SELECT create_hypertable('"k6"."samples"', 'ts', if_not_exists => TRUE)`, srv.queries[len(srv.queries)-1])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c, err := New(config)
+		require.NoError(t, err)
+		require.NoError(t, c.Init())
+		_, err = c.conn.query("INVALID")
+		assert.EqualError(t, err, "ERROR: syntax error (SQLSTATE 42601)")
+
+		_, err = New(NewConfig().Apply(Config{Table: null.StringFrom("")}))
+		assert.EqualError(t, err, "the PostgreSQL table name can't be empty")
+
+		c, err = New(NewConfig().Apply(Config{URL: null.StringFrom("postgres://k6:wrong@" + srv.listener.Addr().String() + "/k6")}))
+		require.NoError(t, err)
+		err = c.Init()
+		assert.EqualError(t, err, "couldn't connect to PostgreSQL: FATAL: password authentication failed (SQLSTATE 28P01)")
+	})
+}
+
+func TestQuoteTableName(t *testing.T) {
+	testdata := map[string]string{
+		"k6_samples":    `"k6_samples"`,
+		"k6.samples":    `"k6"."samples"`,
+		`weird"name`:    `"weird""name"`,
+		"db.k6.samples": `"db"."k6"."samples"`,
+	}
+	for name, quoted := range testdata {
+		assert.Equal(t, quoted, quoteTableName(name), name)
+	}
+}
+
+func TestFormatRows(t *testing.T) {
+	metric := stats.New("values", stats.Gauge)
+	now := time.Unix(0, 0)
+	rows := formatRows([]stats.Sample{
+		{Metric: metric, Time: now, Value: 0.000001},
+		{Metric: metric, Time: now, Value: 1e21},
+	})
+	assert.Equal(t, "1970-01-01 00:00:00Z\tvalues\t1e-06\t{}\n", string(rows[0]))
+	assert.Equal(t, "1970-01-01 00:00:00Z\tvalues\t1e+21\t{}\n", string(rows[1]))
+	assert.Equal(t, "NaN", formatFloat(math.NaN()))
+}
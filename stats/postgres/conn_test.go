@@ -0,0 +1,50 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMD5Password(t *testing.T) {
+	// "md5" + md5(md5("secret" + "k6") + salt)
+	assert.Equal(t, "md576c1cee53d4ef6944b41e56ad7008dee", md5Password("k6", "secret", []byte{1, 2, 3, 4}))
+}
+
+func TestSCRAM(t *testing.T) {
+	// The example exchange from RFC 7677; PostgreSQL sends an empty user name in the SCRAM
+	// messages, so the first message is patched to have the one from the example.
+	s := newSCRAMClient("pencil", "rOprNGfwEbeRWgbNEkqO")
+	assert.Equal(t, "n,,n=,r=rOprNGfwEbeRWgbNEkqO", s.clientFirst())
+	s.clientFirstBare = "n=user,r=rOprNGfwEbeRWgbNEkqO"
+
+	final, err := s.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	require.NoError(t, err)
+	assert.Equal(t, "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=", final)
+	assert.NoError(t, s.verifyServerFinal("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="))
+	assert.EqualError(t, s.verifyServerFinal("v=AAAA"), "the server's SCRAM signature is invalid")
+
+	_, err = s.clientFinal("r=someoneelse,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	assert.EqualError(t, err, "invalid SCRAM server message 'r=someoneelse,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096'")
+}
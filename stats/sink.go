@@ -85,9 +85,15 @@ func (g *GaugeSink) Format(t time.Duration) map[string]float64 {
 	return map[string]float64{"value": g.Value}
 }
 
+// Up to this many values, a TrendSink keeps all of them, for exact percentiles. Past that, it
+// counts them in a histogram instead, so that it doesn't need more memory the longer a test runs,
+// and its percentiles are estimates within histogramAccuracy of the real ones.
+const trendSinkMaxValues = 10000
+
 type TrendSink struct {
-	Values  []float64
-	jumbled bool
+	Values    []float64 // Until there are more than trendSinkMaxValues, nil after that
+	histogram *histogram
+	jumbled   bool
 
 	Count    uint64
 	Min, Max float64
@@ -96,7 +102,18 @@ type TrendSink struct {
 }
 
 func (t *TrendSink) Add(s Sample) {
-	t.Values = append(t.Values, s.Value)
+	if t.histogram != nil {
+		t.histogram.add(s.Value)
+	} else {
+		t.Values = append(t.Values, s.Value)
+		if len(t.Values) > trendSinkMaxValues {
+			t.histogram = newHistogram()
+			for _, v := range t.Values {
+				t.histogram.add(v)
+			}
+			t.Values = nil
+		}
+	}
 	t.jumbled = true
 	t.Count += 1
 	t.Sum += s.Value
@@ -116,27 +133,46 @@ func (t *TrendSink) P(pct float64) float64 {
 	case 0:
 		return 0
 	case 1:
-		return t.Values[0]
+		return t.Min
 	default:
 		// If percentile falls on a value in Values slice, we return that value.
 		// If percentile does not fall on a value in Values slice, we calculate (linear interpolation)
 		// the value that would fall at percentile, given the values above and below that percentile.
 		t.Calc()
 		i := pct * (float64(t.Count) - 1.0)
-		j := t.Values[int(math.Floor(i))]
-		k := t.Values[int(math.Ceil(i))]
+		j, k := t.value(uint64(math.Floor(i))), t.value(uint64(math.Ceil(i)))
 		f := i - math.Floor(i)
 		return j + (k-j)*f
 	}
 }
 
+// value returns the value with the given 0-based rank, in ascending order; the lowest and the
+// highest ones are always exact.
+func (t *TrendSink) value(rank uint64) float64 {
+	switch {
+	case t.histogram == nil:
+		return t.Values[rank]
+	case rank == 0:
+		return t.Min
+	case rank >= t.Count-1:
+		return t.Max
+	default:
+		return math.Max(t.Min, math.Min(t.Max, t.histogram.value(rank)))
+	}
+}
+
 func (t *TrendSink) Calc() {
 	if !t.jumbled {
 		return
 	}
+	t.jumbled = false
+
+	if t.histogram != nil {
+		t.Med = t.P(0.5)
+		return
+	}
 
 	sort.Float64s(t.Values)
-	t.jumbled = false
 
 	// The median of an even number of values is the average of the middle two.
 	if (t.Count & 0x01) == 0 {
@@ -212,6 +212,28 @@ func TestTrendSink(t *testing.T) {
 			"p(95)": 95.49999999999999,
 		}, sink.Format(0))
 	})
+	t.Run("histogram", func(t *testing.T) {
+		sink := TrendSink{}
+		for i := 0; i < 1000000; i++ {
+			sink.Add(Sample{Metric: &Metric{}, Value: float64(i%20000-1000) / 10})
+		}
+		assert.Nil(t, sink.Values)
+		assert.NotNil(t, sink.histogram)
+		assert.Equal(t, uint64(1000000), sink.Count)
+		assert.Equal(t, -100.0, sink.Min)
+		assert.Equal(t, 1899.9, sink.Max)
+		assert.Equal(t, -100.0, sink.P(0.0))
+		assert.Equal(t, 1899.9, sink.P(1.0))
+
+		sink.Calc()
+		assert.InEpsilon(t, 899.95, sink.Med, histogramAccuracy)
+		assert.InEpsilon(t, 1699.95, sink.P(0.90), histogramAccuracy)
+		assert.InEpsilon(t, 1799.95, sink.P(0.95), histogramAccuracy)
+		assert.InEpsilon(t, 1879.95, sink.P(0.99), histogramAccuracy)
+		assert.InEpsilon(t, -50.0, sink.P(0.025), histogramAccuracy)
+		assert.InDelta(t, -0.005, sink.P(0.05), 0.0001)
+		assert.True(t, len(sink.histogram.positive)+len(sink.histogram.negative) < 2000)
+	})
 }
 
 func TestRateSink(t *testing.T) {
@@ -248,6 +248,24 @@ func (st *SampleTags) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &st.tags)
 }
 
+// Decode parses a comma-separated list of name=value tags, e.g. from the K6_TAGS environment
+// variable.
+func (st *SampleTags) Decode(value string) error {
+	tags := map[string]string{}
+	for _, nv := range strings.Split(value, ",") {
+		if nv = strings.TrimSpace(nv); nv == "" {
+			continue
+		}
+		i := strings.IndexByte(nv, '=')
+		if i <= 0 {
+			return fmt.Errorf("invalid tag '%s', it has to be name=value", nv)
+		}
+		tags[strings.TrimSpace(nv[:i])] = strings.TrimSpace(nv[i+1:])
+	}
+	*st = SampleTags{tags: tags}
+	return nil
+}
+
 // CloneTags copies the underlying set of a sample tags and
 // returns it. If the receiver is nil, it returns an empty non-nil map.
 func (st *SampleTags) CloneTags() map[string]string {
@@ -257,6 +257,15 @@ func TestSampleTags(t *testing.T) {
 	assert.Equal(t, tagMap, tagsUnmarshaled.CloneTags())
 }
 
+func TestSampleTagsDecode(t *testing.T) {
+	var tags SampleTags
+	assert.NoError(t, tags.Decode("env=staging, build=42,,empty="))
+	assert.Equal(t, map[string]string{"env": "staging", "build": "42", "empty": ""}, tags.CloneTags())
+
+	assert.EqualError(t, tags.Decode("env=staging,build"), "invalid tag 'build', it has to be name=value")
+	assert.EqualError(t, tags.Decode("=42"), "invalid tag '=42', it has to be name=value")
+}
+
 func TestSampleImplementations(t *testing.T) {
 	tagMap := map[string]string{"key1": "val1", "key2": "val2"}
 	now := time.Now()
@@ -22,6 +22,10 @@ package stats
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dop251/goja"
@@ -51,8 +55,14 @@ type Threshold struct {
 	AbortOnFail      bool
 	AbortGracePeriod types.NullDuration
 
-	pgm *goja.Program
-	rt  *goja.Runtime
+	// Window makes the threshold apply only to the samples of the last Window, instead of to all
+	// of them, for catching a degradation while it's happening; 0 means the whole test.
+	Window   types.Duration
+	Breached bool // Whether the last evaluation over the window failed
+
+	pgm    *goja.Program
+	rt     *goja.Runtime
+	window []Sample
 }
 
 func NewThreshold(src string, rt *goja.Runtime, abortOnFail bool, gracePeriod types.NullDuration) (*Threshold, error) {
@@ -86,10 +96,68 @@ func (t *Threshold) Run() (bool, error) {
 	return b, err
 }
 
+// Validate checks that the threshold works for metrics of a type, by evaluating it with a sample:
+// that it only uses the values that the type's metrics have, and that it's a comparison, not a
+// value that's always true, like `p(95)`.
+func (t Threshold) Validate(typ MetricType) error {
+	rt := goja.New()
+	if _, err := rt.RunProgram(jsEnv); err != nil {
+		return errors.Wrap(err, "builtin")
+	}
+	sink := New("", typ).Sink
+	sink.Add(Sample{Value: 1})
+	setSinkValues(rt, sink, time.Second)
+	setMetrics(rt, nil, time.Second)
+	setBaseline(rt, sink.Format(time.Second))
+
+	v, err := rt.RunProgram(t.pgm)
+	if err != nil {
+		if ex, ok := err.(*goja.Exception); ok {
+			err = errors.New(ex.Value().String())
+		}
+		values := make([]string, 0)
+		for k := range sink.Format(time.Second) {
+			values = append(values, k)
+		}
+		sort.Strings(values)
+		return errors.Errorf("%s (%s metrics have %s)", err, strings.Trim(typ.String(), `"`), strings.Join(values, ", "))
+	}
+	if _, ok := v.Export().(bool); !ok {
+		return errors.Errorf("it evaluates to %s, not to true or false", v)
+	}
+	return nil
+}
+
+func (t *Threshold) addSample(s Sample) {
+	cutoff := s.Time.Add(-time.Duration(t.Window))
+	i := 0
+	for i < len(t.window) && t.window[i].Time.Before(cutoff) {
+		i++
+	}
+	t.window = append(t.window[i:], s)
+}
+
+// windowSink returns a sink with the samples of the window that ends now, or nil if there are none.
+func (t *Threshold) windowSink(now time.Time) Sink {
+	cutoff := now.Add(-time.Duration(t.Window))
+	var sink Sink
+	for _, s := range t.window {
+		if s.Time.Before(cutoff) {
+			continue
+		}
+		if sink == nil {
+			sink = New(s.Metric.Name, s.Metric.Type).Sink
+		}
+		sink.Add(s)
+	}
+	return sink
+}
+
 type ThresholdConfig struct {
 	Threshold        string             `json:"threshold"`
 	AbortOnFail      bool               `json:"abortOnFail"`
 	AbortGracePeriod types.NullDuration `json:"delayAbortEval"`
+	Window           types.Duration     `json:"window,omitempty"`
 }
 
 //used internally for JSON marshalling
@@ -106,7 +174,7 @@ func (tc *ThresholdConfig) UnmarshalJSON(data []byte) error {
 }
 
 func (tc ThresholdConfig) MarshalJSON() ([]byte, error) {
-	if tc.AbortOnFail {
+	if tc.AbortOnFail || tc.Window > 0 {
 		return json.Marshal(rawThresholdConfig(tc))
 	}
 	return json.Marshal(tc.Threshold)
@@ -133,12 +201,24 @@ func NewThresholdsWithConfig(configs []ThresholdConfig) (Thresholds, error) {
 		return Thresholds{}, errors.Wrap(err, "builtin")
 	}
 
+	setBaseline(rt, nil)
+
 	ts := make([]*Threshold, len(configs))
 	for i, config := range configs {
-		t, err := NewThreshold(config.Threshold, rt, config.AbortOnFail, config.AbortGracePeriod)
+		thRuntime := rt
+		if config.Window > 0 {
+			// The values of the window differ from the whole metric's, so they need their own VM
+			thRuntime = goja.New()
+			if _, err := thRuntime.RunProgram(jsEnv); err != nil {
+				return Thresholds{}, errors.Wrap(err, "builtin")
+			}
+			setBaseline(thRuntime, nil)
+		}
+		t, err := NewThreshold(config.Threshold, thRuntime, config.AbortOnFail, config.AbortGracePeriod)
 		if err != nil {
 			return Thresholds{}, errors.Wrapf(err, "%d", i)
 		}
+		t.Window = config.Window
 		ts[i] = t
 	}
 
@@ -146,21 +226,115 @@ func NewThresholdsWithConfig(configs []ThresholdConfig) (Thresholds, error) {
 }
 
 func (ts *Thresholds) UpdateVM(sink Sink, t time.Duration) error {
-	ts.Runtime.Set("__sink__", sink)
+	setSinkValues(ts.Runtime, sink, t)
+	return nil
+}
+
+func setSinkValues(rt *goja.Runtime, sink Sink, t time.Duration) {
+	rt.Set("__sink__", sink)
 	f := sink.Format(t)
 	for k, v := range f {
-		ts.Runtime.Set(k, v)
+		rt.Set(k, v)
 	}
-	return nil
+}
+
+// AddSample keeps a sample for the thresholds with a window, until it's out of their windows.
+func (ts *Thresholds) AddSample(s Sample) {
+	for _, th := range ts.Thresholds {
+		if th.Window > 0 {
+			th.addSample(s)
+		}
+	}
+}
+
+// SetMetrics makes the other metrics available to the threshold expressions, through a
+// metric(name) function, so that they can express things like `count / metric("http_reqs").count
+// < 0.01` or `p(95) - metric("backend_duration").p(95) < 100`. The returned objects have the same
+// values that a threshold on that metric would, plus a p(pct) function for Trend metrics; metrics
+// without any samples yet have no values at all.
+func (ts *Thresholds) SetMetrics(metrics map[string]*Metric, t time.Duration) {
+	setMetrics(ts.Runtime, metrics, t)
+	for _, th := range ts.Thresholds {
+		if th.rt != ts.Runtime {
+			setMetrics(th.rt, metrics, t)
+		}
+	}
+}
+
+func setMetrics(rt *goja.Runtime, metrics map[string]*Metric, t time.Duration) {
+	rt.Set("metric", func(name string) *goja.Object {
+		obj := rt.NewObject()
+		m, ok := metrics[name]
+		if !ok {
+			return obj
+		}
+		for k, v := range m.Sink.Format(t) {
+			_ = obj.Set(k, v)
+		}
+		if trend, ok := m.Sink.(*TrendSink); ok {
+			_ = obj.Set("p", func(pct float64) float64 { return trend.P(pct / 100.0) })
+		}
+		return obj
+	})
+}
+
+// SetBaseline makes the values that the metric had in a previous run available to the threshold
+// expressions, as a baseline object with the same values and p(pct) function as the metric, so
+// that they can express things like `p(95) < baseline.p(95) * 1.1`, i.e. that the p95 mustn't
+// regress by more than 10%. Values that the baseline doesn't have are undefined, or NaN for
+// percentiles, so comparisons to them fail.
+func (ts *Thresholds) SetBaseline(values map[string]float64) {
+	setBaseline(ts.Runtime, values)
+	for _, th := range ts.Thresholds {
+		if th.rt != ts.Runtime {
+			setBaseline(th.rt, values)
+		}
+	}
+}
+
+func setBaseline(rt *goja.Runtime, values map[string]float64) {
+	obj := rt.NewObject()
+	for k, v := range values {
+		_ = obj.Set(k, v)
+	}
+	_ = obj.Set("p", func(pct float64) float64 {
+		if v, ok := values["p("+strconv.FormatFloat(pct, 'f', -1, 64)+")"]; ok {
+			return v
+		}
+		return math.NaN()
+	})
+	rt.Set("baseline", obj)
 }
 
 func (ts *Thresholds) RunAll(t time.Duration) (bool, error) {
+	return ts.runAll(t, time.Now())
+}
+
+func (ts *Thresholds) runAll(t time.Duration, now time.Time) (bool, error) {
 	succ := true
 	for i, th := range ts.Thresholds {
+		if th.Window > 0 {
+			sink := th.windowSink(now)
+			if sink == nil {
+				// Nothing happened in the window, so there's nothing new to judge
+				succ = succ && !th.Failed
+				continue
+			}
+			windowTime := time.Duration(th.Window)
+			if t < windowTime {
+				windowTime = t
+			}
+			setSinkValues(th.rt, sink, windowTime)
+		}
 		b, err := th.Run()
 		if err != nil {
 			return false, errors.Wrapf(err, "%d", i)
 		}
+		if th.Window > 0 {
+			// A breach fails the test, even if the threshold recovers later
+			th.Breached = !b
+			b = !th.Failed
+		}
 		if !b {
 			succ = false
 
@@ -201,6 +375,7 @@ func (ts Thresholds) MarshalJSON() ([]byte, error) {
 		configs[i].Threshold = t.Source
 		configs[i].AbortOnFail = t.AbortOnFail
 		configs[i].AbortGracePeriod = t.AbortGracePeriod
+		configs[i].Window = t.Window
 	}
 	return json.Marshal(configs)
 }
@@ -28,6 +28,7 @@ import (
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewThreshold(t *testing.T) {
@@ -86,6 +87,35 @@ func TestThresholdRun(t *testing.T) {
 	})
 }
 
+func TestThresholdValidate(t *testing.T) {
+	testdata := map[string]struct {
+		typ MetricType
+		err string
+	}{
+		"p(95)<500":                       {Trend, ""},
+		"avg < 200 && med < 100":          {Trend, ""},
+		"rate>0.99":                       {Rate, ""},
+		"count<10":                        {Counter, ""},
+		"value>=1":                        {Gauge, ""},
+		"count / metric('x').count < 0.1": {Counter, ""},
+		"p95<500":                         {Trend, "p95 is not defined"},
+		"avg>0.9":                         {Rate, "ReferenceError: avg is not defined (rate metrics have rate)"},
+		"p(95)":                           {Trend, "it evaluates to 1, not to true or false"},
+	}
+	for src, data := range testdata {
+		t.Run(src, func(t *testing.T) {
+			th, err := NewThreshold(src, goja.New(), false, types.NullDuration{})
+			require.NoError(t, err)
+			err = th.Validate(data.typ)
+			if data.err == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), data.err)
+			}
+		})
+	}
+}
+
 func TestNewThresholds(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		ts, err := NewThresholds([]string{})
@@ -105,6 +135,16 @@ func TestNewThresholds(t *testing.T) {
 			assert.Equal(t, ts.Runtime, th.rt)
 		}
 	})
+	t.Run("window", func(t *testing.T) {
+		ts, err := NewThresholdsWithConfig([]ThresholdConfig{
+			{Threshold: `1+1==2`},
+			{Threshold: `1+1==2`, Window: types.Duration(time.Minute)},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ts.Runtime, ts.Thresholds[0].rt)
+		assert.NotEqual(t, ts.Runtime, ts.Thresholds[1].rt)
+		assert.Equal(t, types.Duration(time.Minute), ts.Thresholds[1].Window)
+	})
 }
 
 func TestNewThresholdsWithConfig(t *testing.T) {
@@ -115,8 +155,8 @@ func TestNewThresholdsWithConfig(t *testing.T) {
 	})
 	t.Run("two", func(t *testing.T) {
 		configs := []ThresholdConfig{
-			{`1+1==2`, false, types.NullDuration{}},
-			{`1+1==4`, true, types.NullDuration{}},
+			{`1+1==2`, false, types.NullDuration{}, 0},
+			{`1+1==4`, true, types.NullDuration{}, 0},
 		}
 		ts, err := NewThresholdsWithConfig(configs)
 		assert.NoError(t, err)
@@ -216,6 +256,126 @@ func TestThresholdsRun(t *testing.T) {
 	})
 }
 
+func TestThresholdsSetMetrics(t *testing.T) {
+	reqs, failed, duration := New("reqs", Counter), New("failed", Counter), New("duration", Trend)
+	for i := 1; i <= 200; i++ {
+		reqs.Sink.Add(Sample{Metric: reqs, Value: 1})
+		duration.Sink.Add(Sample{Metric: duration, Value: float64(i)})
+	}
+	failed.Sink.Add(Sample{Metric: failed, Value: 1})
+	metrics := map[string]*Metric{"reqs": reqs, "failed": failed, "duration": duration}
+
+	testdata := map[string]bool{
+		`count / metric("reqs").count < 0.01`:                true,
+		`count / metric("reqs").count < 0.001`:               false,
+		`metric("duration").p(50) == metric("duration").med`: true,
+		`metric("duration")["p(95)"] - 100 > 89`:             true,
+		`metric("duration").p(99) < 100`:                     false,
+		`metric("missing").count === undefined`:              true,
+	}
+	for src, succ := range testdata {
+		t.Run(src, func(t *testing.T) {
+			ts, err := NewThresholds([]string{src})
+			require.NoError(t, err)
+			ts.SetMetrics(metrics, time.Second)
+			b, err := ts.Run(failed.Sink, time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, succ, b)
+		})
+	}
+}
+
+func TestThresholdsSetBaseline(t *testing.T) {
+	duration := New("duration", Trend)
+	for i := 1; i <= 100; i++ {
+		duration.Sink.Add(Sample{Metric: duration, Value: float64(i)})
+	}
+	baseline := map[string]float64{"avg": 50, "p(95)": 90, "p(99.9)": 100}
+
+	testdata := map[string]bool{
+		`p(95) < baseline.p(95) * 1.1`: true,
+		`p(95) < baseline["p(95)"]`:    false,
+		`avg <= baseline.avg * 1.01`:   true,
+		`p(99.9) <= baseline.p(99.9)`:  true,
+		`p(99) < baseline.p(99)`:       false,
+		`med < baseline.med`:           false,
+		`baseline.count === undefined`: true,
+	}
+	for src, succ := range testdata {
+		t.Run(src, func(t *testing.T) {
+			ts, err := NewThresholds([]string{src})
+			require.NoError(t, err)
+			ts.SetBaseline(baseline)
+			b, err := ts.Run(duration.Sink, time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, succ, b)
+		})
+	}
+
+	t.Run("no baseline", func(t *testing.T) {
+		ts, err := NewThresholds([]string{`p(95) < baseline.p(95) * 1.1`})
+		require.NoError(t, err)
+		b, err := ts.Run(duration.Sink, time.Second)
+		assert.NoError(t, err)
+		assert.False(t, b)
+	})
+	t.Run("validate", func(t *testing.T) {
+		th, err := NewThreshold(`p(95) < baseline.p(95) * 1.1`, nil, false, types.NullDuration{})
+		require.NoError(t, err)
+		assert.NoError(t, th.Validate(Trend))
+	})
+}
+
+func TestThresholdsWindow(t *testing.T) {
+	ts, err := NewThresholdsWithConfig([]ThresholdConfig{
+		{Threshold: `p(95) < 500`},
+		{Threshold: `p(95) < 500`, Window: types.Duration(time.Minute)},
+		{Threshold: `avg < 200`, Window: types.Duration(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	metric := New("duration", Trend)
+	start := time.Now()
+	add := func(at time.Duration, value float64, n int) {
+		for i := 0; i < n; i++ {
+			s := Sample{Metric: metric, Time: start.Add(at), Value: value}
+			metric.Sink.Add(s)
+			ts.AddSample(s)
+		}
+	}
+	run := func(at time.Duration) bool {
+		require.NoError(t, ts.UpdateVM(metric.Sink, at))
+		b, err := ts.runAll(at, start.Add(at))
+		require.NoError(t, err)
+		return b
+	}
+
+	// Nothing to judge in an empty window
+	assert.True(t, run(0))
+	assert.False(t, ts.Thresholds[1].Breached)
+
+	add(10*time.Second, 100, 3000)
+	add(20*time.Second, 1000, 50)
+	assert.True(t, run(30*time.Second))
+	assert.False(t, ts.Thresholds[1].Breached)
+
+	// Late degradation; it's caught by the window, but not by the whole test yet
+	add(5*time.Minute, 1000, 60)
+	assert.False(t, run(5*time.Minute+10*time.Second))
+	assert.False(t, ts.Thresholds[0].Failed)
+	assert.True(t, ts.Thresholds[1].Breached)
+	assert.True(t, ts.Thresholds[1].Failed)
+	assert.True(t, ts.Thresholds[2].Breached)
+	assert.Len(t, ts.Thresholds[1].window, 60)
+
+	// Recovered, but a breached threshold still fails the test
+	add(7*time.Minute, 100, 100)
+	assert.False(t, run(7*time.Minute+10*time.Second))
+	assert.False(t, ts.Thresholds[1].Breached)
+	assert.True(t, ts.Thresholds[1].Failed)
+	assert.False(t, ts.Thresholds[2].Breached)
+}
+
 func TestThresholdsJSON(t *testing.T) {
 	var testdata = []struct {
 		JSON        string
@@ -273,6 +433,13 @@ func TestThresholdsJSON(t *testing.T) {
 			types.NullDuration{},
 			`["1+1==2"]`,
 		},
+		{
+			`[{"threshold":"1+1==2","abortOnFail":false,"delayAbortEval":null,"window":"1m0s"}]`,
+			[]string{"1+1==2"},
+			false,
+			types.NullDuration{},
+			"",
+		},
 		{
 			`[{"threshold":"1+1==2"}, "1+1==3"]`,
 			[]string{"1+1==2", "1+1==3"},
@@ -0,0 +1,210 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math"
+	"time"
+)
+
+// Time series have at most this many buckets; when there would be more, adjacent buckets are
+// merged, so that the interval doubles.
+const timeSeriesMaxBuckets = 60
+
+// A TimeSeries aggregates the samples of some metrics in buckets of an interval of time, to show
+// how they changed over the course of a test, not only their values over the whole of it. The
+// interval starts at a second, and doubles whenever there would be more than 60 buckets, so that
+// its size doesn't depend on how long a test runs.
+type TimeSeries struct {
+	Start    time.Time // Of the first bucket, i.e. the time of the first sample
+	Interval time.Duration
+
+	// The metrics' buckets, with nil for the ones without samples.
+	Buckets map[string][]Sink
+	Metrics map[string]*Metric
+}
+
+// NewTimeSeries returns a time series of the metrics with the names.
+func NewTimeSeries(names ...string) *TimeSeries {
+	ts := &TimeSeries{
+		Interval: time.Second,
+		Buckets:  make(map[string][]Sink, len(names)),
+		Metrics:  make(map[string]*Metric, len(names)),
+	}
+	for _, name := range names {
+		ts.Buckets[name] = nil
+	}
+	return ts
+}
+
+// Add adds a sample to its bucket, if it's of one of the metrics.
+func (ts *TimeSeries) Add(s Sample) {
+	buckets, ok := ts.Buckets[s.Metric.Name]
+	if !ok {
+		return
+	}
+	if _, ok := ts.Metrics[s.Metric.Name]; !ok {
+		ts.Metrics[s.Metric.Name] = s.Metric
+	}
+	if ts.Start.IsZero() {
+		ts.Start = s.Time
+	}
+
+	i := 0
+	if s.Time.After(ts.Start) {
+		i = int(s.Time.Sub(ts.Start) / ts.Interval)
+	}
+	for i >= timeSeriesMaxBuckets {
+		ts.halve()
+		buckets = ts.Buckets[s.Metric.Name]
+		i /= 2
+	}
+	for len(buckets) <= i {
+		buckets = append(buckets, nil)
+	}
+	if buckets[i] == nil {
+		buckets[i] = New(s.Metric.Name, s.Metric.Type).Sink
+	}
+	buckets[i].Add(s)
+	ts.Buckets[s.Metric.Name] = buckets
+}
+
+// halve merges every two adjacent buckets, doubling the interval.
+func (ts *TimeSeries) halve() {
+	for name, buckets := range ts.Buckets {
+		merged := make([]Sink, (len(buckets)+1)/2)
+		for i, sink := range buckets {
+			if sink == nil {
+				continue
+			}
+			if merged[i/2] == nil {
+				merged[i/2] = sink
+			} else {
+				mergeSinks(merged[i/2], sink)
+			}
+		}
+		ts.Buckets[name] = merged
+	}
+	ts.Interval *= 2
+}
+
+// mergeSinks adds what one sink aggregated to another one of the same type.
+func mergeSinks(dst, src Sink) {
+	switch d := dst.(type) {
+	case *CounterSink:
+		d.Value += src.(*CounterSink).Value
+	case *GaugeSink:
+		s := src.(*GaugeSink)
+		d.Value = s.Value
+		d.Max = math.Max(d.Max, s.Max)
+		d.Min = math.Min(d.Min, s.Min)
+	case *RateSink:
+		s := src.(*RateSink)
+		d.Trues += s.Trues
+		d.Total += s.Total
+	case *TrendSink:
+		s := src.(*TrendSink)
+		if s.histogram == nil {
+			for _, v := range s.Values {
+				d.Add(Sample{Value: v})
+			}
+			return
+		}
+		if d.histogram == nil {
+			d.histogram = newHistogram()
+			for _, v := range d.Values {
+				d.histogram.add(v)
+			}
+			d.Values = nil
+		}
+		for i, count := range s.histogram.positive {
+			d.histogram.positive[i] += count
+		}
+		for i, count := range s.histogram.negative {
+			d.histogram.negative[i] += count
+		}
+		d.histogram.zeros += s.histogram.zeros
+		d.histogram.sorted = nil
+		d.jumbled = true
+		d.Count += s.Count
+		d.Sum += s.Sum
+		d.Avg = d.Sum / float64(d.Count)
+		d.Min = math.Min(d.Min, s.Min)
+		d.Max = math.Max(d.Max, s.Max)
+	}
+}
+
+// A TimeSeriesLine is the values of a metric in every bucket of a time series; NaN means that
+// there were no samples in a bucket.
+type TimeSeriesLine struct {
+	Name   string
+	Values []float64
+}
+
+// Lines returns what a metric's time series shows: the average and p95 of trends, the rate per
+// second of counters, the maximum of gauges, and the rate of rates. It returns nil for a metric
+// without samples.
+func (ts *TimeSeries) Lines(name string) []TimeSeriesLine {
+	buckets, m := ts.Buckets[name], ts.Metrics[name]
+	if len(buckets) == 0 || m == nil {
+		return nil
+	}
+
+	line := func(name string, value func(Sink) float64) TimeSeriesLine {
+		values := make([]float64, len(buckets))
+		for i, sink := range buckets {
+			switch {
+			case sink != nil:
+				values[i] = value(sink)
+			case m.Type == Counter:
+				values[i] = 0
+			default:
+				values[i] = math.NaN()
+			}
+		}
+		return TimeSeriesLine{Name: name, Values: values}
+	}
+
+	switch m.Type {
+	case Trend:
+		return []TimeSeriesLine{
+			line("avg", func(s Sink) float64 { return s.(*TrendSink).Avg }),
+			line("p(95)", func(s Sink) float64 { return s.(*TrendSink).P(0.95) }),
+		}
+	case Counter:
+		seconds := ts.Interval.Seconds()
+		return []TimeSeriesLine{
+			line("rate", func(s Sink) float64 { return s.(*CounterSink).Value / seconds }),
+		}
+	case Gauge:
+		return []TimeSeriesLine{
+			line("max", func(s Sink) float64 { return s.(*GaugeSink).Max }),
+		}
+	case Rate:
+		return []TimeSeriesLine{
+			line("rate", func(s Sink) float64 {
+				r := s.(*RateSink)
+				return float64(r.Trues) / float64(r.Total)
+			}),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeSeries(t *testing.T) {
+	duration, reqs, vus, ignored := New("duration", Trend, Time), New("reqs", Counter), New("vus", Gauge), New("ignored", Trend)
+	start := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	ts := NewTimeSeries("duration", "reqs", "vus", "checks")
+
+	for i := 0; i < 5; i++ {
+		now := start.Add(time.Duration(i)*time.Second + 500*time.Millisecond)
+		if i == 2 {
+			continue
+		}
+		ts.Add(Sample{Metric: duration, Time: now, Value: float64(100 * (i + 1))})
+		ts.Add(Sample{Metric: duration, Time: now, Value: float64(300 * (i + 1))})
+		ts.Add(Sample{Metric: reqs, Time: now, Value: 2})
+		ts.Add(Sample{Metric: vus, Time: now, Value: float64(i + 1)})
+		ts.Add(Sample{Metric: ignored, Time: now, Value: 1})
+	}
+
+	assert.Equal(t, start.Add(500*time.Millisecond), ts.Start)
+	assert.Equal(t, time.Second, ts.Interval)
+	assert.NotContains(t, ts.Buckets, "ignored")
+	assert.Nil(t, ts.Lines("checks"))
+	assert.Nil(t, ts.Lines("missing"))
+
+	lines := ts.Lines("duration")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "avg", lines[0].Name)
+	assert.Equal(t, []float64{200, 400}, lines[0].Values[:2])
+	assert.True(t, math.IsNaN(lines[0].Values[2]))
+	assert.Equal(t, "p(95)", lines[1].Name)
+	assert.Equal(t, []TimeSeriesLine{{"rate", []float64{2, 2, 0, 2, 2}}}, ts.Lines("reqs"))
+	assert.Equal(t, "max", ts.Lines("vus")[0].Name)
+
+	t.Run("halving", func(t *testing.T) {
+		ts := NewTimeSeries("duration", "reqs", "vus")
+		for i := 0; i < 300; i++ {
+			now := start.Add(time.Duration(i) * time.Second)
+			ts.Add(Sample{Metric: duration, Time: now, Value: float64(i)})
+			ts.Add(Sample{Metric: reqs, Time: now, Value: 1})
+			ts.Add(Sample{Metric: vus, Time: now, Value: float64(i % 10)})
+		}
+		assert.Equal(t, 8*time.Second, ts.Interval)
+		assert.Len(t, ts.Buckets["duration"], 38)
+
+		first := ts.Buckets["duration"][0].(*TrendSink)
+		assert.Equal(t, uint64(8), first.Count)
+		assert.Equal(t, 3.5, first.Avg)
+		assert.Equal(t, 0.0, first.Min)
+		assert.Equal(t, 7.0, first.Max)
+		assert.Equal(t, 1.0, ts.Lines("reqs")[0].Values[0])
+		assert.Equal(t, 9.0, ts.Lines("vus")[0].Values[1])
+	})
+}
+
+func TestMergeSinks(t *testing.T) {
+	t.Run("trends", func(t *testing.T) {
+		small, large, all := &TrendSink{}, &TrendSink{}, &TrendSink{}
+		for i := 0; i < 100; i++ {
+			small.Add(Sample{Value: float64(i)})
+			all.Add(Sample{Value: float64(i)})
+		}
+		for i := 100; i < 100+2*trendSinkMaxValues; i++ {
+			large.Add(Sample{Value: float64(i)})
+			all.Add(Sample{Value: float64(i)})
+		}
+		require.NotNil(t, large.histogram)
+
+		mergeSinks(small, large)
+		assert.Equal(t, all.Count, small.Count)
+		assert.Equal(t, all.Avg, small.Avg)
+		assert.Equal(t, all.Min, small.Min)
+		assert.Equal(t, all.Max, small.Max)
+		assert.Equal(t, all.P(0.95), small.P(0.95))
+	})
+	t.Run("rates", func(t *testing.T) {
+		a, b := &RateSink{Trues: 1, Total: 2}, &RateSink{Trues: 3, Total: 4}
+		mergeSinks(a, b)
+		assert.Equal(t, &RateSink{Trues: 4, Total: 6}, a)
+	})
+	t.Run("gauges", func(t *testing.T) {
+		a, b := &GaugeSink{}, &GaugeSink{}
+		a.Add(Sample{Value: 5})
+		b.Add(Sample{Value: 3})
+		b.Add(Sample{Value: 9})
+		mergeSinks(a, b)
+		assert.Equal(t, []float64{9, 3, 9}, []float64{a.Value, a.Min, a.Max})
+	})
+}
@@ -0,0 +1,297 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// Collector serves a page with live charts of all metrics, and writes them into a standalone
+// HTML report at the end of the test. Every period, the samples of each metric are aggregated
+// into a point of its chart, regardless of their tags.
+type Collector struct {
+	Config Config
+
+	listener net.Listener
+	server   *http.Server
+
+	buffer     []stats.Sample
+	bufferLock sync.Mutex
+
+	data     Data
+	dataLock sync.RWMutex
+}
+
+// Data is what the charts are drawn from.
+type Data struct {
+	Start   time.Time `json:"start"`
+	Period  float64   `json:"period"` // In seconds
+	Metrics []*Series `json:"metrics"`
+}
+
+// Series are the points of a metric's chart, with a line per aggregated value; counters have
+// count and rate, gauges value, rates rate, and trends avg, p(95) and max.
+type Series struct {
+	Name     string               `json:"name"`
+	Type     stats.MetricType     `json:"type"`
+	Contains stats.ValueType      `json:"contains"`
+	Times    []int64              `json:"times"` // Unix milliseconds
+	Values   map[string][]float64 `json:"values"`
+}
+
+// New creates a new web dashboard; it doesn't start serving until it's initialized.
+func New(conf Config) (*Collector, error) {
+	if time.Duration(conf.Period.Duration) <= 0 {
+		return nil, errors.New("the web dashboard period has to be positive")
+	}
+	return &Collector{
+		Config: conf,
+		data:   Data{Period: time.Duration(conf.Period.Duration).Seconds()},
+	}, nil
+}
+
+// Init starts serving the dashboard, so that a port conflict fails before the test starts.
+func (c *Collector) Init() error {
+	listener, err := net.Listen("tcp", c.Config.Addr())
+	if err != nil {
+		return errors.Wrap(err, "couldn't start the web dashboard")
+	}
+	c.listener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.handlePage)
+	mux.HandleFunc("/data", c.handleData)
+	c.server = &http.Server{Handler: mux}
+	go func() {
+		if err := c.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("Web dashboard: Couldn't serve the dashboard")
+		}
+	}()
+	return nil
+}
+
+// Run aggregates the buffered samples every period until the context is done, and then writes
+// the report and stops serving the dashboard.
+func (c *Collector) Run(ctx context.Context) {
+	log.WithField("url", c.Link()).Debug("Web dashboard: Running!")
+	c.dataLock.Lock()
+	c.data.Start = time.Now()
+	c.dataLock.Unlock()
+
+	ticker := time.NewTicker(time.Duration(c.Config.Period.Duration))
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			c.aggregate(t)
+		case <-ctx.Done():
+			c.aggregate(time.Now())
+			if report := c.Config.Report.String; report != "" {
+				if err := c.WriteReport(report); err != nil {
+					log.WithError(err).Error("Web dashboard: Couldn't write the report")
+				} else {
+					log.WithField("filename", report).Info("Web dashboard: Wrote the report")
+				}
+			}
+			if c.server != nil {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = c.server.Shutdown(shutdownCtx)
+			}
+			return
+		}
+	}
+}
+
+// Collect buffers the samples until they're aggregated.
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+// Link returns the URL of the dashboard.
+func (c *Collector) Link() string {
+	if c.listener != nil {
+		return "http://" + c.listener.Addr().String()
+	}
+	return "http://" + c.Config.Addr()
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector.
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // Samples are aggregated regardless of their tags
+}
+
+// SetRunStatus does nothing in the web dashboard collector.
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Data returns a copy of what the charts are drawn from.
+func (c *Collector) Data() Data {
+	c.dataLock.RLock()
+	defer c.dataLock.RUnlock()
+	data := c.data
+	data.Metrics = make([]*Series, len(c.data.Metrics))
+	for i, s := range c.data.Metrics {
+		cp := *s
+		cp.Times = append([]int64(nil), s.Times...)
+		cp.Values = make(map[string][]float64, len(s.Values))
+		for k, v := range s.Values {
+			cp.Values[k] = append([]float64(nil), v...)
+		}
+		data.Metrics[i] = &cp
+	}
+	return data
+}
+
+// WriteReport writes the charts into a standalone HTML file, with the data embedded in it.
+func (c *Collector) WriteReport(filename string) error {
+	page, err := c.render(true)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, page, 0644)
+}
+
+// aggregate turns the buffered samples into a new point of each metric's chart.
+func (c *Collector) aggregate(t time.Time) {
+	c.bufferLock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+	if len(samples) == 0 {
+		return
+	}
+
+	byMetric := make(map[*stats.Metric][]float64)
+	var order []*stats.Metric
+	for _, s := range samples {
+		if _, ok := byMetric[s.Metric]; !ok {
+			order = append(order, s.Metric)
+		}
+		byMetric[s.Metric] = append(byMetric[s.Metric], s.Value)
+	}
+
+	c.dataLock.Lock()
+	defer c.dataLock.Unlock()
+	for _, m := range order {
+		series := c.series(m)
+		series.Times = append(series.Times, t.UnixNano()/int64(time.Millisecond))
+		for k, v := range aggregateValues(m.Type, byMetric[m], c.data.Period) {
+			series.Values[k] = append(series.Values[k], v)
+		}
+	}
+}
+
+// series returns the series of a metric, creating it if needed; the series are sorted by name.
+func (c *Collector) series(m *stats.Metric) *Series {
+	i := sort.Search(len(c.data.Metrics), func(i int) bool { return c.data.Metrics[i].Name >= m.Name })
+	if i < len(c.data.Metrics) && c.data.Metrics[i].Name == m.Name {
+		return c.data.Metrics[i]
+	}
+	s := &Series{Name: m.Name, Type: m.Type, Contains: m.Contains, Values: make(map[string][]float64)}
+	c.data.Metrics = append(c.data.Metrics, nil)
+	copy(c.data.Metrics[i+1:], c.data.Metrics[i:])
+	c.data.Metrics[i] = s
+	return s
+}
+
+func aggregateValues(t stats.MetricType, values []float64, period float64) map[string]float64 {
+	switch t {
+	case stats.Counter:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return map[string]float64{"count": sum, "rate": sum / period}
+	case stats.Gauge:
+		return map[string]float64{"value": values[len(values)-1]}
+	case stats.Rate:
+		var nonzero float64
+		for _, v := range values {
+			if v != 0 {
+				nonzero++
+			}
+		}
+		return map[string]float64{"rate": nonzero / float64(len(values))}
+	default:
+		sort.Float64s(values)
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		p95 := values[int(math.Ceil(float64(len(values))*0.95))-1] // Nearest rank
+		return map[string]float64{"avg": sum / float64(len(values)), "p(95)": p95, "max": values[len(values)-1]}
+	}
+}
+
+// render returns the dashboard page; if embed is true, the current data is embedded in it,
+// otherwise the page polls the server for it.
+func (c *Collector) render(embed bool) ([]byte, error) {
+	data := []byte("null")
+	if embed {
+		var err error
+		// Marshal escapes <, > and &, so the data can't close the script tag
+		if data, err = json.Marshal(c.Data()); err != nil {
+			return nil, err
+		}
+	}
+	return bytes.Replace([]byte(page), []byte("/*DATA*/null"), data, 1), nil
+}
+
+func (c *Collector) handlePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	body, err := c.render(false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(body)
+}
+
+func (c *Collector) handleData(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = json.NewEncoder(w).Encode(c.Data())
+}
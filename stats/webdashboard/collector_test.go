@@ -0,0 +1,142 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestAggregateValues(t *testing.T) {
+	assert.Equal(t, map[string]float64{"count": 6, "rate": 3},
+		aggregateValues(stats.Counter, []float64{1, 2, 3}, 2))
+	assert.Equal(t, map[string]float64{"value": 3},
+		aggregateValues(stats.Gauge, []float64{5, 1, 3}, 1))
+	assert.Equal(t, map[string]float64{"rate": 0.25},
+		aggregateValues(stats.Rate, []float64{1, 0, 0, 0}, 1))
+
+	values := make([]float64, 0, 100)
+	for i := 100; i > 0; i-- {
+		values = append(values, float64(i))
+	}
+	assert.Equal(t, map[string]float64{"avg": 50.5, "p(95)": 95, "max": 100},
+		aggregateValues(stats.Trend, values, 1))
+}
+
+func TestCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k6-web-dashboard")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	report := filepath.Join(dir, "report.html")
+
+	c, err := New(NewConfig().Apply(Config{
+		Port:   null.IntFrom(0),
+		Period: types.NullDurationFrom(10 * time.Millisecond),
+		Report: null.StringFrom(report),
+	}))
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+	assert.True(t, strings.HasPrefix(c.Link(), "http://127.0.0.1:"), c.Link())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	c.Collect([]stats.SampleContainer{stats.Samples{
+		{Metric: reqs, Time: time.Now(), Value: 1},
+		{Metric: reqs, Time: time.Now(), Value: 1},
+		{Metric: duration, Time: time.Now(), Value: 120},
+		{Metric: duration, Time: time.Now(), Value: 80},
+	}})
+	time.Sleep(50 * time.Millisecond)
+
+	res, err := http.Get(c.Link() + "/data")
+	require.NoError(t, err)
+	var data Data
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&data))
+	_ = res.Body.Close()
+	require.Len(t, data.Metrics, 2)
+	assert.Equal(t, "http_req_duration", data.Metrics[0].Name)
+	assert.Equal(t, stats.Time, data.Metrics[0].Contains)
+	assert.Equal(t, []float64{100}, data.Metrics[0].Values["avg"])
+	assert.Equal(t, []float64{120}, data.Metrics[0].Values["p(95)"])
+	assert.Equal(t, "http_reqs", data.Metrics[1].Name)
+	assert.Equal(t, []float64{2}, data.Metrics[1].Values["count"])
+	assert.Len(t, data.Metrics[1].Times, 1)
+
+	res, err = http.Get(c.Link())
+	require.NoError(t, err)
+	page, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Contains(t, string(page), "var DATA = null;")
+
+	c.Collect([]stats.SampleContainer{stats.Sample{
+		Metric: stats.New("</script>", stats.Gauge), Time: time.Now(), Value: 3,
+	}})
+	cancel()
+	<-done
+
+	page, err = ioutil.ReadFile(report)
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `"name":"http_reqs"`)
+	assert.Contains(t, string(page), `"name":"\u003c/script\u003e"`)
+	assert.NotContains(t, string(page), "var DATA = null;")
+
+	_, err = http.Get(c.Link() + "/data")
+	assert.Error(t, err, "the dashboard is still being served")
+}
+
+func TestCollectorPortInUse(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	port := int64(listener.Addr().(*net.TCPAddr).Port)
+	c, err := New(NewConfig().Apply(Config{Host: null.StringFrom("127.0.0.1"), Port: null.IntFrom(port)}))
+	require.NoError(t, err)
+	err = c.Init()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "couldn't start the web dashboard")
+	}
+
+	_, err = New(NewConfig().Apply(Config{Period: types.NullDurationFrom(0)}))
+	assert.EqualError(t, err, "the web dashboard period has to be positive")
+}
@@ -0,0 +1,116 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kubernetes/helm/pkg/strvals"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Config is the config for the web dashboard.
+type Config struct {
+	// The address that the dashboard is served on, e.g. http://localhost:5665.
+	Host null.String `json:"host" envconfig:"WEB_DASHBOARD_HOST"`
+	Port null.Int    `json:"port" envconfig:"WEB_DASHBOARD_PORT"`
+
+	// How often the metrics are aggregated into a new point of the charts.
+	Period types.NullDuration `json:"period" envconfig:"WEB_DASHBOARD_PERIOD"`
+
+	// Where the standalone HTML report is written at the end of the test; empty disables it.
+	Report null.String `json:"report" envconfig:"WEB_DASHBOARD_REPORT"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		Host:   null.NewString("localhost", false),
+		Port:   null.NewInt(5665, false),
+		Period: types.NewNullDuration(1*time.Second, false),
+		Report: null.NewString("k6-report.html", false),
+	}
+}
+
+// Apply saves config non-zero config values from the passed config in the receiver.
+func (c Config) Apply(cfg Config) Config {
+	if cfg.Host.Valid {
+		c.Host = cfg.Host
+	}
+	if cfg.Port.Valid {
+		c.Port = cfg.Port
+	}
+	if cfg.Period.Valid {
+		c.Period = cfg.Period
+	}
+	if cfg.Report.Valid {
+		c.Report = cfg.Report
+	}
+	return c
+}
+
+// Addr returns the host:port address that the dashboard listens on.
+func (c Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host.String, c.Port.Int64)
+}
+
+// ParseArg takes the argument of `--out web-dashboard=...`, which is a list of key=value pairs,
+// e.g. `port=8080,period=2s,report=results.html`.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if arg == "" {
+		return c, nil
+	}
+
+	params, err := strvals.ParseString(arg)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range params {
+		value, ok := v.(string)
+		if !ok {
+			return c, errors.Errorf("invalid web dashboard option '%s'", k)
+		}
+		switch k {
+		case "host":
+			c.Host = null.StringFrom(value)
+		case "port":
+			port, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || port < 0 || port > 65535 {
+				return c, errors.Errorf("invalid web dashboard port '%s'", value)
+			}
+			c.Port = null.IntFrom(port)
+		case "period":
+			if err := c.Period.UnmarshalText([]byte(value)); err != nil {
+				return c, errors.Wrap(err, "invalid web dashboard period")
+			}
+		case "report":
+			c.Report = null.StringFrom(value)
+		default:
+			return c, errors.Errorf("unknown web dashboard option '%s'", k)
+		}
+	}
+	return c, nil
+}
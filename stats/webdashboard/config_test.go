@@ -0,0 +1,78 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestParseArg(t *testing.T) {
+	c, err := ParseArg("")
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, c)
+
+	c, err = ParseArg("host=0.0.0.0,port=8080,period=2s,report=results.html")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Host:   null.StringFrom("0.0.0.0"),
+		Port:   null.IntFrom(8080),
+		Period: types.NullDurationFrom(2 * time.Second),
+		Report: null.StringFrom("results.html"),
+	}, c)
+
+	c, err = ParseArg("report=")
+	require.NoError(t, err)
+	assert.Equal(t, null.StringFrom(""), c.Report)
+
+	testdata := map[string]string{
+		"port=http":    "invalid web dashboard port 'http'",
+		"port=70000":   "invalid web dashboard port '70000'",
+		"period=often": "invalid web dashboard period",
+		"colour=blue":  "unknown web dashboard option 'colour'",
+	}
+	for arg, msg := range testdata {
+		_, err := ParseArg(arg)
+		if assert.Error(t, err, arg) {
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+}
+
+func TestConfigEnv(t *testing.T) {
+	defer os.Clearenv()
+	os.Clearenv()
+	require.NoError(t, os.Setenv("K6_WEB_DASHBOARD_PORT", "8080"))
+	require.NoError(t, os.Setenv("K6_WEB_DASHBOARD_REPORT", "results.html"))
+
+	c := NewConfig()
+	require.NoError(t, envconfig.Process("k6", &c))
+	assert.Equal(t, null.IntFrom(8080), c.Port)
+	assert.Equal(t, null.StringFrom("results.html"), c.Report)
+	assert.Equal(t, "localhost:8080", c.Addr())
+}
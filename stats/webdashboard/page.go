@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webdashboard
+
+// The dashboard page draws a chart per metric on a canvas, without any external resources, so
+// that the report can be opened offline. DATA is embedded in reports; otherwise it's polled.
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>k6 dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 0; padding: 1em 2em; background: #f7f7f9; color: #333; }
+h1 { font-size: 1.4em; }
+#charts { display: flex; flex-wrap: wrap; }
+.chart { background: #fff; border: 1px solid #ddd; border-radius: 4px; margin: 0 1em 1em 0; padding: 0.5em; }
+.chart h2 { font-size: 1em; margin: 0 0 0.3em; }
+.legend span { margin-right: 1em; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>k6 <span id="status"></span></h1>
+<div id="charts"></div>
+<script>
+var DATA = /*DATA*/null;
+var COLORS = ["#7d64ff", "#e2487a", "#1ea672", "#f08c00"];
+
+function format(series, v) {
+  if (series.contains === "time") { return v.toFixed(2) + "ms"; }
+  if (series.contains === "data") { return (v / 1024).toFixed(1) + "kB"; }
+  if (series.type === "rate" && series.values.rate) { return (v * 100).toFixed(2) + "%"; }
+  return Math.round(v * 100) / 100 + "";
+}
+
+function chart(series) {
+  var id = "chart-" + series.name.replace(/[^a-zA-Z0-9_-]/g, "_");
+  var div = document.getElementById(id);
+  if (!div) {
+    div = document.createElement("div");
+    div.id = id;
+    div.className = "chart";
+    div.innerHTML = "<h2></h2><canvas width='480' height='180'></canvas><div class='legend'></div>";
+    div.querySelector("h2").textContent = series.name;
+    document.getElementById("charts").appendChild(div);
+  }
+  var canvas = div.querySelector("canvas"), ctx = canvas.getContext("2d");
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+
+  var keys = Object.keys(series.values).sort(), max = 0;
+  keys.forEach(function(k) { series.values[k].forEach(function(v) { max = Math.max(max, v); }); });
+  max = max || 1;
+  var first = series.times[0], span = (series.times[series.times.length - 1] - first) || 1;
+  var legend = div.querySelector(".legend");
+  legend.innerHTML = "";
+  keys.forEach(function(k, i) {
+    var values = series.values[k];
+    ctx.strokeStyle = COLORS[i % COLORS.length];
+    ctx.lineWidth = 2;
+    ctx.beginPath();
+    values.forEach(function(v, j) {
+      var x = 4 + (series.times[j] - first) / span * (canvas.width - 8);
+      var y = canvas.height - 4 - v / max * (canvas.height - 8);
+      if (j === 0) { ctx.moveTo(x, y); } else { ctx.lineTo(x, y); }
+    });
+    ctx.stroke();
+    var label = document.createElement("span");
+    label.style.color = ctx.strokeStyle;
+    label.textContent = k + ": " + format(series, values[values.length - 1]);
+    legend.appendChild(label);
+  });
+}
+
+function draw(data) {
+  data.metrics.forEach(chart);
+  var elapsed = data.metrics.reduce(function(t, s) { return Math.max(t, s.times[s.times.length - 1]); }, 0);
+  if (elapsed) {
+    document.getElementById("status").textContent = "- " + Math.round((elapsed - Date.parse(data.start)) / 1000) + "s";
+  }
+}
+
+function poll() {
+  fetch("data").then(function(r) { return r.json(); }).then(function(data) {
+    draw(data);
+    setTimeout(poll, data.period * 1000);
+  }).catch(function() {
+    document.getElementById("status").textContent = "- finished";
+  });
+}
+
+if (DATA) { draw(DATA); } else { poll(); }
+</script>
+</body>
+</html>
+`
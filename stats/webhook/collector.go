@@ -0,0 +1,232 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	attempts       = 3
+	retryInterval  = time.Second
+)
+
+// Verify that Collector implements lib.ThresholdCollector
+var _ lib.ThresholdCollector = &Collector{}
+
+// Collector fires webhooks on the events of a test: when it starts, ends or is aborted, and when
+// a threshold fails, or breaches or recovers if it has a window. It doesn't do anything with the
+// samples, it's only a collector to get the events.
+//
+// Every webhook gets a POST with a JSON Payload. Failed requests are retried a couple of times,
+// and the end of the test waits for the requests, so that the end event isn't lost.
+type Collector struct {
+	Webhooks []Config
+	RunInfo  Run
+	Client   *http.Client
+
+	mutex   sync.Mutex
+	status  lib.RunStatus
+	aborted bool
+	failed  bool // Whether any threshold failed
+	wg      sync.WaitGroup
+}
+
+// Run is the metadata of a test run, which is in every payload.
+type Run struct {
+	ID        string    `json:"id"`
+	Script    string    `json:"script"`
+	Version   string    `json:"version"`
+	StartTime time.Time `json:"startTime,omitempty"`
+}
+
+// Payload is what webhooks get. Text is a message for humans, so that the payload works as is
+// for Slack's and Mattermost's incoming webhooks.
+type Payload struct {
+	Event     string              `json:"event"`
+	Text      string              `json:"text"`
+	Time      time.Time           `json:"time"`
+	Run       Run                 `json:"run"`
+	Status    string              `json:"status,omitempty"`
+	Threshold *lib.ThresholdEvent `json:"threshold,omitempty"`
+
+	// Only for the end event, so that e.g. a deployment can be held back if the test failed
+	ThresholdsPassed *bool `json:"thresholdsPassed,omitempty"`
+}
+
+// New creates a collector that fires the webhooks for a run of a script.
+func New(webhooks []Config, script, version string) (*Collector, error) {
+	for _, hook := range webhooks {
+		if err := hook.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &Collector{
+		Webhooks: webhooks,
+		RunInfo:  Run{ID: hex.EncodeToString(id), Script: script, Version: version},
+		Client:   &http.Client{Timeout: requestTimeout},
+		status:   lib.RunStatusFinished,
+	}, nil
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Init() error { return nil }
+
+// Run fires the start webhooks, and the end webhooks when the context is done, which it waits for.
+func (c *Collector) Run(ctx context.Context) {
+	c.mutex.Lock()
+	c.RunInfo.StartTime = time.Now()
+	c.mutex.Unlock()
+	c.fire(Payload{Event: EventStart, Text: c.text("started")})
+
+	<-ctx.Done()
+	c.mutex.Lock()
+	status, passed := c.status, !c.failed
+	c.mutex.Unlock()
+	text := c.text("finished")
+	if status != lib.RunStatusFinished {
+		text = c.text("ended: " + status.String())
+	}
+	if !passed {
+		text += ", some thresholds failed"
+	}
+	c.fire(Payload{Event: EventEnd, Text: text, Status: status.String(), ThresholdsPassed: &passed})
+	c.wg.Wait()
+}
+
+// Collect does nothing, webhooks don't get samples
+func (c *Collector) Collect(scs []stats.SampleContainer) {}
+
+// Link returns nothing, there's nothing to link to
+func (c *Collector) Link() string {
+	return ""
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() lib.TagSet {
+	return lib.TagSet{} // There are no required tags for this collector
+}
+
+// SetRunStatus fires the abort webhooks the first time that the test is aborted, and saves the
+// status for the end webhooks.
+func (c *Collector) SetRunStatus(status lib.RunStatus) {
+	c.mutex.Lock()
+	c.status = status
+	first := !c.aborted && status >= lib.RunStatusAbortedUser
+	if first {
+		c.aborted = true
+	}
+	c.mutex.Unlock()
+
+	if first {
+		c.fire(Payload{Event: EventAbort, Text: c.text("aborted: " + status.String()), Status: status.String()})
+	}
+}
+
+// ThresholdChanged fires the threshold webhooks.
+func (c *Collector) ThresholdChanged(event lib.ThresholdEvent) {
+	state := "failed"
+	if !event.Breached {
+		state = "recovered"
+	}
+	c.mutex.Lock()
+	c.failed = c.failed || event.Breached // A breach fails the test, even if it recovers later
+	c.mutex.Unlock()
+	text := c.text(fmt.Sprintf("threshold `%s` on %s %s after %s", event.Threshold, event.Metric, state, event.Time))
+	c.fire(Payload{Event: EventThreshold, Text: text, Threshold: &event})
+}
+
+func (c *Collector) text(what string) string {
+	return fmt.Sprintf("k6 test %s %s", filepath.Base(c.RunInfo.Script), what)
+}
+
+// fire sends a payload to the webhooks that are fired on its event, in the background.
+func (c *Collector) fire(p Payload) {
+	c.mutex.Lock()
+	p.Run = c.RunInfo
+	c.mutex.Unlock()
+	p.Time = time.Now()
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.WithError(err).Error("Webhooks: Couldn't encode the payload")
+		return
+	}
+
+	for _, hook := range c.Webhooks {
+		if !hook.FiredOn(p.Event) {
+			continue
+		}
+		c.wg.Add(1)
+		go func(hook Config) {
+			defer c.wg.Done()
+			var err error
+			for i := 0; i < attempts; i++ {
+				if i > 0 {
+					time.Sleep(retryInterval)
+				}
+				if err = c.send(hook, body); err == nil {
+					return
+				}
+			}
+			log.WithError(err).WithFields(log.Fields{"url": hook.URL, "event": p.Event}).Warn("Webhooks: Couldn't fire a webhook")
+		}(hook)
+	}
+}
+
+func (c *Collector) send(hook Config, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6/"+c.RunInfo.Version)
+	for k, v := range hook.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= 300 {
+		return errors.Errorf("the webhook responded with %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package webhook
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// The events that webhooks can be fired on.
+const (
+	EventStart     = "start"
+	EventEnd       = "end"
+	EventAbort     = "abort"
+	EventThreshold = "threshold"
+)
+
+var allEvents = []string{EventStart, EventEnd, EventAbort, EventThreshold}
+
+// Config is the config of a webhook.
+type Config struct {
+	URL string `json:"url"`
+
+	// The events that the webhook is fired on, all of them if it's empty.
+	Events []string `json:"events,omitempty"`
+
+	// Headers that are sent with the webhook's requests, e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// ParseArg takes the argument of `--webhook`, which is the webhook's URL, optionally after the
+// events that it's fired on, e.g. end,abort@https://hooks.slack.com/services/...
+func ParseArg(arg string) (Config, error) {
+	c := Config{URL: arg}
+	if i := strings.IndexByte(arg, '@'); i >= 0 && !strings.Contains(arg[:i], "://") {
+		c.URL = arg[i+1:]
+		c.Events = strings.Split(arg[:i], ",")
+	}
+	return c, c.Validate()
+}
+
+// Validate checks that the URL is an http:// or https:// URL, and that the events exist.
+func (c Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("invalid webhook URL '%s', it has to be an http:// or https:// URL", c.URL)
+	}
+	for _, event := range c.Events {
+		if !hasEvent(allEvents, event) {
+			return errors.Errorf("invalid webhook event '%s', it has to be one of: %s", event, strings.Join(allEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// FiredOn returns whether the webhook is fired on an event.
+func (c Config) FiredOn(event string) bool {
+	return len(c.Events) == 0 || hasEvent(c.Events, event)
+}
+
+func hasEvent(events []string, event string) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
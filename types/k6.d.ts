@@ -0,0 +1,292 @@
+// Type definitions for the k6 JavaScript API.
+//
+// k6 runs TypeScript scripts directly, by stripping their types, so these definitions are only for
+// type-checking scripts with `tsc --noEmit` and for editors. Reference them from a script with
+// `/// <reference path="k6.d.ts" />`, or add them to the "files" of a tsconfig.json.
+
+declare const __ENV: { [name: string]: string };
+declare const __VU: number;
+declare const __ITER: number;
+declare const __filename: string;
+declare const __dirname: string;
+
+declare function require(name: string): any;
+declare const module: { exports: any };
+declare let exports: any;
+
+declare function setTimeout(fn: (...args: any[]) => void, delay?: number, ...args: any[]): number;
+declare function setInterval(fn: (...args: any[]) => void, delay?: number, ...args: any[]): number;
+declare function setImmediate(fn: (...args: any[]) => void, ...args: any[]): number;
+declare function clearTimeout(id: number): void;
+declare function clearInterval(id: number): void;
+declare function clearImmediate(id: number): void;
+
+declare function open(path: string): string;
+declare function open(path: string, mode: "b"): number[];
+
+declare module "k6" {
+    export type Checkers<T> = { [description: string]: (value: T) => boolean };
+
+    export interface CheckOptions {
+        severity?: "error" | "warn";
+        threshold?: string;
+    }
+
+    export function check<T>(value: T, checkers: Checkers<T>, tags?: { [name: string]: string } | null, options?: CheckOptions): boolean;
+    export function group<T>(name: string, fn: () => T): T;
+    export function sleep(seconds: number): void;
+    export function randomSleep(min: number, max: number, distribution?: "uniform" | "normal" | "exponential"): number;
+    export function randomSeed(seed: number): void;
+    export function fail(message?: string): never;
+}
+
+declare module "k6/http" {
+    export type Body = string | number[] | { [name: string]: string | FileData };
+
+    export interface FileData {
+        data: string | number[];
+        filename?: string;
+        content_type?: string;
+    }
+
+    export interface RetryPolicy {
+        attempts?: number;
+        statuses?: number[];
+        errors?: boolean;
+        backoff?: string | number;
+        maxBackoff?: string | number;
+    }
+
+    export interface Params {
+        headers?: { [name: string]: string };
+        cookies?: { [name: string]: string | { value: string; replace?: boolean } };
+        tags?: { [name: string]: string };
+        jar?: CookieJar;
+        redirects?: number;
+        timeout?: number | string;
+        auth?: "basic" | "digest" | "ntlm";
+        compression?: string;
+        unixSocket?: string;
+        throw?: boolean;
+        retry?: RetryPolicy;
+        responseCallback?: ExpectedStatuses | null;
+    }
+
+    export interface Cookie {
+        name: string;
+        value: string;
+        domain: string;
+        path: string;
+        http_only: boolean;
+        secure: boolean;
+        max_age: number;
+        expires: number;
+    }
+
+    export interface Timings {
+        duration: number;
+        blocked: number;
+        queued: number;
+        looking_up: number;
+        connecting: number;
+        tls_handshaking: number;
+        sending: number;
+        waiting: number;
+        receiving: number;
+    }
+
+    export interface Response {
+        remote_ip: string;
+        remote_port: number;
+        url: string;
+        status: number;
+        proto: string;
+        headers: { [name: string]: string };
+        cookies: { [name: string]: Cookie[] };
+        body: string;
+        body_size: number;
+        encoded_body_size: number;
+        timings: Timings;
+        tls_version: string;
+        tls_cipher_suite: string;
+        error: string;
+        error_code: number;
+        request: { method: string; url: string; headers: { [name: string]: string[] }; body: string };
+
+        json(selector?: string): any;
+        html(selector?: string): import("k6/html").Selection;
+        selectXML(path?: string): any;
+        submitForm(args?: { formSelector?: string; fields?: { [name: string]: string }; submitSelector?: string; params?: Params }): Response;
+        clickLink(args?: { selector?: string; params?: Params }): Response;
+    }
+
+    export interface BatchRequest {
+        method: string;
+        url: string;
+        body?: Body;
+        params?: Params;
+    }
+
+    export interface CookieJar {
+        cookiesForURL(url: string): { [name: string]: string[] };
+        set(url: string, name: string, value: string, options?: { domain?: string; path?: string; expires?: string; max_age?: number; secure?: boolean; http_only?: boolean }): void;
+    }
+
+    export interface ExpectedStatuses {}
+
+    export function get(url: string, params?: Params): Response;
+    export function head(url: string, params?: Params): Response;
+    export function post(url: string, body?: Body | null, params?: Params): Response;
+    export function put(url: string, body?: Body | null, params?: Params): Response;
+    export function patch(url: string, body?: Body | null, params?: Params): Response;
+    export function del(url: string, body?: Body | null, params?: Params): Response;
+    export function options(url: string, body?: Body | null, params?: Params): Response;
+    export function request(method: string, url: string, body?: Body | null, params?: Params): Response;
+    export function asyncRequest(method: string, url: string, body?: Body | null, params?: Params): Promise<Response>;
+    export function batch(requests: Array<string | BatchRequest | [string, string, (Body | null)?, Params?]>): Response[];
+    export function batch(requests: { [name: string]: string | BatchRequest }): { [name: string]: Response };
+    export function file(data: string | number[], filename?: string, contentType?: string): FileData;
+    export function cookieJar(): CookieJar;
+    export function expectedStatuses(...statuses: Array<number | { min: number; max: number }>): ExpectedStatuses;
+    export function setResponseCallback(callback: ExpectedStatuses | null): void;
+
+    const http: {
+        get: typeof get; head: typeof head; post: typeof post; put: typeof put; patch: typeof patch;
+        del: typeof del; options: typeof options; request: typeof request; batch: typeof batch;
+        file: typeof file; cookieJar: typeof cookieJar; expectedStatuses: typeof expectedStatuses;
+        setResponseCallback: typeof setResponseCallback;
+    };
+    export default http;
+}
+
+declare module "k6/html" {
+    export interface Selection {
+        find(selector: string): Selection;
+        closest(selector: string): Selection;
+        has(selector: string): Selection;
+        not(selector: string): Selection;
+        filter(selector: string): Selection;
+        is(selector: string): boolean;
+        children(selector?: string): Selection;
+        parent(selector?: string): Selection;
+        parents(selector?: string): Selection;
+        siblings(selector?: string): Selection;
+        next(selector?: string): Selection;
+        nextAll(selector?: string): Selection;
+        prev(selector?: string): Selection;
+        prevAll(selector?: string): Selection;
+        first(): Selection;
+        last(): Selection;
+        eq(index: number): Selection;
+        slice(start: number, end?: number): Selection;
+        size(): number;
+        text(): string;
+        html(): string | undefined;
+        val(): string | undefined;
+        attr(name: string): string | undefined;
+        data(key?: string): any;
+        each(fn: (index: number, element: any) => void): void;
+        map<T>(fn: (index: number, element: Selection) => T): T[];
+        toArray(): Selection[];
+    }
+
+    export function parseHTML(html: string): Selection;
+}
+
+declare module "k6/metrics" {
+    export type Tags = { [name: string]: string };
+
+    export class Counter {
+        constructor(name: string, isTime?: boolean);
+        add(value: number | boolean, tags?: Tags): void;
+    }
+    export class Gauge {
+        constructor(name: string, isTime?: boolean);
+        add(value: number | boolean, tags?: Tags): void;
+    }
+    export class Rate {
+        constructor(name: string, isTime?: boolean);
+        add(value: number | boolean, tags?: Tags): void;
+    }
+    export class Trend {
+        constructor(name: string, isTime?: boolean);
+        add(value: number | boolean, tags?: Tags): void;
+    }
+}
+
+declare module "k6/encoding" {
+    export function b64encode(input: string | number[], encoding?: "std" | "rawstd" | "url" | "rawurl"): string;
+    export function b64decode(input: string, encoding?: "std" | "rawstd" | "url" | "rawurl"): string;
+    export function hexEncode(input: string | number[]): string;
+    export function hexDecode(input: string): string;
+}
+
+declare module "k6/crypto" {
+    export type Encoding = "hex" | "base64" | "base64url" | "base64rawurl" | "binary";
+    export type Algorithm = "md4" | "md5" | "sha1" | "sha224" | "sha256" | "sha384" | "sha512" | "sha512_224" | "sha512_256" | "ripemd160";
+
+    export interface Hasher {
+        update(input: string | number[]): void;
+        digest(encoding: Encoding): string;
+    }
+
+    export function md4(input: string | number[], encoding: Encoding): string;
+    export function md5(input: string | number[], encoding: Encoding): string;
+    export function sha1(input: string | number[], encoding: Encoding): string;
+    export function sha256(input: string | number[], encoding: Encoding): string;
+    export function sha384(input: string | number[], encoding: Encoding): string;
+    export function sha512(input: string | number[], encoding: Encoding): string;
+    export function hmac(algorithm: Algorithm, key: string, input: string | number[], encoding: Encoding): string;
+    export function createHash(algorithm: Algorithm): Hasher;
+    export function createHMAC(algorithm: Algorithm, key: string): Hasher;
+    export function randomBytes(size: number): number[];
+}
+
+declare module "k6/ws" {
+    export interface Socket {
+        on(event: "open" | "close" | "ping" | "pong", handler: () => void): void;
+        on(event: "message", handler: (message: string) => void): void;
+        on(event: "error", handler: (error: { error(): string }) => void): void;
+        send(message: string): void;
+        ping(): void;
+        setTimeout(fn: () => void, ms: number): void;
+        setInterval(fn: () => void, ms: number): void;
+        close(code?: number): void;
+    }
+
+    export interface Params {
+        headers?: { [name: string]: string };
+        tags?: { [name: string]: string };
+    }
+
+    export function connect(url: string, params: Params | null, handler: (socket: Socket) => void): { url: string; status: number; headers: { [name: string]: string }; body: string; error: string };
+    export function connect(url: string, handler: (socket: Socket) => void): { url: string; status: number; headers: { [name: string]: string }; body: string; error: string };
+}
+
+declare module "k6/expect" {
+    export interface Assertion {
+        to: Assertion; be: Assertion; been: Assertion; is: Assertion; that: Assertion; which: Assertion;
+        and: Assertion; has: Assertion; have: Assertion; with: Assertion; at: Assertion; of: Assertion;
+        same: Assertion; does: Assertion; still: Assertion;
+        not: Assertion;
+        ok: Assertion; true: Assertion; false: Assertion; null: Assertion; undefined: Assertion;
+        exist: Assertion; NaN: Assertion; empty: Assertion;
+        equal(value: any): Assertion;
+        eql(value: any): Assertion;
+        above(n: number): Assertion;
+        least(n: number): Assertion;
+        below(n: number): Assertion;
+        most(n: number): Assertion;
+        within(min: number, max: number): Assertion;
+        a(type: string): Assertion;
+        an(type: string): Assertion;
+        include(value: any): Assertion;
+        property(name: string, value?: any): Assertion;
+        lengthOf(n: number): Assertion;
+        match(re: RegExp): Assertion;
+        oneOf(values: any[]): Assertion;
+        satisfy(fn: (value: any) => boolean): Assertion;
+    }
+
+    export function expect(value: any, message?: string): Assertion;
+}
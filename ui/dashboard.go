@@ -0,0 +1,244 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Samples are handed to collectors in batches, so the last seconds aren't complete yet; they're
+// left out of the dashboard until they are.
+const dashboardLag = 2 * time.Second
+
+// Dashboard is a live view of a running test: the progress bar, VUs, and sparklines of the
+// requests per second, error rate and 95th percentile of the request duration, plus the status
+// of the thresholds. It's redrawn in place, so it's only useful on terminals.
+//
+// It implements lib.Collector, so that it gets the samples the same way outputs do.
+type Dashboard struct {
+	History    int // How many seconds the sparklines show
+	Progress   func() string
+	VUs        func() (current, max int64)
+	Thresholds func() map[string]bool // Metric names and whether their thresholds pass
+
+	lock    sync.Mutex
+	seconds map[int64]*dashboardSecond
+	lines   int // How many lines were drawn last time, to redraw them in place
+}
+
+type dashboardSecond struct {
+	requests, failed int
+	durations        []float64
+}
+
+var _ lib.Collector = &Dashboard{}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (d *Dashboard) Init() error { return nil }
+
+// Run does nothing, it's only included to satisfy the lib.Collector interface
+func (d *Dashboard) Run(ctx context.Context) {}
+
+// Link returns an empty string, it's only included to satisfy the lib.Collector interface
+func (d *Dashboard) Link() string { return "" }
+
+// GetRequiredSystemTags returns the status tag, which is needed to tell failed requests apart.
+func (d *Dashboard) GetRequiredSystemTags() lib.TagSet {
+	return lib.GetTagSet("status")
+}
+
+// SetRunStatus does nothing, it's only included to satisfy the lib.Collector interface
+func (d *Dashboard) SetRunStatus(status lib.RunStatus) {}
+
+// Collect adds the HTTP request samples to the second they're from.
+func (d *Dashboard) Collect(scs []stats.SampleContainer) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.seconds == nil {
+		d.seconds = make(map[int64]*dashboardSecond)
+	}
+
+	for _, sc := range scs {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.HTTPReqs && s.Metric != metrics.HTTPReqDuration {
+				continue
+			}
+			sec := d.seconds[s.Time.Unix()]
+			if sec == nil {
+				sec = &dashboardSecond{}
+				d.seconds[s.Time.Unix()] = sec
+			}
+			if s.Metric == metrics.HTTPReqDuration {
+				sec.durations = append(sec.durations, s.Value)
+				continue
+			}
+			sec.requests++
+			if status, ok := s.Tags.Get("status"); ok && (status == "0" || status >= "400") {
+				sec.failed++
+			}
+		}
+	}
+}
+
+// Redraw draws the dashboard over the previously drawn one.
+func (d *Dashboard) Redraw(w io.Writer, now time.Time) {
+	lines := d.Lines(now)
+
+	d.lock.Lock()
+	if d.lines > 0 {
+		_, _ = fmt.Fprintf(w, "\x1b[%dA", d.lines)
+	}
+	d.lines = len(lines)
+	d.lock.Unlock()
+
+	for _, line := range lines {
+		_, _ = fmt.Fprintf(w, "%s\x1b[0K\n", line)
+	}
+}
+
+// Lines returns the lines of the dashboard, for the complete seconds before now.
+func (d *Dashboard) Lines(now time.Time) []string {
+	history := d.History
+	if history <= 0 {
+		history = 30
+	}
+	rps := make([]float64, history)
+	errorRate := make([]float64, history)
+	p95 := make([]float64, history)
+
+	d.lock.Lock()
+	last := now.Add(-dashboardLag).Unix()
+	for t, sec := range d.seconds {
+		i := history - 1 - int(last-t)
+		switch {
+		case i < 0:
+			delete(d.seconds, t) // Too old to be shown anymore
+		case i < history:
+			rps[i] = float64(sec.requests)
+			if sec.requests > 0 {
+				errorRate[i] = float64(sec.failed) / float64(sec.requests)
+			}
+			p95[i] = percentile(sec.durations, 0.95)
+		}
+	}
+	d.lock.Unlock()
+
+	var lines []string
+	if d.Progress != nil {
+		lines = append(lines, d.Progress(), "")
+	}
+	if d.VUs != nil {
+		current, max := d.VUs()
+		lines = append(lines, fmt.Sprintf("  %-10s %s", "vus", ValueColor.Sprintf("%d/%d", current, max)))
+	}
+	row := func(name, value string, values []float64) string {
+		return fmt.Sprintf("  %-10s %s %s", name, ValueColor.Sprintf("%9s", value), Sparkline(values))
+	}
+	lines = append(lines,
+		row("rps", fmt.Sprintf("%.1f", rps[history-1]), rps),
+		row("errors", fmt.Sprintf("%.2f%%", errorRate[history-1]*100), errorRate),
+		row("p95", fmt.Sprintf("%.1fms", p95[history-1]), p95),
+	)
+
+	if d.Thresholds != nil {
+		thresholds := d.Thresholds()
+		names := make([]string, 0, len(thresholds))
+		for name := range thresholds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		statuses := make([]string, len(names))
+		for i, name := range names {
+			if thresholds[name] {
+				statuses[i] = SuccColor.Sprint("✓ " + name)
+			} else {
+				statuses[i] = FailColor.Sprint("✗ " + name)
+			}
+		}
+		if len(statuses) == 0 {
+			statuses = []string{GrayColor.Sprint("none")}
+		}
+		lines = append(lines, fmt.Sprintf("  %-10s %s", "thresholds", strings.Join(statuses, "  ")))
+	}
+	return lines
+}
+
+// Sparkline draws values as a line of block characters, scaled to their maximum.
+func Sparkline(values []float64) string {
+	return ScaledSparkline(values, MaxValue(values))
+}
+
+// ScaledSparkline draws values as a line of block characters, scaled so that max is the highest
+// block, e.g. for several lines to have the same scale, and with spaces for NaNs.
+func ScaledSparkline(values []float64, max float64) string {
+	var b strings.Builder
+	for _, v := range values {
+		if math.IsNaN(v) {
+			b.WriteRune(' ')
+			continue
+		}
+		i := 0
+		if max > 0 {
+			i = int(v / max * float64(len(sparkBlocks)-1))
+		}
+		if i < 0 {
+			i = 0
+		} else if i >= len(sparkBlocks) {
+			i = len(sparkBlocks) - 1
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
+
+// MaxValue returns the largest of the values that aren't NaN, or 0 if there are none.
+func MaxValue(values []float64) float64 {
+	max := 0.0
+	for _, v := range values {
+		if !math.IsNaN(v) && v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	return sorted[int(p*float64(len(sorted)-1))]
+}
@@ -0,0 +1,102 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "▁▁▁", Sparkline([]float64{0, 0, 0}))
+	assert.Equal(t, "▁▄█", Sparkline([]float64{0, 5, 10}))
+	assert.Equal(t, "█▁", Sparkline([]float64{3, 0}))
+	assert.Equal(t, "▁▄ █", ScaledSparkline([]float64{0, 10, math.NaN(), 30}, 20))
+	assert.Equal(t, 30.0, MaxValue([]float64{0, 10, math.NaN(), 30}))
+}
+
+func TestDashboard(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	now := time.Unix(1000, 0)
+	request := func(at time.Time, status string, duration float64) stats.SampleContainer {
+		tags := stats.IntoSampleTags(&map[string]string{"status": status})
+		return stats.Samples{
+			{Metric: metrics.HTTPReqs, Time: at, Tags: tags, Value: 1},
+			{Metric: metrics.HTTPReqDuration, Time: at, Tags: tags, Value: duration},
+		}
+	}
+
+	d := &Dashboard{
+		History:  4,
+		Progress: func() string { return "running [===>]" },
+		VUs:      func() (int64, int64) { return 5, 10 },
+		Thresholds: func() map[string]bool {
+			return map[string]bool{"http_req_duration": true, "checks": false}
+		},
+	}
+	d.Collect([]stats.SampleContainer{
+		request(now.Add(-10*time.Second), "200", 1000), // Too old
+		request(now.Add(-3*time.Second), "200", 100),
+		request(now.Add(-3*time.Second), "500", 300),
+		request(now.Add(-2*time.Second), "200", 50),
+		request(now.Add(-2*time.Second), "0", 10),
+		request(now.Add(-2*time.Second), "404", 20),
+		request(now.Add(-2*time.Second), "200", 40),
+		request(now, "200", 5000), // Not complete yet
+	})
+
+	assert.Equal(t, []string{
+		"running [===>]",
+		"",
+		"  vus        5/10",
+		"  rps              4.0 ▁▁▄█",
+		"  errors        50.00% ▁▁██",
+		"  p95           40.0ms ▁▁█▃",
+		"  thresholds ✗ checks  ✓ http_req_duration",
+	}, d.Lines(now))
+	assert.Len(t, d.seconds, 3, "the old second should be forgotten")
+
+	var buf bytes.Buffer
+	d.Redraw(&buf, now)
+	d.Redraw(&buf, now)
+	assert.Equal(t, 1, strings.Count(buf.String(), "\x1b[7A"), "the second redraw should move up over the first")
+
+	t.Run("empty", func(t *testing.T) {
+		d := &Dashboard{History: 2, Thresholds: func() map[string]bool { return nil }}
+		assert.Equal(t, []string{
+			"  rps              0.0 ▁▁",
+			"  errors         0.00% ▁▁",
+			"  p95            0.0ms ▁▁",
+			"  thresholds none",
+		}, d.Lines(now))
+	})
+}
@@ -24,8 +24,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // A Field in a form.
@@ -64,9 +66,7 @@ func (f Form) Run(r io.Reader, w io.Writer) (map[string]interface{}, error) {
 				return nil, err
 			}
 
-			color.Set(color.FgCyan)
-			s, err := buf.ReadString('\n')
-			color.Unset()
+			s, err := readField(field, r, buf, w)
 			if err != nil {
 				return nil, err
 			}
@@ -86,3 +86,22 @@ func (f Form) Run(r io.Reader, w io.Writer) (map[string]interface{}, error) {
 
 	return data, nil
 }
+
+// readField reads a line of input, without echoing it if the field is a PasswordField and the
+// input is a terminal.
+func readField(field Field, r io.Reader, buf *bufio.Reader, w io.Writer) (string, error) {
+	if f, ok := r.(*os.File); ok {
+		if _, isPassword := field.(PasswordField); isPassword && terminal.IsTerminal(int(f.Fd())) {
+			data, err := terminal.ReadPassword(int(f.Fd()))
+			if err != nil {
+				return "", err
+			}
+			_, err = fmt.Fprintln(w)
+			return string(data), err
+		}
+	}
+
+	color.Set(color.FgCyan)
+	defer color.Unset()
+	return buf.ReadString('\n')
+}
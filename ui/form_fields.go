@@ -62,3 +62,18 @@ func (f StringField) Clean(s string) (interface{}, error) {
 	}
 	return s, nil
 }
+
+var _ Field = PasswordField{}
+
+// A PasswordField is a StringField for secrets: what's typed into it isn't echoed on terminals,
+// and its default isn't shown.
+type PasswordField struct {
+	StringField
+}
+
+func (f PasswordField) GetLabelExtra() string {
+	if f.Default == "" {
+		return ""
+	}
+	return "unchanged if empty"
+}
@@ -61,3 +61,17 @@ func TestStringField(t *testing.T) {
 		assert.Equal(t, "default", v)
 	})
 }
+
+func TestPasswordField(t *testing.T) {
+	f := PasswordField{StringField{Key: "key", Label: "label", Default: "secret"}}
+	assert.Equal(t, "key", f.GetKey())
+	assert.Equal(t, "unchanged if empty", f.GetLabelExtra())
+	assert.Equal(t, "", PasswordField{StringField{Key: "key", Label: "label"}}.GetLabelExtra())
+
+	v, err := f.Clean("\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", v)
+	v, err = f.Clean("hunter2\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+}
@@ -54,6 +54,18 @@ func TestForm(t *testing.T) {
 		assert.Equal(t, map[string]interface{}{"key": "Value"}, data)
 		assert.Equal(t, "  label: ", out.String())
 	})
+	t.Run("Password", func(t *testing.T) {
+		f := Form{
+			Fields: []Field{
+				PasswordField{StringField{Key: "key", Label: "label", Default: "secret"}},
+			},
+		}
+		out := bytes.NewBuffer(nil)
+		data, err := f.Run(strings.NewReader("hunter2\n"), out)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"key": "hunter2"}, data)
+		assert.NotContains(t, out.String(), "secret")
+	})
 	t.Run("Fields", func(t *testing.T) {
 		f := Form{
 			Fields: []Field{
@@ -0,0 +1,148 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/loadimpact/k6/lib"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+func (s *junitTestSuite) add(c junitTestCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+}
+
+// SummarizeJUnit writes the results of the thresholds and checks as a JUnit XML report, which
+// CI servers like Jenkins and GitLab can show natively: there's a "thresholds" test suite, with
+// a test case per threshold of every metric, and a "checks" test suite, with a test case per
+// check in every group.
+func SummarizeJUnit(w io.Writer, data SummaryData) error {
+	seconds := data.Time.Seconds()
+	thresholds := junitTestSuite{Name: "thresholds", Time: seconds}
+	names := make([]string, 0, len(data.Metrics))
+	for name := range data.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, t := range data.Metrics[name].Thresholds.Thresholds {
+			c := junitTestCase{Name: t.Source, ClassName: name}
+			if t.Failed {
+				c.Failure = &junitFailure{
+					Type:    "threshold",
+					Message: fmt.Sprintf("threshold '%s' of %s failed", t.Source, name),
+				}
+			}
+			thresholds.add(c)
+		}
+	}
+
+	checks := junitTestSuite{Name: "checks", Time: seconds}
+	if data.Root != nil {
+		addJUnitChecks(&checks, data.Root)
+	}
+
+	suites := junitTestSuites{
+		Name:     "k6",
+		Tests:    thresholds.Tests + checks.Tests,
+		Failures: thresholds.Failures + checks.Failures,
+		Time:     seconds,
+		Suites:   []junitTestSuite{thresholds, checks},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// addJUnitChecks adds the checks of a group and its subgroups, whose class names are the group
+// paths, e.g. "checks.Outer.Inner", so that CI servers show them as a tree.
+func addJUnitChecks(suite *junitTestSuite, group *lib.Group) {
+	className := "checks" + strings.Replace(group.Path, lib.GroupSeparator, ".", -1)
+
+	checkNames := make([]string, 0, len(group.Checks))
+	for name := range group.Checks {
+		checkNames = append(checkNames, name)
+	}
+	sort.Strings(checkNames)
+	for _, name := range checkNames {
+		check := group.Checks[name]
+		c := junitTestCase{Name: check.Name, ClassName: className}
+		if check.Fails > 0 {
+			c.Failure = &junitFailure{
+				Type:    "check",
+				Message: fmt.Sprintf("%d of %d checks failed", check.Fails, check.Passes+check.Fails),
+			}
+		}
+		suite.add(c)
+	}
+
+	groupNames := make([]string, 0, len(group.Groups))
+	for name := range group.Groups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, name := range groupNames {
+		addJUnitChecks(suite, group.Groups[name])
+	}
+}
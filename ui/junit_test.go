@@ -0,0 +1,73 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeJUnit(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	check, err := root.Check("status is 200")
+	require.NoError(t, err)
+	check.Passes = 10
+	login, err := root.Group("login")
+	require.NoError(t, err)
+	check, err = login.Check("has <token>")
+	require.NoError(t, err)
+	check.Passes, check.Fails = 7, 3
+
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	duration.Thresholds, err = stats.NewThresholds([]string{"p(95)<500", "avg<100"})
+	require.NoError(t, err)
+	duration.Thresholds.Thresholds[1].Failed = true
+	metrics := map[string]*stats.Metric{
+		"http_req_duration": duration,
+		"http_reqs":         stats.New("http_reqs", stats.Counter),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, SummarizeJUnit(&buf, SummaryData{Root: root, Metrics: metrics, Time: 90 * time.Second}))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="k6" tests="4" failures="2" time="90">
+  <testsuite name="thresholds" tests="2" failures="1" time="90">
+    <testcase name="p(95)&lt;500" classname="http_req_duration"></testcase>
+    <testcase name="avg&lt;100" classname="http_req_duration">
+      <failure type="threshold" message="threshold &#39;avg&lt;100&#39; of http_req_duration failed"></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="checks" tests="2" failures="1" time="90">
+    <testcase name="status is 200" classname="checks"></testcase>
+    <testcase name="has &lt;token&gt;" classname="checks.login">
+      <failure type="check" message="3 of 10 checks failed"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, buf.String())
+}
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -48,18 +49,26 @@ var (
 	ErrPercentileStatInvalidValue = errors.New("Invalid percentile stat value, accepts a number")
 )
 
-var TrendColumns = []TrendColumn{
-	{"avg", func(s *stats.TrendSink) float64 { return s.Avg }},
-	{"min", func(s *stats.TrendSink) float64 { return s.Min }},
-	{"med", func(s *stats.TrendSink) float64 { return s.Med }},
-	{"max", func(s *stats.TrendSink) float64 { return s.Max }},
-	{"p(90)", func(s *stats.TrendSink) float64 { return s.P(0.90) }},
-	{"p(95)", func(s *stats.TrendSink) float64 { return s.P(0.95) }},
+// All the trend columns that can be chosen with summaryTrendStats, besides percentiles.
+var builtinTrendColumns = []TrendColumn{
+	{Key: "avg", Get: func(s *stats.TrendSink) float64 { return s.Avg }},
+	{Key: "min", Get: func(s *stats.TrendSink) float64 { return s.Min }},
+	{Key: "med", Get: func(s *stats.TrendSink) float64 { return s.Med }},
+	{Key: "max", Get: func(s *stats.TrendSink) float64 { return s.Max }},
+	{Key: "p(90)", Get: func(s *stats.TrendSink) float64 { return s.P(0.90) }},
+	{Key: "p(95)", Get: func(s *stats.TrendSink) float64 { return s.P(0.95) }},
+	{Key: "count", Get: func(s *stats.TrendSink) float64 { return float64(s.Count) }, Unitless: true},
 }
 
+// TrendColumns are the columns shown for trend metrics; all builtin ones but count by default.
+var TrendColumns = builtinTrendColumns[:6]
+
 type TrendColumn struct {
 	Key string
 	Get func(s *stats.TrendSink) float64
+
+	// Unitless columns aren't in the unit of the metric, e.g. the number of values.
+	Unitless bool
 }
 
 // VerifyTrendColumnStat checks if stat is a valid trend column
@@ -68,7 +77,7 @@ func VerifyTrendColumnStat(stat string) error {
 		return ErrStatEmptyString
 	}
 
-	for _, col := range TrendColumns {
+	for _, col := range builtinTrendColumns {
 		if col.Key == stat {
 			return nil
 		}
@@ -86,11 +95,11 @@ func UpdateTrendColumns(stats []string) {
 		percentileTrendColumn, err := generatePercentileTrendColumn(stat)
 
 		if err == nil {
-			newTrendColumns = append(newTrendColumns, TrendColumn{stat, percentileTrendColumn})
+			newTrendColumns = append(newTrendColumns, TrendColumn{Key: stat, Get: percentileTrendColumn})
 			continue
 		}
 
-		for _, col := range TrendColumns {
+		for _, col := range builtinTrendColumns {
 			if col.Key == stat {
 				newTrendColumns = append(newTrendColumns, col)
 				break
@@ -163,6 +172,15 @@ type SummaryData struct {
 	Root    *lib.Group
 	Metrics map[string]*stats.Metric
 	Time    time.Duration
+
+	// The metrics broken down by the values of the Opts.SummaryBreakdown tags, see Engine.Breakdowns.
+	Breakdowns map[string]map[string]map[string]*stats.Metric
+
+	// The summary of a previous run to compare this one to, if any.
+	Baseline *SummaryExport
+
+	// The values of some metrics over the course of the test, if any.
+	TimeSeries *stats.TimeSeries
 }
 
 func SummarizeCheck(w io.Writer, indent string, check *lib.Check) {
@@ -274,7 +292,12 @@ func SummarizeMetrics(w io.Writer, indent string, t time.Duration, timeUnit stri
 		if sink, ok := m.Sink.(*stats.TrendSink); ok {
 			cols := make([]string, len(TrendColumns))
 			for i, col := range TrendColumns {
-				value := m.HumanizeValue(col.Get(sink), timeUnit)
+				var value string
+				if col.Unitless {
+					value = strconv.FormatFloat(col.Get(sink), 'f', -1, 64)
+				} else {
+					value = m.HumanizeValue(col.Get(sink), timeUnit)
+				}
 				if l := StrWidth(value); l > trendColMaxLens[i] {
 					trendColMaxLens[i] = l
 				}
@@ -353,4 +376,72 @@ func Summarize(w io.Writer, indent string, data SummaryData) {
 		SummarizeGroup(w, indent+"    ", data.Root)
 	}
 	SummarizeMetrics(w, indent+"  ", data.Time, data.Opts.SummaryTimeUnit.String, data.Metrics)
+	SummarizeTimeSeries(w, indent, data)
+	SummarizeBreakdowns(w, indent, data)
+	SummarizeComparison(w, indent, data)
+}
+
+// SummarizeTimeSeries writes sparklines of the values of the metrics over the course of the
+// test, if it ran for long enough to have more than one bucket of them.
+func SummarizeTimeSeries(w io.Writer, indent string, data SummaryData) {
+	ts := data.TimeSeries
+	if ts == nil {
+		return
+	}
+	names := make([]string, 0, len(ts.Buckets))
+	for name, buckets := range ts.Buckets {
+		if len(buckets) > 1 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+
+	type row struct{ label, spark, max string }
+	var rows []row
+	labelLenMax := 0
+	for _, name := range names {
+		m := ts.Metrics[name]
+		lines := ts.Lines(name)
+		max := 0.0
+		for _, l := range lines {
+			max = math.Max(max, MaxValue(l.Values))
+		}
+		for _, l := range lines {
+			fmtMax := m.HumanizeValue(MaxValue(l.Values), data.Opts.SummaryTimeUnit.String)
+			if m.Type == stats.Counter {
+				fmtMax += "/s"
+			}
+			r := row{name + " " + l.Name, ScaledSparkline(l.Values, max), fmtMax}
+			if l := StrWidth(r.label); l > labelLenMax {
+				labelLenMax = l
+			}
+			rows = append(rows, r)
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "\n%s%s over time, every %s\n\n", indent+"    ", GroupPrefix, ts.Interval)
+	for _, r := range rows {
+		fmtLabel := r.label + GrayColor.Sprint(strings.Repeat(".", labelLenMax-StrWidth(r.label)+3)+":")
+		_, _ = fmt.Fprint(w, indent+"    "+fmtLabel+" "+ValueColor.Sprint(r.spark)+" "+ExtraColor.Sprint("max="+r.max)+"\n")
+	}
+}
+
+// SummarizeBreakdowns writes the metrics of each scenario, group etc. that the summary is broken
+// down by, in the order of the options, and in the order of their names.
+func SummarizeBreakdowns(w io.Writer, indent string, data SummaryData) {
+	for _, tag := range data.Opts.SummaryBreakdown {
+		values := data.Breakdowns[tag]
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(w, "\n%s%s %s %s\n\n", indent+"    ", GroupPrefix, tag, name)
+			SummarizeMetrics(w, indent+"    ", data.Time, data.Opts.SummaryTimeUnit.String, values[name])
+		}
+	}
 }
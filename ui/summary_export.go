@@ -0,0 +1,220 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/pkg/errors"
+)
+
+// A SummaryExport is the end-of-test summary as JSON, for other tools to use, and for later runs
+// to be compared to with `k6 run --compare`.
+type SummaryExport struct {
+	Time       float64                        `json:"time"` // In seconds
+	Metrics    map[string]SummaryExportMetric `json:"metrics"`
+	TimeSeries *SummaryExportTimeSeries       `json:"timeSeries,omitempty"`
+}
+
+// A SummaryExportMetric has the values that a metric has in the summary, and whether each of its
+// thresholds passed.
+type SummaryExportMetric struct {
+	Type       stats.MetricType   `json:"type"`
+	Contains   stats.ValueType    `json:"contains"`
+	Values     map[string]float64 `json:"values"`
+	Thresholds map[string]bool    `json:"thresholds,omitempty"`
+}
+
+// SummaryExportTimeSeries has the values of some metrics over the course of the test, by metric
+// and line name, e.g. ["http_req_duration"]["p(95)"], see stats.TimeSeries.Lines; nulls are for
+// the intervals without samples.
+type SummaryExportTimeSeries struct {
+	Start    time.Time                        `json:"start"`
+	Interval float64                          `json:"interval"` // In seconds
+	Metrics  map[string]map[string][]*float64 `json:"metrics"`
+}
+
+// NewSummaryExport returns the export of a summary; the values of trend metrics are their
+// thresholds' values, as well as the summaryTrendStats.
+func NewSummaryExport(data SummaryData) SummaryExport {
+	export := SummaryExport{
+		Time:    data.Time.Seconds(),
+		Metrics: make(map[string]SummaryExportMetric, len(data.Metrics)),
+	}
+	for name, m := range data.Metrics {
+		values := make(map[string]float64)
+		for k, v := range m.Sink.Format(data.Time) {
+			values[k] = v
+		}
+		if sink, ok := m.Sink.(*stats.TrendSink); ok {
+			for _, col := range TrendColumns {
+				values[col.Key] = col.Get(sink)
+			}
+		}
+		for k, v := range values {
+			// JSON has no NaN or infinity, and there's no value to compare to anyway
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				delete(values, k)
+			}
+		}
+
+		em := SummaryExportMetric{Type: m.Type, Contains: m.Contains, Values: values}
+		if len(m.Thresholds.Thresholds) > 0 {
+			em.Thresholds = make(map[string]bool, len(m.Thresholds.Thresholds))
+			for _, t := range m.Thresholds.Thresholds {
+				em.Thresholds[t.Source] = !t.Failed
+			}
+		}
+		export.Metrics[name] = em
+	}
+
+	if ts := data.TimeSeries; ts != nil && !ts.Start.IsZero() {
+		export.TimeSeries = &SummaryExportTimeSeries{
+			Start:    ts.Start,
+			Interval: ts.Interval.Seconds(),
+			Metrics:  make(map[string]map[string][]*float64),
+		}
+		for name := range ts.Buckets {
+			lines := ts.Lines(name)
+			if len(lines) == 0 {
+				continue
+			}
+			export.TimeSeries.Metrics[name] = make(map[string][]*float64, len(lines))
+			for _, l := range lines {
+				values := make([]*float64, len(l.Values))
+				for i, v := range l.Values {
+					if !math.IsNaN(v) && !math.IsInf(v, 0) {
+						v := v
+						values[i] = &v
+					}
+				}
+				export.TimeSeries.Metrics[name][l.Name] = values
+			}
+		}
+	}
+	return export
+}
+
+// SummarizeJSON writes the export of a summary as JSON.
+func SummarizeJSON(w io.Writer, data SummaryData) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewSummaryExport(data))
+}
+
+// ReadSummaryExport reads a summary that SummarizeJSON wrote.
+func ReadSummaryExport(r io.Reader) (SummaryExport, error) {
+	var export SummaryExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return export, err
+	}
+	if export.Metrics == nil {
+		return export, errors.New("it has no metrics")
+	}
+	return export, nil
+}
+
+// MetricValues returns the values of the metrics, by metric name.
+func (e SummaryExport) MetricValues() map[string]map[string]float64 {
+	values := make(map[string]map[string]float64, len(e.Metrics))
+	for name, m := range e.Metrics {
+		values[name] = m.Values
+	}
+	return values
+}
+
+// SummarizeComparison writes how the values of the metrics changed since the baseline run, as
+// percentages, for the metrics and values that both runs have.
+func SummarizeComparison(w io.Writer, indent string, data SummaryData) {
+	if data.Baseline == nil {
+		return
+	}
+	current := NewSummaryExport(data)
+
+	names := []string{}
+	nameLenMax := 0
+	for name := range current.Metrics {
+		if _, ok := data.Baseline.Metrics[name]; !ok {
+			continue
+		}
+		names = append(names, name)
+		if l := StrWidth(name); l > nameLenMax {
+			nameLenMax = l
+		}
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintf(w, "\n%s%s compared to the baseline\n\n", indent+"    ", GroupPrefix)
+	if len(names) == 0 {
+		_, _ = fmt.Fprintf(w, "%s  the baseline has none of the metrics\n", indent+"    ")
+		return
+	}
+	for _, name := range names {
+		now, then := current.Metrics[name].Values, data.Baseline.Metrics[name].Values
+
+		var keys []string
+		if current.Metrics[name].Type == stats.Trend {
+			for _, col := range TrendColumns {
+				keys = append(keys, col.Key)
+			}
+		} else {
+			for k := range now {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+		}
+
+		parts := []string{}
+		for _, k := range keys {
+			v, ok1 := now[k]
+			base, ok2 := then[k]
+			if !ok1 || !ok2 {
+				continue
+			}
+			parts = append(parts, k+"="+ValueColor.Sprint(FormatDelta(v, base)))
+		}
+		fmtName := name + GrayColor.Sprint(strings.Repeat(".", nameLenMax-StrWidth(name)+3)+":")
+		_, _ = fmt.Fprint(w, indent+"    "+fmtName+" "+strings.Join(parts, " ")+"\n")
+	}
+}
+
+// FormatDelta returns the change from a baseline value to a value as a signed percentage, or as
+// the signed difference if the baseline value is 0.
+func FormatDelta(value, base float64) string {
+	if base == 0 {
+		if value == 0 {
+			return "±0%"
+		}
+		return fmt.Sprintf("%+g", value)
+	}
+	delta := (value - base) / math.Abs(base) * 100
+	if delta == 0 {
+		return "±0%"
+	}
+	return fmt.Sprintf("%+.2f%%", delta)
+}
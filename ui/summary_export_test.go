@@ -0,0 +1,108 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2018 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummaryExport(t *testing.T) {
+	newData := func(durations ...float64) SummaryData {
+		duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+		for _, d := range durations {
+			duration.Sink.Add(stats.Sample{Value: d})
+		}
+		reqs := stats.New("http_reqs", stats.Counter)
+		reqs.Sink.Add(stats.Sample{Value: float64(len(durations))})
+		checks := stats.New("checks", stats.Rate)
+		return SummaryData{
+			Time: 10 * time.Second,
+			Metrics: map[string]*stats.Metric{
+				"http_req_duration": duration,
+				"http_reqs":         reqs,
+				"checks":            checks,
+			},
+		}
+	}
+
+	data := newData(100, 200, 300)
+	var err error
+	data.Metrics["http_req_duration"].Thresholds, err = stats.NewThresholds([]string{"p(95)<250"})
+	require.NoError(t, err)
+	data.Metrics["http_req_duration"].Thresholds.Thresholds[0].Failed = true
+
+	var buf bytes.Buffer
+	require.NoError(t, SummarizeJSON(&buf, data))
+	export, err := ReadSummaryExport(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, export.Time)
+	duration := export.Metrics["http_req_duration"]
+	assert.Equal(t, stats.Trend, duration.Type)
+	assert.Equal(t, stats.Time, duration.Contains)
+	assert.Equal(t, 200.0, duration.Values["avg"])
+	assert.Contains(t, duration.Values, "p(95)")
+	assert.Equal(t, map[string]bool{"p(95)<250": false}, duration.Thresholds)
+	assert.Equal(t, map[string]float64{"count": 3, "rate": 0.3}, export.Metrics["http_reqs"].Values)
+	assert.Empty(t, export.Metrics["checks"].Values, "NaN rates can't be in the JSON")
+
+	_, err = ReadSummaryExport(strings.NewReader(`{}`))
+	assert.EqualError(t, err, "it has no metrics")
+
+	t.Run("comparison", func(t *testing.T) {
+		current := newData(110, 220, 330)
+		current.Baseline = &export
+		delete(export.Metrics, "checks")
+
+		var buf bytes.Buffer
+		SummarizeComparison(&buf, "", current)
+		out := buf.String()
+		assert.Contains(t, out, "compared to the baseline")
+		assert.Contains(t, out, "avg=+10.00%")
+		assert.Contains(t, out, "count=±0%")
+		assert.NotContains(t, out, "checks")
+
+		buf.Reset()
+		current.Baseline = nil
+		SummarizeComparison(&buf, "", current)
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestFormatDelta(t *testing.T) {
+	testdata := map[[2]float64]string{
+		{110, 100}: "+10.00%",
+		{90, 100}:  "-10.00%",
+		{100, 100}: "±0%",
+		{0, 0}:     "±0%",
+		{5, 0}:     "+5",
+		{-1, -2}:   "+50.00%",
+	}
+	for values, expected := range testdata {
+		assert.Equal(t, expected, FormatDelta(values[0], values[1]), "%v", values)
+	}
+}
@@ -21,10 +21,15 @@
 package ui
 
 import (
+	"bytes"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var verifyTests = []struct {
@@ -41,6 +46,7 @@ var verifyTests = []struct {
 	{"p(99)", nil},
 	{"p(99.9)", nil},
 	{"p(99.9999)", nil},
+	{"count", nil},
 	{"nil", ErrStatUnknownFormat},
 	{" avg", ErrStatUnknownFormat},
 	{"avg ", ErrStatUnknownFormat},
@@ -108,6 +114,21 @@ func TestUpdateTrendColumns(t *testing.T) {
 		assert.Exactly(t, sink.Max, TrendColumns[1].Get(sink))
 	})
 
+	t.Run("Count", func(t *testing.T) {
+		TrendColumns = defaultTrendColumns
+
+		UpdateTrendColumns([]string{"p(99)", "count"})
+
+		assert.Exactly(t, 2, len(TrendColumns))
+		assert.False(t, TrendColumns[0].Unitless)
+		assert.True(t, TrendColumns[1].Unitless)
+		assert.Exactly(t, float64(100), TrendColumns[1].Get(sink))
+
+		UpdateTrendColumns([]string{"min", "count"})
+		assert.Exactly(t, 2, len(TrendColumns), "builtin columns can be chosen after an update")
+		assert.Exactly(t, sink.Min, TrendColumns[0].Get(sink))
+	})
+
 	t.Run("Percentile stats", func(t *testing.T) {
 		TrendColumns = defaultTrendColumns
 
@@ -118,6 +139,87 @@ func TestUpdateTrendColumns(t *testing.T) {
 	})
 }
 
+func TestSummarizeMetricsTrendColumns(t *testing.T) {
+	defer func() { TrendColumns = defaultTrendColumns }()
+	TrendColumns = defaultTrendColumns
+	UpdateTrendColumns([]string{"med", "p(99.9)", "count"})
+
+	m := stats.New("my_trend", stats.Trend, stats.Time)
+	m.Sink = createTestTrendSink(2000)
+	var buf bytes.Buffer
+	SummarizeMetrics(&buf, "", time.Second, "s", map[string]*stats.Metric{"my_trend": m})
+	assert.Contains(t, buf.String(), "med=")
+	assert.Contains(t, buf.String(), "p(99.9)=")
+	assert.Contains(t, buf.String(), "1.00s")
+	assert.Contains(t, buf.String(), "count=")
+	assert.Contains(t, buf.String(), "2000")
+	assert.NotContains(t, buf.String(), "avg=")
+}
+
+func TestSummarizeBreakdowns(t *testing.T) {
+	newCounter := func(value float64) map[string]*stats.Metric {
+		m := stats.New("iterations", stats.Counter)
+		m.Sink.Add(stats.Sample{Value: value})
+		return map[string]*stats.Metric{"iterations": m}
+	}
+	data := SummaryData{
+		Opts: lib.Options{SummaryBreakdown: []string{"scenario", "group"}},
+		Time: time.Second,
+		Breakdowns: map[string]map[string]map[string]*stats.Metric{
+			"scenario": {"checkout": newCounter(2), "browse": newCounter(5)},
+			"group":    {"::pay": newCounter(3)},
+		},
+	}
+
+	var buf bytes.Buffer
+	SummarizeBreakdowns(&buf, "", data)
+	out := buf.String()
+	browse, checkout, pay := strings.Index(out, "█ scenario browse"), strings.Index(out, "█ scenario checkout"), strings.Index(out, "█ group ::pay")
+	assert.True(t, browse >= 0 && browse < checkout && checkout < pay, out)
+	assert.Contains(t, out[browse:checkout], "iterations")
+	assert.Contains(t, out[checkout:pay], "2")
+	assert.Contains(t, out[pay:], "3")
+
+	buf.Reset()
+	data.Opts.SummaryBreakdown = nil
+	SummarizeBreakdowns(&buf, "", data)
+	assert.Empty(t, buf.String())
+}
+
+func TestSummarizeTimeSeries(t *testing.T) {
+	duration, reqs := stats.New("http_req_duration", stats.Trend, stats.Time), stats.New("http_reqs", stats.Counter)
+	ts := stats.NewTimeSeries("http_req_duration", "http_reqs", "vus")
+	start := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		now := start.Add(time.Duration(i) * time.Second)
+		ts.Add(stats.Sample{Metric: duration, Time: now, Value: float64(100 * (i + 1))})
+		ts.Add(stats.Sample{Metric: reqs, Time: now, Value: float64(i + 1)})
+	}
+
+	var buf bytes.Buffer
+	SummarizeTimeSeries(&buf, "", SummaryData{TimeSeries: ts})
+	out := buf.String()
+	assert.Contains(t, out, "█ over time, every 1s")
+	assert.Contains(t, out, "http_req_duration avg")
+	assert.Contains(t, out, "max=400ms")
+	assert.Contains(t, out, "http_reqs rate")
+	assert.Contains(t, out, "max=4/s")
+	assert.NotContains(t, out, "vus")
+
+	export := NewSummaryExport(SummaryData{TimeSeries: ts, Time: 4 * time.Second})
+	require.NotNil(t, export.TimeSeries)
+	assert.Equal(t, start, export.TimeSeries.Start)
+	assert.Equal(t, 1.0, export.TimeSeries.Interval)
+	avg := export.TimeSeries.Metrics["http_req_duration"]["avg"]
+	require.Len(t, avg, 4)
+	assert.Equal(t, 400.0, *avg[3])
+
+	// A test with one interval of values has nothing to show over time
+	buf.Reset()
+	SummarizeTimeSeries(&buf, "", SummaryData{TimeSeries: stats.NewTimeSeries("vus")})
+	assert.Empty(t, buf.String())
+}
+
 func TestGeneratePercentileTrendColumn(t *testing.T) {
 	sink := createTestTrendSink(100)
 